@@ -10,6 +10,9 @@ const (
 	workerCommand    = "worker"
 	migrateCommand   = "migrate"
 	rollbackCommand  = "rollback"
+	rotateCommand    = "rotate-keys"
+	hashCommand      = "hash-refresh-tokens"
+	encryptCommand   = "encrypt-keys"
 )
 
 func commands() map[string]func(configFile string) {
@@ -18,6 +21,9 @@ func commands() map[string]func(configFile string) {
 		workerCommand:    app.StartWorker,
 		migrateCommand:   app.StartMigrations,
 		rollbackCommand:  app.StartRollbacks,
+		rotateCommand:    app.StartKeyRotation,
+		hashCommand:      app.StartRefreshTokenHashing,
+		encryptCommand:   app.StartKeyEncryption,
 	}
 }
 
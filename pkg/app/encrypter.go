@@ -0,0 +1,8 @@
+package app
+
+import "context"
+
+func StartKeyEncryption(configFile string) {
+	_, err := initClientStoreOnly(configFile).EncryptLegacyKeys(context.Background())
+	logError(err)
+}
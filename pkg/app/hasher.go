@@ -0,0 +1,8 @@
+package app
+
+import "context"
+
+func StartRefreshTokenHashing(configFile string) {
+	_, err := initSessionStoreOnly(configFile).HashLegacyRefreshTokens(context.Background())
+	logError(err)
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"github.com/go-redis/redis/v8"
 	"github.com/nsnikhil/erx"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -13,36 +14,68 @@ import (
 	"identification-service/pkg/http/router"
 	"identification-service/pkg/http/server"
 	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/oauth"
 	"identification-service/pkg/password"
 	"identification-service/pkg/queue"
 	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/role"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/token"
 	"identification-service/pkg/user"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 )
 
 func initHTTPServer(configFile string) server.Server {
 	cfg := config.NewConfig(configFile)
 	lgr, pr := initReporters(cfg)
-	cs, us, ss := initServices(cfg)
-	rt := initRouter(cfg, lgr, pr, cs, us, ss)
+	cs, us, ss, oa, rs, ts, ms := initServices(cfg)
+	rt := initRouter(cfg, lgr, pr, cs, us, ss, oa, rs, ts, ms)
 	return server.NewServer(cfg, lgr, rt)
 }
 
 func initConsumer(configFile string) consumer.Consumer {
 	cfg := config.NewConfig(configFile)
 	lgr := initLogger(cfg)
-	_, _, ss := initServices(cfg)
-	mr := consumer.NewMessageRouter(cfg.QueueConfig(), ss)
+	_, us, ss, _, _, _, _ := initServices(cfg)
+	mr := consumer.NewMessageRouter(cfg.QueueConfig(), ss, us, initMailer(cfg.MailerConfig()))
 	qu := initQueue(cfg.QueueConfig())
 
 	return consumer.NewConsumer(cfg.QueueConfig(), lgr, qu, mr)
 }
 
+func initMailer(cfg config.MailerConfig) mailer.Mailer {
+	var sender mailer.Sender
+
+	switch cfg.Provider() {
+	case "none":
+		return nil
+	case "smtp":
+		sender = mailer.NewSMTPSender(cfg)
+	case "file":
+		var w io.Writer = os.Stdout
+		if len(cfg.FilePath()) != 0 {
+			f, err := os.OpenFile(cfg.FilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+			logError(err)
+
+			w = f
+		}
+
+		sender = mailer.NewFileSender(w)
+	default:
+		log.Fatalf("unsupported mailer provider %s", cfg.Provider())
+	}
+
+	return mailer.NewMailer(cfg.From(), mailer.NewRenderer(cfg.TemplateDir()), sender)
+}
+
 func initQueue(cfg config.QueueConfig) queue.Queue {
 	ch, err := queue.NewHandler(cfg).GetChannel()
 	logError(err)
@@ -64,8 +97,74 @@ func initMigrator(configFile string) database.Migrator {
 	return mg
 }
 
-func initRouter(cfg config.Config, lgr reporters.Logger, prometheus reporters.Prometheus, cs client.Service, us user.Service, ss session.Service) http.Handler {
-	return router.NewRouter(cfg, lgr, prometheus, cs, us, ss)
+func initClientServiceOnly(configFile string) client.Service {
+	cfg := config.NewConfig(configFile)
+
+	db := database.NewSQLDatabase(initSqlDB(cfg), cfg.DatabaseConfig().QueryTTL())
+
+	cc, err := cache.NewHandler(cfg.CacheConfig()).GetCache()
+	logError(err)
+
+	return initClientService(cfg.ClientConfig(), db, cc, initEnvelope(cfg.KMSConfig()), libcrypto.NewKeyGenerator())
+}
+
+func initClientStoreOnly(configFile string) client.Store {
+	cfg := config.NewConfig(configFile)
+
+	db := database.NewSQLDatabase(initSqlDB(cfg), cfg.DatabaseConfig().QueryTTL())
+
+	cc, err := cache.NewHandler(cfg.CacheConfig()).GetCache()
+	logError(err)
+
+	return client.NewStore(db, cc, initEnvelope(cfg.KMSConfig()))
+}
+
+func initEnvelope(cfg config.KMSConfig) libcrypto.Envelope {
+	if cfg.Provider() != "local" {
+		log.Fatalf("unsupported kms provider %s", cfg.Provider())
+	}
+
+	encoded := cfg.MasterKey()
+	if len(cfg.MasterKeyFile()) != 0 {
+		data, err := ioutil.ReadFile(cfg.MasterKeyFile())
+		logError(err)
+
+		encoded = strings.TrimSpace(string(data))
+	}
+
+	masterKey, err := base64.StdEncoding.DecodeString(encoded)
+	logError(err)
+
+	kms, err := libcrypto.NewLocalKMS(masterKey)
+	logError(err)
+
+	return libcrypto.NewEnvelope(kms)
+}
+
+func initHasher(cfg config.TokenConfig) token.Hasher {
+	hasher, err := token.NewHasher(cfg)
+	logError(err)
+
+	return hasher
+}
+
+func initSigner(cfg config.TokenConfig) token.Signer {
+	signer, err := token.NewSigner(cfg)
+	logError(err)
+
+	return signer
+}
+
+func initSessionStoreOnly(configFile string) session.Store {
+	cfg := config.NewConfig(configFile)
+
+	db := database.NewSQLDatabase(initSqlDB(cfg), cfg.DatabaseConfig().QueryTTL())
+
+	return session.NewStore(db, initHasher(cfg.TokenConfig()))
+}
+
+func initRouter(cfg config.Config, lgr reporters.Logger, prometheus reporters.Prometheus, cs client.Service, us user.Service, ss session.Service, oa oauth.Service, rs role.Service, ts tenant.Service, ms mfa.Service) http.Handler {
+	return router.NewRouter(cfg, lgr, prometheus, cs, us, ss, oa, rs, ts, ms)
 }
 
 func initSqlDB(cfg config.Config) *sql.DB {
@@ -77,7 +176,7 @@ func initSqlDB(cfg config.Config) *sql.DB {
 	return sqlDB
 }
 
-func initServices(cfg config.Config) (client.Service, user.Service, session.Service) {
+func initServices(cfg config.Config) (client.Service, user.Service, session.Service, oauth.Service, role.Service, tenant.Service, mfa.Service) {
 	sqlDB := initSqlDB(cfg)
 
 	db := database.NewSQLDatabase(sqlDB, cfg.DatabaseConfig().QueryTTL())
@@ -89,32 +188,56 @@ func initServices(cfg config.Config) (client.Service, user.Service, session.Serv
 
 	kg := libcrypto.NewKeyGenerator()
 
-	tg, err := token.NewGenerator(cfg.TokenConfig(), kg)
-	logError(err)
+	tg := token.NewGenerator(cfg.TokenConfig())
+	tv := token.NewVerifier(cfg.TokenConfig())
 
 	qu := initQueue(cfg.QueueConfig())
 
-	cs := initClientService(cfg.ClientConfig(), db, cc, kg)
-	us := initUserService(cfg.QueueConfig(), db, en, qu)
-	ss := initSessionService(cfg.ClientConfig(), db, us, tg)
+	cs := initClientService(cfg.ClientConfig(), db, cc, initEnvelope(cfg.KMSConfig()), kg)
+	us := initUserService(cfg, db, en, qu)
+	rs := initRoleService(db)
+	ts := initTenantService(db)
+	ms := initMFAService(cfg, db, initEnvelope(cfg.KMSConfig()), en)
+	ss := initSessionService(cfg, db, us, cs, rs, ms, tg, tv, token.NewDenylist(cc), qu)
+	oa := initOAuthService(cfg, db, cs, us, ss, ms, tg)
 
-	return cs, us, ss
+	return cs, us, ss, oa, rs, ts, ms
 }
 
-func initClientService(cfg config.ClientConfig, db database.SQLDatabase, cc *redis.Client, kg libcrypto.Ed25519Generator) client.Service {
-	st := client.NewStore(db, cc)
+func initClientService(cfg config.ClientConfig, db database.SQLDatabase, cc *redis.Client, en libcrypto.Envelope, kg libcrypto.Ed25519Generator) client.Service {
+	st := client.NewStore(db, cc, en)
 	return client.NewService(cfg, st, kg)
 }
 
-func initUserService(cfg config.QueueConfig, db database.SQLDatabase, en password.Encoder, qu queue.Queue) user.Service {
-	st := user.NewStore(db)
-	return user.NewService(cfg, st, en, qu)
+func initUserService(cfg config.Config, db database.SQLDatabase, en password.Encoder, qu queue.Queue) user.Service {
+	st := user.NewStore(db, initHasher(cfg.TokenConfig()))
+	return user.NewService(cfg.QueueConfig(), cfg.UserConfig(), st, en, initSigner(cfg.TokenConfig()), qu)
+}
+
+func initRoleService(db database.SQLDatabase) role.Service {
+	st := role.NewStore(db)
+	return role.NewService(st)
+}
+
+func initTenantService(db database.SQLDatabase) tenant.Service {
+	st := tenant.NewStore(db)
+	return tenant.NewService(st)
+}
+
+func initMFAService(cfg config.Config, db database.SQLDatabase, en libcrypto.Envelope, pe password.Encoder) mfa.Service {
+	st := mfa.NewStore(db, en)
+	return mfa.NewService(cfg.MFAConfig(), st, initSigner(cfg.TokenConfig()), pe)
+}
+
+func initSessionService(cfg config.Config, db database.SQLDatabase, us user.Service, cs client.Service, rs role.Service, ms mfa.Service, tg token.Generator, tv token.Verifier, dl token.Denylist, qu queue.Queue) session.Service {
+	st := session.NewStore(db, initHasher(cfg.TokenConfig()))
+	sts := initStrategies(cfg.ClientConfig(), st)
+	return session.NewService(cfg.QueueConfig(), st, us, cs, rs, ms, tg, tv, dl, qu, sts)
 }
 
-func initSessionService(cfg config.ClientConfig, db database.SQLDatabase, us user.Service, tg token.Generator) session.Service {
-	st := session.NewStore(db)
-	sts := initStrategies(cfg, st)
-	return session.NewService(st, us, tg, sts)
+func initOAuthService(cfg config.Config, db database.SQLDatabase, cs client.Service, us user.Service, ss session.Service, ms mfa.Service, tg token.Generator) oauth.Service {
+	st := oauth.NewStore(db, initHasher(cfg.TokenConfig()))
+	return oauth.NewService(cfg.OAuthConfig(), st, cs, us, ss, ms, tg)
 }
 
 //TODO: NAME SHOULD COME FROM CONFIG
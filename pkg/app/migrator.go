@@ -2,6 +2,9 @@ package app
 
 func StartMigrations(configFile string) {
 	logError(initMigrator(configFile).Migrate())
+
+	//NOTE: SESSIONS ARE LOOKED UP BY THE HASH OF THEIR REFRESH TOKEN, LEGACY ONES MUST BE HASHED BEFORE THE SERVICE STARTS
+	StartRefreshTokenHashing(configFile)
 }
 
 func StartRollbacks(configFile string) {
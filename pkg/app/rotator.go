@@ -0,0 +1,7 @@
+package app
+
+import "context"
+
+func StartKeyRotation(configFile string) {
+	logError(initClientServiceOnly(configFile).RotateAllKeys(context.Background()))
+}
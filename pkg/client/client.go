@@ -3,13 +3,20 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/gob"
 	"errors"
 	"fmt"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/config"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/user"
 	"identification-service/pkg/util"
+	"identification-service/pkg/webauthn"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -17,28 +24,54 @@ type ctxKey string
 
 var clientCtxKey ctxKey = "clientCtxKey"
 
+const (
+	TypeConfidential = "confidential"
+	TypePublic       = "public"
+)
+
+var reservedClaims = map[string]bool{
+	"aud": true, "iss": true, "jti": true, "sub": true, "exp": true, "iat": true, "nbf": true,
+	"scope": true, "session_id": true, "act": true, "roles": true, "permissions": true, "tenant_id": true,
+}
+
 type Client struct {
 	internalClient
 }
 
 type internalClient struct {
-	Id                  string
-	Name                string
-	Secret              string
-	Revoked             bool
-	AccessTokenTTL      int
-	SessionTTL          int
-	MaxActiveSessions   int
-	SessionStrategyName string
-	PrivateKey          []byte
-	CreatedAt           time.Time
-	UpdatedAt           time.Time
+	Id                   string
+	TenantID             string
+	Name                 string
+	Secret               string
+	Type                 string
+	Revoked              bool
+	AccessTokenTTL       int
+	SessionTTL           int
+	MaxActiveSessions    int
+	SessionStrategyName  string
+	RotateRefreshTokens  bool
+	RequireVerifiedEmail bool
+	AllowMagicLinkSignUp bool
+	WebAuthnRPID         string
+	WebAuthnOrigins      []string
+	RedirectURIs         []string
+	AllowedScopes        []string
+	AllowedAudiences     []string
+	ClaimMappings        map[string]string
+	KeyID                string
+	PrivateKey           []byte
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
 }
 
 func (cl Client) IsRevoked() bool {
 	return cl.Revoked
 }
 
+func (cl Client) IsPublic() bool {
+	return cl.Type == TypePublic
+}
+
 func (cl Client) AccessTokenTTL() int {
 	return cl.internalClient.AccessTokenTTL
 }
@@ -55,18 +88,112 @@ func (cl Client) MaxActiveSessions() int {
 	return cl.internalClient.MaxActiveSessions
 }
 
+func (cl Client) RotatesRefreshTokens() bool {
+	return cl.internalClient.RotateRefreshTokens
+}
+
+func (cl Client) RequiresVerifiedEmail() bool {
+	return cl.internalClient.RequireVerifiedEmail
+}
+
+func (cl Client) AllowsMagicLinkSignUp() bool {
+	return cl.internalClient.AllowMagicLinkSignUp
+}
+
+func (cl Client) SupportsWebAuthn() bool {
+	return len(cl.WebAuthnRPID) != 0
+}
+
+func (cl Client) RelyingParty() webauthn.RelyingParty {
+	origins := cl.WebAuthnOrigins
+
+	//NOTE: WITHOUT EXPLICIT ORIGINS ONLY THE HTTPS ORIGIN OF THE RELYING PARTY ID ITSELF IS ACCEPTED
+	if len(origins) == 0 {
+		origins = []string{"https://" + cl.WebAuthnRPID}
+	}
+
+	return webauthn.RelyingParty{
+		ID:      cl.WebAuthnRPID,
+		Name:    cl.Name,
+		Origins: origins,
+	}
+}
+
+func (cl Client) IsRedirectURIRegistered(redirectURI string) bool {
+	//NOTE: REDIRECT URIS ARE COMPARED EXACTLY, NO PREFIX OR WILDCARD MATCHING
+	for _, uri := range cl.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cl Client) AllowsScopes(scopes []string) bool {
+	allowed := make(map[string]bool, len(cl.AllowedScopes))
+	for _, scope := range cl.AllowedScopes {
+		allowed[scope] = true
+	}
+
+	for _, scope := range scopes {
+		if !allowed[scope] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (cl Client) AllowsAudience(audience string) bool {
+	for _, aud := range cl.AllowedAudiences {
+		if aud == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cl Client) SigningKey() libcrypto.Key {
+	return libcrypto.Key{
+		ID:         cl.KeyID,
+		State:      libcrypto.ActiveKey,
+		PrivateKey: cl.PrivateKey,
+	}
+}
+
+type VerificationKey struct {
+	KeyID     string
+	ClientID  string
+	State     libcrypto.KeyState
+	PublicKey ed25519.PublicKey
+}
+
 type Builder struct {
-	id                  string
-	name                string
-	secret              string
-	revoked             bool
-	accessTokenTTL      int
-	sessionTTL          int
-	maxActiveSessions   int
-	sessionStrategyName string
-	privateKey          []byte
-	createdAt           time.Time
-	updatedAt           time.Time
+	id                   string
+	tenantID             string
+	name                 string
+	secret               string
+	clientType           string
+	revoked              bool
+	accessTokenTTL       int
+	sessionTTL           int
+	maxActiveSessions    int
+	sessionStrategyName  string
+	rotateRefreshTokens  bool
+	requireVerifiedEmail bool
+	allowMagicLinkSignUp bool
+	webAuthnRPID         string
+	webAuthnOrigins      []string
+	redirectURIs         []string
+	allowedScopes        []string
+	allowedAudiences     []string
+	claimMappings        map[string]string
+	keyID                string
+	privateKey           []byte
+	createdAt            time.Time
+	updatedAt            time.Time
 
 	err error
 	cfg config.ClientConfig
@@ -86,6 +213,25 @@ func (b *Builder) ID(id string) *Builder {
 	return b
 }
 
+func (b *Builder) TenantID(tenantID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	//NOTE: A CLIENT REGISTERED WITHOUT A TENANT BELONGS TO THE DEFAULT TENANT
+	if len(tenantID) == 0 {
+		return b
+	}
+
+	if !util.IsValidUUID(tenantID) {
+		b.err = fmt.Errorf("invalid tenant id %s", tenantID)
+		return b
+	}
+
+	b.tenantID = tenantID
+	return b
+}
+
 func (b *Builder) Name(name string) *Builder {
 	if b.err != nil {
 		return b
@@ -110,7 +256,26 @@ func (b *Builder) Secret(secret string) *Builder {
 		return b
 	}
 
-	b.id = secret
+	b.secret = secret
+	return b
+}
+
+func (b *Builder) Type(clientType string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	//NOTE: A CLIENT REGISTERED WITHOUT A TYPE IS CONFIDENTIAL, SO IT HAS TO PRESENT ITS SECRET ON EVERY GRANT
+	if len(clientType) == 0 {
+		return b
+	}
+
+	if clientType != TypeConfidential && clientType != TypePublic {
+		b.err = fmt.Errorf("invalid client type %s", clientType)
+		return b
+	}
+
+	b.clientType = clientType
 	return b
 }
 
@@ -183,6 +348,204 @@ func (b *Builder) SessionStrategy(sessionStrategyName string) *Builder {
 	return b
 }
 
+func (b *Builder) RotateRefreshTokens(rotateRefreshTokens bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.rotateRefreshTokens = rotateRefreshTokens
+	return b
+}
+
+func (b *Builder) RequireVerifiedEmail(requireVerifiedEmail bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.requireVerifiedEmail = requireVerifiedEmail
+	return b
+}
+
+func (b *Builder) AllowMagicLinkSignUp(allowMagicLinkSignUp bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.allowMagicLinkSignUp = allowMagicLinkSignUp
+	return b
+}
+
+func (b *Builder) WebAuthnRPID(rpID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if len(rpID) == 0 {
+		return b
+	}
+
+	if !isValidRPID(rpID) {
+		b.err = fmt.Errorf("invalid webauthn relying party id %s", rpID)
+		return b
+	}
+
+	b.webAuthnRPID = rpID
+	return b
+}
+
+func (b *Builder) WebAuthnOrigins(origins []string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	for _, origin := range origins {
+		if !isValidOrigin(origin) {
+			b.err = fmt.Errorf("invalid webauthn origin %s", origin)
+			return b
+		}
+	}
+
+	b.webAuthnOrigins = origins
+	return b
+}
+
+func isValidRPID(rpID string) bool {
+	u, err := url.Parse("https://" + rpID)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == rpID && u.Hostname() == rpID && len(u.Path) == 0
+}
+
+func isValidOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return len(u.Scheme) != 0 && len(u.Host) != 0 && len(u.Path) == 0 && len(u.RawQuery) == 0 && len(u.Fragment) == 0 && u.User == nil
+}
+
+func isOriginInScope(origin, rpID string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	//NOTE: AN ORIGIN CAN ONLY USE A RELYING PARTY ID EQUAL TO ITS HOST OR ONE OF ITS PARENT DOMAINS
+	host := u.Hostname()
+	return host == rpID || strings.HasSuffix(host, "."+rpID)
+}
+
+func (b *Builder) KeyID(keyID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !util.IsValidUUID(keyID) {
+		b.err = fmt.Errorf("invalid key id %s", keyID)
+		return b
+	}
+
+	b.keyID = keyID
+	return b
+}
+
+func (b *Builder) RedirectURIs(redirectURIs []string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	for _, redirectURI := range redirectURIs {
+		if !isValidRedirectURI(redirectURI) {
+			b.err = fmt.Errorf("invalid redirect uri %s", redirectURI)
+			return b
+		}
+	}
+
+	b.redirectURIs = redirectURIs
+	return b
+}
+
+func isValidRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return false
+	}
+
+	return u.IsAbs() && len(u.Fragment) == 0
+}
+
+func (b *Builder) AllowedScopes(allowedScopes []string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	for _, scope := range allowedScopes {
+		if !isValidScope(scope) {
+			b.err = fmt.Errorf("invalid scope %s", scope)
+			return b
+		}
+	}
+
+	b.allowedScopes = allowedScopes
+	return b
+}
+
+func (b *Builder) AllowedAudiences(allowedAudiences []string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	for _, audience := range allowedAudiences {
+		if len(audience) == 0 {
+			b.err = errors.New("audience cannot be empty")
+			return b
+		}
+	}
+
+	b.allowedAudiences = allowedAudiences
+	return b
+}
+
+func (b *Builder) ClaimMappings(claimMappings map[string]string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	//NOTE: CLAIMS SET BY THE SERVICE ITSELF CANNOT BE MAPPED, OTHERWISE A CLIENT COULD OVERRIDE THEM
+	for claim, attribute := range claimMappings {
+		if len(claim) == 0 || reservedClaims[claim] {
+			b.err = fmt.Errorf("invalid claim %s", claim)
+			return b
+		}
+
+		if !user.IsValidAttribute(attribute) {
+			b.err = fmt.Errorf("invalid user attribute %s", attribute)
+			return b
+		}
+	}
+
+	b.claimMappings = claimMappings
+	return b
+}
+
+func isValidScope(scope string) bool {
+	if len(scope) == 0 {
+		return false
+	}
+
+	//NOTE: A SCOPE TOKEN IS ANY PRINTABLE ASCII CHARACTER EXCEPT SPACE, DOUBLE QUOTE AND BACKSLASH AS PER RFC 6749
+	for _, c := range scope {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (b *Builder) PrivateKey(privateKey []byte) *Builder {
 	if b.err != nil {
 		return b
@@ -230,30 +593,48 @@ func (b *Builder) Build() (Client, error) {
 		return Client{}, erx.WithArgs(erx.Operation("ClientBuilder.Build"), erx.ValidationError, b.err)
 	}
 
-	if err := validateArgs(b.name, b.accessTokenTTL, b.sessionTTL, b.maxActiveSessions, b.sessionStrategyName, b.privateKey); err != nil {
+	if err := validateArgs(b.name, b.accessTokenTTL, b.sessionTTL, b.maxActiveSessions, b.sessionStrategyName, b.keyID, b.privateKey); err != nil {
+		return Client{}, erx.WithArgs(erx.Operation("ClientBuilder.Build"), erx.ValidationError, err)
+	}
+
+	if err := validateWebAuthn(b.webAuthnRPID, b.webAuthnOrigins); err != nil {
 		return Client{}, erx.WithArgs(erx.Operation("ClientBuilder.Build"), erx.ValidationError, err)
 	}
 
 	return Client{
 		internalClient{
-			Id:                  b.id,
-			Name:                b.name,
-			Secret:              b.secret,
-			Revoked:             b.revoked,
-			AccessTokenTTL:      b.accessTokenTTL,
-			SessionTTL:          b.sessionTTL,
-			MaxActiveSessions:   b.maxActiveSessions,
-			SessionStrategyName: b.sessionStrategyName,
-			PrivateKey:          b.privateKey,
-			CreatedAt:           b.createdAt,
-			UpdatedAt:           b.updatedAt,
+			Id:                   b.id,
+			TenantID:             b.tenantID,
+			Name:                 b.name,
+			Secret:               b.secret,
+			Type:                 b.clientType,
+			Revoked:              b.revoked,
+			AccessTokenTTL:       b.accessTokenTTL,
+			SessionTTL:           b.sessionTTL,
+			MaxActiveSessions:    b.maxActiveSessions,
+			SessionStrategyName:  b.sessionStrategyName,
+			RotateRefreshTokens:  b.rotateRefreshTokens,
+			RequireVerifiedEmail: b.requireVerifiedEmail,
+			AllowMagicLinkSignUp: b.allowMagicLinkSignUp,
+			WebAuthnRPID:         b.webAuthnRPID,
+			WebAuthnOrigins:      b.webAuthnOrigins,
+			RedirectURIs:         b.redirectURIs,
+			AllowedScopes:        b.allowedScopes,
+			AllowedAudiences:     b.allowedAudiences,
+			ClaimMappings:        b.claimMappings,
+			KeyID:                b.keyID,
+			PrivateKey:           b.privateKey,
+			CreatedAt:            b.createdAt,
+			UpdatedAt:            b.updatedAt,
 		},
 	}, nil
 }
 
 func NewClientBuilder(cfg config.ClientConfig) *Builder {
 	return &Builder{
-		cfg: cfg,
+		tenantID:   tenant.DefaultID,
+		clientType: TypeConfidential,
+		cfg:        cfg,
 	}
 }
 
@@ -268,6 +649,7 @@ func WithContext(ctx context.Context, cl Client) (context.Context, error) {
 		cl.internalClient.SessionTTL,
 		cl.internalClient.MaxActiveSessions,
 		cl.internalClient.SessionStrategyName,
+		cl.KeyID,
 		cl.PrivateKey,
 	)
 
@@ -301,7 +683,8 @@ func FromContext(ctx context.Context) (Client, error) {
 		cl.internalClient.SessionTTL,
 		cl.internalClient.MaxActiveSessions,
 		cl.internalClient.SessionStrategyName,
-		cl.internalClient.PrivateKey,
+		cl.KeyID,
+		cl.PrivateKey,
 	)
 
 	if err != nil {
@@ -311,8 +694,8 @@ func FromContext(ctx context.Context) (Client, error) {
 	return cl, nil
 }
 
-//TODO: THIS IS CURRENTLY REPEATED BECAUSE USING BUILDER SOMEONE MIGHT NOT SET THESE VALUES
-func validateArgs(name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategyName string, privateKey []byte) error {
+// TODO: THIS IS CURRENTLY REPEATED BECAUSE USING BUILDER SOMEONE MIGHT NOT SET THESE VALUES
+func validateArgs(name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategyName, keyID string, privateKey []byte) error {
 	if len(name) == 0 {
 		return errors.New("client name cannot be empty")
 	}
@@ -338,6 +721,10 @@ func validateArgs(name string, accessTokenTTL, sessionTTL, maxActiveSessions int
 		return errors.New("session strategy name cannot be empty")
 	}
 
+	if len(keyID) == 0 {
+		return errors.New("key id cannot be empty")
+	}
+
 	if privateKey == nil || len(privateKey) == 0 {
 		return errors.New("private key cannot be empty")
 	}
@@ -345,6 +732,33 @@ func validateArgs(name string, accessTokenTTL, sessionTTL, maxActiveSessions int
 	return nil
 }
 
+func validateWebAuthn(rpID string, origins []string) error {
+	if len(rpID) == 0 && len(origins) != 0 {
+		return errors.New("webauthn origins require a relying party id")
+	}
+
+	for _, origin := range origins {
+		if !isOriginInScope(origin, rpID) {
+			return fmt.Errorf("webauthn origin %s does not match relying party id %s", origin, rpID)
+		}
+	}
+
+	return nil
+}
+
+func (cl Client) isValid() bool {
+	//NOTE: CLIENTS CACHED BEFORE TENANTS WERE INTRODUCED HAVE NO TENANT AND ARE LOADED AGAIN FROM THE DATABASE
+	return len(cl.TenantID) != 0 && validateArgs(
+		cl.Name,
+		cl.internalClient.AccessTokenTTL,
+		cl.internalClient.SessionTTL,
+		cl.internalClient.MaxActiveSessions,
+		cl.internalClient.SessionStrategyName,
+		cl.KeyID,
+		cl.PrivateKey,
+	) == nil
+}
+
 func encode(cl Client) (string, error) {
 	b := bytes.Buffer{}
 	e := gob.NewEncoder(&b)
@@ -5,7 +5,9 @@ import (
 	"github.com/stretchr/testify/suite"
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"testing"
 	"time"
 )
@@ -34,10 +36,18 @@ func (ct *clientTest) TestClientBuilderBuildSuccess() {
 	ct.Require().NoError(err)
 }
 
+func (ct *clientTest) TestClientBuilderBuildSuccessWithoutTenant() {
+	cl, err := test.NewClient(ct.cfg, map[string]interface{}{test.ClientTenantIDKey: ""})
+	ct.Require().NoError(err)
+
+	ct.Assert().Equal(tenant.DefaultID, cl.TenantID)
+}
+
 func (ct *clientTest) TestClientBuilderBuildFailureValidation() {
 	testCases := map[string]map[string]interface{}{
 		"test failure when id is empty":                        {test.ClientIdKey: ""},
 		"test failure when id is invalid":                      {test.ClientIdKey: "invalid id"},
+		"test failure when tenant id is invalid":               {test.ClientTenantIDKey: "invalid id"},
 		"test failure when name is empty":                      {test.ClientNameKey: ""},
 		"test failure when secret is empty":                    {test.ClientSecretKey: ""},
 		"test failure when secret is invalid":                  {test.ClientSecretKey: "invalid secret"},
@@ -46,6 +56,20 @@ func (ct *clientTest) TestClientBuilderBuildFailureValidation() {
 		"test failure when max active sessions is less than 1": {test.ClientMaxActiveSessionsKey: 0},
 		"test failure when session strategy is empty":          {test.ClientSessionStrategyNameKey: ""},
 		"test failure when session strategy is invalid":        {test.ClientSessionStrategyNameKey: "invalid"},
+		"test failure when redirect uri is relative":           {test.ClientRedirectURIsKey: []string{"/callback"}},
+		"test failure when redirect uri has a fragment":        {test.ClientRedirectURIsKey: []string{"https://app.example.com/callback#top"}},
+		"test failure when webauthn rp id has a scheme":        {test.ClientWebAuthnRPIDKey: "https://app.example.com"},
+		"test failure when webauthn rp id has a port":          {test.ClientWebAuthnRPIDKey: "app.example.com:8443"},
+		"test failure when webauthn origin has a path":         {test.ClientWebAuthnOriginsKey: []string{"https://app.example.com/login"}},
+		"test failure when webauthn origin is out of scope":    {test.ClientWebAuthnOriginsKey: []string{"https://example.org"}},
+		"test failure when webauthn origins have no rp id":     {test.ClientWebAuthnRPIDKey: "", test.ClientWebAuthnOriginsKey: []string{test.WebAuthnOrigin}},
+		"test failure when scope is empty":                     {test.ClientAllowedScopesKey: []string{""}},
+		"test failure when scope has a space":                  {test.ClientAllowedScopesKey: []string{"orders read"}},
+		"test failure when audience is empty":                  {test.ClientAllowedAudiencesKey: []string{""}},
+		"test failure when claim is reserved":                  {test.ClientClaimMappingsKey: map[string]string{"sub": user.AttributeEmail}},
+		"test failure when user attribute is invalid":          {test.ClientClaimMappingsKey: map[string]string{"contact_email": "password"}},
+		"test failure when key id is empty":                    {test.ClientKeyIDKey: ""},
+		"test failure when key id is invalid":                  {test.ClientKeyIDKey: "invalid id"},
 		"test failure when private key is empty":               {test.ClientPrivateKeyKey: []byte{}},
 		"test failure when created at is set to zero value":    {test.ClientCreatedAtKey: time.Time{}},
 		"test failure when updated at is set to zero value":    {test.ClientUpdatedAtKey: time.Time{}},
@@ -92,6 +116,7 @@ func (ct *clientTest) TestClientGetters() {
 	accessTokenTTLVal := test.RandInt(1, 10)
 	sessionTTLVal := test.RandInt(1440, 86701)
 	maxActiveSessionsVal := test.RandInt(1, 10)
+	keyID := test.NewUUID()
 
 	cl, err := test.NewClient(
 		ct.cfg,
@@ -99,6 +124,7 @@ func (ct *clientTest) TestClientGetters() {
 			test.ClientAccessTokenTTLKey:    accessTokenTTLVal,
 			test.ClientSessionTTLKey:        sessionTTLVal,
 			test.ClientMaxActiveSessionsKey: maxActiveSessionsVal,
+			test.ClientKeyIDKey:             keyID,
 		},
 	)
 	ct.Require().NoError(err)
@@ -127,6 +153,54 @@ func (ct *clientTest) TestClientGetters() {
 			actualData:   cl.MaxActiveSessions(),
 			expectedData: maxActiveSessionsVal,
 		},
+		"test get rotate refresh tokens": {
+			actualData:   cl.RotatesRefreshTokens(),
+			expectedData: false,
+		},
+		"test magic link sign up is not allowed by default": {
+			actualData:   cl.AllowsMagicLinkSignUp(),
+			expectedData: false,
+		},
+		"test registered redirect uri": {
+			actualData:   cl.IsRedirectURIRegistered(test.ClientRedirectURI),
+			expectedData: true,
+		},
+		"test unregistered redirect uri": {
+			actualData:   cl.IsRedirectURIRegistered(test.ClientRedirectURI + "/other"),
+			expectedData: false,
+		},
+		"test allowed scopes": {
+			actualData:   cl.AllowsScopes([]string{test.ClientScope}),
+			expectedData: true,
+		},
+		"test no scopes are allowed": {
+			actualData:   cl.AllowsScopes(nil),
+			expectedData: true,
+		},
+		"test scope which is not allowed": {
+			actualData:   cl.AllowsScopes([]string{test.ClientScope, "orders:write"}),
+			expectedData: false,
+		},
+		"test allowed audience": {
+			actualData:   cl.AllowsAudience(test.ClientAudience),
+			expectedData: true,
+		},
+		"test audience which is not allowed": {
+			actualData:   cl.AllowsAudience("inventory"),
+			expectedData: false,
+		},
+		"test supports webauthn": {
+			actualData:   cl.SupportsWebAuthn(),
+			expectedData: true,
+		},
+		"test default webauthn relying party origins": {
+			actualData:   cl.RelyingParty().Origins,
+			expectedData: []string{test.WebAuthnOrigin},
+		},
+		"test get signing key id": {
+			actualData:   cl.SigningKey().ID,
+			expectedData: keyID,
+		},
 	}
 
 	for name, testCase := range testCases {
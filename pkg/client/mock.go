@@ -3,14 +3,16 @@ package client
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/libcrypto"
+	"time"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (mock *MockService) CreateClient(ctx context.Context, name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategy string) (string, string, error) {
-	args := mock.Called(ctx, name, accessTokenTTL, sessionTTL, maxActiveSessions, sessionStrategy)
+func (mock *MockService) CreateClient(ctx context.Context, name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategy string, rotateRefreshTokens bool, redirectURIs, allowedScopes, allowedAudiences []string, claimMappings map[string]string, tenantID string, requireVerifiedEmail, allowMagicLinkSignUp bool, webAuthnRPID string, webAuthnOrigins []string, clientType string) (string, string, error) {
+	args := mock.Called(ctx, name, accessTokenTTL, sessionTTL, maxActiveSessions, sessionStrategy, rotateRefreshTokens, redirectURIs, allowedScopes, allowedAudiences, claimMappings, tenantID, requireVerifiedEmail, allowMagicLinkSignUp, webAuthnRPID, webAuthnOrigins, clientType)
 	return args.String(0), args.String(1), args.Error(2)
 }
 
@@ -24,6 +26,31 @@ func (mock *MockService) GetClient(ctx context.Context, name, secret string) (Cl
 	return args.Get(0).(Client), args.Error(1)
 }
 
+func (mock *MockService) GetClientByName(ctx context.Context, name string) (Client, error) {
+	args := mock.Called(ctx, name)
+	return args.Get(0).(Client), args.Error(1)
+}
+
+func (mock *MockService) GetVerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	args := mock.Called(ctx)
+	return args.Get(0).([]VerificationKey), args.Error(1)
+}
+
+func (mock *MockService) GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error) {
+	args := mock.Called(ctx, keyID)
+	return args.Get(0).(VerificationKey), args.Error(1)
+}
+
+func (mock *MockService) RotateKeys(ctx context.Context, id string) error {
+	args := mock.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (mock *MockService) RotateAllKeys(ctx context.Context) error {
+	args := mock.Called(ctx)
+	return args.Error(0)
+}
+
 type MockStore struct {
 	mock.Mock
 }
@@ -33,8 +60,8 @@ func (mock *MockStore) RevokeClient(ctx context.Context, id string) (int64, erro
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (mock *MockStore) CreateClient(ctx context.Context, client Client) (string, error) {
-	args := mock.Called(ctx, client)
+func (mock *MockStore) CreateClient(ctx context.Context, client Client, keyRing libcrypto.KeyRing) (string, error) {
+	args := mock.Called(ctx, client, keyRing)
 	return args.String(0), args.Error(1)
 }
 
@@ -42,3 +69,43 @@ func (mock *MockStore) GetClient(ctx context.Context, name, secret string) (Clie
 	args := mock.Called(ctx, name, secret)
 	return args.Get(0).(Client), args.Error(1)
 }
+
+func (mock *MockStore) GetClientByName(ctx context.Context, name string) (Client, error) {
+	args := mock.Called(ctx, name)
+	return args.Get(0).(Client), args.Error(1)
+}
+
+func (mock *MockStore) GetVerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	args := mock.Called(ctx)
+	return args.Get(0).([]VerificationKey), args.Error(1)
+}
+
+func (mock *MockStore) GetClientIDs(ctx context.Context) ([]string, error) {
+	args := mock.Called(ctx)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (mock *MockStore) GetKeyRing(ctx context.Context, clientID string) (libcrypto.KeyRing, error) {
+	args := mock.Called(ctx, clientID)
+	return args.Get(0).(libcrypto.KeyRing), args.Error(1)
+}
+
+func (mock *MockStore) UpdateKeyRing(ctx context.Context, clientID string, keyRing libcrypto.KeyRing) error {
+	args := mock.Called(ctx, clientID, keyRing)
+	return args.Error(0)
+}
+
+func (mock *MockStore) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	args := mock.Called(ctx, now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (mock *MockStore) GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error) {
+	args := mock.Called(ctx, keyID)
+	return args.Get(0).(VerificationKey), args.Error(1)
+}
+
+func (mock *MockStore) EncryptLegacyKeys(ctx context.Context) (int, error) {
+	args := mock.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
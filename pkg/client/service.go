@@ -3,15 +3,22 @@ package client
 import (
 	"context"
 	"encoding/base64"
+	"github.com/google/uuid"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/config"
 	"identification-service/pkg/libcrypto"
+	"time"
 )
 
 type Service interface {
-	CreateClient(ctx context.Context, name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategy string) (string, string, error)
+	CreateClient(ctx context.Context, name string, accessTokenTTL, sessionTTL, maxActiveSessions int, sessionStrategy string, rotateRefreshTokens bool, redirectURIs, allowedScopes, allowedAudiences []string, claimMappings map[string]string, tenantID string, requireVerifiedEmail, allowMagicLinkSignUp bool, webAuthnRPID string, webAuthnOrigins []string, clientType string) (string, string, error)
 	RevokeClient(ctx context.Context, id string) error
 	GetClient(ctx context.Context, name, secret string) (Client, error)
+	GetClientByName(ctx context.Context, name string) (Client, error)
+	GetVerificationKeys(ctx context.Context) ([]VerificationKey, error)
+	GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error)
+	RotateKeys(ctx context.Context, id string) error
+	RotateAllKeys(ctx context.Context) error
 }
 
 type clientService struct {
@@ -27,33 +34,61 @@ func (cs *clientService) CreateClient(
 	sessionTTL,
 	maxActiveSessions int,
 	sessionStrategy string,
+	rotateRefreshTokens bool,
+	redirectURIs,
+	allowedScopes,
+	allowedAudiences []string,
+	claimMappings map[string]string,
+	tenantID string,
+	requireVerifiedEmail,
+	allowMagicLinkSignUp bool,
+	webAuthnRPID string,
+	webAuthnOrigins []string,
+	clientType string,
 ) (string, string, error) {
 
-	pubKey, priKey, err := cs.keyGenerator.Generate()
+	keyRing, err := libcrypto.NewKeyRing().Rotate(time.Now().UTC(), cs.newKey)
+	if err != nil {
+		return "", "", erx.WithArgs(erx.Operation("Service.CreateClient"), err)
+	}
+
+	key, err := keyRing.SigningKey()
 	if err != nil {
 		return "", "", erx.WithArgs(erx.Operation("Service.CreateClient"), err)
 	}
 
 	cl, err := NewClientBuilder(cs.cfg).
+		TenantID(tenantID).
 		Name(name).
+		Type(clientType).
 		AccessTokenTTL(accessTokenTTL).
 		SessionTTL(sessionTTL).
 		MaxActiveSessions(maxActiveSessions).
 		SessionStrategy(sessionStrategy).
-		PrivateKey(priKey).
+		RotateRefreshTokens(rotateRefreshTokens).
+		RequireVerifiedEmail(requireVerifiedEmail).
+		AllowMagicLinkSignUp(allowMagicLinkSignUp).
+		WebAuthnRPID(webAuthnRPID).
+		WebAuthnOrigins(webAuthnOrigins).
+		RedirectURIs(redirectURIs).
+		AllowedScopes(allowedScopes).
+		AllowedAudiences(allowedAudiences).
+		ClaimMappings(claimMappings).
+		KeyID(key.ID).
+		PrivateKey(key.PrivateKey).
 		Build()
 
 	if err != nil {
 		return "", "", erx.WithArgs(erx.Operation("Service.CreateClient"), err)
 	}
 
-	id, err := cs.store.CreateClient(ctx, cl)
+	id, err := cs.store.CreateClient(ctx, cl, keyRing)
 	if err != nil {
 		return "", "", erx.WithArgs(erx.Operation("Service.CreateClient"), err)
 	}
 
 	//TODO: PULL ENCODING IN SEPARATE PACKAGE
-	return base64.RawStdEncoding.EncodeToString(pubKey), id, nil
+	return base64.RawStdEncoding.EncodeToString(key.PublicKey()), id, nil
 }
 
 //TODO: SHOULD IT RETURN THE UPDATE COUNT ?
@@ -75,6 +110,87 @@ func (cs *clientService) GetClient(ctx context.Context, name, secret string) (Cl
 	return client, nil
 }
 
+func (cs *clientService) GetClientByName(ctx context.Context, name string) (Client, error) {
+	client, err := cs.store.GetClientByName(ctx, name)
+	if err != nil {
+		return Client{}, erx.WithArgs(erx.Operation("Service.GetClientByName"), err)
+	}
+
+	return client, nil
+}
+
+func (cs *clientService) GetVerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	keys, err := cs.store.GetVerificationKeys(ctx)
+	if err != nil {
+		return nil, erx.WithArgs(erx.Operation("Service.GetVerificationKeys"), err)
+	}
+
+	return keys, nil
+}
+
+func (cs *clientService) GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error) {
+	key, err := cs.store.GetVerificationKey(ctx, keyID)
+	if err != nil {
+		return VerificationKey{}, erx.WithArgs(erx.Operation("Service.GetVerificationKey"), err)
+	}
+
+	return key, nil
+}
+
+func (cs *clientService) RotateKeys(ctx context.Context, id string) error {
+	keyRing, err := cs.store.GetKeyRing(ctx, id)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.RotateKeys"), err)
+	}
+
+	keyRing, err = keyRing.Rotate(time.Now().UTC(), cs.newKey)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.RotateKeys"), err)
+	}
+
+	err = cs.store.UpdateKeyRing(ctx, id, keyRing)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.RotateKeys"), err)
+	}
+
+	return nil
+}
+
+func (cs *clientService) RotateAllKeys(ctx context.Context) error {
+	ids, err := cs.store.GetClientIDs(ctx)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.RotateAllKeys"), err)
+	}
+
+	for _, id := range ids {
+		//NOTE: A CLIENT WHOSE KEYS WERE ROTATED CONCURRENTLY IS ALREADY ROTATED, IT IS NOT ROTATED A SECOND TIME
+		if err := cs.RotateKeys(ctx, id); err != nil && !isConflict(err) {
+			return erx.WithArgs(erx.Operation("Service.RotateAllKeys"), err)
+		}
+	}
+
+	//NOTE: KEYS RETIRED BY AN EARLIER ROTATION ARE REMOVED ONCE THE LAST TOKEN THEY SIGNED HAS EXPIRED
+	if _, err := cs.store.DeleteExpiredKeys(ctx, time.Now().UTC()); err != nil {
+		return erx.WithArgs(erx.Operation("Service.RotateAllKeys"), err)
+	}
+
+	return nil
+}
+
+func (cs *clientService) newKey() (libcrypto.Key, error) {
+	_, priKey, err := cs.keyGenerator.Generate()
+	if err != nil {
+		return libcrypto.Key{}, err
+	}
+
+	return libcrypto.Key{ID: uuid.New().String(), PrivateKey: priKey}, nil
+}
+
+func isConflict(err error) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == erx.DuplicateRecordError
+}
+
 func NewService(cfg config.ClientConfig, store Store, keyGenerator libcrypto.Ed25519Generator) Service {
 	return &clientService{
 		keyGenerator: keyGenerator,
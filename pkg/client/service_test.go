@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/ed25519"
 	"errors"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
 	"identification-service/pkg/libcrypto"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"testing"
 )
 
@@ -41,6 +43,7 @@ func (cst *clientServiceTest) TestCreateNewClientSuccess() {
 		"CreateClient",
 		mock.AnythingOfType("*context.emptyCtx"),
 		mock.AnythingOfType("client.Client"),
+		mock.AnythingOfType("libcrypto.KeyRing"),
 	).Return(test.NewUUID(), nil)
 
 	mockClientConfig := &config.MockClientConfig{}
@@ -56,6 +59,17 @@ func (cst *clientServiceTest) TestCreateNewClientSuccess() {
 		test.RandInt(1440, 86701),
 		test.RandInt(1, 10),
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		"",
+		false,
+		false,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypeConfidential,
 	)
 
 	cst.Require().NoError(err)
@@ -76,6 +90,17 @@ func (cst *clientServiceTest) TestCreateNewClientFailureWhenClientValidationFail
 		test.RandInt(1440, 86701),
 		test.RandInt(1, 10),
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		"",
+		false,
+		false,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypeConfidential,
 	)
 
 	cst.Require().Error(err)
@@ -94,6 +119,17 @@ func (cst *clientServiceTest) TestCreateNewClientFailureWhenKeyGenerationFails()
 		test.RandInt(1440, 86701),
 		test.RandInt(1, 10),
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		"",
+		false,
+		false,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypeConfidential,
 	)
 
 	cst.Require().Error(err)
@@ -106,7 +142,7 @@ func (cst *clientServiceTest) TestCreateNewClientFailureWhenStoreReturnFailure()
 	mockKeyGenerator.On("Generate").Return(pub, pri, nil)
 
 	mockStore := &client.MockStore{}
-	mockStore.On("CreateClient", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("client.Client")).Return("", errors.New("failed to create client"))
+	mockStore.On("CreateClient", mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("client.Client"), mock.AnythingOfType("libcrypto.KeyRing")).Return("", errors.New("failed to create client"))
 
 	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
 
@@ -117,6 +153,17 @@ func (cst *clientServiceTest) TestCreateNewClientFailureWhenStoreReturnFailure()
 		test.RandInt(1440, 86701),
 		test.RandInt(1, 10),
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		"",
+		false,
+		false,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypeConfidential,
 	)
 
 	cst.Require().Error(err)
@@ -169,3 +216,132 @@ func (cst *clientServiceTest) TestGetClientFailure() {
 	_, err := svc.GetClient(context.Background(), clientName, clientSecret)
 	cst.Require().Error(err)
 }
+
+func (cst *clientServiceTest) TestRotateKeysSuccess() {
+	clientID := test.NewUUID()
+	pub, pri := test.GenerateKey()
+	activeKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+	pendingKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.PendingKey, PrivateKey: pri}
+
+	mockKeyGenerator := &libcrypto.MockEd25519Generator{}
+	mockKeyGenerator.On("Generate").Return(pub, pri, nil)
+
+	rotated := func(keyRing libcrypto.KeyRing) bool {
+		key, err := keyRing.SigningKey()
+		return err == nil && key.ID == pendingKey.ID && len(keyRing.Keys()) == 3
+	}
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.NewKeyRing(activeKey, pendingKey), nil)
+	mockStore.On("UpdateKeyRing", mock.Anything, clientID, mock.MatchedBy(rotated)).Return(nil)
+
+	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
+
+	err := svc.RotateKeys(context.Background(), clientID)
+	cst.Require().NoError(err)
+
+	mockStore.AssertExpectations(cst.T())
+}
+
+func (cst *clientServiceTest) TestRotateKeysFailureWhenGetKeyRingFails() {
+	clientID := test.NewUUID()
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.KeyRing{}, errors.New("failed to get key ring"))
+
+	svc := client.NewService(cst.cfg, mockStore, &libcrypto.MockEd25519Generator{})
+
+	err := svc.RotateKeys(context.Background(), clientID)
+	cst.Require().Error(err)
+}
+
+func (cst *clientServiceTest) TestRotateKeysFailureWhenKeyGenerationFails() {
+	clientID := test.NewUUID()
+	activeKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	mockKeyGenerator := &libcrypto.MockEd25519Generator{}
+	mockKeyGenerator.On("Generate").Return(ed25519.PublicKey{}, ed25519.PrivateKey{}, errors.New("failed to generate key"))
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.NewKeyRing(activeKey), nil)
+
+	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
+
+	err := svc.RotateKeys(context.Background(), clientID)
+	cst.Require().Error(err)
+}
+
+func (cst *clientServiceTest) TestRotateKeysFailureWhenUpdateKeyRingFails() {
+	clientID := test.NewUUID()
+	pub, pri := test.GenerateKey()
+	activeKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	mockKeyGenerator := &libcrypto.MockEd25519Generator{}
+	mockKeyGenerator.On("Generate").Return(pub, pri, nil)
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.NewKeyRing(activeKey), nil)
+	mockStore.On("UpdateKeyRing", mock.Anything, clientID, mock.AnythingOfType("libcrypto.KeyRing")).Return(errors.New("failed to update key ring"))
+
+	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
+
+	err := svc.RotateKeys(context.Background(), clientID)
+	cst.Require().Error(err)
+}
+
+func (cst *clientServiceTest) TestRotateAllKeysSuccess() {
+	clientID := test.NewUUID()
+	pub, pri := test.GenerateKey()
+	activeKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	mockKeyGenerator := &libcrypto.MockEd25519Generator{}
+	mockKeyGenerator.On("Generate").Return(pub, pri, nil)
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetClientIDs", mock.Anything).Return([]string{clientID}, nil)
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.NewKeyRing(activeKey), nil)
+	mockStore.On("UpdateKeyRing", mock.Anything, clientID, mock.AnythingOfType("libcrypto.KeyRing")).Return(nil)
+	mockStore.On("DeleteExpiredKeys", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(1), nil)
+
+	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
+
+	err := svc.RotateAllKeys(context.Background())
+	cst.Require().NoError(err)
+
+	mockStore.AssertExpectations(cst.T())
+}
+
+func (cst *clientServiceTest) TestRotateAllKeysSuccessWhenKeysWereRotatedConcurrently() {
+	rotatedID, clientID := test.NewUUID(), test.NewUUID()
+	pub, pri := test.GenerateKey()
+	activeKey := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	mockKeyGenerator := &libcrypto.MockEd25519Generator{}
+	mockKeyGenerator.On("Generate").Return(pub, pri, nil)
+
+	mockStore := &client.MockStore{}
+	mockStore.On("GetClientIDs", mock.Anything).Return([]string{rotatedID, clientID}, nil)
+	mockStore.On("GetKeyRing", mock.Anything, rotatedID).Return(libcrypto.NewKeyRing(activeKey), nil)
+	mockStore.On("GetKeyRing", mock.Anything, clientID).Return(libcrypto.NewKeyRing(activeKey), nil)
+	mockStore.On("UpdateKeyRing", mock.Anything, rotatedID, mock.AnythingOfType("libcrypto.KeyRing")).
+		Return(erx.WithArgs(erx.DuplicateRecordError, errors.New("key ring was changed by another rotation")))
+	mockStore.On("UpdateKeyRing", mock.Anything, clientID, mock.AnythingOfType("libcrypto.KeyRing")).Return(nil)
+	mockStore.On("DeleteExpiredKeys", mock.Anything, mock.AnythingOfType("time.Time")).Return(int64(0), nil)
+
+	svc := client.NewService(cst.cfg, mockStore, mockKeyGenerator)
+
+	err := svc.RotateAllKeys(context.Background())
+	cst.Require().NoError(err)
+
+	mockStore.AssertExpectations(cst.T())
+}
+
+func (cst *clientServiceTest) TestRotateAllKeysFailure() {
+	mockStore := &client.MockStore{}
+	mockStore.On("GetClientIDs", mock.Anything).Return([]string{}, errors.New("failed to get client ids"))
+
+	svc := client.NewService(cst.cfg, mockStore, &libcrypto.MockEd25519Generator{})
+
+	err := svc.RotateAllKeys(context.Background())
+	cst.Require().Error(err)
+}
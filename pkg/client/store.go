@@ -2,33 +2,74 @@ package client
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/lib/pq"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
 	"time"
 )
 
 const (
-	createClient = `insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key) values ($1, $2, $3, $4, $5, $6) returning secret`
-	revokeClient = `update clients set revoked=true where id=$1`
-	getClient    = `select id, revoked, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key from clients where name=$1 and secret=$2`
+	createClient = `with cl as (insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, rotate_refresh_tokens, redirect_uris, allowed_scopes, allowed_audiences, claim_mappings, tenant_id, require_verified_email, allow_magic_link_signup, webauthn_rp_id, webauthn_origins, client_type) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) returning id, secret),
+	ks as (insert into client_keys (id, client_id, private_key, public_key, state) select k.id, cl.id, k.private_key, k.public_key, k.state::key_state from cl, unnest($17::uuid[], $18::bytea[], $19::bytea[], $20::text[]) as k(id, private_key, public_key, state))
+	select secret from cl`
+	revokeClient    = `update clients set revoked=true where id=$1`
+	getClient       = `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1 and c.secret=$2`
+	getClientByName = `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1`
+
+	getClientIDs  = `select id from clients where revoked=false`
+	getKeyRing    = `select k.id, k.state, k.private_key, k.updated_at, c.key_version from client_keys k join clients c on c.id = k.client_id where k.client_id=$1 and k.state <> 'retired'`
+	updateKeyRing = `with cl as (update clients set key_version = key_version + 1 where id=$1 and key_version=$6 returning name),
+	ks as (insert into client_keys (id, client_id, private_key, public_key, state) select k.id, $1, k.private_key, k.public_key, k.state::key_state from cl, unnest($2::uuid[], $3::bytea[], $4::bytea[], $5::text[]) as k(id, private_key, public_key, state)
+	on conflict (id) do update set state = excluded.state, public_key = coalesce(client_keys.public_key, excluded.public_key), updated_at = (now() at time zone 'utc'))
+	select name from cl`
+	deleteExpiredKeys = `delete from client_keys k using clients c where c.id = k.client_id and k.state = 'retired' and k.updated_at + make_interval(mins => c.access_token_ttl) <= $1`
+
+	getVerificationKeys = `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id where c.revoked=false and (k.state <> 'retired' or k.updated_at + make_interval(mins => c.access_token_ttl) > $1)`
+	getVerificationKey  = `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id where c.revoked=false and (k.state <> 'retired' or k.updated_at + make_interval(mins => c.access_token_ttl) > $2) and k.id=$1`
+
+	getPrivateKeys    = `select id, private_key from client_keys`
+	updatePrivateKeys = `with ks as (update client_keys k set private_key=v.private_key from unnest($1::uuid[], $2::bytea[]) as v(id, private_key) where k.id=v.id returning k.client_id)
+	select distinct c.name from clients c join ks on ks.client_id = c.id`
 )
 
 type Store interface {
-	CreateClient(ctx context.Context, client Client) (string, error)
+	CreateClient(ctx context.Context, client Client, keyRing libcrypto.KeyRing) (string, error)
 	RevokeClient(ctx context.Context, id string) (int64, error)
 	GetClient(ctx context.Context, name, secret string) (Client, error)
+	GetClientByName(ctx context.Context, name string) (Client, error)
+	GetClientIDs(ctx context.Context) ([]string, error)
+	GetKeyRing(ctx context.Context, clientID string) (libcrypto.KeyRing, error)
+	UpdateKeyRing(ctx context.Context, clientID string, keyRing libcrypto.KeyRing) error
+	DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error)
+	GetVerificationKeys(ctx context.Context) ([]VerificationKey, error)
+	GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error)
+	EncryptLegacyKeys(ctx context.Context) (int, error)
 }
 
 type clientStore struct {
-	db    database.SQLDatabase
-	cache *redis.Client
+	db       database.SQLDatabase
+	cache    *redis.Client
+	envelope libcrypto.Envelope
 }
 
-func (cs *clientStore) CreateClient(ctx context.Context, client Client) (string, error) {
+func (cs *clientStore) CreateClient(ctx context.Context, client Client, keyRing libcrypto.KeyRing) (string, error) {
+	ids, privateKeys, publicKeys, states, err := keyRingArgs(cs.envelope, keyRing)
+	if err != nil {
+		return "", erx.WithArgs(erx.Operation("Store.CreateClient"), err)
+	}
+
+	claimMappings, err := claimMappingsArg(client.ClaimMappings)
+	if err != nil {
+		return "", erx.WithArgs(erx.Operation("Store.CreateClient"), err)
+	}
 
 	row := cs.db.QueryRowContext(
 		ctx,
@@ -38,7 +79,21 @@ func (cs *clientStore) CreateClient(ctx context.Context, client Client) (string,
 		client.internalClient.SessionTTL,
 		client.internalClient.MaxActiveSessions,
 		client.internalClient.SessionStrategyName,
-		client.PrivateKey,
+		client.internalClient.RotateRefreshTokens,
+		pq.Array(client.RedirectURIs),
+		pq.Array(client.AllowedScopes),
+		pq.Array(client.AllowedAudiences),
+		claimMappings,
+		client.TenantID,
+		client.internalClient.RequireVerifiedEmail,
+		client.internalClient.AllowMagicLinkSignUp,
+		client.WebAuthnRPID,
+		pq.Array(client.WebAuthnOrigins),
+		client.Type,
+		pq.Array(ids),
+		pq.Array(privateKeys),
+		pq.Array(publicKeys),
+		pq.Array(states),
 	)
 
 	//TODO: REMOVE THIS HARD CODING
@@ -47,6 +102,10 @@ func (cs *clientStore) CreateClient(ctx context.Context, client Client) (string,
 			if pgErr.Code == "23505" {
 				return "", erx.WithArgs(erx.Operation(""), erx.DuplicateRecordError, row.Err())
 			}
+
+			if pgErr.Code == "23503" {
+				return "", erx.WithArgs(erx.Operation("Store.CreateClient"), erx.ResourceNotFoundError, fmt.Errorf("no tenant found with id %s", client.TenantID))
+			}
 		}
 
 		return "", erx.WithArgs(erx.Operation("Store.CreateClient"), row.Err())
@@ -54,7 +113,7 @@ func (cs *clientStore) CreateClient(ctx context.Context, client Client) (string,
 
 	var secret string
 
-	err := row.Scan(&secret)
+	err = row.Scan(&secret)
 	if err != nil {
 		return "", erx.WithArgs(erx.Operation("Store.CreateClient"), err)
 	}
@@ -88,8 +147,8 @@ func (cs *clientStore) RevokeClient(ctx context.Context, id string) (int64, erro
 
 func (cs *clientStore) GetClient(ctx context.Context, name, secret string) (Client, error) {
 	//TODO: REFACTOR SECRET CHECK LOGIC
-	if cl, err := fetchFromCache(ctx, cs.cache, name); err == nil {
-		if cl.Secret != secret {
+	if cl, err := fetchFromCache(ctx, cs.cache, cs.envelope, name); err == nil && cl.isValid() {
+		if subtle.ConstantTimeCompare([]byte(cl.Secret), []byte(secret)) != 1 {
 			return Client{}, erx.WithArgs(
 				erx.Operation("Store.GetClient"),
 				erx.InvalidCredentialsError,
@@ -100,37 +159,409 @@ func (cs *clientStore) GetClient(ctx context.Context, name, secret string) (Clie
 		return cl, nil
 	}
 
-	row := cs.db.QueryRowContext(ctx, getClient, name, secret)
+	return cs.getClient(ctx, "Store.GetClient", getClient, name, secret)
+}
+
+func (cs *clientStore) GetClientByName(ctx context.Context, name string) (Client, error) {
+	if cl, err := fetchFromCache(ctx, cs.cache, cs.envelope, name); err == nil && cl.isValid() {
+		return cl, nil
+	}
+
+	return cs.getClient(ctx, "Store.GetClientByName", getClientByName, name)
+}
+
+func (cs *clientStore) getClient(ctx context.Context, op string, query string, args ...interface{}) (Client, error) {
+	row := cs.db.QueryRowContext(ctx, query, args...)
 	if row.Err() != nil {
-		return Client{}, erx.WithArgs(erx.Operation("Store.GetClient"), row.Err())
+		return Client{}, erx.WithArgs(erx.Operation(op), row.Err())
 	}
 
 	var client Client
+	var claimMappings, privateKey []byte
+
 	err := row.Scan(
 		&client.Id,
+		&client.TenantID,
+		&client.Name,
+		&client.Secret,
+		&client.Type,
 		&client.Revoked,
 		&client.internalClient.AccessTokenTTL,
 		&client.internalClient.SessionTTL,
 		&client.internalClient.MaxActiveSessions,
 		&client.internalClient.SessionStrategyName,
-		&client.PrivateKey,
+		&client.internalClient.RotateRefreshTokens,
+		&client.internalClient.RequireVerifiedEmail,
+		&client.internalClient.AllowMagicLinkSignUp,
+		&client.WebAuthnRPID,
+		pq.Array(&client.WebAuthnOrigins),
+		pq.Array(&client.RedirectURIs),
+		pq.Array(&client.AllowedScopes),
+		pq.Array(&client.AllowedAudiences),
+		&claimMappings,
+		&client.KeyID,
+		&privateKey,
 	)
 
 	if err != nil {
-		return client, erx.WithArgs(erx.Operation("Store.GetClient"), err)
+		return client, erx.WithArgs(erx.Operation(op), err)
+	}
+
+	if err := json.Unmarshal(claimMappings, &client.ClaimMappings); err != nil {
+		return Client{}, erx.WithArgs(erx.Operation(op), err)
 	}
 
-	//TODO: REFACTOR THIS
-	client.Name = name
-	client.Secret = secret
+	client.PrivateKey, err = openPrivateKey(cs.envelope, privateKey)
+	if err != nil {
+		return Client{}, erx.WithArgs(erx.Operation(op), err)
+	}
 
 	//TODO: HANDLE ERROR
-	go updateCache(ctx, cs.cache, client)
+	go updateCache(ctx, cs.cache, cs.envelope, client)
 
 	return client, nil
 }
 
-func updateCache(ctx context.Context, cache *redis.Client, cl Client) error {
+func (cs *clientStore) GetClientIDs(ctx context.Context) ([]string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetClientIDs"), err) }
+
+	rows, err := cs.db.QueryContext(ctx, getClientIDs)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrap(err)
+	}
+
+	return ids, nil
+}
+
+func (cs *clientStore) GetKeyRing(ctx context.Context, clientID string) (libcrypto.KeyRing, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetKeyRing"), err) }
+
+	rows, err := cs.db.QueryContext(ctx, getKeyRing, clientID)
+	if err != nil {
+		return libcrypto.KeyRing{}, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var keys []libcrypto.Key
+	var version int
+
+	for rows.Next() {
+		var key libcrypto.Key
+		var privateKey []byte
+
+		err := rows.Scan(&key.ID, &key.State, &privateKey, &key.UpdatedAt, &version)
+		if err != nil {
+			return libcrypto.KeyRing{}, wrap(err)
+		}
+
+		key.PrivateKey, err = openPrivateKey(cs.envelope, privateKey)
+		if err != nil {
+			return libcrypto.KeyRing{}, wrap(err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return libcrypto.KeyRing{}, wrap(err)
+	}
+
+	if len(keys) == 0 {
+		return libcrypto.KeyRing{}, erx.WithArgs(
+			erx.Operation("Store.GetKeyRing"),
+			erx.ResourceNotFoundError,
+			fmt.Errorf("no client found with id %s", clientID),
+		)
+	}
+
+	return libcrypto.NewKeyRing(keys...).WithVersion(version), nil
+}
+
+func (cs *clientStore) UpdateKeyRing(ctx context.Context, clientID string, keyRing libcrypto.KeyRing) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.UpdateKeyRing"), err) }
+
+	ids, privateKeys, publicKeys, states, err := keyRingArgs(cs.envelope, keyRing)
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE RING IS ONLY WRITTEN IF NO OTHER ROTATION CHANGED IT SINCE IT WAS READ, THE LOSER OF A RACE GETS A CONFLICT
+	row := cs.db.QueryRowContext(ctx, updateKeyRing, clientID, pq.Array(ids), pq.Array(privateKeys), pq.Array(publicKeys), pq.Array(states), keyRing.Version())
+	if row.Err() != nil {
+		return wrap(row.Err())
+	}
+
+	var name string
+
+	err = row.Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return wrap(erx.WithArgs(erx.DuplicateRecordError, fmt.Errorf("key ring of client %s was changed by another rotation", clientID)))
+		}
+
+		return wrap(err)
+	}
+
+	//NOTE: CACHED CLIENTS CARRY THEIR SIGNING KEY, EVICT IT SO THAT THE NEW ACTIVE KEY IS PICKED ON NEXT READ
+	_, err = cs.cache.Del(ctx, name).Result()
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (cs *clientStore) DeleteExpiredKeys(ctx context.Context, now time.Time) (int64, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.DeleteExpiredKeys"), err) }
+
+	res, err := cs.db.ExecContext(ctx, deleteExpiredKeys, now)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	return count, nil
+}
+
+func (cs *clientStore) GetVerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetVerificationKeys"), err) }
+
+	//NOTE: RETIRED KEYS ARE ONLY VALID UNTIL THE LAST TOKEN THEY SIGNED EXPIRES, EXPIRED ONES ARE LEFT OUT BY THE QUERY
+	rows, err := cs.db.QueryContext(ctx, getVerificationKeys, time.Now().UTC())
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var keys []VerificationKey
+
+	for rows.Next() {
+		key, err := scanVerificationKey(rows, cs.envelope)
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrap(err)
+	}
+
+	return keys, nil
+}
+
+func (cs *clientStore) GetVerificationKey(ctx context.Context, keyID string) (VerificationKey, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetVerificationKey"), err) }
+
+	row := cs.db.QueryRowContext(ctx, getVerificationKey, keyID, time.Now().UTC())
+	if row.Err() != nil {
+		return VerificationKey{}, wrap(row.Err())
+	}
+
+	key, err := scanVerificationKey(row, cs.envelope)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return VerificationKey{}, erx.WithArgs(
+				erx.Operation("Store.GetVerificationKey"),
+				erx.ResourceNotFoundError,
+				fmt.Errorf("no key found with id %s which is still valid for verification", keyID),
+			)
+		}
+
+		return VerificationKey{}, wrap(err)
+	}
+
+	return key, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVerificationKey(sc scanner, envelope libcrypto.Envelope) (VerificationKey, error) {
+	var key VerificationKey
+	var publicKey, privateKey []byte
+
+	err := sc.Scan(&key.KeyID, &key.ClientID, &key.State, &publicKey, &privateKey)
+	if err != nil {
+		return VerificationKey{}, err
+	}
+
+	//NOTE: KEYS CREATED BEFORE THE PUBLIC HALF WAS STORED ARE DERIVED FROM THE PRIVATE KEY UNTIL THEIR NEXT ROTATION
+	if len(publicKey) == 0 {
+		publicKey, err = derivePublicKey(envelope, privateKey)
+		if err != nil {
+			return VerificationKey{}, fmt.Errorf("invalid key %s for client %s: %w", key.KeyID, key.ClientID, err)
+		}
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return VerificationKey{}, fmt.Errorf("invalid public key %s for client %s", key.KeyID, key.ClientID)
+	}
+
+	key.PublicKey = publicKey
+
+	return key, nil
+}
+
+func derivePublicKey(envelope libcrypto.Envelope, privateKey []byte) ([]byte, error) {
+	privateKey, err := openPrivateKey(envelope, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("private key of length %d", len(privateKey))
+	}
+
+	return ed25519.PrivateKey(privateKey).Public().(ed25519.PublicKey), nil
+}
+
+func (cs *clientStore) EncryptLegacyKeys(ctx context.Context) (int, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.EncryptLegacyKeys"), err) }
+
+	rows, err := cs.db.QueryContext(ctx, getPrivateKeys)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var ids []string
+	var privateKeys [][]byte
+
+	for rows.Next() {
+		var id string
+		var privateKey []byte
+
+		err := rows.Scan(&id, &privateKey)
+		if err != nil {
+			return 0, wrap(err)
+		}
+
+		if libcrypto.IsSealed(privateKey) {
+			continue
+		}
+
+		sealed, err := cs.envelope.Seal(privateKey)
+		if err != nil {
+			return 0, wrap(err)
+		}
+
+		ids = append(ids, id)
+		privateKeys = append(privateKeys, sealed)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, wrap(err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	names, err := cs.db.QueryContext(ctx, updatePrivateKeys, pq.Array(ids), pq.Array(privateKeys))
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	defer names.Close()
+
+	//NOTE: CLIENTS CACHED BEFORE THE KEYS WERE ENCRYPTED HOLD THEM IN CLEARTEXT, EVICT THEM
+	for names.Next() {
+		var name string
+
+		if err := names.Scan(&name); err != nil {
+			return 0, wrap(err)
+		}
+
+		if _, err := cs.cache.Del(ctx, name).Result(); err != nil {
+			return 0, wrap(err)
+		}
+	}
+
+	if err := names.Err(); err != nil {
+		return 0, wrap(err)
+	}
+
+	return len(ids), nil
+}
+
+func claimMappingsArg(claimMappings map[string]string) (string, error) {
+	if claimMappings == nil {
+		claimMappings = map[string]string{}
+	}
+
+	//NOTE: THE MAPPINGS ARE SENT AS TEXT, A BYTE SLICE WOULD BE ENCODED AS BYTEA WHICH JSONB DOES NOT ACCEPT
+	b, err := json.Marshal(claimMappings)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func keyRingArgs(envelope libcrypto.Envelope, keyRing libcrypto.KeyRing) ([]string, [][]byte, [][]byte, []string, error) {
+	var ids, states []string
+	var privateKeys, publicKeys [][]byte
+
+	for _, key := range keyRing.Keys() {
+		sealed, err := envelope.Seal(key.PrivateKey)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		ids = append(ids, key.ID)
+		privateKeys = append(privateKeys, sealed)
+		publicKeys = append(publicKeys, key.PublicKey())
+		states = append(states, string(key.State))
+	}
+
+	return ids, privateKeys, publicKeys, states, nil
+}
+
+func openPrivateKey(envelope libcrypto.Envelope, privateKey []byte) ([]byte, error) {
+	//NOTE: KEYS WRITTEN BEFORE ENCRYPTION WAS INTRODUCED ARE READ AS IS UNTIL THEY ARE MIGRATED WITH encrypt-keys
+	if !libcrypto.IsSealed(privateKey) {
+		return privateKey, nil
+	}
+
+	return envelope.Open(privateKey)
+}
+
+func updateCache(ctx context.Context, cache *redis.Client, envelope libcrypto.Envelope, cl Client) error {
+	sealed, err := envelope.Seal(cl.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	cl.PrivateKey = sealed
+
 	s, err := encode(cl)
 	if err != nil {
 		return err
@@ -145,7 +576,7 @@ func updateCache(ctx context.Context, cache *redis.Client, cl Client) error {
 	return nil
 }
 
-func fetchFromCache(ctx context.Context, cache *redis.Client, name string) (Client, error) {
+func fetchFromCache(ctx context.Context, cache *redis.Client, envelope libcrypto.Envelope, name string) (Client, error) {
 	s, err := cache.Get(ctx, name).Result()
 	if err != nil {
 		return Client{}, err
@@ -156,12 +587,18 @@ func fetchFromCache(ctx context.Context, cache *redis.Client, name string) (Clie
 		return Client{}, err
 	}
 
-	return cl, err
+	cl.PrivateKey, err = envelope.Open(cl.PrivateKey)
+	if err != nil {
+		return Client{}, err
+	}
+
+	return cl, nil
 }
 
-func NewStore(db database.SQLDatabase, cache *redis.Client) Store {
+func NewStore(db database.SQLDatabase, cache *redis.Client, envelope libcrypto.Envelope) Store {
 	return &clientStore{
-		db:    db,
-		cache: cache,
+		db:       db,
+		cache:    cache,
+		envelope: envelope,
 	}
 }
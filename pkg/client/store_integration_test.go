@@ -8,6 +8,7 @@ import (
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
 	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
 	"identification-service/pkg/test"
 	"testing"
 )
@@ -27,7 +28,7 @@ func (cst *clientStoreIntegrationSuite) SetupSuite() {
 	cst.cfg = cfg.ClientConfig()
 	cst.db = test.NewDB(cst.T(), cfg)
 	cst.cache = test.NewCache(cst.T(), cfg)
-	cst.store = client.NewStore(cst.db, cst.cache)
+	cst.store = client.NewStore(cst.db, cst.cache, test.NewEnvelope(cst.T(), cfg))
 	cst.ctx = context.Background()
 	cst.defaultData = map[string]interface{}{}
 }
@@ -39,24 +40,24 @@ func (cst *clientStoreIntegrationSuite) TearDownSuite() {
 func (cst *clientStoreIntegrationSuite) TestCreateClientSuccess() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	_, err = cst.store.CreateClient(cst.ctx, cl)
+	_, err = cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 }
 
 func (cst *clientStoreIntegrationSuite) TestCreateClientFailureWhenRecordsAreDuplicate() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	_, err = cst.store.CreateClient(cst.ctx, cl)
+	_, err = cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
-	_, err = cst.store.CreateClient(cst.ctx, cl)
+	_, err = cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.Error(cst.T(), err)
 }
 
 func (cst *clientStoreIntegrationSuite) TestRevokeClientSuccess() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	secret, err := cst.store.CreateClient(cst.ctx, cl)
+	secret, err := cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
 	var id string
@@ -75,7 +76,7 @@ func (cst *clientStoreIntegrationSuite) TestRevokeClientFailure() {
 func (cst *clientStoreIntegrationSuite) TestGetClientSuccess() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	secret, err := cst.store.CreateClient(cst.ctx, cl)
+	secret, err := cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
 	_, err = cst.store.GetClient(cst.ctx, cl.Name, secret)
@@ -85,7 +86,7 @@ func (cst *clientStoreIntegrationSuite) TestGetClientSuccess() {
 func (cst *clientStoreIntegrationSuite) TestGetClientFromCacheSuccess() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	secret, err := cst.store.CreateClient(cst.ctx, cl)
+	secret, err := cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
 	_, err = cst.store.GetClient(cst.ctx, cl.Name, secret)
@@ -101,7 +102,7 @@ func (cst *clientStoreIntegrationSuite) TestGetClientFromCacheSuccess() {
 func (cst *clientStoreIntegrationSuite) TestGetClientFromCacheFailureWhenSecretIsInvalid() {
 	cl, err := test.NewClient(cst.cfg, cst.defaultData)
 
-	secret, err := cst.store.CreateClient(cst.ctx, cl)
+	secret, err := cst.store.CreateClient(cst.ctx, cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
 	_, err = cst.store.GetClient(cst.ctx, cl.Name, secret)
@@ -2,27 +2,35 @@ package client_test
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/go-redis/redis/v8"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
 	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type clientStoreSuite struct {
 	suite.Suite
-	db    database.SQLDatabase
-	rd    *miniredis.Miniredis
-	mock  sqlmock.Sqlmock
-	store client.Store
-	cfg   config.ClientConfig
+	db       database.SQLDatabase
+	rd       *miniredis.Miniredis
+	mock     sqlmock.Sqlmock
+	envelope libcrypto.Envelope
+	store    client.Store
+	cfg      config.ClientConfig
 }
 
 func (cst *clientStoreSuite) SetupSuite() {
@@ -40,16 +48,30 @@ func (cst *clientStoreSuite) SetupSuite() {
 	cst.mock = mock
 	cst.rd = rd
 	cst.cfg = mockClientConfig
-	cst.store = client.NewStore(cst.db, redis.NewClient(&redis.Options{Addr: rd.Addr()}))
+
+	kms, err := libcrypto.NewLocalKMS([]byte(test.RandString(32)))
+	cst.Require().NoError(err)
+
+	cst.envelope = libcrypto.NewEnvelope(kms)
+	cst.store = client.NewStore(cst.db, redis.NewClient(&redis.Options{Addr: rd.Addr()}), cst.envelope)
+}
+
+func (cst *clientStoreSuite) seal(priKey ed25519.PrivateKey) []byte {
+	sealed, err := cst.envelope.Seal(priKey)
+	cst.Require().NoError(err)
+
+	return sealed
 }
 
 func (cst *clientStoreSuite) TestCreateClientSuccess() {
 	accessTokenTTLVal := test.RandInt(1, 10)
 	sessionTTLVal := test.RandInt(1440, 86701)
 	maxActiveSessionsVal := test.RandInt(1, 10)
-	clientName, priKey := test.RandString(8), test.ClientPriKey()
+	clientName, priKey, keyID := test.RandString(8), test.ClientPriKey(), test.NewUUID()
 
-	query := `insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key) values ($1, $2, $3, $4, $5, $6) returning secret`
+	query := `with cl as (insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, rotate_refresh_tokens, redirect_uris, allowed_scopes, allowed_audiences, claim_mappings, tenant_id, require_verified_email, allow_magic_link_signup, webauthn_rp_id, webauthn_origins, client_type) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) returning id, secret),
+	ks as (insert into client_keys (id, client_id, private_key, public_key, state) select k.id, cl.id, k.private_key, k.public_key, k.state::key_state from cl, unnest($17::uuid[], $18::bytea[], $19::bytea[], $20::text[]) as k(id, private_key, public_key, state))
+	select secret from cl`
 
 	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs(
@@ -58,7 +80,21 @@ func (cst *clientStoreSuite) TestCreateClientSuccess() {
 			sessionTTLVal,
 			maxActiveSessionsVal,
 			test.ClientSessionStrategyRevokeOld,
-			priKey,
+			true,
+			pq.Array([]string{test.ClientRedirectURI}),
+			pq.Array([]string{test.ClientScope}),
+			pq.Array([]string{test.ClientAudience}),
+			`{"contact_email":"email"}`,
+			tenant.DefaultID,
+			false,
+			true,
+			test.WebAuthnRPID,
+			pq.Array([]string{test.WebAuthnOrigin}),
+			client.TypeConfidential,
+			pq.Array([]string{keyID}),
+			sqlmock.AnyArg(),
+			pq.Array([][]byte{priKey.Public().(ed25519.PublicKey)}),
+			pq.Array([]string{string(libcrypto.ActiveKey)}),
 		).WillReturnRows(sqlmock.NewRows([]string{"secret"}).AddRow(test.NewUUID()))
 
 	cl, err := client.NewClientBuilder(cst.cfg).
@@ -67,12 +103,21 @@ func (cst *clientStoreSuite) TestCreateClientSuccess() {
 		SessionTTL(sessionTTLVal).
 		MaxActiveSessions(maxActiveSessionsVal).
 		SessionStrategy(test.ClientSessionStrategyRevokeOld).
+		RotateRefreshTokens(true).
+		AllowMagicLinkSignUp(true).
+		WebAuthnRPID(test.WebAuthnRPID).
+		WebAuthnOrigins([]string{test.WebAuthnOrigin}).
+		RedirectURIs([]string{test.ClientRedirectURI}).
+		AllowedScopes([]string{test.ClientScope}).
+		AllowedAudiences([]string{test.ClientAudience}).
+		ClaimMappings(map[string]string{"contact_email": user.AttributeEmail}).
+		KeyID(keyID).
 		PrivateKey(priKey).
 		Build()
 
 	require.NoError(cst.T(), err)
 
-	_, err = cst.store.CreateClient(context.Background(), cl)
+	_, err = cst.store.CreateClient(context.Background(), cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.NoError(cst.T(), err)
 
 	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
@@ -83,9 +128,11 @@ func (cst *clientStoreSuite) TestCreateClientFailure() {
 	sessionTTLVal := test.RandInt(1440, 86701)
 	maxActiveSessionsVal := test.RandInt(1, 10)
 
-	clientName, priKey := test.RandString(8), test.ClientPriKey()
+	clientName, priKey, keyID := test.RandString(8), test.ClientPriKey(), test.NewUUID()
 
-	query := `insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key) values ($1, $2, $3, $4, $5, $6) returning secret`
+	query := `with cl as (insert into clients (name, access_token_ttl, session_ttl, max_active_sessions, session_strategy, rotate_refresh_tokens, redirect_uris, allowed_scopes, allowed_audiences, claim_mappings, tenant_id, require_verified_email, allow_magic_link_signup, webauthn_rp_id, webauthn_origins, client_type) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) returning id, secret),
+	ks as (insert into client_keys (id, client_id, private_key, public_key, state) select k.id, cl.id, k.private_key, k.public_key, k.state::key_state from cl, unnest($17::uuid[], $18::bytea[], $19::bytea[], $20::text[]) as k(id, private_key, public_key, state))
+	select secret from cl`
 
 	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs(
@@ -94,7 +141,21 @@ func (cst *clientStoreSuite) TestCreateClientFailure() {
 			sessionTTLVal,
 			maxActiveSessionsVal,
 			test.ClientSessionStrategyRevokeOld,
-			priKey,
+			true,
+			pq.Array([]string{test.ClientRedirectURI}),
+			pq.Array([]string{test.ClientScope}),
+			pq.Array([]string{test.ClientAudience}),
+			`{}`,
+			tenant.DefaultID,
+			false,
+			false,
+			"",
+			pq.Array([]string(nil)),
+			client.TypeConfidential,
+			pq.Array([]string{keyID}),
+			sqlmock.AnyArg(),
+			pq.Array([][]byte{priKey.Public().(ed25519.PublicKey)}),
+			pq.Array([]string{string(libcrypto.ActiveKey)}),
 		).WillReturnError(errors.New("failed to create client"))
 
 	cl, err := client.NewClientBuilder(cst.cfg).
@@ -103,12 +164,17 @@ func (cst *clientStoreSuite) TestCreateClientFailure() {
 		SessionTTL(sessionTTLVal).
 		MaxActiveSessions(maxActiveSessionsVal).
 		SessionStrategy(test.ClientSessionStrategyRevokeOld).
+		RotateRefreshTokens(true).
+		RedirectURIs([]string{test.ClientRedirectURI}).
+		AllowedScopes([]string{test.ClientScope}).
+		AllowedAudiences([]string{test.ClientAudience}).
+		KeyID(keyID).
 		PrivateKey(priKey).
 		Build()
 
 	require.NoError(cst.T(), err)
 
-	_, err = cst.store.CreateClient(context.Background(), cl)
+	_, err = cst.store.CreateClient(context.Background(), cl, libcrypto.NewKeyRing(cl.SigningKey()))
 	require.Error(cst.T(), err)
 
 	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
@@ -150,17 +216,31 @@ func (cst *clientStoreSuite) TestGetClientSuccess() {
 	maxActiveSessionsVal := test.RandInt(1, 10)
 	name, secret := test.RandString(8), test.NewUUID()
 
-	query := `select id, revoked, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key from clients where name=$1 and secret=$2`
+	query := `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1 and c.secret=$2`
 
 	rows := sqlmock.NewRows(
-		[]string{"id", "revoked", "access_token_ttl", "session_ttl", "max_active_sessions", "session_strategy", "private_key"},
+		[]string{"id", "tenant_id", "name", "secret", "client_type", "revoked", "access_token_ttl", "session_ttl", "max_active_sessions", "session_strategy", "rotate_refresh_tokens", "require_verified_email", "allow_magic_link_signup", "webauthn_rp_id", "webauthn_origins", "redirect_uris", "allowed_scopes", "allowed_audiences", "claim_mappings", "id", "private_key"},
 	).AddRow(
 		test.NewUUID(),
+		tenant.DefaultID,
+		name,
+		secret,
+		client.TypeConfidential,
 		false,
 		accessTokenTTLVal,
 		sessionTTLVal,
 		maxActiveSessionsVal,
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		false,
+		false,
+		test.WebAuthnRPID,
+		pq.Array([]string{test.WebAuthnOrigin}),
+		pq.Array([]string{test.ClientRedirectURI}),
+		pq.Array([]string{test.ClientScope}),
+		pq.Array([]string{test.ClientAudience}),
+		[]byte(`{}`),
+		test.NewUUID(),
 		test.ClientPriKey(),
 	)
 
@@ -177,7 +257,7 @@ func (cst *clientStoreSuite) TestGetClientSuccess() {
 func (cst *clientStoreSuite) TestGetClientFailure() {
 	name, secret := test.RandString(8), test.NewUUID()
 
-	query := `select id, revoked, access_token_ttl, session_ttl, max_active_sessions, session_strategy, private_key from clients where name=$1 and secret=$2`
+	query := `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1 and c.secret=$2`
 
 	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs(name, secret).
@@ -189,6 +269,406 @@ func (cst *clientStoreSuite) TestGetClientFailure() {
 	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
 }
 
+func (cst *clientStoreSuite) TestGetClientSuccessWithSealedKey() {
+	name, secret, priKey := test.RandString(8), test.NewUUID(), test.ClientPriKey()
+
+	query := `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1 and c.secret=$2`
+
+	rows := sqlmock.NewRows(
+		[]string{"id", "tenant_id", "name", "secret", "client_type", "revoked", "access_token_ttl", "session_ttl", "max_active_sessions", "session_strategy", "rotate_refresh_tokens", "require_verified_email", "allow_magic_link_signup", "webauthn_rp_id", "webauthn_origins", "redirect_uris", "allowed_scopes", "allowed_audiences", "claim_mappings", "id", "private_key"},
+	).AddRow(
+		test.NewUUID(),
+		tenant.DefaultID,
+		name,
+		secret,
+		client.TypeConfidential,
+		false,
+		test.RandInt(1, 10),
+		test.RandInt(1440, 86701),
+		test.RandInt(1, 10),
+		test.ClientSessionStrategyRevokeOld,
+		false,
+		false,
+		false,
+		test.WebAuthnRPID,
+		pq.Array([]string{test.WebAuthnOrigin}),
+		pq.Array([]string{test.ClientRedirectURI}),
+		pq.Array([]string{test.ClientScope}),
+		pq.Array([]string{test.ClientAudience}),
+		[]byte(`{}`),
+		test.NewUUID(),
+		cst.seal(priKey),
+	)
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(name, secret).
+		WillReturnRows(rows)
+
+	cl, err := cst.store.GetClient(context.Background(), name, secret)
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal(priKey, cl.SigningKey().PrivateKey)
+	cst.Assert().True(cl.IsRedirectURIRegistered(test.ClientRedirectURI))
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetClientByNameSuccess() {
+	name, secret := test.RandString(8), test.NewUUID()
+
+	query := `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1`
+
+	rows := sqlmock.NewRows(
+		[]string{"id", "tenant_id", "name", "secret", "client_type", "revoked", "access_token_ttl", "session_ttl", "max_active_sessions", "session_strategy", "rotate_refresh_tokens", "require_verified_email", "allow_magic_link_signup", "webauthn_rp_id", "webauthn_origins", "redirect_uris", "allowed_scopes", "allowed_audiences", "claim_mappings", "id", "private_key"},
+	).AddRow(
+		test.NewUUID(),
+		tenant.DefaultID,
+		name,
+		secret,
+		client.TypeConfidential,
+		false,
+		test.RandInt(1, 10),
+		test.RandInt(1440, 86701),
+		test.RandInt(1, 10),
+		test.ClientSessionStrategyRevokeOld,
+		false,
+		false,
+		false,
+		test.WebAuthnRPID,
+		pq.Array([]string{test.WebAuthnOrigin}),
+		pq.Array([]string{test.ClientRedirectURI}),
+		pq.Array([]string{test.ClientScope}),
+		pq.Array([]string{test.ClientAudience}),
+		[]byte(`{"contact_email":"email"}`),
+		test.NewUUID(),
+		cst.seal(test.ClientPriKey()),
+	)
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(name).WillReturnRows(rows)
+
+	cl, err := cst.store.GetClientByName(context.Background(), name)
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal(secret, cl.Secret)
+	cst.Assert().True(cl.IsRedirectURIRegistered(test.ClientRedirectURI))
+	cst.Assert().Equal(map[string]string{"contact_email": user.AttributeEmail}, cl.ClaimMappings)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetClientByNameFailure() {
+	name := test.RandString(8)
+
+	query := `select c.id, c.tenant_id, c.name, c.secret, c.client_type, c.revoked, c.access_token_ttl, c.session_ttl, c.max_active_sessions, c.session_strategy, c.rotate_refresh_tokens, c.require_verified_email, c.allow_magic_link_signup, c.webauthn_rp_id, c.webauthn_origins, c.redirect_uris, c.allowed_scopes, c.allowed_audiences, c.claim_mappings, k.id, k.private_key from clients c join client_keys k on k.client_id = c.id and k.state = 'active' where c.name=$1`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(name).WillReturnError(errors.New("failed to get client"))
+
+	_, err := cst.store.GetClientByName(context.Background(), name)
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetClientIDsSuccess() {
+	clientID := test.NewUUID()
+
+	query := `select id from clients where revoked=false`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(clientID))
+
+	ids, err := cst.store.GetClientIDs(context.Background())
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal([]string{clientID}, ids)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetClientIDsFailure() {
+	query := `select id from clients where revoked=false`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnError(errors.New("failed to get client ids"))
+
+	_, err := cst.store.GetClientIDs(context.Background())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetClientIDsFailureWhenRowsFail() {
+	query := `select id from clients where revoked=false`
+
+	rows := sqlmock.NewRows([]string{"id"}).
+		AddRow(test.NewUUID()).
+		AddRow(test.NewUUID()).
+		RowError(1, errors.New("failed to read row"))
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnRows(rows).RowsWillBeClosed()
+
+	_, err := cst.store.GetClientIDs(context.Background())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetKeyRingSuccess() {
+	clientID, keyID, priKey := test.NewUUID(), test.NewUUID(), test.ClientPriKey()
+
+	query := `select k.id, k.state, k.private_key, k.updated_at, c.key_version from client_keys k join clients c on c.id = k.client_id where k.client_id=$1 and k.state <> 'retired'`
+
+	rows := sqlmock.NewRows([]string{"id", "state", "private_key", "updated_at", "key_version"}).
+		AddRow(keyID, string(libcrypto.ActiveKey), cst.seal(priKey), test.UpdatedAt, 3)
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(clientID).WillReturnRows(rows)
+
+	keyRing, err := cst.store.GetKeyRing(context.Background(), clientID)
+	require.NoError(cst.T(), err)
+
+	key, err := keyRing.SigningKey()
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal(keyID, key.ID)
+	cst.Assert().Equal(priKey, key.PrivateKey)
+	cst.Assert().Equal(3, keyRing.Version())
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetKeyRingFailureWhenClientIsNotFound() {
+	clientID := test.NewUUID()
+
+	query := `select k.id, k.state, k.private_key, k.updated_at, c.key_version from client_keys k join clients c on c.id = k.client_id where k.client_id=$1 and k.state <> 'retired'`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(clientID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "state", "private_key", "updated_at", "key_version"}))
+
+	_, err := cst.store.GetKeyRing(context.Background(), clientID)
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestUpdateKeyRingSuccess() {
+	clientID, name, priKey := test.NewUUID(), test.RandString(8), test.ClientPriKey()
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	cst.rd.Set(name, "cached client")
+
+	query := `with cl as (update clients set key_version = key_version + 1 where id=$1 and key_version=$6 returning name),
+	ks as (insert into client_keys (id, client_id, private_key, public_key, state) select k.id, $1, k.private_key, k.public_key, k.state::key_state from cl, unnest($2::uuid[], $3::bytea[], $4::bytea[], $5::text[]) as k(id, private_key, public_key, state)
+	on conflict (id) do update set state = excluded.state, public_key = coalesce(client_keys.public_key, excluded.public_key), updated_at = (now() at time zone 'utc'))
+	select name from cl`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(
+			clientID,
+			pq.Array([]string{key.ID}),
+			sqlmock.AnyArg(),
+			pq.Array([][]byte{key.PublicKey()}),
+			pq.Array([]string{string(libcrypto.ActiveKey)}),
+			2,
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(name))
+
+	err := cst.store.UpdateKeyRing(context.Background(), clientID, libcrypto.NewKeyRing(key).WithVersion(2))
+	require.NoError(cst.T(), err)
+
+	cst.Assert().False(cst.rd.Exists(name))
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestUpdateKeyRingFailureWhenRingWasChangedConcurrently() {
+	clientID, name := test.NewUUID(), test.RandString(8)
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	cst.rd.Set(name, "cached client")
+
+	query := `with cl as (update clients set key_version = key_version + 1 where id=$1 and key_version=$6 returning name)`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(clientID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+	err := cst.store.UpdateKeyRing(context.Background(), clientID, libcrypto.NewKeyRing(key).WithVersion(1))
+	require.Error(cst.T(), err)
+
+	cst.Assert().Equal(erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	cst.Assert().True(cst.rd.Exists(name))
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestUpdateKeyRingFailure() {
+	clientID := test.NewUUID()
+
+	query := `with cl as (update clients set key_version`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnError(errors.New("failed to update key ring"))
+
+	err := cst.store.UpdateKeyRing(context.Background(), clientID, libcrypto.NewKeyRing())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestDeleteExpiredKeysSuccess() {
+	now := time.Now().UTC()
+
+	query := `delete from client_keys k using clients c where c.id = k.client_id and k.state = 'retired' and k.updated_at + make_interval(mins => c.access_token_ttl) <= $1`
+
+	cst.mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(now).WillReturnResult(sqlmock.NewResult(0, 2))
+
+	count, err := cst.store.DeleteExpiredKeys(context.Background(), now)
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal(int64(2), count)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestDeleteExpiredKeysFailure() {
+	cst.mock.ExpectExec(regexp.QuoteMeta(`delete from client_keys`)).WillReturnError(errors.New("failed to delete keys"))
+
+	_, err := cst.store.DeleteExpiredKeys(context.Background(), time.Now().UTC())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetVerificationKeysSuccess() {
+	clientID, priKey, legacyPriKey := test.NewUUID(), test.ClientPriKey(), test.ClientPriKey()
+	activeKeyID, retiredKeyID, legacyKeyID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	pub := priKey.Public().(ed25519.PublicKey)
+
+	query := `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id where c.revoked=false and (k.state <> 'retired' or k.updated_at + make_interval(mins => c.access_token_ttl) > $1)`
+
+	rows := sqlmock.NewRows([]string{"id", "client_id", "state", "public_key", "private_key"}).
+		AddRow(activeKeyID, clientID, string(libcrypto.ActiveKey), []byte(pub), nil).
+		AddRow(retiredKeyID, clientID, string(libcrypto.RetiredKey), []byte(pub), nil).
+		AddRow(legacyKeyID, clientID, string(libcrypto.PendingKey), nil, cst.seal(legacyPriKey))
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+
+	keys, err := cst.store.GetVerificationKeys(context.Background())
+	require.NoError(cst.T(), err)
+
+	expected := []client.VerificationKey{
+		{KeyID: activeKeyID, ClientID: clientID, State: libcrypto.ActiveKey, PublicKey: pub},
+		{KeyID: retiredKeyID, ClientID: clientID, State: libcrypto.RetiredKey, PublicKey: pub},
+		{KeyID: legacyKeyID, ClientID: clientID, State: libcrypto.PendingKey, PublicKey: legacyPriKey.Public().(ed25519.PublicKey)},
+	}
+
+	cst.Assert().Equal(expected, keys)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetVerificationKeysFailure() {
+	query := `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WillReturnError(errors.New("failed to get keys"))
+
+	_, err := cst.store.GetVerificationKeys(context.Background())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetVerificationKeySuccess() {
+	clientID, keyID, priKey := test.NewUUID(), test.NewUUID(), test.ClientPriKey()
+
+	query := `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id where c.revoked=false and (k.state <> 'retired' or k.updated_at + make_interval(mins => c.access_token_ttl) > $2) and k.id=$1`
+
+	rows := sqlmock.NewRows([]string{"id", "client_id", "state", "public_key", "private_key"}).
+		AddRow(keyID, clientID, string(libcrypto.ActiveKey), []byte(priKey.Public().(ed25519.PublicKey)), nil)
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(keyID, sqlmock.AnyArg()).WillReturnRows(rows)
+
+	key, err := cst.store.GetVerificationKey(context.Background(), keyID)
+	require.NoError(cst.T(), err)
+
+	expected := client.VerificationKey{
+		KeyID:     keyID,
+		ClientID:  clientID,
+		State:     libcrypto.ActiveKey,
+		PublicKey: priKey.Public().(ed25519.PublicKey),
+	}
+
+	cst.Assert().Equal(expected, key)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestGetVerificationKeyFailure() {
+	keyID := test.NewUUID()
+
+	query := `select k.id, k.client_id, k.state, k.public_key, case when k.public_key is null then k.private_key end from client_keys k join clients c on c.id = k.client_id`
+
+	testCases := map[string]*sqlmock.Rows{
+		"test failure when key is not found or has expired": sqlmock.NewRows(
+			[]string{"id", "client_id", "state", "public_key", "private_key"},
+		),
+		"test failure when public key is invalid": sqlmock.NewRows(
+			[]string{"id", "client_id", "state", "public_key", "private_key"},
+		).AddRow(keyID, test.NewUUID(), string(libcrypto.ActiveKey), []byte("short"), nil),
+	}
+
+	for name, rows := range testCases {
+		cst.Run(name, func() {
+			cst.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(keyID, sqlmock.AnyArg()).WillReturnRows(rows)
+
+			_, err := cst.store.GetVerificationKey(context.Background(), keyID)
+			require.Error(cst.T(), err)
+
+			require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (cst *clientStoreSuite) TestEncryptLegacyKeysSuccess() {
+	sealedKeyID, legacyKeyID, name := test.NewUUID(), test.NewUUID(), test.RandString(8)
+
+	cst.rd.Set(name, "cached client")
+
+	rows := sqlmock.NewRows([]string{"id", "private_key"}).
+		AddRow(sealedKeyID, cst.seal(test.ClientPriKey())).
+		AddRow(legacyKeyID, []byte(test.ClientPriKey()))
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(`select id, private_key from client_keys`)).WillReturnRows(rows)
+
+	query := `with ks as (update client_keys k set private_key=v.private_key from unnest($1::uuid[], $2::bytea[]) as v(id, private_key) where k.id=v.id returning k.client_id)
+	select distinct c.name from clients c join ks on ks.client_id = c.id`
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(pq.Array([]string{legacyKeyID}), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(name))
+
+	count, err := cst.store.EncryptLegacyKeys(context.Background())
+	require.NoError(cst.T(), err)
+
+	cst.Assert().Equal(1, count)
+	cst.Assert().False(cst.rd.Exists(name))
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
+func (cst *clientStoreSuite) TestEncryptLegacyKeysFailure() {
+	rows := sqlmock.NewRows([]string{"id", "private_key"}).AddRow(test.NewUUID(), []byte(test.ClientPriKey()))
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(`select id, private_key from client_keys`)).WillReturnRows(rows)
+
+	cst.mock.ExpectQuery(regexp.QuoteMeta(`with ks as (update client_keys`)).
+		WillReturnError(errors.New("failed to update keys"))
+
+	_, err := cst.store.EncryptLegacyKeys(context.Background())
+	require.Error(cst.T(), err)
+
+	require.NoError(cst.T(), cst.mock.ExpectationsWereMet())
+}
+
 func TestStore(t *testing.T) {
 	suite.Run(t, new(clientStoreSuite))
 }
@@ -18,6 +18,11 @@ type Config interface {
 	CacheConfig() CacheConfig
 	ClientConfig() ClientConfig
 	QueueConfig() QueueConfig
+	KMSConfig() KMSConfig
+	OAuthConfig() OAuthConfig
+	UserConfig() UserConfig
+	MailerConfig() MailerConfig
+	MFAConfig() MFAConfig
 }
 
 type appConfig struct {
@@ -33,6 +38,11 @@ type appConfig struct {
 	authConfig       AuthConfig
 	clientConfig     ClientConfig
 	ampqConfig       QueueConfig
+	kmsConfig        KMSConfig
+	oauthConfig      OAuthConfig
+	userConfig       UserConfig
+	mailerConfig     MailerConfig
+	mfaConfig        MFAConfig
 }
 
 func (c appConfig) HTTPServerConfig() HTTPServerConfig {
@@ -83,6 +93,26 @@ func (c appConfig) QueueConfig() QueueConfig {
 	return c.ampqConfig
 }
 
+func (c appConfig) KMSConfig() KMSConfig {
+	return c.kmsConfig
+}
+
+func (c appConfig) OAuthConfig() OAuthConfig {
+	return c.oauthConfig
+}
+
+func (c appConfig) UserConfig() UserConfig {
+	return c.userConfig
+}
+
+func (c appConfig) MailerConfig() MailerConfig {
+	return c.mailerConfig
+}
+
+func (c appConfig) MFAConfig() MFAConfig {
+	return c.mfaConfig
+}
+
 //TODO: FIGURE OUT OF WAY TO KEEP ONE CONFIG FILE FOR LOCAL AND DOCKER
 func NewConfig(configFile string) Config {
 	viper.AutomaticEnv()
@@ -106,5 +136,10 @@ func NewConfig(configFile string) Config {
 		cacheConfig:      newCacheConfig(),
 		clientConfig:     newClientConfig(),
 		ampqConfig:       newQueueConfig(),
+		kmsConfig:        newKMSConfig(),
+		oauthConfig:      newOAuthConfig(),
+		userConfig:       newUserConfig(),
+		mailerConfig:     newMailerConfig(),
+		mfaConfig:        newMFAConfig(),
 	}
 }
@@ -0,0 +1,54 @@
+package config
+
+import "github.com/stretchr/testify/mock"
+
+type KMSConfig interface {
+	Provider() string
+	MasterKey() string
+	MasterKeyFile() string
+}
+
+type appKMSConfig struct {
+	provider      string
+	masterKey     string
+	masterKeyFile string
+}
+
+func newKMSConfig() KMSConfig {
+	return appKMSConfig{
+		provider:      getString("KMS_PROVIDER", "local"),
+		masterKey:     getString("KMS_MASTER_KEY"),
+		masterKeyFile: getString("KMS_MASTER_KEY_FILE"),
+	}
+}
+
+func (kc appKMSConfig) Provider() string {
+	return kc.provider
+}
+
+func (kc appKMSConfig) MasterKey() string {
+	return kc.masterKey
+}
+
+func (kc appKMSConfig) MasterKeyFile() string {
+	return kc.masterKeyFile
+}
+
+type MockKMSConfig struct {
+	mock.Mock
+}
+
+func (mock *MockKMSConfig) Provider() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockKMSConfig) MasterKey() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockKMSConfig) MasterKeyFile() string {
+	args := mock.Called()
+	return args.String(0)
+}
@@ -0,0 +1,114 @@
+package config
+
+import "github.com/stretchr/testify/mock"
+
+type MailerConfig interface {
+	Provider() string
+	From() string
+	SMTPHost() string
+	SMTPPort() string
+	SMTPUsername() string
+	SMTPPassword() string
+	FilePath() string
+	TemplateDir() string
+}
+
+type appMailerConfig struct {
+	provider     string
+	from         string
+	smtpHost     string
+	smtpPort     string
+	smtpUsername string
+	smtpPassword string
+	filePath     string
+	templateDir  string
+}
+
+func newMailerConfig() MailerConfig {
+	return appMailerConfig{
+		provider:     getString("MAILER_PROVIDER", "none"),
+		from:         getString("MAILER_FROM"),
+		smtpHost:     getString("MAILER_SMTP_HOST"),
+		smtpPort:     getString("MAILER_SMTP_PORT", "587"),
+		smtpUsername: getString("MAILER_SMTP_USERNAME"),
+		smtpPassword: getString("MAILER_SMTP_PASSWORD"),
+		filePath:     getString("MAILER_FILE_PATH"),
+		templateDir:  getString("MAILER_TEMPLATE_DIR"),
+	}
+}
+
+func (mc appMailerConfig) Provider() string {
+	return mc.provider
+}
+
+func (mc appMailerConfig) From() string {
+	return mc.from
+}
+
+func (mc appMailerConfig) SMTPHost() string {
+	return mc.smtpHost
+}
+
+func (mc appMailerConfig) SMTPPort() string {
+	return mc.smtpPort
+}
+
+func (mc appMailerConfig) SMTPUsername() string {
+	return mc.smtpUsername
+}
+
+func (mc appMailerConfig) SMTPPassword() string {
+	return mc.smtpPassword
+}
+
+func (mc appMailerConfig) FilePath() string {
+	return mc.filePath
+}
+
+func (mc appMailerConfig) TemplateDir() string {
+	return mc.templateDir
+}
+
+type MockMailerConfig struct {
+	mock.Mock
+}
+
+func (mock *MockMailerConfig) Provider() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) From() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) SMTPHost() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) SMTPPort() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) SMTPUsername() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) SMTPPassword() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) FilePath() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMailerConfig) TemplateDir() string {
+	args := mock.Called()
+	return args.String(0)
+}
@@ -0,0 +1,54 @@
+package config
+
+import "github.com/stretchr/testify/mock"
+
+type MFAConfig interface {
+	Issuer() string
+	ChallengeTTL() int
+	ChallengeMaxAttempts() int
+}
+
+type appMFAConfig struct {
+	issuer               string
+	challengeTTL         int
+	challengeMaxAttempts int
+}
+
+func newMFAConfig() MFAConfig {
+	return appMFAConfig{
+		issuer:               getString("MFA_ISSUER", "identification-service"),
+		challengeTTL:         getInt("MFA_CHALLENGE_TTL", 300),
+		challengeMaxAttempts: getInt("MFA_CHALLENGE_MAX_ATTEMPTS", 5),
+	}
+}
+
+func (mc appMFAConfig) Issuer() string {
+	return mc.issuer
+}
+
+func (mc appMFAConfig) ChallengeTTL() int {
+	return mc.challengeTTL
+}
+
+func (mc appMFAConfig) ChallengeMaxAttempts() int {
+	return mc.challengeMaxAttempts
+}
+
+type MockMFAConfig struct {
+	mock.Mock
+}
+
+func (mock *MockMFAConfig) Issuer() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockMFAConfig) ChallengeTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
+
+func (mock *MockMFAConfig) ChallengeMaxAttempts() int {
+	args := mock.Called()
+	return args.Int(0)
+}
@@ -65,3 +65,28 @@ func (mock *MockConfig) QueueConfig() QueueConfig {
 	args := mock.Called()
 	return args.Get(0).(QueueConfig)
 }
+
+func (mock *MockConfig) KMSConfig() KMSConfig {
+	args := mock.Called()
+	return args.Get(0).(KMSConfig)
+}
+
+func (mock *MockConfig) OAuthConfig() OAuthConfig {
+	args := mock.Called()
+	return args.Get(0).(OAuthConfig)
+}
+
+func (mock *MockConfig) UserConfig() UserConfig {
+	args := mock.Called()
+	return args.Get(0).(UserConfig)
+}
+
+func (mock *MockConfig) MailerConfig() MailerConfig {
+	args := mock.Called()
+	return args.Get(0).(MailerConfig)
+}
+
+func (mock *MockConfig) MFAConfig() MFAConfig {
+	args := mock.Called()
+	return args.Get(0).(MFAConfig)
+}
@@ -0,0 +1,66 @@
+package config
+
+import "github.com/stretchr/testify/mock"
+
+type OAuthConfig interface {
+	AuthorizationCodeTTL() int
+	IssuerURL() string
+	DeviceCodeTTL() int
+	DevicePollInterval() int
+}
+
+type appOAuthConfig struct {
+	authorizationCodeTTL int
+	issuerURL            string
+	deviceCodeTTL        int
+	devicePollInterval   int
+}
+
+func newOAuthConfig() OAuthConfig {
+	return appOAuthConfig{
+		authorizationCodeTTL: getInt("OAUTH_AUTHORIZATION_CODE_TTL", 60),
+		issuerURL:            getString("OAUTH_ISSUER_URL"),
+		deviceCodeTTL:        getInt("OAUTH_DEVICE_CODE_TTL", 600),
+		devicePollInterval:   getInt("OAUTH_DEVICE_POLL_INTERVAL", 5),
+	}
+}
+
+func (oc appOAuthConfig) AuthorizationCodeTTL() int {
+	return oc.authorizationCodeTTL
+}
+
+func (oc appOAuthConfig) IssuerURL() string {
+	return oc.issuerURL
+}
+
+func (oc appOAuthConfig) DeviceCodeTTL() int {
+	return oc.deviceCodeTTL
+}
+
+func (oc appOAuthConfig) DevicePollInterval() int {
+	return oc.devicePollInterval
+}
+
+type MockOAuthConfig struct {
+	mock.Mock
+}
+
+func (mock *MockOAuthConfig) AuthorizationCodeTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
+
+func (mock *MockOAuthConfig) IssuerURL() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockOAuthConfig) DeviceCodeTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
+
+func (mock *MockOAuthConfig) DevicePollInterval() int {
+	args := mock.Called()
+	return args.Int(0)
+}
@@ -8,28 +8,40 @@ import (
 type QueueConfig interface {
 	SignUpQueueName() string
 	UpdatePasswordQueueName() string
+	RefreshTokenReuseQueueName() string
+	EmailVerificationQueueName() string
+	PasswordResetQueueName() string
+	MagicLinkQueueName() string
 	Address() string
 }
 
 type appQueueConfig struct {
-	host                    string
-	port                    string
-	user                    string
-	password                string
-	vhost                   string
-	signUpQueueName         string
-	updatePasswordQueueName string
+	host                       string
+	port                       string
+	user                       string
+	password                   string
+	vhost                      string
+	signUpQueueName            string
+	updatePasswordQueueName    string
+	refreshTokenReuseQueueName string
+	emailVerificationQueueName string
+	passwordResetQueueName     string
+	magicLinkQueueName         string
 }
 
 func newQueueConfig() QueueConfig {
 	return appQueueConfig{
-		host:                    getString("AMPQ_HOST"),
-		port:                    getString("AMPQ_PORT"),
-		user:                    getString("AMPQ_USER"),
-		password:                getString("AMPQ_PASSWORD"),
-		vhost:                   getString("AMPQ_VHOST"),
-		signUpQueueName:         getString("SIGNUP_EVENT_QUEUE_NAME"),
-		updatePasswordQueueName: getString("UPDATE_PASSWORD_EVENT_QUEUE_NAME"),
+		host:                       getString("AMPQ_HOST"),
+		port:                       getString("AMPQ_PORT"),
+		user:                       getString("AMPQ_USER"),
+		password:                   getString("AMPQ_PASSWORD"),
+		vhost:                      getString("AMPQ_VHOST"),
+		signUpQueueName:            getString("SIGNUP_EVENT_QUEUE_NAME"),
+		updatePasswordQueueName:    getString("UPDATE_PASSWORD_EVENT_QUEUE_NAME"),
+		refreshTokenReuseQueueName: getString("REFRESH_TOKEN_REUSE_EVENT_QUEUE_NAME"),
+		emailVerificationQueueName: getString("EMAIL_VERIFICATION_EVENT_QUEUE_NAME"),
+		passwordResetQueueName:     getString("PASSWORD_RESET_EVENT_QUEUE_NAME"),
+		magicLinkQueueName:         getString("MAGIC_LINK_EVENT_QUEUE_NAME"),
 	}
 }
 
@@ -41,6 +53,22 @@ func (qc appQueueConfig) UpdatePasswordQueueName() string {
 	return qc.updatePasswordQueueName
 }
 
+func (qc appQueueConfig) RefreshTokenReuseQueueName() string {
+	return qc.refreshTokenReuseQueueName
+}
+
+func (qc appQueueConfig) EmailVerificationQueueName() string {
+	return qc.emailVerificationQueueName
+}
+
+func (qc appQueueConfig) PasswordResetQueueName() string {
+	return qc.passwordResetQueueName
+}
+
+func (qc appQueueConfig) MagicLinkQueueName() string {
+	return qc.magicLinkQueueName
+}
+
 func (qc appQueueConfig) Address() string {
 	return fmt.Sprintf("amqp://%s:%s@%s:%s/%s", qc.user, qc.password, qc.host, qc.port, qc.vhost)
 }
@@ -59,6 +87,26 @@ func (mock *MockQueueConfig) UpdatePasswordQueueName() string {
 	return args.String(0)
 }
 
+func (mock *MockQueueConfig) RefreshTokenReuseQueueName() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockQueueConfig) EmailVerificationQueueName() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockQueueConfig) PasswordResetQueueName() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockQueueConfig) MagicLinkQueueName() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
 func (mock *MockQueueConfig) Address() string {
 	args := mock.Called()
 	return args.String(0)
@@ -5,20 +5,23 @@ import "github.com/stretchr/testify/mock"
 type TokenConfig interface {
 	Audience() string
 	Issuer() string
-	EncodedSigningKey() string
+	RefreshTokenSecret() string
+	SignedTokenSecret() string
 }
 
 type appTokenConfig struct {
-	audience          string
-	issuer            string
-	encodedSigningKey string
+	audience           string
+	issuer             string
+	refreshTokenSecret string
+	signedTokenSecret  string
 }
 
 func newTokenConfig() TokenConfig {
 	return appTokenConfig{
-		audience:          getString("TOKEN_AUDIENCE"),
-		issuer:            getString("TOKEN_ISSUER"),
-		encodedSigningKey: getString("ENCODED_TOKEN_SIGNING_KEY"),
+		audience:           getString("TOKEN_AUDIENCE"),
+		issuer:             getString("TOKEN_ISSUER"),
+		refreshTokenSecret: getString("REFRESH_TOKEN_SECRET"),
+		signedTokenSecret:  getString("SIGNED_TOKEN_SECRET"),
 	}
 }
 
@@ -30,8 +33,12 @@ func (tc appTokenConfig) Issuer() string {
 	return tc.issuer
 }
 
-func (tc appTokenConfig) EncodedSigningKey() string {
-	return tc.encodedSigningKey
+func (tc appTokenConfig) RefreshTokenSecret() string {
+	return tc.refreshTokenSecret
+}
+
+func (tc appTokenConfig) SignedTokenSecret() string {
+	return tc.signedTokenSecret
 }
 
 type MockTokenConfig struct {
@@ -48,7 +55,12 @@ func (mock *MockTokenConfig) Issuer() string {
 	return args.String(0)
 }
 
-func (mock *MockTokenConfig) EncodedSigningKey() string {
+func (mock *MockTokenConfig) RefreshTokenSecret() string {
+	args := mock.Called()
+	return args.String(0)
+}
+
+func (mock *MockTokenConfig) SignedTokenSecret() string {
 	args := mock.Called()
 	return args.String(0)
 }
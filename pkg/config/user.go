@@ -0,0 +1,54 @@
+package config
+
+import "github.com/stretchr/testify/mock"
+
+type UserConfig interface {
+	EmailVerificationTTL() int
+	PasswordResetTTL() int
+	MagicLinkTTL() int
+}
+
+type appUserConfig struct {
+	emailVerificationTTL int
+	passwordResetTTL     int
+	magicLinkTTL         int
+}
+
+func newUserConfig() UserConfig {
+	return appUserConfig{
+		emailVerificationTTL: getInt("EMAIL_VERIFICATION_TTL", 86400),
+		passwordResetTTL:     getInt("PASSWORD_RESET_TTL", 3600),
+		magicLinkTTL:         getInt("MAGIC_LINK_TTL", 900),
+	}
+}
+
+func (uc appUserConfig) EmailVerificationTTL() int {
+	return uc.emailVerificationTTL
+}
+
+func (uc appUserConfig) PasswordResetTTL() int {
+	return uc.passwordResetTTL
+}
+
+func (uc appUserConfig) MagicLinkTTL() int {
+	return uc.magicLinkTTL
+}
+
+type MockUserConfig struct {
+	mock.Mock
+}
+
+func (mock *MockUserConfig) EmailVerificationTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
+
+func (mock *MockUserConfig) PasswordResetTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
+
+func (mock *MockUserConfig) MagicLinkTTL() int {
+	args := mock.Called()
+	return args.Int(0)
+}
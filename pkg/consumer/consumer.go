@@ -23,7 +23,10 @@ type ampqConsumer struct {
 }
 
 func (aq *ampqConsumer) Start() {
-	go consume(aq.cfg.UpdatePasswordQueueName(), aq)
+	for _, topic := range aq.messageRouter.Topics() {
+		go consume(topic, aq)
+	}
+
 	handleGracefulShutdown(aq)
 }
 
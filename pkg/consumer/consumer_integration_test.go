@@ -9,6 +9,7 @@ import (
 	reporters "identification-service/pkg/reporting"
 	"identification-service/pkg/session"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"testing"
 	"time"
 )
@@ -40,7 +41,7 @@ func (cts *consumerTestSuite) SetupSuite() {
 		mock.Anything,
 	).Return(nil)
 
-	rt := consumer.NewMessageRouter(cfg.QueueConfig(), mockSessionService)
+	rt := consumer.NewMessageRouter(cfg.QueueConfig(), mockSessionService, &user.MockService{}, nil)
 
 	cts.consumer = consumer.NewConsumer(cfg.QueueConfig(), lgr, qu, rt)
 	cts.queue = qu
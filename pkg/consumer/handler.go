@@ -2,10 +2,15 @@ package consumer
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/nsnikhil/erx"
+	"identification-service/pkg/mailer"
 	"identification-service/pkg/session"
+	"identification-service/pkg/user"
 )
 
+const refreshTokenReuseAlert = "refresh_token_reuse"
+
 type MessageHandler interface {
 	Handle(msg []byte) error
 }
@@ -27,3 +32,123 @@ func NewUpdatePasswordHandler(ss session.Service) MessageHandler {
 		ss: ss,
 	}
 }
+
+type verificationMailHandler struct {
+	ml mailer.Mailer
+}
+
+func (vmh *verificationMailHandler) Handle(msg []byte) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("verificationMailHandler"), err) }
+
+	var event user.EmailVerificationEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return wrap(err)
+	}
+
+	data := map[string]string{"Email": event.Email, "Token": event.Token}
+	origin := mailer.Origin{ClientID: event.ClientID, Locale: event.Locale}
+
+	if err := vmh.ml.Mail(context.Background(), mailer.VerificationMail, event.Email, origin, data); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func NewVerificationMailHandler(ml mailer.Mailer) MessageHandler {
+	return &verificationMailHandler{
+		ml: ml,
+	}
+}
+
+type passwordResetMailHandler struct {
+	ml mailer.Mailer
+}
+
+func (prh *passwordResetMailHandler) Handle(msg []byte) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("passwordResetMailHandler"), err) }
+
+	var event user.PasswordResetEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return wrap(err)
+	}
+
+	data := map[string]string{"Email": event.Email, "Token": event.Token}
+	origin := mailer.Origin{ClientID: event.ClientID, Locale: event.Locale}
+
+	if err := prh.ml.Mail(context.Background(), mailer.PasswordResetMail, event.Email, origin, data); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func NewPasswordResetMailHandler(ml mailer.Mailer) MessageHandler {
+	return &passwordResetMailHandler{
+		ml: ml,
+	}
+}
+
+type magicLinkMailHandler struct {
+	ml mailer.Mailer
+}
+
+func (mlh *magicLinkMailHandler) Handle(msg []byte) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("magicLinkMailHandler"), err) }
+
+	var event user.MagicLinkEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return wrap(err)
+	}
+
+	data := map[string]string{"Email": event.Email, "Token": event.Token}
+	origin := mailer.Origin{ClientID: event.ClientID, Locale: event.Locale}
+
+	if err := mlh.ml.Mail(context.Background(), mailer.MagicLinkMail, event.Email, origin, data); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func NewMagicLinkMailHandler(ml mailer.Mailer) MessageHandler {
+	return &magicLinkMailHandler{
+		ml: ml,
+	}
+}
+
+type securityAlertMailHandler struct {
+	us user.Service
+	ml mailer.Mailer
+}
+
+func (sah *securityAlertMailHandler) Handle(msg []byte) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("securityAlertMailHandler"), err) }
+
+	var event session.RefreshTokenReuseEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		//NOTE: EVENTS QUEUED BEFORE THE ORIGIN WAS ADDED ONLY CARRY THE USER ID, THEIR ALERT USES THE DEFAULT TEMPLATES
+		event = session.RefreshTokenReuseEvent{UserID: string(msg)}
+	}
+
+	u, err := sah.us.GetUser(context.Background(), event.UserID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	data := map[string]string{"Email": u.Email(), "Alert": refreshTokenReuseAlert}
+	origin := mailer.Origin{ClientID: event.ClientID, Locale: event.Locale}
+
+	if err := sah.ml.Mail(context.Background(), mailer.SecurityAlertMail, u.Email(), origin, data); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func NewSecurityAlertMailHandler(us user.Service, ml mailer.Mailer) MessageHandler {
+	return &securityAlertMailHandler{
+		us: us,
+		ml: ml,
+	}
+}
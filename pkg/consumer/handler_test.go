@@ -1,12 +1,17 @@
 package consumer_test
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"identification-service/pkg/consumer"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/password"
 	"identification-service/pkg/session"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"testing"
 )
 
@@ -35,3 +40,175 @@ func TestUpdatePasswordHandlerFailure(t *testing.T) {
 	err := uph.Handle([]byte(userID))
 	assert.Error(t, err)
 }
+
+func TestVerificationMailHandlerSuccess(t *testing.T) {
+	event := user.EmailVerificationEvent{
+		UserID:   test.NewUUID(),
+		Email:    test.NewEmail(),
+		Token:    test.RandString(64),
+		ClientID: test.NewUUID(),
+		Locale:   "fr",
+	}
+
+	msg, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mockMailer := &mailer.MockMailer{}
+	mockMailer.On(
+		"Mail",
+		mock.Anything,
+		mailer.VerificationMail,
+		event.Email,
+		mailer.Origin{ClientID: event.ClientID, Locale: event.Locale},
+		map[string]string{"Email": event.Email, "Token": event.Token},
+	).Return(nil)
+
+	err = consumer.NewVerificationMailHandler(mockMailer).Handle(msg)
+	assert.NoError(t, err)
+}
+
+func TestVerificationMailHandlerFailure(t *testing.T) {
+	testCases := map[string]struct {
+		msg    []byte
+		mailer func() mailer.Mailer
+	}{
+		"test failure when message is malformed": {
+			msg:    []byte(test.RandString(8)),
+			mailer: func() mailer.Mailer { return &mailer.MockMailer{} },
+		},
+		"test failure when mail fails": {
+			msg: []byte(`{"email":"user@mail.com","token":"token"}`),
+			mailer: func() mailer.Mailer {
+				mockMailer := &mailer.MockMailer{}
+				mockMailer.On("Mail", mock.Anything, mailer.VerificationMail, "user@mail.com", mailer.Origin{}, mock.Anything).
+					Return(errors.New("failed to send mail"))
+
+				return mockMailer
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := consumer.NewVerificationMailHandler(testCase.mailer()).Handle(testCase.msg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPasswordResetMailHandlerSuccess(t *testing.T) {
+	event := user.PasswordResetEvent{
+		UserID:   test.NewUUID(),
+		Email:    test.NewEmail(),
+		Token:    test.RandString(43),
+		ClientID: test.NewUUID(),
+	}
+
+	msg, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mockMailer := &mailer.MockMailer{}
+	mockMailer.On(
+		"Mail",
+		mock.Anything,
+		mailer.PasswordResetMail,
+		event.Email,
+		mailer.Origin{ClientID: event.ClientID},
+		map[string]string{"Email": event.Email, "Token": event.Token},
+	).Return(nil)
+
+	err = consumer.NewPasswordResetMailHandler(mockMailer).Handle(msg)
+	assert.NoError(t, err)
+}
+
+func TestPasswordResetMailHandlerFailure(t *testing.T) {
+	err := consumer.NewPasswordResetMailHandler(&mailer.MockMailer{}).Handle([]byte(test.RandString(8)))
+	assert.Error(t, err)
+}
+
+func TestMagicLinkMailHandlerSuccess(t *testing.T) {
+	event := user.MagicLinkEvent{
+		Email:    test.NewEmail(),
+		Token:    test.RandString(64),
+		ClientID: test.NewUUID(),
+		Locale:   "en",
+	}
+
+	msg, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mockMailer := &mailer.MockMailer{}
+	mockMailer.On(
+		"Mail",
+		mock.Anything,
+		mailer.MagicLinkMail,
+		event.Email,
+		mailer.Origin{ClientID: event.ClientID, Locale: event.Locale},
+		map[string]string{"Email": event.Email, "Token": event.Token},
+	).Return(nil)
+
+	err = consumer.NewMagicLinkMailHandler(mockMailer).Handle(msg)
+	assert.NoError(t, err)
+}
+
+func TestMagicLinkMailHandlerFailure(t *testing.T) {
+	err := consumer.NewMagicLinkMailHandler(&mailer.MockMailer{}).Handle([]byte(test.RandString(8)))
+	assert.Error(t, err)
+}
+
+func TestSecurityAlertMailHandlerSuccess(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+	userEmail := test.NewEmail()
+
+	u, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	msg, err := json.Marshal(session.RefreshTokenReuseEvent{UserID: userID, ClientID: clientID, Locale: "pt-br"})
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		msg    []byte
+		origin mailer.Origin
+	}{
+		"test alert is mailed with the origin of the event": {
+			msg:    msg,
+			origin: mailer.Origin{ClientID: clientID, Locale: "pt-br"},
+		},
+		"test alert of an event with only the user id is mailed with the default origin": {
+			msg:    []byte(userID),
+			origin: mailer.Origin{},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserService := &user.MockService{}
+			mockUserService.On("GetUser", mock.Anything, userID).Return(u, nil)
+
+			mockMailer := &mailer.MockMailer{}
+			mockMailer.On(
+				"Mail",
+				mock.Anything,
+				mailer.SecurityAlertMail,
+				userEmail,
+				testCase.origin,
+				map[string]string{"Email": userEmail, "Alert": "refresh_token_reuse"},
+			).Return(nil)
+
+			err := consumer.NewSecurityAlertMailHandler(mockUserService, mockMailer).Handle(testCase.msg)
+			assert.NoError(t, err)
+
+			mockMailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSecurityAlertMailHandlerFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(user.User{}, errors.New("failed to get user"))
+
+	err := consumer.NewSecurityAlertMailHandler(mockUserService, &mailer.MockMailer{}).Handle([]byte(userID))
+	assert.Error(t, err)
+}
@@ -4,16 +4,19 @@ import (
 	"fmt"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/config"
+	"identification-service/pkg/mailer"
 	"identification-service/pkg/session"
+	"identification-service/pkg/user"
+	"sort"
 )
 
 type MessageRouter interface {
 	Route(queueName string, message []byte) error
+	Topics() []string
 }
 
 type ampqMessageRouter struct {
-	cfg config.QueueConfig
-	ss  session.Service
+	handlers map[string]MessageHandler
 }
 
 func (amr *ampqMessageRouter) Route(queueName string, message []byte) error {
@@ -21,9 +24,9 @@ func (amr *ampqMessageRouter) Route(queueName string, message []byte) error {
 		return erx.WithArgs(erx.Operation("router.route"), err)
 	}
 
-	handler, err := getHandler(queueName, amr)
-	if err != nil {
-		return wrap(err)
+	handler, ok := amr.handlers[queueName]
+	if !ok {
+		return wrap(fmt.Errorf("no handler found for the topics %s", queueName))
 	}
 
 	if err := handler.Handle(message); err != nil {
@@ -33,18 +36,31 @@ func (amr *ampqMessageRouter) Route(queueName string, message []byte) error {
 	return nil
 }
 
-func getHandler(topic string, amr *ampqMessageRouter) (MessageHandler, error) {
-	switch topic {
-	case amr.cfg.UpdatePasswordQueueName():
-		return NewUpdatePasswordHandler(amr.ss), nil
-	default:
-		return nil, fmt.Errorf("no handler found for the topics %s", topic)
+func (amr *ampqMessageRouter) Topics() []string {
+	topics := make([]string, 0, len(amr.handlers))
+	for topic := range amr.handlers {
+		topics = append(topics, topic)
 	}
+
+	sort.Strings(topics)
+
+	return topics
 }
 
-func NewMessageRouter(cfg config.QueueConfig, ss session.Service) MessageRouter {
+func NewMessageRouter(cfg config.QueueConfig, ss session.Service, us user.Service, ml mailer.Mailer) MessageRouter {
+	handlers := map[string]MessageHandler{
+		cfg.UpdatePasswordQueueName(): NewUpdatePasswordHandler(ss),
+	}
+
+	//NOTE: WITHOUT A MAILER THE MAIL EVENTS ARE LEFT ON THEIR QUEUES FOR CLIENTS WHICH SEND THEIR OWN MAIL
+	if ml != nil {
+		handlers[cfg.EmailVerificationQueueName()] = NewVerificationMailHandler(ml)
+		handlers[cfg.PasswordResetQueueName()] = NewPasswordResetMailHandler(ml)
+		handlers[cfg.MagicLinkQueueName()] = NewMagicLinkMailHandler(ml)
+		handlers[cfg.RefreshTokenReuseQueueName()] = NewSecurityAlertMailHandler(us, ml)
+	}
+
 	return &ampqMessageRouter{
-		cfg: cfg,
-		ss:  ss,
+		handlers: handlers,
 	}
 }
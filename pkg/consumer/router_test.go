@@ -6,8 +6,10 @@ import (
 	"github.com/stretchr/testify/mock"
 	"identification-service/pkg/config"
 	"identification-service/pkg/consumer"
+	"identification-service/pkg/mailer"
 	"identification-service/pkg/session"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"testing"
 )
 
@@ -21,7 +23,7 @@ func TestRouterSuccess(t *testing.T) {
 	mockSessionService.On("RevokeAllSessions", mock.AnythingOfType("*context.emptyCtx"), userID).
 		Return(nil)
 
-	rt := consumer.NewMessageRouter(mockQueueConfig, mockSessionService)
+	rt := consumer.NewMessageRouter(mockQueueConfig, mockSessionService, &user.MockService{}, nil)
 
 	testCases := map[string]struct {
 		topic string
@@ -78,8 +80,31 @@ func TestRouterFailure(t *testing.T) {
 
 	for name, testCase := range testCases {
 		t.Run(name, func(t *testing.T) {
-			rt := consumer.NewMessageRouter(testCase.cfg(), testCase.ss())
+			rt := consumer.NewMessageRouter(testCase.cfg(), testCase.ss(), &user.MockService{}, nil)
 			assert.Error(t, rt.Route(testCase.topic, testCase.msg))
 		})
 	}
 }
+
+func TestRouterMailTopics(t *testing.T) {
+	newQueueConfig := func() *config.MockQueueConfig {
+		mockQueueConfig := &config.MockQueueConfig{}
+		mockQueueConfig.On("UpdatePasswordQueueName").Return("update-password")
+		mockQueueConfig.On("EmailVerificationQueueName").Return("email-verification")
+		mockQueueConfig.On("PasswordResetQueueName").Return("password-reset")
+		mockQueueConfig.On("MagicLinkQueueName").Return("magic-link")
+		mockQueueConfig.On("RefreshTokenReuseQueueName").Return("refresh-token-reuse")
+		return mockQueueConfig
+	}
+
+	rt := consumer.NewMessageRouter(newQueueConfig(), &session.MockService{}, &user.MockService{}, nil)
+	assert.Equal(t, []string{"update-password"}, rt.Topics())
+	assert.Error(t, rt.Route("email-verification", []byte("{}")))
+
+	mockMailer := &mailer.MockMailer{}
+	mockMailer.On("Mail", mock.Anything, mailer.VerificationMail, "user@mail.com", mailer.Origin{}, mock.Anything).Return(nil)
+
+	rt = consumer.NewMessageRouter(newQueueConfig(), &session.MockService{}, &user.MockService{}, mockMailer)
+	assert.Equal(t, []string{"email-verification", "magic-link", "password-reset", "refresh-token-reuse", "update-password"}, rt.Topics())
+	assert.NoError(t, rt.Route("email-verification", []byte(`{"email":"user@mail.com"}`)))
+}
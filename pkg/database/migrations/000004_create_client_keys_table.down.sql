@@ -0,0 +1,6 @@
+alter table clients add column if not exists private_key bytea unique;
+
+update clients set private_key = k.private_key from client_keys k where k.client_id = clients.id and k.state = 'active';
+
+drop table if exists client_keys;
+drop type if exists key_state;
\ No newline at end of file
@@ -0,0 +1,22 @@
+DO $$ BEGIN
+    PERFORM 'public.key_state'::regtype;
+EXCEPTION
+    WHEN undefined_object THEN
+        create type key_state as enum ('pending', 'active', 'retired');
+END $$;
+
+create table if not exists client_keys (
+	id uuid primary key default gen_random_uuid(),
+	client_id uuid not null references clients(id) on delete cascade,
+	private_key bytea unique not null,
+	state key_state not null,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	updated_at timestamp without time zone default (now() at time zone 'utc'),
+	check (private_key <> '')
+);
+
+create index if not exists client_keys_client_id_idx on client_keys (client_id);
+
+insert into client_keys (client_id, private_key, state) select id, private_key, 'active' from clients;
+
+alter table clients drop column if exists private_key;
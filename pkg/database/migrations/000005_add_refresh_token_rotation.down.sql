@@ -0,0 +1,6 @@
+drop index if exists session_family_id_idx;
+
+alter table sessions drop column if exists used;
+alter table sessions drop column if exists family_id;
+
+alter table clients drop column if exists rotate_refresh_tokens;
\ No newline at end of file
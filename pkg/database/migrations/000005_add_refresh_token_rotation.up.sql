@@ -0,0 +1,6 @@
+alter table clients add column if not exists rotate_refresh_tokens boolean not null default false;
+
+alter table sessions add column if not exists family_id uuid references sessions (id);
+alter table sessions add column if not exists used boolean not null default false;
+
+create index if not exists session_family_id_idx on sessions (family_id);
@@ -0,0 +1,3 @@
+delete from sessions where refresh_token !~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$';
+
+alter table sessions alter column refresh_token type uuid using refresh_token::uuid;
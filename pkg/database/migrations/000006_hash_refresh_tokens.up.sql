@@ -0,0 +1 @@
+alter table sessions alter column refresh_token type text using refresh_token::text;
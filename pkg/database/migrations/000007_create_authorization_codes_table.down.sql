@@ -0,0 +1,5 @@
+drop index if exists authorization_codes_expires_at_idx;
+
+drop table if exists authorization_codes;
+
+alter table clients drop column if exists redirect_uris;
\ No newline at end of file
@@ -0,0 +1,17 @@
+alter table clients add column if not exists redirect_uris text[] not null default '{}';
+
+create table if not exists authorization_codes (
+	code text primary key,
+	client_id uuid not null references clients(id) on delete cascade,
+	user_id uuid not null references users(id) on delete cascade,
+	redirect_uri text not null,
+	code_challenge text not null,
+	used boolean not null default false,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	check (code <> ''),
+	check (redirect_uri <> ''),
+	check (code_challenge <> '')
+);
+
+create index if not exists authorization_codes_expires_at_idx on authorization_codes (expires_at);
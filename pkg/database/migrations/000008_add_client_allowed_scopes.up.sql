@@ -0,0 +1 @@
+alter table clients add column if not exists allowed_scopes text[] not null default '{}';
\ No newline at end of file
@@ -0,0 +1,3 @@
+alter table authorization_codes drop column if exists auth_time;
+alter table authorization_codes drop column if exists nonce;
+alter table authorization_codes drop column if exists scope;
\ No newline at end of file
@@ -0,0 +1,3 @@
+alter table authorization_codes add column if not exists scope text not null default '';
+alter table authorization_codes add column if not exists nonce text not null default '';
+alter table authorization_codes add column if not exists auth_time timestamp without time zone not null default (now() at time zone 'utc');
\ No newline at end of file
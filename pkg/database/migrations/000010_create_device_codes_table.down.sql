@@ -0,0 +1,3 @@
+drop index if exists device_codes_expires_at_idx;
+
+drop table if exists device_codes;
\ No newline at end of file
@@ -0,0 +1,18 @@
+create table if not exists device_codes (
+	device_code text primary key,
+	user_code text not null unique,
+	client_id uuid not null references clients(id) on delete cascade,
+	user_id uuid references users(id) on delete cascade,
+	scope text not null default '',
+	status text not null default 'pending',
+	poll_interval integer not null,
+	last_polled_at timestamp without time zone,
+	auth_time timestamp without time zone,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	check (device_code <> ''),
+	check (user_code <> ''),
+	check (status in ('pending', 'approved', 'denied', 'consumed'))
+);
+
+create index if not exists device_codes_expires_at_idx on device_codes (expires_at);
\ No newline at end of file
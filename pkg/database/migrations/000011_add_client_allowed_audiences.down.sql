@@ -0,0 +1 @@
+alter table clients drop column if exists allowed_audiences;
\ No newline at end of file
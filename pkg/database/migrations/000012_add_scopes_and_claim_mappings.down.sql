@@ -0,0 +1,3 @@
+alter table sessions drop column if exists scope;
+
+alter table clients drop column if exists claim_mappings;
\ No newline at end of file
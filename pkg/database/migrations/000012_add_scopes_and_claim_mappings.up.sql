@@ -0,0 +1,3 @@
+alter table clients add column if not exists claim_mappings jsonb not null default '{}';
+
+alter table sessions add column if not exists scope text not null default '';
\ No newline at end of file
@@ -0,0 +1,7 @@
+drop index if exists user_roles_user_id_role_id_client_id_idx;
+
+drop table if exists user_roles;
+
+drop table if exists role_permissions;
+
+drop table if exists roles;
\ No newline at end of file
@@ -0,0 +1,23 @@
+create table if not exists roles (
+	id uuid primary key default gen_random_uuid(),
+	name varchar(100) unique not null,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	updated_at timestamp without time zone default (now() at time zone 'utc'),
+	check (name <> '')
+);
+
+create table if not exists role_permissions (
+	role_id uuid not null references roles(id) on delete cascade,
+	permission varchar(100) not null,
+	primary key (role_id, permission),
+	check (permission <> '')
+);
+
+create table if not exists user_roles (
+	user_id uuid not null references users(id) on delete cascade,
+	role_id uuid not null references roles(id) on delete cascade,
+	client_id uuid references clients(id) on delete cascade,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create unique index if not exists user_roles_user_id_role_id_client_id_idx on user_roles (user_id, role_id, coalesce(client_id, '00000000-0000-0000-0000-000000000000'));
\ No newline at end of file
@@ -0,0 +1,9 @@
+alter table sessions drop column if exists tenant_id;
+
+drop index if exists users_tenant_id_email_idx;
+alter table users add constraint users_email_key unique (email);
+alter table users drop column if exists tenant_id;
+
+alter table clients drop column if exists tenant_id;
+
+drop table if exists tenants;
\ No newline at end of file
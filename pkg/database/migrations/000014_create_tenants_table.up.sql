@@ -0,0 +1,17 @@
+create table if not exists tenants (
+	id uuid primary key default gen_random_uuid(),
+	name varchar(100) unique not null,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	updated_at timestamp without time zone default (now() at time zone 'utc'),
+	check (name <> '')
+);
+
+insert into tenants (id, name) values ('00000000-0000-4000-8000-000000000000', 'default') on conflict do nothing;
+
+alter table clients add column if not exists tenant_id uuid not null default '00000000-0000-4000-8000-000000000000' references tenants(id);
+
+alter table users add column if not exists tenant_id uuid not null default '00000000-0000-4000-8000-000000000000' references tenants(id);
+alter table users drop constraint if exists users_email_key;
+create unique index if not exists users_tenant_id_email_idx on users (tenant_id, email);
+
+alter table sessions add column if not exists tenant_id uuid not null default '00000000-0000-4000-8000-000000000000' references tenants(id);
\ No newline at end of file
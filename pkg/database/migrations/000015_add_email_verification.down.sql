@@ -0,0 +1,7 @@
+drop index if exists email_verifications_user_id_idx;
+
+drop table if exists email_verifications;
+
+alter table clients drop column if exists require_verified_email;
+
+alter table users drop column if exists email_verified;
\ No newline at end of file
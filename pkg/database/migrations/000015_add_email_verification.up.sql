@@ -0,0 +1,12 @@
+alter table users add column if not exists email_verified boolean not null default false;
+
+alter table clients add column if not exists require_verified_email boolean not null default false;
+
+create table if not exists email_verifications (
+	id uuid primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index if not exists email_verifications_user_id_idx on email_verifications (user_id);
\ No newline at end of file
@@ -0,0 +1,3 @@
+drop index if exists password_resets_user_id_idx;
+
+drop table if exists password_resets;
\ No newline at end of file
@@ -0,0 +1,8 @@
+create table if not exists password_resets (
+	token_hash text primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index if not exists password_resets_user_id_idx on password_resets (user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists user_totp;
\ No newline at end of file
@@ -0,0 +1,8 @@
+create table if not exists user_totp (
+	user_id uuid primary key references users(id) on delete cascade,
+	secret bytea not null,
+	confirmed boolean not null default false,
+	last_used_step bigint not null default 0,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	updated_at timestamp without time zone default (now() at time zone 'utc')
+);
\ No newline at end of file
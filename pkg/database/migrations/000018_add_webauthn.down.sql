@@ -0,0 +1,5 @@
+drop table if exists webauthn_challenges;
+drop table if exists webauthn_credentials;
+
+alter table clients drop column if exists webauthn_origins;
+alter table clients drop column if exists webauthn_rp_id;
\ No newline at end of file
@@ -0,0 +1,24 @@
+alter table clients add column if not exists webauthn_rp_id text not null default '';
+alter table clients add column if not exists webauthn_origins text[] not null default '{}';
+
+create table if not exists webauthn_credentials (
+	id bytea primary key,
+	user_id uuid not null references users(id) on delete cascade,
+	rp_id text not null,
+	public_key bytea not null,
+	sign_count bigint not null default 0,
+	created_at timestamp without time zone default (now() at time zone 'utc'),
+	last_used_at timestamp without time zone
+);
+
+create index if not exists webauthn_credentials_user_id_idx on webauthn_credentials (user_id, rp_id);
+
+create table if not exists webauthn_challenges (
+	id uuid primary key default gen_random_uuid(),
+	client_id uuid not null references clients(id) on delete cascade,
+	user_id uuid references users(id) on delete cascade,
+	ceremony text not null,
+	challenge bytea not null,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists mfa_recovery_codes;
\ No newline at end of file
@@ -0,0 +1,10 @@
+create table if not exists mfa_recovery_codes (
+	id uuid primary key default gen_random_uuid(),
+	user_id uuid not null references users(id) on delete cascade,
+	code_hash text not null,
+	salt bytea not null,
+	used_at timestamp without time zone,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index if not exists mfa_recovery_codes_user_id_idx on mfa_recovery_codes (user_id);
\ No newline at end of file
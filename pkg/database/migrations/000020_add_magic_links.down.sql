@@ -0,0 +1,5 @@
+drop index if exists magic_links_email_idx;
+
+drop table if exists magic_links;
+
+alter table clients drop column if exists allow_magic_link_signup;
\ No newline at end of file
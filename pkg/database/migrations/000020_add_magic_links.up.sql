@@ -0,0 +1,13 @@
+alter table clients add column if not exists allow_magic_link_signup boolean not null default false;
+
+create table if not exists magic_links (
+	id uuid primary key,
+	client_id uuid not null references clients(id) on delete cascade,
+	tenant_id uuid not null references tenants(id) on delete cascade,
+	email text not null,
+	name text not null default '',
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index if not exists magic_links_email_idx on magic_links (tenant_id, email);
\ No newline at end of file
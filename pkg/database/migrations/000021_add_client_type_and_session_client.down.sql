@@ -0,0 +1,3 @@
+alter table sessions drop column if exists client_id;
+
+alter table clients drop column if exists client_type;
\ No newline at end of file
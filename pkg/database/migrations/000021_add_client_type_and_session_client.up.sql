@@ -0,0 +1,3 @@
+alter table clients add column if not exists client_type text not null default 'confidential' check (client_type in ('confidential', 'public'));
+
+alter table sessions add column if not exists client_id uuid references clients(id) on delete cascade;
\ No newline at end of file
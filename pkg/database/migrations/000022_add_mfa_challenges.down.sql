@@ -0,0 +1,3 @@
+drop index if exists mfa_challenges_user_id_idx;
+
+drop table if exists mfa_challenges;
\ No newline at end of file
@@ -0,0 +1,10 @@
+create table if not exists mfa_challenges (
+	id uuid primary key,
+	client_id uuid not null references clients(id) on delete cascade,
+	user_id uuid not null references users(id) on delete cascade,
+	attempts integer not null default 0,
+	expires_at timestamp without time zone not null,
+	created_at timestamp without time zone default (now() at time zone 'utc')
+);
+
+create index if not exists mfa_challenges_user_id_idx on mfa_challenges (user_id);
\ No newline at end of file
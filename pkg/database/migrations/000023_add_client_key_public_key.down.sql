@@ -0,0 +1 @@
+alter table client_keys drop column if exists public_key;
\ No newline at end of file
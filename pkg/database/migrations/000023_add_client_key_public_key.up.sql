@@ -0,0 +1 @@
+alter table client_keys add column if not exists public_key bytea;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table clients drop column if exists key_version;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table clients add column if not exists key_version integer not null default 0;
\ No newline at end of file
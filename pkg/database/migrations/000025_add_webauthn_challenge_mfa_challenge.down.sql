@@ -0,0 +1 @@
+alter table webauthn_challenges drop column if exists mfa_challenge_id;
\ No newline at end of file
@@ -0,0 +1 @@
+alter table webauthn_challenges add column if not exists mfa_challenge_id uuid references mfa_challenges(id) on delete cascade;
\ No newline at end of file
@@ -1,13 +1,27 @@
 package contract
 
-const ClientRevokeSuccessful = "client revoked successfully"
+const (
+	ClientRevokeSuccessful     = "client revoked successfully"
+	ClientRotateKeysSuccessful = "client keys rotated successfully"
+)
 
 type CreateClientRequest struct {
-	Name              string `json:"name"`
-	AccessTokenTTL    int    `json:"access_token_ttl"`
-	SessionTTL        int    `json:"session_ttl"`
-	MaxActiveSessions int    `json:"max_active_sessions"`
-	SessionStrategy   string `json:"session_strategy"`
+	Name                 string            `json:"name"`
+	AccessTokenTTL       int               `json:"access_token_ttl"`
+	SessionTTL           int               `json:"session_ttl"`
+	MaxActiveSessions    int               `json:"max_active_sessions"`
+	SessionStrategy      string            `json:"session_strategy"`
+	RotateRefreshTokens  bool              `json:"rotate_refresh_tokens"`
+	RedirectURIs         []string          `json:"redirect_uris"`
+	AllowedScopes        []string          `json:"allowed_scopes"`
+	AllowedAudiences     []string          `json:"allowed_audiences"`
+	ClaimMappings        map[string]string `json:"claim_mappings"`
+	TenantID             string            `json:"tenant_id"`
+	RequireVerifiedEmail bool              `json:"require_verified_email"`
+	AllowMagicLinkSignUp bool              `json:"allow_magic_link_signup"`
+	WebAuthnRPID         string            `json:"webauthn_rp_id"`
+	WebAuthnOrigins      []string          `json:"webauthn_origins"`
+	ClientType           string            `json:"client_type"`
 }
 
 type CreateClientResponse struct {
@@ -22,3 +36,11 @@ type ClientRevokeRequest struct {
 type ClientRevokeResponse struct {
 	Message string `json:"message"`
 }
+
+type ClientRotateKeysRequest struct {
+	ID string `json:"id"`
+}
+
+type ClientRotateKeysResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,31 @@
+package contract
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Issuer    string `json:"iss"`
+	ClientID  string `json:"client_id"`
+	Status    string `json:"status"`
+	Paserk    string `json:"paserk"`
+}
+
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+type PaserkKey struct {
+	KeyID    string `json:"kid"`
+	PaserkID string `json:"pid"`
+	Issuer   string `json:"iss"`
+	ClientID string `json:"client_id"`
+	Status   string `json:"status"`
+	Paserk   string `json:"paserk"`
+}
+
+type PaserkResponse struct {
+	Keys []PaserkKey `json:"keys"`
+}
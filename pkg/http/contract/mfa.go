@@ -0,0 +1,130 @@
+package contract
+
+const TOTPConfirmationSuccess = "totp enabled successfully"
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+func (cr ConfirmTOTPRequest) IsValid() error {
+	return isValid("ConfirmTOTPRequest.IsValid",
+		pair{name: "code", data: cr.Code},
+	)
+}
+
+type ConfirmTOTPResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+const WebAuthnRegistrationSuccess = "passkey registered successfully"
+
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type WebAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type WebAuthnCredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+type WebAuthnCreationOptions struct {
+	RelyingParty           WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	Challenge              string                         `json:"challenge"`
+	CredentialParameters   []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RelyingPartyID   string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+type BeginWebAuthnRegistrationResponse struct {
+	ChallengeID string                  `json:"challenge_id"`
+	PublicKey   WebAuthnCreationOptions `json:"public_key"`
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	ChallengeID       string `json:"challenge_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AttestationObject string `json:"attestation_object"`
+}
+
+func (fr FinishWebAuthnRegistrationRequest) IsValid() error {
+	return isValid("FinishWebAuthnRegistrationRequest.IsValid",
+		pair{name: "challenge id", data: fr.ChallengeID},
+		pair{name: "client data json", data: fr.ClientDataJSON},
+		pair{name: "attestation object", data: fr.AttestationObject},
+	)
+}
+
+type FinishWebAuthnRegistrationResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RemainingRecoveryCodesResponse struct {
+	Remaining int `json:"remaining"`
+}
+
+type StepUpRequest struct {
+	MFAToken          string `json:"mfa_token"`
+	Code              string `json:"code"`
+	ChallengeID       string `json:"challenge_id"`
+	CredentialID      string `json:"credential_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"user_handle"`
+}
+
+func (sr StepUpRequest) IsValid() error {
+	//NOTE: A PASSKEY ASSERTION IS SENT WITH THE CHALLENGE STARTED FROM THE MFA TOKEN, OTHERWISE A CODE IS SENT WITH THE TOKEN ITSELF
+	if len(sr.ChallengeID) != 0 {
+		return isValid("StepUpRequest.IsValid",
+			pair{name: "credential id", data: sr.CredentialID},
+			pair{name: "client data json", data: sr.ClientDataJSON},
+			pair{name: "authenticator data", data: sr.AuthenticatorData},
+			pair{name: "signature", data: sr.Signature},
+		)
+	}
+
+	return isValid("StepUpRequest.IsValid",
+		pair{name: "mfa token", data: sr.MFAToken},
+		pair{name: "code", data: sr.Code},
+	)
+}
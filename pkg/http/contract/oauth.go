@@ -0,0 +1,145 @@
+package contract
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+)
+
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Scope               string
+	Nonce               string
+	Email               string
+	Password            string
+	MFAToken            string
+	MFACode             string
+}
+
+type DeviceAuthorizationRequest struct {
+	Scope        string
+	ClientID     string
+	ClientSecret string
+}
+
+func (dr DeviceAuthorizationRequest) IsValid() error {
+	return isValid("DeviceAuthorizationRequest.IsValid", pair{name: "client id", data: dr.ClientID})
+}
+
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceVerificationRequest struct {
+	UserCode string
+	Email    string
+	Password string
+	MFAToken string
+	MFACode  string
+	Approved bool
+}
+
+type OAuthTokenRequest struct {
+	GrantType        string
+	Code             string
+	RedirectURI      string
+	CodeVerifier     string
+	RefreshToken     string
+	DeviceCode       string
+	SubjectToken     string
+	SubjectTokenType string
+	Audience         string
+	Scope            string
+	ClientID         string
+	ClientSecret     string
+}
+
+func (tr OAuthTokenRequest) IsValid() error {
+	switch tr.GrantType {
+	case GrantTypeAuthorizationCode:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "code", data: tr.Code},
+			pair{name: "redirect uri", data: tr.RedirectURI},
+			pair{name: "code verifier", data: tr.CodeVerifier},
+			pair{name: "client id", data: tr.ClientID},
+		)
+	case GrantTypeRefreshToken:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "refresh token", data: tr.RefreshToken},
+			pair{name: "client id", data: tr.ClientID},
+		)
+	case GrantTypeClientCredentials:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "client id", data: tr.ClientID},
+			pair{name: "client secret", data: tr.ClientSecret},
+		)
+	case GrantTypeTokenExchange:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "subject token", data: tr.SubjectToken},
+			pair{name: "subject token type", data: tr.SubjectTokenType},
+			pair{name: "audience", data: tr.Audience},
+			pair{name: "client id", data: tr.ClientID},
+			pair{name: "client secret", data: tr.ClientSecret},
+		)
+	case GrantTypeDeviceCode:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "device code", data: tr.DeviceCode},
+			pair{name: "client id", data: tr.ClientID},
+		)
+	default:
+		return isValid("OAuthTokenRequest.IsValid",
+			pair{name: "grant type", data: tr.GrantType},
+			pair{name: "client id", data: tr.ClientID},
+		)
+	}
+}
+
+type OAuthTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	RefreshToken    string `json:"refresh_token,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	IDToken         string `json:"id_token,omitempty"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type UserInfoResponse struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+}
+
+type OpenIDConfigurationResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
@@ -0,0 +1,25 @@
+package contract
+
+const (
+	RoleAssignSuccessful   = "role assigned successfully"
+	RoleUnassignSuccessful = "role unassigned successfully"
+)
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type CreateRoleResponse struct {
+	ID string `json:"id"`
+}
+
+type RoleAssignmentRequest struct {
+	UserID   string `json:"user_id"`
+	Role     string `json:"role"`
+	ClientID string `json:"client_id"`
+}
+
+type RoleAssignmentResponse struct {
+	Message string `json:"message"`
+}
@@ -1,10 +1,14 @@
 package contract
 
-const LogoutSuccessfulMessage = "Logout Successful"
+const (
+	LogoutSuccessfulMessage = "Logout Successful"
+	MagicLinkSent           = "sign in link sent if the account exists or can be created"
+)
 
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	Scope    string `json:"scope"`
 }
 
 func (lr LoginRequest) IsValid() error {
@@ -19,6 +23,74 @@ type LoginResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type MFAChallengeResponse struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type CompleteLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+	Scope    string `json:"scope"`
+}
+
+func (cr CompleteLoginRequest) IsValid() error {
+	return isValid("CompleteLoginRequest.IsValid",
+		pair{name: "mfa token", data: cr.MFAToken},
+		pair{name: "code", data: cr.Code},
+	)
+}
+
+type BeginWebAuthnLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type BeginWebAuthnLoginResponse struct {
+	ChallengeID string                 `json:"challenge_id"`
+	PublicKey   WebAuthnRequestOptions `json:"public_key"`
+}
+
+type FinishWebAuthnLoginRequest struct {
+	ChallengeID       string `json:"challenge_id"`
+	CredentialID      string `json:"credential_id"`
+	ClientDataJSON    string `json:"client_data_json"`
+	AuthenticatorData string `json:"authenticator_data"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"user_handle"`
+	Scope             string `json:"scope"`
+}
+
+func (fr FinishWebAuthnLoginRequest) IsValid() error {
+	return isValid("FinishWebAuthnLoginRequest.IsValid",
+		pair{name: "challenge id", data: fr.ChallengeID},
+		pair{name: "credential id", data: fr.CredentialID},
+		pair{name: "client data json", data: fr.ClientDataJSON},
+		pair{name: "authenticator data", data: fr.AuthenticatorData},
+		pair{name: "signature", data: fr.Signature},
+	)
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func (mr MagicLinkRequest) IsValid() error {
+	return isValid("MagicLinkRequest.IsValid", pair{name: "email", data: mr.Email})
+}
+
+type MagicLinkResponse struct {
+	Message string `json:"message"`
+}
+
+type MagicLinkLoginRequest struct {
+	Token string `json:"token"`
+	Scope string `json:"scope"`
+}
+
+func (mr MagicLinkLoginRequest) IsValid() error {
+	return isValid("MagicLinkLoginRequest.IsValid", pair{name: "token", data: mr.Token})
+}
+
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
@@ -30,7 +102,8 @@ func (rr RefreshTokenRequest) IsValid() error {
 }
 
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 type LogoutRequest struct {
@@ -11,6 +11,14 @@ const (
 	sessionEmailKey     = "email"
 	sessionPasswordKey  = "password"
 	sessionRefreshToken = "refreshToken"
+	sessionMFAToken     = "mfaToken"
+	sessionCode         = "code"
+
+	webAuthnChallengeID       = "challengeID"
+	webAuthnCredentialID      = "credentialID"
+	webAuthnClientDataJSON    = "clientDataJSON"
+	webAuthnAuthenticatorData = "authenticatorData"
+	webAuthnSignature         = "signature"
 )
 
 var loginRequestDefaultData = map[string]string{
@@ -26,6 +34,19 @@ var refreshTokenRequestDefaultData = map[string]string{
 	sessionRefreshToken: test.NewUUID(),
 }
 
+var completeLoginRequestDefaultData = map[string]string{
+	sessionMFAToken: test.RandString(32),
+	sessionCode:     "123456",
+}
+
+var finishWebAuthnLoginRequestDefaultData = map[string]string{
+	webAuthnChallengeID:       test.NewUUID(),
+	webAuthnCredentialID:      test.RandString(22),
+	webAuthnClientDataJSON:    test.RandString(64),
+	webAuthnAuthenticatorData: test.RandString(50),
+	webAuthnSignature:         test.RandString(96),
+}
+
 func TestLoginRequestIsValidSuccess(t *testing.T) {
 	lr := newLoginRequest(loginRequestDefaultData)
 	assert.NoError(t, lr.IsValid())
@@ -95,6 +116,65 @@ func TestRefreshTokenRequestIsValidFailure(t *testing.T) {
 	}
 }
 
+func TestCompleteLoginRequestIsValidSuccess(t *testing.T) {
+	cr := newCompleteLoginRequest(completeLoginRequestDefaultData)
+	assert.NoError(t, cr.IsValid())
+}
+
+func TestCompleteLoginRequestIsValidFailure(t *testing.T) {
+	testCases := map[string]struct {
+		overrides map[string]string
+	}{
+		"test failure when mfa token is empty": {
+			overrides: removeKey(sessionMFAToken, completeLoginRequestDefaultData),
+		},
+		"test failure when code is empty": {
+			overrides: removeKey(sessionCode, completeLoginRequestDefaultData),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cr := newCompleteLoginRequest(testCase.overrides)
+			assert.Error(t, cr.IsValid())
+		})
+	}
+}
+
+func TestFinishWebAuthnLoginRequestIsValidSuccess(t *testing.T) {
+	fr := newFinishWebAuthnLoginRequest(finishWebAuthnLoginRequestDefaultData)
+	assert.NoError(t, fr.IsValid())
+}
+
+func TestFinishWebAuthnLoginRequestIsValidFailure(t *testing.T) {
+	testCases := map[string]struct {
+		overrides map[string]string
+	}{
+		"test failure when challenge id is empty": {
+			overrides: removeKey(webAuthnChallengeID, finishWebAuthnLoginRequestDefaultData),
+		},
+		"test failure when credential id is empty": {
+			overrides: removeKey(webAuthnCredentialID, finishWebAuthnLoginRequestDefaultData),
+		},
+		"test failure when client data json is empty": {
+			overrides: removeKey(webAuthnClientDataJSON, finishWebAuthnLoginRequestDefaultData),
+		},
+		"test failure when authenticator data is empty": {
+			overrides: removeKey(webAuthnAuthenticatorData, finishWebAuthnLoginRequestDefaultData),
+		},
+		"test failure when signature is empty": {
+			overrides: removeKey(webAuthnSignature, finishWebAuthnLoginRequestDefaultData),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			fr := newFinishWebAuthnLoginRequest(testCase.overrides)
+			assert.Error(t, fr.IsValid())
+		})
+	}
+}
+
 func newLoginRequest(data map[string]string) contract.LoginRequest {
 	return contract.LoginRequest{
 		Email:    data[sessionEmailKey],
@@ -113,3 +193,20 @@ func newRefreshTokenRequest(data map[string]string) contract.RefreshTokenRequest
 		RefreshToken: data[sessionRefreshToken],
 	}
 }
+
+func newCompleteLoginRequest(data map[string]string) contract.CompleteLoginRequest {
+	return contract.CompleteLoginRequest{
+		MFAToken: data[sessionMFAToken],
+		Code:     data[sessionCode],
+	}
+}
+
+func newFinishWebAuthnLoginRequest(data map[string]string) contract.FinishWebAuthnLoginRequest {
+	return contract.FinishWebAuthnLoginRequest{
+		ChallengeID:       data[webAuthnChallengeID],
+		CredentialID:      data[webAuthnCredentialID],
+		ClientDataJSON:    data[webAuthnClientDataJSON],
+		AuthenticatorData: data[webAuthnAuthenticatorData],
+		Signature:         data[webAuthnSignature],
+	}
+}
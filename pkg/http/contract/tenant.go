@@ -0,0 +1,9 @@
+package contract
+
+type CreateTenantRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateTenantResponse struct {
+	ID string `json:"id"`
+}
@@ -0,0 +1,22 @@
+package contract
+
+type IntrospectRequest struct {
+	Token string `json:"token"`
+}
+
+func (ir IntrospectRequest) IsValid() error {
+	return isValid("IntrospectRequest.IsValid",
+		pair{name: "token", data: ir.Token},
+	)
+}
+
+type IntrospectResponse struct {
+	Active     bool              `json:"active"`
+	Subject    string            `json:"sub,omitempty"`
+	Expiration int64             `json:"exp,omitempty"`
+	IssuedAt   int64             `json:"iat,omitempty"`
+	Audience   string            `json:"aud,omitempty"`
+	ClientID   string            `json:"client_id,omitempty"`
+	Scope      string            `json:"scope,omitempty"`
+	Claims     map[string]string `json:"claims,omitempty"`
+}
@@ -0,0 +1,18 @@
+package contract_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/test"
+	"testing"
+)
+
+func TestIntrospectRequestIsValidSuccess(t *testing.T) {
+	ir := contract.IntrospectRequest{Token: test.NewPasetoToken()}
+	assert.NoError(t, ir.IsValid())
+}
+
+func TestIntrospectRequestIsValidFailure(t *testing.T) {
+	ir := contract.IntrospectRequest{}
+	assert.Error(t, ir.IsValid())
+}
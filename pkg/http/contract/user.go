@@ -1,8 +1,12 @@
 package contract
 
 const (
-	UserCreationSuccess   = "user created successfully"
-	PasswordUpdateSuccess = "password updated successfully"
+	UserCreationSuccess       = "user created successfully"
+	PasswordUpdateSuccess     = "password updated successfully"
+	EmailVerificationSuccess  = "email verified successfully"
+	VerificationResendSuccess = "verification email sent if the account exists and is not verified"
+	PasswordResetSent         = "password reset email sent if the account exists"
+	PasswordResetSuccess      = "password reset successfully"
 )
 
 type CreateUserRequest struct {
@@ -33,3 +37,55 @@ func (upr UpdatePasswordRequest) IsValid() error {
 type UpdatePasswordResponse struct {
 	Message string `json:"message"`
 }
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (ver VerifyEmailRequest) IsValid() error {
+	return isValid("VerifyEmailRequest.IsValid", pair{name: "token", data: ver.Token})
+}
+
+type VerifyEmailResponse struct {
+	Message string `json:"message"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+func (rvr ResendVerificationRequest) IsValid() error {
+	return isValid("ResendVerificationRequest.IsValid", pair{name: "email", data: rvr.Email})
+}
+
+type ResendVerificationResponse struct {
+	Message string `json:"message"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+func (fpr ForgotPasswordRequest) IsValid() error {
+	return isValid("ForgotPasswordRequest.IsValid", pair{name: "email", data: fpr.Email})
+}
+
+type ForgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (rpr ResetPasswordRequest) IsValid() error {
+	return isValid("ResetPasswordRequest.IsValid",
+		pair{name: "token", data: rpr.Token},
+		pair{name: "new password", data: rpr.NewPassword},
+	)
+}
+
+type ResetPasswordResponse struct {
+	Message string `json:"message"`
+}
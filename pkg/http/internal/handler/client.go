@@ -25,6 +25,17 @@ func (ch *ClientHandler) Register(resp http.ResponseWriter, req *http.Request) e
 		reqBody.SessionTTL,
 		reqBody.MaxActiveSessions,
 		reqBody.SessionStrategy,
+		reqBody.RotateRefreshTokens,
+		reqBody.RedirectURIs,
+		reqBody.AllowedScopes,
+		reqBody.AllowedAudiences,
+		reqBody.ClaimMappings,
+		reqBody.TenantID,
+		reqBody.RequireVerifiedEmail,
+		reqBody.AllowMagicLinkSignUp,
+		reqBody.WebAuthnRPID,
+		reqBody.WebAuthnOrigins,
+		reqBody.ClientType,
 	)
 
 	if err != nil {
@@ -54,6 +65,23 @@ func (ch *ClientHandler) Revoke(resp http.ResponseWriter, req *http.Request) err
 	return nil
 }
 
+func (ch *ClientHandler) RotateKeys(resp http.ResponseWriter, req *http.Request) error {
+	var reqBody contract.ClientRotateKeysRequest
+	if err := util.ParseRequest(req, &reqBody); err != nil {
+		return erx.WithArgs(erx.Operation("ClientHandler.RotateKeys"), err)
+	}
+
+	err := ch.service.RotateKeys(req.Context(), reqBody.ID)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("ClientHandler.RotateKeys"), err)
+	}
+
+	respBody := contract.ClientRotateKeysResponse{Message: contract.ClientRotateKeysSuccessful}
+
+	util.WriteSuccessResponse(http.StatusOK, respBody, resp)
+	return nil
+}
+
 func NewClientHandler(service client.Service) *ClientHandler {
 	return &ClientHandler{
 		service: service,
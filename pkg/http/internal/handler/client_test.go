@@ -15,6 +15,7 @@ import (
 	mdl "identification-service/pkg/http/internal/middleware"
 	reporters "identification-service/pkg/reporting"
 	"identification-service/pkg/test"
+	"identification-service/pkg/user"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -28,13 +29,24 @@ func TestClientHandlerCreateSuccess(t *testing.T) {
 	accessTokenTTL := test.RandInt(1, 10)
 	sessionTokenTTL := test.RandInt(1, 10)
 	maxActiveSession := test.RandInt(1, 10)
+	tenantID := test.NewUUID()
 
 	req := contract.CreateClientRequest{
-		Name:              clientName,
-		AccessTokenTTL:    accessTokenTTL,
-		SessionTTL:        sessionTokenTTL,
-		MaxActiveSessions: maxActiveSession,
-		SessionStrategy:   test.ClientSessionStrategyRevokeOld,
+		Name:                 clientName,
+		AccessTokenTTL:       accessTokenTTL,
+		SessionTTL:           sessionTokenTTL,
+		MaxActiveSessions:    maxActiveSession,
+		SessionStrategy:      test.ClientSessionStrategyRevokeOld,
+		RedirectURIs:         []string{test.ClientRedirectURI},
+		AllowedScopes:        []string{test.ClientScope},
+		AllowedAudiences:     []string{test.ClientAudience},
+		ClaimMappings:        map[string]string{"contact_email": user.AttributeEmail},
+		TenantID:             tenantID,
+		RequireVerifiedEmail: true,
+		AllowMagicLinkSignUp: true,
+		WebAuthnRPID:         test.WebAuthnRPID,
+		WebAuthnOrigins:      []string{test.WebAuthnOrigin},
+		ClientType:           client.TypePublic,
 	}
 
 	body, err := json.Marshal(&req)
@@ -49,6 +61,17 @@ func TestClientHandlerCreateSuccess(t *testing.T) {
 		sessionTokenTTL,
 		maxActiveSession,
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		tenantID,
+		true,
+		true,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypePublic,
 	).Return(clientEncodedPublicKey, clientSecret, nil)
 
 	expectedBody := fmt.Sprintf(
@@ -65,13 +88,24 @@ func TestClientHandlerCreateFailure(t *testing.T) {
 	accessTokenTTL := test.RandInt(1, 10)
 	sessionTokenTTL := test.RandInt(1, 10)
 	maxActiveSession := test.RandInt(1, 10)
+	tenantID := test.NewUUID()
 
 	req := contract.CreateClientRequest{
-		Name:              clientName,
-		AccessTokenTTL:    accessTokenTTL,
-		SessionTTL:        sessionTokenTTL,
-		MaxActiveSessions: maxActiveSession,
-		SessionStrategy:   test.ClientSessionStrategyRevokeOld,
+		Name:                 clientName,
+		AccessTokenTTL:       accessTokenTTL,
+		SessionTTL:           sessionTokenTTL,
+		MaxActiveSessions:    maxActiveSession,
+		SessionStrategy:      test.ClientSessionStrategyRevokeOld,
+		RedirectURIs:         []string{test.ClientRedirectURI},
+		AllowedScopes:        []string{test.ClientScope},
+		AllowedAudiences:     []string{test.ClientAudience},
+		ClaimMappings:        map[string]string{"contact_email": user.AttributeEmail},
+		TenantID:             tenantID,
+		RequireVerifiedEmail: true,
+		AllowMagicLinkSignUp: true,
+		WebAuthnRPID:         test.WebAuthnRPID,
+		WebAuthnOrigins:      []string{test.WebAuthnOrigin},
+		ClientType:           client.TypePublic,
 	}
 
 	body, err := json.Marshal(&req)
@@ -86,6 +120,17 @@ func TestClientHandlerCreateFailure(t *testing.T) {
 		sessionTokenTTL,
 		maxActiveSession,
 		test.ClientSessionStrategyRevokeOld,
+		false,
+		[]string{test.ClientRedirectURI},
+		[]string{test.ClientScope},
+		[]string{test.ClientAudience},
+		map[string]string{"contact_email": user.AttributeEmail},
+		tenantID,
+		true,
+		true,
+		test.WebAuthnRPID,
+		[]string{test.WebAuthnOrigin},
+		client.TypePublic,
 	).Return("", "", erx.WithArgs(errors.New("failed to create client")))
 
 	expectedBody := `{"error":{"message":"internal server error"},"success":false}`
@@ -158,3 +203,49 @@ func testClientHandlerRevoke(t *testing.T, expectedCode int, expectedBody string
 	assert.Equal(t, expectedCode, w.Code)
 	assert.Equal(t, expectedBody, w.Body.String())
 }
+
+func TestClientRotateKeysSuccess(t *testing.T) {
+	clientID := test.NewUUID()
+
+	req := contract.ClientRotateKeysRequest{ID: clientID}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("RotateKeys", mock.Anything, clientID).Return(nil)
+
+	expectedBody := `{"data":{"message":"client keys rotated successfully"},"success":true}`
+
+	testClientHandlerRotateKeys(t, http.StatusOK, expectedBody, bytes.NewBuffer(body), mockClientService)
+}
+
+func TestClientRotateKeysFailure(t *testing.T) {
+	clientID := test.NewUUID()
+
+	req := contract.ClientRotateKeysRequest{ID: clientID}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("RotateKeys", mock.Anything, clientID).
+		Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no client found")))
+
+	expectedBody := `{"error":{"message":"resource not found"},"success":false}`
+
+	testClientHandlerRotateKeys(t, http.StatusNotFound, expectedBody, bytes.NewBuffer(body), mockClientService)
+}
+
+func testClientHandlerRotateKeys(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service client.Service) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/client/rotate-keys", body)
+
+	ch := handler.NewClientHandler(service)
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), ch.RotateKeys)(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
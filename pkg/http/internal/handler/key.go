@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/base64"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/libcrypto"
+	"net/http"
+)
+
+const (
+	keyCacheControl = "public, max-age=300"
+
+	jwkKeyType   = "OKP"
+	jwkCurve     = "Ed25519"
+	jwkUse       = "sig"
+	jwkAlgorithm = "EdDSA"
+)
+
+type KeyHandler struct {
+	issuer  string
+	service client.Service
+}
+
+func (kh *KeyHandler) JWKS(resp http.ResponseWriter, req *http.Request) error {
+	keys, err := kh.service.GetVerificationKeys(req.Context())
+	if err != nil {
+		return erx.WithArgs(erx.Operation("KeyHandler.JWKS"), err)
+	}
+
+	respBody := contract.JWKSResponse{Keys: make([]contract.JWK, 0, len(keys))}
+
+	for _, key := range keys {
+		respBody.Keys = append(respBody.Keys, contract.JWK{
+			KeyType:   jwkKeyType,
+			Curve:     jwkCurve,
+			X:         base64.RawURLEncoding.EncodeToString(key.PublicKey),
+			KeyID:     key.KeyID,
+			Use:       jwkUse,
+			Algorithm: jwkAlgorithm,
+			Issuer:    kh.issuer,
+			ClientID:  key.ClientID,
+			Status:    string(key.State),
+			Paserk:    libcrypto.PaserkPublic(key.PublicKey),
+		})
+	}
+
+	resp.Header().Set("Cache-Control", keyCacheControl)
+	util.WriteJSONResponse(http.StatusOK, respBody, resp)
+	return nil
+}
+
+func (kh *KeyHandler) Paserk(resp http.ResponseWriter, req *http.Request) error {
+	keys, err := kh.service.GetVerificationKeys(req.Context())
+	if err != nil {
+		return erx.WithArgs(erx.Operation("KeyHandler.Paserk"), err)
+	}
+
+	respBody := contract.PaserkResponse{Keys: make([]contract.PaserkKey, 0, len(keys))}
+
+	for _, key := range keys {
+		respBody.Keys = append(respBody.Keys, contract.PaserkKey{
+			KeyID:    key.KeyID,
+			PaserkID: libcrypto.PaserkID(key.PublicKey),
+			Issuer:   kh.issuer,
+			ClientID: key.ClientID,
+			Status:   string(key.State),
+			Paserk:   libcrypto.PaserkPublic(key.PublicKey),
+		})
+	}
+
+	resp.Header().Set("Cache-Control", keyCacheControl)
+	util.WriteJSONResponse(http.StatusOK, respBody, resp)
+	return nil
+}
+
+func NewKeyHandler(issuer string, service client.Service) *KeyHandler {
+	return &KeyHandler{
+		issuer:  issuer,
+		service: service,
+	}
+}
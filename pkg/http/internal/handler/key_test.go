@@ -0,0 +1,103 @@
+package handler_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/client"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/libcrypto"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/test"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const keyIssuer = "identification-service"
+
+func TestKeyHandlerJWKSSuccess(t *testing.T) {
+	clientID, keyID := test.NewUUID(), test.NewUUID()
+	pub := test.ClientPubKey()
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKeys", mock.Anything).
+		Return([]client.VerificationKey{{KeyID: keyID, ClientID: clientID, State: libcrypto.ActiveKey, PublicKey: pub}}, nil)
+
+	expectedBody := fmt.Sprintf(
+		`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"%s","kid":"%s","use":"sig","alg":"EdDSA","iss":"%s","client_id":"%s","status":"active","paserk":"%s"}]}`,
+		base64.RawURLEncoding.EncodeToString(pub),
+		keyID,
+		keyIssuer,
+		clientID,
+		libcrypto.PaserkPublic(pub),
+	)
+
+	testKeyHandler(t, http.StatusOK, expectedBody, "/.well-known/jwks.json", mockClientService, jwksFunc)
+}
+
+func TestKeyHandlerJWKSFailure(t *testing.T) {
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKeys", mock.Anything).
+		Return([]client.VerificationKey{}, erx.WithArgs(errors.New("failed to get keys")))
+
+	expectedBody := `{"error":{"message":"internal server error"},"success":false}`
+
+	testKeyHandler(t, http.StatusInternalServerError, expectedBody, "/.well-known/jwks.json", mockClientService, jwksFunc)
+}
+
+func TestKeyHandlerPaserkSuccess(t *testing.T) {
+	clientID, keyID := test.NewUUID(), test.NewUUID()
+	pub := test.ClientPubKey()
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKeys", mock.Anything).
+		Return([]client.VerificationKey{{KeyID: keyID, ClientID: clientID, State: libcrypto.ActiveKey, PublicKey: pub}}, nil)
+
+	expectedBody := fmt.Sprintf(
+		`{"keys":[{"kid":"%s","pid":"%s","iss":"%s","client_id":"%s","status":"active","paserk":"%s"}]}`,
+		keyID,
+		libcrypto.PaserkID(pub),
+		keyIssuer,
+		clientID,
+		libcrypto.PaserkPublic(pub),
+	)
+
+	testKeyHandler(t, http.StatusOK, expectedBody, "/.well-known/paserk.json", mockClientService, paserkFunc)
+}
+
+func TestKeyHandlerPaserkSuccessWhenNoKeys(t *testing.T) {
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKeys", mock.Anything).
+		Return([]client.VerificationKey(nil), nil)
+
+	testKeyHandler(t, http.StatusOK, `{"keys":[]}`, "/.well-known/paserk.json", mockClientService, paserkFunc)
+}
+
+var jwksFunc = func(kh *handler.KeyHandler) func(http.ResponseWriter, *http.Request) error { return kh.JWKS }
+var paserkFunc = func(kh *handler.KeyHandler) func(http.ResponseWriter, *http.Request) error { return kh.Paserk }
+
+func testKeyHandler(
+	t *testing.T,
+	expectedCode int,
+	expectedBody string,
+	path string,
+	service client.Service,
+	fn func(kh *handler.KeyHandler) func(http.ResponseWriter, *http.Request) error,
+) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+
+	kh := handler.NewKeyHandler(keyIssuer, service)
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), fn(kh))(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
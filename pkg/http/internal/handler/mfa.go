@@ -0,0 +1,396 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/session"
+	"identification-service/pkg/user"
+	"identification-service/pkg/webauthn"
+	"net/http"
+	"strings"
+)
+
+type MFAHandler struct {
+	service        mfa.Service
+	sessionService session.Service
+	userService    user.Service
+}
+
+func (mh *MFAHandler) EnrollTOTP(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.EnrollTOTP"), err) }
+
+	cl, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if err := mh.stepUp(req, cl, u); err != nil {
+		return wrap(err)
+	}
+
+	enrollment, err := mh.service.EnrollTOTP(req.Context(), u.ID(), u.Email())
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.TOTPEnrollmentResponse{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusCreated, respData, resp)
+	return nil
+}
+
+func (mh *MFAHandler) ConfirmTOTP(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.ConfirmTOTP"), err) }
+
+	_, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	var data contract.ConfirmTOTPRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	codes, err := mh.service.ConfirmTOTP(req.Context(), u.ID(), data.Code)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.ConfirmTOTPResponse{
+		Message:       contract.TOTPConfirmationSuccess,
+		RecoveryCodes: codes,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (mh *MFAHandler) BeginWebAuthnRegistration(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.BeginWebAuthnRegistration"), err) }
+
+	cl, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if err := mh.stepUp(req, cl, u); err != nil {
+		return wrap(err)
+	}
+
+	challengeID, options, err := mh.service.BeginWebAuthnRegistration(req.Context(), u.ID(), u.Email(), u.Name())
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.BeginWebAuthnRegistrationResponse{
+		ChallengeID: challengeID,
+		PublicKey:   creationOptions(options),
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (mh *MFAHandler) FinishWebAuthnRegistration(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error {
+		return erx.WithArgs(erx.Operation("MFAHandler.FinishWebAuthnRegistration"), err)
+	}
+
+	_, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	var data contract.FinishWebAuthnRegistrationRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	var attestation webauthn.Attestation
+
+	err = decodeBinary(
+		binaryField{name: "client data json", value: data.ClientDataJSON, dst: &attestation.ClientDataJSON},
+		binaryField{name: "attestation object", value: data.AttestationObject, dst: &attestation.AttestationObject},
+	)
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	codes, err := mh.service.FinishWebAuthnRegistration(req.Context(), u.ID(), data.ChallengeID, attestation)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.FinishWebAuthnRegistrationResponse{
+		Message:       contract.WebAuthnRegistrationSuccess,
+		RecoveryCodes: codes,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusCreated, respData, resp)
+	return nil
+}
+
+func (mh *MFAHandler) RegenerateRecoveryCodes(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.RegenerateRecoveryCodes"), err) }
+
+	cl, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if err := mh.stepUp(req, cl, u); err != nil {
+		return wrap(err)
+	}
+
+	codes, err := mh.service.RegenerateRecoveryCodes(req.Context(), u.ID())
+	if err != nil {
+		return wrap(err)
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusCreated, contract.RecoveryCodesResponse{RecoveryCodes: codes}, resp)
+	return nil
+}
+
+func (mh *MFAHandler) RemainingRecoveryCodes(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.RemainingRecoveryCodes"), err) }
+
+	_, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	remaining, err := mh.service.RemainingRecoveryCodes(req.Context(), u.ID())
+	if err != nil {
+		return wrap(err)
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, contract.RemainingRecoveryCodesResponse{Remaining: remaining}, resp)
+	return nil
+}
+
+func (mh *MFAHandler) StepUp(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("MFAHandler.StepUp"), err) }
+
+	cl, u, err := mh.authenticatedUser(req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	enabled, err := mh.service.IsEnabled(req.Context(), u.ID())
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !enabled {
+		return wrap(erx.WithArgs(erx.ValidationError, errors.New("mfa not enabled")))
+	}
+
+	mfaToken, err := mh.service.Challenge(req.Context(), cl.Id, u.ID())
+	if err != nil {
+		return wrap(err)
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteSuccessResponse(http.StatusCreated, contract.MFAChallengeResponse{MFAToken: mfaToken}, resp)
+	return nil
+}
+
+func (mh *MFAHandler) authenticatedUser(req *http.Request) (client.Client, user.User, error) {
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return client.Client{}, user.User{}, err
+	}
+
+	accessToken, ok := bearerToken(req)
+	if !ok {
+		return client.Client{}, user.User{}, erx.WithArgs(erx.AuthenticationError, errors.New("bearer token is missing"))
+	}
+
+	in, err := mh.sessionService.IntrospectToken(req.Context(), accessToken)
+	if err != nil {
+		return client.Client{}, user.User{}, err
+	}
+
+	if !in.Active {
+		return client.Client{}, user.User{}, erx.WithArgs(erx.AuthenticationError, errors.New("access token is not active"))
+	}
+
+	//NOTE: FACTORS ARE ONLY MANAGED WITH THE USER'S OWN TOKEN FROM THE CALLING CLIENT, NEVER WITH ONE EXCHANGED TO ACT FOR THEM
+	if in.ClientID != cl.Id || in.TenantID != cl.TenantID {
+		return client.Client{}, user.User{}, erx.WithArgs(erx.AuthenticationError, fmt.Errorf("access token was not issued to client %s", cl.Name))
+	}
+
+	if len(in.Actor) != 0 {
+		return client.Client{}, user.User{}, erx.WithArgs(erx.AuthenticationError, errors.New("access token was issued to an actor"))
+	}
+
+	//NOTE: TOKENS ISSUED THROUGH CLIENT CREDENTIALS HAVE THE CLIENT AS SUBJECT, SO NO USER IS FOUND FOR THEM
+	u, err := mh.userService.GetUser(req.Context(), in.Claims.Subject)
+	if err != nil {
+		if isNotFound(err) {
+			return client.Client{}, user.User{}, erx.WithArgs(erx.AuthenticationError, err)
+		}
+
+		return client.Client{}, user.User{}, err
+	}
+
+	return cl, u, nil
+}
+
+func (mh *MFAHandler) stepUp(req *http.Request, cl client.Client, u user.User) error {
+	enabled, err := mh.service.IsEnabled(req.Context(), u.ID())
+	if err != nil {
+		return err
+	}
+
+	//NOTE: THE FIRST FACTOR IS ENROLLED WITH THE ACCESS TOKEN ALONE, ANY CHANGE AFTER IT NEEDS A FRESH SECOND FACTOR
+	if !enabled {
+		return nil
+	}
+
+	var data contract.StepUpRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return err
+	}
+
+	if err := data.IsValid(); err != nil {
+		return err
+	}
+
+	userID, err := mh.verifyStepUp(req, cl, data)
+	if err != nil {
+		return err
+	}
+
+	if userID != u.ID() {
+		return erx.WithArgs(erx.AuthenticationError, errors.New("second factor belongs to another user"))
+	}
+
+	return nil
+}
+
+func (mh *MFAHandler) verifyStepUp(req *http.Request, cl client.Client, data contract.StepUpRequest) (string, error) {
+	if len(data.ChallengeID) == 0 {
+		return mh.service.VerifyChallenge(req.Context(), cl.Id, data.MFAToken, data.Code)
+	}
+
+	var assertion webauthn.Assertion
+
+	err := decodeBinary(
+		binaryField{name: "credential id", value: data.CredentialID, dst: &assertion.CredentialID},
+		binaryField{name: "client data json", value: data.ClientDataJSON, dst: &assertion.ClientDataJSON},
+		binaryField{name: "authenticator data", value: data.AuthenticatorData, dst: &assertion.AuthenticatorData},
+		binaryField{name: "signature", value: data.Signature, dst: &assertion.Signature},
+		binaryField{name: "user handle", value: data.UserHandle, dst: &assertion.UserHandle},
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	return mh.service.FinishWebAuthnLogin(req.Context(), data.ChallengeID, assertion)
+}
+
+func isNotFound(err error) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == erx.ResourceNotFoundError
+}
+
+type binaryField struct {
+	name  string
+	value string
+	dst   *[]byte
+}
+
+func decodeBinary(fields ...binaryField) error {
+	for _, field := range fields {
+		//NOTE: BROWSERS ENCODE WEBAUTHN BINARY DATA AS BASE64URL, PADDING IS ACCEPTED BUT NOT REQUIRED
+		value, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(field.value, "="))
+		if err != nil {
+			return erx.WithArgs(erx.ValidationError, fmt.Errorf("%s is not valid base64url", field.name))
+		}
+
+		*field.dst = value
+	}
+
+	return nil
+}
+
+func creationOptions(options webauthn.CreationOptions) contract.WebAuthnCreationOptions {
+	parameters := make([]contract.WebAuthnCredentialParameter, 0, len(options.CredentialParameters))
+	for _, parameter := range options.CredentialParameters {
+		parameters = append(parameters, contract.WebAuthnCredentialParameter{Type: parameter.Type, Algorithm: parameter.Algorithm})
+	}
+
+	return contract.WebAuthnCreationOptions{
+		RelyingParty: contract.WebAuthnRelyingParty{
+			ID:   options.RelyingParty.ID,
+			Name: options.RelyingParty.Name,
+		},
+		User: contract.WebAuthnUser{
+			ID:          options.User.ID,
+			Name:        options.User.Name,
+			DisplayName: options.User.DisplayName,
+		},
+		Challenge:            options.Challenge,
+		CredentialParameters: parameters,
+		Timeout:              options.Timeout,
+		ExcludeCredentials:   credentialDescriptors(options.ExcludeCredentials),
+		AuthenticatorSelection: contract.WebAuthnAuthenticatorSelection{
+			ResidentKey:      options.AuthenticatorSelection.ResidentKey,
+			UserVerification: options.AuthenticatorSelection.UserVerification,
+		},
+		Attestation: options.Attestation,
+	}
+}
+
+func requestOptions(options webauthn.RequestOptions) contract.WebAuthnRequestOptions {
+	return contract.WebAuthnRequestOptions{
+		Challenge:        options.Challenge,
+		Timeout:          options.Timeout,
+		RelyingPartyID:   options.RelyingPartyID,
+		AllowCredentials: credentialDescriptors(options.AllowCredentials),
+		UserVerification: options.UserVerification,
+	}
+}
+
+func credentialDescriptors(descriptors []webauthn.CredentialDescriptor) []contract.WebAuthnCredentialDescriptor {
+	res := make([]contract.WebAuthnCredentialDescriptor, 0, len(descriptors))
+	for _, descriptor := range descriptors {
+		res = append(res, contract.WebAuthnCredentialDescriptor{Type: descriptor.Type, ID: descriptor.ID})
+	}
+
+	return res
+}
+
+func NewMFAHandler(service mfa.Service, sessionService session.Service, userService user.Service) *MFAHandler {
+	return &MFAHandler{
+		service:        service,
+		sessionService: sessionService,
+		userService:    userService,
+	}
+}
@@ -0,0 +1,500 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/client"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/password"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/session"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"identification-service/pkg/user"
+	"identification-service/pkg/webauthn"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnrollTOTPSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	enrollment := mfa.Enrollment{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", URI: "otpauth://totp/identification-service:" + userEmail}
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+	mockMFAService.On("EnrollTOTP", mock.Anything, userID, userEmail).Return(enrollment, nil)
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).EnrollTOTP, accessToken, nil)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	expectedBody := fmt.Sprintf(`{"data":{"secret":"%s","uri":"%s"},"success":true}`, enrollment.Secret, enrollment.URI)
+	assert.Equal(t, expectedBody, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestEnrollTOTPFailure(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, mfaToken := test.NewUUID(), test.NewEmail(), test.RandString(64)
+
+	introspection := func(in session.Introspection) session.Service {
+		mockSessionService := &session.MockService{}
+		mockSessionService.On("IntrospectToken", mock.Anything, accessToken).Return(in, nil)
+
+		return mockSessionService
+	}
+
+	testCases := map[string]struct {
+		accessToken  string
+		reqBody      interface{}
+		services     func() (mfa.Service, session.Service, user.Service)
+		expectedCode int
+	}{
+		"test failure when bearer token is missing": {
+			services: func() (mfa.Service, session.Service, user.Service) {
+				return &mfa.MockService{}, &session.MockService{}, &user.MockService{}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when access token is not active": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				return &mfa.MockService{}, introspection(session.Introspection{}), &user.MockService{}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when access token was issued to another client": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				in := session.Introspection{Active: true, ClientID: test.NewUUID(), TenantID: cl.TenantID, Claims: token.Claims{Subject: userID}}
+
+				return &mfa.MockService{}, introspection(in), &user.MockService{}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when access token belongs to another tenant": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				in := session.Introspection{Active: true, ClientID: cl.Id, TenantID: test.NewUUID(), Claims: token.Claims{Subject: userID}}
+
+				return &mfa.MockService{}, introspection(in), &user.MockService{}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when access token was exchanged to an actor": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				in := session.Introspection{Active: true, ClientID: cl.Id, TenantID: cl.TenantID, Actor: cl.Id, Claims: token.Claims{Subject: userID}}
+
+				return &mfa.MockService{}, introspection(in), &user.MockService{}
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when token subject is not a user": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				in := session.Introspection{Active: true, ClientID: cl.Id, TenantID: cl.TenantID, Claims: token.Claims{Subject: userID}}
+
+				mockUserService := &user.MockService{}
+				mockUserService.On("GetUser", mock.Anything, userID).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+
+				return &mfa.MockService{}, introspection(in), mockUserService
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when step up is missing": {
+			accessToken: accessToken,
+			services: func() (mfa.Service, session.Service, user.Service) {
+				sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+
+				return mockMFAService, sessionService, userService
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		"test failure when step up code is invalid": {
+			accessToken: accessToken,
+			reqBody:     contract.StepUpRequest{MFAToken: mfaToken, Code: "123456"},
+			services: func() (mfa.Service, session.Service, user.Service) {
+				sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+				mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, mfaToken, "123456").
+					Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid code")))
+
+				return mockMFAService, sessionService, userService
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when step up belongs to another user": {
+			accessToken: accessToken,
+			reqBody:     contract.StepUpRequest{MFAToken: mfaToken, Code: "123456"},
+			services: func() (mfa.Service, session.Service, user.Service) {
+				sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+				mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, mfaToken, "123456").Return(test.NewUUID(), nil)
+
+				return mockMFAService, sessionService, userService
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when totp is already enabled": {
+			accessToken: accessToken,
+			reqBody:     contract.StepUpRequest{MFAToken: mfaToken, Code: "123456"},
+			services: func() (mfa.Service, session.Service, user.Service) {
+				sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+				mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, mfaToken, "123456").Return(userID, nil)
+				mockMFAService.On("EnrollTOTP", mock.Anything, userID, userEmail).
+					Return(mfa.Enrollment{}, erx.WithArgs(erx.DuplicateRecordError, errors.New("totp already enabled")))
+
+				return mockMFAService, sessionService, userService
+			},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			w := testMFAHandler(t, cl, handler.NewMFAHandler(testCase.services()).EnrollTOTP, testCase.accessToken, testCase.reqBody)
+
+			assert.Equal(t, testCase.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestConfirmTOTPSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("ConfirmTOTP", mock.Anything, userID, "123456").Return([]string{"abcde-23456", "fghij-34567"}, nil)
+
+	reqBody := contract.ConfirmTOTPRequest{Code: "123456"}
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).ConfirmTOTP, accessToken, reqBody)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":{"message":"totp enabled successfully","recovery_codes":["abcde-23456","fghij-34567"]},"success":true}`, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestConfirmTOTPFailure(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	testCases := map[string]struct {
+		reqBody      contract.ConfirmTOTPRequest
+		mfaService   func() mfa.Service
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when code is empty": {
+			mfaService:   func() mfa.Service { return &mfa.MockService{} },
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"code cannot be empty"},"success":false}`,
+		},
+		"test failure when code is invalid": {
+			reqBody: contract.ConfirmTOTPRequest{Code: "123456"},
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("ConfirmTOTP", mock.Anything, userID, "123456").
+					Return([]string(nil), erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+
+				return mockMFAService
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+			w := testMFAHandler(t, cl, handler.NewMFAHandler(testCase.mfaService(), sessionService, userService).ConfirmTOTP, accessToken, testCase.reqBody)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestBeginWebAuthnRegistrationSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, challengeID, stepUpChallengeID := test.NewUUID(), test.NewEmail(), test.NewUUID(), test.NewUUID()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	options := webauthn.CreationOptions{
+		RelyingParty:         webauthn.RelyingPartyEntity{ID: test.WebAuthnRPID, Name: "app"},
+		User:                 webauthn.UserEntity{ID: "dXNlcg", Name: userEmail, DisplayName: "user"},
+		Challenge:            "AQID",
+		CredentialParameters: []webauthn.CredentialParameter{{Type: "public-key", Algorithm: webauthn.AlgorithmES256}},
+		Timeout:              300000,
+		ExcludeCredentials:   []webauthn.CredentialDescriptor{{Type: "public-key", ID: "BAUG"}},
+		AuthenticatorSelection: webauthn.AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}
+
+	assertion := webauthn.Assertion{
+		CredentialID:      []byte{0x04, 0x05, 0x06},
+		ClientDataJSON:    []byte(`{"type":"webauthn.get"}`),
+		AuthenticatorData: []byte{0x01, 0x02},
+		Signature:         []byte{0x03},
+		UserHandle:        []byte{},
+	}
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+	mockMFAService.On("FinishWebAuthnLogin", mock.Anything, stepUpChallengeID, assertion).Return(userID, nil)
+	mockMFAService.On("BeginWebAuthnRegistration", mock.Anything, userID, userEmail, mock.AnythingOfType("string")).Return(challengeID, options, nil)
+
+	reqBody := contract.StepUpRequest{
+		ChallengeID:       stepUpChallengeID,
+		CredentialID:      base64.RawURLEncoding.EncodeToString(assertion.CredentialID),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(assertion.ClientDataJSON),
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(assertion.AuthenticatorData),
+		Signature:         base64.RawURLEncoding.EncodeToString(assertion.Signature),
+	}
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).BeginWebAuthnRegistration, accessToken, reqBody)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(
+		`{"data":{"challenge_id":"%s","public_key":{"rp":{"id":"%s","name":"app"},"user":{"id":"dXNlcg","name":"%s","displayName":"user"},"challenge":"AQID","pubKeyCredParams":[{"type":"public-key","alg":-7}],"timeout":300000,"excludeCredentials":[{"type":"public-key","id":"BAUG"}],"authenticatorSelection":{"residentKey":"preferred","userVerification":"preferred"},"attestation":"none"}},"success":true}`,
+		challengeID,
+		test.WebAuthnRPID,
+		userEmail,
+	)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestFinishWebAuthnRegistrationSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, challengeID := test.NewUUID(), test.NewEmail(), test.NewUUID()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	attestation := webauthn.Attestation{ClientDataJSON: []byte(`{"type":"webauthn.create"}`), AttestationObject: []byte{0xa0}}
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("FinishWebAuthnRegistration", mock.Anything, userID, challengeID, attestation).Return([]string{"abcde-23456"}, nil)
+
+	reqBody := contract.FinishWebAuthnRegistrationRequest{
+		ChallengeID:       challengeID,
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(attestation.ClientDataJSON),
+		AttestationObject: base64.URLEncoding.EncodeToString(attestation.AttestationObject),
+	}
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).FinishWebAuthnRegistration, accessToken, reqBody)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"data":{"message":"passkey registered successfully","recovery_codes":["abcde-23456"]},"success":true}`, w.Body.String())
+}
+
+func TestFinishWebAuthnRegistrationFailure(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, challengeID := test.NewUUID(), test.NewEmail(), test.NewUUID()
+
+	testCases := map[string]struct {
+		reqBody      contract.FinishWebAuthnRegistrationRequest
+		mfaService   func() mfa.Service
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when attestation object is empty": {
+			reqBody:      contract.FinishWebAuthnRegistrationRequest{ChallengeID: challengeID, ClientDataJSON: "e30"},
+			mfaService:   func() mfa.Service { return &mfa.MockService{} },
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"attestation object cannot be empty"},"success":false}`,
+		},
+		"test failure when client data json is not base64url": {
+			reqBody:      contract.FinishWebAuthnRegistrationRequest{ChallengeID: challengeID, ClientDataJSON: "e30+/", AttestationObject: "oA"},
+			mfaService:   func() mfa.Service { return &mfa.MockService{} },
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"client data json is not valid base64url"},"success":false}`,
+		},
+		"test failure when attestation is invalid": {
+			reqBody: contract.FinishWebAuthnRegistrationRequest{ChallengeID: challengeID, ClientDataJSON: "e30", AttestationObject: "oA"},
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("FinishWebAuthnRegistration", mock.Anything, userID, challengeID, mock.AnythingOfType("webauthn.Attestation")).
+					Return([]string(nil), erx.WithArgs(erx.AuthenticationError, errors.New("challenge mismatch")))
+
+				return mockMFAService
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+			w := testMFAHandler(t, cl, handler.NewMFAHandler(testCase.mfaService(), sessionService, userService).FinishWebAuthnRegistration, accessToken, testCase.reqBody)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestRegenerateRecoveryCodesSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, mfaToken := test.NewUUID(), test.NewEmail(), test.RandString(64)
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+	mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, mfaToken, "fghij-34567").Return(userID, nil)
+	mockMFAService.On("RegenerateRecoveryCodes", mock.Anything, userID).Return([]string{"abcde-23456"}, nil)
+
+	reqBody := contract.StepUpRequest{MFAToken: mfaToken, Code: "fghij-34567"}
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).RegenerateRecoveryCodes, accessToken, reqBody)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, `{"data":{"recovery_codes":["abcde-23456"]},"success":true}`, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestRegenerateRecoveryCodesFailureWhenMFAIsNotEnabled(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+	mockMFAService.On("RegenerateRecoveryCodes", mock.Anything, userID).
+		Return([]string(nil), erx.WithArgs(erx.ValidationError, errors.New("mfa not enabled")))
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).RegenerateRecoveryCodes, accessToken, nil)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":{"message":"mfa not enabled"},"success":false}`, w.Body.String())
+}
+
+func TestRemainingRecoveryCodesSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("RemainingRecoveryCodes", mock.Anything, userID).Return(7, nil)
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).RemainingRecoveryCodes, accessToken, nil)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":{"remaining":7},"success":true}`, w.Body.String())
+}
+
+func TestRemainingRecoveryCodesFailureWhenBearerTokenIsMissing(t *testing.T) {
+	w := testMFAHandler(t, newOAuthClient(t), handler.NewMFAHandler(&mfa.MockService{}, &session.MockService{}, &user.MockService{}).RemainingRecoveryCodes, test.EmptyString, nil)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `{"error":{"message":"authentication failed"},"success":false}`, w.Body.String())
+}
+
+func TestStepUpSuccess(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail, mfaToken := test.NewUUID(), test.NewEmail(), test.RandString(64)
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+	mockMFAService.On("Challenge", mock.Anything, cl.Id, userID).Return(mfaToken, nil)
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).StepUp, accessToken, nil)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, fmt.Sprintf(`{"data":{"mfa_token":"%s"},"success":true}`, mfaToken), w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestStepUpFailureWhenMFAIsNotEnabled(t *testing.T) {
+	cl, accessToken := newOAuthClient(t), test.NewPasetoToken()
+	userID, userEmail := test.NewUUID(), test.NewEmail()
+
+	sessionService, userService := newMFAAuthServices(t, cl, accessToken, userID, userEmail)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+
+	w := testMFAHandler(t, cl, handler.NewMFAHandler(mockMFAService, sessionService, userService).StepUp, accessToken, nil)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"error":{"message":"mfa not enabled"},"success":false}`, w.Body.String())
+}
+
+func newMFAAuthServices(t *testing.T, cl client.Client, accessToken, userID, userEmail string) (session.Service, user.Service) {
+	u, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).
+		Return(session.Introspection{Active: true, ClientID: cl.Id, TenantID: cl.TenantID, Claims: token.Claims{Subject: userID}}, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(u, nil)
+
+	return mockSessionService, mockUserService
+}
+
+func testMFAHandler(t *testing.T, cl client.Client, handle func(resp http.ResponseWriter, req *http.Request) error, accessToken string, reqBody interface{}) *httptest.ResponseRecorder {
+	b, err := json.Marshal(reqBody)
+	require.NoError(t, err)
+
+	r, err := http.NewRequest(http.MethodPost, "/mfa/totp", bytes.NewBuffer(b))
+	require.NoError(t, err)
+
+	if len(accessToken) != 0 {
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	ctx, err := client.WithContext(r.Context(), cl)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	lgr := reporters.NewLogger("dev", "debug")
+	mdl.WithErrorHandler(lgr, handle)(w, r.WithContext(ctx))
+
+	return w
+}
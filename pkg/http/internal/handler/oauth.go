@@ -0,0 +1,502 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"html/template"
+	"identification-service/pkg/client"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/resperr"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/oauth"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	invalidCredentialsMessage = "invalid email or password"
+	invalidUserCodeMessage    = "invalid or expired code"
+	mfaRequiredMessage        = "enter the code from your authenticator app or a recovery code"
+	invalidMFACodeMessage     = "invalid authentication code"
+	bearerScheme              = "Bearer "
+)
+
+var loginTemplate = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Sign in</title></head>
+<body>
+<form method="post" action="/oauth/authorize">
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<input type="hidden" name="response_type" value="{{.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+<input type="hidden" name="state" value="{{.State}}">
+<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.CodeChallengeMethod}}">
+<input type="hidden" name="scope" value="{{.Scope}}">
+<input type="hidden" name="nonce" value="{{.Nonce}}">
+<label>Email <input type="email" name="email" required></label>
+<label>Password <input type="password" name="password" required></label>
+{{if .MFARequired}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label>Authentication code <input type="text" name="mfa_code" autocomplete="one-time-code" required></label>{{end}}
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
+
+var errorTemplate = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorization failed</title></head>
+<body><p>{{.}}</p></body>
+</html>
+`))
+
+var deviceTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Connect a device</title></head>
+<body>
+<form method="post" action="/oauth/device">
+{{if .Error}}<p>{{.Error}}</p>{{end}}
+<label>Code <input type="text" name="user_code" value="{{.UserCode}}" required></label>
+<label>Email <input type="email" name="email" required></label>
+<label>Password <input type="password" name="password" required></label>
+{{if .MFARequired}}<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label>Authentication code <input type="text" name="mfa_code" autocomplete="one-time-code" required></label>{{end}}
+<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+`))
+
+var deviceResultTemplate = template.Must(template.New("device-result").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Connect a device</title></head>
+<body><p>{{if .}}Device approved, you can return to your device.{{else}}Device denied.{{end}}</p></body>
+</html>
+`))
+
+type deviceForm struct {
+	UserCode    string
+	MFARequired bool
+	MFAToken    string
+	Error       string
+}
+
+type loginForm struct {
+	oauth.AuthorizationRequest
+	MFARequired bool
+	MFAToken    string
+	Error       string
+}
+
+type OAuthHandler struct {
+	issuer  string
+	service oauth.Service
+}
+
+func (oh *OAuthHandler) AuthorizePage(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.AuthorizePage"), err) }
+
+	ar := toAuthorizationRequest(parseAuthorizeRequest(req.URL.Query()))
+
+	if err := oh.service.ValidateAuthorizationRequest(req.Context(), ar); err != nil {
+		if err := writeAuthorizationError(resp, req, ar, err); err != nil {
+			return wrap(err)
+		}
+
+		return nil
+	}
+
+	if err := writeHTML(resp, http.StatusOK, loginTemplate, loginForm{AuthorizationRequest: ar}); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (oh *OAuthHandler) Authorize(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.Authorize"), err) }
+
+	if err := util.ParseForm(req); err != nil {
+		return wrap(err)
+	}
+
+	data := parseAuthorizeRequest(req.PostForm)
+	ar := toAuthorizationRequest(data)
+
+	code, mfaToken, err := oh.service.Authorize(req.Context(), ar, data.Email, data.Password, data.MFAToken, data.MFACode)
+	if err != nil {
+		t, ok := err.(*erx.Erx)
+		if ok && t.Kind() == erx.InvalidCredentialsError {
+			err = writeHTML(resp, http.StatusUnauthorized, loginTemplate, loginForm{AuthorizationRequest: ar, MFARequired: len(data.MFAToken) != 0, MFAToken: data.MFAToken, Error: invalidCredentialsMessage})
+		} else if ok && t.Kind() == oauth.MFARequiredError {
+			err = writeHTML(resp, http.StatusUnauthorized, loginTemplate, loginForm{AuthorizationRequest: ar, MFARequired: true, MFAToken: mfaToken, Error: mfaMessage(data.MFAToken, data.MFACode)})
+		} else {
+			err = writeAuthorizationError(resp, req, ar, err)
+		}
+
+		if err != nil {
+			return wrap(err)
+		}
+
+		return nil
+	}
+
+	redirect(resp, req, ar, url.Values{"code": {code}})
+	return nil
+}
+
+func (oh *OAuthHandler) Token(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.Token"), err) }
+
+	if err := util.ParseForm(req); err != nil {
+		return wrap(err)
+	}
+
+	data := parseOAuthTokenRequest(req)
+
+	if !isSupportedGrantType(data.GrantType) {
+		return wrap(erx.WithArgs(oauth.UnsupportedGrantTypeError, fmt.Errorf("unsupported grant type %s", data.GrantType)))
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	cl, err := oh.service.AuthenticateClient(req.Context(), data.ClientID, data.ClientSecret)
+	if err != nil {
+		return wrap(err)
+	}
+
+	ctx, err := client.WithContext(req.Context(), cl)
+	if err != nil {
+		return wrap(err)
+	}
+
+	var tk oauth.Token
+
+	switch data.GrantType {
+	case contract.GrantTypeAuthorizationCode:
+		tk, err = oh.service.ExchangeAuthorizationCode(ctx, data.Code, data.RedirectURI, data.CodeVerifier)
+	case contract.GrantTypeRefreshToken:
+		tk, err = oh.service.RefreshToken(withMailOrigin(req.WithContext(ctx), cl), data.RefreshToken)
+	case contract.GrantTypeClientCredentials:
+		tk, err = oh.service.ClientCredentials(ctx, strings.Fields(data.Scope))
+	case contract.GrantTypeDeviceCode:
+		tk, err = oh.service.DeviceToken(ctx, data.DeviceCode)
+	case contract.GrantTypeTokenExchange:
+		tk, err = oh.service.ExchangeToken(ctx, data.SubjectToken, data.SubjectTokenType, data.Audience, strings.Fields(data.Scope))
+	}
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.OAuthTokenResponse{
+		AccessToken:     tk.AccessToken,
+		TokenType:       tk.TokenType,
+		ExpiresIn:       tk.ExpiresIn,
+		RefreshToken:    tk.RefreshToken,
+		Scope:           tk.Scope,
+		IDToken:         tk.IDToken,
+		IssuedTokenType: tk.IssuedTokenType,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.Header().Set("Pragma", "no-cache")
+	util.WriteJSONResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (oh *OAuthHandler) DeviceAuthorization(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.DeviceAuthorization"), err) }
+
+	if err := util.ParseForm(req); err != nil {
+		return wrap(err)
+	}
+
+	clientID, clientSecret := parseClientCredentials(req)
+	data := contract.DeviceAuthorizationRequest{Scope: req.PostForm.Get("scope"), ClientID: clientID, ClientSecret: clientSecret}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	cl, err := oh.service.AuthenticateClient(req.Context(), data.ClientID, data.ClientSecret)
+	if err != nil {
+		return wrap(err)
+	}
+
+	ctx, err := client.WithContext(req.Context(), cl)
+	if err != nil {
+		return wrap(err)
+	}
+
+	da, err := oh.service.AuthorizeDevice(ctx, data.Scope)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.DeviceAuthorizationResponse{
+		DeviceCode:              da.DeviceCode,
+		UserCode:                da.UserCode,
+		VerificationURI:         da.VerificationURI,
+		VerificationURIComplete: da.VerificationURIComplete,
+		ExpiresIn:               da.ExpiresIn,
+		Interval:                da.Interval,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteJSONResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (oh *OAuthHandler) DevicePage(resp http.ResponseWriter, req *http.Request) error {
+	if err := writeHTML(resp, http.StatusOK, deviceTemplate, deviceForm{UserCode: req.URL.Query().Get("user_code")}); err != nil {
+		return erx.WithArgs(erx.Operation("OAuthHandler.DevicePage"), err)
+	}
+
+	return nil
+}
+
+func (oh *OAuthHandler) VerifyDevice(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.VerifyDevice"), err) }
+
+	if err := util.ParseForm(req); err != nil {
+		return wrap(err)
+	}
+
+	data := contract.DeviceVerificationRequest{
+		UserCode: req.PostForm.Get("user_code"),
+		Email:    req.PostForm.Get("email"),
+		Password: req.PostForm.Get("password"),
+		MFAToken: req.PostForm.Get("mfa_token"),
+		MFACode:  req.PostForm.Get("mfa_code"),
+		Approved: req.PostForm.Get("action") == "approve",
+	}
+
+	mfaToken, err := oh.service.VerifyDevice(req.Context(), data.UserCode, data.Email, data.Password, data.MFAToken, data.MFACode, data.Approved)
+	if err != nil {
+		t, ok := err.(*erx.Erx)
+		if !ok {
+			return wrap(err)
+		}
+
+		switch t.Kind() {
+		case erx.InvalidCredentialsError:
+			err = writeHTML(resp, http.StatusUnauthorized, deviceTemplate, deviceForm{UserCode: data.UserCode, MFARequired: len(data.MFAToken) != 0, MFAToken: data.MFAToken, Error: invalidCredentialsMessage})
+		case oauth.MFARequiredError:
+			err = writeHTML(resp, http.StatusUnauthorized, deviceTemplate, deviceForm{UserCode: data.UserCode, MFARequired: true, MFAToken: mfaToken, Error: mfaMessage(data.MFAToken, data.MFACode)})
+		case oauth.InvalidGrantError:
+			err = writeHTML(resp, http.StatusBadRequest, deviceTemplate, deviceForm{Error: invalidUserCodeMessage})
+		default:
+			return wrap(err)
+		}
+
+		if err != nil {
+			return wrap(err)
+		}
+
+		return nil
+	}
+
+	if err := writeHTML(resp, http.StatusOK, deviceResultTemplate, data.Approved); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (oh *OAuthHandler) UserInfo(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("OAuthHandler.UserInfo"), err) }
+
+	accessToken, ok := bearerToken(req)
+	if !ok {
+		return wrap(erx.WithArgs(oauth.InvalidTokenError, errors.New("bearer token is missing")))
+	}
+
+	ui, err := oh.service.UserInfo(req.Context(), accessToken)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.UserInfoResponse{
+		Subject: ui.Subject,
+		Email:   ui.Email,
+		Name:    ui.Name,
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteJSONResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (oh *OAuthHandler) OpenIDConfiguration(resp http.ResponseWriter, req *http.Request) error {
+	respData := contract.OpenIDConfigurationResponse{
+		Issuer:                            oh.issuer,
+		AuthorizationEndpoint:             oh.issuer + "/oauth/authorize",
+		TokenEndpoint:                     oh.issuer + "/oauth/token",
+		UserInfoEndpoint:                  oh.issuer + "/userinfo",
+		DeviceAuthorizationEndpoint:       oh.issuer + "/oauth/device/code",
+		JWKSURI:                           oh.issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   []string{oauth.ScopeOpenID, oauth.ScopeEmail, oauth.ScopeProfile},
+		ResponseTypesSupported:            []string{oauth.ResponseTypeCode},
+		GrantTypesSupported:               []string{contract.GrantTypeAuthorizationCode, contract.GrantTypeRefreshToken, contract.GrantTypeClientCredentials, contract.GrantTypeDeviceCode, contract.GrantTypeTokenExchange},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwkAlgorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oauth.CodeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "name"},
+	}
+
+	resp.Header().Set("Cache-Control", keyCacheControl)
+	util.WriteJSONResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) <= len(bearerScheme) || !strings.EqualFold(header[:len(bearerScheme)], bearerScheme) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(bearerScheme):]), true
+}
+
+func isSupportedGrantType(grantType string) bool {
+	switch grantType {
+	case contract.GrantTypeAuthorizationCode, contract.GrantTypeRefreshToken, contract.GrantTypeClientCredentials, contract.GrantTypeDeviceCode, contract.GrantTypeTokenExchange:
+		return true
+	default:
+		return false
+	}
+}
+
+func parseAuthorizeRequest(values url.Values) contract.AuthorizeRequest {
+	return contract.AuthorizeRequest{
+		ResponseType:        values.Get("response_type"),
+		ClientID:            values.Get("client_id"),
+		RedirectURI:         values.Get("redirect_uri"),
+		State:               values.Get("state"),
+		CodeChallenge:       values.Get("code_challenge"),
+		CodeChallengeMethod: values.Get("code_challenge_method"),
+		Scope:               values.Get("scope"),
+		Nonce:               values.Get("nonce"),
+		Email:               values.Get("email"),
+		Password:            values.Get("password"),
+		MFAToken:            values.Get("mfa_token"),
+		MFACode:             values.Get("mfa_code"),
+	}
+}
+
+func mfaMessage(mfaToken, mfaCode string) string {
+	//NOTE: A CODE SENT WITHOUT THE CHALLENGE OF THE FORM IS NOT CHECKED, A NEW CHALLENGE IS ISSUED INSTEAD
+	if len(mfaToken) == 0 || len(mfaCode) == 0 {
+		return mfaRequiredMessage
+	}
+
+	return invalidMFACodeMessage
+}
+
+func toAuthorizationRequest(data contract.AuthorizeRequest) oauth.AuthorizationRequest {
+	return oauth.AuthorizationRequest{
+		ResponseType:        data.ResponseType,
+		ClientID:            data.ClientID,
+		RedirectURI:         data.RedirectURI,
+		State:               data.State,
+		CodeChallenge:       data.CodeChallenge,
+		CodeChallengeMethod: data.CodeChallengeMethod,
+		Scope:               data.Scope,
+		Nonce:               data.Nonce,
+	}
+}
+
+func parseOAuthTokenRequest(req *http.Request) contract.OAuthTokenRequest {
+	data := contract.OAuthTokenRequest{
+		GrantType:        req.PostForm.Get("grant_type"),
+		Code:             req.PostForm.Get("code"),
+		RedirectURI:      req.PostForm.Get("redirect_uri"),
+		CodeVerifier:     req.PostForm.Get("code_verifier"),
+		RefreshToken:     req.PostForm.Get("refresh_token"),
+		DeviceCode:       req.PostForm.Get("device_code"),
+		SubjectToken:     req.PostForm.Get("subject_token"),
+		SubjectTokenType: req.PostForm.Get("subject_token_type"),
+		Audience:         req.PostForm.Get("audience"),
+		Scope:            req.PostForm.Get("scope"),
+	}
+
+	data.ClientID, data.ClientSecret = parseClientCredentials(req)
+
+	return data
+}
+
+func parseClientCredentials(req *http.Request) (string, string) {
+	//NOTE: CREDENTIALS IN THE AUTHORIZATION HEADER ARE FORM ENCODED BEFORE BEING BASE64 ENCODED AS PER RFC 6749
+	if clientID, clientSecret, ok := req.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+
+		return clientID, clientSecret
+	}
+
+	return req.PostForm.Get("client_id"), req.PostForm.Get("client_secret")
+}
+
+func writeAuthorizationError(resp http.ResponseWriter, req *http.Request, ar oauth.AuthorizationRequest, err error) error {
+	t, ok := err.(*erx.Erx)
+	if !ok {
+		return err
+	}
+
+	switch t.Kind() {
+	case oauth.InvalidClientError, oauth.InvalidRedirectURIError:
+		//NOTE: THE REDIRECT URI CANNOT BE TRUSTED, SO THE ERROR IS SHOWN TO THE USER INSTEAD OF BEING REDIRECTED
+		return writeHTML(resp, http.StatusBadRequest, errorTemplate, "invalid client or redirect uri")
+	case oauth.InvalidRequestError, oauth.UnsupportedResponseTypeError, oauth.InvalidScopeError:
+		oe := resperr.MapOAuthError(err)
+		redirect(resp, req, ar, url.Values{"error": {oe.Code()}, "error_description": {oe.Description()}})
+		return nil
+	default:
+		return err
+	}
+}
+
+func redirect(resp http.ResponseWriter, req *http.Request, ar oauth.AuthorizationRequest, params url.Values) {
+	u, err := url.Parse(ar.RedirectURI)
+	if err != nil {
+		_ = writeHTML(resp, http.StatusBadRequest, errorTemplate, "invalid client or redirect uri")
+		return
+	}
+
+	query := u.Query()
+	for key := range params {
+		query.Set(key, params.Get(key))
+	}
+
+	if len(ar.State) != 0 {
+		query.Set("state", ar.State)
+	}
+
+	u.RawQuery = query.Encode()
+
+	http.Redirect(resp, req, u.String(), http.StatusFound)
+}
+
+func writeHTML(resp http.ResponseWriter, statusCode int, tmpl *template.Template, data interface{}) error {
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.WriteHeader(statusCode)
+
+	return tmpl.Execute(resp, data)
+}
+
+func NewOAuthHandler(issuer string, service oauth.Service) *OAuthHandler {
+	return &OAuthHandler{
+		issuer:  issuer,
+		service: service,
+	}
+}
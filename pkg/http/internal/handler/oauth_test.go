@@ -0,0 +1,866 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/oauth"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/test"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const oauthIssuer = "https://id.example.com"
+
+func newAuthorizeValues() url.Values {
+	return url.Values{
+		"response_type":         {oauth.ResponseTypeCode},
+		"client_id":             {test.RandString(8)},
+		"redirect_uri":          {test.ClientRedirectURI},
+		"state":                 {test.RandString(8)},
+		"code_challenge":        {oauth.NewCodeChallenge(test.RandString(43))},
+		"code_challenge_method": {oauth.CodeChallengeMethodS256},
+	}
+}
+
+func TestAuthorizePageSuccess(t *testing.T) {
+	values := newAuthorizeValues()
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("ValidateAuthorizationRequest", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest")).Return(nil)
+
+	r, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+values.Encode(), nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, mockOAuthService)
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.AuthorizePage)(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Contains(t, w.Body.String(), `<form method="post" action="/oauth/authorize">`)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`name="state" value="%s"`, values.Get("state")))
+}
+
+func TestAuthorizePageFailure(t *testing.T) {
+	values := newAuthorizeValues()
+
+	testCases := map[string]struct {
+		err            error
+		expectedCode   int
+		expectedResult func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		"test failure when client is invalid": {
+			err:          erx.WithArgs(oauth.InvalidClientError, errors.New("client not found")),
+			expectedCode: http.StatusBadRequest,
+			expectedResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Contains(t, w.Body.String(), "invalid client or redirect uri")
+				assert.Empty(t, w.Header().Get("Location"))
+			},
+		},
+		"test failure when redirect uri is not registered": {
+			err:          erx.WithArgs(oauth.InvalidRedirectURIError, errors.New("redirect uri not registered")),
+			expectedCode: http.StatusBadRequest,
+			expectedResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Empty(t, w.Header().Get("Location"))
+			},
+		},
+		"test failure is redirected when request is invalid": {
+			err:          erx.WithArgs(oauth.InvalidRequestError, errors.New("code challenge method must be S256")),
+			expectedCode: http.StatusFound,
+			expectedResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				u, err := url.Parse(w.Header().Get("Location"))
+				require.NoError(t, err)
+
+				assert.Equal(t, "invalid_request", u.Query().Get("error"))
+				assert.Equal(t, "code challenge method must be S256", u.Query().Get("error_description"))
+				assert.Equal(t, values.Get("state"), u.Query().Get("state"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockOAuthService := &oauth.MockService{}
+			mockOAuthService.On("ValidateAuthorizationRequest", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest")).Return(testCase.err)
+
+			r, err := http.NewRequest(http.MethodGet, "/oauth/authorize?"+values.Encode(), nil)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+
+			oh := handler.NewOAuthHandler(oauthIssuer, mockOAuthService)
+			mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.AuthorizePage)(w, r)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			testCase.expectedResult(t, w)
+		})
+	}
+}
+
+func TestAuthorizeSuccess(t *testing.T) {
+	email, password, code := test.NewEmail(), test.NewPassword(), test.RandString(43)
+
+	values := newAuthorizeValues()
+	values.Set("email", email)
+	values.Set("password", password)
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("Authorize", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest"), email, password, "", "").Return(code, "", nil)
+
+	w := testAuthorize(t, mockOAuthService, values)
+
+	require.Equal(t, http.StatusFound, w.Code)
+
+	u, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+
+	assert.Equal(t, test.ClientRedirectURI, fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, u.Path))
+	assert.Equal(t, code, u.Query().Get("code"))
+	assert.Equal(t, values.Get("state"), u.Query().Get("state"))
+}
+
+func TestAuthorizeFailureWhenCredentialsAreInvalid(t *testing.T) {
+	email, password := test.NewEmail(), test.NewPassword()
+
+	values := newAuthorizeValues()
+	values.Set("email", email)
+	values.Set("password", password)
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("Authorize", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest"), email, password, "", "").
+		Return("", "", erx.WithArgs(erx.InvalidCredentialsError, errors.New("invalid credentials")))
+
+	w := testAuthorize(t, mockOAuthService, values)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Contains(t, w.Body.String(), "invalid email or password")
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestAuthorizeFailureWhenSecondFactorIsRequired(t *testing.T) {
+	email, password, mfaToken := test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	values := newAuthorizeValues()
+	values.Set("email", email)
+	values.Set("password", password)
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("Authorize", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest"), email, password, "", "").
+		Return("", mfaToken, erx.WithArgs(oauth.MFARequiredError, errors.New("second factor required")))
+
+	w := testAuthorize(t, mockOAuthService, values)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Contains(t, w.Body.String(), "enter the code from your authenticator app or a recovery code")
+	assert.Contains(t, w.Body.String(), `name="mfa_code"`)
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`name="mfa_token" value="%s"`, mfaToken))
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestAuthorizeFailureWhenSecondFactorIsInvalid(t *testing.T) {
+	email, password, mfaToken := test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	values := newAuthorizeValues()
+	values.Set("email", email)
+	values.Set("password", password)
+	values.Set("mfa_token", mfaToken)
+	values.Set("mfa_code", "000000")
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("Authorize", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest"), email, password, mfaToken, "000000").
+		Return("", mfaToken, erx.WithArgs(oauth.MFARequiredError, errors.New("invalid totp code")))
+
+	w := testAuthorize(t, mockOAuthService, values)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.Contains(t, w.Body.String(), "invalid authentication code")
+	assert.Contains(t, w.Body.String(), fmt.Sprintf(`name="mfa_token" value="%s"`, mfaToken))
+}
+
+func TestAuthorizeFailureWhenServiceCallFails(t *testing.T) {
+	email, password := test.NewEmail(), test.NewPassword()
+
+	values := newAuthorizeValues()
+	values.Set("email", email)
+	values.Set("password", password)
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("Authorize", mock.Anything, mock.AnythingOfType("oauth.AuthorizationRequest"), email, password, "", "").
+		Return("", "", erx.WithArgs(errors.New("failed to create code")))
+
+	w := testAuthorize(t, mockOAuthService, values)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, `{"error":{"message":"internal server error"},"success":false}`, w.Body.String())
+}
+
+func testAuthorize(t *testing.T, oauthService oauth.Service, values url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(values.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, oauthService)
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.Authorize)(w, r)
+
+	return w
+}
+
+func TestOAuthTokenSuccess(t *testing.T) {
+	cl := newOAuthClient(t)
+	clientID, clientSecret := cl.Name, test.NewUUID()
+	code, codeVerifier := test.RandString(43), test.RandString(43)
+	accessToken, refreshToken := test.NewPasetoToken(), test.NewRefreshToken()
+
+	tk := oauth.Token{AccessToken: accessToken, RefreshToken: refreshToken, TokenType: oauth.TokenTypeBearer, ExpiresIn: 600}
+
+	testCases := map[string]struct {
+		values func() url.Values
+		setup  func(r *http.Request, mockOAuthService *oauth.MockService)
+	}{
+		"test authorization code grant for confidential client": {
+			values: func() url.Values {
+				return url.Values{
+					"grant_type":    {"authorization_code"},
+					"code":          {code},
+					"redirect_uri":  {test.ClientRedirectURI},
+					"code_verifier": {codeVerifier},
+				}
+			},
+			setup: func(r *http.Request, mockOAuthService *oauth.MockService) {
+				r.SetBasicAuth(clientID, clientSecret)
+
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("ExchangeAuthorizationCode", mock.Anything, code, test.ClientRedirectURI, codeVerifier).Return(tk, nil)
+			},
+		},
+		"test authorization code grant for public client": {
+			values: func() url.Values {
+				return url.Values{
+					"grant_type":    {"authorization_code"},
+					"code":          {code},
+					"redirect_uri":  {test.ClientRedirectURI},
+					"code_verifier": {codeVerifier},
+					"client_id":     {clientID},
+				}
+			},
+			setup: func(r *http.Request, mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, "").Return(cl, nil)
+				mockOAuthService.On("ExchangeAuthorizationCode", mock.Anything, code, test.ClientRedirectURI, codeVerifier).Return(tk, nil)
+			},
+		},
+		"test refresh token grant": {
+			values: func() url.Values {
+				return url.Values{
+					"grant_type":    {"refresh_token"},
+					"refresh_token": {refreshToken},
+				}
+			},
+			setup: func(r *http.Request, mockOAuthService *oauth.MockService) {
+				r.SetBasicAuth(clientID, clientSecret)
+				r.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+
+				hasClientAndOrigin := mock.MatchedBy(func(ctx context.Context) bool {
+					reqClient, err := client.FromContext(ctx)
+					return err == nil && reqClient.Id == cl.Id && mailer.OriginFromContext(ctx) == mailer.Origin{ClientID: cl.Id, Locale: "pt-br"}
+				})
+
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("RefreshToken", hasClientAndOrigin, refreshToken).Return(tk, nil)
+			},
+		},
+		"test device code grant": {
+			values: func() url.Values {
+				return url.Values{
+					"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+					"device_code": {code},
+					"client_id":   {clientID},
+				}
+			},
+			setup: func(r *http.Request, mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, "").Return(cl, nil)
+				mockOAuthService.On("DeviceToken", mock.Anything, code).Return(tk, nil)
+			},
+		},
+	}
+
+	expectedBody := fmt.Sprintf(
+		`{"access_token":"%s","token_type":"Bearer","expires_in":600,"refresh_token":"%s"}`,
+		accessToken,
+		refreshToken,
+	)
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(testCase.values().Encode()))
+			require.NoError(t, err)
+
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			mockOAuthService := &oauth.MockService{}
+			testCase.setup(r, mockOAuthService)
+
+			w := testOAuthToken(mockOAuthService, r)
+
+			require.Equal(t, http.StatusOK, w.Code)
+
+			assert.Equal(t, expectedBody, w.Body.String())
+			assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestOAuthTokenSuccessForClientCredentials(t *testing.T) {
+	cl := newOAuthClient(t)
+	clientSecret, accessToken := test.NewUUID(), test.NewPasetoToken()
+
+	values := url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:read orders:write"}}
+
+	r, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(values.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(cl.Name, clientSecret)
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("AuthenticateClient", mock.Anything, cl.Name, clientSecret).Return(cl, nil)
+	mockOAuthService.On("ClientCredentials", mock.Anything, []string{"orders:read", "orders:write"}).
+		Return(oauth.Token{AccessToken: accessToken, TokenType: oauth.TokenTypeBearer, ExpiresIn: 600, Scope: "orders:read orders:write"}, nil)
+
+	w := testOAuthToken(mockOAuthService, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(`{"access_token":"%s","token_type":"Bearer","expires_in":600,"scope":"orders:read orders:write"}`, accessToken)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestOAuthTokenSuccessForTokenExchange(t *testing.T) {
+	cl := newOAuthClient(t)
+	clientSecret, subjectToken, accessToken := test.NewUUID(), test.NewPasetoToken(), test.NewPasetoToken()
+
+	values := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.TokenTypeAccessToken},
+		"audience":           {test.ClientAudience},
+		"scope":              {test.ClientScope},
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(values.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(cl.Name, clientSecret)
+
+	tk := oauth.Token{
+		AccessToken:     accessToken,
+		TokenType:       oauth.TokenTypeBearer,
+		ExpiresIn:       600,
+		Scope:           test.ClientScope,
+		IssuedTokenType: oauth.TokenTypeAccessToken,
+	}
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("AuthenticateClient", mock.Anything, cl.Name, clientSecret).Return(cl, nil)
+	mockOAuthService.On("ExchangeToken", mock.Anything, subjectToken, oauth.TokenTypeAccessToken, test.ClientAudience, []string{test.ClientScope}).Return(tk, nil)
+
+	w := testOAuthToken(mockOAuthService, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(
+		`{"access_token":"%s","token_type":"Bearer","expires_in":600,"scope":"%s","issued_token_type":"%s"}`,
+		accessToken,
+		test.ClientScope,
+		oauth.TokenTypeAccessToken,
+	)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestOAuthTokenFailure(t *testing.T) {
+	cl := newOAuthClient(t)
+	clientID, clientSecret, code := cl.Name, test.NewUUID(), test.RandString(43)
+
+	testCases := map[string]struct {
+		values       url.Values
+		public       bool
+		setup        func(mockOAuthService *oauth.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when grant type is not supported": {
+			values:       url.Values{"grant_type": {"password"}},
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"unsupported_grant_type","error_description":"unsupported grant type password"}`,
+		},
+		"test failure when code is missing": {
+			values:       url.Values{"grant_type": {"authorization_code"}, "redirect_uri": {test.ClientRedirectURI}, "code_verifier": {test.RandString(43)}},
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"code cannot be empty"}`,
+		},
+		"test failure when public client uses client credentials": {
+			values:       url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}},
+			public:       true,
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"client secret cannot be empty"}`,
+		},
+		"test failure when scope is not allowed": {
+			values: url.Values{"grant_type": {"client_credentials"}, "scope": {"orders:write"}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("ClientCredentials", mock.Anything, []string{"orders:write"}).
+					Return(oauth.Token{}, erx.WithArgs(oauth.InvalidScopeError, errors.New("scope not allowed")))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_scope","error_description":"scope not allowed"}`,
+		},
+		"test failure when client authentication fails": {
+			values: url.Values{"grant_type": {"refresh_token"}, "refresh_token": {test.NewRefreshToken()}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).
+					Return(client.Client{}, erx.WithArgs(oauth.InvalidClientError, errors.New("invalid credentials")))
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+		"test failure when code is invalid": {
+			values: url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {test.ClientRedirectURI}, "code_verifier": {test.RandString(43)}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("ExchangeAuthorizationCode", mock.Anything, code, test.ClientRedirectURI, mock.Anything).
+					Return(oauth.Token{}, erx.WithArgs(oauth.InvalidGrantError, errors.New("code has expired")))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_grant","error_description":"code has expired"}`,
+		},
+		"test failure when audience is missing for token exchange": {
+			values:       url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:token-exchange"}, "subject_token": {test.NewPasetoToken()}, "subject_token_type": {oauth.TokenTypeAccessToken}},
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"audience cannot be empty"}`,
+		},
+		"test failure when audience is not allowed for token exchange": {
+			values: url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:token-exchange"}, "subject_token": {code}, "subject_token_type": {oauth.TokenTypeAccessToken}, "audience": {"inventory"}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("ExchangeToken", mock.Anything, code, oauth.TokenTypeAccessToken, "inventory", []string{}).
+					Return(oauth.Token{}, erx.WithArgs(oauth.InvalidTargetError, errors.New("audience not allowed")))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_target","error_description":"audience not allowed"}`,
+		},
+		"test failure when device is not approved yet": {
+			values: url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:device_code"}, "device_code": {code}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, clientID, clientSecret).Return(cl, nil)
+				mockOAuthService.On("DeviceToken", mock.Anything, code).
+					Return(oauth.Token{}, erx.WithArgs(oauth.AuthorizationPendingError, errors.New("user has not approved the device yet")))
+			},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"authorization_pending","error_description":"user has not approved the device yet"}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(testCase.values.Encode()))
+			require.NoError(t, err)
+
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if !testCase.public {
+				r.SetBasicAuth(clientID, clientSecret)
+			}
+
+			mockOAuthService := &oauth.MockService{}
+			testCase.setup(mockOAuthService)
+
+			w := testOAuthToken(mockOAuthService, r)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestOAuthTokenSuccessWithIDToken(t *testing.T) {
+	cl := newOAuthClient(t)
+	code, codeVerifier := test.RandString(43), test.RandString(43)
+	accessToken, refreshToken, idToken := test.NewPasetoToken(), test.NewRefreshToken(), test.RandString(64)
+
+	values := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {test.ClientRedirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {cl.Name},
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(values.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	tk := oauth.Token{AccessToken: accessToken, RefreshToken: refreshToken, TokenType: oauth.TokenTypeBearer, ExpiresIn: 600, IDToken: idToken}
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("AuthenticateClient", mock.Anything, cl.Name, "").Return(cl, nil)
+	mockOAuthService.On("ExchangeAuthorizationCode", mock.Anything, code, test.ClientRedirectURI, codeVerifier).Return(tk, nil)
+
+	w := testOAuthToken(mockOAuthService, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(
+		`{"access_token":"%s","token_type":"Bearer","expires_in":600,"refresh_token":"%s","id_token":"%s"}`,
+		accessToken,
+		refreshToken,
+		idToken,
+	)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestUserInfoSuccess(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+
+	r, err := http.NewRequest(http.MethodGet, "/userinfo", nil)
+	require.NoError(t, err)
+
+	r.Header.Set("Authorization", "Bearer "+accessToken)
+
+	ui := oauth.UserInfo{Subject: test.NewUUID(), Email: test.NewEmail(), Name: test.RandString(8)}
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("UserInfo", mock.Anything, accessToken).Return(ui, nil)
+
+	w := testUserInfo(mockOAuthService, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(`{"sub":"%s","email":"%s","name":"%s"}`, ui.Subject, ui.Email, ui.Name)
+	assert.Equal(t, expectedBody, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestUserInfoFailure(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+
+	testCases := map[string]struct {
+		authorization string
+		setup         func(mockOAuthService *oauth.MockService)
+		expectedBody  string
+	}{
+		"test failure when bearer token is missing": {
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedBody: `{"error":"invalid_token","error_description":"bearer token is missing"}`,
+		},
+		"test failure when authorization scheme is not bearer": {
+			authorization: "Basic " + accessToken,
+			setup:         func(mockOAuthService *oauth.MockService) {},
+			expectedBody:  `{"error":"invalid_token","error_description":"bearer token is missing"}`,
+		},
+		"test failure when token is invalid": {
+			authorization: "Bearer " + accessToken,
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("UserInfo", mock.Anything, accessToken).
+					Return(oauth.UserInfo{}, erx.WithArgs(oauth.InvalidTokenError, errors.New("token is not active")))
+			},
+			expectedBody: `{"error":"invalid_token","error_description":"token is not active"}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/userinfo", nil)
+			require.NoError(t, err)
+
+			if len(testCase.authorization) != 0 {
+				r.Header.Set("Authorization", testCase.authorization)
+			}
+
+			mockOAuthService := &oauth.MockService{}
+			testCase.setup(mockOAuthService)
+
+			w := testUserInfo(mockOAuthService, r)
+
+			require.Equal(t, http.StatusUnauthorized, w.Code)
+
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+			assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestOpenIDConfigurationSuccess(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, &oauth.MockService{})
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.OpenIDConfiguration)(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &data))
+
+	assert.Equal(t, oauthIssuer, data["issuer"])
+	assert.Equal(t, oauthIssuer+"/oauth/authorize", data["authorization_endpoint"])
+	assert.Equal(t, oauthIssuer+"/oauth/token", data["token_endpoint"])
+	assert.Equal(t, oauthIssuer+"/userinfo", data["userinfo_endpoint"])
+	assert.Equal(t, oauthIssuer+"/.well-known/jwks.json", data["jwks_uri"])
+	assert.Equal(t, []interface{}{"EdDSA"}, data["id_token_signing_alg_values_supported"])
+	assert.Contains(t, data["scopes_supported"], oauth.ScopeOpenID)
+}
+
+func TestDeviceAuthorizationSuccess(t *testing.T) {
+	cl := newOAuthClient(t)
+
+	r, err := http.NewRequest(http.MethodPost, "/oauth/device/code", strings.NewReader(url.Values{"client_id": {cl.Name}, "scope": {"openid"}}.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	da := oauth.DeviceAuthorization{
+		DeviceCode:              test.RandString(43),
+		UserCode:                "BCDF-GHJK",
+		VerificationURI:         oauthIssuer + "/oauth/device",
+		VerificationURIComplete: oauthIssuer + "/oauth/device?user_code=BCDF-GHJK",
+		ExpiresIn:               600,
+		Interval:                5,
+	}
+
+	mockOAuthService := &oauth.MockService{}
+	mockOAuthService.On("AuthenticateClient", mock.Anything, cl.Name, "").Return(cl, nil)
+	mockOAuthService.On("AuthorizeDevice", mock.Anything, "openid").Return(da, nil)
+
+	w := testDeviceAuthorization(mockOAuthService, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	expectedBody := fmt.Sprintf(
+		`{"device_code":"%s","user_code":"BCDF-GHJK","verification_uri":"%s/oauth/device","verification_uri_complete":"%s/oauth/device?user_code=BCDF-GHJK","expires_in":600,"interval":5}`,
+		da.DeviceCode,
+		oauthIssuer,
+		oauthIssuer,
+	)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+}
+
+func TestDeviceAuthorizationFailure(t *testing.T) {
+	cl := newOAuthClient(t)
+
+	testCases := map[string]struct {
+		values       url.Values
+		setup        func(mockOAuthService *oauth.MockService)
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when client id is missing": {
+			values:       url.Values{},
+			setup:        func(mockOAuthService *oauth.MockService) {},
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid_request","error_description":"client id cannot be empty"}`,
+		},
+		"test failure when client authentication fails": {
+			values: url.Values{"client_id": {cl.Name}},
+			setup: func(mockOAuthService *oauth.MockService) {
+				mockOAuthService.On("AuthenticateClient", mock.Anything, cl.Name, "").
+					Return(client.Client{}, erx.WithArgs(oauth.InvalidClientError, errors.New("client not found")))
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"invalid_client","error_description":"client authentication failed"}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, "/oauth/device/code", strings.NewReader(testCase.values.Encode()))
+			require.NoError(t, err)
+
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			mockOAuthService := &oauth.MockService{}
+			testCase.setup(mockOAuthService)
+
+			w := testDeviceAuthorization(mockOAuthService, r)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			assert.Equal(t, testCase.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestDevicePageSuccess(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/oauth/device?user_code=BCDF-GHJK", nil)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, &oauth.MockService{})
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.DevicePage)(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Contains(t, w.Body.String(), `<form method="post" action="/oauth/device">`)
+	assert.Contains(t, w.Body.String(), `name="user_code" value="BCDF-GHJK"`)
+}
+
+func TestVerifyDeviceSuccess(t *testing.T) {
+	email, password := test.NewEmail(), test.NewPassword()
+
+	testCases := map[string]struct {
+		action       string
+		approved     bool
+		expectedBody string
+	}{
+		"test user approves the device": {
+			action:       "approve",
+			approved:     true,
+			expectedBody: "Device approved",
+		},
+		"test user denies the device": {
+			action:       "deny",
+			approved:     false,
+			expectedBody: "Device denied",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values := url.Values{"user_code": {"BCDF-GHJK"}, "email": {email}, "password": {password}, "action": {testCase.action}}
+
+			mockOAuthService := &oauth.MockService{}
+			mockOAuthService.On("VerifyDevice", mock.Anything, "BCDF-GHJK", email, password, "", "", testCase.approved).Return("", nil)
+
+			w := testVerifyDevice(t, mockOAuthService, values)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), testCase.expectedBody)
+		})
+	}
+}
+
+func TestVerifyDeviceFailure(t *testing.T) {
+	email, password := test.NewEmail(), test.NewPassword()
+
+	testCases := map[string]struct {
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when credentials are invalid": {
+			err:          erx.WithArgs(erx.InvalidCredentialsError, errors.New("invalid credentials")),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "invalid email or password",
+		},
+		"test failure when second factor is required": {
+			err:          erx.WithArgs(oauth.MFARequiredError, errors.New("second factor required")),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `name="mfa_token" value="mfa-token"`,
+		},
+		"test failure when user code is invalid or expired": {
+			err:          erx.WithArgs(oauth.InvalidGrantError, errors.New("user code not found or expired")),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "invalid or expired code",
+		},
+		"test failure when service call fails": {
+			err:          erx.WithArgs(errors.New("database error")),
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values := url.Values{"user_code": {"BCDF-GHJK"}, "email": {email}, "password": {password}, "action": {"approve"}}
+
+			mockOAuthService := &oauth.MockService{}
+			mockOAuthService.On("VerifyDevice", mock.Anything, "BCDF-GHJK", email, password, "", "", true).Return("mfa-token", testCase.err)
+
+			w := testVerifyDevice(t, mockOAuthService, values)
+
+			require.Equal(t, testCase.expectedCode, w.Code)
+			assert.Contains(t, w.Body.String(), testCase.expectedBody)
+		})
+	}
+}
+
+func newOAuthClient(t *testing.T) client.Client {
+	mockClientConfig := &config.MockClientConfig{}
+	mockClientConfig.On("Strategies").Return(map[string]bool{test.ClientSessionStrategyRevokeOld: true})
+
+	cl, err := test.NewClient(mockClientConfig, map[string]interface{}{})
+	require.NoError(t, err)
+
+	return cl
+}
+
+func testOAuthToken(oauthService oauth.Service, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, oauthService)
+	mdl.WithOAuthErrorHandler(reporters.NewLogger("dev", "debug"), oh.Token)(w, r)
+
+	return w
+}
+
+func testUserInfo(oauthService oauth.Service, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, oauthService)
+	mdl.WithOAuthErrorHandler(reporters.NewLogger("dev", "debug"), oh.UserInfo)(w, r)
+
+	return w
+}
+
+func testDeviceAuthorization(oauthService oauth.Service, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, oauthService)
+	mdl.WithOAuthErrorHandler(reporters.NewLogger("dev", "debug"), oh.DeviceAuthorization)(w, r)
+
+	return w
+}
+
+func testVerifyDevice(t *testing.T, oauthService oauth.Service, values url.Values) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(http.MethodPost, "/oauth/device", strings.NewReader(values.Encode()))
+	require.NoError(t, err)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+
+	oh := handler.NewOAuthHandler(oauthIssuer, oauthService)
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), oh.VerifyDevice)(w, r)
+
+	return w
+}
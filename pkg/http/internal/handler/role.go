@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/role"
+	"net/http"
+)
+
+type RoleHandler struct {
+	service role.Service
+}
+
+func (rh *RoleHandler) Create(resp http.ResponseWriter, req *http.Request) error {
+	var reqBody contract.CreateRoleRequest
+	if err := util.ParseRequest(req, &reqBody); err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Create"), err)
+	}
+
+	id, err := rh.service.CreateRole(req.Context(), reqBody.Name, reqBody.Permissions)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Create"), err)
+	}
+
+	util.WriteSuccessResponse(http.StatusCreated, contract.CreateRoleResponse{ID: id}, resp)
+	return nil
+}
+
+func (rh *RoleHandler) Assign(resp http.ResponseWriter, req *http.Request) error {
+	var reqBody contract.RoleAssignmentRequest
+	if err := util.ParseRequest(req, &reqBody); err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Assign"), err)
+	}
+
+	err := rh.service.AssignRole(req.Context(), reqBody.UserID, reqBody.Role, reqBody.ClientID)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Assign"), err)
+	}
+
+	respBody := contract.RoleAssignmentResponse{Message: contract.RoleAssignSuccessful}
+
+	util.WriteSuccessResponse(http.StatusOK, respBody, resp)
+	return nil
+}
+
+func (rh *RoleHandler) Unassign(resp http.ResponseWriter, req *http.Request) error {
+	var reqBody contract.RoleAssignmentRequest
+	if err := util.ParseRequest(req, &reqBody); err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Unassign"), err)
+	}
+
+	err := rh.service.UnassignRole(req.Context(), reqBody.UserID, reqBody.Role, reqBody.ClientID)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("RoleHandler.Unassign"), err)
+	}
+
+	respBody := contract.RoleAssignmentResponse{Message: contract.RoleUnassignSuccessful}
+
+	util.WriteSuccessResponse(http.StatusOK, respBody, resp)
+	return nil
+}
+
+func NewRoleHandler(service role.Service) *RoleHandler {
+	return &RoleHandler{
+		service: service,
+	}
+}
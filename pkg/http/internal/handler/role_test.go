@@ -0,0 +1,116 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/role"
+	"identification-service/pkg/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type roleHandlerFunc func(resp http.ResponseWriter, req *http.Request) error
+
+func TestRoleHandlerCreateSuccess(t *testing.T) {
+	roleID := test.NewUUID()
+
+	req := contract.CreateRoleRequest{Name: "admin", Permissions: []string{"users:read"}}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("CreateRole", mock.Anything, "admin", []string{"users:read"}).Return(roleID, nil)
+
+	expectedBody := `{"data":{"id":"` + roleID + `"},"success":true}`
+
+	testRoleHandler(t, http.StatusCreated, expectedBody, bytes.NewBuffer(body), mockRoleService, func(rh *handler.RoleHandler) roleHandlerFunc { return rh.Create })
+}
+
+func TestRoleHandlerCreateFailureWhenRoleAlreadyExists(t *testing.T) {
+	req := contract.CreateRoleRequest{Name: "admin"}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("CreateRole", mock.Anything, "admin", []string(nil)).
+		Return("", erx.WithArgs(erx.DuplicateRecordError, errors.New("role already exists")))
+
+	expectedBody := `{"error":{"message":"duplicate record"},"success":false}`
+
+	testRoleHandler(t, http.StatusConflict, expectedBody, bytes.NewBuffer(body), mockRoleService, func(rh *handler.RoleHandler) roleHandlerFunc { return rh.Create })
+}
+
+func TestRoleHandlerAssignSuccess(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	req := contract.RoleAssignmentRequest{UserID: userID, Role: "admin", ClientID: clientID}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("AssignRole", mock.Anything, userID, "admin", clientID).Return(nil)
+
+	expectedBody := `{"data":{"message":"role assigned successfully"},"success":true}`
+
+	testRoleHandler(t, http.StatusOK, expectedBody, bytes.NewBuffer(body), mockRoleService, func(rh *handler.RoleHandler) roleHandlerFunc { return rh.Assign })
+}
+
+func TestRoleHandlerAssignFailureWhenRoleDoesNotExist(t *testing.T) {
+	userID := test.NewUUID()
+
+	req := contract.RoleAssignmentRequest{UserID: userID, Role: "admin"}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("AssignRole", mock.Anything, userID, "admin", "").
+		Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no role found")))
+
+	expectedBody := `{"error":{"message":"resource not found"},"success":false}`
+
+	testRoleHandler(t, http.StatusNotFound, expectedBody, bytes.NewBuffer(body), mockRoleService, func(rh *handler.RoleHandler) roleHandlerFunc { return rh.Assign })
+}
+
+func TestRoleHandlerUnassignSuccess(t *testing.T) {
+	userID := test.NewUUID()
+
+	req := contract.RoleAssignmentRequest{UserID: userID, Role: "admin"}
+
+	body, err := json.Marshal(&req)
+	require.NoError(t, err)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("UnassignRole", mock.Anything, userID, "admin", "").Return(nil)
+
+	expectedBody := `{"data":{"message":"role unassigned successfully"},"success":true}`
+
+	testRoleHandler(t, http.StatusOK, expectedBody, bytes.NewBuffer(body), mockRoleService, func(rh *handler.RoleHandler) roleHandlerFunc { return rh.Unassign })
+}
+
+func testRoleHandler(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service role.Service, h func(rh *handler.RoleHandler) roleHandlerFunc) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/role", body)
+
+	rh := handler.NewRoleHandler(service)
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), h(rh))(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
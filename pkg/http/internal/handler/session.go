@@ -2,10 +2,13 @@ package handler
 
 import (
 	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
 	"identification-service/pkg/http/contract"
 	"identification-service/pkg/http/internal/util"
 	"identification-service/pkg/session"
+	"identification-service/pkg/webauthn"
 	"net/http"
+	"strings"
 )
 
 type SessionHandler struct {
@@ -24,7 +27,39 @@ func (sh *SessionHandler) Login(resp http.ResponseWriter, req *http.Request) err
 		return wrap(err)
 	}
 
-	accessToken, refreshToken, err := sh.service.LoginUser(req.Context(), data.Email, data.Password)
+	accessToken, refreshToken, mfaToken, err := sh.service.LoginUser(req.Context(), data.Email, data.Password, strings.Fields(data.Scope))
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: NO SESSION WAS CREATED YET, THE CLIENT HAS TO COMPLETE THE CHALLENGE AT /session/login/mfa
+	if len(mfaToken) != 0 {
+		util.WriteSuccessResponse(http.StatusOK, contract.MFAChallengeResponse{MFAToken: mfaToken}, resp)
+		return nil
+	}
+
+	respData := contract.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	util.WriteSuccessResponse(http.StatusCreated, respData, resp)
+	return nil
+}
+
+func (sh *SessionHandler) CompleteLogin(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.CompleteLogin"), err) }
+
+	var data contract.CompleteLoginRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := sh.service.CompleteLogin(req.Context(), data.MFAToken, data.Code, strings.Fields(data.Scope))
 	if err != nil {
 		return wrap(err)
 	}
@@ -38,6 +73,125 @@ func (sh *SessionHandler) Login(resp http.ResponseWriter, req *http.Request) err
 	return nil
 }
 
+func (sh *SessionHandler) BeginWebAuthnLogin(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.BeginWebAuthnLogin"), err) }
+
+	var data contract.BeginWebAuthnLoginRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	challengeID, options, err := sh.service.BeginWebAuthnLogin(req.Context(), data.MFAToken)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.BeginWebAuthnLoginResponse{
+		ChallengeID: challengeID,
+		PublicKey:   requestOptions(options),
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func (sh *SessionHandler) FinishWebAuthnLogin(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.FinishWebAuthnLogin"), err) }
+
+	var data contract.FinishWebAuthnLoginRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	var assertion webauthn.Assertion
+
+	err := decodeBinary(
+		binaryField{name: "credential id", value: data.CredentialID, dst: &assertion.CredentialID},
+		binaryField{name: "client data json", value: data.ClientDataJSON, dst: &assertion.ClientDataJSON},
+		binaryField{name: "authenticator data", value: data.AuthenticatorData, dst: &assertion.AuthenticatorData},
+		binaryField{name: "signature", value: data.Signature, dst: &assertion.Signature},
+		binaryField{name: "user handle", value: data.UserHandle, dst: &assertion.UserHandle},
+	)
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := sh.service.FinishWebAuthnLogin(req.Context(), data.ChallengeID, assertion, strings.Fields(data.Scope))
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	util.WriteSuccessResponse(http.StatusCreated, respData, resp)
+	return nil
+}
+
+func (sh *SessionHandler) SendMagicLink(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.SendMagicLink"), err) }
+
+	var data contract.MagicLinkRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = sh.service.SendMagicLink(withMailOrigin(req, cl), data.Email, data.Name)
+	if err != nil {
+		return wrap(err)
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, contract.MagicLinkResponse{Message: contract.MagicLinkSent}, resp)
+	return nil
+}
+
+func (sh *SessionHandler) LoginWithMagicLink(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.LoginWithMagicLink"), err) }
+
+	var data contract.MagicLinkLoginRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, mfaToken, err := sh.service.LoginWithMagicLink(req.Context(), data.Token, strings.Fields(data.Scope))
+	if err != nil {
+		return wrap(err)
+	}
+
+	if len(mfaToken) != 0 {
+		util.WriteSuccessResponse(http.StatusOK, contract.MFAChallengeResponse{MFAToken: mfaToken}, resp)
+		return nil
+	}
+
+	respData := contract.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}
+
+	util.WriteSuccessResponse(http.StatusCreated, respData, resp)
+	return nil
+}
+
 func (sh *SessionHandler) RefreshToken(resp http.ResponseWriter, req *http.Request) error {
 	wrap := func(err error) error { return erx.WithArgs(erx.Operation("SessionHandler.RefreshToken"), err) }
 
@@ -50,13 +204,19 @@ func (sh *SessionHandler) RefreshToken(resp http.ResponseWriter, req *http.Reque
 		return wrap(err)
 	}
 
-	accessToken, err := sh.service.RefreshToken(req.Context(), data.RefreshToken)
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := sh.service.RefreshToken(withMailOrigin(req, cl), data.RefreshToken)
 	if err != nil {
 		return wrap(err)
 	}
 
 	respData := contract.RefreshTokenResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}
 
 	util.WriteSuccessResponse(http.StatusOK, respData, resp)
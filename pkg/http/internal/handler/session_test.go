@@ -2,6 +2,8 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,12 +11,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"identification-service/pkg/client"
 	"identification-service/pkg/http/contract"
 	"identification-service/pkg/http/internal/handler"
 	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/mailer"
 	reporters "identification-service/pkg/reporting"
 	"identification-service/pkg/session"
 	"identification-service/pkg/test"
+	"identification-service/pkg/webauthn"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -26,7 +31,7 @@ func TestLoginSuccess(t *testing.T) {
 	refreshToken := test.NewUUID()
 	userPassword := test.NewPassword()
 
-	reqBody := contract.LoginRequest{Email: userEmail, Password: userPassword}
+	reqBody := contract.LoginRequest{Email: userEmail, Password: userPassword, Scope: test.ClientScope}
 
 	expectedBody := fmt.Sprintf(
 		`{"data":{"access_token":"%s","refresh_token":"%s"},"success":true}`,
@@ -40,7 +45,8 @@ func TestLoginSuccess(t *testing.T) {
 		mock.AnythingOfType("*context.emptyCtx"),
 		userEmail,
 		userPassword,
-	).Return(accessToken, refreshToken, nil)
+		[]string{test.ClientScope},
+	).Return(accessToken, refreshToken, "", nil)
 
 	testLogin(t, http.StatusCreated, expectedBody, mockSessionService, reqBody)
 }
@@ -94,11 +100,27 @@ func TestLoginFailureWhenServiceCallFails(t *testing.T) {
 		mock.AnythingOfType("*context.emptyCtx"),
 		userEmail,
 		userPassword,
-	).Return("", "", erx.WithArgs(errors.New("failed to login")))
+		[]string{},
+	).Return("", "", "", erx.WithArgs(errors.New("failed to login")))
 
 	testLogin(t, http.StatusInternalServerError, expectedBody, mockSessionService, reqBody)
 }
 
+func TestLoginSuccessWhenMFAIsEnabled(t *testing.T) {
+	userEmail := test.NewEmail()
+	userPassword := test.NewPassword()
+	mfaToken := test.RandString(32)
+
+	reqBody := contract.LoginRequest{Email: userEmail, Password: userPassword}
+
+	expectedBody := fmt.Sprintf(`{"data":{"mfa_token":"%s"},"success":true}`, mfaToken)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("LoginUser", mock.Anything, userEmail, userPassword, []string{}).Return("", "", mfaToken, nil)
+
+	testLogin(t, http.StatusOK, expectedBody, mockSessionService, reqBody)
+}
+
 func testLogin(t *testing.T, expectedCode int, expectedBody string, sessionService session.Service, reqBody contract.LoginRequest) {
 	b, err := json.Marshal(&reqBody)
 
@@ -116,18 +138,337 @@ func testLogin(t *testing.T, expectedCode int, expectedBody string, sessionServi
 	assert.Equal(t, expectedBody, w.Body.String())
 }
 
+func TestCompleteLoginSuccess(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+	refreshToken := test.NewUUID()
+	mfaToken := test.RandString(32)
+
+	reqBody := contract.CompleteLoginRequest{MFAToken: mfaToken, Code: "123456", Scope: test.ClientScope}
+
+	expectedBody := fmt.Sprintf(`{"data":{"access_token":"%s","refresh_token":"%s"},"success":true}`, accessToken, refreshToken)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("CompleteLogin", mock.Anything, mfaToken, "123456", []string{test.ClientScope}).Return(accessToken, refreshToken, nil)
+
+	testCompleteLogin(t, http.StatusCreated, expectedBody, mockSessionService, reqBody)
+}
+
+func TestCompleteLoginFailure(t *testing.T) {
+	mfaToken := test.RandString(32)
+
+	testCases := map[string]struct {
+		reqBody        contract.CompleteLoginRequest
+		sessionService func() session.Service
+		expectedCode   int
+		expectedBody   string
+	}{
+		"test failure when mfa token is empty": {
+			reqBody:        contract.CompleteLoginRequest{Code: "123456"},
+			sessionService: func() session.Service { return &session.MockService{} },
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   `{"error":{"message":"mfa token cannot be empty"},"success":false}`,
+		},
+		"test failure when code is empty": {
+			reqBody:        contract.CompleteLoginRequest{MFAToken: mfaToken},
+			sessionService: func() session.Service { return &session.MockService{} },
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   `{"error":{"message":"code cannot be empty"},"success":false}`,
+		},
+		"test failure when code is invalid": {
+			reqBody: contract.CompleteLoginRequest{MFAToken: mfaToken, Code: "123456"},
+			sessionService: func() session.Service {
+				mockSessionService := &session.MockService{}
+				mockSessionService.On("CompleteLogin", mock.Anything, mfaToken, "123456", []string{}).
+					Return("", "", erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+
+				return mockSessionService
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testCompleteLogin(t, testCase.expectedCode, testCase.expectedBody, testCase.sessionService(), testCase.reqBody)
+		})
+	}
+}
+
+func testCompleteLogin(t *testing.T, expectedCode int, expectedBody string, sessionService session.Service, reqBody contract.CompleteLoginRequest) {
+	b, err := json.Marshal(&reqBody)
+	require.NoError(t, err)
+
+	r, err := http.NewRequest(http.MethodPost, "/session/login/mfa", bytes.NewBuffer(b))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	sh := handler.NewSessionHandler(sessionService)
+
+	lgr := reporters.NewLogger("dev", "debug")
+	mdl.WithErrorHandler(lgr, sh.CompleteLogin)(w, r)
+
+	require.Equal(t, expectedCode, w.Code)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestBeginWebAuthnLoginSuccess(t *testing.T) {
+	mfaToken, challengeID := test.RandString(32), test.NewUUID()
+
+	options := webauthn.RequestOptions{
+		Challenge:        "AQID",
+		Timeout:          300000,
+		RelyingPartyID:   test.WebAuthnRPID,
+		AllowCredentials: []webauthn.CredentialDescriptor{{Type: "public-key", ID: "BAUG"}},
+		UserVerification: "preferred",
+	}
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("BeginWebAuthnLogin", mock.Anything, mfaToken).Return(challengeID, options, nil)
+
+	reqBody := contract.BeginWebAuthnLoginRequest{MFAToken: mfaToken}
+
+	expectedBody := fmt.Sprintf(
+		`{"data":{"challenge_id":"%s","public_key":{"challenge":"AQID","timeout":300000,"rpId":"%s","allowCredentials":[{"type":"public-key","id":"BAUG"}],"userVerification":"preferred"}},"success":true}`,
+		challengeID,
+		test.WebAuthnRPID,
+	)
+
+	testSessionHandler(t, http.StatusOK, expectedBody, handler.NewSessionHandler(mockSessionService).BeginWebAuthnLogin, reqBody)
+}
+
+func TestFinishWebAuthnLoginSuccess(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+	refreshToken, challengeID := test.NewUUID(), test.NewUUID()
+
+	assertion := webauthn.Assertion{
+		CredentialID:      []byte{1, 2, 3},
+		ClientDataJSON:    []byte(`{"type":"webauthn.get"}`),
+		AuthenticatorData: []byte{4, 5, 6},
+		Signature:         []byte{7, 8, 9},
+		UserHandle:        []byte{},
+	}
+
+	reqBody := contract.FinishWebAuthnLoginRequest{
+		ChallengeID:       challengeID,
+		CredentialID:      base64.RawURLEncoding.EncodeToString(assertion.CredentialID),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(assertion.ClientDataJSON),
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(assertion.AuthenticatorData),
+		Signature:         base64.RawURLEncoding.EncodeToString(assertion.Signature),
+		Scope:             test.ClientScope,
+	}
+
+	expectedBody := fmt.Sprintf(`{"data":{"access_token":"%s","refresh_token":"%s"},"success":true}`, accessToken, refreshToken)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("FinishWebAuthnLogin", mock.Anything, challengeID, assertion, []string{test.ClientScope}).Return(accessToken, refreshToken, nil)
+
+	testSessionHandler(t, http.StatusCreated, expectedBody, handler.NewSessionHandler(mockSessionService).FinishWebAuthnLogin, reqBody)
+}
+
+func TestFinishWebAuthnLoginFailure(t *testing.T) {
+	challengeID := test.NewUUID()
+
+	validRequest := func() contract.FinishWebAuthnLoginRequest {
+		return contract.FinishWebAuthnLoginRequest{
+			ChallengeID:       challengeID,
+			CredentialID:      "AQID",
+			ClientDataJSON:    "e30",
+			AuthenticatorData: "BAUG",
+			Signature:         "BwgJ",
+		}
+	}
+
+	testCases := map[string]struct {
+		reqBody        func() contract.FinishWebAuthnLoginRequest
+		sessionService func() session.Service
+		expectedCode   int
+		expectedBody   string
+	}{
+		"test failure when signature is empty": {
+			reqBody: func() contract.FinishWebAuthnLoginRequest {
+				req := validRequest()
+				req.Signature = test.EmptyString
+				return req
+			},
+			sessionService: func() session.Service { return &session.MockService{} },
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   `{"error":{"message":"signature cannot be empty"},"success":false}`,
+		},
+		"test failure when user handle is not base64url": {
+			reqBody: func() contract.FinishWebAuthnLoginRequest {
+				req := validRequest()
+				req.UserHandle = "a+b/"
+				return req
+			},
+			sessionService: func() session.Service { return &session.MockService{} },
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   `{"error":{"message":"user handle is not valid base64url"},"success":false}`,
+		},
+		"test failure when assertion is invalid": {
+			reqBody: validRequest,
+			sessionService: func() session.Service {
+				mockSessionService := &session.MockService{}
+				mockSessionService.On("FinishWebAuthnLogin", mock.Anything, challengeID, mock.AnythingOfType("webauthn.Assertion"), []string{}).
+					Return("", "", erx.WithArgs(erx.AuthenticationError, errors.New("invalid signature")))
+
+				return mockSessionService
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sh := handler.NewSessionHandler(testCase.sessionService())
+
+			testSessionHandler(t, testCase.expectedCode, testCase.expectedBody, sh.FinishWebAuthnLogin, testCase.reqBody())
+		})
+	}
+}
+
+func TestSendMagicLinkSuccess(t *testing.T) {
+	userEmail, userName := test.NewEmail(), test.RandString(8)
+	cl := newOAuthClient(t)
+
+	hasOrigin := mock.MatchedBy(func(ctx context.Context) bool {
+		return mailer.OriginFromContext(ctx) == mailer.Origin{ClientID: cl.Id, Locale: "pt-br"}
+	})
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("SendMagicLink", hasOrigin, userEmail, userName).Return(nil)
+
+	b, err := json.Marshal(contract.MagicLinkRequest{Email: userEmail, Name: userName})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/session/login/magic-link", bytes.NewBuffer(b))
+	r.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+
+	ctx, err := client.WithContext(r.Context(), cl)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), handler.NewSessionHandler(mockSessionService).SendMagicLink)(w, r.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":{"message":"sign in link sent if the account exists or can be created"},"success":true}`, w.Body.String())
+	mockSessionService.AssertExpectations(t)
+}
+
+func TestSendMagicLinkFailureWhenValidationFails(t *testing.T) {
+	expectedBody := `{"error":{"message":"email cannot be empty"},"success":false}`
+
+	sh := handler.NewSessionHandler(&session.MockService{})
+
+	testSessionHandler(t, http.StatusBadRequest, expectedBody, sh.SendMagicLink, contract.MagicLinkRequest{Name: test.RandString(8)})
+}
+
+func TestLoginWithMagicLinkSuccess(t *testing.T) {
+	magicLinkToken := test.RandString(64)
+	accessToken := test.NewPasetoToken()
+	refreshToken := test.NewUUID()
+
+	expectedBody := fmt.Sprintf(`{"data":{"access_token":"%s","refresh_token":"%s"},"success":true}`, accessToken, refreshToken)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("LoginWithMagicLink", mock.Anything, magicLinkToken, []string{test.ClientScope}).Return(accessToken, refreshToken, "", nil)
+
+	reqBody := contract.MagicLinkLoginRequest{Token: magicLinkToken, Scope: test.ClientScope}
+
+	testSessionHandler(t, http.StatusCreated, expectedBody, handler.NewSessionHandler(mockSessionService).LoginWithMagicLink, reqBody)
+}
+
+func TestLoginWithMagicLinkSuccessWhenMFAIsEnabled(t *testing.T) {
+	magicLinkToken := test.RandString(64)
+	mfaToken := test.RandString(32)
+
+	expectedBody := fmt.Sprintf(`{"data":{"mfa_token":"%s"},"success":true}`, mfaToken)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("LoginWithMagicLink", mock.Anything, magicLinkToken, []string{}).Return("", "", mfaToken, nil)
+
+	reqBody := contract.MagicLinkLoginRequest{Token: magicLinkToken}
+
+	testSessionHandler(t, http.StatusOK, expectedBody, handler.NewSessionHandler(mockSessionService).LoginWithMagicLink, reqBody)
+}
+
+func TestLoginWithMagicLinkFailure(t *testing.T) {
+	magicLinkToken := test.RandString(64)
+
+	testCases := map[string]struct {
+		reqBody        contract.MagicLinkLoginRequest
+		sessionService func() session.Service
+		expectedCode   int
+		expectedBody   string
+	}{
+		"test failure when token is empty": {
+			reqBody:        contract.MagicLinkLoginRequest{Token: test.EmptyString},
+			sessionService: func() session.Service { return &session.MockService{} },
+			expectedCode:   http.StatusBadRequest,
+			expectedBody:   `{"error":{"message":"token cannot be empty"},"success":false}`,
+		},
+		"test failure when link is invalid": {
+			reqBody: contract.MagicLinkLoginRequest{Token: magicLinkToken},
+			sessionService: func() session.Service {
+				mockSessionService := &session.MockService{}
+				mockSessionService.On("LoginWithMagicLink", mock.Anything, magicLinkToken, []string{}).
+					Return("", "", "", erx.WithArgs(erx.AuthenticationError, errors.New("no pending magic link")))
+
+				return mockSessionService
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			sh := handler.NewSessionHandler(testCase.sessionService())
+
+			testSessionHandler(t, testCase.expectedCode, testCase.expectedBody, sh.LoginWithMagicLink, testCase.reqBody)
+		})
+	}
+}
+
+func testSessionHandler(t *testing.T, expectedCode int, expectedBody string, handle func(http.ResponseWriter, *http.Request) error, reqBody interface{}) {
+	b, err := json.Marshal(reqBody)
+	require.NoError(t, err)
+
+	r, err := http.NewRequest(http.MethodPost, "/session/login/webauthn", bytes.NewBuffer(b))
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	lgr := reporters.NewLogger("dev", "debug")
+	mdl.WithErrorHandler(lgr, handle)(w, r)
+
+	require.Equal(t, expectedCode, w.Code)
+
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
 func TestRefreshTokenSuccess(t *testing.T) {
 	accessToken := test.NewPasetoToken()
 	refreshToken := test.NewUUID()
 
 	reqBody := contract.RefreshTokenRequest{RefreshToken: refreshToken}
 
+	cl := newOAuthClient(t)
+
+	hasOrigin := mock.MatchedBy(func(ctx context.Context) bool {
+		return mailer.OriginFromContext(ctx) == mailer.Origin{ClientID: cl.Id, Locale: "pt-br"}
+	})
+
 	mockSessionService := &session.MockService{}
-	mockSessionService.On("RefreshToken", mock.AnythingOfType("*context.emptyCtx"), refreshToken).Return(accessToken, nil)
+	mockSessionService.On("RefreshToken", hasOrigin, refreshToken).Return(accessToken, refreshToken, nil)
 
-	expectedBody := fmt.Sprintf(`{"data":{"access_token":"%s"},"success":true}`, accessToken)
+	expectedBody := fmt.Sprintf(`{"data":{"access_token":"%s","refresh_token":"%s"},"success":true}`, accessToken, refreshToken)
 
-	testRefreshToken(t, http.StatusOK, expectedBody, mockSessionService, reqBody)
+	testRefreshToken(t, http.StatusOK, expectedBody, mockSessionService, cl, reqBody)
+	mockSessionService.AssertExpectations(t)
 }
 
 func TestRefreshTokenFailureWhenValidationFails(t *testing.T) {
@@ -135,7 +476,7 @@ func TestRefreshTokenFailureWhenValidationFails(t *testing.T) {
 
 	expectedBody := `{"error":{"message":"refresh token cannot be empty"},"success":false}`
 
-	testRefreshToken(t, http.StatusBadRequest, expectedBody, &session.MockService{}, reqBody)
+	testRefreshToken(t, http.StatusBadRequest, expectedBody, &session.MockService{}, newOAuthClient(t), reqBody)
 }
 
 func TestRefreshTokenFailureWhenServiceCallFails(t *testing.T) {
@@ -146,21 +487,28 @@ func TestRefreshTokenFailureWhenServiceCallFails(t *testing.T) {
 	mockSessionService := &session.MockService{}
 	mockSessionService.On(
 		"RefreshToken",
-		mock.AnythingOfType("*context.emptyCtx"),
+		mock.AnythingOfType("*context.valueCtx"),
 		refreshToken,
-	).Return("", erx.WithArgs(errors.New("failed to refresh token")))
+	).Return("", "", erx.WithArgs(errors.New("failed to refresh token")))
 
 	expectedBody := `{"error":{"message":"internal server error"},"success":false}`
 
-	testRefreshToken(t, http.StatusInternalServerError, expectedBody, mockSessionService, reqBody)
+	testRefreshToken(t, http.StatusInternalServerError, expectedBody, mockSessionService, newOAuthClient(t), reqBody)
 }
 
-func testRefreshToken(t *testing.T, expectedCode int, expectedBody string, sessionService session.Service, reqBody contract.RefreshTokenRequest) {
+func testRefreshToken(t *testing.T, expectedCode int, expectedBody string, sessionService session.Service, cl client.Client, reqBody contract.RefreshTokenRequest) {
 	b, err := json.Marshal(&reqBody)
 
 	r, err := http.NewRequest(http.MethodPost, "/session/refresh-token", bytes.NewBuffer(b))
 	require.NoError(t, err)
 
+	r.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+
+	ctx, err := client.WithContext(r.Context(), cl)
+	require.NoError(t, err)
+
+	r = r.WithContext(ctx)
+
 	w := httptest.NewRecorder()
 
 	sh := handler.NewSessionHandler(sessionService)
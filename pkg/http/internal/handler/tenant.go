@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/tenant"
+	"net/http"
+)
+
+type TenantHandler struct {
+	service tenant.Service
+}
+
+func (th *TenantHandler) Create(resp http.ResponseWriter, req *http.Request) error {
+	var reqBody contract.CreateTenantRequest
+	if err := util.ParseRequest(req, &reqBody); err != nil {
+		return erx.WithArgs(erx.Operation("TenantHandler.Create"), err)
+	}
+
+	id, err := th.service.CreateTenant(req.Context(), reqBody.Name)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("TenantHandler.Create"), err)
+	}
+
+	util.WriteSuccessResponse(http.StatusCreated, contract.CreateTenantResponse{ID: id}, resp)
+	return nil
+}
+
+func NewTenantHandler(service tenant.Service) *TenantHandler {
+	return &TenantHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,87 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/test"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenantHandlerCreateSuccess(t *testing.T) {
+	tenantID := test.NewUUID()
+
+	body, err := json.Marshal(&contract.CreateTenantRequest{Name: "acme"})
+	require.NoError(t, err)
+
+	mockTenantService := &tenant.MockService{}
+	mockTenantService.On("CreateTenant", mock.Anything, "acme").Return(tenantID, nil)
+
+	expectedBody := `{"data":{"id":"` + tenantID + `"},"success":true}`
+
+	testTenantHandlerCreate(t, http.StatusCreated, expectedBody, bytes.NewBuffer(body), mockTenantService)
+}
+
+func TestTenantHandlerCreateFailure(t *testing.T) {
+	testCases := map[string]struct {
+		service      func() tenant.Service
+		body         func() io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when body parsing fails": {
+			service:      func() tenant.Service { return &tenant.MockService{} },
+			body:         func() io.Reader { return nil },
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"unexpected end of JSON input"},"success":false}`,
+		},
+		"test failure when tenant already exists": {
+			service: func() tenant.Service {
+				mockTenantService := &tenant.MockService{}
+				mockTenantService.On("CreateTenant", mock.Anything, "acme").
+					Return("", erx.WithArgs(erx.DuplicateRecordError, errors.New("tenant already exists")))
+
+				return mockTenantService
+			},
+			body: func() io.Reader {
+				body, err := json.Marshal(&contract.CreateTenantRequest{Name: "acme"})
+				require.NoError(t, err)
+
+				return bytes.NewBuffer(body)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":{"message":"duplicate record"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testTenantHandlerCreate(t, testCase.expectedCode, testCase.expectedBody, testCase.body(), testCase.service())
+		})
+	}
+}
+
+func testTenantHandlerCreate(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service tenant.Service) {
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/tenant/create", body)
+
+	th := handler.NewTenantHandler(service)
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), th.Create)(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/session"
+	"identification-service/pkg/token"
+	"net/http"
+)
+
+type TokenHandler struct {
+	service session.Service
+}
+
+func (th *TokenHandler) Introspect(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("TokenHandler.Introspect"), err) }
+
+	var data contract.IntrospectRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(err)
+	}
+
+	res, err := th.service.IntrospectToken(req.Context(), data.Token)
+	if err != nil {
+		return wrap(err)
+	}
+
+	respData := contract.IntrospectResponse{Active: res.Active}
+
+	if res.Active {
+		respData.Subject = res.Claims.Subject
+		respData.Expiration = res.Claims.Expiration.Unix()
+		respData.IssuedAt = res.Claims.IssuedAt.Unix()
+		respData.Audience = res.Claims.Audience
+		respData.ClientID = res.ClientID
+
+		//NOTE: THE SCOPE IS A STANDARD INTROSPECTION MEMBER, EVERY OTHER CUSTOM CLAIM IS NESTED SO IT CANNOT SHADOW ONE
+		claims := res.Claims.Custom()
+		respData.Scope = claims[token.ScopeClaim]
+		delete(claims, token.ScopeClaim)
+
+		if len(claims) != 0 {
+			respData.Claims = claims
+		}
+	}
+
+	resp.Header().Set("Cache-Control", "no-store")
+	util.WriteJSONResponse(http.StatusOK, respData, resp)
+	return nil
+}
+
+func NewTokenHandler(service session.Service) *TokenHandler {
+	return &TokenHandler{
+		service: service,
+	}
+}
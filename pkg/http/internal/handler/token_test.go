@@ -0,0 +1,128 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/config"
+	"identification-service/pkg/http/contract"
+	"identification-service/pkg/http/internal/handler"
+	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/libcrypto"
+	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/session"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIntrospectSuccessWhenTokenIsActive(t *testing.T) {
+	accessToken, userID, clientID := test.NewPasetoToken(), test.NewUUID(), test.NewUUID()
+	issuedAt := time.Now().Truncate(time.Second)
+	expiration := issuedAt.Add(10 * time.Minute)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).Return(session.Introspection{
+		Active:   true,
+		ClientID: clientID,
+		Claims: token.Claims{
+			Subject:    userID,
+			Audience:   "user",
+			IssuedAt:   issuedAt,
+			Expiration: expiration,
+		},
+	}, nil)
+
+	expectedBody := fmt.Sprintf(
+		`{"active":true,"sub":"%s","exp":%d,"iat":%d,"aud":"user","client_id":"%s"}`,
+		userID,
+		expiration.Unix(),
+		issuedAt.Unix(),
+		clientID,
+	)
+
+	testIntrospect(t, http.StatusOK, expectedBody, mockSessionService, contract.IntrospectRequest{Token: accessToken})
+}
+
+func TestIntrospectSuccessWithScopeAndCustomClaims(t *testing.T) {
+	accessToken, userID, clientID, sessionID := test.NewPasetoToken(), test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("user")
+	mockTokenConfig.On("Issuer").Return("identification-service")
+
+	_, claims, err := token.NewGenerator(mockTokenConfig).GenerateAccessToken(
+		10,
+		userID,
+		libcrypto.Key{ID: test.NewUUID(), PrivateKey: test.ClientPriKey()},
+		map[string]string{"session_id": sessionID, token.ScopeClaim: test.ClientScope, "contact_email": "user@example.com"},
+	)
+	require.NoError(t, err)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).
+		Return(session.Introspection{Active: true, ClientID: clientID, Claims: claims}, nil)
+
+	expectedBody := fmt.Sprintf(
+		`{"active":true,"sub":"%s","exp":%d,"iat":%d,"aud":"user","client_id":"%s","scope":"%s","claims":{"contact_email":"user@example.com","session_id":"%s"}}`,
+		userID,
+		claims.Expiration.Unix(),
+		claims.IssuedAt.Unix(),
+		clientID,
+		test.ClientScope,
+		sessionID,
+	)
+
+	testIntrospect(t, http.StatusOK, expectedBody, mockSessionService, contract.IntrospectRequest{Token: accessToken})
+}
+
+func TestIntrospectSuccessWhenTokenIsInactive(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).Return(session.Introspection{}, nil)
+
+	testIntrospect(t, http.StatusOK, `{"active":false}`, mockSessionService, contract.IntrospectRequest{Token: accessToken})
+}
+
+func TestIntrospectFailureWhenTokenIsEmpty(t *testing.T) {
+	expectedBody := `{"error":{"message":"token cannot be empty"},"success":false}`
+
+	testIntrospect(t, http.StatusBadRequest, expectedBody, &session.MockService{}, contract.IntrospectRequest{})
+}
+
+func TestIntrospectFailureWhenSvcCallFails(t *testing.T) {
+	accessToken := test.NewPasetoToken()
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).
+		Return(session.Introspection{}, erx.WithArgs(errors.New("failed to introspect token")))
+
+	expectedBody := `{"error":{"message":"internal server error"},"success":false}`
+
+	testIntrospect(t, http.StatusInternalServerError, expectedBody, mockSessionService, contract.IntrospectRequest{Token: accessToken})
+}
+
+func testIntrospect(t *testing.T, expectedCode int, expectedBody string, service session.Service, reqBody contract.IntrospectRequest) {
+	b, err := json.Marshal(&reqBody)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/token/introspect", bytes.NewBuffer(b))
+
+	th := handler.NewTokenHandler(service)
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), th.Introspect)(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
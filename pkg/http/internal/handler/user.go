@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"context"
 	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
 	"identification-service/pkg/http/contract"
 	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/mailer"
 	"identification-service/pkg/user"
 	"net/http"
 )
@@ -18,8 +21,13 @@ func (uh *UserHandler) SignUp(resp http.ResponseWriter, req *http.Request) error
 		return erx.WithArgs(erx.Operation("UserHandler.SignUp"), err)
 	}
 
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return erx.WithArgs(erx.Operation("UserHandler.SignUp"), err)
+	}
+
 	//TODO: THINK IF THE VALIDATION SHOULD BE DELEGATED TO SVC LAYER ?
-	_, err := uh.service.CreateUser(req.Context(), data.Name, data.Email, data.Password)
+	_, err = uh.service.CreateUser(withMailOrigin(req, cl), cl.TenantID, data.Name, data.Email, data.Password)
 	if err != nil {
 		return erx.WithArgs(erx.Operation("UserHandler.SignUp"), err)
 	}
@@ -42,7 +50,12 @@ func (uh *UserHandler) UpdatePassword(resp http.ResponseWriter, req *http.Reques
 		return wrap(erx.WithArgs(erx.ValidationError, err))
 	}
 
-	err := uh.service.UpdatePassword(req.Context(), data.Email, data.OldPassword, data.NewPassword)
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = uh.service.UpdatePassword(req.Context(), cl.TenantID, data.Email, data.OldPassword, data.NewPassword)
 	if err != nil {
 		return wrap(err)
 	}
@@ -51,6 +64,109 @@ func (uh *UserHandler) UpdatePassword(resp http.ResponseWriter, req *http.Reques
 	return nil
 }
 
+func (uh *UserHandler) VerifyEmail(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("UserHandler.VerifyEmail"), err) }
+
+	var data contract.VerifyEmailRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(erx.WithArgs(erx.ValidationError, err))
+	}
+
+	err := uh.service.VerifyEmail(req.Context(), data.Token)
+	if err != nil {
+		return wrap(err)
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, contract.VerifyEmailResponse{Message: contract.EmailVerificationSuccess}, resp)
+	return nil
+}
+
+func (uh *UserHandler) ResendVerification(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("UserHandler.ResendVerification"), err) }
+
+	var data contract.ResendVerificationRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(erx.WithArgs(erx.ValidationError, err))
+	}
+
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = uh.service.ResendVerification(withMailOrigin(req, cl), cl.TenantID, data.Email)
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE SAME RESPONSE IS SENT WHETHER OR NOT AN EMAIL WAS QUEUED
+	util.WriteSuccessResponse(http.StatusOK, contract.ResendVerificationResponse{Message: contract.VerificationResendSuccess}, resp)
+	return nil
+}
+
+func (uh *UserHandler) ForgotPassword(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("UserHandler.ForgotPassword"), err) }
+
+	var data contract.ForgotPasswordRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(erx.WithArgs(erx.ValidationError, err))
+	}
+
+	cl, err := client.FromContext(req.Context())
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = uh.service.ForgotPassword(withMailOrigin(req, cl), cl.TenantID, data.Email)
+	if err != nil {
+		return wrap(err)
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, contract.ForgotPasswordResponse{Message: contract.PasswordResetSent}, resp)
+	return nil
+}
+
+func (uh *UserHandler) ResetPassword(resp http.ResponseWriter, req *http.Request) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("UserHandler.ResetPassword"), err) }
+
+	var data contract.ResetPasswordRequest
+	if err := util.ParseRequest(req, &data); err != nil {
+		return wrap(err)
+	}
+
+	if err := data.IsValid(); err != nil {
+		return wrap(erx.WithArgs(erx.ValidationError, err))
+	}
+
+	err := uh.service.ResetPassword(req.Context(), data.Token, data.NewPassword)
+	if err != nil {
+		return wrap(err)
+	}
+
+	util.WriteSuccessResponse(http.StatusOK, contract.ResetPasswordResponse{Message: contract.PasswordResetSuccess}, resp)
+	return nil
+}
+
+func withMailOrigin(req *http.Request, cl client.Client) context.Context {
+	//NOTE: MAIL IS RENDERED WITH THE TEMPLATES OF THE CLIENT AND IN THE LANGUAGE THE REQUEST ASKED FOR
+	return mailer.WithOrigin(req.Context(), mailer.Origin{
+		ClientID: cl.Id,
+		Locale:   mailer.ParseLocale(req.Header.Get("Accept-Language")),
+	})
+}
+
 func NewUserHandler(svc user.Service) *UserHandler {
 	return &UserHandler{
 		service: svc,
@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,10 +10,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"identification-service/pkg/client"
 	"identification-service/pkg/http/contract"
 	"identification-service/pkg/http/internal/handler"
 	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/mailer"
 	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
 	"identification-service/pkg/user"
 	"io"
@@ -25,7 +29,7 @@ func TestCreateUserSuccess(t *testing.T) {
 	userName, userEmail, userPassword := test.RandString(8), test.NewEmail(), test.NewPassword()
 
 	service := &user.MockService{}
-	service.On("CreateUser", mock.AnythingOfType("*context.emptyCtx"), userName, userEmail, userPassword).Return(test.NewUUID(), nil)
+	service.On("CreateUser", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userName, userEmail, userPassword).Return(test.NewUUID(), nil)
 
 	req := contract.CreateUserRequest{Name: userName, Email: userEmail, Password: userPassword}
 
@@ -55,7 +59,7 @@ func TestCreateUserFailure(t *testing.T) {
 		"test failure when service call fails fails": {
 			service: func() user.Service {
 				service := &user.MockService{}
-				service.On("CreateUser", mock.AnythingOfType("*context.emptyCtx"), userName, userEmail, userPassword).Return("", erx.WithArgs(errors.New("failed to create new user")))
+				service.On("CreateUser", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userName, userEmail, userPassword).Return("", erx.WithArgs(errors.New("failed to create new user")))
 
 				return service
 			},
@@ -79,6 +83,23 @@ func TestCreateUserFailure(t *testing.T) {
 	}
 }
 
+func TestCreateUserFailureWhenClientIsNotInContext(t *testing.T) {
+	req := contract.CreateUserRequest{Name: test.RandString(8), Email: test.NewEmail(), Password: test.NewPassword()}
+
+	b, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	service := &user.MockService{}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/user/create", bytes.NewBuffer(b))
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), handler.NewUserHandler(service).SignUp)(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	service.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
 func testCreateUser(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service user.Service) {
 	lgr := reporters.NewLogger("dev", "debug")
 
@@ -88,7 +109,10 @@ func testCreateUser(t *testing.T, expectedCode int, expectedBody string, body io
 
 	r := httptest.NewRequest(http.MethodPost, "/user/create", body)
 
-	mdl.WithErrorHandler(lgr, uh.SignUp)(w, r)
+	ctx, err := client.WithContext(r.Context(), newOAuthClient(t))
+	require.NoError(t, err)
+
+	mdl.WithErrorHandler(lgr, uh.SignUp)(w, r.WithContext(ctx))
 
 	assert.Equal(t, expectedCode, w.Code)
 	assert.Equal(t, expectedBody, w.Body.String())
@@ -98,7 +122,7 @@ func TestUpdatePasswordSuccess(t *testing.T) {
 	userEmail, userPassword, userPasswordNew := test.NewEmail(), test.NewPassword(), test.NewPassword()
 
 	mockUserService := &user.MockService{}
-	mockUserService.On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), userEmail, userPassword, userPasswordNew).Return(nil)
+	mockUserService.On("UpdatePassword", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword, userPasswordNew).Return(nil)
 
 	req := contract.UpdatePasswordRequest{Email: userEmail, OldPassword: userPassword, NewPassword: userPasswordNew}
 
@@ -114,7 +138,7 @@ func TestUpdatePasswordFailureWhenSvcCallFails(t *testing.T) {
 	userEmail, userPassword, userPasswordNew := test.NewEmail(), test.NewPassword(), test.NewPassword()
 
 	mockUserService := &user.MockService{}
-	mockUserService.On("UpdatePassword", mock.AnythingOfType("*context.emptyCtx"), userEmail, userPassword, userPasswordNew).Return(erx.WithArgs(errors.New("failed to update password")))
+	mockUserService.On("UpdatePassword", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword, userPasswordNew).Return(erx.WithArgs(errors.New("failed to update password")))
 
 	req := contract.UpdatePasswordRequest{Email: userEmail, OldPassword: userPassword, NewPassword: userPasswordNew}
 
@@ -186,7 +210,338 @@ func testUpdatePassword(t *testing.T, expectedCode int, expectedBody string, bod
 
 	r := httptest.NewRequest(http.MethodPost, "/user/update-password", body)
 
-	mdl.WithErrorHandler(lgr, uh.UpdatePassword)(w, r)
+	ctx, err := client.WithContext(r.Context(), newOAuthClient(t))
+	require.NoError(t, err)
+
+	mdl.WithErrorHandler(lgr, uh.UpdatePassword)(w, r.WithContext(ctx))
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestVerifyEmailSuccess(t *testing.T) {
+	verificationToken := test.RandString(64)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("VerifyEmail", mock.Anything, verificationToken).Return(nil)
+
+	b, err := json.Marshal(contract.VerifyEmailRequest{Token: verificationToken})
+	require.NoError(t, err)
+
+	expectedBody := `{"data":{"message":"email verified successfully"},"success":true}`
+
+	testVerifyEmail(t, http.StatusOK, expectedBody, bytes.NewBuffer(b), mockUserService)
+}
+
+func TestVerifyEmailFailure(t *testing.T) {
+	verificationToken := test.RandString(64)
+
+	toReader := func(reqBody contract.VerifyEmailRequest) io.Reader {
+		b, err := json.Marshal(reqBody)
+		require.NoError(t, err)
+
+		return bytes.NewBuffer(b)
+	}
+
+	testCases := map[string]struct {
+		service      func() user.Service
+		body         io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when token is empty": {
+			service:      func() user.Service { return &user.MockService{} },
+			body:         toReader(contract.VerifyEmailRequest{}),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"token cannot be empty"},"success":false}`,
+		},
+		"test failure when token is invalid or used": {
+			service: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("VerifyEmail", mock.Anything, verificationToken).
+					Return(erx.WithArgs(erx.AuthenticationError, errors.New("invalid token")))
+
+				return mockUserService
+			},
+			body:         toReader(contract.VerifyEmailRequest{Token: verificationToken}),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testVerifyEmail(t, testCase.expectedCode, testCase.expectedBody, testCase.body, testCase.service())
+		})
+	}
+}
+
+func testVerifyEmail(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service user.Service) {
+	lgr := reporters.NewLogger("dev", "debug")
+
+	uh := handler.NewUserHandler(service)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/user/verify-email", body)
+
+	mdl.WithErrorHandler(lgr, uh.VerifyEmail)(w, r)
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestResendVerificationSuccess(t *testing.T) {
+	userEmail := test.NewEmail()
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("ResendVerification", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail).Return(nil)
+
+	b, err := json.Marshal(contract.ResendVerificationRequest{Email: userEmail})
+	require.NoError(t, err)
+
+	expectedBody := `{"data":{"message":"verification email sent if the account exists and is not verified"},"success":true}`
+
+	testResendVerification(t, http.StatusOK, expectedBody, bytes.NewBuffer(b), mockUserService)
+}
+
+func TestResendVerificationFailure(t *testing.T) {
+	userEmail := test.NewEmail()
+
+	toReader := func(reqBody contract.ResendVerificationRequest) io.Reader {
+		b, err := json.Marshal(reqBody)
+		require.NoError(t, err)
+
+		return bytes.NewBuffer(b)
+	}
+
+	testCases := map[string]struct {
+		service      func() user.Service
+		body         io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when email is empty": {
+			service:      func() user.Service { return &user.MockService{} },
+			body:         toReader(contract.ResendVerificationRequest{}),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"email cannot be empty"},"success":false}`,
+		},
+		"test failure when svc call fails": {
+			service: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("ResendVerification", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail).
+					Return(erx.WithArgs(errors.New("failed to resend verification")))
+
+				return mockUserService
+			},
+			body:         toReader(contract.ResendVerificationRequest{Email: userEmail}),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":{"message":"internal server error"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testResendVerification(t, testCase.expectedCode, testCase.expectedBody, testCase.body, testCase.service())
+		})
+	}
+}
+
+func testResendVerification(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service user.Service) {
+	lgr := reporters.NewLogger("dev", "debug")
+
+	uh := handler.NewUserHandler(service)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/user/resend-verification", body)
+
+	ctx, err := client.WithContext(r.Context(), newOAuthClient(t))
+	require.NoError(t, err)
+
+	mdl.WithErrorHandler(lgr, uh.ResendVerification)(w, r.WithContext(ctx))
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestForgotPasswordSuccess(t *testing.T) {
+	userEmail := test.NewEmail()
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("ForgotPassword", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail).Return(nil)
+
+	b, err := json.Marshal(contract.ForgotPasswordRequest{Email: userEmail})
+	require.NoError(t, err)
+
+	expectedBody := `{"data":{"message":"password reset email sent if the account exists"},"success":true}`
+
+	testForgotPassword(t, http.StatusOK, expectedBody, bytes.NewBuffer(b), mockUserService)
+}
+
+func TestForgotPasswordSuccessWithMailOrigin(t *testing.T) {
+	userEmail := test.NewEmail()
+	cl := newOAuthClient(t)
+
+	hasOrigin := mock.MatchedBy(func(ctx context.Context) bool {
+		return mailer.OriginFromContext(ctx) == mailer.Origin{ClientID: cl.Id, Locale: "pt-br"}
+	})
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("ForgotPassword", hasOrigin, tenant.DefaultID, userEmail).Return(nil)
+
+	b, err := json.Marshal(contract.ForgotPasswordRequest{Email: userEmail})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/user/forgot-password", bytes.NewBuffer(b))
+	r.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+
+	ctx, err := client.WithContext(r.Context(), cl)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+
+	mdl.WithErrorHandler(reporters.NewLogger("dev", "debug"), handler.NewUserHandler(mockUserService).ForgotPassword)(w, r.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockUserService.AssertExpectations(t)
+}
+
+func TestForgotPasswordFailure(t *testing.T) {
+	userEmail := test.NewEmail()
+
+	toReader := func(reqBody contract.ForgotPasswordRequest) io.Reader {
+		b, err := json.Marshal(reqBody)
+		require.NoError(t, err)
+
+		return bytes.NewBuffer(b)
+	}
+
+	testCases := map[string]struct {
+		service      func() user.Service
+		body         io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when email is empty": {
+			service:      func() user.Service { return &user.MockService{} },
+			body:         toReader(contract.ForgotPasswordRequest{}),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"email cannot be empty"},"success":false}`,
+		},
+		"test failure when svc call fails": {
+			service: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("ForgotPassword", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail).
+					Return(erx.WithArgs(errors.New("failed to create password reset")))
+
+				return mockUserService
+			},
+			body:         toReader(contract.ForgotPasswordRequest{Email: userEmail}),
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":{"message":"internal server error"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testForgotPassword(t, testCase.expectedCode, testCase.expectedBody, testCase.body, testCase.service())
+		})
+	}
+}
+
+func testForgotPassword(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service user.Service) {
+	lgr := reporters.NewLogger("dev", "debug")
+
+	uh := handler.NewUserHandler(service)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/user/forgot-password", body)
+
+	ctx, err := client.WithContext(r.Context(), newOAuthClient(t))
+	require.NoError(t, err)
+
+	mdl.WithErrorHandler(lgr, uh.ForgotPassword)(w, r.WithContext(ctx))
+
+	assert.Equal(t, expectedCode, w.Code)
+	assert.Equal(t, expectedBody, w.Body.String())
+}
+
+func TestResetPasswordSuccess(t *testing.T) {
+	resetToken, newPassword := test.RandString(43), test.NewPassword()
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("ResetPassword", mock.Anything, resetToken, newPassword).Return(nil)
+
+	b, err := json.Marshal(contract.ResetPasswordRequest{Token: resetToken, NewPassword: newPassword})
+	require.NoError(t, err)
+
+	expectedBody := `{"data":{"message":"password reset successfully"},"success":true}`
+
+	testResetPassword(t, http.StatusOK, expectedBody, bytes.NewBuffer(b), mockUserService)
+}
+
+func TestResetPasswordFailure(t *testing.T) {
+	resetToken, newPassword := test.RandString(43), test.NewPassword()
+
+	toReader := func(reqBody contract.ResetPasswordRequest) io.Reader {
+		b, err := json.Marshal(reqBody)
+		require.NoError(t, err)
+
+		return bytes.NewBuffer(b)
+	}
+
+	testCases := map[string]struct {
+		service      func() user.Service
+		body         io.Reader
+		expectedCode int
+		expectedBody string
+	}{
+		"test failure when token is empty": {
+			service:      func() user.Service { return &user.MockService{} },
+			body:         toReader(contract.ResetPasswordRequest{NewPassword: newPassword}),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"token cannot be empty"},"success":false}`,
+		},
+		"test failure when new password is empty": {
+			service:      func() user.Service { return &user.MockService{} },
+			body:         toReader(contract.ResetPasswordRequest{Token: resetToken}),
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":{"message":"new password cannot be empty"},"success":false}`,
+		},
+		"test failure when token is invalid or used": {
+			service: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("ResetPassword", mock.Anything, resetToken, newPassword).
+					Return(erx.WithArgs(erx.AuthenticationError, errors.New("invalid token")))
+
+				return mockUserService
+			},
+			body:         toReader(contract.ResetPasswordRequest{Token: resetToken, NewPassword: newPassword}),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":{"message":"authentication failed"},"success":false}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			testResetPassword(t, testCase.expectedCode, testCase.expectedBody, testCase.body, testCase.service())
+		})
+	}
+}
+
+func testResetPassword(t *testing.T, expectedCode int, expectedBody string, body io.Reader, service user.Service) {
+	lgr := reporters.NewLogger("dev", "debug")
+
+	uh := handler.NewUserHandler(service)
+
+	w := httptest.NewRecorder()
+
+	r := httptest.NewRequest(http.MethodPost, "/user/reset-password", body)
+
+	mdl.WithErrorHandler(lgr, uh.ResetPassword)(w, r)
 
 	assert.Equal(t, expectedCode, w.Code)
 	assert.Equal(t, expectedBody, w.Body.String())
@@ -8,6 +8,7 @@ import (
 	"identification-service/pkg/client"
 	"identification-service/pkg/http/internal/resperr"
 	"identification-service/pkg/http/internal/util"
+	"identification-service/pkg/oauth"
 	reporters "identification-service/pkg/reporting"
 	"net/http"
 	"time"
@@ -24,6 +25,33 @@ func WithErrorHandler(lgr reporters.Logger, handler func(resp http.ResponseWrite
 	}
 }
 
+func WithOAuthErrorHandler(lgr reporters.Logger, handler func(resp http.ResponseWriter, req *http.Request) error) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		err := handler(resp, req)
+		if err == nil {
+			return
+		}
+
+		logError(lgr, err)
+
+		oe := resperr.MapOAuthError(err)
+		if oe.StatusCode() == http.StatusUnauthorized {
+			resp.Header().Set("WWW-Authenticate", authenticateChallenge(oe))
+		}
+
+		util.WriteOAuthFailureResponse(oe, resp)
+	}
+}
+
+func authenticateChallenge(oe resperr.OAuthError) string {
+	//NOTE: BEARER TOKEN FAILURES ARE CHALLENGED AS PER RFC 6750, CLIENT AUTHENTICATION FAILURES WITH BASIC AUTH
+	if oe.Code() == string(oauth.InvalidTokenError) {
+		return fmt.Sprintf(`Bearer error="%s"`, oe.Code())
+	}
+
+	return `Basic realm="oauth"`
+}
+
 //TODO: ADD MASKING BEFORE LOGGING REQ AND RESP
 func WithReqRespLog(lgr reporters.Logger, handler http.HandlerFunc) http.HandlerFunc {
 	return func(resp http.ResponseWriter, req *http.Request) {
@@ -119,14 +147,18 @@ func WithClientAuth(lgr reporters.Logger, service client.Service, handler http.H
 }
 
 func logAndWriteError(lgr reporters.Logger, resp http.ResponseWriter, err error) {
+	logError(lgr, err)
+
+	util.WriteFailureResponse(resperr.MapError(err), resp)
+}
+
+func logError(lgr reporters.Logger, err error) {
 	t, ok := err.(*erx.Erx)
 	if ok {
 		lgr.Error(t.String())
 	} else {
 		lgr.Error(err.Error())
 	}
-
-	util.WriteFailureResponse(resperr.MapError(err), resp)
 }
 
 func WithRequestContext(handler http.HandlerFunc) http.HandlerFunc {
@@ -334,6 +334,7 @@ func TestWithClientAuthenticationSuccess(t *testing.T) {
 		SessionTTL(test.RandInt(1440, 86701)).
 		SessionStrategy(test.ClientSessionStrategyRevokeOld).
 		MaxActiveSessions(test.RandInt(1, 10)).
+		KeyID(test.NewUUID()).
 		PrivateKey(test.ClientPriKey()).
 		Build()
 
@@ -372,6 +373,7 @@ func TestWithClientAuthenticationFailureWhenClientIsRevoked(t *testing.T) {
 		SessionTTL(test.RandInt(1440, 86701)).
 		SessionStrategy(test.ClientSessionStrategyRevokeOld).
 		MaxActiveSessions(test.RandInt(1, 10)).
+		KeyID(test.NewUUID()).
 		PrivateKey(test.ClientPriKey()).
 		Revoked(true).
 		Build()
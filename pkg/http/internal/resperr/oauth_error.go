@@ -0,0 +1,58 @@
+package resperr
+
+import (
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/oauth"
+	"net/http"
+)
+
+type OAuthError struct {
+	statusCode  int
+	code        string
+	description string
+}
+
+func (oe OAuthError) StatusCode() int {
+	return oe.statusCode
+}
+
+func (oe OAuthError) Code() string {
+	return oe.code
+}
+
+func (oe OAuthError) Description() string {
+	return oe.description
+}
+
+func NewOAuthError(statusCode int, code, description string) OAuthError {
+	return OAuthError{
+		statusCode:  statusCode,
+		code:        code,
+		description: description,
+	}
+}
+
+func MapOAuthError(err error) OAuthError {
+	t, ok := err.(*erx.Erx)
+	if !ok {
+		return NewOAuthError(http.StatusInternalServerError, string(oauth.ServerError), "")
+	}
+
+	k := t.Kind()
+
+	switch k {
+	case oauth.InvalidClientError:
+		return NewOAuthError(http.StatusUnauthorized, string(k), "client authentication failed")
+	case oauth.InvalidTokenError:
+		return NewOAuthError(http.StatusUnauthorized, string(k), t.Error())
+	case oauth.InvalidRequestError, oauth.InvalidGrantError, oauth.InvalidScopeError, oauth.InvalidTargetError, oauth.UnsupportedGrantTypeError, oauth.UnsupportedResponseTypeError,
+		oauth.AuthorizationPendingError, oauth.SlowDownError, oauth.AccessDeniedError, oauth.ExpiredTokenError:
+		return NewOAuthError(http.StatusBadRequest, string(k), t.Error())
+	case erx.ValidationError:
+		return NewOAuthError(http.StatusBadRequest, string(oauth.InvalidRequestError), t.Error())
+	case erx.AuthenticationError:
+		return NewOAuthError(http.StatusBadRequest, string(oauth.InvalidGrantError), "authentication failed")
+	default:
+		return NewOAuthError(http.StatusInternalServerError, string(oauth.ServerError), "")
+	}
+}
@@ -0,0 +1,65 @@
+package resperr_test
+
+import (
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/http/internal/resperr"
+	"identification-service/pkg/oauth"
+	"net/http"
+	"testing"
+)
+
+func TestOAuthErrorMap(t *testing.T) {
+	testCases := map[string]struct {
+		err             error
+		expectedRespErr resperr.OAuthError
+	}{
+		"test mapping for invalid client error": {
+			err:             erx.WithArgs(oauth.InvalidClientError, errors.New("client revoked")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusUnauthorized, "invalid_client", "client authentication failed"),
+		},
+		"test mapping for invalid grant error": {
+			err:             erx.WithArgs(oauth.InvalidGrantError, errors.New("code expired")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "invalid_grant", "code expired"),
+		},
+		"test mapping for unsupported grant type error": {
+			err:             erx.WithArgs(oauth.UnsupportedGrantTypeError, errors.New("unsupported grant type password")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "unsupported_grant_type", "unsupported grant type password"),
+		},
+		"test mapping for invalid target error": {
+			err:             erx.WithArgs(oauth.InvalidTargetError, errors.New("audience not allowed")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "invalid_target", "audience not allowed"),
+		},
+		"test mapping for authorization pending error": {
+			err:             erx.WithArgs(oauth.AuthorizationPendingError, errors.New("user has not approved the device yet")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "authorization_pending", "user has not approved the device yet"),
+		},
+		"test mapping for slow down error": {
+			err:             erx.WithArgs(oauth.SlowDownError, errors.New("polling interval is 10 seconds")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "slow_down", "polling interval is 10 seconds"),
+		},
+		"test mapping for validation error": {
+			err:             erx.WithArgs(erx.ValidationError, errors.New("code cannot be empty")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "invalid_request", "code cannot be empty"),
+		},
+		"test mapping for authentication error": {
+			err:             erx.WithArgs(erx.AuthenticationError, errors.New("subject token does not belong to the tenant")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusBadRequest, "invalid_grant", "authentication failed"),
+		},
+		"test mapping for lib error with no kind": {
+			err:             erx.WithArgs(errors.New("database error")),
+			expectedRespErr: resperr.NewOAuthError(http.StatusInternalServerError, "server_error", ""),
+		},
+		"test mapping for not lib error": {
+			err:             errors.New("database error"),
+			expectedRespErr: resperr.NewOAuthError(http.StatusInternalServerError, "server_error", ""),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedRespErr, resperr.MapOAuthError(testCase.err))
+		})
+	}
+}
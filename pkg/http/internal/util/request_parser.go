@@ -31,6 +31,18 @@ func ParseRequest(req *http.Request, data interface{}) error {
 	return nil
 }
 
+func ParseForm(req *http.Request) error {
+	if req == nil {
+		return e("", errors.New("request is nil"))
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return e("req.ParseForm", err)
+	}
+
+	return nil
+}
+
 //TODO: REMOVE THIS HELPER FUNCTION OR AT-LEAST RENAME
 func e(op string, err error) *erx.Erx {
 	opf := func() erx.Operation {
@@ -14,14 +14,7 @@ func writeResponse(code int, data []byte, resp http.ResponseWriter) {
 }
 
 func writeAPIResponse(code int, ar contract.APIResponse, resp http.ResponseWriter) {
-	b, err := json.Marshal(&ar)
-	if err != nil {
-		//TODO: SHOULD YOU WRITE INTERNAL SERVER ERROR WHEN MARSHALLING FAILS
-		writeResponse(http.StatusInternalServerError, []byte("internal server error"), resp)
-		return
-	}
-
-	writeResponse(code, b, resp)
+	WriteJSONResponse(code, &ar, resp)
 }
 
 func WriteSuccessResponse(statusCode int, data interface{}, resp http.ResponseWriter) {
@@ -31,3 +24,19 @@ func WriteSuccessResponse(statusCode int, data interface{}, resp http.ResponseWr
 func WriteFailureResponse(gr resperr.ResponseError, resp http.ResponseWriter) {
 	writeAPIResponse(gr.StatusCode(), contract.NewFailureResponse(gr.Description()), resp)
 }
+
+func WriteOAuthFailureResponse(oe resperr.OAuthError, resp http.ResponseWriter) {
+	resp.Header().Set("Cache-Control", "no-store")
+	WriteJSONResponse(oe.StatusCode(), contract.OAuthErrorResponse{Error: oe.Code(), ErrorDescription: oe.Description()}, resp)
+}
+
+func WriteJSONResponse(statusCode int, data interface{}, resp http.ResponseWriter) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		//TODO: SHOULD YOU WRITE INTERNAL SERVER ERROR WHEN MARSHALLING FAILS
+		writeResponse(http.StatusInternalServerError, []byte("internal server error"), resp)
+		return
+	}
+
+	writeResponse(statusCode, b, resp)
+}
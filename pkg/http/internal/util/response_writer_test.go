@@ -93,3 +93,50 @@ func TestWriteFailureResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteJSONResponse(t *testing.T) {
+	testCases := []struct {
+		name           string
+		actualResult   func() (string, int)
+		expectedCode   int
+		expectedResult string
+	}{
+		{
+			name: "write json response success",
+			actualResult: func() (string, int) {
+				type CusResp struct {
+					Keys []string `json:"keys"`
+				}
+
+				w := httptest.NewRecorder()
+
+				util.WriteJSONResponse(http.StatusOK, CusResp{Keys: []string{"key"}}, w)
+
+				return w.Body.String(), w.Code
+			},
+			expectedCode:   http.StatusOK,
+			expectedResult: "{\"keys\":[\"key\"]}",
+		},
+		{
+			name: "write json response failure",
+			actualResult: func() (string, int) {
+				w := httptest.NewRecorder()
+
+				util.WriteJSONResponse(http.StatusOK, make(chan int), w)
+
+				return w.Body.String(), w.Code
+			},
+			expectedCode:   http.StatusInternalServerError,
+			expectedResult: "internal server error",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			res, code := testCase.actualResult()
+
+			assert.Equal(t, testCase.expectedCode, code)
+			assert.Equal(t, testCase.expectedResult, res)
+		})
+	}
+}
@@ -10,18 +10,22 @@ import (
 	"identification-service/pkg/config"
 	"identification-service/pkg/http/internal/handler"
 	mdl "identification-service/pkg/http/internal/middleware"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/oauth"
 	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/role"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/user"
 	"net/http"
 )
 
-func NewRouter(cfg config.Config, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, us user.Service, ss session.Service) http.Handler {
-	return getChiRouter(cfg, lgr, pr, cs, us, ss)
+func NewRouter(cfg config.Config, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, us user.Service, ss session.Service, oa oauth.Service, rs role.Service, ts tenant.Service, ms mfa.Service) http.Handler {
+	return getChiRouter(cfg, lgr, pr, cs, us, ss, oa, rs, ts, ms)
 }
 
 //TODO: FIX MIDDLEWARE REPETITION CODE
-func getChiRouter(cfg config.Config, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, us user.Service, ss session.Service) *chi.Mux {
+func getChiRouter(cfg config.Config, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, us user.Service, ss session.Service, oa oauth.Service, rs role.Service, ts tenant.Service, ms mfa.Service) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(getCorsOptions(cfg.Env())))
@@ -31,7 +35,13 @@ func getChiRouter(cfg config.Config, lgr reporters.Logger, pr reporters.Promethe
 
 	registerUserRoutes(r, lgr, pr, cs, us)
 	registerSessionRoutes(r, lgr, pr, cs, ss)
+	registerMFARoutes(r, lgr, pr, cs, ss, us, ms)
 	registerClientRoutes(r, cfg.AuthConfig(), lgr, pr, cs)
+	registerRoleRoutes(r, cfg.AuthConfig(), lgr, pr, rs)
+	registerTenantRoutes(r, cfg.AuthConfig(), lgr, pr, ts)
+	registerKeyRoutes(r, cfg.TokenConfig(), lgr, pr, cs)
+	registerTokenRoutes(r, lgr, pr, cs, ss)
+	registerOAuthRoutes(r, cfg.OAuthConfig(), lgr, pr, oa)
 
 	return r
 }
@@ -74,9 +84,48 @@ func registerUserRoutes(r chi.Router, lgr reporters.Logger, pr reporters.Prometh
 		),
 	)
 
+	//NOTE: VERIFICATION AND RESET LINKS ARE OPENED BY THE USER, SO THERE ARE NO CLIENT CREDENTIALS TO CHECK
+	verifyEmailHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("user", "verify-email"),
+				mdl.WithErrorHandler(lgr, uh.VerifyEmail),
+			),
+		),
+	)
+
+	resendVerificationHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("user", "resend-verification"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, uh.ResendVerification)),
+			),
+		),
+	)
+
+	forgotPasswordHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("user", "forgot-password"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, uh.ForgotPassword)),
+			),
+		),
+	)
+
+	resetPasswordHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("user", "reset-password"),
+				mdl.WithErrorHandler(lgr, uh.ResetPassword),
+			),
+		),
+	)
+
 	r.Route("/user", func(r chi.Router) {
 		r.Post("/sign-up", signUpHandler)
 		r.Post("/update-password", updatePasswordHandler)
+		r.Post("/verify-email", verifyEmailHandler)
+		r.Post("/resend-verification", resendVerificationHandler)
+		r.Post("/forgot-password", forgotPasswordHandler)
+		r.Post("/reset-password", resetPasswordHandler)
 	})
 }
 
@@ -92,6 +141,51 @@ func registerSessionRoutes(r chi.Router, lgr reporters.Logger, pr reporters.Prom
 		),
 	)
 
+	completeLoginHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("session", "login-mfa"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, sh.CompleteLogin)),
+			),
+		),
+	)
+
+	beginWebAuthnLoginHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("session", "login-webauthn-begin"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, sh.BeginWebAuthnLogin)),
+			),
+		),
+	)
+
+	finishWebAuthnLoginHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("session", "login-webauthn-finish"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, sh.FinishWebAuthnLogin)),
+			),
+		),
+	)
+
+	sendMagicLinkHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("session", "login-magic-link"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, sh.SendMagicLink)),
+			),
+		),
+	)
+
+	loginWithMagicLinkHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("session", "login-magic-link-redeem"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, sh.LoginWithMagicLink)),
+			),
+		),
+	)
+
 	refreshTokenHandler := mdl.WithReqRespLog(lgr,
 		mdl.WithResponseHeaders(
 			mdl.WithPrometheus(pr, apiFunc("session", "refresh-token"),
@@ -112,11 +206,94 @@ func registerSessionRoutes(r chi.Router, lgr reporters.Logger, pr reporters.Prom
 
 	r.Route("/session", func(r chi.Router) {
 		r.Post("/login", loginHandler)
+		r.Post("/login/mfa", completeLoginHandler)
+		r.Post("/login/webauthn/begin", beginWebAuthnLoginHandler)
+		r.Post("/login/webauthn/finish", finishWebAuthnLoginHandler)
+		r.Post("/login/magic-link", sendMagicLinkHandler)
+		r.Post("/login/magic-link/redeem", loginWithMagicLinkHandler)
 		r.Post("/refresh-token", refreshTokenHandler)
 		r.Post("/logout", logoutHandler)
 	})
 }
 
+func registerMFARoutes(r chi.Router, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, ss session.Service, us user.Service, ms mfa.Service) {
+	mh := handler.NewMFAHandler(ms, ss, us)
+
+	//NOTE: ENROLLMENT IS DONE BY A LOGGED IN USER, THE ACCESS TOKEN IS READ FROM THE AUTHORIZATION HEADER
+	enrollTOTPHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "totp-enroll"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.EnrollTOTP)),
+			),
+		),
+	)
+
+	confirmTOTPHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "totp-confirm"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.ConfirmTOTP)),
+			),
+		),
+	)
+
+	beginWebAuthnRegistrationHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "webauthn-register-begin"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.BeginWebAuthnRegistration)),
+			),
+		),
+	)
+
+	finishWebAuthnRegistrationHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "webauthn-register-finish"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.FinishWebAuthnRegistration)),
+			),
+		),
+	)
+
+	regenerateRecoveryCodesHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "recovery-codes-regenerate"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.RegenerateRecoveryCodes)),
+			),
+		),
+	)
+
+	stepUpHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "step-up"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.StepUp)),
+			),
+		),
+	)
+
+	remainingRecoveryCodesHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("mfa", "recovery-codes-remaining"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, mh.RemainingRecoveryCodes)),
+			),
+		),
+	)
+
+	r.Route("/mfa", func(r chi.Router) {
+		r.Post("/totp/enroll", enrollTOTPHandler)
+		r.Post("/totp/confirm", confirmTOTPHandler)
+		r.Post("/webauthn/register/begin", beginWebAuthnRegistrationHandler)
+		r.Post("/webauthn/register/finish", finishWebAuthnRegistrationHandler)
+		r.Get("/recovery-codes", remainingRecoveryCodesHandler)
+		r.Post("/recovery-codes/regenerate", regenerateRecoveryCodesHandler)
+		r.Post("/step-up", stepUpHandler)
+	})
+}
+
 func registerClientRoutes(r chi.Router, cfg config.AuthConfig, lgr reporters.Logger, pr reporters.Prometheus, ss client.Service) {
 	ch := handler.NewClientHandler(ss)
 
@@ -140,12 +317,203 @@ func registerClientRoutes(r chi.Router, cfg config.AuthConfig, lgr reporters.Log
 		),
 	)
 
+	rotateKeysHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("client", "rotate-keys"),
+				mdl.WithBasicAuth(cred, lgr, "client",
+					mdl.WithErrorHandler(lgr, ch.RotateKeys)),
+			),
+		),
+	)
+
 	r.Route("/client", func(r chi.Router) {
 		r.Post("/register", registerHandler)
 		r.Post("/revoke", revokeHandler)
+		r.Post("/rotate-keys", rotateKeysHandler)
+	})
+}
+
+func registerRoleRoutes(r chi.Router, cfg config.AuthConfig, lgr reporters.Logger, pr reporters.Prometheus, rs role.Service) {
+	rh := handler.NewRoleHandler(rs)
+
+	cred := map[string]string{cfg.UserName(): cfg.Password()}
+
+	createHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("role", "create"),
+				mdl.WithBasicAuth(cred, lgr, "role",
+					mdl.WithErrorHandler(lgr, rh.Create)),
+			),
+		),
+	)
+
+	assignHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("role", "assign"),
+				mdl.WithBasicAuth(cred, lgr, "role",
+					mdl.WithErrorHandler(lgr, rh.Assign)),
+			),
+		),
+	)
+
+	unassignHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("role", "unassign"),
+				mdl.WithBasicAuth(cred, lgr, "role",
+					mdl.WithErrorHandler(lgr, rh.Unassign)),
+			),
+		),
+	)
+
+	r.Route("/role", func(r chi.Router) {
+		r.Post("/create", createHandler)
+		r.Post("/assign", assignHandler)
+		r.Post("/unassign", unassignHandler)
+	})
+}
+
+func registerTenantRoutes(r chi.Router, cfg config.AuthConfig, lgr reporters.Logger, pr reporters.Prometheus, ts tenant.Service) {
+	th := handler.NewTenantHandler(ts)
+
+	cred := map[string]string{cfg.UserName(): cfg.Password()}
+
+	createHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("tenant", "create"),
+				mdl.WithBasicAuth(cred, lgr, "tenant",
+					mdl.WithErrorHandler(lgr, th.Create)),
+			),
+		),
+	)
+
+	r.Route("/tenant", func(r chi.Router) {
+		r.Post("/create", createHandler)
+	})
+}
+
+func registerKeyRoutes(r chi.Router, cfg config.TokenConfig, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service) {
+	kh := handler.NewKeyHandler(cfg.Issuer(), cs)
+
+	jwksHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("key", "jwks"),
+				mdl.WithErrorHandler(lgr, kh.JWKS),
+			),
+		),
+	)
+
+	paserkHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("key", "paserk"),
+				mdl.WithErrorHandler(lgr, kh.Paserk),
+			),
+		),
+	)
+
+	r.Route("/.well-known", func(r chi.Router) {
+		r.Get("/jwks.json", jwksHandler)
+		r.Get("/paserk.json", paserkHandler)
+	})
+}
+
+func registerTokenRoutes(r chi.Router, lgr reporters.Logger, pr reporters.Prometheus, cs client.Service, ss session.Service) {
+	th := handler.NewTokenHandler(ss)
+
+	introspectHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("token", "introspect"),
+				mdl.WithClientAuth(lgr, cs,
+					mdl.WithErrorHandler(lgr, th.Introspect)),
+			),
+		),
+	)
+
+	r.Route("/token", func(r chi.Router) {
+		r.Post("/introspect", introspectHandler)
 	})
 }
 
+func registerOAuthRoutes(r chi.Router, cfg config.OAuthConfig, lgr reporters.Logger, pr reporters.Prometheus, oa oauth.Service) {
+	oh := handler.NewOAuthHandler(cfg.IssuerURL(), oa)
+
+	authorizePageHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "authorize-page"),
+				mdl.WithErrorHandler(lgr, oh.AuthorizePage),
+			),
+		),
+	)
+
+	authorizeHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "authorize"),
+				mdl.WithErrorHandler(lgr, oh.Authorize),
+			),
+		),
+	)
+
+	tokenHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "token"),
+				mdl.WithOAuthErrorHandler(lgr, oh.Token),
+			),
+		),
+	)
+
+	deviceAuthorizationHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "device-authorization"),
+				mdl.WithOAuthErrorHandler(lgr, oh.DeviceAuthorization),
+			),
+		),
+	)
+
+	devicePageHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "device-page"),
+				mdl.WithErrorHandler(lgr, oh.DevicePage),
+			),
+		),
+	)
+
+	verifyDeviceHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "verify-device"),
+				mdl.WithErrorHandler(lgr, oh.VerifyDevice),
+			),
+		),
+	)
+
+	userInfoHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "userinfo"),
+				mdl.WithOAuthErrorHandler(lgr, oh.UserInfo),
+			),
+		),
+	)
+
+	openIDConfigurationHandler := mdl.WithReqRespLog(lgr,
+		mdl.WithResponseHeaders(
+			mdl.WithPrometheus(pr, apiFunc("oauth", "openid-configuration"),
+				mdl.WithErrorHandler(lgr, oh.OpenIDConfiguration),
+			),
+		),
+	)
+
+	r.Route("/oauth", func(r chi.Router) {
+		r.Get("/authorize", authorizePageHandler)
+		r.Post("/authorize", authorizeHandler)
+		r.Post("/token", tokenHandler)
+		r.Post("/device/code", deviceAuthorizationHandler)
+		r.Get("/device", devicePageHandler)
+		r.Post("/device", verifyDeviceHandler)
+	})
+
+	r.Get("/userinfo", userInfoHandler)
+	r.Post("/userinfo", userInfoHandler)
+	r.Get("/.well-known/openid-configuration", openIDConfigurationHandler)
+}
+
 func apiFunc(api, path string) string {
 	return fmt.Sprintf("%s_%s", api, path)
 }
@@ -6,8 +6,12 @@ import (
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
 	"identification-service/pkg/http/router"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/oauth"
 	reporters "identification-service/pkg/reporting"
+	"identification-service/pkg/role"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/user"
 	"net/http"
 	"net/http/httptest"
@@ -19,9 +23,17 @@ func TestRouter(t *testing.T) {
 	mockConfig.On("Env").Return("dev")
 	mockConfig.On("AuthConfig").Return(config.AuthConfig{})
 
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Issuer").Return("identification-service")
+	mockConfig.On("TokenConfig").Return(mockTokenConfig)
+
+	mockOAuthConfig := &config.MockOAuthConfig{}
+	mockOAuthConfig.On("IssuerURL").Return("http://127.0.0.1:8089")
+	mockConfig.On("OAuthConfig").Return(mockOAuthConfig)
+
 	r := router.NewRouter(
 		mockConfig, &reporters.MockLogger{}, &reporters.MockPrometheus{},
-		&client.MockService{}, &user.MockService{}, &session.MockService{},
+		&client.MockService{}, &user.MockService{}, &session.MockService{}, &oauth.MockService{}, &role.MockService{}, &tenant.MockService{}, &mfa.MockService{},
 	)
 
 	rf := func(method, path string) *http.Request {
@@ -46,21 +58,120 @@ func TestRouter(t *testing.T) {
 		"test update password route": {
 			request: rf(http.MethodPost, "/user/update-password"),
 		},
+		"test verify email route": {
+			request: rf(http.MethodPost, "/user/verify-email"),
+		},
+		"test resend verification route": {
+			request: rf(http.MethodPost, "/user/resend-verification"),
+		},
+		"test forgot password route": {
+			request: rf(http.MethodPost, "/user/forgot-password"),
+		},
+		"test reset password route": {
+			request: rf(http.MethodPost, "/user/reset-password"),
+		},
 		"test session login route": {
 			request: rf(http.MethodPost, "/session/login"),
 		},
+		"test session login mfa route": {
+			request: rf(http.MethodPost, "/session/login/mfa"),
+		},
+		"test session login webauthn begin route": {
+			request: rf(http.MethodPost, "/session/login/webauthn/begin"),
+		},
+		"test session login webauthn finish route": {
+			request: rf(http.MethodPost, "/session/login/webauthn/finish"),
+		},
+		"test session login magic link route": {
+			request: rf(http.MethodPost, "/session/login/magic-link"),
+		},
+		"test session login magic link redeem route": {
+			request: rf(http.MethodPost, "/session/login/magic-link/redeem"),
+		},
 		"test session refresh token route": {
 			request: rf(http.MethodPost, "/session/refresh-token"),
 		},
 		"test session logout route": {
 			request: rf(http.MethodPost, "/session/logout"),
 		},
+		"test mfa totp enroll route": {
+			request: rf(http.MethodPost, "/mfa/totp/enroll"),
+		},
+		"test mfa totp confirm route": {
+			request: rf(http.MethodPost, "/mfa/totp/confirm"),
+		},
+		"test mfa webauthn register begin route": {
+			request: rf(http.MethodPost, "/mfa/webauthn/register/begin"),
+		},
+		"test mfa webauthn register finish route": {
+			request: rf(http.MethodPost, "/mfa/webauthn/register/finish"),
+		},
+		"test mfa recovery codes route": {
+			request: rf(http.MethodGet, "/mfa/recovery-codes"),
+		},
+		"test mfa recovery codes regenerate route": {
+			request: rf(http.MethodPost, "/mfa/recovery-codes/regenerate"),
+		},
+		"test mfa step up route": {
+			request: rf(http.MethodPost, "/mfa/step-up"),
+		},
 		"test client register route": {
 			request: rf(http.MethodPost, "/client/register"),
 		},
 		"test client revoke route": {
 			request: rf(http.MethodPost, "/client/revoke"),
 		},
+		"test client rotate keys route": {
+			request: rf(http.MethodPost, "/client/rotate-keys"),
+		},
+		"test role create route": {
+			request: rf(http.MethodPost, "/role/create"),
+		},
+		"test role assign route": {
+			request: rf(http.MethodPost, "/role/assign"),
+		},
+		"test role unassign route": {
+			request: rf(http.MethodPost, "/role/unassign"),
+		},
+		"test tenant create route": {
+			request: rf(http.MethodPost, "/tenant/create"),
+		},
+		"test jwks route": {
+			request: rf(http.MethodGet, "/.well-known/jwks.json"),
+		},
+		"test paserk route": {
+			request: rf(http.MethodGet, "/.well-known/paserk.json"),
+		},
+		"test token introspect route": {
+			request: rf(http.MethodPost, "/token/introspect"),
+		},
+		"test oauth authorize page route": {
+			request: rf(http.MethodGet, "/oauth/authorize"),
+		},
+		"test oauth authorize route": {
+			request: rf(http.MethodPost, "/oauth/authorize"),
+		},
+		"test oauth token route": {
+			request: rf(http.MethodPost, "/oauth/token"),
+		},
+		"test oauth device authorization route": {
+			request: rf(http.MethodPost, "/oauth/device/code"),
+		},
+		"test oauth device page route": {
+			request: rf(http.MethodGet, "/oauth/device"),
+		},
+		"test oauth verify device route": {
+			request: rf(http.MethodPost, "/oauth/device"),
+		},
+		"test userinfo get route": {
+			request: rf(http.MethodGet, "/userinfo"),
+		},
+		"test userinfo post route": {
+			request: rf(http.MethodPost, "/userinfo"),
+		},
+		"test openid configuration route": {
+			request: rf(http.MethodGet, "/.well-known/openid-configuration"),
+		},
 	}
 
 	for name, testCase := range testCases {
@@ -0,0 +1,158 @@
+package libcrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const dataKeySize = 32
+
+var envelopeMagic = []byte("ENV1")
+
+type KMS interface {
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(wrappedKey []byte) ([]byte, error)
+}
+
+type localKMS struct {
+	aead cipher.AEAD
+}
+
+func (lk *localKMS) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(lk.aead, dataKey)
+}
+
+func (lk *localKMS) Unwrap(wrappedKey []byte) ([]byte, error) {
+	return open(lk.aead, wrappedKey)
+}
+
+func NewLocalKMS(masterKey []byte) (KMS, error) {
+	if len(masterKey) != dataKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", dataKeySize, len(masterKey))
+	}
+
+	aead, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localKMS{aead: aead}, nil
+}
+
+type Envelope interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+type kmsEnvelope struct {
+	kms KMS
+}
+
+func (ke *kmsEnvelope) Seal(plaintext []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := ke.kms.Wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := seal(aead, plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrappedKey) > 0xffff {
+		return nil, fmt.Errorf("wrapped key of length %d is too long", len(wrappedKey))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(envelopeMagic)
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(wrappedKey)))
+	buf.Write(wrappedKey)
+	buf.Write(ciphertext)
+
+	return buf.Bytes(), nil
+}
+
+func (ke *kmsEnvelope) Open(sealed []byte) ([]byte, error) {
+	if !IsSealed(sealed) {
+		return nil, errors.New("data is not sealed")
+	}
+
+	rest := sealed[len(envelopeMagic):]
+	if len(rest) < 2 {
+		return nil, errors.New("sealed data is truncated")
+	}
+
+	wrappedKeyLength := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+
+	if len(rest) < wrappedKeyLength {
+		return nil, errors.New("sealed data is truncated")
+	}
+
+	dataKey, err := ke.kms.Unwrap(rest[:wrappedKeyLength])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, rest[wrappedKeyLength:])
+}
+
+func NewEnvelope(kms KMS) Envelope {
+	return &kmsEnvelope{
+		kms: kms,
+	}
+}
+
+func IsSealed(data []byte) bool {
+	//NOTE: A RAW ED25519 PRIVATE KEY IS ALWAYS EXACTLY 64 BYTES, A SEALED ONE NEVER IS
+	return len(data) != ed25519.PrivateKeySize && bytes.HasPrefix(data, envelopeMagic)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is truncated")
+	}
+
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, data, nil)
+}
@@ -0,0 +1,79 @@
+package libcrypto_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/test"
+	"testing"
+)
+
+func newTestEnvelope(t *testing.T) libcrypto.Envelope {
+	kms, err := libcrypto.NewLocalKMS(test.RandBytes(32))
+	require.NoError(t, err)
+
+	return libcrypto.NewEnvelope(kms)
+}
+
+func TestEnvelopeSealAndOpen(t *testing.T) {
+	envelope := newTestEnvelope(t)
+	_, privateKey := test.GenerateKey()
+
+	sealed, err := envelope.Seal(privateKey)
+	require.NoError(t, err)
+
+	assert.True(t, libcrypto.IsSealed(sealed))
+	assert.NotContains(t, string(sealed), string(privateKey))
+
+	other, err := envelope.Seal(privateKey)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, other)
+
+	opened, err := envelope.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(privateKey), opened)
+}
+
+func TestEnvelopeOpenFailure(t *testing.T) {
+	envelope := newTestEnvelope(t)
+	_, privateKey := test.GenerateKey()
+
+	sealed, err := envelope.Seal(privateKey)
+	require.NoError(t, err)
+
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	testCases := map[string]struct {
+		envelope libcrypto.Envelope
+		sealed   []byte
+	}{
+		"test failure when data is not sealed":           {envelope: envelope, sealed: privateKey},
+		"test failure when sealed data is truncated":     {envelope: envelope, sealed: sealed[:6]},
+		"test failure when ciphertext is tampered":       {envelope: envelope, sealed: tampered},
+		"test failure when master key is different":      {envelope: newTestEnvelope(t), sealed: sealed},
+		"test failure when wrapped key length overflows": {envelope: envelope, sealed: append([]byte("ENV1"), 0xff, 0xff, 0x00)},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := testCase.envelope.Open(testCase.sealed)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEnvelopeSealFailureWhenWrapFails(t *testing.T) {
+	mockKMS := &libcrypto.MockKMS{}
+	mockKMS.On("Wrap", mock.Anything).Return([]byte{}, errors.New("kms unavailable"))
+
+	_, err := libcrypto.NewEnvelope(mockKMS).Seal(test.RandBytes(64))
+	assert.Error(t, err)
+}
+
+func TestNewLocalKMSFailureWhenMasterKeyIsInvalid(t *testing.T) {
+	_, err := libcrypto.NewLocalKMS(test.RandBytes(16))
+	assert.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package libcrypto
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"time"
+)
+
+type KeyState string
+
+const (
+	PendingKey KeyState = "pending"
+	ActiveKey  KeyState = "active"
+	RetiredKey KeyState = "retired"
+)
+
+type Key struct {
+	ID         string
+	State      KeyState
+	PrivateKey ed25519.PrivateKey
+	UpdatedAt  time.Time
+}
+
+func (k Key) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+type KeyRing struct {
+	keys    []Key
+	version int
+}
+
+func (kr KeyRing) Keys() []Key {
+	return kr.keys
+}
+
+func (kr KeyRing) Version() int {
+	return kr.version
+}
+
+func (kr KeyRing) WithVersion(version int) KeyRing {
+	return KeyRing{keys: kr.keys, version: version}
+}
+
+func (kr KeyRing) SigningKey() (Key, error) {
+	for _, key := range kr.keys {
+		if key.State == ActiveKey {
+			return key, nil
+		}
+	}
+
+	return Key{}, erx.WithArgs(erx.Operation("KeyRing.SigningKey"), errors.New("no active key found"))
+}
+
+func (kr KeyRing) Rotate(now time.Time, generate func() (Key, error)) (KeyRing, error) {
+	wrap := func(err error) (KeyRing, error) {
+		return KeyRing{}, erx.WithArgs(erx.Operation("KeyRing.Rotate"), err)
+	}
+
+	keys := make([]Key, len(kr.keys))
+	copy(keys, kr.keys)
+
+	//NOTE: A RING WITHOUT A PENDING KEY GETS ONE FIRST, SO AN EMPTY RING ROTATES INTO A USABLE ONE
+	if !hasState(keys, PendingKey) {
+		key, err := generate()
+		if err != nil {
+			return wrap(err)
+		}
+
+		keys = append(keys, Key{ID: key.ID, PrivateKey: key.PrivateKey, State: PendingKey, UpdatedAt: now})
+	}
+
+	//NOTE: THE ACTIVE KEY RETIRES AND THE PENDING KEY, ALREADY PUBLISHED TO VERIFIERS, STARTS SIGNING
+	for i := range keys {
+		switch keys[i].State {
+		case ActiveKey:
+			keys[i].State, keys[i].UpdatedAt = RetiredKey, now
+		case PendingKey:
+			keys[i].State, keys[i].UpdatedAt = ActiveKey, now
+		}
+	}
+
+	next, err := generate()
+	if err != nil {
+		return wrap(err)
+	}
+
+	keys = append(keys, Key{ID: next.ID, PrivateKey: next.PrivateKey, State: PendingKey, UpdatedAt: now})
+
+	return KeyRing{keys: keys, version: kr.version}, nil
+}
+
+func hasState(keys []Key, state KeyState) bool {
+	for _, key := range keys {
+		if key.State == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+func NewKeyRing(keys ...Key) KeyRing {
+	return KeyRing{keys: keys}
+}
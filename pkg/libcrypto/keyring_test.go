@@ -0,0 +1,78 @@
+package libcrypto_test
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/test"
+	"testing"
+	"time"
+)
+
+func newTestKey() (libcrypto.Key, error) {
+	_, pri := test.GenerateKey()
+	return libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri}, nil
+}
+
+func keysByState(keyRing libcrypto.KeyRing, state libcrypto.KeyState) []libcrypto.Key {
+	var keys []libcrypto.Key
+
+	for _, key := range keyRing.Keys() {
+		if key.State == state {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func TestKeyRingRotateEmptyRing(t *testing.T) {
+	keyRing, err := libcrypto.NewKeyRing().Rotate(time.Now(), newTestKey)
+	require.NoError(t, err)
+
+	assert.Len(t, keysByState(keyRing, libcrypto.ActiveKey), 1)
+	assert.Len(t, keysByState(keyRing, libcrypto.PendingKey), 1)
+	assert.Len(t, keysByState(keyRing, libcrypto.RetiredKey), 0)
+}
+
+func TestKeyRingRotatePromotesPendingKey(t *testing.T) {
+	keyRing, err := libcrypto.NewKeyRing().Rotate(time.Now(), newTestKey)
+	require.NoError(t, err)
+
+	active, pending := keysByState(keyRing, libcrypto.ActiveKey)[0], keysByState(keyRing, libcrypto.PendingKey)[0]
+
+	rotated, err := keyRing.Rotate(time.Now(), newTestKey)
+	require.NoError(t, err)
+
+	signingKey, err := rotated.SigningKey()
+	require.NoError(t, err)
+
+	assert.Equal(t, pending.ID, signingKey.ID)
+	assert.Equal(t, active.ID, keysByState(rotated, libcrypto.RetiredKey)[0].ID)
+	assert.Len(t, keysByState(rotated, libcrypto.PendingKey), 1)
+
+	original, err := keyRing.SigningKey()
+	require.NoError(t, err)
+	assert.Equal(t, active.ID, original.ID)
+}
+
+func TestKeyRingRotateKeepsVersion(t *testing.T) {
+	keyRing, err := libcrypto.NewKeyRing().WithVersion(4).Rotate(time.Now(), newTestKey)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, keyRing.Version())
+}
+
+func TestKeyRingRotateFailureWhenGenerationFails(t *testing.T) {
+	_, err := libcrypto.NewKeyRing().Rotate(time.Now(), func() (libcrypto.Key, error) {
+		return libcrypto.Key{}, errors.New("failed to generate key")
+	})
+
+	assert.Error(t, err)
+}
+
+func TestKeyRingSigningKeyFailureWhenNoActiveKey(t *testing.T) {
+	_, err := libcrypto.NewKeyRing(libcrypto.Key{ID: test.NewUUID(), State: libcrypto.PendingKey}).SigningKey()
+	assert.Error(t, err)
+}
@@ -18,3 +18,31 @@ func (mock *MockEd25519Generator) FromEncodedPem(encodedPem string) (ed25519.Pub
 	args := mock.Called(encodedPem)
 	return args.Get(0).(ed25519.PublicKey), args.Get(1).(ed25519.PrivateKey), args.Error(2)
 }
+
+type MockKMS struct {
+	mock.Mock
+}
+
+func (mock *MockKMS) Wrap(dataKey []byte) ([]byte, error) {
+	args := mock.Called(dataKey)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (mock *MockKMS) Unwrap(wrappedKey []byte) ([]byte, error) {
+	args := mock.Called(wrappedKey)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+type MockEnvelope struct {
+	mock.Mock
+}
+
+func (mock *MockEnvelope) Seal(plaintext []byte) ([]byte, error) {
+	args := mock.Called(plaintext)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (mock *MockEnvelope) Open(sealed []byte) ([]byte, error) {
+	args := mock.Called(sealed)
+	return args.Get(0).([]byte), args.Error(1)
+}
@@ -0,0 +1,27 @@
+package libcrypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	paserkPublicHeader = "k2.public."
+	paserkIDHeader     = "k2.pid."
+	paserkIDSize       = 33
+)
+
+func PaserkPublic(publicKey ed25519.PublicKey) string {
+	return paserkPublicHeader + base64.RawURLEncoding.EncodeToString(publicKey)
+}
+
+func PaserkID(publicKey ed25519.PublicKey) string {
+	//NOTE: NEW ONLY FAILS FOR AN INVALID SIZE OR KEY, NEITHER OF WHICH CAN HAPPEN HERE
+	h, _ := blake2b.New(paserkIDSize, nil)
+
+	h.Write([]byte(paserkIDHeader))
+	h.Write([]byte(PaserkPublic(publicKey)))
+
+	return paserkIDHeader + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package libcrypto_test
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/test"
+	"strings"
+	"testing"
+)
+
+func TestPaserkPublic(t *testing.T) {
+	pub, _ := test.GenerateKey()
+
+	paserk := libcrypto.PaserkPublic(pub)
+
+	assert.True(t, strings.HasPrefix(paserk, "k2.public."))
+
+	key, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(paserk, "k2.public."))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(pub), key)
+}
+
+func TestPaserkID(t *testing.T) {
+	pub, _ := test.GenerateKey()
+	otherPub, _ := test.GenerateKey()
+
+	id := libcrypto.PaserkID(pub)
+
+	assert.True(t, strings.HasPrefix(id, "k2.pid."))
+	assert.Equal(t, id, libcrypto.PaserkID(pub))
+	assert.NotEqual(t, id, libcrypto.PaserkID(otherPub))
+}
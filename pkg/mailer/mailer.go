@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"github.com/nsnikhil/erx"
+)
+
+type Kind string
+
+const (
+	VerificationMail  Kind = "verification"
+	PasswordResetMail Kind = "password_reset"
+	SecurityAlertMail Kind = "security_alert"
+	MagicLinkMail     Kind = "magic_link"
+)
+
+type ctxKey string
+
+const originKey ctxKey = "origin"
+
+type Origin struct {
+	ClientID string
+	Locale   string
+}
+
+func WithOrigin(ctx context.Context, origin Origin) context.Context {
+	return context.WithValue(ctx, originKey, origin)
+}
+
+func OriginFromContext(ctx context.Context) Origin {
+	//NOTE: MAIL SENT WITHOUT AN ORIGIN IS RENDERED WITH THE DEFAULT TEMPLATES
+	origin, ok := ctx.Value(originKey).(Origin)
+	if !ok {
+		return Origin{}
+	}
+
+	return origin
+}
+
+type Mailer interface {
+	Mail(ctx context.Context, kind Kind, to string, origin Origin, data map[string]string) error
+}
+
+type templateMailer struct {
+	from     string
+	renderer Renderer
+	sender   Sender
+}
+
+func (tm *templateMailer) Mail(ctx context.Context, kind Kind, to string, origin Origin, data map[string]string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Mailer.Mail"), err) }
+
+	message, err := tm.renderer.Render(kind, origin, data)
+	if err != nil {
+		return wrap(err)
+	}
+
+	message.From = tm.from
+	message.To = to
+
+	err = tm.sender.Send(ctx, message)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func NewMailer(from string, renderer Renderer, sender Sender) Mailer {
+	return &templateMailer{
+		from:     from,
+		renderer: renderer,
+		sender:   sender,
+	}
+}
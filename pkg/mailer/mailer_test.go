@@ -0,0 +1,85 @@
+package mailer_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/test"
+	"testing"
+)
+
+func TestOriginFromContext(t *testing.T) {
+	origin := mailer.Origin{ClientID: test.NewUUID(), Locale: "pt-br"}
+
+	assert.Equal(t, origin, mailer.OriginFromContext(mailer.WithOrigin(context.Background(), origin)))
+	assert.Equal(t, mailer.Origin{}, mailer.OriginFromContext(context.Background()))
+}
+
+func TestMailSuccess(t *testing.T) {
+	to := test.NewEmail()
+	origin := mailer.Origin{ClientID: test.NewUUID()}
+	data := map[string]string{"Email": to, "Token": test.RandString(43)}
+
+	mockRenderer := &mailer.MockRenderer{}
+	mockRenderer.On("Render", mailer.VerificationMail, origin, data).
+		Return(mailer.Message{Subject: "Verify your email", Body: "body"}, nil)
+
+	mockSender := &mailer.MockSender{}
+	mockSender.On("Send", mock.Anything, mailer.Message{From: "no-reply@mail.com", To: to, Subject: "Verify your email", Body: "body"}).
+		Return(nil)
+
+	ml := mailer.NewMailer("no-reply@mail.com", mockRenderer, mockSender)
+
+	err := ml.Mail(context.Background(), mailer.VerificationMail, to, origin, data)
+	require.NoError(t, err)
+
+	mockSender.AssertExpectations(t)
+}
+
+func TestMailFailure(t *testing.T) {
+	to := test.NewEmail()
+	data := map[string]string{"Email": to}
+
+	testCases := map[string]struct {
+		renderer func() mailer.Renderer
+		sender   func() mailer.Sender
+	}{
+		"test failure when render fails": {
+			renderer: func() mailer.Renderer {
+				mockRenderer := &mailer.MockRenderer{}
+				mockRenderer.On("Render", mailer.PasswordResetMail, mailer.Origin{}, data).
+					Return(mailer.Message{}, errors.New("failed to render"))
+
+				return mockRenderer
+			},
+			sender: func() mailer.Sender { return &mailer.MockSender{} },
+		},
+		"test failure when send fails": {
+			renderer: func() mailer.Renderer {
+				mockRenderer := &mailer.MockRenderer{}
+				mockRenderer.On("Render", mailer.PasswordResetMail, mailer.Origin{}, data).
+					Return(mailer.Message{Subject: "Reset your password"}, nil)
+
+				return mockRenderer
+			},
+			sender: func() mailer.Sender {
+				mockSender := &mailer.MockSender{}
+				mockSender.On("Send", mock.Anything, mock.AnythingOfType("Message")).Return(errors.New("failed to send"))
+
+				return mockSender
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ml := mailer.NewMailer("no-reply@mail.com", testCase.renderer(), testCase.sender())
+
+			err := ml.Mail(context.Background(), mailer.PasswordResetMail, to, mailer.Origin{}, data)
+			require.Error(t, err)
+		})
+	}
+}
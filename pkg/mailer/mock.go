@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (mock *MockMailer) Mail(ctx context.Context, kind Kind, to string, origin Origin, data map[string]string) error {
+	args := mock.Called(ctx, kind, to, origin, data)
+	return args.Error(0)
+}
+
+type MockRenderer struct {
+	mock.Mock
+}
+
+func (mock *MockRenderer) Render(kind Kind, origin Origin, data map[string]string) (Message, error) {
+	args := mock.Called(kind, origin, data)
+	return args.Get(0).(Message), args.Error(1)
+}
+
+type MockSender struct {
+	mock.Mock
+}
+
+func (mock *MockSender) Send(ctx context.Context, message Message) error {
+	args := mock.Called(ctx, message)
+	return args.Error(0)
+}
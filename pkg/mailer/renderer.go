@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultOwner  = "default"
+	defaultLocale = "en"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+type Renderer interface {
+	Render(kind Kind, origin Origin, data map[string]string) (Message, error)
+}
+
+type templateRenderer struct {
+	sources []fs.FS
+}
+
+func (tr *templateRenderer) Render(kind Kind, origin Origin, data map[string]string) (Message, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Renderer.Render"), err) }
+
+	fsys, name, ok := tr.lookup(kind, origin)
+	if !ok {
+		return Message{}, wrap(fmt.Errorf("no template found for %s", kind))
+	}
+
+	tmpl, err := template.New(path.Base(name)).Option("missingkey=error").ParseFS(fsys, name)
+	if err != nil {
+		return Message{}, wrap(err)
+	}
+
+	subject, err := execute(tmpl, "subject", data)
+	if err != nil {
+		return Message{}, wrap(err)
+	}
+
+	body, err := execute(tmpl, "body", data)
+	if err != nil {
+		return Message{}, wrap(err)
+	}
+
+	return Message{Subject: strings.TrimSpace(subject), Body: strings.TrimSpace(body) + "\n"}, nil
+}
+
+func (tr *templateRenderer) lookup(kind Kind, origin Origin) (fs.FS, string, bool) {
+	//NOTE: THE USER'S LANGUAGE WINS OVER THE CLIENT, SO A CLIENT TEMPLATE IS ONLY USED FOR A LOCALE IT WAS WRITTEN IN
+	for _, dir := range candidates(origin) {
+		name := path.Join(dir, string(kind)+".tmpl")
+
+		for _, fsys := range tr.sources {
+			if _, err := fs.Stat(fsys, name); err == nil {
+				return fsys, name, true
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+func candidates(origin Origin) []string {
+	owners := []string{defaultOwner}
+	if isValidSegment(origin.ClientID) {
+		owners = []string{origin.ClientID, defaultOwner}
+	}
+
+	var locales []string
+	if locale := strings.ToLower(origin.Locale); isValidSegment(locale) {
+		locales = append(locales, locale)
+
+		if i := strings.Index(locale, "-"); i > 0 {
+			locales = append(locales, locale[:i])
+		}
+	}
+
+	locales = append(locales, defaultLocale)
+
+	var dirs []string
+	for _, locale := range locales {
+		for _, owner := range owners {
+			dirs = append(dirs, path.Join(owner, locale))
+		}
+	}
+
+	return dirs
+}
+
+func isValidSegment(segment string) bool {
+	if len(segment) == 0 {
+		return false
+	}
+
+	//NOTE: SEGMENTS BECOME PATHS, SO ANYTHING BUT LETTERS, DIGITS AND DASHES IS REJECTED
+	for _, c := range segment {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func ParseLocale(acceptLanguage string) string {
+	first := strings.Split(acceptLanguage, ",")[0]
+	locale := strings.TrimSpace(strings.Split(first, ";")[0])
+
+	if !isValidSegment(locale) {
+		return ""
+	}
+
+	return strings.ToLower(locale)
+}
+
+func execute(tmpl *template.Template, name string, data map[string]string) (string, error) {
+	var b bytes.Buffer
+
+	if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+func NewRenderer(templateDir string) Renderer {
+	defaults, _ := fs.Sub(embeddedTemplates, "templates")
+
+	var sources []fs.FS
+	if len(templateDir) != 0 {
+		sources = append(sources, os.DirFS(templateDir))
+	}
+
+	return &templateRenderer{
+		sources: append(sources, defaults),
+	}
+}
@@ -0,0 +1,135 @@
+package mailer_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/test"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	email, token := test.NewEmail(), test.RandString(43)
+
+	testCases := map[string]struct {
+		kind    mailer.Kind
+		data    map[string]string
+		subject string
+		content string
+	}{
+		"test render verification": {
+			kind:    mailer.VerificationMail,
+			data:    map[string]string{"Email": email, "Token": token},
+			subject: "Verify your email",
+			content: token,
+		},
+		"test render password reset": {
+			kind:    mailer.PasswordResetMail,
+			data:    map[string]string{"Email": email, "Token": token},
+			subject: "Reset your password",
+			content: token,
+		},
+		"test render magic link": {
+			kind:    mailer.MagicLinkMail,
+			data:    map[string]string{"Email": email, "Token": token},
+			subject: "Your sign in link",
+			content: token,
+		},
+		"test render security alert": {
+			kind:    mailer.SecurityAlertMail,
+			data:    map[string]string{"Email": email, "Alert": "refresh_token_reuse"},
+			subject: "Security alert for your account",
+			content: "signed out",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			message, err := mailer.NewRenderer("").Render(testCase.kind, mailer.Origin{Locale: "de-ch"}, testCase.data)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.subject, message.Subject)
+			assert.Contains(t, message.Body, testCase.content)
+		})
+	}
+}
+
+func TestRenderClientAndLocaleTemplates(t *testing.T) {
+	clientID := test.NewUUID()
+	dir := t.TempDir()
+
+	writeTemplate(t, dir, "default/fr/verification.tmpl", `{{define "subject"}}Vérifiez votre email{{end}}{{define "body"}}{{.Token}}{{end}}`)
+	writeTemplate(t, dir, clientID+"/en/verification.tmpl", `{{define "subject"}}Welcome to the app{{end}}{{define "body"}}https://app.com/verify?token={{.Token}}{{end}}`)
+
+	data := map[string]string{"Email": test.NewEmail(), "Token": test.RandString(43)}
+
+	testCases := map[string]struct {
+		origin  mailer.Origin
+		subject string
+	}{
+		"test client template is preferred": {
+			origin:  mailer.Origin{ClientID: clientID, Locale: "en-gb"},
+			subject: "Welcome to the app",
+		},
+		"test default locale template is used when client has none": {
+			origin:  mailer.Origin{ClientID: clientID, Locale: "fr-ca"},
+			subject: "Vérifiez votre email",
+		},
+		"test embedded template is used when nothing matches": {
+			origin:  mailer.Origin{ClientID: test.NewUUID(), Locale: "es"},
+			subject: "Verify your email",
+		},
+		"test invalid locale falls back to the default locale": {
+			origin:  mailer.Origin{ClientID: clientID, Locale: "../fr"},
+			subject: "Welcome to the app",
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			message, err := mailer.NewRenderer(dir).Render(mailer.VerificationMail, testCase.origin, data)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.subject, message.Subject)
+		})
+	}
+}
+
+func TestRenderFailure(t *testing.T) {
+	testCases := map[string]struct {
+		kind mailer.Kind
+		data map[string]string
+	}{
+		"test failure when kind has no template": {
+			kind: mailer.Kind("unknown"),
+			data: map[string]string{},
+		},
+		"test failure when data is missing": {
+			kind: mailer.VerificationMail,
+			data: map[string]string{"Email": test.NewEmail()},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := mailer.NewRenderer("").Render(testCase.kind, mailer.Origin{}, testCase.data)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	assert.Equal(t, "pt-br", mailer.ParseLocale("pt-BR,pt;q=0.9,en;q=0.8"))
+	assert.Equal(t, "fr", mailer.ParseLocale("fr;q=0.7"))
+	assert.Equal(t, "", mailer.ParseLocale("*"))
+	assert.Equal(t, "", mailer.ParseLocale(""))
+}
+
+func writeTemplate(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
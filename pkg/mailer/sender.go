@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/config"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+func (m Message) bytes() []byte {
+	var b bytes.Buffer
+
+	//NOTE: HEADERS ARE STRIPPED OF LINE BREAKS SO RENDERED VALUES CANNOT INJECT HEADERS OF THEIR OWN
+	fmt.Fprintf(&b, "From: %s\r\n", header(m.From))
+	fmt.Fprintf(&b, "To: %s\r\n", header(m.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header(m.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+
+	return b.Bytes()
+}
+
+func header(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+type smtpSender struct {
+	addr string
+	auth smtp.Auth
+}
+
+func (ss *smtpSender) Send(ctx context.Context, message Message) error {
+	err := smtp.SendMail(ss.addr, ss.auth, message.From, []string{message.To}, message.bytes())
+	if err != nil {
+		return erx.WithArgs(erx.Operation("SMTPSender.Send"), err)
+	}
+
+	return nil
+}
+
+func NewSMTPSender(cfg config.MailerConfig) Sender {
+	var auth smtp.Auth
+	if len(cfg.SMTPUsername()) != 0 {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername(), cfg.SMTPPassword(), cfg.SMTPHost())
+	}
+
+	return &smtpSender{
+		addr: net.JoinHostPort(cfg.SMTPHost(), cfg.SMTPPort()),
+		auth: auth,
+	}
+}
+
+type fileSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (fs *fileSender) Send(ctx context.Context, message Message) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, err := fs.w.Write(append(message.bytes(), "\r\n\r\n"...))
+	if err != nil {
+		return erx.WithArgs(erx.Operation("FileSender.Send"), err)
+	}
+
+	return nil
+}
+
+func NewFileSender(w io.Writer) Sender {
+	return &fileSender{
+		w: w,
+	}
+}
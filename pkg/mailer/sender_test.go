@@ -0,0 +1,29 @@
+package mailer_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/mailer"
+	"testing"
+)
+
+func TestFileSenderSend(t *testing.T) {
+	var b bytes.Buffer
+
+	message := mailer.Message{
+		From:    "no-reply@mail.com",
+		To:      "user@mail.com",
+		Subject: "Verify your email\r\nBcc: other@mail.com",
+		Body:    "code\n",
+	}
+
+	err := mailer.NewFileSender(&b).Send(context.Background(), message)
+	require.NoError(t, err)
+
+	assert.Contains(t, b.String(), "From: no-reply@mail.com\r\n")
+	assert.Contains(t, b.String(), "To: user@mail.com\r\n")
+	assert.Contains(t, b.String(), "Subject: Verify your emailBcc: other@mail.com\r\n")
+	assert.Contains(t, b.String(), "\r\n\r\ncode\n")
+}
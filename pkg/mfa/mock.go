@@ -0,0 +1,161 @@
+package mfa
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/webauthn"
+	"time"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (mock *MockService) EnrollTOTP(ctx context.Context, userID, accountName string) (Enrollment, error) {
+	args := mock.Called(ctx, userID, accountName)
+	return args.Get(0).(Enrollment), args.Error(1)
+}
+
+func (mock *MockService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	args := mock.Called(ctx, userID, code)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (mock *MockService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	args := mock.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (mock *MockService) Challenge(ctx context.Context, clientID, userID string) (string, error) {
+	args := mock.Called(ctx, clientID, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) VerifyChallenge(ctx context.Context, clientID, challengeToken, code string) (string, error) {
+	args := mock.Called(ctx, clientID, challengeToken, code)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) BeginWebAuthnRegistration(ctx context.Context, userID, userName, displayName string) (string, webauthn.CreationOptions, error) {
+	args := mock.Called(ctx, userID, userName, displayName)
+	return args.String(0), args.Get(1).(webauthn.CreationOptions), args.Error(2)
+}
+
+func (mock *MockService) FinishWebAuthnRegistration(ctx context.Context, userID, challengeID string, attestation webauthn.Attestation) ([]string, error) {
+	args := mock.Called(ctx, userID, challengeID, attestation)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (mock *MockService) BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error) {
+	args := mock.Called(ctx, mfaToken)
+	return args.String(0), args.Get(1).(webauthn.RequestOptions), args.Error(2)
+}
+
+func (mock *MockService) FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion) (string, error) {
+	args := mock.Called(ctx, challengeID, assertion)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	args := mock.Called(ctx, userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (mock *MockService) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	args := mock.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (mock *MockStore) SaveTOTP(ctx context.Context, userID string, secret []byte) error {
+	args := mock.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (mock *MockStore) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	args := mock.Called(ctx, userID)
+	return args.Get(0).(TOTP), args.Error(1)
+}
+
+func (mock *MockStore) ConfirmTOTP(ctx context.Context, userID string, step int64) error {
+	args := mock.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (mock *MockStore) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	args := mock.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (mock *MockStore) IsEnabled(ctx context.Context, userID, rpID string) (bool, error) {
+	args := mock.Called(ctx, userID, rpID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (mock *MockStore) CreateChallenge(ctx context.Context, id, clientID, userID string, expiresAt time.Time) error {
+	args := mock.Called(ctx, id, clientID, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (mock *MockStore) AttemptChallenge(ctx context.Context, id, clientID string, maxAttempts int, now time.Time) (string, error) {
+	args := mock.Called(ctx, id, clientID, maxAttempts, now)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) ConsumeChallenge(ctx context.Context, id string) error {
+	args := mock.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (mock *MockStore) CreateWebAuthnChallenge(ctx context.Context, clientID, userID, mfaChallengeID, ceremony string, challenge []byte, expiresAt time.Time) (string, error) {
+	args := mock.Called(ctx, clientID, userID, mfaChallengeID, ceremony, challenge, expiresAt)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) ConsumeWebAuthnChallenge(ctx context.Context, id, clientID, ceremony string, now time.Time) (string, string, []byte, error) {
+	args := mock.Called(ctx, id, clientID, ceremony, now)
+	return args.String(0), args.String(1), args.Get(2).([]byte), args.Error(3)
+}
+
+func (mock *MockStore) SaveWebAuthnCredential(ctx context.Context, userID, rpID string, credential webauthn.Credential) error {
+	args := mock.Called(ctx, userID, rpID, credential)
+	return args.Error(0)
+}
+
+func (mock *MockStore) GetWebAuthnCredentialIDs(ctx context.Context, userID, rpID string) ([][]byte, error) {
+	args := mock.Called(ctx, userID, rpID)
+	return args.Get(0).([][]byte), args.Error(1)
+}
+
+func (mock *MockStore) GetWebAuthnCredential(ctx context.Context, id []byte, rpID string) (string, webauthn.Credential, error) {
+	args := mock.Called(ctx, id, rpID)
+	return args.String(0), args.Get(1).(webauthn.Credential), args.Error(2)
+}
+
+func (mock *MockStore) UpdateSignCount(ctx context.Context, id []byte, previous, next uint32) error {
+	args := mock.Called(ctx, id, previous, next)
+	return args.Error(0)
+}
+
+func (mock *MockStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error {
+	args := mock.Called(ctx, userID, codes)
+	return args.Error(0)
+}
+
+func (mock *MockStore) GetRecoveryCodes(ctx context.Context, userID string) ([]RecoveryCode, error) {
+	args := mock.Called(ctx, userID)
+	return args.Get(0).([]RecoveryCode), args.Error(1)
+}
+
+func (mock *MockStore) UseRecoveryCode(ctx context.Context, id string) error {
+	args := mock.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (mock *MockStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	args := mock.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
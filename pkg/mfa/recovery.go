@@ -0,0 +1,40 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeBytes  = 7
+	recoveryCodeLength = 10
+	recoveryCodeGroup  = 5
+)
+
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(secretEncoding.EncodeToString(b))[:recoveryCodeLength]
+
+		//NOTE: CODES ARE SHOWN IN GROUPS TO MAKE THEM EASIER TO WRITE DOWN, THE SEPARATOR IS IGNORED WHEN ONE IS USED
+		codes[i] = code[:recoveryCodeGroup] + "-" + code[recoveryCodeGroup:]
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func isRecoveryCode(code string) bool {
+	return len(normalizeRecoveryCode(code)) == recoveryCodeLength
+}
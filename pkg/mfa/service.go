@@ -0,0 +1,546 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/password"
+	"identification-service/pkg/token"
+	"identification-service/pkg/util"
+	"identification-service/pkg/webauthn"
+	"time"
+)
+
+const (
+	challengePurpose = "mfa-challenge"
+
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+type Service interface {
+	EnrollTOTP(ctx context.Context, userID, accountName string) (Enrollment, error)
+	ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error)
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	Challenge(ctx context.Context, clientID, userID string) (string, error)
+	VerifyChallenge(ctx context.Context, clientID, challengeToken, code string) (string, error)
+
+	BeginWebAuthnRegistration(ctx context.Context, userID, userName, displayName string) (string, webauthn.CreationOptions, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID, challengeID string, attestation webauthn.Attestation) ([]string, error)
+	BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error)
+	FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion) (string, error)
+
+	RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error)
+	RemainingRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type mfaService struct {
+	cfg     config.MFAConfig
+	store   Store
+	signer  token.Signer
+	encoder password.Encoder
+}
+
+func (ms *mfaService) EnrollTOTP(ctx context.Context, userID, accountName string) (Enrollment, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.EnrollTOTP"), err) }
+
+	if !util.IsValidUUID(userID) {
+		return Enrollment{}, wrap(erx.WithArgs(erx.ValidationError, fmt.Errorf("invalid user id %s", userID)))
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return Enrollment{}, wrap(err)
+	}
+
+	//NOTE: ENROLLING AGAIN BEFORE CONFIRMATION REPLACES THE PENDING SECRET, A CONFIRMED ONE IS KEPT
+	err = ms.store.SaveTOTP(ctx, userID, secret)
+	if err != nil {
+		return Enrollment{}, wrap(err)
+	}
+
+	return Enrollment{
+		Secret: secretEncoding.EncodeToString(secret),
+		URI:    otpauthURI(ms.cfg.Issuer(), accountName, secret),
+	}, nil
+}
+
+func (ms *mfaService) ConfirmTOTP(ctx context.Context, userID, code string) ([]string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.ConfirmTOTP"), err) }
+
+	totp, err := ms.store.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	if totp.confirmed {
+		return nil, wrap(erx.WithArgs(erx.DuplicateRecordError, fmt.Errorf("totp already enabled for user %s", userID)))
+	}
+
+	s, ok := matchTOTP(totp.secret, code, time.Now(), totp.lastUsedStep)
+	if !ok {
+		return nil, wrap(erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+	}
+
+	err = ms.store.ConfirmTOTP(ctx, userID, s)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return nil, wrap(err)
+	}
+
+	codes, err := ms.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	return codes, nil
+}
+
+func (ms *mfaService) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	var rpID string
+
+	if cl, err := client.FromContext(ctx); err == nil {
+		rpID = cl.WebAuthnRPID
+	}
+
+	enabled, err := ms.store.IsEnabled(ctx, userID, rpID)
+	if err != nil {
+		return false, erx.WithArgs(erx.Operation("Service.IsEnabled"), err)
+	}
+
+	return enabled, nil
+}
+
+func (ms *mfaService) Challenge(ctx context.Context, clientID, userID string) (string, error) {
+	wrap := func(err error) (string, error) { return "", erx.WithArgs(erx.Operation("Service.Challenge"), err) }
+
+	challengeToken, claims, err := ms.signer.Sign(purpose(clientID), userID, ms.cfg.ChallengeTTL())
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE CHALLENGE IS KEPT ALONGSIDE THE SIGNED TOKEN, SO IT CAN BE ANSWERED ONLY ONCE AND GUESSED ONLY A FEW TIMES
+	err = ms.store.CreateChallenge(ctx, claims.ID, clientID, userID, claims.ExpiresAt)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return challengeToken, nil
+}
+
+func (ms *mfaService) VerifyChallenge(ctx context.Context, clientID, challengeToken, code string) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.VerifyChallenge"), err) }
+
+	challengeID, userID, err := ms.attemptChallenge(ctx, clientID, challengeToken)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	err = ms.verifyCode(ctx, userID, code)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	err = ms.store.ConsumeChallenge(ctx, challengeID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return "", wrap(err)
+	}
+
+	return userID, nil
+}
+
+func (ms *mfaService) attemptChallenge(ctx context.Context, clientID, challengeToken string) (string, string, error) {
+	claims, err := ms.signer.Verify(purpose(clientID), challengeToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	userID, err := ms.store.AttemptChallenge(ctx, claims.ID, clientID, ms.cfg.ChallengeMaxAttempts(), time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return "", "", erx.WithArgs(erx.AuthenticationError, err)
+		}
+
+		return "", "", err
+	}
+
+	if userID != claims.Subject {
+		return "", "", erx.WithArgs(erx.AuthenticationError, fmt.Errorf("mfa challenge %s was issued to another user", claims.ID))
+	}
+
+	return claims.ID, userID, nil
+}
+
+func (ms *mfaService) verifyCode(ctx context.Context, userID, code string) error {
+	//NOTE: A RECOVERY CODE MAY ANSWER THE CHALLENGE IN PLACE OF A TOTP CODE, THE TWO ARE TOLD APART BY THEIR LENGTH
+	if isRecoveryCode(code) {
+		return ms.useRecoveryCode(ctx, userID, code)
+	}
+
+	totp, err := ms.store.GetTOTP(ctx, userID)
+	if err != nil {
+		if isNotFound(err) {
+			return erx.WithArgs(erx.AuthenticationError, err)
+		}
+
+		return err
+	}
+
+	if !totp.confirmed {
+		return erx.WithArgs(erx.AuthenticationError, fmt.Errorf("totp not enabled for user %s", userID))
+	}
+
+	s, ok := matchTOTP(totp.secret, code, time.Now(), totp.lastUsedStep)
+	if !ok {
+		return erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code"))
+	}
+
+	err = ms.store.UseTOTPStep(ctx, userID, s)
+	if err != nil {
+		if isNotFound(err) {
+			return erx.WithArgs(erx.AuthenticationError, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (ms *mfaService) BeginWebAuthnRegistration(ctx context.Context, userID, userName, displayName string) (string, webauthn.CreationOptions, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.BeginWebAuthnRegistration"), err) }
+
+	cl, err := webAuthnClient(ctx)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, wrap(err)
+	}
+
+	rp := cl.RelyingParty()
+
+	//NOTE: PASSKEYS THE USER ALREADY HAS ARE EXCLUDED, SO THE SAME AUTHENTICATOR IS NOT REGISTERED TWICE
+	exclude, err := ms.store.GetWebAuthnCredentialIDs(ctx, userID, rp.ID)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, wrap(err)
+	}
+
+	challengeID, challenge, err := ms.newWebAuthnChallenge(ctx, cl.Id, userID, "", ceremonyRegistration)
+	if err != nil {
+		return "", webauthn.CreationOptions{}, wrap(err)
+	}
+
+	return challengeID, webauthn.NewCreationOptions(rp, challenge, userID, userName, displayName, exclude, ms.challengeTTL()), nil
+}
+
+func (ms *mfaService) FinishWebAuthnRegistration(ctx context.Context, userID, challengeID string, attestation webauthn.Attestation) ([]string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.FinishWebAuthnRegistration"), err) }
+
+	cl, err := webAuthnClient(ctx)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	challengeUserID, _, challenge, err := ms.consumeWebAuthnChallenge(ctx, challengeID, cl.Id, ceremonyRegistration)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	if challengeUserID != userID {
+		return nil, wrap(erx.WithArgs(erx.AuthenticationError, fmt.Errorf("webauthn challenge %s was issued to another user", challengeID)))
+	}
+
+	rp := cl.RelyingParty()
+
+	credential, err := webauthn.VerifyAttestation(rp, challenge, attestation)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	err = ms.store.SaveWebAuthnCredential(ctx, userID, rp.ID, credential)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	remaining, err := ms.store.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	//NOTE: RECOVERY CODES ARE ONLY HANDED OUT WITH THE FIRST FACTOR, ADDING A PASSKEY DOES NOT REPLACE CODES THE USER ALREADY HAS
+	if remaining != 0 {
+		return nil, nil
+	}
+
+	codes, err := ms.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	return codes, nil
+}
+
+func (ms *mfaService) BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.BeginWebAuthnLogin"), err) }
+
+	cl, err := webAuthnClient(ctx)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, wrap(err)
+	}
+
+	rp := cl.RelyingParty()
+
+	var userID, mfaChallengeID string
+	var allow [][]byte
+
+	//NOTE: WITH AN MFA TOKEN THE PASSKEY IS A SECOND FACTOR FOR A KNOWN USER, WITHOUT ONE IT IS THE ONLY FACTOR AND THE USER IS FOUND FROM THE CREDENTIAL
+	if len(mfaToken) != 0 {
+		mfaChallengeID, userID, err = ms.attemptChallenge(ctx, cl.Id, mfaToken)
+		if err != nil {
+			return "", webauthn.RequestOptions{}, wrap(err)
+		}
+
+		allow, err = ms.store.GetWebAuthnCredentialIDs(ctx, userID, rp.ID)
+		if err != nil {
+			return "", webauthn.RequestOptions{}, wrap(err)
+		}
+
+		if len(allow) == 0 {
+			return "", webauthn.RequestOptions{}, wrap(erx.WithArgs(erx.AuthenticationError, fmt.Errorf("no passkey registered for user %s", userID)))
+		}
+	}
+
+	challengeID, challenge, err := ms.newWebAuthnChallenge(ctx, cl.Id, userID, mfaChallengeID, ceremonyLogin)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, wrap(err)
+	}
+
+	return challengeID, webauthn.NewRequestOptions(rp, challenge, allow, len(userID) == 0, ms.challengeTTL()), nil
+}
+
+func (ms *mfaService) FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.FinishWebAuthnLogin"), err) }
+
+	cl, err := webAuthnClient(ctx)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	challengeUserID, mfaChallengeID, challenge, err := ms.consumeWebAuthnChallenge(ctx, challengeID, cl.Id, ceremonyLogin)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	rp := cl.RelyingParty()
+
+	userID, credential, err := ms.store.GetWebAuthnCredential(ctx, assertion.CredentialID, rp.ID)
+	if err != nil {
+		if isNotFound(err) {
+			return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return "", wrap(err)
+	}
+
+	if len(challengeUserID) != 0 && challengeUserID != userID {
+		return "", wrap(erx.WithArgs(erx.AuthenticationError, fmt.Errorf("webauthn challenge %s was issued to another user", challengeID)))
+	}
+
+	passwordless := len(challengeUserID) == 0
+
+	//NOTE: A DISCOVERABLE CREDENTIAL RETURNS THE USER HANDLE, WHICH MUST BE THE OWNER OF THE CREDENTIAL
+	if (passwordless || len(assertion.UserHandle) != 0) && string(assertion.UserHandle) != string(webauthn.UserHandle(userID)) {
+		return "", wrap(erx.WithArgs(erx.AuthenticationError, errors.New("user handle does not match the credential owner")))
+	}
+
+	signCount, err := webauthn.VerifyAssertion(rp, challenge, credential, assertion, passwordless)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	err = ms.store.UpdateSignCount(ctx, credential.ID, credential.SignCount, signCount)
+	if err != nil {
+		if isNotFound(err) {
+			return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return "", wrap(err)
+	}
+
+	//NOTE: A PASSKEY ANSWERING AN MFA CHALLENGE USES IT UP, SO THE SAME MFA TOKEN CANNOT COMPLETE A SECOND LOGIN
+	if len(mfaChallengeID) != 0 {
+		err = ms.store.ConsumeChallenge(ctx, mfaChallengeID)
+		if err != nil {
+			if isNotFound(err) {
+				return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+			}
+
+			return "", wrap(err)
+		}
+	}
+
+	return userID, nil
+}
+
+func (ms *mfaService) RegenerateRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.RegenerateRecoveryCodes"), err) }
+
+	enabled, err := ms.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	if !enabled {
+		return nil, wrap(erx.WithArgs(erx.ValidationError, fmt.Errorf("mfa not enabled for user %s", userID)))
+	}
+
+	codes, err := ms.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	return codes, nil
+}
+
+func (ms *mfaService) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	remaining, err := ms.store.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return 0, erx.WithArgs(erx.Operation("Service.RemainingRecoveryCodes"), err)
+	}
+
+	return remaining, nil
+}
+
+func (ms *mfaService) replaceRecoveryCodes(ctx context.Context, userID string) ([]string, error) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashed := make([]RecoveryCode, len(codes))
+
+	for i, code := range codes {
+		salt, err := ms.encoder.GenerateSalt()
+		if err != nil {
+			return nil, err
+		}
+
+		hashed[i] = RecoveryCode{
+			hash: ms.encoder.EncodeKey(ms.encoder.GenerateKey(normalizeRecoveryCode(code), salt)),
+			salt: salt,
+		}
+	}
+
+	err = ms.store.ReplaceRecoveryCodes(ctx, userID, hashed)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (ms *mfaService) useRecoveryCode(ctx context.Context, userID, code string) error {
+	codes, err := ms.store.GetRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range codes {
+		if ms.encoder.VerifyPassword(normalizeRecoveryCode(code), c.hash, c.salt) != nil {
+			continue
+		}
+
+		err := ms.store.UseRecoveryCode(ctx, c.id)
+		if err != nil {
+			if isNotFound(err) {
+				return erx.WithArgs(erx.AuthenticationError, err)
+			}
+
+			return err
+		}
+
+		return nil
+	}
+
+	return erx.WithArgs(erx.AuthenticationError, errors.New("invalid recovery code"))
+}
+
+func (ms *mfaService) newWebAuthnChallenge(ctx context.Context, clientID, userID, mfaChallengeID, ceremony string) (string, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, err
+	}
+
+	challengeID, err := ms.store.CreateWebAuthnChallenge(ctx, clientID, userID, mfaChallengeID, ceremony, challenge, time.Now().UTC().Add(ms.challengeTTL()))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return challengeID, challenge, nil
+}
+
+func (ms *mfaService) consumeWebAuthnChallenge(ctx context.Context, challengeID, clientID, ceremony string) (string, string, []byte, error) {
+	if !util.IsValidUUID(challengeID) {
+		return "", "", nil, erx.WithArgs(erx.AuthenticationError, fmt.Errorf("invalid webauthn challenge id %s", challengeID))
+	}
+
+	userID, mfaChallengeID, challenge, err := ms.store.ConsumeWebAuthnChallenge(ctx, challengeID, clientID, ceremony, time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return "", "", nil, erx.WithArgs(erx.AuthenticationError, err)
+		}
+
+		return "", "", nil, err
+	}
+
+	return userID, mfaChallengeID, challenge, nil
+}
+
+func (ms *mfaService) challengeTTL() time.Duration {
+	return time.Duration(ms.cfg.ChallengeTTL()) * time.Second
+}
+
+func webAuthnClient(ctx context.Context) (client.Client, error) {
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return client.Client{}, err
+	}
+
+	if !cl.SupportsWebAuthn() {
+		return client.Client{}, erx.WithArgs(erx.ValidationError, fmt.Errorf("client %s has no webauthn relying party", cl.Name))
+	}
+
+	return cl, nil
+}
+
+func purpose(clientID string) string {
+	//NOTE: THE CLIENT IS PART OF THE PURPOSE, SO A CHALLENGE CAN ONLY BE COMPLETED BY THE CLIENT IT WAS ISSUED TO
+	return challengePurpose + ":" + clientID
+}
+
+func isNotFound(err error) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == erx.ResourceNotFoundError
+}
+
+func NewService(cfg config.MFAConfig, store Store, signer token.Signer, encoder password.Encoder) Service {
+	return &mfaService{
+		cfg:     cfg,
+		store:   store,
+		signer:  signer,
+		encoder: encoder,
+	}
+}
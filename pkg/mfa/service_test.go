@@ -0,0 +1,939 @@
+package mfa_test
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/password"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"identification-service/pkg/webauthn"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("12345678901234567890")
+
+func newMFAConfig() config.MFAConfig {
+	mockMFAConfig := &config.MockMFAConfig{}
+	mockMFAConfig.On("Issuer").Return("identification-service")
+	mockMFAConfig.On("ChallengeTTL").Return(300)
+	mockMFAConfig.On("ChallengeMaxAttempts").Return(5)
+
+	return mockMFAConfig
+}
+
+func newSigner(t *testing.T) token.Signer {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("SignedTokenSecret").Return(test.TokenSecret)
+
+	signer, err := token.NewSigner(mockTokenConfig)
+	require.NoError(t, err)
+
+	return signer
+}
+
+func newEncoder() password.Encoder {
+	mockPasswordConfig := &config.MockPasswordConfig{}
+	mockPasswordConfig.On("SaltLength").Return(16)
+	mockPasswordConfig.On("Iterations").Return(16)
+	mockPasswordConfig.On("KeyLength").Return(32)
+
+	return password.NewEncoder(mockPasswordConfig)
+}
+
+func isRecoveryCodes(codes []mfa.RecoveryCode) bool {
+	return len(codes) == 10
+}
+
+func currentCode(t *testing.T) string {
+	code, err := mfa.TOTPCode(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(testSecret), time.Now())
+	require.NoError(t, err)
+
+	return code
+}
+
+func expectChallenge(mockStore *mfa.MockStore, userID string) *mfa.MockStore {
+	mockStore.On("CreateChallenge", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), userID, mock.AnythingOfType("time.Time")).
+		Return(nil).Maybe()
+	mockStore.On("AttemptChallenge", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), 5, mock.AnythingOfType("time.Time")).
+		Return(userID, nil).Maybe()
+	mockStore.On("ConsumeChallenge", mock.Anything, mock.AnythingOfType("string")).Return(nil).Maybe()
+
+	return mockStore
+}
+
+func TestMFAServiceEnrollTOTPSuccess(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("SaveTOTP", mock.Anything, userID, mock.AnythingOfType("[]uint8")).Return(nil)
+
+	res, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).EnrollTOTP(context.Background(), userID, "user@mail.com")
+	require.NoError(t, err)
+
+	uri, err := url.Parse(res.URI)
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/identification-service:user@mail.com", uri.Path)
+	assert.Equal(t, res.Secret, uri.Query().Get("secret"))
+	assert.Equal(t, "identification-service", uri.Query().Get("issuer"))
+
+	secret := mockStore.Calls[0].Arguments.Get(2).([]byte)
+	assert.Equal(t, strings.ToUpper(res.Secret), base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+}
+
+func TestMFAServiceEnrollTOTPFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	testCases := map[string]struct {
+		userID   string
+		store    func() mfa.Store
+		expected erx.Kind
+	}{
+		"test failure when user id is invalid": {
+			userID:   "invalid",
+			store:    func() mfa.Store { return &mfa.MockStore{} },
+			expected: erx.ValidationError,
+		},
+		"test failure when totp is already enabled": {
+			userID: userID,
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("SaveTOTP", mock.Anything, userID, mock.AnythingOfType("[]uint8")).
+					Return(erx.WithArgs(erx.DuplicateRecordError, errors.New("totp already enabled")))
+
+				return mockStore
+			},
+			expected: erx.DuplicateRecordError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := mfa.NewService(newMFAConfig(), testCase.store(), newSigner(t), newEncoder()).EnrollTOTP(context.Background(), testCase.userID, "user@mail.com")
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceConfirmTOTPSuccess(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, false, 0), nil)
+	mockStore.On("ConfirmTOTP", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil)
+	mockStore.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.MatchedBy(isRecoveryCodes)).Return(nil)
+
+	codes, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).ConfirmTOTP(context.Background(), userID, currentCode(t))
+	require.NoError(t, err)
+
+	require.Len(t, codes, 10)
+	assert.Regexp(t, "^[a-z2-7]{5}-[a-z2-7]{5}$", codes[0])
+	assert.NotEqual(t, codes[0], codes[1])
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestMFAServiceConfirmTOTPFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	testCases := map[string]struct {
+		code     string
+		store    func() mfa.Store
+		expected erx.Kind
+	}{
+		"test failure when totp is not enrolled": {
+			code: "123456",
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).
+					Return(mfa.TOTP{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no totp found")))
+
+				return mockStore
+			},
+			expected: erx.ResourceNotFoundError,
+		},
+		"test failure when totp is already confirmed": {
+			code: "123456",
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, true, 0), nil)
+
+				return mockStore
+			},
+			expected: erx.DuplicateRecordError,
+		},
+		"test failure when code is invalid": {
+			code: "abcdef",
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, false, 0), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when step was used concurrently": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, false, 0), nil)
+				mockStore.On("ConfirmTOTP", mock.Anything, userID, mock.AnythingOfType("int64")).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no usable totp found")))
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			code := testCase.code
+			if len(code) == 0 {
+				code = currentCode(t)
+			}
+
+			_, err := mfa.NewService(newMFAConfig(), testCase.store(), newSigner(t), newEncoder()).ConfirmTOTP(context.Background(), userID, code)
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceIsEnabled(t *testing.T) {
+	userID := test.NewUUID()
+
+	testCases := map[string]struct {
+		ctx      func() context.Context
+		rpID     string
+		enabled  bool
+		expected bool
+	}{
+		"test enabled for client with relying party": {
+			ctx:      func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			rpID:     test.WebAuthnRPID,
+			enabled:  true,
+			expected: true,
+		},
+		"test disabled for client with relying party": {
+			ctx:      func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			rpID:     test.WebAuthnRPID,
+			expected: false,
+		},
+		"test enabled without client in context": {
+			ctx:      context.Background,
+			enabled:  true,
+			expected: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mfa.MockStore{}
+			mockStore.On("IsEnabled", mock.Anything, userID, testCase.rpID).Return(testCase.enabled, nil)
+
+			res, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).IsEnabled(testCase.ctx(), userID)
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.expected, res)
+		})
+	}
+}
+
+func TestMFAServiceIsEnabledFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("IsEnabled", mock.Anything, userID, "").Return(false, errors.New("failed to check mfa"))
+
+	_, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).IsEnabled(context.Background(), userID)
+	require.Error(t, err)
+}
+
+func TestMFAServiceVerifyChallengeSuccess(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	mockStore := expectChallenge(&mfa.MockStore{}, userID)
+	mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, true, 0), nil)
+	mockStore.On("UseTOTPStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil)
+
+	service := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder())
+
+	challengeToken, err := service.Challenge(context.Background(), clientID, userID)
+	require.NoError(t, err)
+
+	res, err := service.VerifyChallenge(context.Background(), clientID, challengeToken, currentCode(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, userID, res)
+	mockStore.AssertExpectations(t)
+
+	challengeID := mockStore.Calls[0].Arguments.Get(1).(string)
+	mockStore.AssertCalled(t, "CreateChallenge", mock.Anything, challengeID, clientID, userID, mock.AnythingOfType("time.Time"))
+	mockStore.AssertCalled(t, "AttemptChallenge", mock.Anything, challengeID, clientID, 5, mock.AnythingOfType("time.Time"))
+	mockStore.AssertCalled(t, "ConsumeChallenge", mock.Anything, challengeID)
+}
+
+func TestMFAServiceVerifyChallengeFailureWhenChallengeIsNotUsable(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	testCases := map[string]struct {
+		store func() *mfa.MockStore
+	}{
+		"test failure when challenge was already answered, expired or ran out of attempts": {
+			store: func() *mfa.MockStore {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("CreateChallenge", mock.Anything, mock.Anything, clientID, userID, mock.Anything).Return(nil)
+				mockStore.On("AttemptChallenge", mock.Anything, mock.Anything, clientID, 5, mock.Anything).
+					Return("", erx.WithArgs(erx.ResourceNotFoundError, errors.New("no usable mfa challenge found")))
+
+				return mockStore
+			},
+		},
+		"test failure when challenge was stored for another user": {
+			store: func() *mfa.MockStore {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("CreateChallenge", mock.Anything, mock.Anything, clientID, userID, mock.Anything).Return(nil)
+				mockStore.On("AttemptChallenge", mock.Anything, mock.Anything, clientID, 5, mock.Anything).Return(test.NewUUID(), nil)
+
+				return mockStore
+			},
+		},
+		"test failure when challenge was answered concurrently": {
+			store: func() *mfa.MockStore {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("CreateChallenge", mock.Anything, mock.Anything, clientID, userID, mock.Anything).Return(nil)
+				mockStore.On("AttemptChallenge", mock.Anything, mock.Anything, clientID, 5, mock.Anything).Return(userID, nil)
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, true, 0), nil)
+				mockStore.On("UseTOTPStep", mock.Anything, userID, mock.AnythingOfType("int64")).Return(nil)
+				mockStore.On("ConsumeChallenge", mock.Anything, mock.Anything).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no mfa challenge found")))
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockStore := testCase.store()
+
+			service := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder())
+
+			challengeToken, err := service.Challenge(context.Background(), clientID, userID)
+			require.NoError(t, err)
+
+			_, err = service.VerifyChallenge(context.Background(), clientID, challengeToken, currentCode(t))
+			require.Error(t, err)
+
+			assert.Equal(t, erx.AuthenticationError, err.(*erx.Erx).Kind())
+			mockStore.AssertNotCalled(t, "GetRecoveryCodes", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestMFAServiceVerifyChallengeFailure(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	challenge := func(service mfa.Service, clientID string) string {
+		challengeToken, err := service.Challenge(context.Background(), clientID, userID)
+		require.NoError(t, err)
+
+		return challengeToken
+	}
+
+	testCases := map[string]struct {
+		challengeClientID string
+		code              string
+		store             func() mfa.Store
+		expected          erx.Kind
+	}{
+		"test failure when challenge was issued to other client": {
+			challengeClientID: test.NewUUID(),
+			store:             func() mfa.Store { return &mfa.MockStore{} },
+			expected:          erx.AuthenticationError,
+		},
+		"test failure when totp is not enrolled": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).
+					Return(mfa.TOTP{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no totp found")))
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when totp is not confirmed": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, false, 0), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when code is invalid": {
+			code: "abcdef",
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, true, 0), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when code was already used": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).
+					Return(mfa.NewTOTP(testSecret, true, time.Now().Unix()/30+1), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when step was used concurrently": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(testSecret, true, 0), nil)
+				mockStore.On("UseTOTPStep", mock.Anything, userID, mock.AnythingOfType("int64")).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no usable totp found")))
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := mfa.NewService(newMFAConfig(), expectChallenge(testCase.store().(*mfa.MockStore), userID), newSigner(t), newEncoder())
+
+			challengeClientID := testCase.challengeClientID
+			if len(challengeClientID) == 0 {
+				challengeClientID = clientID
+			}
+
+			code := testCase.code
+			if len(code) == 0 {
+				code = currentCode(t)
+			}
+
+			_, err := service.VerifyChallenge(context.Background(), clientID, challenge(service, challengeClientID), code)
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceVerifyChallengeSuccessWithRecoveryCode(t *testing.T) {
+	userID, clientID, codeID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	mockStore := expectChallenge(&mfa.MockStore{}, userID)
+	mockStore.On("GetRecoveryCodes", mock.Anything, userID).
+		Return([]mfa.RecoveryCode{newRecoveryCode(t, test.NewUUID(), "zzzzz22222"), newRecoveryCode(t, codeID, "abcde23456")}, nil)
+	mockStore.On("UseRecoveryCode", mock.Anything, codeID).Return(nil)
+
+	service := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder())
+
+	challengeToken, err := service.Challenge(context.Background(), clientID, userID)
+	require.NoError(t, err)
+
+	res, err := service.VerifyChallenge(context.Background(), clientID, challengeToken, "ABCDE-23456")
+	require.NoError(t, err)
+
+	assert.Equal(t, userID, res)
+	mockStore.AssertExpectations(t)
+}
+
+func TestMFAServiceVerifyChallengeFailureWithRecoveryCode(t *testing.T) {
+	userID, clientID, codeID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	testCases := map[string]struct {
+		store    func() mfa.Store
+		expected erx.Kind
+	}{
+		"test failure when recovery code is invalid": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetRecoveryCodes", mock.Anything, userID).
+					Return([]mfa.RecoveryCode{newRecoveryCode(t, codeID, "zzzzz22222")}, nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when user has no recovery codes": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetRecoveryCodes", mock.Anything, userID).Return([]mfa.RecoveryCode(nil), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when recovery code was used concurrently": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetRecoveryCodes", mock.Anything, userID).
+					Return([]mfa.RecoveryCode{newRecoveryCode(t, codeID, "abcde23456")}, nil)
+				mockStore.On("UseRecoveryCode", mock.Anything, codeID).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no unused recovery code found")))
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := mfa.NewService(newMFAConfig(), expectChallenge(testCase.store().(*mfa.MockStore), userID), newSigner(t), newEncoder())
+
+			challengeToken, err := service.Challenge(context.Background(), clientID, userID)
+			require.NoError(t, err)
+
+			_, err = service.VerifyChallenge(context.Background(), clientID, challengeToken, "abcde-23456")
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceRegenerateRecoveryCodesSuccess(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("IsEnabled", mock.Anything, userID, "").Return(true, nil)
+	mockStore.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.MatchedBy(isRecoveryCodes)).Return(nil)
+
+	codes, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).RegenerateRecoveryCodes(context.Background(), userID)
+	require.NoError(t, err)
+
+	assert.Len(t, codes, 10)
+	mockStore.AssertExpectations(t)
+}
+
+func TestMFAServiceRegenerateRecoveryCodesFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	testCases := map[string]struct {
+		store    func() mfa.Store
+		expected erx.Kind
+	}{
+		"test failure when mfa is not enabled": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("IsEnabled", mock.Anything, userID, "").Return(false, nil)
+
+				return mockStore
+			},
+			expected: erx.ValidationError,
+		},
+		"test failure when store call fails": {
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("IsEnabled", mock.Anything, userID, "").Return(true, nil)
+				mockStore.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.Anything).
+					Return(erx.WithArgs(erx.Kind("databaseError"), errors.New("failed to insert")))
+
+				return mockStore
+			},
+			expected: erx.Kind("databaseError"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := mfa.NewService(newMFAConfig(), testCase.store(), newSigner(t), newEncoder()).RegenerateRecoveryCodes(context.Background(), userID)
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceRemainingRecoveryCodes(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("CountRecoveryCodes", mock.Anything, userID).Return(7, nil)
+
+	res, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).RemainingRecoveryCodes(context.Background(), userID)
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, res)
+}
+
+func newRecoveryCode(t *testing.T, id, code string) mfa.RecoveryCode {
+	encoder := newEncoder()
+
+	salt, err := encoder.GenerateSalt()
+	require.NoError(t, err)
+
+	return mfa.NewRecoveryCode(id, encoder.EncodeKey(encoder.GenerateKey(code, salt)), salt)
+}
+
+func clientContext(t *testing.T, rpID string) context.Context {
+	mockClientConfig := &config.MockClientConfig{}
+	mockClientConfig.On("Strategies").Return(map[string]bool{test.ClientSessionStrategyRevokeOld: true})
+
+	cl, err := test.NewClient(mockClientConfig, map[string]interface{}{test.ClientWebAuthnRPIDKey: rpID})
+	require.NoError(t, err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	require.NoError(t, err)
+
+	return ctx
+}
+
+func decodeChallenge(t *testing.T, challenge string) []byte {
+	res, err := base64.RawURLEncoding.DecodeString(challenge)
+	require.NoError(t, err)
+
+	return res
+}
+
+func TestMFAServiceWebAuthnRegistrationSuccess(t *testing.T) {
+	ctx := clientContext(t, test.WebAuthnRPID)
+	cl, err := client.FromContext(ctx)
+	require.NoError(t, err)
+
+	userID, challengeID, existing := test.NewUUID(), test.NewUUID(), test.RandBytes(16)
+	authenticator := test.NewAuthenticator(webauthn.AlgorithmES256)
+
+	mockStore := &mfa.MockStore{}
+	mockStore.On("GetWebAuthnCredentialIDs", mock.Anything, userID, test.WebAuthnRPID).Return([][]byte{existing}, nil)
+	mockStore.On("CreateWebAuthnChallenge", mock.Anything, cl.Id, userID, "", "registration", mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Time")).
+		Return(challengeID, nil)
+
+	service := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder())
+
+	id, options, err := service.BeginWebAuthnRegistration(ctx, userID, "user@mail.com", "User")
+	require.NoError(t, err)
+
+	assert.Equal(t, challengeID, id)
+	assert.Equal(t, test.WebAuthnRPID, options.RelyingParty.ID)
+	assert.Equal(t, int64(300000), options.Timeout)
+	assert.Len(t, options.ExcludeCredentials, 1)
+
+	challenge := decodeChallenge(t, options.Challenge)
+
+	mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, cl.Id, "registration", mock.AnythingOfType("time.Time")).
+		Return(userID, "", challenge, nil)
+	mockStore.On("SaveWebAuthnCredential", mock.Anything, userID, test.WebAuthnRPID, authenticator.Credential()).Return(nil)
+	mockStore.On("CountRecoveryCodes", mock.Anything, userID).Return(0, nil)
+	mockStore.On("ReplaceRecoveryCodes", mock.Anything, userID, mock.MatchedBy(isRecoveryCodes)).Return(nil)
+
+	codes, err := service.FinishWebAuthnRegistration(ctx, userID, challengeID, authenticator.Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge))
+	require.NoError(t, err)
+
+	assert.Len(t, codes, 10)
+
+	mockStore.AssertExpectations(t)
+}
+
+func TestMFAServiceWebAuthnRegistrationFailure(t *testing.T) {
+	userID, challengeID, challenge := test.NewUUID(), test.NewUUID(), test.RandBytes(32)
+	authenticator := test.NewAuthenticator(webauthn.AlgorithmEdDSA)
+
+	testCases := map[string]struct {
+		ctx         func() context.Context
+		store       func() mfa.Store
+		attestation webauthn.Attestation
+		expected    erx.Kind
+	}{
+		"test failure when client has no relying party": {
+			ctx:      func() context.Context { return clientContext(t, "") },
+			store:    func() mfa.Store { return &mfa.MockStore{} },
+			expected: erx.ValidationError,
+		},
+		"test failure when challenge is not found": {
+			ctx: func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, mock.Anything, "registration", mock.Anything).
+					Return("", "", []byte(nil), erx.WithArgs(erx.ResourceNotFoundError, errors.New("no pending webauthn challenge")))
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when challenge was issued to another user": {
+			ctx: func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, mock.Anything, "registration", mock.Anything).
+					Return(test.NewUUID(), "", challenge, nil)
+
+				return mockStore
+			},
+			attestation: authenticator.Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge),
+			expected:    erx.AuthenticationError,
+		},
+		"test failure when attestation is for another challenge": {
+			ctx: func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, mock.Anything, "registration", mock.Anything).
+					Return(userID, "", challenge, nil)
+
+				return mockStore
+			},
+			attestation: authenticator.Attest(test.WebAuthnRPID, test.WebAuthnOrigin, test.RandBytes(32)),
+			expected:    erx.AuthenticationError,
+		},
+		"test failure when credential is already registered": {
+			ctx: func() context.Context { return clientContext(t, test.WebAuthnRPID) },
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, mock.Anything, "registration", mock.Anything).
+					Return(userID, "", challenge, nil)
+				mockStore.On("SaveWebAuthnCredential", mock.Anything, userID, test.WebAuthnRPID, mock.Anything).
+					Return(erx.WithArgs(erx.DuplicateRecordError, errors.New("duplicate credential")))
+
+				return mockStore
+			},
+			attestation: authenticator.Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge),
+			expected:    erx.DuplicateRecordError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := mfa.NewService(newMFAConfig(), testCase.store(), newSigner(t), newEncoder()).
+				FinishWebAuthnRegistration(testCase.ctx(), userID, challengeID, testCase.attestation)
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceWebAuthnLoginSuccess(t *testing.T) {
+	ctx := clientContext(t, test.WebAuthnRPID)
+	cl, err := client.FromContext(ctx)
+	require.NoError(t, err)
+
+	userID, challengeID := test.NewUUID(), test.NewUUID()
+
+	testCases := map[string]struct {
+		mfaToken         func(service mfa.Service) string
+		challengeUserID  string
+		userVerification string
+		allowed          int
+	}{
+		"test passwordless login": {
+			mfaToken:         func(service mfa.Service) string { return "" },
+			userVerification: "required",
+		},
+		"test second factor login": {
+			mfaToken: func(service mfa.Service) string {
+				mfaToken, err := service.Challenge(ctx, cl.Id, userID)
+				require.NoError(t, err)
+
+				return mfaToken
+			},
+			challengeUserID:  userID,
+			userVerification: "preferred",
+			allowed:          1,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			authenticator := test.NewAuthenticator(webauthn.AlgorithmES256)
+			authenticator.SignCount = 7
+			credential := authenticator.Credential()
+
+			mockStore := expectChallenge(&mfa.MockStore{}, userID)
+			if testCase.allowed != 0 {
+				mockStore.On("GetWebAuthnCredentialIDs", mock.Anything, userID, test.WebAuthnRPID).Return([][]byte{credential.ID}, nil)
+			}
+
+			var mfaChallengeID string
+
+			mockStore.On("CreateWebAuthnChallenge", mock.Anything, cl.Id, testCase.challengeUserID, mock.AnythingOfType("string"), "login", mock.AnythingOfType("[]uint8"), mock.AnythingOfType("time.Time")).
+				Run(func(args mock.Arguments) { mfaChallengeID = args.String(3) }).
+				Return(challengeID, nil)
+
+			service := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder())
+
+			id, options, err := service.BeginWebAuthnLogin(ctx, testCase.mfaToken(service))
+			require.NoError(t, err)
+
+			assert.Equal(t, challengeID, id)
+			assert.Equal(t, testCase.userVerification, options.UserVerification)
+			assert.Len(t, options.AllowCredentials, testCase.allowed)
+
+			challenge := decodeChallenge(t, options.Challenge)
+
+			mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, cl.Id, "login", mock.AnythingOfType("time.Time")).
+				Return(testCase.challengeUserID, mfaChallengeID, challenge, nil)
+			mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+			mockStore.On("UpdateSignCount", mock.Anything, credential.ID, uint32(7), uint32(8)).Return(nil)
+
+			assertion := authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, webauthn.UserHandle(userID))
+
+			res, err := service.FinishWebAuthnLogin(ctx, challengeID, assertion)
+			require.NoError(t, err)
+
+			assert.Equal(t, userID, res)
+			mockStore.AssertExpectations(t)
+
+			if testCase.allowed != 0 {
+				assert.NotEmpty(t, mfaChallengeID)
+				mockStore.AssertCalled(t, "ConsumeChallenge", mock.Anything, mfaChallengeID)
+			} else {
+				assert.Empty(t, mfaChallengeID)
+				mockStore.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestMFAServiceBeginWebAuthnLoginFailure(t *testing.T) {
+	ctx := clientContext(t, test.WebAuthnRPID)
+	cl, err := client.FromContext(ctx)
+	require.NoError(t, err)
+
+	userID := test.NewUUID()
+
+	testCases := map[string]struct {
+		mfaToken func(service mfa.Service) string
+		store    func() mfa.Store
+		expected erx.Kind
+	}{
+		"test failure when mfa token was issued to another client": {
+			mfaToken: func(service mfa.Service) string {
+				mfaToken, err := service.Challenge(ctx, test.NewUUID(), userID)
+				require.NoError(t, err)
+
+				return mfaToken
+			},
+			store:    func() mfa.Store { return &mfa.MockStore{} },
+			expected: erx.AuthenticationError,
+		},
+		"test failure when user has no passkey": {
+			mfaToken: func(service mfa.Service) string {
+				mfaToken, err := service.Challenge(ctx, cl.Id, userID)
+				require.NoError(t, err)
+
+				return mfaToken
+			},
+			store: func() mfa.Store {
+				mockStore := &mfa.MockStore{}
+				mockStore.On("GetWebAuthnCredentialIDs", mock.Anything, userID, test.WebAuthnRPID).Return([][]byte(nil), nil)
+
+				return mockStore
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := mfa.NewService(newMFAConfig(), expectChallenge(testCase.store().(*mfa.MockStore), userID), newSigner(t), newEncoder())
+
+			_, _, err := service.BeginWebAuthnLogin(ctx, testCase.mfaToken(service))
+			require.Error(t, err)
+
+			assert.Equal(t, testCase.expected, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestMFAServiceFinishWebAuthnLoginFailure(t *testing.T) {
+	ctx := clientContext(t, test.WebAuthnRPID)
+
+	userID, challengeID, mfaChallengeID, challenge := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.RandBytes(32)
+	authenticator := test.NewAuthenticator(webauthn.AlgorithmEdDSA)
+	credential := authenticator.Credential()
+
+	testCases := map[string]struct {
+		challengeUserID string
+		mfaChallengeID  string
+		userHandle      []byte
+		store           func(mockStore *mfa.MockStore)
+	}{
+		"test failure when credential is not registered": {
+			userHandle: webauthn.UserHandle(userID),
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).
+					Return("", webauthn.Credential{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no webauthn credential found")))
+			},
+		},
+		"test failure when challenge was issued to another user": {
+			challengeUserID: test.NewUUID(),
+			userHandle:      webauthn.UserHandle(userID),
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+			},
+		},
+		"test failure when user handle is missing for passwordless login": {
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+			},
+		},
+		"test failure when user handle belongs to another user": {
+			userHandle: webauthn.UserHandle(test.NewUUID()),
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+			},
+		},
+		"test failure when sign count changed concurrently": {
+			userHandle: webauthn.UserHandle(userID),
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+				mockStore.On("UpdateSignCount", mock.Anything, credential.ID, uint32(0), uint32(1)).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("sign count changed")))
+			},
+		},
+		"test failure when mfa challenge was already answered": {
+			challengeUserID: userID,
+			mfaChallengeID:  mfaChallengeID,
+			userHandle:      webauthn.UserHandle(userID),
+			store: func(mockStore *mfa.MockStore) {
+				mockStore.On("GetWebAuthnCredential", mock.Anything, credential.ID, test.WebAuthnRPID).Return(userID, credential, nil)
+				mockStore.On("UpdateSignCount", mock.Anything, credential.ID, uint32(0), uint32(1)).Return(nil)
+				mockStore.On("ConsumeChallenge", mock.Anything, mfaChallengeID).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no mfa challenge found")))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockStore := &mfa.MockStore{}
+			mockStore.On("ConsumeWebAuthnChallenge", mock.Anything, challengeID, mock.Anything, "login", mock.Anything).
+				Return(testCase.challengeUserID, testCase.mfaChallengeID, challenge, nil)
+			testCase.store(mockStore)
+
+			authenticator.SignCount = 0
+			assertion := authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, testCase.userHandle)
+
+			_, err := mfa.NewService(newMFAConfig(), mockStore, newSigner(t), newEncoder()).FinishWebAuthnLogin(ctx, challengeID, assertion)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.AuthenticationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
@@ -0,0 +1,498 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/webauthn"
+	"time"
+)
+
+const (
+	saveTOTP = `insert into user_totp (user_id, secret) values ($1, $2)
+	on conflict (user_id) do update set secret=excluded.secret, last_used_step=0, updated_at=(now() at time zone 'utc') where user_totp.confirmed=false
+	returning user_id`
+	getTOTP     = `select secret, confirmed, last_used_step from user_totp where user_id=$1`
+	confirmTOTP = `update user_totp set confirmed=true, last_used_step=$2, updated_at=(now() at time zone 'utc') where user_id=$1 and confirmed=false and last_used_step < $2`
+	useTOTPStep = `update user_totp set last_used_step=$2, updated_at=(now() at time zone 'utc') where user_id=$1 and confirmed=true and last_used_step < $2`
+	isEnabled   = `select exists(select 1 from user_totp where user_id=$1 and confirmed=true) or exists(select 1 from webauthn_credentials where user_id=$1 and rp_id=$2)`
+
+	createChallenge  = `with expired as (delete from mfa_challenges where user_id=$3 and expires_at <= (now() at time zone 'utc')) insert into mfa_challenges (id, client_id, user_id, expires_at) values ($1, $2, $3, $4)`
+	attemptChallenge = `update mfa_challenges set attempts=attempts+1 where id=$1 and client_id=$2 and attempts < $3 and expires_at > $4 returning user_id`
+	consumeChallenge = `delete from mfa_challenges where id=$1`
+
+	createWebAuthnChallenge  = `insert into webauthn_challenges (client_id, user_id, mfa_challenge_id, ceremony, challenge, expires_at) values ($1, $2, $3, $4, $5, $6) returning id`
+	consumeWebAuthnChallenge = `delete from webauthn_challenges where id=$1 and client_id=$2 and ceremony=$3 and expires_at > $4 returning coalesce(user_id::text, ''), coalesce(mfa_challenge_id::text, ''), challenge`
+	saveWebAuthnCredential   = `insert into webauthn_credentials (id, user_id, rp_id, public_key, sign_count) values ($1, $2, $3, $4, $5) returning id`
+	getWebAuthnCredentialIDs = `select id from webauthn_credentials where user_id=$1 and rp_id=$2`
+	getWebAuthnCredential    = `select user_id, public_key, sign_count from webauthn_credentials where id=$1 and rp_id=$2`
+	updateSignCount          = `update webauthn_credentials set sign_count=$3, last_used_at=(now() at time zone 'utc') where id=$1 and sign_count=$2`
+
+	replaceRecoveryCodes = `with d as (delete from mfa_recovery_codes where user_id=$1)
+	insert into mfa_recovery_codes (user_id, code_hash, salt) select $1, c.code_hash, c.salt from unnest($2::text[], $3::bytea[]) as c(code_hash, salt)`
+	getRecoveryCodes   = `select id, code_hash, salt from mfa_recovery_codes where user_id=$1 and used_at is null`
+	useRecoveryCode    = `update mfa_recovery_codes set used_at=(now() at time zone 'utc') where id=$1 and used_at is null`
+	countRecoveryCodes = `select count(*) from mfa_recovery_codes where user_id=$1 and used_at is null`
+)
+
+type TOTP struct {
+	secret       []byte
+	confirmed    bool
+	lastUsedStep int64
+}
+
+func NewTOTP(secret []byte, confirmed bool, lastUsedStep int64) TOTP {
+	return TOTP{
+		secret:       secret,
+		confirmed:    confirmed,
+		lastUsedStep: lastUsedStep,
+	}
+}
+
+func (t TOTP) Confirmed() bool {
+	return t.confirmed
+}
+
+type RecoveryCode struct {
+	id   string
+	hash string
+	salt []byte
+}
+
+func NewRecoveryCode(id, hash string, salt []byte) RecoveryCode {
+	return RecoveryCode{
+		id:   id,
+		hash: hash,
+		salt: salt,
+	}
+}
+
+type Store interface {
+	SaveTOTP(ctx context.Context, userID string, secret []byte) error
+	GetTOTP(ctx context.Context, userID string) (TOTP, error)
+	ConfirmTOTP(ctx context.Context, userID string, step int64) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	IsEnabled(ctx context.Context, userID, rpID string) (bool, error)
+
+	CreateChallenge(ctx context.Context, id, clientID, userID string, expiresAt time.Time) error
+	AttemptChallenge(ctx context.Context, id, clientID string, maxAttempts int, now time.Time) (string, error)
+	ConsumeChallenge(ctx context.Context, id string) error
+
+	CreateWebAuthnChallenge(ctx context.Context, clientID, userID, mfaChallengeID, ceremony string, challenge []byte, expiresAt time.Time) (string, error)
+	ConsumeWebAuthnChallenge(ctx context.Context, id, clientID, ceremony string, now time.Time) (string, string, []byte, error)
+	SaveWebAuthnCredential(ctx context.Context, userID, rpID string, credential webauthn.Credential) error
+	GetWebAuthnCredentialIDs(ctx context.Context, userID, rpID string) ([][]byte, error)
+	GetWebAuthnCredential(ctx context.Context, id []byte, rpID string) (string, webauthn.Credential, error)
+	UpdateSignCount(ctx context.Context, id []byte, previous, next uint32) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error
+	GetRecoveryCodes(ctx context.Context, userID string) ([]RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id string) error
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+type mfaStore struct {
+	db       database.SQLDatabase
+	envelope libcrypto.Envelope
+}
+
+func (ms *mfaStore) SaveTOTP(ctx context.Context, userID string, secret []byte) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.SaveTOTP"), err) }
+
+	sealed, err := ms.envelope.Seal(secret)
+	if err != nil {
+		return wrap(err)
+	}
+
+	var id string
+
+	row := ms.db.QueryRowContext(ctx, saveTOTP, userID, sealed)
+	if row.Err() != nil {
+		return wrap(row.Err())
+	}
+
+	//NOTE: A CONFIRMED SECRET IS NEVER OVERWRITTEN, THE CONFLICT UPDATE THEN RETURNS NO ROWS
+	err = row.Scan(&id)
+	if err == sql.ErrNoRows {
+		return wrap(erx.WithArgs(erx.DuplicateRecordError, fmt.Errorf("totp already enabled for user %s", userID)))
+	}
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) GetTOTP(ctx context.Context, userID string) (TOTP, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetTOTP"), err) }
+
+	var totp TOTP
+	var sealed []byte
+
+	row := ms.db.QueryRowContext(ctx, getTOTP, userID)
+	if row.Err() != nil {
+		return TOTP{}, wrap(row.Err())
+	}
+
+	err := row.Scan(&sealed, &totp.confirmed, &totp.lastUsedStep)
+	if err == sql.ErrNoRows {
+		return TOTP{}, wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no totp found for user %s", userID)))
+	}
+
+	if err != nil {
+		return TOTP{}, wrap(err)
+	}
+
+	totp.secret, err = ms.envelope.Open(sealed)
+	if err != nil {
+		return TOTP{}, wrap(err)
+	}
+
+	return totp, nil
+}
+
+func (ms *mfaStore) ConfirmTOTP(ctx context.Context, userID string, step int64) error {
+	return ms.updateStep(ctx, "Store.ConfirmTOTP", confirmTOTP, userID, step)
+}
+
+func (ms *mfaStore) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	return ms.updateStep(ctx, "Store.UseTOTPStep", useTOTPStep, userID, step)
+}
+
+func (ms *mfaStore) updateStep(ctx context.Context, operation, query, userID string, step int64) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation(operation), err) }
+
+	res, err := ms.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE STEP ONLY MOVES FORWARD, SO TWO CONCURRENT REQUESTS WITH THE SAME CODE CANNOT BOTH SUCCEED
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no usable totp found for user %s", userID)))
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) CreateChallenge(ctx context.Context, id, clientID, userID string, expiresAt time.Time) error {
+	_, err := ms.db.ExecContext(ctx, createChallenge, id, clientID, userID, expiresAt)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreateChallenge"), err)
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) AttemptChallenge(ctx context.Context, id, clientID string, maxAttempts int, now time.Time) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.AttemptChallenge"), err) }
+
+	var userID string
+
+	row := ms.db.QueryRowContext(ctx, attemptChallenge, id, clientID, maxAttempts, now)
+	if row.Err() != nil {
+		return "", wrap(row.Err())
+	}
+
+	//NOTE: EVERY ATTEMPT IS COUNTED BEFORE THE CODE IS CHECKED, SO CONCURRENT GUESSES CANNOT GO PAST THE LIMIT
+	err := row.Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no usable mfa challenge found with id %s", id)))
+	}
+
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	return userID, nil
+}
+
+func (ms *mfaStore) ConsumeChallenge(ctx context.Context, id string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.ConsumeChallenge"), err) }
+
+	res, err := ms.db.ExecContext(ctx, consumeChallenge, id)
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no mfa challenge found with id %s", id)))
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) IsEnabled(ctx context.Context, userID, rpID string) (bool, error) {
+	var enabled bool
+
+	row := ms.db.QueryRowContext(ctx, isEnabled, userID, rpID)
+	if row.Err() != nil {
+		return false, erx.WithArgs(erx.Operation("Store.IsEnabled"), row.Err())
+	}
+
+	//NOTE: ONLY PASSKEYS OF THE CURRENT RELYING PARTY COUNT, A PASSKEY FOR ANOTHER CLIENT CANNOT ANSWER THE CHALLENGE
+	err := row.Scan(&enabled)
+	if err != nil {
+		return false, erx.WithArgs(erx.Operation("Store.IsEnabled"), err)
+	}
+
+	return enabled, nil
+}
+
+func (ms *mfaStore) CreateWebAuthnChallenge(ctx context.Context, clientID, userID, mfaChallengeID, ceremony string, challenge []byte, expiresAt time.Time) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.CreateWebAuthnChallenge"), err) }
+
+	var id string
+
+	row := ms.db.QueryRowContext(ctx, createWebAuthnChallenge, clientID, nullable(userID), nullable(mfaChallengeID), ceremony, challenge, expiresAt)
+	if row.Err() != nil {
+		return "", wrap(row.Err())
+	}
+
+	err := row.Scan(&id)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	return id, nil
+}
+
+func (ms *mfaStore) ConsumeWebAuthnChallenge(ctx context.Context, id, clientID, ceremony string, now time.Time) (string, string, []byte, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.ConsumeWebAuthnChallenge"), err) }
+
+	var userID, mfaChallengeID string
+	var challenge []byte
+
+	row := ms.db.QueryRowContext(ctx, consumeWebAuthnChallenge, id, clientID, ceremony, now)
+	if row.Err() != nil {
+		return "", "", nil, wrap(row.Err())
+	}
+
+	//NOTE: THE CHALLENGE IS DELETED ON READ, SO EVERY CEREMONY CAN BE COMPLETED ONLY ONCE
+	err := row.Scan(&userID, &mfaChallengeID, &challenge)
+	if err == sql.ErrNoRows {
+		return "", "", nil, wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no pending webauthn challenge found with id %s", id)))
+	}
+
+	if err != nil {
+		return "", "", nil, wrap(err)
+	}
+
+	return userID, mfaChallengeID, challenge, nil
+}
+
+func (ms *mfaStore) SaveWebAuthnCredential(ctx context.Context, userID, rpID string, credential webauthn.Credential) error {
+	var id []byte
+
+	row := ms.db.QueryRowContext(ctx, saveWebAuthnCredential, credential.ID, userID, rpID, credential.PublicKey, int64(credential.SignCount))
+	if row.Err() != nil {
+		if pgErr, ok := row.Err().(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return erx.WithArgs(erx.Operation("Store.SaveWebAuthnCredential"), erx.DuplicateRecordError, row.Err())
+			}
+		}
+
+		return erx.WithArgs(erx.Operation("Store.SaveWebAuthnCredential"), row.Err())
+	}
+
+	err := row.Scan(&id)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.SaveWebAuthnCredential"), err)
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) GetWebAuthnCredentialIDs(ctx context.Context, userID, rpID string) ([][]byte, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetWebAuthnCredentialIDs"), err) }
+
+	rows, err := ms.db.QueryContext(ctx, getWebAuthnCredentialIDs, userID, rpID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var ids [][]byte
+
+	for rows.Next() {
+		var id []byte
+
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrap(err)
+	}
+
+	return ids, nil
+}
+
+func (ms *mfaStore) GetWebAuthnCredential(ctx context.Context, id []byte, rpID string) (string, webauthn.Credential, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetWebAuthnCredential"), err) }
+
+	var userID string
+	var signCount int64
+
+	credential := webauthn.Credential{ID: id}
+
+	row := ms.db.QueryRowContext(ctx, getWebAuthnCredential, id, rpID)
+	if row.Err() != nil {
+		return "", webauthn.Credential{}, wrap(row.Err())
+	}
+
+	err := row.Scan(&userID, &credential.PublicKey, &signCount)
+	if err == sql.ErrNoRows {
+		return "", webauthn.Credential{}, wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no webauthn credential found for relying party %s", rpID)))
+	}
+
+	if err != nil {
+		return "", webauthn.Credential{}, wrap(err)
+	}
+
+	credential.SignCount = uint32(signCount)
+
+	return userID, credential, nil
+}
+
+func (ms *mfaStore) UpdateSignCount(ctx context.Context, id []byte, previous, next uint32) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.UpdateSignCount"), err) }
+
+	res, err := ms.db.ExecContext(ctx, updateSignCount, id, int64(previous), int64(next))
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE PREVIOUS COUNT IS PART OF THE UPDATE, SO TWO CONCURRENT ASSERTIONS WITH THE SAME COUNTER CANNOT BOTH SUCCEED
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, errors.New("webauthn credential sign count changed concurrently")))
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) ReplaceRecoveryCodes(ctx context.Context, userID string, codes []RecoveryCode) error {
+	hashes := make([]string, len(codes))
+	salts := make([][]byte, len(codes))
+
+	for i, code := range codes {
+		hashes[i] = code.hash
+		salts[i] = code.salt
+	}
+
+	//NOTE: THE OLD CODES ARE DELETED IN THE SAME STATEMENT, SO A USER NEVER HAS TWO SETS OF CODES AT ONCE
+	_, err := ms.db.ExecContext(ctx, replaceRecoveryCodes, userID, pq.Array(hashes), pq.Array(salts))
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.ReplaceRecoveryCodes"), err)
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) GetRecoveryCodes(ctx context.Context, userID string) ([]RecoveryCode, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.GetRecoveryCodes"), err) }
+
+	rows, err := ms.db.QueryContext(ctx, getRecoveryCodes, userID)
+	if err != nil {
+		return nil, wrap(err)
+	}
+
+	defer rows.Close()
+
+	var codes []RecoveryCode
+
+	for rows.Next() {
+		var code RecoveryCode
+
+		err := rows.Scan(&code.id, &code.hash, &code.salt)
+		if err != nil {
+			return nil, wrap(err)
+		}
+
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, wrap(err)
+	}
+
+	return codes, nil
+}
+
+func (ms *mfaStore) UseRecoveryCode(ctx context.Context, id string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.UseRecoveryCode"), err) }
+
+	res, err := ms.db.ExecContext(ctx, useRecoveryCode, id)
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: ONLY AN UNUSED CODE IS UPDATED, SO TWO CONCURRENT REQUESTS WITH THE SAME CODE CANNOT BOTH SUCCEED
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no unused recovery code found with id %s", id)))
+	}
+
+	return nil
+}
+
+func (ms *mfaStore) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+
+	row := ms.db.QueryRowContext(ctx, countRecoveryCodes, userID)
+	if row.Err() != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.CountRecoveryCodes"), row.Err())
+	}
+
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.CountRecoveryCodes"), err)
+	}
+
+	return count, nil
+}
+
+func nullable(value string) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return value
+}
+
+func NewStore(db database.SQLDatabase, envelope libcrypto.Envelope) Store {
+	return &mfaStore{
+		db:       db,
+		envelope: envelope,
+	}
+}
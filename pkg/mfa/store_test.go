@@ -0,0 +1,497 @@
+package mfa_test
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/test"
+	"identification-service/pkg/webauthn"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const (
+	saveTOTPQuery = `insert into user_totp (user_id, secret) values ($1, $2)
+	on conflict (user_id) do update set secret=excluded.secret, last_used_step=0, updated_at=(now() at time zone 'utc') where user_totp.confirmed=false
+	returning user_id`
+	getTOTPQuery     = `select secret, confirmed, last_used_step from user_totp where user_id=$1`
+	confirmTOTPQuery = `update user_totp set confirmed=true, last_used_step=$2, updated_at=(now() at time zone 'utc') where user_id=$1 and confirmed=false and last_used_step < $2`
+	useTOTPStepQuery = `update user_totp set last_used_step=$2, updated_at=(now() at time zone 'utc') where user_id=$1 and confirmed=true and last_used_step < $2`
+	isEnabledQuery   = `select exists(select 1 from user_totp where user_id=$1 and confirmed=true) or exists(select 1 from webauthn_credentials where user_id=$1 and rp_id=$2)`
+
+	createChallengeQuery  = `with expired as (delete from mfa_challenges where user_id=$3 and expires_at <= (now() at time zone 'utc')) insert into mfa_challenges (id, client_id, user_id, expires_at) values ($1, $2, $3, $4)`
+	attemptChallengeQuery = `update mfa_challenges set attempts=attempts+1 where id=$1 and client_id=$2 and attempts < $3 and expires_at > $4 returning user_id`
+	consumeChallengeQuery = `delete from mfa_challenges where id=$1`
+
+	createWebAuthnChallengeQuery  = `insert into webauthn_challenges (client_id, user_id, mfa_challenge_id, ceremony, challenge, expires_at) values ($1, $2, $3, $4, $5, $6) returning id`
+	consumeWebAuthnChallengeQuery = `delete from webauthn_challenges where id=$1 and client_id=$2 and ceremony=$3 and expires_at > $4 returning coalesce(user_id::text, ''), coalesce(mfa_challenge_id::text, ''), challenge`
+	saveWebAuthnCredentialQuery   = `insert into webauthn_credentials (id, user_id, rp_id, public_key, sign_count) values ($1, $2, $3, $4, $5) returning id`
+	getWebAuthnCredentialIDsQuery = `select id from webauthn_credentials where user_id=$1 and rp_id=$2`
+	getWebAuthnCredentialQuery    = `select user_id, public_key, sign_count from webauthn_credentials where id=$1 and rp_id=$2`
+	updateSignCountQuery          = `update webauthn_credentials set sign_count=$3, last_used_at=(now() at time zone 'utc') where id=$1 and sign_count=$2`
+
+	replaceRecoveryCodesQuery = `with d as (delete from mfa_recovery_codes where user_id=$1)
+	insert into mfa_recovery_codes (user_id, code_hash, salt) select $1, c.code_hash, c.salt from unnest($2::text[], $3::bytea[]) as c(code_hash, salt)`
+	getRecoveryCodesQuery   = `select id, code_hash, salt from mfa_recovery_codes where user_id=$1 and used_at is null`
+	useRecoveryCodeQuery    = `update mfa_recovery_codes set used_at=(now() at time zone 'utc') where id=$1 and used_at is null`
+	countRecoveryCodesQuery = `select count(*) from mfa_recovery_codes where user_id=$1 and used_at is null`
+)
+
+type mfaStoreSuite struct {
+	suite.Suite
+	mock     sqlmock.Sqlmock
+	envelope *libcrypto.MockEnvelope
+	store    mfa.Store
+}
+
+func (mst *mfaStoreSuite) SetupTest() {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(mst.T(), err)
+
+	mst.mock = mock
+	mst.envelope = &libcrypto.MockEnvelope{}
+
+	mst.store = mfa.NewStore(database.NewSQLDatabase(sqlDB, test.QueryTTL), mst.envelope)
+}
+
+func (mst *mfaStoreSuite) TestSaveTOTPSuccess() {
+	userID := test.NewUUID()
+
+	mst.envelope.On("Seal", testSecret).Return([]byte("sealed"), nil)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(saveTOTPQuery)).
+		WithArgs(userID, []byte("sealed")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+
+	require.NoError(mst.T(), mst.store.SaveTOTP(context.Background(), userID, testSecret))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestSaveTOTPFailureWhenTOTPIsConfirmed() {
+	userID := test.NewUUID()
+
+	mst.envelope.On("Seal", testSecret).Return([]byte("sealed"), nil)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(saveTOTPQuery)).
+		WithArgs(userID, []byte("sealed")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	err := mst.store.SaveTOTP(context.Background(), userID, testSecret)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestSaveTOTPFailureWhenSealFails() {
+	mst.envelope.On("Seal", testSecret).Return([]byte{}, errors.New("failed to seal"))
+
+	require.Error(mst.T(), mst.store.SaveTOTP(context.Background(), test.NewUUID(), testSecret))
+}
+
+func (mst *mfaStoreSuite) TestGetTOTPSuccess() {
+	userID := test.NewUUID()
+
+	mst.envelope.On("Open", []byte("sealed")).Return(testSecret, nil)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getTOTPQuery)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed", "last_used_step"}).AddRow([]byte("sealed"), true, 42))
+
+	res, err := mst.store.GetTOTP(context.Background(), userID)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), mfa.NewTOTP(testSecret, true, 42), res)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetTOTPFailureWhenNotFound() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getTOTPQuery)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"secret", "confirmed", "last_used_step"}))
+
+	_, err := mst.store.GetTOTP(context.Background(), userID)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestConfirmTOTPSuccess() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(confirmTOTPQuery)).
+		WithArgs(userID, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.ConfirmTOTP(context.Background(), userID, 42))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUseTOTPStepSuccess() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(useTOTPStepQuery)).
+		WithArgs(userID, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.UseTOTPStep(context.Background(), userID, 42))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUseTOTPStepFailureWhenStepWasUsed() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(useTOTPStepQuery)).
+		WithArgs(userID, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := mst.store.UseTOTPStep(context.Background(), userID, 42)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestIsEnabledSuccess() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(isEnabledQuery)).
+		WithArgs(userID, test.WebAuthnRPID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	enabled, err := mst.store.IsEnabled(context.Background(), userID, test.WebAuthnRPID)
+	require.NoError(mst.T(), err)
+
+	assert.True(mst.T(), enabled)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestCreateChallengeSuccess() {
+	challengeID, clientID, userID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+	expiresAt := time.Now().UTC()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(createChallengeQuery)).
+		WithArgs(challengeID, clientID, userID, expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.CreateChallenge(context.Background(), challengeID, clientID, userID, expiresAt))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestAttemptChallengeSuccess() {
+	challengeID, clientID, userID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+	now := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(attemptChallengeQuery)).
+		WithArgs(challengeID, clientID, 5, now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+
+	res, err := mst.store.AttemptChallenge(context.Background(), challengeID, clientID, 5, now)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), userID, res)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestAttemptChallengeFailureWhenNotUsable() {
+	challengeID, clientID := test.NewUUID(), test.NewUUID()
+	now := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(attemptChallengeQuery)).
+		WithArgs(challengeID, clientID, 5, now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	_, err := mst.store.AttemptChallenge(context.Background(), challengeID, clientID, 5, now)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestConsumeChallengeSuccess() {
+	challengeID := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(consumeChallengeQuery)).
+		WithArgs(challengeID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.ConsumeChallenge(context.Background(), challengeID))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestConsumeChallengeFailureWhenAlreadyConsumed() {
+	challengeID := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(consumeChallengeQuery)).
+		WithArgs(challengeID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := mst.store.ConsumeChallenge(context.Background(), challengeID)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestCreateWebAuthnChallengeSuccess() {
+	clientID, challengeID, challenge := test.NewUUID(), test.NewUUID(), test.RandBytes(32)
+	expiresAt := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(createWebAuthnChallengeQuery)).
+		WithArgs(clientID, nil, nil, "login", challenge, expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(challengeID))
+
+	id, err := mst.store.CreateWebAuthnChallenge(context.Background(), clientID, "", "", "login", challenge, expiresAt)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), challengeID, id)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestCreateWebAuthnChallengeSuccessForSecondFactor() {
+	clientID, userID, mfaChallengeID, challengeID, challenge := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.NewUUID(), test.RandBytes(32)
+	expiresAt := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(createWebAuthnChallengeQuery)).
+		WithArgs(clientID, userID, mfaChallengeID, "login", challenge, expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(challengeID))
+
+	id, err := mst.store.CreateWebAuthnChallenge(context.Background(), clientID, userID, mfaChallengeID, "login", challenge, expiresAt)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), challengeID, id)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestConsumeWebAuthnChallengeSuccess() {
+	userID, clientID, challengeID, challenge := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.RandBytes(32)
+	mfaChallengeID := test.NewUUID()
+	now := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(consumeWebAuthnChallengeQuery)).
+		WithArgs(challengeID, clientID, "login", now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "mfa_challenge_id", "challenge"}).AddRow(userID, mfaChallengeID, challenge))
+
+	resUserID, resMFAChallengeID, resChallenge, err := mst.store.ConsumeWebAuthnChallenge(context.Background(), challengeID, clientID, "login", now)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), userID, resUserID)
+	assert.Equal(mst.T(), mfaChallengeID, resMFAChallengeID)
+	assert.Equal(mst.T(), challenge, resChallenge)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestConsumeWebAuthnChallengeFailureWhenNotFound() {
+	clientID, challengeID := test.NewUUID(), test.NewUUID()
+	now := time.Now().UTC()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(consumeWebAuthnChallengeQuery)).
+		WithArgs(challengeID, clientID, "login", now).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "mfa_challenge_id", "challenge"}))
+
+	_, _, _, err := mst.store.ConsumeWebAuthnChallenge(context.Background(), challengeID, clientID, "login", now)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestSaveWebAuthnCredentialSuccess() {
+	userID := test.NewUUID()
+	credential := test.NewAuthenticator(webauthn.AlgorithmES256).Credential()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(saveWebAuthnCredentialQuery)).
+		WithArgs(credential.ID, userID, test.WebAuthnRPID, credential.PublicKey, int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(credential.ID))
+
+	require.NoError(mst.T(), mst.store.SaveWebAuthnCredential(context.Background(), userID, test.WebAuthnRPID, credential))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestSaveWebAuthnCredentialFailureWhenDuplicate() {
+	userID := test.NewUUID()
+	credential := test.NewAuthenticator(webauthn.AlgorithmES256).Credential()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(saveWebAuthnCredentialQuery)).
+		WithArgs(credential.ID, userID, test.WebAuthnRPID, credential.PublicKey, int64(0)).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	err := mst.store.SaveWebAuthnCredential(context.Background(), userID, test.WebAuthnRPID, credential)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetWebAuthnCredentialIDsSuccess() {
+	userID, id := test.NewUUID(), test.RandBytes(16)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getWebAuthnCredentialIDsQuery)).
+		WithArgs(userID, test.WebAuthnRPID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+
+	ids, err := mst.store.GetWebAuthnCredentialIDs(context.Background(), userID, test.WebAuthnRPID)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), [][]byte{id}, ids)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetWebAuthnCredentialSuccess() {
+	userID := test.NewUUID()
+	credential := test.NewAuthenticator(webauthn.AlgorithmEdDSA).Credential()
+	credential.SignCount = 12
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getWebAuthnCredentialQuery)).
+		WithArgs(credential.ID, test.WebAuthnRPID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "public_key", "sign_count"}).AddRow(userID, credential.PublicKey, 12))
+
+	resUserID, resCredential, err := mst.store.GetWebAuthnCredential(context.Background(), credential.ID, test.WebAuthnRPID)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), userID, resUserID)
+	assert.Equal(mst.T(), credential, resCredential)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetWebAuthnCredentialFailureWhenNotFound() {
+	id := test.RandBytes(16)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getWebAuthnCredentialQuery)).
+		WithArgs(id, test.WebAuthnRPID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "public_key", "sign_count"}))
+
+	_, _, err := mst.store.GetWebAuthnCredential(context.Background(), id, test.WebAuthnRPID)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUpdateSignCountSuccess() {
+	id := test.RandBytes(16)
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(updateSignCountQuery)).
+		WithArgs(id, int64(4), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.UpdateSignCount(context.Background(), id, 4, 5))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUpdateSignCountFailureWhenCountChanged() {
+	id := test.RandBytes(16)
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(updateSignCountQuery)).
+		WithArgs(id, int64(4), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := mst.store.UpdateSignCount(context.Background(), id, 4, 5)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestReplaceRecoveryCodesSuccess() {
+	userID := test.NewUUID()
+	salt := test.RandBytes(16)
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(replaceRecoveryCodesQuery)).
+		WithArgs(userID, pq.Array([]string{"hash"}), pq.Array([][]byte{salt})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := mst.store.ReplaceRecoveryCodes(context.Background(), userID, []mfa.RecoveryCode{mfa.NewRecoveryCode("", "hash", salt)})
+	require.NoError(mst.T(), err)
+
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetRecoveryCodesSuccess() {
+	userID, id := test.NewUUID(), test.NewUUID()
+	salt := test.RandBytes(16)
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getRecoveryCodesQuery)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code_hash", "salt"}).AddRow(id, "hash", salt))
+
+	codes, err := mst.store.GetRecoveryCodes(context.Background(), userID)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), []mfa.RecoveryCode{mfa.NewRecoveryCode(id, "hash", salt)}, codes)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestGetRecoveryCodesFailureWhenRowsFail() {
+	userID := test.NewUUID()
+
+	rows := sqlmock.NewRows([]string{"id", "code_hash", "salt"}).
+		AddRow(test.NewUUID(), "hash", test.RandBytes(16)).
+		AddRow(test.NewUUID(), "hash", test.RandBytes(16)).
+		RowError(1, errors.New("failed to read row"))
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(getRecoveryCodesQuery)).
+		WithArgs(userID).
+		WillReturnRows(rows).
+		RowsWillBeClosed()
+
+	_, err := mst.store.GetRecoveryCodes(context.Background(), userID)
+	require.Error(mst.T(), err)
+
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUseRecoveryCodeSuccess() {
+	id := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(useRecoveryCodeQuery)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(mst.T(), mst.store.UseRecoveryCode(context.Background(), id))
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestUseRecoveryCodeFailureWhenAlreadyUsed() {
+	id := test.NewUUID()
+
+	mst.mock.ExpectExec(regexp.QuoteMeta(useRecoveryCodeQuery)).
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := mst.store.UseRecoveryCode(context.Background(), id)
+	require.Error(mst.T(), err)
+
+	assert.Equal(mst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func (mst *mfaStoreSuite) TestCountRecoveryCodesSuccess() {
+	userID := test.NewUUID()
+
+	mst.mock.ExpectQuery(regexp.QuoteMeta(countRecoveryCodesQuery)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(8))
+
+	count, err := mst.store.CountRecoveryCodes(context.Background(), userID)
+	require.NoError(mst.T(), err)
+
+	assert.Equal(mst.T(), 8, count)
+	require.NoError(mst.T(), mst.mock.ExpectationsWereMet())
+}
+
+func TestMFAStore(t *testing.T) {
+	suite.Run(t, new(mfaStoreSuite))
+}
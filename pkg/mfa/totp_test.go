@@ -0,0 +1,38 @@
+package mfa_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/mfa"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	//NOTE: TEST VECTORS FROM RFC 6238 TRUNCATED TO SIX DIGITS, THE SECRET IS THE BASE32 ENCODING OF "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	testCases := map[string]struct {
+		at       int64
+		expected string
+	}{
+		"test code at 59":         {at: 59, expected: "287082"},
+		"test code at 1111111109": {at: 1111111109, expected: "081804"},
+		"test code at 1234567890": {at: 1234567890, expected: "005924"},
+		"test code at 2000000000": {at: 2000000000, expected: "279037"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			code, err := mfa.TOTPCode(secret, time.Unix(testCase.at, 0))
+			require.NoError(t, err)
+
+			assert.Equal(t, testCase.expected, code)
+		})
+	}
+}
+
+func TestTOTPCodeFailureWhenSecretIsInvalid(t *testing.T) {
+	_, err := mfa.TOTPCode("not base32!", time.Now())
+	require.Error(t, err)
+}
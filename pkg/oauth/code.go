@@ -0,0 +1,77 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+	"time"
+)
+
+const (
+	codeSize                = 32
+	CodeChallengeMethodS256 = "S256"
+)
+
+var (
+	codeVerifierRegex  = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+	codeChallengeRegex = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+)
+
+type AuthorizationCode struct {
+	code          string
+	clientID      string
+	userID        string
+	redirectURI   string
+	codeChallenge string
+	scope         string
+	nonce         string
+	authTime      time.Time
+	expiresAt     time.Time
+}
+
+func NewAuthorizationCode(code, clientID, userID, redirectURI, codeChallenge, scope, nonce string, authTime, expiresAt time.Time) AuthorizationCode {
+	return AuthorizationCode{
+		code:          code,
+		clientID:      clientID,
+		userID:        userID,
+		redirectURI:   redirectURI,
+		codeChallenge: codeChallenge,
+		scope:         scope,
+		nonce:         nonce,
+		authTime:      authTime,
+		expiresAt:     expiresAt,
+	}
+}
+
+func (ac AuthorizationCode) IsExpired(now time.Time) bool {
+	return !now.Before(ac.expiresAt)
+}
+
+func newCode() (string, error) {
+	b := make([]byte, codeSize)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isValidCodeChallenge(codeChallenge string) bool {
+	return codeChallengeRegex.MatchString(codeChallenge)
+}
+
+func isValidCodeVerifier(codeVerifier string) bool {
+	return codeVerifierRegex.MatchString(codeVerifier)
+}
+
+func NewCodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func verifyCodeChallenge(codeChallenge, codeVerifier string) bool {
+	return subtle.ConstantTimeCompare([]byte(NewCodeChallenge(codeVerifier)), []byte(codeChallenge)) == 1
+}
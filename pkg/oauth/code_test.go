@@ -0,0 +1,35 @@
+package oauth_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/oauth"
+	"testing"
+	"time"
+)
+
+func TestNewCodeChallenge(t *testing.T) {
+	//NOTE: TEST VECTOR FROM APPENDIX B OF RFC 7636
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oauth.NewCodeChallenge(verifier))
+}
+
+func TestAuthorizationCodeIsExpired(t *testing.T) {
+	now := time.Now().UTC()
+
+	testCases := map[string]struct {
+		expiresAt time.Time
+		expected  bool
+	}{
+		"test code is not expired before expiry": {expiresAt: now.Add(time.Second), expected: false},
+		"test code is expired at expiry":         {expiresAt: now, expected: true},
+		"test code is expired after expiry":      {expiresAt: now.Add(-time.Second), expected: true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ac := oauth.NewAuthorizationCode("code", "client", "user", "https://app.example.com", "challenge", "", "", time.Now(), testCase.expiresAt)
+			assert.Equal(t, testCase.expected, ac.IsExpired(now))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	DeviceCodeStatusPending  = "pending"
+	DeviceCodeStatusApproved = "approved"
+	DeviceCodeStatusDenied   = "denied"
+	DeviceCodeStatusConsumed = "consumed"
+
+	//NOTE: CONSONANTS ONLY AS RECOMMENDED BY RFC 8628, THE CODE IS TYPED BY HAND AND SHOULD NOT SPELL WORDS
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeSize    = 8
+
+	slowDownIncrement = 5
+)
+
+type DeviceCode struct {
+	deviceCode   string
+	userCode     string
+	clientID     string
+	userID       string
+	scope        string
+	status       string
+	interval     int
+	lastPolledAt time.Time
+	authTime     time.Time
+	expiresAt    time.Time
+}
+
+func NewDeviceCode(
+	deviceCode, userCode, clientID, userID, scope, status string,
+	interval int,
+	lastPolledAt, authTime, expiresAt time.Time,
+) DeviceCode {
+	return DeviceCode{
+		deviceCode:   deviceCode,
+		userCode:     userCode,
+		clientID:     clientID,
+		userID:       userID,
+		scope:        scope,
+		status:       status,
+		interval:     interval,
+		lastPolledAt: lastPolledAt,
+		authTime:     authTime,
+		expiresAt:    expiresAt,
+	}
+}
+
+func (dc DeviceCode) IsExpired(now time.Time) bool {
+	return !now.Before(dc.expiresAt)
+}
+
+func (dc DeviceCode) IsPolledTooSoon(now time.Time) bool {
+	if dc.lastPolledAt.IsZero() {
+		return false
+	}
+
+	return now.Before(dc.lastPolledAt.Add(time.Duration(dc.interval) * time.Second))
+}
+
+func newUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeCharset)))
+
+	var sb strings.Builder
+
+	for i := 0; i < userCodeSize; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteByte(userCodeCharset[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+func normalizeUserCode(userCode string) string {
+	//NOTE: USERS MAY TYPE THE CODE IN LOWER CASE AND WITH OR WITHOUT THE SEPARATOR
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+}
+
+func formatUserCode(userCode string) string {
+	if len(userCode) != userCodeSize {
+		return userCode
+	}
+
+	return userCode[:userCodeSize/2] + "-" + userCode[userCodeSize/2:]
+}
@@ -0,0 +1,48 @@
+package oauth_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/oauth"
+	"testing"
+	"time"
+)
+
+func TestDeviceCodeIsExpired(t *testing.T) {
+	now := time.Now().UTC()
+
+	testCases := map[string]struct {
+		expiresAt time.Time
+		expected  bool
+	}{
+		"test code is not expired before expiry": {expiresAt: now.Add(time.Second), expected: false},
+		"test code is expired at expiry":         {expiresAt: now, expected: true},
+		"test code is expired after expiry":      {expiresAt: now.Add(-time.Second), expected: true},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dc := oauth.NewDeviceCode("device", "user", "client", "", "", oauth.DeviceCodeStatusPending, 5, time.Time{}, time.Time{}, testCase.expiresAt)
+			assert.Equal(t, testCase.expected, dc.IsExpired(now))
+		})
+	}
+}
+
+func TestDeviceCodeIsPolledTooSoon(t *testing.T) {
+	now := time.Now().UTC()
+
+	testCases := map[string]struct {
+		lastPolledAt time.Time
+		expected     bool
+	}{
+		"test first poll is never too soon":            {lastPolledAt: time.Time{}, expected: false},
+		"test poll within the interval is too soon":    {lastPolledAt: now.Add(-4 * time.Second), expected: true},
+		"test poll after the interval is not too soon": {lastPolledAt: now.Add(-5 * time.Second), expected: false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dc := oauth.NewDeviceCode("device", "user", "client", "", "", oauth.DeviceCodeStatusPending, 5, testCase.lastPolledAt, time.Time{}, now.Add(time.Minute))
+			assert.Equal(t, testCase.expected, dc.IsPolledTooSoon(now))
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package oauth
+
+import "github.com/nsnikhil/erx"
+
+const (
+	InvalidRequestError          erx.Kind = "invalid_request"
+	InvalidClientError           erx.Kind = "invalid_client"
+	InvalidGrantError            erx.Kind = "invalid_grant"
+	InvalidScopeError            erx.Kind = "invalid_scope"
+	InvalidTargetError           erx.Kind = "invalid_target"
+	UnsupportedGrantTypeError    erx.Kind = "unsupported_grant_type"
+	UnsupportedResponseTypeError erx.Kind = "unsupported_response_type"
+	ServerError                  erx.Kind = "server_error"
+
+	//NOTE: RFC 6750 ERROR CODE, RETURNED BY RESOURCES SUCH AS USERINFO WHICH ACCEPT BEARER TOKENS
+	InvalidTokenError erx.Kind = "invalid_token"
+
+	//NOTE: RFC 8628 ERROR CODES, RETURNED TO A DEVICE POLLING THE TOKEN ENDPOINT
+	AuthorizationPendingError erx.Kind = "authorization_pending"
+	SlowDownError             erx.Kind = "slow_down"
+	AccessDeniedError         erx.Kind = "access_denied"
+	ExpiredTokenError         erx.Kind = "expired_token"
+
+	//NOTE: NOT AN RFC 6749 ERROR CODE, AN UNREGISTERED REDIRECT URI MUST NEVER BE REDIRECTED TO
+	InvalidRedirectURIError erx.Kind = "invalid_redirect_uri"
+
+	//NOTE: NOT AN RFC 6749 ERROR CODE, THE LOGIN FORM IS SHOWN AGAIN ASKING FOR A SECOND FACTOR
+	MFARequiredError erx.Kind = "mfa_required"
+)
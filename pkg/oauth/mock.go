@@ -0,0 +1,111 @@
+package oauth
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/client"
+	"time"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (mock *MockService) ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) error {
+	args := mock.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (mock *MockService) Authorize(ctx context.Context, req AuthorizationRequest, email, password, mfaToken, mfaCode string) (string, string, error) {
+	args := mock.Called(ctx, req, email, password, mfaToken, mfaCode)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (mock *MockService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (client.Client, error) {
+	args := mock.Called(ctx, clientID, clientSecret)
+	return args.Get(0).(client.Client), args.Error(1)
+}
+
+func (mock *MockService) ExchangeAuthorizationCode(ctx context.Context, code, redirectURI, codeVerifier string) (Token, error) {
+	args := mock.Called(ctx, code, redirectURI, codeVerifier)
+	return args.Get(0).(Token), args.Error(1)
+}
+
+func (mock *MockService) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	args := mock.Called(ctx, refreshToken)
+	return args.Get(0).(Token), args.Error(1)
+}
+
+func (mock *MockService) ClientCredentials(ctx context.Context, scopes []string) (Token, error) {
+	args := mock.Called(ctx, scopes)
+	return args.Get(0).(Token), args.Error(1)
+}
+
+func (mock *MockService) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	args := mock.Called(ctx, accessToken)
+	return args.Get(0).(UserInfo), args.Error(1)
+}
+
+func (mock *MockService) AuthorizeDevice(ctx context.Context, scope string) (DeviceAuthorization, error) {
+	args := mock.Called(ctx, scope)
+	return args.Get(0).(DeviceAuthorization), args.Error(1)
+}
+
+func (mock *MockService) VerifyDevice(ctx context.Context, userCode, email, password, mfaToken, mfaCode string, approved bool) (string, error) {
+	args := mock.Called(ctx, userCode, email, password, mfaToken, mfaCode, approved)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) DeviceToken(ctx context.Context, deviceCode string) (Token, error) {
+	args := mock.Called(ctx, deviceCode)
+	return args.Get(0).(Token), args.Error(1)
+}
+
+func (mock *MockService) ExchangeToken(ctx context.Context, subjectToken, subjectTokenType, audience string, scopes []string) (Token, error) {
+	args := mock.Called(ctx, subjectToken, subjectTokenType, audience, scopes)
+	return args.Get(0).(Token), args.Error(1)
+}
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (mock *MockStore) CreateAuthorizationCode(ctx context.Context, code AuthorizationCode) error {
+	args := mock.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (mock *MockStore) ConsumeAuthorizationCode(ctx context.Context, code string) (AuthorizationCode, error) {
+	args := mock.Called(ctx, code)
+	return args.Get(0).(AuthorizationCode), args.Error(1)
+}
+
+func (mock *MockStore) CreateDeviceCode(ctx context.Context, code DeviceCode) error {
+	args := mock.Called(ctx, code)
+	return args.Error(0)
+}
+
+func (mock *MockStore) GetDeviceCodeClient(ctx context.Context, userCode string, now time.Time) (string, error) {
+	args := mock.Called(ctx, userCode, now)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) ResolveDeviceCode(ctx context.Context, userCode, userID string, approved bool, authTime time.Time) error {
+	args := mock.Called(ctx, userCode, userID, approved, authTime)
+	return args.Error(0)
+}
+
+func (mock *MockStore) PollDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time) (DeviceCode, error) {
+	args := mock.Called(ctx, deviceCode, polledAt)
+	return args.Get(0).(DeviceCode), args.Error(1)
+}
+
+func (mock *MockStore) SlowDownDeviceCode(ctx context.Context, deviceCode string) error {
+	args := mock.Called(ctx, deviceCode)
+	return args.Error(0)
+}
+
+func (mock *MockStore) ConsumeDeviceCode(ctx context.Context, deviceCode string) error {
+	args := mock.Called(ctx, deviceCode)
+	return args.Error(0)
+}
@@ -0,0 +1,752 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/session"
+	"identification-service/pkg/token"
+	"identification-service/pkg/user"
+	"strings"
+	"time"
+)
+
+const (
+	ResponseTypeCode = "code"
+	TokenTypeBearer  = "Bearer"
+	ScopeOpenID      = "openid"
+	ScopeEmail       = "email"
+	ScopeProfile     = "profile"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+	scopeClaim       = "scope"
+	actorClaim       = "act"
+	sessionIDClaim   = "session_id"
+	tenantIDClaim    = "tenant_id"
+	rolesClaim       = "roles"
+	permissionsClaim = "permissions"
+)
+
+var openIDScopes = map[string]bool{ScopeOpenID: true, ScopeEmail: true, ScopeProfile: true}
+
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Scope               string
+	Nonce               string
+}
+
+type Token struct {
+	AccessToken     string
+	RefreshToken    string
+	TokenType       string
+	ExpiresIn       int
+	Scope           string
+	IDToken         string
+	IssuedTokenType string
+}
+
+type DeviceAuthorization struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               int
+	Interval                int
+}
+
+type UserInfo struct {
+	Subject string
+	Email   string
+	Name    string
+}
+
+type Service interface {
+	ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) error
+	Authorize(ctx context.Context, req AuthorizationRequest, email, password, mfaToken, mfaCode string) (string, string, error)
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) (client.Client, error)
+	ExchangeAuthorizationCode(ctx context.Context, code, redirectURI, codeVerifier string) (Token, error)
+	RefreshToken(ctx context.Context, refreshToken string) (Token, error)
+	ClientCredentials(ctx context.Context, scopes []string) (Token, error)
+	UserInfo(ctx context.Context, accessToken string) (UserInfo, error)
+	AuthorizeDevice(ctx context.Context, scope string) (DeviceAuthorization, error)
+	VerifyDevice(ctx context.Context, userCode, email, password, mfaToken, mfaCode string, approved bool) (string, error)
+	DeviceToken(ctx context.Context, deviceCode string) (Token, error)
+	ExchangeToken(ctx context.Context, subjectToken, subjectTokenType, audience string, scopes []string) (Token, error)
+}
+
+type oauthService struct {
+	cfg            config.OAuthConfig
+	store          Store
+	clientService  client.Service
+	userService    user.Service
+	sessionService session.Service
+	mfaService     mfa.Service
+	generator      token.Generator
+}
+
+func (oa *oauthService) ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) error {
+	_, err := oa.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.ValidateAuthorizationRequest"), err)
+	}
+
+	return nil
+}
+
+func (oa *oauthService) validateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) (client.Client, error) {
+	cl, err := oa.clientService.GetClientByName(ctx, req.ClientID)
+	if err != nil {
+		return client.Client{}, erx.WithArgs(InvalidClientError, err)
+	}
+
+	if cl.IsRevoked() {
+		return client.Client{}, erx.WithArgs(InvalidClientError, fmt.Errorf("client %s is revoked", req.ClientID))
+	}
+
+	if !cl.IsRedirectURIRegistered(req.RedirectURI) {
+		return client.Client{}, erx.WithArgs(InvalidRedirectURIError, fmt.Errorf("redirect uri %s is not registered", req.RedirectURI))
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return client.Client{}, erx.WithArgs(UnsupportedResponseTypeError, fmt.Errorf("unsupported response type %s", req.ResponseType))
+	}
+
+	//NOTE: PKCE IS MANDATORY FOR EVERY CLIENT AND ONLY THE S256 METHOD IS ACCEPTED
+	if req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return client.Client{}, erx.WithArgs(InvalidRequestError, errors.New("code challenge method must be S256"))
+	}
+
+	if !isValidCodeChallenge(req.CodeChallenge) {
+		return client.Client{}, erx.WithArgs(InvalidRequestError, errors.New("invalid code challenge"))
+	}
+
+	if !cl.AllowsScopes(clientScopes(req.Scope)) {
+		return client.Client{}, erx.WithArgs(InvalidScopeError, fmt.Errorf("client %s is not allowed the requested scopes", cl.Name))
+	}
+
+	return cl, nil
+}
+
+func (oa *oauthService) Authorize(ctx context.Context, req AuthorizationRequest, email, password, mfaToken, mfaCode string) (string, string, error) {
+	wrap := func(err error) (string, string, error) {
+		return "", "", erx.WithArgs(erx.Operation("Service.Authorize"), err)
+	}
+
+	cl, err := oa.validateAuthorizationRequest(ctx, req)
+	if err != nil {
+		return wrap(err)
+	}
+
+	userID, err := oa.userService.GetUserID(ctx, cl.TenantID, email, password)
+	if err != nil {
+		return wrap(err)
+	}
+
+	mfaToken, err = oa.verifySecondFactor(ctx, cl, userID, mfaToken, mfaCode)
+	if err != nil {
+		return "", mfaToken, erx.WithArgs(erx.Operation("Service.Authorize"), err)
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return wrap(err)
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(oa.cfg.AuthorizationCodeTTL()) * time.Second)
+
+	ac := NewAuthorizationCode(code, cl.Id, userID, req.RedirectURI, req.CodeChallenge, req.Scope, req.Nonce, now, expiresAt)
+
+	if err := oa.store.CreateAuthorizationCode(ctx, ac); err != nil {
+		return wrap(err)
+	}
+
+	return code, "", nil
+}
+
+func (oa *oauthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (client.Client, error) {
+	wrap := func(err error) (client.Client, error) {
+		return client.Client{}, erx.WithArgs(erx.Operation("Service.AuthenticateClient"), InvalidClientError, err)
+	}
+
+	cl, err := oa.clientService.GetClientByName(ctx, clientID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if cl.IsRevoked() {
+		return wrap(fmt.Errorf("client %s is revoked", clientID))
+	}
+
+	//NOTE: PUBLIC CLIENTS CANNOT KEEP A SECRET, THEY ARE IDENTIFIED BY NAME AND BOUND TO THEIR CODES THROUGH PKCE
+	if cl.IsPublic() {
+		if len(clientSecret) != 0 {
+			return wrap(fmt.Errorf("public client %s cannot authenticate with a secret", clientID))
+		}
+
+		return cl, nil
+	}
+
+	if len(clientSecret) == 0 || subtle.ConstantTimeCompare([]byte(cl.Secret), []byte(clientSecret)) != 1 {
+		return wrap(fmt.Errorf("invalid secret for client %s", clientID))
+	}
+
+	return cl, nil
+}
+
+func (oa *oauthService) ExchangeAuthorizationCode(ctx context.Context, code, redirectURI, codeVerifier string) (Token, error) {
+	wrap := func(err error) (Token, error) {
+		return Token{}, erx.WithArgs(erx.Operation("Service.ExchangeAuthorizationCode"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !isValidCodeVerifier(codeVerifier) {
+		return wrap(erx.WithArgs(InvalidRequestError, errors.New("invalid code verifier")))
+	}
+
+	ac, err := oa.store.ConsumeAuthorizationCode(ctx, code)
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	if err := validateAuthorizationCode(ac, cl, redirectURI, codeVerifier); err != nil {
+		return wrap(erx.WithArgs(InvalidGrantError, err))
+	}
+
+	var idToken string
+
+	if hasScope(ac.scope, ScopeOpenID) {
+		idToken, err = oa.newIDToken(ctx, cl, ac.userID, ac.nonce, ac.authTime)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	accessToken, refreshToken, err := oa.sessionService.StartSession(ctx, ac.userID, ac.scope)
+	if err != nil {
+		return wrap(err)
+	}
+
+	tk := newToken(cl, accessToken, refreshToken)
+	tk.Scope = ac.scope
+	tk.IDToken = idToken
+
+	return tk, nil
+}
+
+func (oa *oauthService) newIDToken(ctx context.Context, cl client.Client, userID, nonce string, authTime time.Time) (string, error) {
+	u, err := oa.userService.GetUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return oa.generator.GenerateIDToken(cl.AccessTokenTTL(), cl.SigningKey(), token.IDClaims{
+		Issuer:   oa.cfg.IssuerURL(),
+		Subject:  userID,
+		Audience: cl.Name,
+		AuthTime: authTime.Unix(),
+		Nonce:    nonce,
+		Email:    u.Email(),
+		Name:     u.Name(),
+	})
+}
+
+func hasScope(scope, expected string) bool {
+	for _, s := range strings.Fields(scope) {
+		if s == expected {
+			return true
+		}
+	}
+
+	return false
+}
+
+func clientScopes(scope string) []string {
+	//NOTE: OPENID CONNECT SCOPES ONLY SELECT WHAT THE ID TOKEN AND USERINFO CARRY, SO CLIENTS DO NOT NEED TO BE ALLOWED THEM
+	var scopes []string
+
+	for _, s := range strings.Fields(scope) {
+		if !openIDScopes[s] {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return scopes
+}
+
+func validateAuthorizationCode(ac AuthorizationCode, cl client.Client, redirectURI, codeVerifier string) error {
+	if ac.clientID != cl.Id {
+		return errors.New("authorization code was issued to another client")
+	}
+
+	if ac.IsExpired(time.Now().UTC()) {
+		return errors.New("authorization code expired")
+	}
+
+	if ac.redirectURI != redirectURI {
+		return errors.New("redirect uri does not match the authorization request")
+	}
+
+	if !verifyCodeChallenge(ac.codeChallenge, codeVerifier) {
+		return errors.New("code verifier does not match the code challenge")
+	}
+
+	return nil
+}
+
+func (oa *oauthService) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	wrap := func(err error) (Token, error) {
+		return Token{}, erx.WithArgs(erx.Operation("Service.RefreshToken"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, nextRefreshToken, err := oa.sessionService.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		if isKind(err, erx.AuthenticationError) || isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	return newToken(cl, accessToken, nextRefreshToken), nil
+}
+
+func (oa *oauthService) ClientCredentials(ctx context.Context, scopes []string) (Token, error) {
+	wrap := func(err error) (Token, error) {
+		return Token{}, erx.WithArgs(erx.Operation("Service.ClientCredentials"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: A CLIENT WHICH DOES NOT ASK FOR ANY SCOPE IS GRANTED EVERY SCOPE IT IS ALLOWED
+	if len(scopes) == 0 {
+		scopes = cl.AllowedScopes
+	}
+
+	if !cl.AllowsScopes(scopes) {
+		return wrap(erx.WithArgs(InvalidScopeError, fmt.Errorf("client %s is not allowed the requested scopes", cl.Name)))
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	//NOTE: THE CLIENT ACTS ON ITS OWN BEHALF, SO IT IS THE SUBJECT AND NO SESSION BACKS THE TOKEN
+	accessToken, _, err := oa.generator.GenerateAccessToken(
+		cl.AccessTokenTTL(),
+		cl.Id,
+		cl.SigningKey(),
+		map[string]string{scopeClaim: scope, tenantIDClaim: cl.TenantID},
+	)
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	tk := newToken(cl, accessToken, "")
+	tk.Scope = scope
+
+	return tk, nil
+}
+
+func (oa *oauthService) UserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	wrap := func(err error) (UserInfo, error) {
+		return UserInfo{}, erx.WithArgs(erx.Operation("Service.UserInfo"), err)
+	}
+
+	in, err := oa.sessionService.IntrospectToken(ctx, accessToken)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !in.Active {
+		return wrap(erx.WithArgs(InvalidTokenError, errors.New("access token is not active")))
+	}
+
+	//NOTE: TOKENS ISSUED THROUGH CLIENT CREDENTIALS HAVE THE CLIENT AS SUBJECT, SO NO USER IS FOUND FOR THEM
+	u, err := oa.userService.GetUser(ctx, in.Claims.Subject)
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidTokenError, err))
+		}
+
+		return wrap(err)
+	}
+
+	return UserInfo{Subject: u.ID(), Email: u.Email(), Name: u.Name()}, nil
+}
+
+func (oa *oauthService) AuthorizeDevice(ctx context.Context, scope string) (DeviceAuthorization, error) {
+	wrap := func(err error) (DeviceAuthorization, error) {
+		return DeviceAuthorization{}, erx.WithArgs(erx.Operation("Service.AuthorizeDevice"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !cl.AllowsScopes(clientScopes(scope)) {
+		return wrap(erx.WithArgs(InvalidScopeError, fmt.Errorf("client %s is not allowed the requested scopes", cl.Name)))
+	}
+
+	deviceCode, err := newCode()
+	if err != nil {
+		return wrap(err)
+	}
+
+	userCode, err := newUserCode()
+	if err != nil {
+		return wrap(err)
+	}
+
+	ttl, interval := oa.cfg.DeviceCodeTTL(), oa.cfg.DevicePollInterval()
+	expiresAt := time.Now().UTC().Add(time.Duration(ttl) * time.Second)
+
+	dc := NewDeviceCode(deviceCode, userCode, cl.Id, "", scope, DeviceCodeStatusPending, interval, time.Time{}, time.Time{}, expiresAt)
+
+	if err := oa.store.CreateDeviceCode(ctx, dc); err != nil {
+		return wrap(err)
+	}
+
+	verificationURI := oa.cfg.IssuerURL() + "/oauth/device"
+
+	return DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                formatUserCode(userCode),
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + formatUserCode(userCode),
+		ExpiresIn:               ttl,
+		Interval:                interval,
+	}, nil
+}
+
+func (oa *oauthService) VerifyDevice(ctx context.Context, userCode, email, password, mfaToken, mfaCode string, approved bool) (string, error) {
+	wrap := func(err error) (string, error) { return "", erx.WithArgs(erx.Operation("Service.VerifyDevice"), err) }
+
+	//NOTE: THE DEVICE PAGE IS NOT CALLED BY A CLIENT, SO THE USER IS LOOKED UP THROUGH THE CLIENT WHICH ASKED FOR THE CODE
+	clientName, err := oa.store.GetDeviceCodeClient(ctx, normalizeUserCode(userCode), time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	cl, err := oa.clientService.GetClientByName(ctx, clientName)
+	if err != nil {
+		return wrap(err)
+	}
+
+	userID, err := oa.userService.GetUserID(ctx, cl.TenantID, email, password)
+	if err != nil {
+		return wrap(err)
+	}
+
+	mfaToken, err = oa.verifySecondFactor(ctx, cl, userID, mfaToken, mfaCode)
+	if err != nil {
+		return mfaToken, erx.WithArgs(erx.Operation("Service.VerifyDevice"), err)
+	}
+
+	if err := oa.store.ResolveDeviceCode(ctx, normalizeUserCode(userCode), userID, approved, time.Now().UTC()); err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	return "", nil
+}
+
+func (oa *oauthService) DeviceToken(ctx context.Context, deviceCode string) (Token, error) {
+	wrap := func(err error) (Token, error) {
+		return Token{}, erx.WithArgs(erx.Operation("Service.DeviceToken"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	now := time.Now().UTC()
+
+	dc, err := oa.store.PollDeviceCode(ctx, deviceCode, now)
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	if dc.clientID != cl.Id {
+		return wrap(erx.WithArgs(InvalidGrantError, errors.New("device code was issued to another client")))
+	}
+
+	if dc.IsExpired(now) {
+		return wrap(erx.WithArgs(ExpiredTokenError, errors.New("device code expired")))
+	}
+
+	//NOTE: A DEVICE POLLING FASTER THAN ITS INTERVAL HAS THE INTERVAL INCREASED FOR EVERY LATER POLL AS PER RFC 8628
+	if dc.IsPolledTooSoon(now) {
+		if err := oa.store.SlowDownDeviceCode(ctx, deviceCode); err != nil {
+			return wrap(err)
+		}
+
+		return wrap(erx.WithArgs(SlowDownError, fmt.Errorf("polling interval is %d seconds", dc.interval+slowDownIncrement)))
+	}
+
+	switch dc.status {
+	case DeviceCodeStatusPending:
+		return wrap(erx.WithArgs(AuthorizationPendingError, errors.New("user has not approved the device yet")))
+	case DeviceCodeStatusDenied:
+		return wrap(erx.WithArgs(AccessDeniedError, errors.New("user denied the device")))
+	case DeviceCodeStatusConsumed:
+		return wrap(erx.WithArgs(InvalidGrantError, errors.New("device code already used")))
+	}
+
+	if err := oa.store.ConsumeDeviceCode(ctx, deviceCode); err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(InvalidGrantError, err))
+		}
+
+		return wrap(err)
+	}
+
+	var idToken string
+
+	if hasScope(dc.scope, ScopeOpenID) {
+		idToken, err = oa.newIDToken(ctx, cl, dc.userID, "", dc.authTime)
+		if err != nil {
+			return wrap(err)
+		}
+	}
+
+	accessToken, refreshToken, err := oa.sessionService.StartSession(ctx, dc.userID, dc.scope)
+	if err != nil {
+		return wrap(err)
+	}
+
+	tk := newToken(cl, accessToken, refreshToken)
+	tk.Scope = dc.scope
+	tk.IDToken = idToken
+
+	return tk, nil
+}
+
+func (oa *oauthService) ExchangeToken(ctx context.Context, subjectToken, subjectTokenType, audience string, scopes []string) (Token, error) {
+	wrap := func(err error) (Token, error) {
+		return Token{}, erx.WithArgs(erx.Operation("Service.ExchangeToken"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if subjectTokenType != TokenTypeAccessToken {
+		return wrap(erx.WithArgs(InvalidRequestError, fmt.Errorf("unsupported subject token type %s", subjectTokenType)))
+	}
+
+	if !cl.AllowsAudience(audience) {
+		return wrap(erx.WithArgs(InvalidTargetError, fmt.Errorf("client %s is not allowed to exchange tokens for %s", cl.Name, audience)))
+	}
+
+	in, err := oa.sessionService.IntrospectToken(ctx, subjectToken)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if !in.Active {
+		return wrap(erx.WithArgs(InvalidRequestError, errors.New("subject token is not active")))
+	}
+
+	if in.TenantID != cl.TenantID {
+		return wrap(erx.WithArgs(erx.AuthenticationError, fmt.Errorf("subject token does not belong to tenant %s", cl.TenantID)))
+	}
+
+	subjectScope := in.Claims.Get(scopeClaim)
+
+	//NOTE: THE EXCHANGED TOKEN IS NEVER BROADER THAN THE SUBJECT TOKEN OR THE SCOPES THE ACTING CLIENT IS ALLOWED
+	//NOTE: A SUBJECT TOKEN WITHOUT A SCOPE IS EXCHANGED FOR A TOKEN WITHOUT ONE, IT NEVER PICKS UP THE SCOPES OF THE ACTING CLIENT
+	if len(scopes) == 0 {
+		scopes = grantedScopes(cl, strings.Fields(subjectScope))
+	}
+
+	if !cl.AllowsScopes(scopes) || !containsScopes(subjectScope, scopes) {
+		return wrap(erx.WithArgs(InvalidScopeError, fmt.Errorf("client %s cannot exchange for the requested scopes", cl.Name)))
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	claims := map[string]string{token.AudienceClaim: audience, actorClaim: cl.Id, scopeClaim: scope, tenantIDClaim: in.TenantID}
+
+	//NOTE: ROLES AND PERMISSIONS ARE NARROWED LIKE THE SCOPES, ONLY THOSE NAMED BY A REQUESTED SCOPE REACH THE TARGET SERVICE
+	for _, name := range []string{rolesClaim, permissionsClaim} {
+		if granted := scopedValues(in.Claims.Get(name), scope); len(granted) != 0 {
+			claims[name] = granted
+		}
+	}
+
+	//NOTE: THE SESSION IS CARRIED OVER SO THE EXCHANGED TOKEN IS REVOKED ALONG WITH THE SUBJECT TOKEN
+	if sessionID := in.Claims.Get(sessionIDClaim); len(sessionID) != 0 {
+		claims[sessionIDClaim] = sessionID
+	}
+
+	accessToken, _, err := oa.generator.GenerateAccessToken(cl.AccessTokenTTL(), in.Claims.Subject, cl.SigningKey(), claims)
+	if err != nil {
+		return wrap(err)
+	}
+
+	tk := newToken(cl, accessToken, "")
+	tk.Scope = scope
+	tk.IssuedTokenType = TokenTypeAccessToken
+
+	return tk, nil
+}
+
+func grantedScopes(cl client.Client, scopes []string) []string {
+	var granted []string
+
+	for _, scope := range scopes {
+		if cl.AllowsScopes([]string{scope}) {
+			granted = append(granted, scope)
+		}
+	}
+
+	return granted
+}
+
+func scopedValues(values, scope string) string {
+	var granted []string
+
+	for _, value := range strings.Fields(values) {
+		if hasScope(scope, value) {
+			granted = append(granted, value)
+		}
+	}
+
+	return strings.Join(granted, " ")
+}
+
+func containsScopes(scope string, expected []string) bool {
+	for _, e := range expected {
+		if !hasScope(scope, e) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newToken(cl client.Client, accessToken, refreshToken string) Token {
+	return Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    TokenTypeBearer,
+		ExpiresIn:    cl.AccessTokenTTL() * 60,
+	}
+}
+
+func isNotFound(err error) bool {
+	return isKind(err, erx.ResourceNotFoundError)
+}
+
+func (oa *oauthService) verifySecondFactor(ctx context.Context, cl client.Client, userID, mfaToken, mfaCode string) (string, error) {
+	//NOTE: PASSKEYS ONLY COUNT FOR THE RELYING PARTY OF THE CLIENT, SO IT IS PUT ON THE CONTEXT FOR THE BROWSER FLOWS WHICH HAVE NONE
+	ctx, err := client.WithContext(ctx, cl)
+	if err != nil {
+		return "", err
+	}
+
+	enabled, err := oa.mfaService.IsEnabled(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if !enabled {
+		return "", nil
+	}
+
+	//NOTE: THE CHALLENGE IS ISSUED ONCE AND CARRIED BY THE FORM, SO ITS ATTEMPT LIMIT HOLDS ACROSS EVERY POST OF THE SAME LOGIN
+	if len(mfaToken) == 0 {
+		mfaToken, err = oa.mfaService.Challenge(ctx, cl.Id, userID)
+		if err != nil {
+			return "", err
+		}
+
+		return mfaToken, erx.WithArgs(MFARequiredError, fmt.Errorf("second factor required for user %s", userID))
+	}
+
+	//NOTE: THE LOGIN FORMS CANNOT RUN A PASSKEY CEREMONY, A TOTP OR RECOVERY CODE IS ENTERED ALONG WITH THE PASSWORD INSTEAD
+	if len(mfaCode) == 0 {
+		return mfaToken, erx.WithArgs(MFARequiredError, fmt.Errorf("second factor required for user %s", userID))
+	}
+
+	challengedUserID, err := oa.mfaService.VerifyChallenge(ctx, cl.Id, mfaToken, mfaCode)
+	if err != nil {
+		if isKind(err, erx.AuthenticationError) {
+			return mfaToken, erx.WithArgs(MFARequiredError, err)
+		}
+
+		return "", err
+	}
+
+	if challengedUserID != userID {
+		return "", erx.WithArgs(MFARequiredError, fmt.Errorf("mfa challenge was issued to another user than %s", userID))
+	}
+
+	return "", nil
+}
+
+func isKind(err error, kind erx.Kind) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == kind
+}
+
+func NewService(
+	cfg config.OAuthConfig,
+	store Store,
+	clientService client.Service,
+	userService user.Service,
+	sessionService session.Service,
+	mfaService mfa.Service,
+	generator token.Generator,
+) Service {
+	return &oauthService{
+		cfg:            cfg,
+		store:          store,
+		clientService:  clientService,
+		userService:    userService,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		generator:      generator,
+	}
+}
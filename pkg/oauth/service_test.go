@@ -0,0 +1,1253 @@
+package oauth_test
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/oauth"
+	"identification-service/pkg/password"
+	"identification-service/pkg/session"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"identification-service/pkg/user"
+	"testing"
+	"time"
+)
+
+const codeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+type oauthServiceSuite struct {
+	suite.Suite
+	clientCfg config.ClientConfig
+	oauthCfg  config.OAuthConfig
+}
+
+func (st *oauthServiceSuite) SetupSuite() {
+	mockClientConfig := &config.MockClientConfig{}
+	mockClientConfig.On("Strategies").
+		Return(map[string]bool{test.ClientSessionStrategyRevokeOld: true})
+
+	mockOAuthConfig := &config.MockOAuthConfig{}
+	mockOAuthConfig.On("AuthorizationCodeTTL").Return(60)
+	mockOAuthConfig.On("IssuerURL").Return("https://id.example.com")
+	mockOAuthConfig.On("DeviceCodeTTL").Return(600)
+	mockOAuthConfig.On("DevicePollInterval").Return(5)
+
+	st.clientCfg = mockClientConfig
+	st.oauthCfg = mockOAuthConfig
+}
+
+func (st *oauthServiceSuite) newClient(data map[string]interface{}) client.Client {
+	cl, err := test.NewClient(st.clientCfg, data)
+	st.Require().NoError(err)
+
+	return cl
+}
+
+func (st *oauthServiceSuite) newAuthorizationRequest(clientID string) oauth.AuthorizationRequest {
+	return oauth.AuthorizationRequest{
+		ResponseType:        oauth.ResponseTypeCode,
+		ClientID:            clientID,
+		RedirectURI:         test.ClientRedirectURI,
+		State:               test.RandString(8),
+		CodeChallenge:       oauth.NewCodeChallenge(codeVerifier),
+		CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+		Scope:               oauth.ScopeOpenID + " " + test.ClientScope,
+	}
+}
+
+func (st *oauthServiceSuite) TestValidateAuthorizationRequestSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, mockClientService, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	err := svc.ValidateAuthorizationRequest(context.Background(), st.newAuthorizationRequest(cl.Name))
+	st.Require().NoError(err)
+}
+
+func (st *oauthServiceSuite) TestValidateAuthorizationRequestFailure() {
+	cl := st.newClient(map[string]interface{}{})
+	revoked := st.newClient(map[string]interface{}{test.ClientRevokedKey: true})
+
+	testCases := map[string]struct {
+		client       func() (client.Client, error)
+		request      func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest
+		expectedKind erx.Kind
+	}{
+		"test failure when client is not found": {
+			client:       func() (client.Client, error) { return client.Client{}, errors.New("client not found") },
+			expectedKind: oauth.InvalidClientError,
+		},
+		"test failure when client is revoked": {
+			client:       func() (client.Client, error) { return revoked, nil },
+			expectedKind: oauth.InvalidClientError,
+		},
+		"test failure when redirect uri is not registered": {
+			request: func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest {
+				req.RedirectURI = "https://evil.example.com/callback"
+				return req
+			},
+			expectedKind: oauth.InvalidRedirectURIError,
+		},
+		"test failure when response type is not code": {
+			request: func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest {
+				req.ResponseType = "token"
+				return req
+			},
+			expectedKind: oauth.UnsupportedResponseTypeError,
+		},
+		"test failure when code challenge method is plain": {
+			request: func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest {
+				req.CodeChallengeMethod = "plain"
+				return req
+			},
+			expectedKind: oauth.InvalidRequestError,
+		},
+		"test failure when code challenge is missing": {
+			request: func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest {
+				req.CodeChallenge = ""
+				return req
+			},
+			expectedKind: oauth.InvalidRequestError,
+		},
+		"test failure when scope is not allowed": {
+			request: func(req oauth.AuthorizationRequest) oauth.AuthorizationRequest {
+				req.Scope = "openid orders:write"
+				return req
+			},
+			expectedKind: oauth.InvalidScopeError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			getClient := func() (client.Client, error) { return cl, nil }
+			if testCase.client != nil {
+				getClient = testCase.client
+			}
+
+			res, err := getClient()
+
+			mockClientService := &client.MockService{}
+			mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(res, err)
+
+			req := st.newAuthorizationRequest(cl.Name)
+			if testCase.request != nil {
+				req = testCase.request(req)
+			}
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, mockClientService, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+			err = svc.ValidateAuthorizationRequest(context.Background(), req)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestAuthorizeSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, email, password := test.NewUUID(), test.NewEmail(), test.NewPassword()
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("CreateAuthorizationCode", mock.Anything, mock.AnythingOfType("AuthorizationCode")).Return(nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+	code, mfaToken, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, "", "")
+	st.Require().NoError(err)
+
+	st.Assert().Len(code, 43)
+	st.Assert().Empty(mfaToken)
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestAuthorizeSuccessWithSecondFactor() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, email, password, challenge := test.NewUUID(), test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("CreateAuthorizationCode", mock.Anything, mock.AnythingOfType("AuthorizationCode")).Return(nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+	mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, challenge, "123456").Return(userID, nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+	code, mfaToken, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, challenge, "123456")
+	st.Require().NoError(err)
+
+	st.Assert().Len(code, 43)
+	st.Assert().Empty(mfaToken)
+	mockStore.AssertExpectations(st.T())
+	mockMFAService.AssertExpectations(st.T())
+	mockMFAService.AssertNotCalled(st.T(), "Challenge", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (st *oauthServiceSuite) TestAuthorizeFailureWhenSecondFactorIsEnabled() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, email, password, challenge := test.NewUUID(), test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	testCases := map[string]struct {
+		mfaToken      string
+		mfaCode       string
+		expectedToken string
+		setup         func(mockMFAService *mfa.MockService)
+	}{
+		"test failure when no challenge was issued yet": {
+			expectedToken: challenge,
+			setup: func(mockMFAService *mfa.MockService) {
+				mockMFAService.On("Challenge", mock.Anything, cl.Id, userID).Return(challenge, nil)
+			},
+		},
+		"test failure when code is sent without a challenge": {
+			mfaCode:       "123456",
+			expectedToken: challenge,
+			setup: func(mockMFAService *mfa.MockService) {
+				mockMFAService.On("Challenge", mock.Anything, cl.Id, userID).Return(challenge, nil)
+			},
+		},
+		"test failure when no code is entered": {
+			mfaToken:      challenge,
+			expectedToken: challenge,
+			setup:         func(mockMFAService *mfa.MockService) {},
+		},
+		"test failure when code is invalid": {
+			mfaToken:      challenge,
+			mfaCode:       "000000",
+			expectedToken: challenge,
+			setup: func(mockMFAService *mfa.MockService) {
+				mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, challenge, "000000").
+					Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+			},
+		},
+		"test failure when challenge was issued to another user": {
+			mfaToken: challenge,
+			mfaCode:  "123456",
+			setup: func(mockMFAService *mfa.MockService) {
+				mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, challenge, "123456").Return(test.NewUUID(), nil)
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockClientService := &client.MockService{}
+			mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+			mockUserService := &user.MockService{}
+			mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+			mockMFAService := &mfa.MockService{}
+			mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+			testCase.setup(mockMFAService)
+
+			mockStore := &oauth.MockStore{}
+
+			svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+			_, mfaToken, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, testCase.mfaToken, testCase.mfaCode)
+			st.Require().Error(err)
+
+			st.Assert().Equal(oauth.MFARequiredError, err.(*erx.Erx).Kind())
+			st.Assert().Equal(testCase.expectedToken, mfaToken)
+			mockStore.AssertNotCalled(st.T(), "CreateAuthorizationCode", mock.Anything, mock.Anything)
+			mockMFAService.AssertNotCalled(st.T(), "VerifyChallenge", mock.Anything, mock.Anything, "", mock.Anything)
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestAuthorizeFailureWhenChallengeAttemptsAreExhausted() {
+	cl, encoder := st.newClient(map[string]interface{}{}), &password.MockEncoder{}
+	userID, email, password := test.NewUUID(), test.NewEmail(), test.NewPassword()
+	secret, maxAttempts := []byte("12345678901234567890"), 3
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+	mockMFAConfig := &config.MockMFAConfig{}
+	mockMFAConfig.On("ChallengeTTL").Return(300)
+	mockMFAConfig.On("ChallengeMaxAttempts").Return(maxAttempts)
+
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("SignedTokenSecret").Return(test.TokenSecret)
+
+	signer, err := token.NewSigner(mockTokenConfig)
+	st.Require().NoError(err)
+
+	mockMFAStore := &mfa.MockStore{}
+	mockMFAStore.On("IsEnabled", mock.Anything, userID, mock.AnythingOfType("string")).Return(true, nil)
+	mockMFAStore.On("CreateChallenge", mock.Anything, mock.AnythingOfType("string"), cl.Id, userID, mock.AnythingOfType("time.Time")).Return(nil)
+	mockMFAStore.On("AttemptChallenge", mock.Anything, mock.AnythingOfType("string"), cl.Id, maxAttempts, mock.AnythingOfType("time.Time")).
+		Return(userID, nil).Times(maxAttempts)
+	mockMFAStore.On("AttemptChallenge", mock.Anything, mock.AnythingOfType("string"), cl.Id, maxAttempts, mock.AnythingOfType("time.Time")).
+		Return("", erx.WithArgs(erx.ResourceNotFoundError, errors.New("no pending mfa challenge")))
+	mockMFAStore.On("GetTOTP", mock.Anything, userID).Return(mfa.NewTOTP(secret, true, 0), nil)
+
+	mfaService := mfa.NewService(mockMFAConfig, mockMFAStore, signer, encoder)
+
+	mockStore := &oauth.MockStore{}
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mfaService, &token.MockGenerator{})
+
+	_, mfaToken, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, "", "")
+	st.Require().Error(err)
+	st.Require().NotEmpty(mfaToken)
+
+	for i := 0; i < maxAttempts; i++ {
+		_, next, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, mfaToken, "000000")
+		st.Require().Error(err)
+
+		st.Assert().Equal(oauth.MFARequiredError, err.(*erx.Erx).Kind())
+		st.Assert().Equal(mfaToken, next)
+	}
+
+	code, err := mfa.TOTPCode(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret), time.Now())
+	st.Require().NoError(err)
+
+	_, _, err = svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, mfaToken, code)
+	st.Require().Error(err)
+
+	st.Assert().Equal(oauth.MFARequiredError, err.(*erx.Erx).Kind())
+	mockStore.AssertNotCalled(st.T(), "CreateAuthorizationCode", mock.Anything, mock.Anything)
+	mockMFAStore.AssertNumberOfCalls(st.T(), "CreateChallenge", 1)
+}
+
+func (st *oauthServiceSuite) TestAuthorizeFailureWhenCredentialsAreInvalid() {
+	cl := st.newClient(map[string]interface{}{})
+	email, password := test.NewEmail(), test.NewPassword()
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).
+		Return("", erx.WithArgs(erx.InvalidCredentialsError, errors.New("invalid credentials")))
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, mockClientService, mockUserService, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	_, _, err := svc.Authorize(context.Background(), st.newAuthorizationRequest(cl.Name), email, password, "", "")
+	st.Require().Error(err)
+
+	st.Assert().Equal(erx.InvalidCredentialsError, err.(*erx.Erx).Kind())
+}
+
+func (st *oauthServiceSuite) TestAuthenticateClient() {
+	secret := test.NewUUID()
+	cl := st.newClient(map[string]interface{}{test.ClientSecretKey: secret})
+	public := st.newClient(map[string]interface{}{test.ClientTypeKey: client.TypePublic})
+	revoked := st.newClient(map[string]interface{}{test.ClientSecretKey: secret, test.ClientRevokedKey: true})
+
+	testCases := map[string]struct {
+		client   client.Client
+		secret   string
+		hasError bool
+	}{
+		"test confidential client is authenticated with its secret": {
+			client: cl,
+			secret: secret,
+		},
+		"test public client is identified by its name": {
+			client: public,
+		},
+		"test failure when confidential client does not present a secret": {
+			client:   cl,
+			hasError: true,
+		},
+		"test failure when secret is invalid": {
+			client:   cl,
+			secret:   "invalid",
+			hasError: true,
+		},
+		"test failure when public client presents a secret": {
+			client:   public,
+			secret:   secret,
+			hasError: true,
+		},
+		"test failure when client is revoked": {
+			client:   revoked,
+			secret:   secret,
+			hasError: true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockClientService := &client.MockService{}
+			mockClientService.On("GetClientByName", mock.Anything, testCase.client.Name).Return(testCase.client, nil)
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, mockClientService, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+			_, err := svc.AuthenticateClient(context.Background(), testCase.client.Name, testCase.secret)
+			if !testCase.hasError {
+				st.Require().NoError(err)
+				return
+			}
+
+			st.Require().Error(err)
+			st.Assert().Equal(oauth.InvalidClientError, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestExchangeAuthorizationCodeSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+	code, userID := test.RandString(43), test.NewUUID()
+	accessToken, refreshToken := test.NewPasetoToken(), test.NewRefreshToken()
+
+	ac := oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, oauth.NewCodeChallenge(codeVerifier), test.ClientScope, "", time.Now().UTC(), time.Now().UTC().Add(time.Minute))
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("ConsumeAuthorizationCode", mock.Anything, code).Return(ac, nil)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("StartSession", mock.Anything, userID, test.ClientScope).Return(accessToken, refreshToken, nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	tk, err := svc.ExchangeAuthorizationCode(ctx, code, test.ClientRedirectURI, codeVerifier)
+	st.Require().NoError(err)
+
+	expected := oauth.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    oauth.TokenTypeBearer,
+		ExpiresIn:    cl.AccessTokenTTL() * 60,
+		Scope:        test.ClientScope,
+	}
+
+	st.Assert().Equal(expected, tk)
+}
+
+func (st *oauthServiceSuite) TestExchangeAuthorizationCodeSuccessWithIDToken() {
+	cl := st.newClient(map[string]interface{}{})
+	code, userID, nonce := test.RandString(43), test.NewUUID(), test.RandString(12)
+	accessToken, refreshToken, idToken := test.NewPasetoToken(), test.NewRefreshToken(), test.RandString(64)
+	authTime := time.Now().UTC().Add(-time.Second)
+
+	ac := oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, oauth.NewCodeChallenge(codeVerifier), "openid email", nonce, authTime, time.Now().UTC().Add(time.Minute))
+
+	u := st.newUser(userID)
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("ConsumeAuthorizationCode", mock.Anything, code).Return(ac, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(u, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateIDToken", cl.AccessTokenTTL(), cl.SigningKey(), token.IDClaims{
+		Issuer:   "https://id.example.com",
+		Subject:  userID,
+		Audience: cl.Name,
+		AuthTime: authTime.Unix(),
+		Nonce:    nonce,
+		Email:    u.Email(),
+		Name:     u.Name(),
+	}).Return(idToken, nil)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("StartSession", mock.Anything, userID, mock.AnythingOfType("string")).Return(accessToken, refreshToken, nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, mockUserService, mockSessionService, &mfa.MockService{}, mockGenerator)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	tk, err := svc.ExchangeAuthorizationCode(ctx, code, test.ClientRedirectURI, codeVerifier)
+	st.Require().NoError(err)
+
+	st.Assert().Equal(idToken, tk.IDToken)
+	st.Assert().Equal(accessToken, tk.AccessToken)
+}
+
+func (st *oauthServiceSuite) TestExchangeAuthorizationCodeFailureWhenUserIsNotFound() {
+	cl := st.newClient(map[string]interface{}{})
+	code, userID := test.RandString(43), test.NewUUID()
+
+	ac := oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, oauth.NewCodeChallenge(codeVerifier), "openid", "", time.Now().UTC(), time.Now().UTC().Add(time.Minute))
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("ConsumeAuthorizationCode", mock.Anything, code).Return(ac, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).
+		Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("user not found")))
+
+	mockSessionService := &session.MockService{}
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, mockUserService, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, err = svc.ExchangeAuthorizationCode(ctx, code, test.ClientRedirectURI, codeVerifier)
+	st.Require().Error(err)
+
+	mockSessionService.AssertNotCalled(st.T(), "StartSession", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (st *oauthServiceSuite) TestExchangeAuthorizationCodeFailure() {
+	cl := st.newClient(map[string]interface{}{})
+	code, userID := test.RandString(43), test.NewUUID()
+	challenge := oauth.NewCodeChallenge(codeVerifier)
+	authTime, expiresAt := time.Now().UTC(), time.Now().UTC().Add(time.Minute)
+
+	testCases := map[string]struct {
+		code         oauth.AuthorizationCode
+		codeErr      error
+		redirectURI  string
+		codeVerifier string
+		expectedKind erx.Kind
+	}{
+		"test failure when code is not found or already used": {
+			codeErr:      erx.WithArgs(erx.ResourceNotFoundError, errors.New("not found")),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when code was issued to another client": {
+			code:         oauth.NewAuthorizationCode(code, test.NewUUID(), userID, test.ClientRedirectURI, challenge, "", "", authTime, expiresAt),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when code has expired": {
+			code:         oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, challenge, "", "", authTime, time.Now().UTC().Add(-time.Second)),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when redirect uri does not match": {
+			code:         oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, challenge, "", "", authTime, expiresAt),
+			redirectURI:  test.ClientRedirectURI + "/other",
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when code verifier does not match": {
+			code:         oauth.NewAuthorizationCode(code, cl.Id, userID, test.ClientRedirectURI, challenge, "", "", authTime, expiresAt),
+			codeVerifier: test.RandString(43),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when code verifier is malformed": {
+			codeVerifier: "short",
+			expectedKind: oauth.InvalidRequestError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockStore := &oauth.MockStore{}
+			mockStore.On("ConsumeAuthorizationCode", mock.Anything, code).Return(testCase.code, testCase.codeErr)
+
+			redirectURI, verifier := test.ClientRedirectURI, codeVerifier
+			if len(testCase.redirectURI) != 0 {
+				redirectURI = testCase.redirectURI
+			}
+
+			if len(testCase.codeVerifier) != 0 {
+				verifier = testCase.codeVerifier
+			}
+
+			svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			_, err = svc.ExchangeAuthorizationCode(ctx, code, redirectURI, verifier)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestExchangeAuthorizationCodeFailureWhenFailedToGetClientFromContext() {
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	_, err := svc.ExchangeAuthorizationCode(context.Background(), test.RandString(43), test.ClientRedirectURI, codeVerifier)
+	st.Require().Error(err)
+}
+
+func (st *oauthServiceSuite) TestRefreshTokenSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+	refreshToken, nextRefreshToken, accessToken := test.NewRefreshToken(), test.NewRefreshToken(), test.NewPasetoToken()
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("RefreshToken", mock.Anything, refreshToken).Return(accessToken, nextRefreshToken, nil)
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	tk, err := svc.RefreshToken(ctx, refreshToken)
+	st.Require().NoError(err)
+
+	st.Assert().Equal(accessToken, tk.AccessToken)
+	st.Assert().Equal(nextRefreshToken, tk.RefreshToken)
+}
+
+func (st *oauthServiceSuite) TestRefreshTokenFailureWhenSessionIsInvalid() {
+	cl := st.newClient(map[string]interface{}{})
+	refreshToken := test.NewRefreshToken()
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("RefreshToken", mock.Anything, refreshToken).
+		Return("", "", erx.WithArgs(erx.AuthenticationError, errors.New("session expired")))
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, err = svc.RefreshToken(ctx, refreshToken)
+	st.Require().Error(err)
+
+	st.Assert().Equal(oauth.InvalidGrantError, err.(*erx.Erx).Kind())
+}
+
+func (st *oauthServiceSuite) TestClientCredentialsSuccess() {
+	cl := st.newClient(map[string]interface{}{test.ClientAllowedScopesKey: []string{"orders:read", "orders:write"}})
+	accessToken := test.NewPasetoToken()
+
+	testCases := map[string]struct {
+		scopes        []string
+		expectedScope string
+	}{
+		"test client is granted the requested scopes": {
+			scopes:        []string{"orders:read"},
+			expectedScope: "orders:read",
+		},
+		"test client is granted every allowed scope when none is requested": {
+			expectedScope: "orders:read orders:write",
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockGenerator := &token.MockGenerator{}
+			mockGenerator.On(
+				"GenerateAccessToken",
+				cl.AccessTokenTTL(),
+				cl.Id,
+				cl.SigningKey(),
+				map[string]string{"scope": testCase.expectedScope, "tenant_id": cl.TenantID},
+			).Return(accessToken, token.Claims{}, nil)
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, mockGenerator)
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			tk, err := svc.ClientCredentials(ctx, testCase.scopes)
+			st.Require().NoError(err)
+
+			expected := oauth.Token{
+				AccessToken: accessToken,
+				TokenType:   oauth.TokenTypeBearer,
+				ExpiresIn:   cl.AccessTokenTTL() * 60,
+				Scope:       testCase.expectedScope,
+			}
+
+			st.Assert().Equal(expected, tk)
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestClientCredentialsFailure() {
+	cl := st.newClient(map[string]interface{}{})
+
+	testCases := map[string]struct {
+		ctx       func() context.Context
+		generator func() token.Generator
+	}{
+		"test failure when client is not in context": {
+			ctx:       func() context.Context { return context.Background() },
+			generator: func() token.Generator { return &token.MockGenerator{} },
+		},
+		"test failure when token generation fails": {
+			ctx: func() context.Context {
+				ctx, err := client.WithContext(context.Background(), cl)
+				st.Require().NoError(err)
+				return ctx
+			},
+			generator: func() token.Generator {
+				mockGenerator := &token.MockGenerator{}
+				mockGenerator.On("GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return("", token.Claims{}, errors.New("failed to generate token"))
+				return mockGenerator
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, testCase.generator())
+
+			_, err := svc.ClientCredentials(testCase.ctx(), nil)
+			st.Require().Error(err)
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestClientCredentialsFailureWhenScopeIsNotAllowed() {
+	cl := st.newClient(map[string]interface{}{})
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, err = svc.ClientCredentials(ctx, []string{test.ClientScope, "orders:write"})
+	st.Require().Error(err)
+
+	st.Assert().Equal(oauth.InvalidScopeError, err.(*erx.Erx).Kind())
+}
+
+func (st *oauthServiceSuite) TestUserInfoSuccess() {
+	userID, accessToken := test.NewUUID(), test.NewPasetoToken()
+	u := st.newUser(userID)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("IntrospectToken", mock.Anything, accessToken).
+		Return(session.Introspection{Active: true, Claims: token.Claims{Subject: userID}}, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(u, nil)
+
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, mockUserService, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	res, err := svc.UserInfo(context.Background(), accessToken)
+	st.Require().NoError(err)
+
+	st.Assert().Equal(oauth.UserInfo{Subject: userID, Email: u.Email(), Name: u.Name()}, res)
+}
+
+func (st *oauthServiceSuite) TestUserInfoFailure() {
+	userID, accessToken := test.NewUUID(), test.NewPasetoToken()
+
+	testCases := map[string]struct {
+		introspection session.Introspection
+		introspectErr error
+		userErr       error
+		expectedKind  erx.Kind
+	}{
+		"test failure when token is not active": {
+			expectedKind: oauth.InvalidTokenError,
+		},
+		"test failure when subject is not a user": {
+			introspection: session.Introspection{Active: true, Claims: token.Claims{Subject: userID}},
+			userErr:       erx.WithArgs(erx.ResourceNotFoundError, errors.New("user not found")),
+			expectedKind:  oauth.InvalidTokenError,
+		},
+		"test failure when introspection fails": {
+			introspectErr: errors.New("failed to get key"),
+		},
+		"test failure when user lookup fails": {
+			introspection: session.Introspection{Active: true, Claims: token.Claims{Subject: userID}},
+			userErr:       errors.New("failed to get user"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockSessionService := &session.MockService{}
+			mockSessionService.On("IntrospectToken", mock.Anything, accessToken).Return(testCase.introspection, testCase.introspectErr)
+
+			mockUserService := &user.MockService{}
+			mockUserService.On("GetUser", mock.Anything, userID).Return(user.User{}, testCase.userErr)
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, mockUserService, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+			_, err := svc.UserInfo(context.Background(), accessToken)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestAuthorizeDeviceSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("CreateDeviceCode", mock.Anything, mock.AnythingOfType("DeviceCode")).Return(nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	da, err := svc.AuthorizeDevice(ctx, "openid "+test.ClientScope)
+	st.Require().NoError(err)
+
+	st.Assert().Len(da.DeviceCode, 43)
+	st.Assert().Regexp(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`, da.UserCode)
+	st.Assert().Equal("https://id.example.com/oauth/device", da.VerificationURI)
+	st.Assert().Equal("https://id.example.com/oauth/device?user_code="+da.UserCode, da.VerificationURIComplete)
+	st.Assert().Equal(600, da.ExpiresIn)
+	st.Assert().Equal(5, da.Interval)
+
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestAuthorizeDeviceFailureWhenScopeIsNotAllowed() {
+	mockStore := &oauth.MockStore{}
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), st.newClient(map[string]interface{}{}))
+	st.Require().NoError(err)
+
+	_, err = svc.AuthorizeDevice(ctx, "orders:write")
+	st.Require().Error(err)
+
+	st.Assert().Equal(oauth.InvalidScopeError, err.(*erx.Erx).Kind())
+	mockStore.AssertNotCalled(st.T(), "CreateDeviceCode", mock.Anything, mock.Anything)
+}
+
+func (st *oauthServiceSuite) TestAuthorizeDeviceFailureWhenFailedToGetClientFromContext() {
+	svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	_, err := svc.AuthorizeDevice(context.Background(), "")
+	st.Require().Error(err)
+}
+
+func (st *oauthServiceSuite) TestVerifyDeviceSuccess() {
+	cl := st.newClient(map[string]interface{}{test.ClientTenantIDKey: test.NewUUID()})
+	userID, email, password := test.NewUUID(), test.NewEmail(), test.NewPassword()
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(false, nil)
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("GetDeviceCodeClient", mock.Anything, "BCDFGHJK", mock.AnythingOfType("time.Time")).Return(cl.Name, nil)
+	mockStore.On("ResolveDeviceCode", mock.Anything, "BCDFGHJK", userID, true, mock.AnythingOfType("time.Time")).Return(nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+	mfaToken, err := svc.VerifyDevice(context.Background(), "bcdf-ghjk", email, password, "", "", true)
+	st.Require().NoError(err)
+
+	st.Assert().Empty(mfaToken)
+
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestVerifyDeviceSuccessWithSecondFactor() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, email, password, challenge := test.NewUUID(), test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, userID).Return(true, nil)
+	mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, challenge, "123456").Return(userID, nil)
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("GetDeviceCodeClient", mock.Anything, "BCDFGHJK", mock.AnythingOfType("time.Time")).Return(cl.Name, nil)
+	mockStore.On("ResolveDeviceCode", mock.Anything, "BCDFGHJK", userID, true, mock.AnythingOfType("time.Time")).Return(nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+	_, err := svc.VerifyDevice(context.Background(), "BCDF-GHJK", email, password, challenge, "123456", true)
+	st.Require().NoError(err)
+
+	mockStore.AssertExpectations(st.T())
+	mockMFAService.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestVerifyDeviceFailure() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, email, password, challenge := test.NewUUID(), test.NewEmail(), test.NewPassword(), test.RandString(32)
+
+	testCases := map[string]struct {
+		clientErr    error
+		userErr      error
+		storeErr     error
+		mfaEnabled   bool
+		mfaToken     string
+		mfaCode      string
+		expectedKind erx.Kind
+	}{
+		"test failure when user code is not found before verifying credentials": {
+			clientErr:    erx.WithArgs(erx.ResourceNotFoundError, errors.New("not found")),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when credentials are invalid": {
+			userErr:      erx.WithArgs(erx.InvalidCredentialsError, errors.New("invalid credentials")),
+			expectedKind: erx.InvalidCredentialsError,
+		},
+		"test failure when second factor is enabled and no code is entered": {
+			mfaEnabled:   true,
+			expectedKind: oauth.MFARequiredError,
+		},
+		"test failure when second factor code is invalid": {
+			mfaEnabled:   true,
+			mfaToken:     challenge,
+			mfaCode:      "000000",
+			expectedKind: oauth.MFARequiredError,
+		},
+		"test failure when user code is not found or expired": {
+			storeErr:     erx.WithArgs(erx.ResourceNotFoundError, errors.New("not found")),
+			expectedKind: oauth.InvalidGrantError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockClientService := &client.MockService{}
+			mockClientService.On("GetClientByName", mock.Anything, cl.Name).Return(cl, nil)
+
+			mockUserService := &user.MockService{}
+			mockUserService.On("GetUserID", mock.Anything, cl.TenantID, email, password).Return(userID, testCase.userErr)
+
+			mockMFAService := &mfa.MockService{}
+			mockMFAService.On("IsEnabled", mock.Anything, userID).Return(testCase.mfaEnabled, nil)
+			mockMFAService.On("Challenge", mock.Anything, cl.Id, userID).Return(challenge, nil)
+			mockMFAService.On("VerifyChallenge", mock.Anything, cl.Id, challenge, "000000").
+				Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+
+			mockStore := &oauth.MockStore{}
+			mockStore.On("GetDeviceCodeClient", mock.Anything, "BCDFGHJK", mock.AnythingOfType("time.Time")).Return(cl.Name, testCase.clientErr)
+			mockStore.On("ResolveDeviceCode", mock.Anything, "BCDFGHJK", userID, false, mock.AnythingOfType("time.Time")).Return(testCase.storeErr)
+
+			svc := oauth.NewService(st.oauthCfg, mockStore, mockClientService, mockUserService, &session.MockService{}, mockMFAService, &token.MockGenerator{})
+
+			mfaToken, err := svc.VerifyDevice(context.Background(), "BCDF-GHJK", email, password, testCase.mfaToken, testCase.mfaCode, false)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+
+			if testCase.expectedKind == oauth.MFARequiredError {
+				st.Assert().Equal(challenge, mfaToken)
+				mockStore.AssertNotCalled(st.T(), "ResolveDeviceCode", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestDeviceTokenSuccess() {
+	cl := st.newClient(map[string]interface{}{})
+	deviceCode, userID := test.RandString(43), test.NewUUID()
+	accessToken, refreshToken := test.NewPasetoToken(), test.NewRefreshToken()
+	now := time.Now().UTC()
+
+	dc := oauth.NewDeviceCode(deviceCode, "", cl.Id, userID, "", oauth.DeviceCodeStatusApproved, 5, now.Add(-time.Minute), now, now.Add(time.Minute))
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("PollDeviceCode", mock.Anything, deviceCode, mock.AnythingOfType("time.Time")).Return(dc, nil)
+	mockStore.On("ConsumeDeviceCode", mock.Anything, deviceCode).Return(nil)
+
+	mockSessionService := &session.MockService{}
+	mockSessionService.On("StartSession", mock.Anything, userID, mock.AnythingOfType("string")).Return(accessToken, refreshToken, nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	tk, err := svc.DeviceToken(ctx, deviceCode)
+	st.Require().NoError(err)
+
+	expected := oauth.Token{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    oauth.TokenTypeBearer,
+		ExpiresIn:    cl.AccessTokenTTL() * 60,
+	}
+
+	st.Assert().Equal(expected, tk)
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestDeviceTokenFailure() {
+	cl := st.newClient(map[string]interface{}{})
+	deviceCode, userID := test.RandString(43), test.NewUUID()
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Minute)
+
+	newDeviceCode := func(clientID, status string, lastPolledAt, expiresAt time.Time) oauth.DeviceCode {
+		return oauth.NewDeviceCode(deviceCode, "", clientID, userID, "", status, 5, lastPolledAt, now, expiresAt)
+	}
+
+	testCases := map[string]struct {
+		code         oauth.DeviceCode
+		codeErr      error
+		consumeErr   error
+		expectedKind erx.Kind
+	}{
+		"test failure when device code is not found": {
+			codeErr:      erx.WithArgs(erx.ResourceNotFoundError, errors.New("not found")),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when device code was issued to another client": {
+			code:         newDeviceCode(test.NewUUID(), oauth.DeviceCodeStatusApproved, time.Time{}, expiresAt),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when device code has expired": {
+			code:         newDeviceCode(cl.Id, oauth.DeviceCodeStatusPending, time.Time{}, now.Add(-time.Second)),
+			expectedKind: oauth.ExpiredTokenError,
+		},
+		"test failure when user has not approved the device yet": {
+			code:         newDeviceCode(cl.Id, oauth.DeviceCodeStatusPending, now.Add(-time.Minute), expiresAt),
+			expectedKind: oauth.AuthorizationPendingError,
+		},
+		"test failure when user denied the device": {
+			code:         newDeviceCode(cl.Id, oauth.DeviceCodeStatusDenied, time.Time{}, expiresAt),
+			expectedKind: oauth.AccessDeniedError,
+		},
+		"test failure when device code was already used": {
+			code:         newDeviceCode(cl.Id, oauth.DeviceCodeStatusConsumed, time.Time{}, expiresAt),
+			expectedKind: oauth.InvalidGrantError,
+		},
+		"test failure when device code is consumed by a concurrent poll": {
+			code:         newDeviceCode(cl.Id, oauth.DeviceCodeStatusApproved, time.Time{}, expiresAt),
+			consumeErr:   erx.WithArgs(erx.ResourceNotFoundError, errors.New("not found")),
+			expectedKind: oauth.InvalidGrantError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockStore := &oauth.MockStore{}
+			mockStore.On("PollDeviceCode", mock.Anything, deviceCode, mock.AnythingOfType("time.Time")).Return(testCase.code, testCase.codeErr)
+			mockStore.On("ConsumeDeviceCode", mock.Anything, deviceCode).Return(testCase.consumeErr)
+
+			svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			_, err = svc.DeviceToken(ctx, deviceCode)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestDeviceTokenFailureWhenPolledTooSoon() {
+	cl := st.newClient(map[string]interface{}{})
+	deviceCode, now := test.RandString(43), time.Now().UTC()
+
+	dc := oauth.NewDeviceCode(deviceCode, "", cl.Id, "", "", oauth.DeviceCodeStatusPending, 5, now.Add(-time.Second), time.Time{}, now.Add(time.Minute))
+
+	mockStore := &oauth.MockStore{}
+	mockStore.On("PollDeviceCode", mock.Anything, deviceCode, mock.AnythingOfType("time.Time")).Return(dc, nil)
+	mockStore.On("SlowDownDeviceCode", mock.Anything, deviceCode).Return(nil)
+
+	svc := oauth.NewService(st.oauthCfg, mockStore, &client.MockService{}, &user.MockService{}, &session.MockService{}, &mfa.MockService{}, &token.MockGenerator{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, err = svc.DeviceToken(ctx, deviceCode)
+	st.Require().Error(err)
+
+	st.Assert().Equal(oauth.SlowDownError, err.(*erx.Erx).Kind())
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *oauthServiceSuite) TestExchangeTokenSuccess() {
+	cl := st.newClient(map[string]interface{}{test.ClientAllowedScopesKey: []string{"orders:read", "orders:write", "admin"}})
+	userID, sessionID := test.NewUUID(), test.NewUUID()
+	subjectToken, accessToken := test.NewPasetoToken(), test.NewPasetoToken()
+
+	testCases := map[string]struct {
+		subjectClaims  map[string]string
+		scopes         []string
+		expectedScope  string
+		expectedClaims map[string]string
+	}{
+		"test exchanged token is granted the requested scopes": {
+			subjectClaims: map[string]string{"session_id": sessionID, "scope": "orders:read orders:write"},
+			scopes:        []string{"orders:read"},
+			expectedScope: "orders:read",
+		},
+		"test exchanged token is granted no scope when the subject token has none": {
+			subjectClaims: map[string]string{"session_id": sessionID, "roles": "admin", "permissions": "orders:read"},
+			expectedScope: "",
+		},
+		"test exchanged token inherits the allowed scopes of the subject token when none is requested": {
+			subjectClaims: map[string]string{"session_id": sessionID, "scope": "orders:write payments:read"},
+			expectedScope: "orders:write",
+		},
+		"test exchanged token carries the roles and permissions named by the requested scopes": {
+			subjectClaims:  map[string]string{"session_id": sessionID, "scope": "orders:read orders:write admin", "roles": "admin support", "permissions": "orders:read orders:write"},
+			scopes:         []string{"orders:read", "admin"},
+			expectedScope:  "orders:read admin",
+			expectedClaims: map[string]string{"roles": "admin", "permissions": "orders:read"},
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockSessionService := &session.MockService{}
+			mockSessionService.On("IntrospectToken", mock.Anything, subjectToken).
+				Return(session.Introspection{Active: true, TenantID: cl.TenantID, Claims: st.newClaims(userID, testCase.subjectClaims)}, nil)
+
+			expectedClaims := map[string]string{"aud": test.ClientAudience, "act": cl.Id, "scope": testCase.expectedScope, "session_id": sessionID, "tenant_id": cl.TenantID}
+			for name, value := range testCase.expectedClaims {
+				expectedClaims[name] = value
+			}
+
+			mockGenerator := &token.MockGenerator{}
+			mockGenerator.On("GenerateAccessToken", cl.AccessTokenTTL(), userID, cl.SigningKey(), expectedClaims).Return(accessToken, token.Claims{}, nil)
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, mockGenerator)
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			tk, err := svc.ExchangeToken(ctx, subjectToken, oauth.TokenTypeAccessToken, test.ClientAudience, testCase.scopes)
+			st.Require().NoError(err)
+
+			expected := oauth.Token{
+				AccessToken:     accessToken,
+				TokenType:       oauth.TokenTypeBearer,
+				ExpiresIn:       cl.AccessTokenTTL() * 60,
+				Scope:           testCase.expectedScope,
+				IssuedTokenType: oauth.TokenTypeAccessToken,
+			}
+
+			st.Assert().Equal(expected, tk)
+		})
+	}
+}
+
+func (st *oauthServiceSuite) TestExchangeTokenFailure() {
+	cl := st.newClient(map[string]interface{}{})
+	userID, subjectToken := test.NewUUID(), test.NewPasetoToken()
+
+	testCases := map[string]struct {
+		subjectTokenType string
+		audience         string
+		scopes           []string
+		introspection    session.Introspection
+		introspectionErr error
+		expectedKind     erx.Kind
+	}{
+		"test failure when subject token type is not supported": {
+			subjectTokenType: "urn:ietf:params:oauth:token-type:id_token",
+			audience:         test.ClientAudience,
+			expectedKind:     oauth.InvalidRequestError,
+		},
+		"test failure when audience is not allowed": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         "inventory",
+			expectedKind:     oauth.InvalidTargetError,
+		},
+		"test failure when subject token is not active": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         test.ClientAudience,
+			introspection:    session.Introspection{},
+			expectedKind:     oauth.InvalidRequestError,
+		},
+		"test failure when scope is not allowed for the client": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         test.ClientAudience,
+			scopes:           []string{"orders:write"},
+			introspection:    session.Introspection{Active: true, TenantID: cl.TenantID, Claims: st.newClaims(userID, nil)},
+			expectedKind:     oauth.InvalidScopeError,
+		},
+		"test failure when scope is broader than the subject token": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         test.ClientAudience,
+			scopes:           []string{test.ClientScope},
+			introspection:    session.Introspection{Active: true, TenantID: cl.TenantID, Claims: st.newClaims(userID, map[string]string{"scope": "payments:read"})},
+			expectedKind:     oauth.InvalidScopeError,
+		},
+		"test failure when scope is requested for a subject token without one": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         test.ClientAudience,
+			scopes:           []string{test.ClientScope},
+			introspection:    session.Introspection{Active: true, TenantID: cl.TenantID, Claims: st.newClaims(userID, nil)},
+			expectedKind:     oauth.InvalidScopeError,
+		},
+		"test failure when subject token belongs to another tenant": {
+			subjectTokenType: oauth.TokenTypeAccessToken,
+			audience:         test.ClientAudience,
+			introspection:    session.Introspection{Active: true, TenantID: test.NewUUID(), Claims: st.newClaims(userID, nil)},
+			expectedKind:     erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockSessionService := &session.MockService{}
+			mockSessionService.On("IntrospectToken", mock.Anything, subjectToken).Return(testCase.introspection, testCase.introspectionErr)
+
+			svc := oauth.NewService(st.oauthCfg, &oauth.MockStore{}, &client.MockService{}, &user.MockService{}, mockSessionService, &mfa.MockService{}, &token.MockGenerator{})
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			_, err = svc.ExchangeToken(ctx, subjectToken, testCase.subjectTokenType, testCase.audience, testCase.scopes)
+			st.Require().Error(err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func (st *oauthServiceSuite) newClaims(subject string, claims map[string]string) token.Claims {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("user")
+	mockTokenConfig.On("Issuer").Return("identification-service")
+
+	_, c, err := token.NewGenerator(mockTokenConfig).
+		GenerateAccessToken(10, subject, libcrypto.Key{ID: test.NewUUID(), PrivateKey: test.ClientPriKey()}, claims)
+
+	st.Require().NoError(err)
+
+	return c
+}
+
+func (st *oauthServiceSuite) newUser(userID string) user.User {
+	u, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(test.NewEmail()).Build()
+	st.Require().NoError(err)
+
+	return u
+}
+
+func TestOAuthService(t *testing.T) {
+	suite.Run(t, new(oauthServiceSuite))
+}
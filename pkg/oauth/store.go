@@ -0,0 +1,227 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/database"
+	"identification-service/pkg/token"
+	"time"
+)
+
+const (
+	createAuthorizationCode  = `insert into authorization_codes (code, client_id, user_id, redirect_uri, code_challenge, scope, nonce, auth_time, expires_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	consumeAuthorizationCode = `update authorization_codes set used=true where code=$1 and used=false returning client_id, user_id, redirect_uri, code_challenge, scope, nonce, auth_time, expires_at`
+
+	createDeviceCode    = `insert into device_codes (device_code, user_code, client_id, scope, poll_interval, expires_at) values ($1, $2, $3, $4, $5, $6)`
+	getDeviceCodeClient = `select c.name from device_codes d join clients c on c.id = d.client_id where d.user_code=$1 and d.status='pending' and d.expires_at > $2`
+	resolveDeviceCode   = `update device_codes set status=$2, user_id=$3, auth_time=$4 where user_code=$1 and status='pending' and expires_at > $4`
+	pollDeviceCode      = `update device_codes d set last_polled_at=$2 from device_codes p where d.device_code=$1 and p.device_code=d.device_code returning d.client_id, coalesce(d.user_id::text, ''), d.scope, d.status, d.poll_interval, p.last_polled_at, d.auth_time, d.expires_at`
+	slowDownDeviceCode  = `update device_codes set poll_interval=poll_interval+$2 where device_code=$1`
+	consumeDeviceCode   = `update device_codes set status='consumed' where device_code=$1 and status='approved'`
+)
+
+type Store interface {
+	CreateAuthorizationCode(ctx context.Context, code AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, code string) (AuthorizationCode, error)
+
+	CreateDeviceCode(ctx context.Context, code DeviceCode) error
+	GetDeviceCodeClient(ctx context.Context, userCode string, now time.Time) (string, error)
+	ResolveDeviceCode(ctx context.Context, userCode, userID string, approved bool, authTime time.Time) error
+	PollDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time) (DeviceCode, error)
+	SlowDownDeviceCode(ctx context.Context, deviceCode string) error
+	ConsumeDeviceCode(ctx context.Context, deviceCode string) error
+}
+
+type oauthStore struct {
+	db     database.SQLDatabase
+	hasher token.Hasher
+}
+
+func (st *oauthStore) CreateAuthorizationCode(ctx context.Context, code AuthorizationCode) error {
+	_, err := st.db.ExecContext(
+		ctx,
+		createAuthorizationCode,
+		st.hasher.Hash(code.code),
+		code.clientID,
+		code.userID,
+		code.redirectURI,
+		code.codeChallenge,
+		code.scope,
+		code.nonce,
+		code.authTime,
+		code.expiresAt,
+	)
+
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreateAuthorizationCode"), err)
+	}
+
+	return nil
+}
+
+func (st *oauthStore) ConsumeAuthorizationCode(ctx context.Context, code string) (AuthorizationCode, error) {
+	//NOTE: THE CODE IS MARKED USED IN THE SAME STATEMENT THAT READS IT, SO IT CAN BE EXCHANGED ONLY ONCE
+	ac := AuthorizationCode{code: code}
+
+	err := st.db.QueryRowContext(ctx, consumeAuthorizationCode, st.hasher.Hash(code)).Scan(
+		&ac.clientID,
+		&ac.userID,
+		&ac.redirectURI,
+		&ac.codeChallenge,
+		&ac.scope,
+		&ac.nonce,
+		&ac.authTime,
+		&ac.expiresAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AuthorizationCode{}, erx.WithArgs(
+				erx.Operation("Store.ConsumeAuthorizationCode"),
+				erx.ResourceNotFoundError,
+				errors.New("authorization code not found or already used"),
+			)
+		}
+
+		return AuthorizationCode{}, erx.WithArgs(erx.Operation("Store.ConsumeAuthorizationCode"), err)
+	}
+
+	return ac, nil
+}
+
+func (st *oauthStore) CreateDeviceCode(ctx context.Context, code DeviceCode) error {
+	_, err := st.db.ExecContext(
+		ctx,
+		createDeviceCode,
+		st.hasher.Hash(code.deviceCode),
+		st.hasher.Hash(code.userCode),
+		code.clientID,
+		code.scope,
+		code.interval,
+		code.expiresAt,
+	)
+
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreateDeviceCode"), err)
+	}
+
+	return nil
+}
+
+func (st *oauthStore) GetDeviceCodeClient(ctx context.Context, userCode string, now time.Time) (string, error) {
+	var clientName string
+
+	err := st.db.QueryRowContext(ctx, getDeviceCodeClient, st.hasher.Hash(userCode), now).Scan(&clientName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", erx.WithArgs(
+				erx.Operation("Store.GetDeviceCodeClient"),
+				erx.ResourceNotFoundError,
+				errors.New("user code not found or expired"),
+			)
+		}
+
+		return "", erx.WithArgs(erx.Operation("Store.GetDeviceCodeClient"), err)
+	}
+
+	return clientName, nil
+}
+
+func (st *oauthStore) ResolveDeviceCode(ctx context.Context, userCode, userID string, approved bool, authTime time.Time) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.ResolveDeviceCode"), err) }
+
+	status := DeviceCodeStatusDenied
+	if approved {
+		status = DeviceCodeStatusApproved
+	}
+
+	//NOTE: ONLY A PENDING AND UNEXPIRED CODE CAN BE RESOLVED, A CODE IS NEVER APPROVED TWICE
+	res, err := st.db.ExecContext(ctx, resolveDeviceCode, st.hasher.Hash(userCode), status, userID, authTime)
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, errors.New("user code not found or expired")))
+	}
+
+	return nil
+}
+
+func (st *oauthStore) PollDeviceCode(ctx context.Context, deviceCode string, polledAt time.Time) (DeviceCode, error) {
+	//NOTE: THE SELF JOIN READS THE PREVIOUS POLL TIME WHILE THE SAME STATEMENT RECORDS THE CURRENT ONE
+	dc := DeviceCode{deviceCode: deviceCode}
+
+	var lastPolledAt, authTime sql.NullTime
+
+	err := st.db.QueryRowContext(ctx, pollDeviceCode, st.hasher.Hash(deviceCode), polledAt).Scan(
+		&dc.clientID,
+		&dc.userID,
+		&dc.scope,
+		&dc.status,
+		&dc.interval,
+		&lastPolledAt,
+		&authTime,
+		&dc.expiresAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return DeviceCode{}, erx.WithArgs(
+				erx.Operation("Store.PollDeviceCode"),
+				erx.ResourceNotFoundError,
+				errors.New("device code not found"),
+			)
+		}
+
+		return DeviceCode{}, erx.WithArgs(erx.Operation("Store.PollDeviceCode"), err)
+	}
+
+	dc.lastPolledAt = lastPolledAt.Time
+	dc.authTime = authTime.Time
+
+	return dc, nil
+}
+
+func (st *oauthStore) SlowDownDeviceCode(ctx context.Context, deviceCode string) error {
+	_, err := st.db.ExecContext(ctx, slowDownDeviceCode, st.hasher.Hash(deviceCode), slowDownIncrement)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.SlowDownDeviceCode"), err)
+	}
+
+	return nil
+}
+
+func (st *oauthStore) ConsumeDeviceCode(ctx context.Context, deviceCode string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.ConsumeDeviceCode"), err) }
+
+	res, err := st.db.ExecContext(ctx, consumeDeviceCode, st.hasher.Hash(deviceCode))
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, errors.New("device code not found or already used")))
+	}
+
+	return nil
+}
+
+func NewStore(db database.SQLDatabase, hasher token.Hasher) Store {
+	return &oauthStore{
+		db:     db,
+		hasher: hasher,
+	}
+}
@@ -0,0 +1,338 @@
+package oauth_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/config"
+	"identification-service/pkg/database"
+	"identification-service/pkg/oauth"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type oauthStoreSuite struct {
+	suite.Suite
+	mock   sqlmock.Sqlmock
+	hasher token.Hasher
+	store  oauth.Store
+}
+
+func (st *oauthStoreSuite) SetupSuite() {
+	sqlDB, mock, err := sqlmock.New()
+	st.Require().NoError(err)
+
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("RefreshTokenSecret").Return(test.TokenSecret)
+
+	st.mock = mock
+	hasher, err := token.NewHasher(mockTokenConfig)
+	st.Require().NoError(err)
+
+	st.hasher = hasher
+	st.store = oauth.NewStore(database.NewSQLDatabase(sqlDB, test.QueryTTL), st.hasher)
+}
+
+func (st *oauthStoreSuite) TestCreateAuthorizationCodeSuccess() {
+	code, clientID, userID, nonce := test.RandString(43), test.NewUUID(), test.NewUUID(), test.RandString(12)
+	authTime, expiresAt := time.Now().UTC(), time.Now().UTC().Add(time.Minute)
+
+	query := `insert into authorization_codes (code, client_id, user_id, redirect_uri, code_challenge, scope, nonce, auth_time, expires_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(code), clientID, userID, test.ClientRedirectURI, "challenge", "openid", nonce, authTime, expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	ac := oauth.NewAuthorizationCode(code, clientID, userID, test.ClientRedirectURI, "challenge", "openid", nonce, authTime, expiresAt)
+
+	err := st.store.CreateAuthorizationCode(context.Background(), ac)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestCreateAuthorizationCodeFailure() {
+	query := `insert into authorization_codes`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(errors.New("failed to create code"))
+
+	ac := oauth.NewAuthorizationCode(test.RandString(43), test.NewUUID(), test.NewUUID(), test.ClientRedirectURI, "challenge", "", "", time.Now(), time.Now())
+
+	err := st.store.CreateAuthorizationCode(context.Background(), ac)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestConsumeAuthorizationCodeSuccess() {
+	code, clientID, userID, nonce := test.RandString(43), test.NewUUID(), test.NewUUID(), test.RandString(12)
+	authTime, expiresAt := time.Now().UTC(), time.Now().UTC().Add(time.Minute)
+
+	query := `update authorization_codes set used=true where code=$1 and used=false returning client_id, user_id, redirect_uri, code_challenge, scope, nonce, auth_time, expires_at`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(code)).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"client_id", "user_id", "redirect_uri", "code_challenge", "scope", "nonce", "auth_time", "expires_at"}).
+				AddRow(clientID, userID, test.ClientRedirectURI, "challenge", "openid", nonce, authTime, expiresAt),
+		)
+
+	ac, err := st.store.ConsumeAuthorizationCode(context.Background(), code)
+	require.NoError(st.T(), err)
+
+	st.Assert().Equal(oauth.NewAuthorizationCode(code, clientID, userID, test.ClientRedirectURI, "challenge", "openid", nonce, authTime, expiresAt), ac)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestConsumeAuthorizationCodeFailure() {
+	testCases := map[string]struct {
+		err          error
+		expectedKind erx.Kind
+	}{
+		"test failure when code is not found or already used": {
+			err:          sql.ErrNoRows,
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			err: errors.New("failed to consume code"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			st.mock.ExpectQuery(regexp.QuoteMeta(`update authorization_codes`)).WillReturnError(testCase.err)
+
+			_, err := st.store.ConsumeAuthorizationCode(context.Background(), test.RandString(43))
+			require.Error(st.T(), err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+
+			require.NoError(st.T(), st.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (st *oauthStoreSuite) TestCreateDeviceCodeSuccess() {
+	deviceCode, userCode, clientID := test.RandString(43), "BCDFGHJK", test.NewUUID()
+	expiresAt := time.Now().UTC().Add(time.Minute)
+
+	query := `insert into device_codes (device_code, user_code, client_id, scope, poll_interval, expires_at) values ($1, $2, $3, $4, $5, $6)`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(deviceCode), st.hasher.Hash(userCode), clientID, "openid", 5, expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	dc := oauth.NewDeviceCode(deviceCode, userCode, clientID, "", "openid", oauth.DeviceCodeStatusPending, 5, time.Time{}, time.Time{}, expiresAt)
+
+	err := st.store.CreateDeviceCode(context.Background(), dc)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestCreateDeviceCodeFailure() {
+	st.mock.ExpectExec(regexp.QuoteMeta(`insert into device_codes`)).WillReturnError(errors.New("failed to create code"))
+
+	dc := oauth.NewDeviceCode(test.RandString(43), "BCDFGHJK", test.NewUUID(), "", "", oauth.DeviceCodeStatusPending, 5, time.Time{}, time.Time{}, time.Now())
+
+	err := st.store.CreateDeviceCode(context.Background(), dc)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestGetDeviceCodeClientSuccess() {
+	userCode, clientName, now := "BCDFGHJK", test.RandString(8), time.Now().UTC()
+
+	query := `select c.name from device_codes d join clients c on c.id = d.client_id where d.user_code=$1 and d.status='pending' and d.expires_at > $2`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(userCode), now).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(clientName))
+
+	res, err := st.store.GetDeviceCodeClient(context.Background(), userCode, now)
+	require.NoError(st.T(), err)
+
+	st.Assert().Equal(clientName, res)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestGetDeviceCodeClientFailure() {
+	testCases := map[string]struct {
+		err          error
+		expectedKind erx.Kind
+	}{
+		"test failure when user code is not found or expired": {
+			err:          sql.ErrNoRows,
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			err: errors.New("failed to get client"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			st.mock.ExpectQuery(regexp.QuoteMeta(`select c.name from device_codes d`)).WillReturnError(testCase.err)
+
+			_, err := st.store.GetDeviceCodeClient(context.Background(), "BCDFGHJK", time.Now())
+			require.Error(st.T(), err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+
+			require.NoError(st.T(), st.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (st *oauthStoreSuite) TestResolveDeviceCodeSuccess() {
+	userCode, userID, authTime := "BCDFGHJK", test.NewUUID(), time.Now().UTC()
+
+	query := `update device_codes set status=$2, user_id=$3, auth_time=$4 where user_code=$1 and status='pending' and expires_at > $4`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(userCode), oauth.DeviceCodeStatusApproved, userID, authTime).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := st.store.ResolveDeviceCode(context.Background(), userCode, userID, true, authTime)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestResolveDeviceCodeFailure() {
+	testCases := map[string]struct {
+		result       driver.Result
+		err          error
+		expectedKind erx.Kind
+	}{
+		"test failure when user code is not found or expired": {
+			result:       sqlmock.NewResult(0, 0),
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			err: errors.New("failed to resolve code"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			st.mock.ExpectExec(regexp.QuoteMeta(`update device_codes`)).
+				WithArgs(sqlmock.AnyArg(), oauth.DeviceCodeStatusDenied, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(testCase.result).
+				WillReturnError(testCase.err)
+
+			err := st.store.ResolveDeviceCode(context.Background(), "BCDFGHJK", test.NewUUID(), false, time.Now())
+			require.Error(st.T(), err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+
+			require.NoError(st.T(), st.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (st *oauthStoreSuite) TestPollDeviceCodeSuccess() {
+	deviceCode, clientID, userID := test.RandString(43), test.NewUUID(), test.NewUUID()
+	polledAt, authTime, expiresAt := time.Now().UTC(), time.Now().UTC().Add(-time.Second), time.Now().UTC().Add(time.Minute)
+
+	query := `update device_codes d set last_polled_at=$2 from device_codes p where d.device_code=$1 and p.device_code=d.device_code returning d.client_id, coalesce(d.user_id::text, ''), d.scope, d.status, d.poll_interval, p.last_polled_at, d.auth_time, d.expires_at`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(deviceCode), polledAt).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"client_id", "user_id", "scope", "status", "poll_interval", "last_polled_at", "auth_time", "expires_at"}).
+				AddRow(clientID, userID, "openid", oauth.DeviceCodeStatusApproved, 5, nil, authTime, expiresAt),
+		)
+
+	dc, err := st.store.PollDeviceCode(context.Background(), deviceCode, polledAt)
+	require.NoError(st.T(), err)
+
+	expected := oauth.NewDeviceCode(deviceCode, "", clientID, userID, "openid", oauth.DeviceCodeStatusApproved, 5, time.Time{}, authTime, expiresAt)
+	st.Assert().Equal(expected, dc)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestPollDeviceCodeFailure() {
+	testCases := map[string]struct {
+		err          error
+		expectedKind erx.Kind
+	}{
+		"test failure when device code is not found": {
+			err:          sql.ErrNoRows,
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			err: errors.New("failed to poll code"),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			st.mock.ExpectQuery(regexp.QuoteMeta(`update device_codes d`)).WillReturnError(testCase.err)
+
+			_, err := st.store.PollDeviceCode(context.Background(), test.RandString(43), time.Now())
+			require.Error(st.T(), err)
+
+			st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+
+			require.NoError(st.T(), st.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (st *oauthStoreSuite) TestSlowDownDeviceCodeSuccess() {
+	deviceCode := test.RandString(43)
+
+	query := `update device_codes set poll_interval=poll_interval+$2 where device_code=$1`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(deviceCode), 5).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := st.store.SlowDownDeviceCode(context.Background(), deviceCode)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestConsumeDeviceCodeSuccess() {
+	deviceCode := test.RandString(43)
+
+	query := `update device_codes set status='consumed' where device_code=$1 and status='approved'`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(st.hasher.Hash(deviceCode)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := st.store.ConsumeDeviceCode(context.Background(), deviceCode)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *oauthStoreSuite) TestConsumeDeviceCodeFailure() {
+	st.mock.ExpectExec(regexp.QuoteMeta(`update device_codes set status='consumed'`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := st.store.ConsumeDeviceCode(context.Background(), test.RandString(43))
+	require.Error(st.T(), err)
+
+	st.Assert().Equal(erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func TestOAuthStore(t *testing.T) {
+	suite.Run(t, new(oauthStoreSuite))
+}
@@ -0,0 +1,54 @@
+package role
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (mock *MockService) CreateRole(ctx context.Context, name string, permissions []string) (string, error) {
+	args := mock.Called(ctx, name, permissions)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) AssignRole(ctx context.Context, userID, roleName, clientID string) error {
+	args := mock.Called(ctx, userID, roleName, clientID)
+	return args.Error(0)
+}
+
+func (mock *MockService) UnassignRole(ctx context.Context, userID, roleName, clientID string) error {
+	args := mock.Called(ctx, userID, roleName, clientID)
+	return args.Error(0)
+}
+
+func (mock *MockService) GetGrants(ctx context.Context, userID, clientID string) (Grants, error) {
+	args := mock.Called(ctx, userID, clientID)
+	return args.Get(0).(Grants), args.Error(1)
+}
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (mock *MockStore) CreateRole(ctx context.Context, role Role) (string, error) {
+	args := mock.Called(ctx, role)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) AssignRole(ctx context.Context, userID, roleName, clientID string) error {
+	args := mock.Called(ctx, userID, roleName, clientID)
+	return args.Error(0)
+}
+
+func (mock *MockStore) UnassignRole(ctx context.Context, userID, roleName, clientID string) error {
+	args := mock.Called(ctx, userID, roleName, clientID)
+	return args.Error(0)
+}
+
+func (mock *MockStore) GetGrants(ctx context.Context, userID, clientID string) (Grants, error) {
+	args := mock.Called(ctx, userID, clientID)
+	return args.Get(0).(Grants), args.Error(1)
+}
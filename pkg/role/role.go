@@ -0,0 +1,118 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"sort"
+)
+
+type Role struct {
+	name        string
+	permissions []string
+}
+
+func (r Role) Name() string {
+	return r.name
+}
+
+func (r Role) Permissions() []string {
+	return r.permissions
+}
+
+type Grants struct {
+	Roles       []string
+	Permissions []string
+}
+
+func newGrants(rolePermissions map[string][]string) Grants {
+	var grants Grants
+	seen := make(map[string]bool)
+
+	for name, permissions := range rolePermissions {
+		grants.Roles = append(grants.Roles, name)
+
+		for _, permission := range permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				grants.Permissions = append(grants.Permissions, permission)
+			}
+		}
+	}
+
+	sort.Strings(grants.Roles)
+	sort.Strings(grants.Permissions)
+
+	return grants
+}
+
+type Builder struct {
+	name        string
+	permissions []string
+
+	err error
+}
+
+func (b *Builder) Name(name string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !isValidName(name) {
+		b.err = fmt.Errorf("invalid role name %s", name)
+		return b
+	}
+
+	b.name = name
+	return b
+}
+
+func (b *Builder) Permissions(permissions []string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	for _, permission := range permissions {
+		if !isValidName(permission) {
+			b.err = fmt.Errorf("invalid permission %s", permission)
+			return b
+		}
+	}
+
+	b.permissions = permissions
+	return b
+}
+
+func (b *Builder) Build() (Role, error) {
+	if b.err != nil {
+		return Role{}, erx.WithArgs(erx.Operation("RoleBuilder.Build"), erx.ValidationError, b.err)
+	}
+
+	if len(b.name) == 0 {
+		return Role{}, erx.WithArgs(erx.Operation("RoleBuilder.Build"), erx.ValidationError, errors.New("role name cannot be empty"))
+	}
+
+	return Role{
+		name:        b.name,
+		permissions: b.permissions,
+	}, nil
+}
+
+func NewRoleBuilder() *Builder {
+	return &Builder{}
+}
+
+func isValidName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	//NOTE: ROLES AND PERMISSIONS ARE JOINED BY SPACES IN TOKENS, SO THEY FOLLOW THE SAME CHARACTER RULES AS SCOPES
+	for _, c := range name {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
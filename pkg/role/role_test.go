@@ -0,0 +1,39 @@
+package role_test
+
+import (
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/role"
+	"testing"
+)
+
+func TestCreateNewRoleSuccess(t *testing.T) {
+	r, err := role.NewRoleBuilder().Name("admin").Permissions([]string{"users:read", "users:write"}).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "admin", r.Name())
+	assert.Equal(t, []string{"users:read", "users:write"}, r.Permissions())
+}
+
+func TestCreateNewRoleValidationFailure(t *testing.T) {
+	testCases := map[string]struct {
+		name        string
+		permissions []string
+	}{
+		"test failure when name is empty":           {name: ""},
+		"test failure when name contains space":     {name: "super admin"},
+		"test failure when permission is empty":     {name: "admin", permissions: []string{""}},
+		"test failure when permission has a quote":  {name: "admin", permissions: []string{`users"read`}},
+		"test failure when permission contains tab": {name: "admin", permissions: []string{"users\tread"}},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := role.NewRoleBuilder().Name(testCase.name).Permissions(testCase.permissions).Build()
+			require.Error(t, err)
+
+			assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
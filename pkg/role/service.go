@@ -0,0 +1,99 @@
+package role
+
+import (
+	"context"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/util"
+)
+
+type Service interface {
+	CreateRole(ctx context.Context, name string, permissions []string) (string, error)
+	AssignRole(ctx context.Context, userID, roleName, clientID string) error
+	UnassignRole(ctx context.Context, userID, roleName, clientID string) error
+	GetGrants(ctx context.Context, userID, clientID string) (Grants, error)
+}
+
+type roleService struct {
+	store Store
+}
+
+func (rs *roleService) CreateRole(ctx context.Context, name string, permissions []string) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.CreateRole"), err) }
+
+	role, err := NewRoleBuilder().Name(name).Permissions(permissions).Build()
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	id, err := rs.store.CreateRole(ctx, role)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	return id, nil
+}
+
+func (rs *roleService) AssignRole(ctx context.Context, userID, roleName, clientID string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.AssignRole"), err) }
+
+	err := validateAssignment(userID, roleName, clientID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = rs.store.AssignRole(ctx, userID, roleName, clientID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (rs *roleService) UnassignRole(ctx context.Context, userID, roleName, clientID string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.UnassignRole"), err) }
+
+	err := validateAssignment(userID, roleName, clientID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = rs.store.UnassignRole(ctx, userID, roleName, clientID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (rs *roleService) GetGrants(ctx context.Context, userID, clientID string) (Grants, error) {
+	grants, err := rs.store.GetGrants(ctx, userID, clientID)
+	if err != nil {
+		return Grants{}, erx.WithArgs(erx.Operation("Service.GetGrants"), err)
+	}
+
+	return grants, nil
+}
+
+func validateAssignment(userID, roleName, clientID string) error {
+	if !util.IsValidUUID(userID) {
+		return erx.WithArgs(erx.ValidationError, fmt.Errorf("invalid user id %s", userID))
+	}
+
+	if !isValidName(roleName) {
+		return erx.WithArgs(erx.ValidationError, fmt.Errorf("invalid role name %s", roleName))
+	}
+
+	//NOTE: AN EMPTY CLIENT ID ASSIGNS THE ROLE FOR EVERY CLIENT
+	if len(clientID) != 0 && !util.IsValidUUID(clientID) {
+		return erx.WithArgs(erx.ValidationError, fmt.Errorf("invalid client id %s", clientID))
+	}
+
+	return nil
+}
+
+func NewService(store Store) Service {
+	return &roleService{
+		store: store,
+	}
+}
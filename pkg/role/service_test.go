@@ -0,0 +1,102 @@
+package role_test
+
+import (
+	"context"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/role"
+	"identification-service/pkg/test"
+	"testing"
+)
+
+func TestRoleServiceCreateRoleSuccess(t *testing.T) {
+	id := test.NewUUID()
+
+	mockStore := &role.MockStore{}
+	mockStore.On("CreateRole", mock.Anything, mock.AnythingOfType("Role")).Return(id, nil)
+
+	res, err := role.NewService(mockStore).CreateRole(context.Background(), "admin", []string{"users:read"})
+	require.NoError(t, err)
+
+	assert.Equal(t, id, res)
+}
+
+func TestRoleServiceCreateRoleFailureWhenRoleIsInvalid(t *testing.T) {
+	_, err := role.NewService(&role.MockStore{}).CreateRole(context.Background(), "super admin", nil)
+	require.Error(t, err)
+
+	assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+}
+
+func TestRoleServiceCreateRoleFailureWhenStoreCallFails(t *testing.T) {
+	mockStore := &role.MockStore{}
+	mockStore.On("CreateRole", mock.Anything, mock.AnythingOfType("Role")).
+		Return("", errors.New("failed to create role"))
+
+	_, err := role.NewService(mockStore).CreateRole(context.Background(), "admin", nil)
+	require.Error(t, err)
+}
+
+func TestRoleServiceAssignRoleSuccess(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	mockStore := &role.MockStore{}
+	mockStore.On("AssignRole", mock.Anything, userID, "admin", clientID).Return(nil)
+
+	require.NoError(t, role.NewService(mockStore).AssignRole(context.Background(), userID, "admin", clientID))
+}
+
+func TestRoleServiceAssignRoleValidationFailure(t *testing.T) {
+	testCases := map[string]struct {
+		userID   string
+		roleName string
+		clientID string
+	}{
+		"test failure when user id is invalid":   {userID: "invalid", roleName: "admin"},
+		"test failure when role name is empty":   {userID: test.NewUUID(), roleName: ""},
+		"test failure when client id is invalid": {userID: test.NewUUID(), roleName: "admin", clientID: "invalid"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := role.NewService(&role.MockStore{})
+
+			err := service.AssignRole(context.Background(), testCase.userID, testCase.roleName, testCase.clientID)
+			require.Error(t, err)
+			assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+
+			err = service.UnassignRole(context.Background(), testCase.userID, testCase.roleName, testCase.clientID)
+			require.Error(t, err)
+			assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestRoleServiceUnassignRoleFailureWhenStoreCallFails(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &role.MockStore{}
+	mockStore.On("UnassignRole", mock.Anything, userID, "admin", "").
+		Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("not assigned")))
+
+	err := role.NewService(mockStore).UnassignRole(context.Background(), userID, "admin", "")
+	require.Error(t, err)
+
+	assert.Equal(t, erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+}
+
+func TestRoleServiceGetGrantsSuccess(t *testing.T) {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+	grants := role.Grants{Roles: []string{"admin"}, Permissions: []string{"users:read"}}
+
+	mockStore := &role.MockStore{}
+	mockStore.On("GetGrants", mock.Anything, userID, clientID).Return(grants, nil)
+
+	res, err := role.NewService(mockStore).GetGrants(context.Background(), userID, clientID)
+	require.NoError(t, err)
+
+	assert.Equal(t, grants, res)
+}
@@ -0,0 +1,148 @@
+package role
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/database"
+)
+
+const (
+	createRole = `with r as (insert into roles (name) values ($1) returning id),
+	ps as (insert into role_permissions (role_id, permission) select r.id, p from r, unnest($2::text[]) as p)
+	select id from r`
+	assignRole   = `insert into user_roles (user_id, role_id, client_id) select $1, id, $3::uuid from roles where name=$2 returning role_id`
+	unassignRole = `delete from user_roles ur using roles r where ur.role_id=r.id and ur.user_id=$1 and r.name=$2 and ur.client_id is not distinct from $3::uuid`
+	getGrants    = `select r.name, coalesce(array_agg(p.permission) filter (where p.permission is not null), '{}') from user_roles ur join roles r on r.id = ur.role_id left join role_permissions p on p.role_id = r.id where ur.user_id=$1 and (ur.client_id is null or ur.client_id=$2::uuid) group by r.name`
+)
+
+type Store interface {
+	CreateRole(ctx context.Context, role Role) (string, error)
+	AssignRole(ctx context.Context, userID, roleName, clientID string) error
+	UnassignRole(ctx context.Context, userID, roleName, clientID string) error
+	GetGrants(ctx context.Context, userID, clientID string) (Grants, error)
+}
+
+type roleStore struct {
+	db database.SQLDatabase
+}
+
+func (rs *roleStore) CreateRole(ctx context.Context, role Role) (string, error) {
+	var id string
+
+	row := rs.db.QueryRowContext(ctx, createRole, role.name, pq.Array(role.permissions))
+	if row.Err() != nil {
+		if pgErr, ok := row.Err().(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return "", erx.WithArgs(erx.Operation("Store.CreateRole"), erx.DuplicateRecordError, row.Err())
+			}
+		}
+
+		return "", erx.WithArgs(erx.Operation("Store.CreateRole"), row.Err())
+	}
+
+	err := row.Scan(&id)
+	if err != nil {
+		return "", erx.WithArgs(erx.Operation("Store.CreateRole"), err)
+	}
+
+	return id, nil
+}
+
+func (rs *roleStore) AssignRole(ctx context.Context, userID, roleName, clientID string) error {
+	var roleID string
+
+	row := rs.db.QueryRowContext(ctx, assignRole, userID, roleName, nullable(clientID))
+	if row.Err() != nil {
+		if pgErr, ok := row.Err().(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return erx.WithArgs(erx.Operation("Store.AssignRole"), erx.DuplicateRecordError, row.Err())
+			}
+		}
+
+		return erx.WithArgs(erx.Operation("Store.AssignRole"), row.Err())
+	}
+
+	err := row.Scan(&roleID)
+	if err == sql.ErrNoRows {
+		return erx.WithArgs(
+			erx.Operation("Store.AssignRole"),
+			erx.ResourceNotFoundError,
+			fmt.Errorf("no role found with name %s", roleName),
+		)
+	}
+
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.AssignRole"), err)
+	}
+
+	return nil
+}
+
+func (rs *roleStore) UnassignRole(ctx context.Context, userID, roleName, clientID string) error {
+	res, err := rs.db.ExecContext(ctx, unassignRole, userID, roleName, nullable(clientID))
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.UnassignRole"), err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.UnassignRole"), err)
+	}
+
+	if c == 0 {
+		return erx.WithArgs(
+			erx.Operation("Store.UnassignRole"),
+			erx.ResourceNotFoundError,
+			fmt.Errorf("role %s is not assigned to user %s", roleName, userID),
+		)
+	}
+
+	return nil
+}
+
+func (rs *roleStore) GetGrants(ctx context.Context, userID, clientID string) (Grants, error) {
+	//NOTE: ROLES ASSIGNED WITHOUT A CLIENT APPLY TO EVERY CLIENT, THE REST ONLY TO THE CLIENT THEY WERE ASSIGNED FOR
+	rows, err := rs.db.QueryContext(ctx, getGrants, userID, nullable(clientID))
+	if err != nil {
+		return Grants{}, erx.WithArgs(erx.Operation("Store.GetGrants"), err)
+	}
+
+	defer rows.Close()
+
+	rolePermissions := make(map[string][]string)
+
+	for rows.Next() {
+		var name string
+		var permissions []string
+
+		err := rows.Scan(&name, pq.Array(&permissions))
+		if err != nil {
+			return Grants{}, erx.WithArgs(erx.Operation("Store.GetGrants"), err)
+		}
+
+		rolePermissions[name] = permissions
+	}
+
+	if err := rows.Err(); err != nil {
+		return Grants{}, erx.WithArgs(erx.Operation("Store.GetGrants"), err)
+	}
+
+	return newGrants(rolePermissions), nil
+}
+
+func nullable(value string) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+
+	return value
+}
+
+func NewStore(db database.SQLDatabase) Store {
+	return &roleStore{
+		db: db,
+	}
+}
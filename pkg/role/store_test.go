@@ -0,0 +1,199 @@
+package role_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/database"
+	"identification-service/pkg/role"
+	"identification-service/pkg/test"
+	"regexp"
+	"testing"
+)
+
+const (
+	createRoleQuery = `with r as (insert into roles (name) values ($1) returning id),
+	ps as (insert into role_permissions (role_id, permission) select r.id, p from r, unnest($2::text[]) as p)
+	select id from r`
+	assignRoleQuery   = `insert into user_roles (user_id, role_id, client_id) select $1, id, $3::uuid from roles where name=$2 returning role_id`
+	unassignRoleQuery = `delete from user_roles ur using roles r where ur.role_id=r.id and ur.user_id=$1 and r.name=$2 and ur.client_id is not distinct from $3::uuid`
+	getGrantsQuery    = `select r.name, coalesce(array_agg(p.permission) filter (where p.permission is not null), '{}') from user_roles ur join roles r on r.id = ur.role_id left join role_permissions p on p.role_id = r.id where ur.user_id=$1 and (ur.client_id is null or ur.client_id=$2::uuid) group by r.name`
+)
+
+type roleStoreSuite struct {
+	suite.Suite
+	db    database.SQLDatabase
+	mock  sqlmock.Sqlmock
+	store role.Store
+}
+
+func (rst *roleStoreSuite) SetupSuite() {
+	sqlDB, mock := getMockDB(rst.T())
+
+	rst.db = database.NewSQLDatabase(sqlDB, test.QueryTTL)
+	rst.mock = mock
+
+	rst.store = role.NewStore(rst.db)
+}
+
+func (rst *roleStoreSuite) TestCreateRoleSuccess() {
+	permissions := []string{"users:read", "users:write"}
+
+	r, err := role.NewRoleBuilder().Name("admin").Permissions(permissions).Build()
+	require.NoError(rst.T(), err)
+
+	id := test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(createRoleQuery)).
+		WithArgs("admin", pq.Array(permissions)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+
+	res, err := rst.store.CreateRole(context.Background(), r)
+	require.NoError(rst.T(), err)
+
+	assert.Equal(rst.T(), id, res)
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestCreateRoleFailureWhenRoleAlreadyExists() {
+	r, err := role.NewRoleBuilder().Name("admin").Build()
+	require.NoError(rst.T(), err)
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(createRoleQuery)).
+		WithArgs("admin", pq.Array([]string(nil))).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err = rst.store.CreateRole(context.Background(), r)
+	require.Error(rst.T(), err)
+
+	assert.Equal(rst.T(), erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestAssignRoleSuccess() {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(assignRoleQuery)).
+		WithArgs(userID, "admin", clientID).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(test.NewUUID()))
+
+	require.NoError(rst.T(), rst.store.AssignRole(context.Background(), userID, "admin", clientID))
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestAssignRoleSuccessWithoutClient() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(assignRoleQuery)).
+		WithArgs(userID, "admin", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}).AddRow(test.NewUUID()))
+
+	require.NoError(rst.T(), rst.store.AssignRole(context.Background(), userID, "admin", ""))
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestAssignRoleFailureWhenRoleDoesNotExist() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(assignRoleQuery)).
+		WithArgs(userID, "admin", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id"}))
+
+	err := rst.store.AssignRole(context.Background(), userID, "admin", "")
+	require.Error(rst.T(), err)
+
+	assert.Equal(rst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestAssignRoleFailureWhenAlreadyAssigned() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(assignRoleQuery)).
+		WithArgs(userID, "admin", nil).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	err := rst.store.AssignRole(context.Background(), userID, "admin", "")
+	require.Error(rst.T(), err)
+
+	assert.Equal(rst.T(), erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestUnassignRoleSuccess() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectExec(regexp.QuoteMeta(unassignRoleQuery)).
+		WithArgs(userID, "admin", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(rst.T(), rst.store.UnassignRole(context.Background(), userID, "admin", ""))
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestUnassignRoleFailureWhenNotAssigned() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectExec(regexp.QuoteMeta(unassignRoleQuery)).
+		WithArgs(userID, "admin", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := rst.store.UnassignRole(context.Background(), userID, "admin", "")
+	require.Error(rst.T(), err)
+
+	assert.Equal(rst.T(), erx.ResourceNotFoundError, err.(*erx.Erx).Kind())
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestGetGrantsSuccess() {
+	userID, clientID := test.NewUUID(), test.NewUUID()
+
+	rows := sqlmock.NewRows([]string{"name", "permissions"}).
+		AddRow("editor", "{posts:read,posts:write}").
+		AddRow("admin", "{users:read,posts:read}")
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(getGrantsQuery)).
+		WithArgs(userID, clientID).
+		WillReturnRows(rows)
+
+	grants, err := rst.store.GetGrants(context.Background(), userID, clientID)
+	require.NoError(rst.T(), err)
+
+	expected := role.Grants{
+		Roles:       []string{"admin", "editor"},
+		Permissions: []string{"posts:read", "posts:write", "users:read"},
+	}
+
+	assert.Equal(rst.T(), expected, grants)
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func (rst *roleStoreSuite) TestGetGrantsFailure() {
+	userID := test.NewUUID()
+
+	rst.mock.ExpectQuery(regexp.QuoteMeta(getGrantsQuery)).
+		WithArgs(userID, nil).
+		WillReturnError(errors.New("failed to get grants"))
+
+	_, err := rst.store.GetGrants(context.Background(), userID, "")
+	require.Error(rst.T(), err)
+
+	require.NoError(rst.T(), rst.mock.ExpectationsWereMet())
+}
+
+func TestRoleStore(t *testing.T) {
+	suite.Run(t, new(roleStoreSuite))
+}
+
+func getMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	return db, mock
+}
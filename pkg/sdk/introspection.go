@@ -0,0 +1,66 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const introspectPath = "/token/introspect"
+
+type introspection struct {
+	Active bool `json:"active"`
+}
+
+type introspector struct {
+	url    string
+	name   string
+	secret string
+	client *http.Client
+}
+
+func (in *introspector) active(ctx context.Context, accessToken string) (bool, error) {
+	body, err := json.Marshal(map[string]string{"token": accessToken})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("CLIENT-ID", in.name)
+	req.Header.Set("CLIENT-SECRET", in.secret)
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to introspect token: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("failed to introspect token: unexpected status %d", resp.StatusCode)
+	}
+
+	var res introspection
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return false, fmt.Errorf("failed to decode introspection: %w", err)
+	}
+
+	return res.Active, nil
+}
+
+func newIntrospector(baseURL, name, secret string, client *http.Client) *introspector {
+	return &introspector{
+		url:    strings.TrimSuffix(baseURL, "/") + introspectPath,
+		name:   name,
+		secret: secret,
+		client: client,
+	}
+}
@@ -0,0 +1,136 @@
+package sdk
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const jwksPath = "/.well-known/jwks.json"
+
+type jwk struct {
+	KeyType  string `json:"kty"`
+	Curve    string `json:"crv"`
+	X        string `json:"x"`
+	KeyID    string `json:"kid"`
+	ClientID string `json:"client_id"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type keySet struct {
+	url        string
+	clientID   string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+func (ks *keySet) key(ctx context.Context, keyID string) (ed25519.PublicKey, error) {
+	ks.mu.RLock()
+	key, ok := ks.keys[keyID]
+	stale := time.Since(ks.fetchedAt) > ks.ttl
+	ks.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := ks.refresh(ctx, ok); err != nil {
+		if ok {
+			return key, nil
+		}
+
+		return nil, err
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok = ks.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	return key, nil
+}
+
+func (ks *keySet) refresh(ctx context.Context, known bool) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	//NOTE: AN UNKNOWN KEY ID TRIGGERS A FETCH, BUT NOT MORE OFTEN THAN MIN REFRESH SO FORGED KIDS CANNOT FLOOD THE SERVICE
+	sinceFetch := time.Since(ks.fetchedAt)
+	if (!known && sinceFetch < ks.minRefresh) || (known && sinceFetch <= ks.ttl) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch keys: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch keys: unexpected status %d", resp.StatusCode)
+	}
+
+	var body jwks
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode keys: %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(body.Keys))
+
+	for _, k := range body.Keys {
+		if k.KeyType != "OKP" || k.Curve != "Ed25519" {
+			continue
+		}
+
+		//NOTE: THE DOCUMENT PUBLISHES THE KEYS OF EVERY CLIENT, A TOKEN SIGNED BY ANOTHER CLIENT MUST NOT BE ACCEPTED
+		if k.ClientID != ks.clientID {
+			continue
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+
+		keys[k.KeyID] = x
+	}
+
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+
+	return nil
+}
+
+func newKeySet(baseURL, clientID string, client *http.Client, ttl, minRefresh time.Duration) *keySet {
+	return &keySet{
+		url:        strings.TrimSuffix(baseURL, "/") + jwksPath,
+		clientID:   clientID,
+		client:     client,
+		ttl:        ttl,
+		minRefresh: minRefresh,
+		keys:       map[string]ed25519.PublicKey{},
+	}
+}
@@ -0,0 +1,49 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type ctxKey string
+
+var principalCtxKey ctxKey = "principalCtxKey"
+
+const bearerPrefix = "Bearer "
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalCtxKey).(Principal)
+	return principal, ok
+}
+
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		header := req.Header.Get("Authorization")
+		if !strings.HasPrefix(header, bearerPrefix) {
+			unauthorized(resp)
+			return
+		}
+
+		principal, err := v.Verify(req.Context(), strings.TrimPrefix(header, bearerPrefix))
+		if err != nil {
+			unauthorized(resp)
+			return
+		}
+
+		next.ServeHTTP(resp, req.WithContext(WithPrincipal(req.Context(), principal)))
+	})
+}
+
+func (v *Verifier) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
+	return v.Middleware(next).ServeHTTP
+}
+
+func unauthorized(resp http.ResponseWriter) {
+	resp.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(resp, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
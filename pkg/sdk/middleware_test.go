@@ -0,0 +1,85 @@
+package sdk_test
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/sdk"
+	"identification-service/pkg/test"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	key := newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	verifier, err := sdk.NewVerifier(sdk.Config{BaseURL: ks.server.URL, ClientID: testClientID, Audience: "user", Issuer: "identification-service"})
+	require.NoError(t, err)
+
+	subject := test.NewUUID()
+	accessToken := newAccessToken(t, newTokenConfig("user", "identification-service"), 10, subject, key, nil)
+
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		principal, ok := sdk.PrincipalFromContext(req.Context())
+		require.True(t, ok)
+
+		_, _ = resp.Write([]byte(principal.Subject))
+	}
+
+	r := chi.NewRouter()
+	r.Use(verifier.Middleware)
+	r.Get("/me", handler)
+
+	routers := map[string]http.Handler{
+		"net/http": verifier.Middleware(http.HandlerFunc(handler)),
+		"chi":      r,
+	}
+
+	testCases := map[string]struct {
+		header       string
+		expectedCode int
+		expectedBody string
+	}{
+		"test success with valid bearer token": {
+			header:       "Bearer " + accessToken,
+			expectedCode: http.StatusOK,
+			expectedBody: subject,
+		},
+		"test failure when header is missing": {
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when scheme is not bearer": {
+			header:       "Basic " + accessToken,
+			expectedCode: http.StatusUnauthorized,
+		},
+		"test failure when token is invalid": {
+			header:       "Bearer invalid",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for routerName, router := range routers {
+		for name, testCase := range testCases {
+			t.Run(routerName+" "+name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "/me", nil)
+				if len(testCase.header) > 0 {
+					req.Header.Set("Authorization", testCase.header)
+				}
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, testCase.expectedCode, w.Code)
+				if testCase.expectedCode == http.StatusOK {
+					assert.Equal(t, testCase.expectedBody, w.Body.String())
+				} else {
+					assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/o1egl/paseto"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKeyTTL        = 5 * time.Minute
+	defaultMinKeyRefresh = 10 * time.Second
+
+	sessionIDClaim = "session_id"
+	scopeClaim     = "scope"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid access token")
+	ErrUnknownKey   = errors.New("unknown signing key")
+)
+
+var registeredClaims = map[string]bool{
+	"aud": true, "iss": true, "jti": true, "sub": true, "exp": true, "iat": true, "nbf": true,
+}
+
+type Config struct {
+	BaseURL  string
+	ClientID string
+	Audience string
+	Issuer   string
+
+	HTTPClient    *http.Client
+	KeyTTL        time.Duration
+	MinKeyRefresh time.Duration
+
+	Introspect   bool
+	ClientName   string
+	ClientSecret string
+}
+
+type Principal struct {
+	Subject    string
+	SessionID  string
+	KeyID      string
+	Jti        string
+	Audience   string
+	Issuer     string
+	IssuedAt   time.Time
+	Expiration time.Time
+
+	claims map[string]string
+}
+
+func (p Principal) Claim(name string) (string, bool) {
+	value, ok := p.claims[name]
+	return value, ok
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range strings.Fields(p.claims[scopeClaim]) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Principal) Claims() map[string]string {
+	res := make(map[string]string, len(p.claims))
+	for k, v := range p.claims {
+		res[k] = v
+	}
+
+	return res
+}
+
+type footer struct {
+	KeyID string `json:"kid"`
+}
+
+type Verifier struct {
+	audience     string
+	issuer       string
+	keys         *keySet
+	introspector *introspector
+}
+
+func (v *Verifier) Verify(ctx context.Context, accessToken string) (Principal, error) {
+	var f footer
+	if err := paseto.ParseFooter(accessToken, &f); err != nil || len(f.KeyID) == 0 {
+		return Principal{}, fmt.Errorf("%w: key id not present in footer", ErrInvalidToken)
+	}
+
+	publicKey, err := v.keys.key(ctx, f.KeyID)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	var payload []byte
+	if err := paseto.NewV2().Verify(accessToken, publicKey, &payload, nil); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var jsonToken paseto.JSONToken
+	if err := json.Unmarshal(payload, &jsonToken); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	err = jsonToken.Validate(
+		paseto.ForAudience(v.audience),
+		paseto.IssuedBy(v.issuer),
+		paseto.ValidAt(time.Now()),
+	)
+
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims := make(map[string]string)
+	for k, val := range raw {
+		if !registeredClaims[k] {
+			claims[k] = val
+		}
+	}
+
+	//NOTE: A LOCALLY VALID TOKEN CAN STILL BE ON THE DENYLIST OF THE SERVICE, ONLY INTROSPECTION SEES A LOGOUT BEFORE EXPIRY
+	if v.introspector != nil {
+		active, err := v.introspector.active(ctx, accessToken)
+		if err != nil {
+			return Principal{}, err
+		}
+
+		if !active {
+			return Principal{}, fmt.Errorf("%w: token is no longer active", ErrInvalidToken)
+		}
+	}
+
+	return Principal{
+		Subject:    jsonToken.Subject,
+		SessionID:  claims[sessionIDClaim],
+		KeyID:      f.KeyID,
+		Jti:        jsonToken.Jti,
+		Audience:   jsonToken.Audience,
+		Issuer:     jsonToken.Issuer,
+		IssuedAt:   jsonToken.IssuedAt,
+		Expiration: jsonToken.Expiration,
+		claims:     claims,
+	}, nil
+}
+
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if len(cfg.BaseURL) == 0 {
+		return nil, errors.New("base url cannot be empty")
+	}
+
+	if len(cfg.ClientID) == 0 {
+		return nil, errors.New("client id cannot be empty")
+	}
+
+	if len(cfg.Audience) == 0 {
+		return nil, errors.New("audience cannot be empty")
+	}
+
+	if len(cfg.Issuer) == 0 {
+		return nil, errors.New("issuer cannot be empty")
+	}
+
+	if cfg.Introspect && (len(cfg.ClientName) == 0 || len(cfg.ClientSecret) == 0) {
+		return nil, errors.New("client name and secret are required for introspection")
+	}
+
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	keyTTL := cfg.KeyTTL
+	if keyTTL <= 0 {
+		keyTTL = defaultKeyTTL
+	}
+
+	minKeyRefresh := cfg.MinKeyRefresh
+	if minKeyRefresh <= 0 {
+		minKeyRefresh = defaultMinKeyRefresh
+	}
+
+	var in *introspector
+	if cfg.Introspect {
+		in = newIntrospector(cfg.BaseURL, cfg.ClientName, cfg.ClientSecret, client)
+	}
+
+	return &Verifier{
+		audience:     cfg.Audience,
+		issuer:       cfg.Issuer,
+		keys:         newKeySet(cfg.BaseURL, cfg.ClientID, client, keyTTL, minKeyRefresh),
+		introspector: in,
+	}, nil
+}
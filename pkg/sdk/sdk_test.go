@@ -0,0 +1,347 @@
+package sdk_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/config"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/sdk"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testClientID = "5c4a5b4e-6a5f-4bde-9a55-2f8d3c1b7e90"
+
+type keyServer struct {
+	mu      sync.Mutex
+	keys    []libcrypto.Key
+	clients map[string]string
+	fetches int32
+	server  *httptest.Server
+}
+
+func (ks *keyServer) add(key libcrypto.Key) {
+	ks.addForClient(key, testClientID)
+}
+
+func (ks *keyServer) addForClient(key libcrypto.Key, clientID string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys = append(ks.keys, key)
+	ks.clients[key.ID] = clientID
+}
+
+func newKeyServer(keys ...libcrypto.Key) *keyServer {
+	ks := &keyServer{clients: map[string]string{}}
+
+	for _, key := range keys {
+		ks.add(key)
+	}
+
+	ks.server = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&ks.fetches, 1)
+
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+
+		body := map[string][]map[string]string{"keys": {}}
+		for _, key := range ks.keys {
+			body["keys"] = append(body["keys"], map[string]string{
+				"kty":       "OKP",
+				"crv":       "Ed25519",
+				"kid":       key.ID,
+				"x":         base64.RawURLEncoding.EncodeToString(key.PublicKey()),
+				"client_id": ks.clients[key.ID],
+			})
+		}
+
+		_ = json.NewEncoder(resp).Encode(body)
+	}))
+
+	return ks
+}
+
+func newKey() libcrypto.Key {
+	_, pri := test.GenerateKey()
+	return libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: pri}
+}
+
+func newTokenConfig(audience, issuer string) config.TokenConfig {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return(audience)
+	mockTokenConfig.On("Issuer").Return(issuer)
+	return mockTokenConfig
+}
+
+func newAccessToken(t *testing.T, cfg config.TokenConfig, ttl int, subject string, key libcrypto.Key, claims map[string]string) string {
+	accessToken, _, err := token.NewGenerator(cfg).GenerateAccessToken(ttl, subject, key, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return accessToken
+}
+
+type sdkTest struct {
+	suite.Suite
+	cfg config.TokenConfig
+}
+
+func (st *sdkTest) SetupSuite() {
+	st.cfg = newTokenConfig("user", "identification-service")
+}
+
+func TestSDK(t *testing.T) {
+	suite.Run(t, new(sdkTest))
+}
+
+func (st *sdkTest) newVerifier(baseURL string) *sdk.Verifier {
+	verifier, err := sdk.NewVerifier(sdk.Config{BaseURL: baseURL, ClientID: testClientID, Audience: "user", Issuer: "identification-service"})
+	st.Require().NoError(err)
+	return verifier
+}
+
+func (st *sdkTest) TestVerifySuccess() {
+	key := newKey()
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	subject, sessionID := test.NewUUID(), test.NewUUID()
+	accessToken := newAccessToken(st.T(), st.cfg, 10, subject, key, map[string]string{"session_id": sessionID, "scope": "read"})
+
+	principal, err := st.newVerifier(ks.server.URL).Verify(context.Background(), accessToken)
+	st.Require().NoError(err)
+
+	st.Assert().Equal(subject, principal.Subject)
+	st.Assert().Equal(sessionID, principal.SessionID)
+	st.Assert().Equal(key.ID, principal.KeyID)
+	st.Assert().NotEmpty(principal.Jti)
+
+	scope, ok := principal.Claim("scope")
+	st.Assert().True(ok)
+	st.Assert().Equal("read", scope)
+	st.Assert().True(principal.HasScope("read"))
+	st.Assert().False(principal.HasScope("write"))
+
+	_, ok = principal.Claim("sub")
+	st.Assert().False(ok)
+}
+
+func (st *sdkTest) TestVerifyFailure() {
+	key := newKey()
+	unpublished := newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	forged := libcrypto.Key{ID: key.ID, State: libcrypto.ActiveKey, PrivateKey: unpublished.PrivateKey}
+
+	testCases := map[string]struct {
+		accessToken string
+		err         error
+	}{
+		"test failure when token is malformed": {
+			accessToken: "invalid token",
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when token is expired": {
+			accessToken: newAccessToken(st.T(), st.cfg, -1, test.NewUUID(), key, nil),
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when audience does not match": {
+			accessToken: newAccessToken(st.T(), newTokenConfig("admin", "identification-service"), 10, test.NewUUID(), key, nil),
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when issuer does not match": {
+			accessToken: newAccessToken(st.T(), newTokenConfig("user", "other-service"), 10, test.NewUUID(), key, nil),
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when token is signed by another key": {
+			accessToken: newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), forged, nil),
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when key is not published": {
+			accessToken: newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), unpublished, nil),
+			err:         sdk.ErrUnknownKey,
+		},
+	}
+
+	verifier := st.newVerifier(ks.server.URL)
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			_, err := verifier.Verify(context.Background(), testCase.accessToken)
+			st.Require().Error(err)
+			st.Assert().True(errors.Is(err, testCase.err))
+		})
+	}
+}
+
+func (st *sdkTest) TestVerifyRefetchesKeysForNewKeyID() {
+	key, rotated := newKey(), newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	verifier, err := sdk.NewVerifier(sdk.Config{
+		BaseURL:       ks.server.URL,
+		ClientID:      testClientID,
+		Audience:      "user",
+		Issuer:        "identification-service",
+		MinKeyRefresh: time.Millisecond,
+	})
+	st.Require().NoError(err)
+
+	_, err = verifier.Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), key, nil))
+	st.Require().NoError(err)
+
+	ks.add(rotated)
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = verifier.Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), rotated, nil))
+	st.Require().NoError(err)
+
+	st.Assert().Equal(int32(2), atomic.LoadInt32(&ks.fetches))
+}
+
+func (st *sdkTest) TestVerifyCachesKeys() {
+	key := newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	verifier := st.newVerifier(ks.server.URL)
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), key, nil))
+		st.Require().NoError(err)
+	}
+
+	_, err := verifier.Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), newKey(), nil))
+	st.Require().Error(err)
+
+	st.Assert().Equal(int32(1), atomic.LoadInt32(&ks.fetches))
+}
+
+func (st *sdkTest) TestVerifyFailureWhenKeyBelongsToAnotherClient() {
+	key, foreign := newKey(), newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	ks.addForClient(foreign, test.NewUUID())
+
+	_, err := st.newVerifier(ks.server.URL).Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), foreign, nil))
+	st.Require().Error(err)
+
+	st.Assert().True(errors.Is(err, sdk.ErrUnknownKey))
+}
+
+func (st *sdkTest) TestVerifyFailureWhenKeysCannotBeFetched() {
+	ks := newKeyServer()
+	ks.server.Close()
+
+	_, err := st.newVerifier(ks.server.URL).Verify(context.Background(), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), newKey(), nil))
+	st.Require().Error(err)
+}
+
+func (st *sdkTest) TestVerifyWithIntrospection() {
+	key := newKey()
+
+	ks := newKeyServer(key)
+	defer ks.server.Close()
+
+	active, revoked := newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), key, nil), newAccessToken(st.T(), st.cfg, 10, test.NewUUID(), key, nil)
+	clientName, clientSecret := test.RandString(8), test.NewUUID()
+
+	mux := http.NewServeMux()
+	mux.Handle("/.well-known/jwks.json", ks.server.Config.Handler)
+	mux.HandleFunc("/token/introspect", func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("CLIENT-ID") != clientName || req.Header.Get("CLIENT-SECRET") != clientSecret {
+			resp.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body map[string]string
+		_ = json.NewDecoder(req.Body).Decode(&body)
+
+		_ = json.NewEncoder(resp).Encode(map[string]bool{"active": body["token"] == active})
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	newVerifier := func(secret string) *sdk.Verifier {
+		verifier, err := sdk.NewVerifier(sdk.Config{
+			BaseURL:      server.URL,
+			ClientID:     testClientID,
+			Audience:     "user",
+			Issuer:       "identification-service",
+			Introspect:   true,
+			ClientName:   clientName,
+			ClientSecret: secret,
+		})
+		st.Require().NoError(err)
+
+		return verifier
+	}
+
+	_, err := newVerifier(clientSecret).Verify(context.Background(), active)
+	st.Require().NoError(err)
+
+	testCases := map[string]struct {
+		secret      string
+		accessToken string
+		err         error
+	}{
+		"test failure when token was revoked": {
+			secret:      clientSecret,
+			accessToken: revoked,
+			err:         sdk.ErrInvalidToken,
+		},
+		"test failure when introspection fails": {
+			secret:      test.NewUUID(),
+			accessToken: active,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			_, err := newVerifier(testCase.secret).Verify(context.Background(), testCase.accessToken)
+			st.Require().Error(err)
+
+			if testCase.err != nil {
+				st.Assert().True(errors.Is(err, testCase.err))
+			}
+		})
+	}
+}
+
+func (st *sdkTest) TestNewVerifierValidationFailure() {
+	testCases := map[string]sdk.Config{
+		"test failure when base url is empty":  {ClientID: testClientID, Audience: "user", Issuer: "identification-service"},
+		"test failure when client id is empty": {BaseURL: "http://localhost", Audience: "user", Issuer: "identification-service"},
+		"test failure when audience is empty":  {BaseURL: "http://localhost", ClientID: testClientID, Issuer: "identification-service"},
+		"test failure when issuer is empty":    {BaseURL: "http://localhost", ClientID: testClientID, Audience: "user"},
+		"test failure when introspection has no client secret": {
+			BaseURL: "http://localhost", ClientID: testClientID, Audience: "user", Issuer: "identification-service", Introspect: true, ClientName: "service",
+		},
+	}
+
+	for name, cfg := range testCases {
+		st.Run(name, func() {
+			_, err := sdk.NewVerifier(cfg)
+			st.Require().Error(err)
+		})
+	}
+}
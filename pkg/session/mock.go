@@ -3,14 +3,45 @@ package session
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/webauthn"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (mock *MockService) LoginUser(ctx context.Context, email, password string) (string, string, error) {
-	args := mock.Called(ctx, email, password)
+func (mock *MockService) LoginUser(ctx context.Context, email, password string, scopes []string) (string, string, string, error) {
+	args := mock.Called(ctx, email, password, scopes)
+	return args.String(0), args.String(1), args.String(2), args.Error(3)
+}
+
+func (mock *MockService) SendMagicLink(ctx context.Context, email, name string) error {
+	args := mock.Called(ctx, email, name)
+	return args.Error(0)
+}
+
+func (mock *MockService) LoginWithMagicLink(ctx context.Context, magicLinkToken string, scopes []string) (string, string, string, error) {
+	args := mock.Called(ctx, magicLinkToken, scopes)
+	return args.String(0), args.String(1), args.String(2), args.Error(3)
+}
+
+func (mock *MockService) CompleteLogin(ctx context.Context, mfaToken, code string, scopes []string) (string, string, error) {
+	args := mock.Called(ctx, mfaToken, code, scopes)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (mock *MockService) BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error) {
+	args := mock.Called(ctx, mfaToken)
+	return args.String(0), args.Get(1).(webauthn.RequestOptions), args.Error(2)
+}
+
+func (mock *MockService) FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion, scopes []string) (string, string, error) {
+	args := mock.Called(ctx, challengeID, assertion, scopes)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (mock *MockService) StartSession(ctx context.Context, userID, scope string) (string, string, error) {
+	args := mock.Called(ctx, userID, scope)
 	return args.String(0), args.String(1), args.Error(2)
 }
 
@@ -19,9 +50,9 @@ func (mock *MockService) LogoutUser(ctx context.Context, refreshToken string) er
 	return args.Error(0)
 }
 
-func (mock *MockService) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
+func (mock *MockService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
 	args := mock.Called(ctx, refreshToken)
-	return args.String(0), args.Error(1)
+	return args.String(0), args.String(1), args.Error(2)
 }
 
 func (mock *MockService) RevokeAllSessions(ctx context.Context, userID string) error {
@@ -29,6 +60,11 @@ func (mock *MockService) RevokeAllSessions(ctx context.Context, userID string) e
 	return args.Error(0)
 }
 
+func (mock *MockService) IntrospectToken(ctx context.Context, accessToken string) (Introspection, error) {
+	args := mock.Called(ctx, accessToken)
+	return args.Get(0).(Introspection), args.Error(1)
+}
+
 type MockStore struct {
 	mock.Mock
 }
@@ -43,6 +79,11 @@ func (mock *MockStore) GetSession(ctx context.Context, refreshToken string) (Ses
 	return args.Get(0).(Session), args.Error(1)
 }
 
+func (mock *MockStore) GetSessionByID(ctx context.Context, id string) (Session, error) {
+	args := mock.Called(ctx, id)
+	return args.Get(0).(Session), args.Error(1)
+}
+
 func (mock *MockStore) GetActiveSessionsCount(ctx context.Context, userID string) (int, error) {
 	args := mock.Called(ctx, userID)
 	return args.Int(0), args.Error(1)
@@ -53,12 +94,32 @@ func (mock *MockStore) RevokeSessions(ctx context.Context, refreshTokens ...stri
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (mock *MockStore) RotateSession(ctx context.Context, sessionID, refreshToken string) (string, error) {
+	args := mock.Called(ctx, sessionID, refreshToken)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) RevokeSessionFamily(ctx context.Context, familyID string) (int64, error) {
+	args := mock.Called(ctx, familyID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (mock *MockStore) RevokeLastNSessions(ctx context.Context, userID string, n int) (int64, error) {
 	args := mock.Called(ctx, userID, n)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (mock *MockStore) GetSessionFamilies(ctx context.Context, userID string) ([]string, error) {
+	args := mock.Called(ctx, userID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (mock *MockStore) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
 	args := mock.Called(ctx, userID)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (mock *MockStore) HashLegacyRefreshTokens(ctx context.Context) (int64, error) {
+	args := mock.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -2,32 +2,174 @@ package session
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/client"
+	"identification-service/pkg/config"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/queue"
+	"identification-service/pkg/role"
 	"identification-service/pkg/token"
 	"identification-service/pkg/user"
+	"identification-service/pkg/webauthn"
+	"strings"
 )
 
-const invalidToken = "NA"
+const (
+	invalidToken     = "NA"
+	sessionIDClaim   = "session_id"
+	actorClaim       = "act"
+	rolesClaim       = "roles"
+	permissionsClaim = "permissions"
+	tenantIDClaim    = "tenant_id"
+)
+
+type RefreshTokenReuseEvent struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
 
 type Service interface {
-	LoginUser(ctx context.Context, email, password string) (string, string, error)
+	LoginUser(ctx context.Context, email, password string, scopes []string) (string, string, string, error)
+	SendMagicLink(ctx context.Context, email, name string) error
+	LoginWithMagicLink(ctx context.Context, magicLinkToken string, scopes []string) (string, string, string, error)
+	CompleteLogin(ctx context.Context, mfaToken, code string, scopes []string) (string, string, error)
+	BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error)
+	FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion, scopes []string) (string, string, error)
+	StartSession(ctx context.Context, userID, scope string) (string, string, error)
 	LogoutUser(ctx context.Context, refreshToken string) error
-	RefreshToken(ctx context.Context, refreshToken string) (string, error)
+	RefreshToken(ctx context.Context, refreshToken string) (string, string, error)
 	RevokeAllSessions(ctx context.Context, userID string) error
+	IntrospectToken(ctx context.Context, accessToken string) (Introspection, error)
+}
+
+type Introspection struct {
+	Active   bool
+	ClientID string
+	TenantID string
+	Actor    string
+	Claims   token.Claims
 }
 
 type sessionService struct {
-	store       Store
-	strategies  map[string]Strategy
-	userService user.Service
-	generator   token.Generator
+	cfg           config.QueueConfig
+	store         Store
+	strategies    map[string]Strategy
+	userService   user.Service
+	clientService client.Service
+	roleService   role.Service
+	mfaService    mfa.Service
+	generator     token.Generator
+	verifier      token.Verifier
+	denylist      token.Denylist
+	queue         queue.Queue
+}
+
+func (ss *sessionService) LoginUser(ctx context.Context, email, password string, scopes []string) (string, string, string, error) {
+	wrap := func(err error) (string, string, string, error) {
+		return invalidToken, invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.LoginUser"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	scopes, err = requestedScopes(cl, scopes)
+	if err != nil {
+		return wrap(err)
+	}
+
+	userID, err := ss.userService.GetUserID(ctx, cl.TenantID, email, password)
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, mfaToken, err := ss.loginUser(ctx, cl, userID, scopes)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return accessToken, refreshToken, mfaToken, nil
+}
+
+func (ss *sessionService) SendMagicLink(ctx context.Context, email, name string) error {
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.SendMagicLink"), err)
+	}
+
+	err = ss.userService.SendMagicLink(ctx, cl.Id, cl.TenantID, email, name, cl.AllowsMagicLinkSignUp())
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Service.SendMagicLink"), err)
+	}
+
+	return nil
 }
 
-func (ss *sessionService) LoginUser(ctx context.Context, email, password string) (string, string, error) {
+func (ss *sessionService) LoginWithMagicLink(ctx context.Context, magicLinkToken string, scopes []string) (string, string, string, error) {
+	wrap := func(err error) (string, string, string, error) {
+		return invalidToken, invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.LoginWithMagicLink"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	scopes, err = requestedScopes(cl, scopes)
+	if err != nil {
+		return wrap(err)
+	}
+
+	userID, err := ss.userService.RedeemMagicLink(ctx, cl.Id, cl.TenantID, magicLinkToken, cl.AllowsMagicLinkSignUp())
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, mfaToken, err := ss.loginUser(ctx, cl, userID, scopes)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return accessToken, refreshToken, mfaToken, nil
+}
+
+func (ss *sessionService) loginUser(ctx context.Context, cl client.Client, userID string, scopes []string) (string, string, string, error) {
+	if err := ss.checkUserEmailVerified(ctx, cl, userID); err != nil {
+		return invalidToken, invalidToken, invalidToken, err
+	}
+
+	mfaEnabled, err := ss.mfaService.IsEnabled(ctx, userID)
+	if err != nil {
+		return invalidToken, invalidToken, invalidToken, err
+	}
+
+	//NOTE: NO SESSION IS STARTED UNTIL THE SECOND FACTOR IS PRESENTED, THE CALLER ONLY GETS A CHALLENGE TO COMPLETE
+	if mfaEnabled {
+		mfaToken, err := ss.mfaService.Challenge(ctx, cl.Id, userID)
+		if err != nil {
+			return invalidToken, invalidToken, invalidToken, err
+		}
+
+		return "", "", mfaToken, nil
+	}
+
+	accessToken, refreshToken, err := ss.startSession(ctx, cl, userID, strings.Join(scopes, " "))
+	if err != nil {
+		return invalidToken, invalidToken, invalidToken, err
+	}
+
+	return accessToken, refreshToken, "", nil
+}
+
+func (ss *sessionService) CompleteLogin(ctx context.Context, mfaToken, code string, scopes []string) (string, string, error) {
 	wrap := func(err error) (string, string, error) {
-		return invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.LoginUser"), err)
+		return invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.CompleteLogin"), err)
 	}
 
 	cl, err := client.FromContext(ctx)
@@ -35,50 +177,147 @@ func (ss *sessionService) LoginUser(ctx context.Context, email, password string)
 		return wrap(err)
 	}
 
-	userID, err := ss.userService.GetUserID(ctx, email, password)
+	scopes, err = requestedScopes(cl, scopes)
 	if err != nil {
 		return wrap(err)
 	}
 
-	activeSessionsCount, err := ss.store.GetActiveSessionsCount(ctx, userID)
+	userID, err := ss.mfaService.VerifyChallenge(ctx, cl.Id, mfaToken, code)
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := ss.startSession(ctx, cl, userID, strings.Join(scopes, " "))
+	if err != nil {
+		return wrap(err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func (ss *sessionService) BeginWebAuthnLogin(ctx context.Context, mfaToken string) (string, webauthn.RequestOptions, error) {
+	challengeID, options, err := ss.mfaService.BeginWebAuthnLogin(ctx, mfaToken)
+	if err != nil {
+		return "", webauthn.RequestOptions{}, erx.WithArgs(erx.Operation("Service.BeginWebAuthnLogin"), err)
+	}
+
+	return challengeID, options, nil
+}
+
+func (ss *sessionService) FinishWebAuthnLogin(ctx context.Context, challengeID string, assertion webauthn.Assertion, scopes []string) (string, string, error) {
+	wrap := func(err error) (string, string, error) {
+		return invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.FinishWebAuthnLogin"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	scopes, err = requestedScopes(cl, scopes)
+	if err != nil {
+		return wrap(err)
+	}
+
+	userID, err := ss.mfaService.FinishWebAuthnLogin(ctx, challengeID, assertion)
+	if err != nil {
+		return wrap(err)
+	}
+
+	u, err := ss.userService.GetUser(ctx, userID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: A PASSKEY IS BOUND TO A RELYING PARTY AND NOT TO A TENANT, SO ITS OWNER MUST STILL BELONG TO THE TENANT OF THE CLIENT
+	if u.TenantID() != cl.TenantID {
+		return wrap(erx.WithArgs(erx.AuthenticationError, fmt.Errorf("user %s does not belong to tenant %s", userID, cl.TenantID)))
+	}
+
+	err = checkEmailVerified(cl, u)
+	if err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := ss.startSession(ctx, cl, userID, strings.Join(scopes, " "))
 	if err != nil {
 		return wrap(err)
 	}
 
+	return accessToken, refreshToken, nil
+}
+
+func (ss *sessionService) StartSession(ctx context.Context, userID, scope string) (string, string, error) {
+	//NOTE: THE CALLER HAS ALREADY AUTHENTICATED THE USER, E.G. THROUGH AN AUTHORIZATION CODE
+	wrap := func(err error) (string, string, error) {
+		return invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.StartSession"), err)
+	}
+
+	cl, err := client.FromContext(ctx)
+	if err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: EVERY OAUTH GRANT WHICH SIGNS A USER IN ENDS HERE, SO THE CLIENT'S VERIFIED EMAIL REQUIREMENT IS ENFORCED FOR ALL OF THEM
+	if err := ss.checkUserEmailVerified(ctx, cl, userID); err != nil {
+		return wrap(err)
+	}
+
+	accessToken, refreshToken, err := ss.startSession(ctx, cl, userID, scope)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+func (ss *sessionService) startSession(ctx context.Context, cl client.Client, userID, scope string) (string, string, error) {
+	activeSessionsCount, err := ss.store.GetActiveSessionsCount(ctx, userID)
+	if err != nil {
+		return invalidToken, invalidToken, err
+	}
+
 	if activeSessionsCount >= cl.MaxActiveSessions() {
 		strategy, ok := ss.strategies[cl.SessionStrategyName()]
 		if !ok {
-			return wrap(fmt.Errorf("invalid sesion strategy %s", cl.SessionStrategyName()))
+			return invalidToken, invalidToken, fmt.Errorf("invalid sesion strategy %s", cl.SessionStrategyName())
 		}
 
 		err = strategy.Apply(ctx, userID, activeSessionsCount, cl.MaxActiveSessions())
 		if err != nil {
-			return wrap(err)
+			return invalidToken, invalidToken, err
 		}
 	}
 
 	refreshToken, err := ss.generator.GenerateRefreshToken()
 	if err != nil {
-		return wrap(err)
+		return invalidToken, invalidToken, err
 	}
 
-	session, err := NewSessionBuilder().UserID(userID).RefreshToken(refreshToken).Build()
+	session, err := NewSessionBuilder().UserID(userID).TenantID(cl.TenantID).ClientID(cl.Id).RefreshToken(refreshToken).Scope(scope).Build()
 	if err != nil {
-		return wrap(err)
+		return invalidToken, invalidToken, err
 	}
 
 	sessionID, err := ss.store.CreateSession(ctx, session)
 	if err != nil {
-		return wrap(err)
+		return invalidToken, invalidToken, err
 	}
 
-	accessToken, err := ss.generator.GenerateAccessToken(
-		cl.AccessTokenTTL(),
-		userID, map[string]string{"session_id": sessionID},
-	)
+	accessTokenClaims, err := ss.accessTokenClaims(ctx, cl, userID, sessionID, scope)
+	if err != nil {
+		return invalidToken, invalidToken, err
+	}
+
+	accessToken, claims, err := ss.generator.GenerateAccessToken(cl.AccessTokenTTL(), userID, cl.SigningKey(), accessTokenClaims)
 
 	if err != nil {
-		return wrap(err)
+		return invalidToken, invalidToken, err
+	}
+
+	//NOTE: A NEW SESSION STARTS ITS OWN FAMILY, SO ITS ID IS THE FAMILY ID
+	if err := ss.denylist.Track(ctx, sessionID, claims); err != nil {
+		return invalidToken, invalidToken, err
 	}
 
 	return accessToken, refreshToken, nil
@@ -94,7 +333,7 @@ func (ss *sessionService) LogoutUser(ctx context.Context, refreshToken string) e
 		return wrap(err)
 	}
 
-	_, err = getValidSession(ctx, cl, ss.store, refreshToken)
+	session, err := getValidSession(ctx, cl, ss.store, refreshToken)
 	if err != nil {
 		return wrap(err)
 	}
@@ -104,12 +343,16 @@ func (ss *sessionService) LogoutUser(ctx context.Context, refreshToken string) e
 		return wrap(err)
 	}
 
+	if err := ss.denylist.DenyGroup(ctx, session.familyID); err != nil {
+		return wrap(err)
+	}
+
 	return nil
 }
 
-func (ss *sessionService) RefreshToken(ctx context.Context, refreshToken string) (string, error) {
-	wrap := func(err error) (string, error) {
-		return invalidToken, erx.WithArgs(erx.Operation("Service.RefreshToken"), err)
+func (ss *sessionService) RefreshToken(ctx context.Context, refreshToken string) (string, string, error) {
+	wrap := func(err error) (string, string, error) {
+		return invalidToken, invalidToken, erx.WithArgs(erx.Operation("Service.RefreshToken"), err)
 	}
 
 	cl, err := client.FromContext(ctx)
@@ -122,35 +365,265 @@ func (ss *sessionService) RefreshToken(ctx context.Context, refreshToken string)
 		return wrap(err)
 	}
 
-	accessToken, err := ss.generator.GenerateAccessToken(
-		cl.AccessTokenTTL(),
-		session.userID,
-		map[string]string{"session_id": session.id},
-	)
+	if session.used {
+		return wrap(ss.revokeReusedSession(ctx, cl, session))
+	}
+
+	sessionID, nextRefreshToken := session.id, refreshToken
 
+	if cl.RotatesRefreshTokens() {
+		nextRefreshToken, err = ss.generator.GenerateRefreshToken()
+		if err != nil {
+			return wrap(err)
+		}
+
+		sessionID, err = ss.store.RotateSession(ctx, session.id, nextRefreshToken)
+		if err != nil {
+			if isNotFound(err) {
+				return wrap(ss.revokeReusedSession(ctx, cl, session))
+			}
+
+			return wrap(err)
+		}
+	}
+
+	accessTokenClaims, err := ss.accessTokenClaims(ctx, cl, session.userID, sessionID, session.scope)
 	if err != nil {
 		return wrap(err)
 	}
 
-	return accessToken, nil
+	accessToken, claims, err := ss.generator.GenerateAccessToken(cl.AccessTokenTTL(), session.userID, cl.SigningKey(), accessTokenClaims)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if err := ss.denylist.Track(ctx, session.familyID, claims); err != nil {
+		return wrap(err)
+	}
+
+	return accessToken, nextRefreshToken, nil
 }
 
-func (ss *sessionService) RevokeAllSessions(ctx context.Context, userID string) error {
-	_, err := ss.store.RevokeAllSessions(ctx, userID)
+func (ss *sessionService) accessTokenClaims(ctx context.Context, cl client.Client, userID, sessionID, scope string) (map[string]string, error) {
+	claims := make(map[string]string)
+
+	//NOTE: USER ATTRIBUTES ARE READ ON EVERY ISSUE, SO A REFRESHED TOKEN CARRIES THE LATEST VALUES
+	if len(cl.ClaimMappings) != 0 {
+		u, err := ss.userService.GetUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		for claim, attribute := range cl.ClaimMappings {
+			claims[claim] = u.Attribute(attribute)
+		}
+	}
+
+	//NOTE: ROLES ARE READ ON EVERY ISSUE AS WELL, SO AN UNASSIGNED ROLE IS DROPPED ON THE NEXT REFRESH
+	grants, err := ss.roleService.GetGrants(ctx, userID, cl.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(grants.Roles) != 0 {
+		claims[rolesClaim] = strings.Join(grants.Roles, " ")
+	}
+
+	if len(grants.Permissions) != 0 {
+		claims[permissionsClaim] = strings.Join(grants.Permissions, " ")
+	}
+
+	claims[tenantIDClaim] = cl.TenantID
+	claims[sessionIDClaim] = sessionID
+
+	if len(scope) != 0 {
+		claims[token.ScopeClaim] = scope
+	}
+
+	return claims, nil
+}
+
+func (ss *sessionService) revokeReusedSession(ctx context.Context, cl client.Client, session Session) error {
+	//NOTE: A USED REFRESH TOKEN BEING PRESENTED AGAIN MEANS IT LEAKED, EVERY SESSION DERIVED FROM THE SAME LOGIN IS REVOKED
+	_, err := ss.store.RevokeSessionFamily(ctx, session.familyID)
 	if err != nil {
+		return err
+	}
+
+	if err := ss.denylist.DenyGroup(ctx, session.familyID); err != nil {
+		return err
+	}
+
+	//NOTE: THE ALERT IS RENDERED WITH THE TEMPLATES OF THE CLIENT THE TOKEN WAS REUSED WITH
+	event, err := json.Marshal(RefreshTokenReuseEvent{
+		UserID:   session.userID,
+		ClientID: cl.Id,
+		Locale:   mailer.OriginFromContext(ctx).Locale,
+	})
+	if err != nil {
+		return err
+	}
+
+	go ss.queue.Push(ss.cfg.RefreshTokenReuseQueueName(), event)
+
+	return erx.WithArgs(erx.AuthenticationError, fmt.Errorf("refresh token reused for session %s", session.id))
+}
+
+func (ss *sessionService) RevokeAllSessions(ctx context.Context, userID string) error {
+	wrap := func(err error) error {
 		return erx.WithArgs(erx.Operation("Service.RevokeAllSessions"), err)
 	}
 
+	familyIDs, err := ss.store.GetSessionFamilies(ctx, userID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	_, err = ss.store.RevokeAllSessions(ctx, userID)
+	if err != nil {
+		return wrap(err)
+	}
+
+	for _, familyID := range familyIDs {
+		if err := ss.denylist.DenyGroup(ctx, familyID); err != nil {
+			return wrap(err)
+		}
+	}
+
+	return nil
+}
+
+func (ss *sessionService) IntrospectToken(ctx context.Context, accessToken string) (Introspection, error) {
+	//NOTE: A TOKEN WHICH CANNOT BE VERIFIED IS REPORTED AS INACTIVE, ONLY INFRASTRUCTURE FAILURES ARE RETURNED AS ERRORS
+	wrap := func(err error) (Introspection, error) {
+		return Introspection{}, erx.WithArgs(erx.Operation("Service.IntrospectToken"), err)
+	}
+
+	keyID, err := ss.verifier.KeyID(accessToken)
+	if err != nil {
+		return Introspection{}, nil
+	}
+
+	key, err := ss.clientService.GetVerificationKey(ctx, keyID)
+	if err != nil {
+		if isNotFound(err) {
+			return Introspection{}, nil
+		}
+
+		return wrap(err)
+	}
+
+	claims, err := ss.verifier.VerifyAccessToken(accessToken, key.PublicKey)
+	if err != nil {
+		return Introspection{}, nil
+	}
+
+	denied, err := ss.denylist.IsDenied(ctx, claims.Jti)
+	if err != nil {
+		return wrap(err)
+	}
+
+	if denied {
+		return Introspection{}, nil
+	}
+
+	//NOTE: TOKENS ISSUED TO A CLIENT ON ITS OWN BEHALF HAVE NO SESSION, THEY STAY VALID UNTIL THEY EXPIRE OR THE CLIENT IS REVOKED
+	//NOTE: THE SAME HOLDS FOR SUCH A TOKEN EXCHANGED BY ANOTHER CLIENT, WHICH SIGNS IT AS THE ACTOR
+	if len(claims.Get(sessionIDClaim)) == 0 && (claims.Subject == key.ClientID || claims.Get(actorClaim) == key.ClientID) {
+		return activeIntrospection(key.ClientID, claims), nil
+	}
+
+	session, err := ss.store.GetSessionByID(ctx, claims.Get(sessionIDClaim))
+	if err != nil {
+		if isNotFound(err) {
+			return Introspection{}, nil
+		}
+
+		return wrap(err)
+	}
+
+	if session.revoked {
+		return Introspection{}, nil
+	}
+
+	//NOTE: A TOKEN OF A SESSION IS ONLY ACTIVE WHEN SIGNED BY THE CLIENT WHICH STARTED IT, OR BY THE CLIENT IT WAS EXCHANGED TO
+	if len(session.clientID) != 0 && session.clientID != key.ClientID && claims.Get(actorClaim) != key.ClientID {
+		return Introspection{}, nil
+	}
+
+	return activeIntrospection(key.ClientID, claims), nil
+}
+
+func activeIntrospection(clientID string, claims token.Claims) Introspection {
+	return Introspection{
+		Active:   true,
+		ClientID: clientID,
+		TenantID: claims.Get(tenantIDClaim),
+		Actor:    claims.Get(actorClaim),
+		Claims:   claims,
+	}
+}
+
+func requestedScopes(cl client.Client, scopes []string) ([]string, error) {
+	//NOTE: A LOGIN WHICH DOES NOT ASK FOR ANY SCOPE IS GRANTED EVERY SCOPE THE CLIENT IS ALLOWED
+	if len(scopes) == 0 {
+		scopes = cl.AllowedScopes
+	}
+
+	if !cl.AllowsScopes(scopes) {
+		return nil, erx.WithArgs(erx.ValidationError, fmt.Errorf("client %s is not allowed the requested scopes", cl.Name))
+	}
+
+	return scopes, nil
+}
+
+func (ss *sessionService) checkUserEmailVerified(ctx context.Context, cl client.Client, userID string) error {
+	if !cl.RequiresVerifiedEmail() {
+		return nil
+	}
+
+	u, err := ss.userService.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return checkEmailVerified(cl, u)
+}
+
+func checkEmailVerified(cl client.Client, u user.User) error {
+	if cl.RequiresVerifiedEmail() && !u.EmailVerified() {
+		return erx.WithArgs(erx.AuthenticationError, fmt.Errorf("email of user %s is not verified", u.ID()))
+	}
+
 	return nil
 }
 
+func isNotFound(err error) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == erx.ResourceNotFoundError
+}
+
 func getValidSession(ctx context.Context, cl client.Client, store Store, refreshToken string) (Session, error) {
+	if !token.IsValidRefreshToken(refreshToken) {
+		return Session{}, erx.WithArgs(erx.AuthenticationError, errors.New("malformed refresh token"))
+	}
+
 	session, err := store.GetSession(ctx, refreshToken)
 	if err != nil {
 		return Session{}, err
 	}
 
-	err = validateSession(cl.SessionTTL(), session, refreshToken)
+	//NOTE: A SESSION CAN ONLY BE USED BY CLIENTS OF THE TENANT IT WAS STARTED IN
+	if session.tenantID != cl.TenantID {
+		return Session{}, erx.WithArgs(erx.AuthenticationError, fmt.Errorf("session %s belongs to another tenant", session.id))
+	}
+
+	//NOTE: SESSIONS STARTED BEFORE THEY WERE BOUND TO A CLIENT HAVE NONE AND STAY USABLE BY EVERY CLIENT OF THEIR TENANT
+	if len(session.clientID) != 0 && session.clientID != cl.Id {
+		return Session{}, erx.WithArgs(erx.AuthenticationError, fmt.Errorf("session %s belongs to another client", session.id))
+	}
+
+	err = validateSession(cl.SessionTTL(), session)
 	if err != nil {
 		return Session{}, err
 	}
@@ -158,19 +631,38 @@ func getValidSession(ctx context.Context, cl client.Client, store Store, refresh
 	return session, nil
 }
 
-func validateSession(sessionTTL int, session Session, refreshToken string) error {
+func validateSession(sessionTTL int, session Session) error {
 	if session.revoked || session.IsExpired(float64(sessionTTL)) {
-		return erx.WithArgs(erx.AuthenticationError, fmt.Errorf("session expired for %s", refreshToken))
+		return erx.WithArgs(erx.AuthenticationError, fmt.Errorf("session %s expired", session.id))
 	}
 
 	return nil
 }
 
-func NewService(store Store, userService user.Service, generator token.Generator, strategies map[string]Strategy) Service {
+func NewService(
+	cfg config.QueueConfig,
+	store Store,
+	userService user.Service,
+	clientService client.Service,
+	roleService role.Service,
+	mfaService mfa.Service,
+	generator token.Generator,
+	verifier token.Verifier,
+	denylist token.Denylist,
+	queue queue.Queue,
+	strategies map[string]Strategy,
+) Service {
 	return &sessionService{
-		store:       store,
-		userService: userService,
-		generator:   generator,
-		strategies:  strategies,
+		cfg:           cfg,
+		store:         store,
+		userService:   userService,
+		clientService: clientService,
+		roleService:   roleService,
+		mfaService:    mfaService,
+		generator:     generator,
+		verifier:      verifier,
+		denylist:      denylist,
+		queue:         queue,
+		strategies:    strategies,
 	}
 }
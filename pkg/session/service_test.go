@@ -2,15 +2,25 @@ package session_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/mailer"
+	"identification-service/pkg/mfa"
+	"identification-service/pkg/password"
+	"identification-service/pkg/queue"
+	"identification-service/pkg/role"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
 	"identification-service/pkg/token"
 	"identification-service/pkg/user"
+	"identification-service/pkg/webauthn"
 	"testing"
 	"time"
 )
@@ -41,27 +51,32 @@ func (st *sessionTest) TestLoginUserSuccess() {
 	sessionID := test.NewUUID()
 	maxActiveSessions := test.RandInt(2, 10)
 	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
 
 	mockStore := &session.MockStore{}
 	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
 	mockStore.On("GetActiveSessionsCount", mock.AnythingOfType("*context.valueCtx"), userID).Return(maxActiveSessions-1, nil)
 
 	mockGenerator := &token.MockGenerator{}
-	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, map[string]string{"session_id": sessionID}).Return(test.NewPasetoToken(), nil)
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
 	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
 
 	mockUserService := &user.MockService{}
-	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 	strategies := map[string]session.Strategy{
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, mockUserService, mockGenerator, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
 	clientData := map[string]interface{}{
 		test.ClientAccessTokenTTLKey:    accessTokenTTL,
 		test.ClientMaxActiveSessionsKey: maxActiveSessions,
+		test.ClientKeyIDKey:             keyID,
+		test.ClientPrivateKeyKey:        []byte(priKey),
 	}
 
 	cl, err := test.NewClient(st.clientCfg, clientData)
@@ -70,7 +85,7 @@ func (st *sessionTest) TestLoginUserSuccess() {
 	ctx, err := client.WithContext(context.Background(), cl)
 	st.Require().NoError(err)
 
-	_, _, err = service.LoginUser(ctx, userEmail, userPassword)
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
 	st.Require().NoError(err)
 }
 
@@ -80,6 +95,9 @@ func (st *sessionTest) TestLoginUserSuccessWhenSessionCountExceed() {
 	sessionID := test.NewUUID()
 	userEmail := test.NewEmail()
 	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
 
 	mockStore := &session.MockStore{}
 	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
@@ -87,20 +105,22 @@ func (st *sessionTest) TestLoginUserSuccessWhenSessionCountExceed() {
 	mockStore.On("RevokeLastNSessions", mock.AnythingOfType("*context.valueCtx"), userID, 1).Return(int64(1), nil)
 
 	mockGenerator := &token.MockGenerator{}
-	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, map[string]string{"session_id": sessionID}).Return(test.NewPasetoToken(), nil)
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
 	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
 
 	mockUserService := &user.MockService{}
-	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 	strategies := map[string]session.Strategy{
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, mockUserService, mockGenerator, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
 	clientData := map[string]interface{}{
+		test.ClientKeyIDKey:          keyID,
 		test.ClientAccessTokenTTLKey: accessTokenTTL,
+		test.ClientPrivateKeyKey:     []byte(priKey),
 	}
 
 	cl, err := test.NewClient(st.clientCfg, clientData)
@@ -109,7 +129,7 @@ func (st *sessionTest) TestLoginUserSuccessWhenSessionCountExceed() {
 	ctx, err := client.WithContext(context.Background(), cl)
 	st.Require().NoError(err)
 
-	_, _, err = service.LoginUser(ctx, userEmail, userPassword)
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
 	st.Require().NoError(err)
 }
 
@@ -127,13 +147,13 @@ func (st *sessionTest) TestLoginUserFailureWhenSessionCountExceed() {
 		Return(int64(0), errors.New("failed to revoke last n sessions"))
 
 	mockUserService := &user.MockService{}
-	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 	strategies := map[string]session.Strategy{
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, mockUserService, &token.MockGenerator{}, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
 	clientData := map[string]interface{}{
 		test.ClientMaxActiveSessionsKey: maxActiveSession,
@@ -145,7 +165,7 @@ func (st *sessionTest) TestLoginUserFailureWhenSessionCountExceed() {
 	ctx, err := client.WithContext(context.Background(), cl)
 	st.Require().NoError(err)
 
-	_, _, err = service.LoginUser(ctx, userEmail, userPassword)
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
 	st.Require().Error(err)
 }
 
@@ -156,9 +176,814 @@ func (st *sessionTest) TestLoginFailureWhenFailedToGetClientFromContext() {
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(&session.MockStore{}),
 	}
 
-	service := session.NewService(&session.MockStore{}, &user.MockService{}, &token.MockGenerator{}, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
-	_, _, err := service.LoginUser(context.Background(), test.NewEmail(), userPassword)
+	_, _, _, err := service.LoginUser(context.Background(), test.NewEmail(), userPassword, nil)
+	st.Require().Error(err)
+}
+
+func (st *sessionTest) TestStartSessionSuccess() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	refreshToken := test.NewRefreshToken()
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.Anything, userID).Return(0, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return("access-token", token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(refreshToken, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey: accessTokenTTL,
+		test.ClientKeyIDKey:          keyID,
+		test.ClientPrivateKeyKey:     []byte(priKey),
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, nextRefreshToken, err := service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().NoError(err)
+
+	st.Assert().Equal("access-token", accessToken)
+	st.Assert().Equal(refreshToken, nextRefreshToken)
+}
+
+func (st *sessionTest) TestStartSessionSuccessWithClaimMappings() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	u, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(test.NewEmail()).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.Anything, userID).Return(0, nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(u, nil)
+
+	claims := map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope, "contact_email": u.Email()}
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, claims).Return("access-token", token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewRefreshToken(), nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey: accessTokenTTL,
+		test.ClientKeyIDKey:          keyID,
+		test.ClientPrivateKeyKey:     []byte(priKey),
+		test.ClientClaimMappingsKey:  map[string]string{"contact_email": user.AttributeEmail},
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, err = service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().NoError(err)
+
+	mockGenerator.AssertExpectations(st.T())
+}
+
+func (st *sessionTest) TestStartSessionSuccessWithRoles() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.Anything, userID).Return(0, nil)
+
+	grants := role.Grants{Roles: []string{"admin", "editor"}, Permissions: []string{"posts:write", "users:read"}}
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("GetGrants", mock.Anything, userID, mock.AnythingOfType("string")).Return(grants, nil)
+
+	claims := map[string]string{
+		"tenant_id":   tenant.DefaultID,
+		"session_id":  sessionID,
+		"scope":       test.ClientScope,
+		"roles":       "admin editor",
+		"permissions": "posts:write users:read",
+	}
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, claims).Return("access-token", token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewRefreshToken(), nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, mockRoleService, newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey: accessTokenTTL,
+		test.ClientKeyIDKey:          keyID,
+		test.ClientPrivateKeyKey:     []byte(priKey),
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, err = service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().NoError(err)
+
+	mockGenerator.AssertExpectations(st.T())
+}
+
+func (st *sessionTest) TestStartSessionFailureWhenFailedToGetGrants() {
+	userID := test.NewUUID()
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(test.NewUUID(), nil)
+	mockStore.On("GetActiveSessionsCount", mock.Anything, userID).Return(0, nil)
+
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("GetGrants", mock.Anything, userID, mock.AnythingOfType("string")).
+		Return(role.Grants{}, errors.New("failed to get grants"))
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewRefreshToken(), nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, mockRoleService, newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, err = service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().Error(err)
+
+	mockGenerator.AssertNotCalled(st.T(), "GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestStartSessionSuccessWhenClientRequiresVerifiedEmail() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+
+	verifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(test.NewEmail()).EmailVerified(true).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.Anything, mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.Anything, userID).Return(0, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", mock.Anything, userID, mock.Anything, mock.Anything).Return("access-token", token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewRefreshToken(), nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(verifiedUser, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientRequireVerifiedEmailKey: true})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, _, err := service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().NoError(err)
+
+	st.Assert().Equal("access-token", accessToken)
+}
+
+func (st *sessionTest) TestStartSessionFailureWhenEmailIsNotVerified() {
+	userID := test.NewUUID()
+
+	unverifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(test.NewEmail()).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.Anything, userID).Return(unverifiedUser, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientRequireVerifiedEmailKey: true})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, err = service.StartSession(ctx, userID, test.ClientScope)
+	st.Require().Error(err)
+	st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
+
+	mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestLoginUserFailureWhenScopeIsNotAllowed() {
+	mockUserService := &user.MockService{}
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, _, err = service.LoginUser(ctx, test.NewEmail(), test.NewPassword(), []string{test.ClientScope, "orders:write"})
+	st.Require().Error(err)
+
+	mockUserService.AssertNotCalled(st.T(), "GetUserID", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestLoginUserSuccessWhenClientRequiresVerifiedEmail() {
+	userPassword := test.NewPassword()
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+	sessionID := test.NewUUID()
+	maxActiveSessions := test.RandInt(2, 10)
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	verifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).EmailVerified(true).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.AnythingOfType("*context.valueCtx"), userID).Return(maxActiveSessions-1, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
+	mockUserService.On("GetUser", mock.AnythingOfType("*context.valueCtx"), userID).Return(verifiedUser, nil)
+
+	strategies := map[string]session.Strategy{
+		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
+	}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey:       accessTokenTTL,
+		test.ClientMaxActiveSessionsKey:    maxActiveSessions,
+		test.ClientKeyIDKey:                keyID,
+		test.ClientPrivateKeyKey:           []byte(priKey),
+		test.ClientRequireVerifiedEmailKey: true,
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
+	st.Require().NoError(err)
+}
+
+func (st *sessionTest) TestLoginUserFailureWhenEmailIsNotVerified() {
+	userPassword := test.NewPassword()
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	unverifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
+	mockUserService.On("GetUser", mock.AnythingOfType("*context.valueCtx"), userID).Return(unverifiedUser, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientRequireVerifiedEmailKey: true})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
+	st.Require().Error(err)
+	st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
+
+	mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestLoginUserReturnsMFATokenWhenMFAIsEnabled() {
+	userPassword := test.NewPassword()
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+	mfaToken := test.RandString(32)
+
+	mockStore := &session.MockStore{}
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.AnythingOfType("*context.valueCtx"), userID).Return(true, nil)
+	mockMFAService.On("Challenge", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("string"), userID).Return(mfaToken, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), mockMFAService, &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, refreshToken, res, err := service.LoginUser(ctx, userEmail, userPassword, nil)
+	st.Require().NoError(err)
+
+	st.Assert().Empty(accessToken)
+	st.Assert().Empty(refreshToken)
+	st.Assert().Equal(mfaToken, res)
+
+	mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestLoginUserFailureWhenMFACheckFails() {
+	userPassword := test.NewPassword()
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.AnythingOfType("*context.valueCtx"), userID).Return(false, errors.New("failed to get totp"))
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, mockUserService, &client.MockService{}, newRoleService(), mockMFAService, &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, _, err = service.LoginUser(ctx, userEmail, userPassword, nil)
+	st.Require().Error(err)
+}
+
+func (st *sessionTest) TestSendMagicLinkSuccess() {
+	userEmail := test.NewEmail()
+	userName := test.RandString(8)
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientAllowMagicLinkSignUpKey: true})
+	st.Require().NoError(err)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("SendMagicLink", mock.AnythingOfType("*context.valueCtx"), cl.Id, tenant.DefaultID, userEmail, userName, true).Return(nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	err = service.SendMagicLink(ctx, userEmail, userName)
+	st.Require().NoError(err)
+}
+
+func (st *sessionTest) TestSendMagicLinkFailure() {
+	userEmail := test.NewEmail()
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	st.Require().NoError(err)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("SendMagicLink", mock.AnythingOfType("*context.valueCtx"), cl.Id, tenant.DefaultID, userEmail, "", false).
+		Return(errors.New("failed to send magic link"))
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	err = service.SendMagicLink(ctx, userEmail, "")
+	st.Require().Error(err)
+
+	err = service.SendMagicLink(context.Background(), userEmail, "")
+	st.Require().Error(err)
+}
+
+func (st *sessionTest) TestLoginWithMagicLinkSuccessWhenSessionCountExceed() {
+	magicLinkToken := test.RandString(64)
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.AnythingOfType("*context.valueCtx"), userID).Return(2, nil)
+	mockStore.On("RevokeLastNSessions", mock.AnythingOfType("*context.valueCtx"), userID, 1).Return(int64(1), nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
+
+	clientData := map[string]interface{}{
+		test.ClientKeyIDKey:                keyID,
+		test.ClientAccessTokenTTLKey:       accessTokenTTL,
+		test.ClientPrivateKeyKey:           []byte(priKey),
+		test.ClientMaxActiveSessionsKey:    2,
+		test.ClientAllowMagicLinkSignUpKey: true,
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("RedeemMagicLink", mock.AnythingOfType("*context.valueCtx"), cl.Id, tenant.DefaultID, magicLinkToken, true).Return(userID, nil)
+
+	strategies := map[string]session.Strategy{
+		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
+	}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, refreshToken, mfaToken, err := service.LoginWithMagicLink(ctx, magicLinkToken, nil)
+	st.Require().NoError(err)
+
+	st.Assert().NotEmpty(accessToken)
+	st.Assert().NotEmpty(refreshToken)
+	st.Assert().Empty(mfaToken)
+
+	mockStore.AssertCalled(st.T(), "RevokeLastNSessions", mock.AnythingOfType("*context.valueCtx"), userID, 1)
+}
+
+func (st *sessionTest) TestLoginWithMagicLinkReturnsMFATokenWhenMFAIsEnabled() {
+	magicLinkToken := test.RandString(64)
+	userID := test.NewUUID()
+	mfaToken := test.RandString(32)
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("RedeemMagicLink", mock.AnythingOfType("*context.valueCtx"), cl.Id, tenant.DefaultID, magicLinkToken, false).Return(userID, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.AnythingOfType("*context.valueCtx"), userID).Return(true, nil)
+	mockMFAService.On("Challenge", mock.AnythingOfType("*context.valueCtx"), cl.Id, userID).Return(mfaToken, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), mockMFAService, &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, refreshToken, res, err := service.LoginWithMagicLink(ctx, magicLinkToken, nil)
+	st.Require().NoError(err)
+
+	st.Assert().Empty(accessToken)
+	st.Assert().Empty(refreshToken)
+	st.Assert().Equal(mfaToken, res)
+
+	mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestLoginWithMagicLinkFailure() {
+	magicLinkToken := test.RandString(64)
+
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	st.Require().NoError(err)
+
+	testCases := map[string]struct {
+		ctx          func() context.Context
+		scopes       []string
+		userService  func() user.Service
+		expectedKind erx.Kind
+	}{
+		"test failure when failed to get client from context": {
+			ctx:         context.Background,
+			userService: func() user.Service { return &user.MockService{} },
+		},
+		"test failure when scope is not allowed": {
+			scopes:       []string{"admin"},
+			userService:  func() user.Service { return &user.MockService{} },
+			expectedKind: erx.ValidationError,
+		},
+		"test failure when link is invalid": {
+			userService: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("RedeemMagicLink", mock.AnythingOfType("*context.valueCtx"), cl.Id, tenant.DefaultID, magicLinkToken, false).
+					Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid magic link")))
+
+				return mockUserService
+			},
+			expectedKind: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, testCase.userService(), &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			if testCase.ctx != nil {
+				ctx = testCase.ctx()
+			}
+
+			_, _, _, err = service.LoginWithMagicLink(ctx, magicLinkToken, testCase.scopes)
+			st.Require().Error(err)
+
+			if len(testCase.expectedKind) != 0 {
+				st.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+			}
+		})
+	}
+}
+
+func (st *sessionTest) TestCompleteLoginSuccess() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	mfaToken := test.RandString(32)
+	maxActiveSessions := test.RandInt(2, 10)
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.AnythingOfType("*context.valueCtx"), userID).Return(maxActiveSessions-1, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("VerifyChallenge", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("string"), mfaToken, "123456").Return(userID, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), mockMFAService, mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey:    accessTokenTTL,
+		test.ClientMaxActiveSessionsKey: maxActiveSessions,
+		test.ClientKeyIDKey:             keyID,
+		test.ClientPrivateKeyKey:        []byte(priKey),
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, refreshToken, err := service.CompleteLogin(ctx, mfaToken, "123456", nil)
+	st.Require().NoError(err)
+
+	st.Assert().NotEmpty(accessToken)
+	st.Assert().NotEmpty(refreshToken)
+}
+
+func (st *sessionTest) TestCompleteLoginFailure() {
+	mfaToken := test.RandString(32)
+
+	testCases := map[string]struct {
+		scopes     []string
+		mfaService func() mfa.Service
+		expected   erx.Kind
+	}{
+		"test failure when scope is not allowed": {
+			scopes:     []string{"orders:write"},
+			mfaService: func() mfa.Service { return &mfa.MockService{} },
+			expected:   erx.ValidationError,
+		},
+		"test failure when challenge verification fails": {
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("VerifyChallenge", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("string"), mfaToken, "123456").
+					Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid totp code")))
+
+				return mockMFAService
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockStore := &session.MockStore{}
+
+			service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), testCase.mfaService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+			cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+			st.Require().NoError(err)
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			_, _, err = service.CompleteLogin(ctx, mfaToken, "123456", testCase.scopes)
+			st.Require().Error(err)
+			st.Assert().Equal(testCase.expected, err.(*erx.Erx).Kind())
+
+			mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func (st *sessionTest) TestCompleteLoginFailureWhenFailedToGetClientFromContext() {
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	_, _, err := service.CompleteLogin(context.Background(), test.RandString(32), "123456", nil)
+	st.Require().Error(err)
+}
+
+func (st *sessionTest) TestBeginWebAuthnLoginSuccess() {
+	challengeID, mfaToken := test.NewUUID(), test.RandString(32)
+	options := webauthn.RequestOptions{Challenge: test.RandString(43), RelyingPartyID: test.WebAuthnRPID}
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("BeginWebAuthnLogin", mock.Anything, mfaToken).Return(challengeID, options, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, &user.MockService{}, &client.MockService{}, newRoleService(), mockMFAService, &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	id, res, err := service.BeginWebAuthnLogin(context.Background(), mfaToken)
+	st.Require().NoError(err)
+
+	st.Assert().Equal(challengeID, id)
+	st.Assert().Equal(options, res)
+}
+
+func (st *sessionTest) TestFinishWebAuthnLoginSuccess() {
+	userID := test.NewUUID()
+	sessionID := test.NewUUID()
+	challengeID := test.NewUUID()
+	maxActiveSessions := test.RandInt(2, 10)
+	accessTokenTTL := test.RandInt(1, 10)
+	priKey := test.ClientPriKey()
+	keyID := test.NewUUID()
+	signingKey := libcrypto.Key{ID: keyID, State: libcrypto.ActiveKey, PrivateKey: priKey}
+	assertion := webauthn.Assertion{CredentialID: test.RandBytes(16)}
+
+	verifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(test.NewEmail()).EmailVerified(true).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("CreateSession", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("Session")).Return(sessionID, nil)
+	mockStore.On("GetActiveSessionsCount", mock.AnythingOfType("*context.valueCtx"), userID).Return(maxActiveSessions-1, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, signingKey, map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
+	mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
+
+	mockUserService := &user.MockService{}
+	mockUserService.On("GetUser", mock.AnythingOfType("*context.valueCtx"), userID).Return(verifiedUser, nil)
+
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("FinishWebAuthnLogin", mock.AnythingOfType("*context.valueCtx"), challengeID, assertion).Return(userID, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, mockUserService, &client.MockService{}, newRoleService(), mockMFAService, mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey:       accessTokenTTL,
+		test.ClientMaxActiveSessionsKey:    maxActiveSessions,
+		test.ClientKeyIDKey:                keyID,
+		test.ClientPrivateKeyKey:           []byte(priKey),
+		test.ClientRequireVerifiedEmailKey: true,
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	accessToken, refreshToken, err := service.FinishWebAuthnLogin(ctx, challengeID, assertion, nil)
+	st.Require().NoError(err)
+
+	st.Assert().NotEmpty(accessToken)
+	st.Assert().NotEmpty(refreshToken)
+}
+
+func (st *sessionTest) TestFinishWebAuthnLoginFailure() {
+	userID, challengeID := test.NewUUID(), test.NewUUID()
+	assertion := webauthn.Assertion{CredentialID: test.RandBytes(16)}
+
+	newUser := func(tenantID string, emailVerified bool) user.User {
+		u, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).TenantID(tenantID).Name(test.RandString(8)).Email(test.NewEmail()).EmailVerified(emailVerified).Build()
+		st.Require().NoError(err)
+
+		return u
+	}
+
+	testCases := map[string]struct {
+		scopes      []string
+		mfaService  func() mfa.Service
+		userService func() user.Service
+		expected    erx.Kind
+	}{
+		"test failure when scope is not allowed": {
+			scopes:      []string{"orders:write"},
+			mfaService:  func() mfa.Service { return &mfa.MockService{} },
+			userService: func() user.Service { return &user.MockService{} },
+			expected:    erx.ValidationError,
+		},
+		"test failure when assertion verification fails": {
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("FinishWebAuthnLogin", mock.AnythingOfType("*context.valueCtx"), challengeID, assertion).
+					Return("", erx.WithArgs(erx.AuthenticationError, errors.New("invalid assertion signature")))
+
+				return mockMFAService
+			},
+			userService: func() user.Service { return &user.MockService{} },
+			expected:    erx.AuthenticationError,
+		},
+		"test failure when user belongs to another tenant": {
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("FinishWebAuthnLogin", mock.AnythingOfType("*context.valueCtx"), challengeID, assertion).Return(userID, nil)
+
+				return mockMFAService
+			},
+			userService: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("GetUser", mock.AnythingOfType("*context.valueCtx"), userID).Return(newUser(test.NewUUID(), true), nil)
+
+				return mockUserService
+			},
+			expected: erx.AuthenticationError,
+		},
+		"test failure when email is not verified": {
+			mfaService: func() mfa.Service {
+				mockMFAService := &mfa.MockService{}
+				mockMFAService.On("FinishWebAuthnLogin", mock.AnythingOfType("*context.valueCtx"), challengeID, assertion).Return(userID, nil)
+
+				return mockMFAService
+			},
+			userService: func() user.Service {
+				mockUserService := &user.MockService{}
+				mockUserService.On("GetUser", mock.AnythingOfType("*context.valueCtx"), userID).Return(newUser(tenant.DefaultID, false), nil)
+
+				return mockUserService
+			},
+			expected: erx.AuthenticationError,
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			mockStore := &session.MockStore{}
+
+			service := session.NewService(&config.MockQueueConfig{}, mockStore, testCase.userService(), &client.MockService{}, newRoleService(), testCase.mfaService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+			cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientRequireVerifiedEmailKey: true})
+			st.Require().NoError(err)
+
+			ctx, err := client.WithContext(context.Background(), cl)
+			st.Require().NoError(err)
+
+			_, _, err = service.FinishWebAuthnLogin(ctx, challengeID, assertion, testCase.scopes)
+			st.Require().Error(err)
+			st.Assert().Equal(testCase.expected, err.(*erx.Erx).Kind())
+
+			mockStore.AssertNotCalled(st.T(), "CreateSession", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func (st *sessionTest) TestStartSessionFailureWhenFailedToGetClientFromContext() {
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, map[string]session.Strategy{})
+
+	_, _, err := service.StartSession(context.Background(), test.NewUUID(), test.ClientScope)
 	st.Require().Error(err)
 }
 
@@ -190,7 +1015,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			store: func() session.Store { return &session.MockStore{} },
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return("", errors.New("failed to get user id"))
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return("", errors.New("failed to get user id"))
 
 				return mockUserService
 			},
@@ -205,7 +1030,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
@@ -221,7 +1046,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
@@ -236,7 +1061,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
@@ -256,7 +1081,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
@@ -277,7 +1102,7 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
@@ -298,14 +1123,14 @@ func (st *sessionTest) TestLoginUserFailure() {
 			},
 			userService: func() user.Service {
 				mockUserService := &user.MockService{}
-				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), userEmail, userPassword).Return(userID, nil)
+				mockUserService.On("GetUserID", mock.AnythingOfType("*context.valueCtx"), tenant.DefaultID, userEmail, userPassword).Return(userID, nil)
 
 				return mockUserService
 			},
 			generator: func() token.Generator {
 				mockGenerator := &token.MockGenerator{}
 				mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
-				mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, map[string]string{"session_id": sessionID}).Return("", errors.New("failed to generate access token"))
+				mockGenerator.On("GenerateAccessToken", accessTokenTTL, userID, mock.AnythingOfType("libcrypto.Key"), map[string]string{"tenant_id": tenant.DefaultID, "session_id": sessionID, "scope": test.ClientScope}).Return("", token.Claims{}, errors.New("failed to generate access token"))
 
 				return mockGenerator
 			},
@@ -318,18 +1143,18 @@ func (st *sessionTest) TestLoginUserFailure() {
 				test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(testCase.store()),
 			}
 
-			service := session.NewService(testCase.store(), testCase.userService(), testCase.generator(), strategies)
+			service := session.NewService(&config.MockQueueConfig{}, testCase.store(), testCase.userService(), &client.MockService{}, newRoleService(), newMFAService(), testCase.generator(), &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
-			_, _, err := service.LoginUser(ctx, userEmail, userPassword)
+			_, _, _, err := service.LoginUser(ctx, userEmail, userPassword, nil)
 			st.Require().Error(err)
 		})
 	}
 }
 
 func (st *sessionTest) TestLogoutSuccess() {
-	refreshToken := test.NewUUID()
+	refreshToken, familyID := test.NewUUID(), test.NewUUID()
 
-	ss, err := session.NewSessionBuilder().CreatedAt(time.Now()).Build()
+	ss, err := session.NewSessionBuilder().FamilyID(familyID).CreatedAt(time.Now()).Build()
 	st.Require().NoError(err)
 
 	mockStore := &session.MockStore{}
@@ -344,7 +1169,10 @@ func (st *sessionTest) TestLogoutSuccess() {
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, &user.MockService{}, &token.MockGenerator{}, strategies)
+	mockDenylist := &token.MockDenylist{}
+	mockDenylist.On("DenyGroup", mock.Anything, familyID).Return(nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, mockDenylist, &queue.MockQueue{}, strategies)
 
 	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
 	st.Require().NoError(err)
@@ -354,6 +1182,8 @@ func (st *sessionTest) TestLogoutSuccess() {
 
 	err = service.LogoutUser(ctx, refreshToken)
 	st.Require().NoError(err)
+
+	mockDenylist.AssertExpectations(st.T())
 }
 
 func (st *sessionTest) TestLogoutFailureWhenStoreCallFails() {
@@ -410,95 +1240,281 @@ func (st *sessionTest) TestLogoutFailureWhenStoreCallFails() {
 					CreatedAt(time.Now().AddDate(0, -2, -1)).
 					Build()
 
-				st.Require().NoError(err)
+				st.Require().NoError(err)
+
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).
+					Return(ss, nil)
+				return mockStore
+			},
+		},
+		"test failure when revoke session call fails": {
+			ctx: func() context.Context {
+				return ctx
+			},
+			store: func() session.Store {
+				ss, err := session.NewSessionBuilder().CreatedAt(time.Now()).Build()
+				st.Require().NoError(err)
+
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).
+					Return(ss, nil)
+				mockStore.On(
+					"RevokeSessions",
+					mock.AnythingOfType("*context.valueCtx"),
+					[]string{refreshToken},
+				).Return(int64(0), errors.New("failed to revoke session"))
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			strategies := map[string]session.Strategy{
+				test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(testCase.store()),
+			}
+
+			svc := session.NewService(&config.MockQueueConfig{}, testCase.store(), &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
+
+			err := svc.LogoutUser(testCase.ctx(), refreshToken)
+			st.Assert().Error(err)
+		})
+	}
+
+	//mockStore := &session.MockStore{}
+	//mockStore.On(
+	//	"RevokeSessions",
+	//	mock.AnythingOfType("*context.emptyCtx"),
+	//	[]string{refreshToken},
+	//).Return(int64(0), errors.New("failed to revoke session"))
+	//
+	//
+	//cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
+	//st.Require().NoError(err)
+	//
+	//ctx, err := client.WithContext(context.Background(), cl)
+	//st.Require().NoError(err)
+
+}
+
+func (st *sessionTest) TestRefreshTokenSuccess() {
+	refreshToken := test.NewUUID()
+	accessTokenTTL := test.RandInt(1, 10)
+
+	ss, err := session.NewSessionBuilder().CreatedAt(time.Now()).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).Return(ss, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, mock.AnythingOfType("string"), mock.AnythingOfType("libcrypto.Key"), mock.AnythingOfType("map[string]string")).Return(test.NewPasetoToken(), token.Claims{}, nil)
+
+	strategies := map[string]session.Strategy{
+		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
+	}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey: accessTokenTTL,
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	st.Require().NoError(err)
+}
+
+func (st *sessionTest) TestRefreshTokenSuccessWhenClientRotatesRefreshTokens() {
+	refreshToken, nextRefreshToken := test.NewUUID(), test.NewUUID()
+	sessionID, nextSessionID := test.NewUUID(), test.NewUUID()
+	accessTokenTTL := test.RandInt(1, 10)
+
+	ss, err := session.NewSessionBuilder().ID(sessionID).Scope(test.ClientScope).CreatedAt(time.Now()).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("GetSession", mock.Anything, refreshToken).Return(ss, nil)
+	mockStore.On("RotateSession", mock.Anything, sessionID, nextRefreshToken).Return(nextSessionID, nil)
+
+	mockGenerator := &token.MockGenerator{}
+	mockGenerator.On("GenerateRefreshToken").Return(nextRefreshToken, nil)
+	mockGenerator.On("GenerateAccessToken", accessTokenTTL, mock.AnythingOfType("string"), mock.AnythingOfType("libcrypto.Key"), map[string]string{"tenant_id": tenant.DefaultID, "session_id": nextSessionID, "scope": test.ClientScope}).Return(test.NewPasetoToken(), token.Claims{}, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, nil)
+
+	clientData := map[string]interface{}{
+		test.ClientAccessTokenTTLKey:      accessTokenTTL,
+		test.ClientRotateRefreshTokensKey: true,
+	}
+
+	cl, err := test.NewClient(st.clientCfg, clientData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
+
+	_, rt, err := service.RefreshToken(ctx, refreshToken)
+	st.Require().NoError(err)
+	st.Assert().Equal(nextRefreshToken, rt)
+
+	mockStore.AssertExpectations(st.T())
+}
+
+func (st *sessionTest) TestRefreshTokenRevokesFamilyOnReuse() {
+	refreshToken := test.NewUUID()
+	sessionID, familyID, userID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	used, err := session.NewSessionBuilder().ID(sessionID).FamilyID(familyID).UserID(userID).Used(true).CreatedAt(time.Now()).Build()
+	st.Require().NoError(err)
+
+	unused, err := session.NewSessionBuilder().ID(sessionID).FamilyID(familyID).UserID(userID).CreatedAt(time.Now()).Build()
+	st.Require().NoError(err)
 
-				mockStore := &session.MockStore{}
-				mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).
-					Return(ss, nil)
-				return mockStore
-			},
+	testCases := map[string]struct {
+		session   session.Session
+		store     func(*session.MockStore)
+		generator func() token.Generator
+	}{
+		"test reuse when session is already used": {
+			session:   used,
+			store:     func(*session.MockStore) {},
+			generator: func() token.Generator { return &token.MockGenerator{} },
 		},
-		"test failure when revoke session call fails": {
-			ctx: func() context.Context {
-				return ctx
+		"test reuse when session is used by a concurrent refresh": {
+			session: unused,
+			store: func(mockStore *session.MockStore) {
+				mockStore.On("RotateSession", mock.Anything, sessionID, mock.AnythingOfType("string")).
+					Return("", erx.WithArgs(erx.ResourceNotFoundError, errors.New("no unused session found")))
 			},
-			store: func() session.Store {
-				ss, err := session.NewSessionBuilder().CreatedAt(time.Now()).Build()
-				st.Require().NoError(err)
-
-				mockStore := &session.MockStore{}
-				mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).
-					Return(ss, nil)
-				mockStore.On(
-					"RevokeSessions",
-					mock.AnythingOfType("*context.valueCtx"),
-					[]string{refreshToken},
-				).Return(int64(0), errors.New("failed to revoke session"))
-				return mockStore
+			generator: func() token.Generator {
+				mockGenerator := &token.MockGenerator{}
+				mockGenerator.On("GenerateRefreshToken").Return(test.NewUUID(), nil)
+				return mockGenerator
 			},
 		},
 	}
 
+	cl, err := test.NewClient(st.clientCfg, map[string]interface{}{test.ClientRotateRefreshTokensKey: true})
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(mailer.WithOrigin(context.Background(), mailer.Origin{Locale: "pt-br"}), cl)
+	st.Require().NoError(err)
+
+	event, err := json.Marshal(session.RefreshTokenReuseEvent{UserID: userID, ClientID: cl.Id, Locale: "pt-br"})
+	st.Require().NoError(err)
+
 	for name, testCase := range testCases {
 		st.Run(name, func() {
-			strategies := map[string]session.Strategy{
-				test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(testCase.store()),
-			}
+			mockStore := &session.MockStore{}
+			mockStore.On("GetSession", mock.Anything, refreshToken).Return(testCase.session, nil)
+			mockStore.On("RevokeSessionFamily", mock.Anything, familyID).Return(int64(2), nil)
+			testCase.store(mockStore)
 
-			svc := session.NewService(testCase.store(), &user.MockService{}, &token.MockGenerator{}, strategies)
+			mockQueueConfig := &config.MockQueueConfig{}
+			mockQueueConfig.On("RefreshTokenReuseQueueName").Return("refresh-token-reuse")
 
-			err := svc.LogoutUser(testCase.ctx(), refreshToken)
-			st.Assert().Error(err)
-		})
-	}
+			pushed := make(chan []byte, 1)
 
-	//mockStore := &session.MockStore{}
-	//mockStore.On(
-	//	"RevokeSessions",
-	//	mock.AnythingOfType("*context.emptyCtx"),
-	//	[]string{refreshToken},
-	//).Return(int64(0), errors.New("failed to revoke session"))
-	//
-	//
-	//cl, err := test.NewClient(st.clientCfg, map[string]interface{}{})
-	//st.Require().NoError(err)
-	//
-	//ctx, err := client.WithContext(context.Background(), cl)
-	//st.Require().NoError(err)
+			mockQueue := &queue.MockQueue{}
+			mockQueue.On("Push", "refresh-token-reuse", mock.Anything).
+				Run(func(args mock.Arguments) { pushed <- args.Get(1).([]byte) }).
+				Return(nil)
+
+			service := session.NewService(mockQueueConfig, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), testCase.generator(), &token.MockVerifier{}, newDenylist(), mockQueue, nil)
+
+			_, _, err := service.RefreshToken(ctx, refreshToken)
+			st.Require().Error(err)
+			st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
 
+			st.Assert().Equal(event, <-pushed)
+			mockStore.AssertExpectations(st.T())
+		})
+	}
 }
 
-func (st *sessionTest) TestRefreshTokenSuccess() {
+func (st *sessionTest) TestRefreshTokenFailureWhenSessionBelongsToAnotherTenant() {
 	refreshToken := test.NewUUID()
-	accessTokenTTL := test.RandInt(1, 10)
 
-	ss, err := session.NewSessionBuilder().CreatedAt(time.Now()).Build()
+	ss, err := session.NewSessionBuilder().TenantID(test.NewUUID()).CreatedAt(time.Now()).Build()
 	st.Require().NoError(err)
 
 	mockStore := &session.MockStore{}
-	mockStore.On("GetSession", mock.AnythingOfType("*context.valueCtx"), refreshToken).Return(ss, nil)
+	mockStore.On("GetSession", mock.Anything, refreshToken).Return(ss, nil)
 
 	mockGenerator := &token.MockGenerator{}
-	mockGenerator.On("GenerateAccessToken", accessTokenTTL, mock.AnythingOfType("string"), mock.AnythingOfType("map[string]string")).Return(test.NewPasetoToken(), nil)
 
-	strategies := map[string]session.Strategy{
-		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
-	}
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, nil)
 
-	service := session.NewService(mockStore, &user.MockService{}, mockGenerator, strategies)
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
 
-	clientData := map[string]interface{}{
-		test.ClientAccessTokenTTLKey: accessTokenTTL,
-	}
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
 
-	cl, err := test.NewClient(st.clientCfg, clientData)
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	st.Require().Error(err)
+	st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
+
+	mockGenerator.AssertNotCalled(st.T(), "GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestRefreshTokenFailureWhenSessionIsRevoked() {
+	refreshToken, sessionID := test.NewRefreshToken(), test.NewUUID()
+
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
+
+	ss, err := session.NewSessionBuilder().ID(sessionID).TenantID(cl.TenantID).ClientID(cl.Id).CreatedAt(time.Now()).Revoked(true).Build()
 	st.Require().NoError(err)
 
+	mockStore := &session.MockStore{}
+	mockStore.On("GetSession", mock.Anything, refreshToken).Return(ss, nil)
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, nil)
+
 	ctx, err := client.WithContext(context.Background(), cl)
 	st.Require().NoError(err)
 
-	_, err = service.RefreshToken(ctx, refreshToken)
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	st.Require().Error(err)
+
+	st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
+	st.Assert().Contains(err.Error(), sessionID)
+	st.Assert().NotContains(err.Error(), refreshToken)
+}
+
+func (st *sessionTest) TestRefreshTokenFailureWhenSessionBelongsToAnotherClient() {
+	refreshToken := test.NewUUID()
+
+	ss, err := session.NewSessionBuilder().ClientID(test.NewUUID()).CreatedAt(time.Now()).Build()
+	st.Require().NoError(err)
+
+	mockStore := &session.MockStore{}
+	mockStore.On("GetSession", mock.Anything, refreshToken).Return(ss, nil)
+
+	mockGenerator := &token.MockGenerator{}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), mockGenerator, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, nil)
+
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
 	st.Require().NoError(err)
+
+	_, _, err = service.RefreshToken(ctx, refreshToken)
+	st.Require().Error(err)
+	st.Assert().Equal(erx.AuthenticationError, err.(*erx.Erx).Kind())
+
+	mockStore.AssertNotCalled(st.T(), "RotateSession", mock.Anything, mock.Anything, mock.Anything)
+	mockGenerator.AssertNotCalled(st.T(), "GenerateAccessToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
 func (st *sessionTest) TestRefreshTokenFailureWhenFailedToGetClientFromContext() {
@@ -508,10 +1524,27 @@ func (st *sessionTest) TestRefreshTokenFailureWhenFailedToGetClientFromContext()
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, &user.MockService{}, &token.MockGenerator{}, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
+
+	_, _, err := service.RefreshToken(context.Background(), test.NewUUID())
+	st.Require().Error(err)
+}
+
+func (st *sessionTest) TestRefreshTokenFailureWhenRefreshTokenIsMalformed() {
+	mockStore := &session.MockStore{}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, nil)
+
+	cl, err := test.NewClient(st.clientCfg, st.clientDefaultData)
+	st.Require().NoError(err)
+
+	ctx, err := client.WithContext(context.Background(), cl)
+	st.Require().NoError(err)
 
-	_, err := service.RefreshToken(context.Background(), test.NewUUID())
+	_, _, err = service.RefreshToken(ctx, "idr_malformed")
 	st.Require().Error(err)
+
+	mockStore.AssertNotCalled(st.T(), "GetSession", mock.Anything, mock.Anything)
 }
 
 func (st *sessionTest) TestRefreshTokenFailure() {
@@ -592,7 +1625,7 @@ func (st *sessionTest) TestRefreshTokenFailure() {
 			},
 			generator: func() token.Generator {
 				mockGenerator := &token.MockGenerator{}
-				mockGenerator.On("GenerateAccessToken", accessTokenTTL, mock.AnythingOfType("string"), mock.AnythingOfType("map[string]string")).Return("", errors.New("failed to generate token"))
+				mockGenerator.On("GenerateAccessToken", accessTokenTTL, mock.AnythingOfType("string"), mock.AnythingOfType("libcrypto.Key"), mock.AnythingOfType("map[string]string")).Return("", token.Claims{}, errors.New("failed to generate token"))
 
 				return mockGenerator
 			},
@@ -605,9 +1638,9 @@ func (st *sessionTest) TestRefreshTokenFailure() {
 				test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(testCase.store()),
 			}
 
-			service := session.NewService(testCase.store(), &user.MockService{}, testCase.generator(), strategies)
+			service := session.NewService(&config.MockQueueConfig{}, testCase.store(), &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), testCase.generator(), &token.MockVerifier{}, newDenylist(), &queue.MockQueue{}, strategies)
 
-			_, err := service.RefreshToken(ctx, refreshToken)
+			_, _, err := service.RefreshToken(ctx, refreshToken)
 			st.Require().Error(err)
 		})
 	}
@@ -615,38 +1648,329 @@ func (st *sessionTest) TestRefreshTokenFailure() {
 
 func (st *sessionTest) TestRevokeAllSessionsSuccess() {
 	userID := test.NewUUID()
+	familyIDs := []string{test.NewUUID(), test.NewUUID()}
 
 	mockStore := &session.MockStore{}
+	mockStore.On("GetSessionFamilies", mock.Anything, userID).Return(familyIDs, nil)
 	mockStore.On(
 		"RevokeAllSessions",
-		mock.AnythingOfType("*context.emptyCtx"), userID,
+		mock.Anything, userID,
 	).Return(int64(1), nil)
 
+	mockDenylist := &token.MockDenylist{}
+	mockDenylist.On("DenyGroup", mock.Anything, familyIDs[0]).Return(nil)
+	mockDenylist.On("DenyGroup", mock.Anything, familyIDs[1]).Return(nil)
+
 	strategies := map[string]session.Strategy{
 		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
 	}
 
-	service := session.NewService(mockStore, &user.MockService{}, &token.MockGenerator{}, strategies)
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, mockDenylist, &queue.MockQueue{}, strategies)
 
 	err := service.RevokeAllSessions(context.Background(), userID)
 	st.Require().NoError(err)
+
+	mockDenylist.AssertExpectations(st.T())
 }
 
 func (st *sessionTest) TestRevokeAllSessionsFailure() {
 	userID := test.NewUUID()
 
+	testCases := map[string]struct {
+		store    func() session.Store
+		denylist func() token.Denylist
+	}{
+		"test failure when store call fails to get session families": {
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionFamilies", mock.Anything, userID).Return([]string{}, errors.New("failed to get session families"))
+				return mockStore
+			},
+			denylist: func() token.Denylist { return &token.MockDenylist{} },
+		},
+		"test failure when store call fails to revoke sessions": {
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionFamilies", mock.Anything, userID).Return([]string{test.NewUUID()}, nil)
+				mockStore.On(
+					"RevokeAllSessions",
+					mock.Anything, userID,
+				).Return(int64(0), errors.New("failed to revoke all sessions"))
+				return mockStore
+			},
+			denylist: func() token.Denylist { return &token.MockDenylist{} },
+		},
+		"test failure when denylist call fails": {
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionFamilies", mock.Anything, userID).Return([]string{test.NewUUID()}, nil)
+				mockStore.On("RevokeAllSessions", mock.Anything, userID).Return(int64(1), nil)
+				return mockStore
+			},
+			denylist: func() token.Denylist {
+				mockDenylist := &token.MockDenylist{}
+				mockDenylist.On("DenyGroup", mock.Anything, mock.AnythingOfType("string")).Return(errors.New("failed to deny tokens"))
+				return mockDenylist
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			service := session.NewService(&config.MockQueueConfig{}, testCase.store(), &user.MockService{}, &client.MockService{}, newRoleService(), newMFAService(), &token.MockGenerator{}, &token.MockVerifier{}, testCase.denylist(), &queue.MockQueue{}, nil)
+
+			err := service.RevokeAllSessions(context.Background(), userID)
+			st.Require().Error(err)
+		})
+	}
+}
+
+func (st *sessionTest) TestIntrospectTokenSuccess() {
+	userID, sessionID, clientID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	accessToken := newIntrospectionToken(st, userID, sessionID, key)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKey", mock.Anything, key.ID).
+		Return(client.VerificationKey{KeyID: key.ID, ClientID: clientID, State: key.State, PublicKey: key.PublicKey()}, nil)
+
 	mockStore := &session.MockStore{}
-	mockStore.On(
-		"RevokeAllSessions",
-		mock.AnythingOfType("*context.emptyCtx"), userID,
-	).Return(int64(0), errors.New("failed to revoke all sessions"))
+	mockStore.On("GetSessionByID", mock.Anything, sessionID).Return(session.Session{}, nil)
 
-	strategies := map[string]session.Strategy{
-		test.ClientSessionStrategyRevokeOld: session.NewRevokeOldStrategy(mockStore),
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, mockClientService, newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), newDenylist(), &queue.MockQueue{}, nil)
+
+	res, err := service.IntrospectToken(context.Background(), accessToken)
+	st.Require().NoError(err)
+
+	st.Assert().True(res.Active)
+	st.Assert().Equal(clientID, res.ClientID)
+	st.Assert().Equal(userID, res.Claims.Subject)
+	st.Assert().Equal(key.ID, res.Claims.KeyID)
+}
+
+func (st *sessionTest) TestIntrospectTokenSuccessForClientToken() {
+	clientID := test.NewUUID()
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	accessToken, _, err := token.NewGenerator(newIntrospectionTokenConfig()).
+		GenerateAccessToken(10, clientID, key, map[string]string{"scope": test.ClientScope})
+
+	st.Require().NoError(err)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKey", mock.Anything, key.ID).
+		Return(client.VerificationKey{KeyID: key.ID, ClientID: clientID, State: key.State, PublicKey: key.PublicKey()}, nil)
+
+	mockStore := &session.MockStore{}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, mockClientService, newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), newDenylist(), &queue.MockQueue{}, nil)
+
+	res, err := service.IntrospectToken(context.Background(), accessToken)
+	st.Require().NoError(err)
+
+	st.Assert().True(res.Active)
+	st.Assert().Equal(clientID, res.Claims.Subject)
+	mockStore.AssertNotCalled(st.T(), "GetSessionByID", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestIntrospectTokenSuccessForExchangedClientToken() {
+	subject, actorID := test.NewUUID(), test.NewUUID()
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	accessToken, _, err := token.NewGenerator(newIntrospectionTokenConfig()).
+		GenerateAccessToken(10, subject, key, map[string]string{"act": actorID, "aud": test.ClientAudience})
+
+	st.Require().NoError(err)
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKey", mock.Anything, key.ID).
+		Return(client.VerificationKey{KeyID: key.ID, ClientID: actorID, State: key.State, PublicKey: key.PublicKey()}, nil)
+
+	mockStore := &session.MockStore{}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, mockClientService, newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), newDenylist(), &queue.MockQueue{}, nil)
+
+	res, err := service.IntrospectToken(context.Background(), accessToken)
+	st.Require().NoError(err)
+
+	st.Assert().True(res.Active)
+	st.Assert().Equal(subject, res.Claims.Subject)
+	st.Assert().Equal(test.ClientAudience, res.Claims.Audience)
+	mockStore.AssertNotCalled(st.T(), "GetSessionByID", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestIntrospectTokenInactive() {
+	userID, sessionID := test.NewUUID(), test.NewUUID()
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+	_, otherPri := test.GenerateKey()
+	otherKey := libcrypto.Key{ID: key.ID, State: libcrypto.ActiveKey, PrivateKey: otherPri}
+
+	verificationKey := client.VerificationKey{KeyID: key.ID, ClientID: test.NewUUID(), State: key.State, PublicKey: key.PublicKey()}
+
+	revokedSession, err := session.NewSessionBuilder().Revoked(true).Build()
+	st.Require().NoError(err)
+
+	otherClientSession, err := session.NewSessionBuilder().ClientID(test.NewUUID()).Build()
+	st.Require().NoError(err)
+
+	testCases := map[string]struct {
+		accessToken   string
+		clientService func() client.Service
+		store         func() session.Store
+	}{
+		"test inactive when token is malformed": {
+			accessToken:   "invalid token",
+			clientService: func() client.Service { return &client.MockService{} },
+			store:         func() session.Store { return &session.MockStore{} },
+		},
+		"test inactive when key is not found": {
+			accessToken: newIntrospectionToken(st, userID, sessionID, key),
+			clientService: func() client.Service {
+				mockClientService := &client.MockService{}
+				mockClientService.On("GetVerificationKey", mock.Anything, key.ID).
+					Return(client.VerificationKey{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("key not found")))
+				return mockClientService
+			},
+			store: func() session.Store { return &session.MockStore{} },
+		},
+		"test inactive when token is signed by another key": {
+			accessToken: newIntrospectionToken(st, userID, sessionID, otherKey),
+			clientService: func() client.Service {
+				mockClientService := &client.MockService{}
+				mockClientService.On("GetVerificationKey", mock.Anything, key.ID).Return(verificationKey, nil)
+				return mockClientService
+			},
+			store: func() session.Store { return &session.MockStore{} },
+		},
+		"test inactive when session is not found": {
+			accessToken: newIntrospectionToken(st, userID, sessionID, key),
+			clientService: func() client.Service {
+				mockClientService := &client.MockService{}
+				mockClientService.On("GetVerificationKey", mock.Anything, key.ID).Return(verificationKey, nil)
+				return mockClientService
+			},
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionByID", mock.Anything, sessionID).
+					Return(session.Session{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("session not found")))
+				return mockStore
+			},
+		},
+		"test inactive when session is revoked": {
+			accessToken: newIntrospectionToken(st, userID, sessionID, key),
+			clientService: func() client.Service {
+				mockClientService := &client.MockService{}
+				mockClientService.On("GetVerificationKey", mock.Anything, key.ID).Return(verificationKey, nil)
+				return mockClientService
+			},
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionByID", mock.Anything, sessionID).Return(revokedSession, nil)
+				return mockStore
+			},
+		},
+		"test inactive when session belongs to another client": {
+			accessToken: newIntrospectionToken(st, userID, sessionID, key),
+			clientService: func() client.Service {
+				mockClientService := &client.MockService{}
+				mockClientService.On("GetVerificationKey", mock.Anything, key.ID).Return(verificationKey, nil)
+				return mockClientService
+			},
+			store: func() session.Store {
+				mockStore := &session.MockStore{}
+				mockStore.On("GetSessionByID", mock.Anything, sessionID).Return(otherClientSession, nil)
+				return mockStore
+			},
+		},
 	}
 
-	service := session.NewService(mockStore, &user.MockService{}, &token.MockGenerator{}, strategies)
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			service := session.NewService(&config.MockQueueConfig{}, testCase.store(), &user.MockService{}, testCase.clientService(), newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), newDenylist(), &queue.MockQueue{}, nil)
 
-	err := service.RevokeAllSessions(context.Background(), userID)
+			res, err := service.IntrospectToken(context.Background(), testCase.accessToken)
+			st.Require().NoError(err)
+
+			st.Assert().False(res.Active)
+		})
+	}
+}
+
+func (st *sessionTest) TestIntrospectTokenInactiveWhenTokenIsDenied() {
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+	verificationKey := client.VerificationKey{KeyID: key.ID, ClientID: test.NewUUID(), State: key.State, PublicKey: key.PublicKey()}
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKey", mock.Anything, key.ID).Return(verificationKey, nil)
+
+	mockDenylist := &token.MockDenylist{}
+	mockDenylist.On("IsDenied", mock.Anything, mock.AnythingOfType("string")).Return(true, nil)
+
+	mockStore := &session.MockStore{}
+
+	service := session.NewService(&config.MockQueueConfig{}, mockStore, &user.MockService{}, mockClientService, newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), mockDenylist, &queue.MockQueue{}, nil)
+
+	res, err := service.IntrospectToken(context.Background(), newIntrospectionToken(st, test.NewUUID(), test.NewUUID(), key))
+	st.Require().NoError(err)
+
+	st.Assert().False(res.Active)
+	mockStore.AssertNotCalled(st.T(), "GetSessionByID", mock.Anything, mock.Anything)
+}
+
+func (st *sessionTest) TestIntrospectTokenFailureWhenKeyLookupFails() {
+	key := libcrypto.Key{ID: test.NewUUID(), State: libcrypto.ActiveKey, PrivateKey: test.ClientPriKey()}
+
+	mockClientService := &client.MockService{}
+	mockClientService.On("GetVerificationKey", mock.Anything, key.ID).
+		Return(client.VerificationKey{}, errors.New("failed to get key"))
+
+	service := session.NewService(&config.MockQueueConfig{}, &session.MockStore{}, &user.MockService{}, mockClientService, newRoleService(), newMFAService(), &token.MockGenerator{}, newIntrospectionVerifier(), newDenylist(), &queue.MockQueue{}, nil)
+
+	_, err := service.IntrospectToken(context.Background(), newIntrospectionToken(st, test.NewUUID(), test.NewUUID(), key))
 	st.Require().Error(err)
 }
+
+func newIntrospectionTokenConfig() config.TokenConfig {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("user")
+	mockTokenConfig.On("Issuer").Return("identification-service")
+	return mockTokenConfig
+}
+
+func newIntrospectionVerifier() token.Verifier {
+	return token.NewVerifier(newIntrospectionTokenConfig())
+}
+
+func newIntrospectionToken(st *sessionTest, userID, sessionID string, key libcrypto.Key) string {
+	accessToken, _, err := token.NewGenerator(newIntrospectionTokenConfig()).
+		GenerateAccessToken(10, userID, key, map[string]string{"session_id": sessionID})
+
+	st.Require().NoError(err)
+
+	return accessToken
+}
+
+func newRoleService() role.Service {
+	mockRoleService := &role.MockService{}
+	mockRoleService.On("GetGrants", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		Return(role.Grants{}, nil)
+
+	return mockRoleService
+}
+
+func newMFAService() mfa.Service {
+	mockMFAService := &mfa.MockService{}
+	mockMFAService.On("IsEnabled", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+	return mockMFAService
+}
+
+func newDenylist() token.Denylist {
+	mockDenylist := &token.MockDenylist{}
+	mockDenylist.On("Track", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("token.Claims")).Return(nil)
+	mockDenylist.On("DenyGroup", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+	mockDenylist.On("IsDenied", mock.Anything, mock.AnythingOfType("string")).Return(false, nil)
+
+	return mockDenylist
+}
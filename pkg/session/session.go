@@ -4,17 +4,24 @@ import (
 	"errors"
 	"fmt"
 	"github.com/nsnikhil/erx"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/token"
 	"identification-service/pkg/util"
 	"time"
 )
 
 type Session struct {
-	id string
+	id       string
+	familyID string
 
 	userID       string
+	tenantID     string
+	clientID     string
 	refreshToken string
+	scope        string
 
 	revoked bool
+	used    bool
 
 	createdAt time.Time
 	updatedAt time.Time
@@ -25,12 +32,17 @@ func (s Session) IsExpired(ttl float64) bool {
 }
 
 type Builder struct {
-	id string
+	id       string
+	familyID string
 
 	userID       string
+	tenantID     string
+	clientID     string
 	refreshToken string
+	scope        string
 
 	revoked bool
+	used    bool
 
 	createdAt time.Time
 	updatedAt time.Time
@@ -52,6 +64,20 @@ func (b *Builder) ID(id string) *Builder {
 	return b
 }
 
+func (b *Builder) FamilyID(familyID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !util.IsValidUUID(familyID) {
+		b.err = fmt.Errorf("invalid family id %s", familyID)
+		return b
+	}
+
+	b.familyID = familyID
+	return b
+}
+
 func (b *Builder) UserID(userID string) *Builder {
 	if b.err != nil {
 		return b
@@ -66,13 +92,41 @@ func (b *Builder) UserID(userID string) *Builder {
 	return b
 }
 
+func (b *Builder) TenantID(tenantID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !util.IsValidUUID(tenantID) {
+		b.err = fmt.Errorf("invalid tenant id %s", tenantID)
+		return b
+	}
+
+	b.tenantID = tenantID
+	return b
+}
+
+func (b *Builder) ClientID(clientID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !util.IsValidUUID(clientID) {
+		b.err = fmt.Errorf("invalid client id %s", clientID)
+		return b
+	}
+
+	b.clientID = clientID
+	return b
+}
+
 func (b *Builder) RefreshToken(refreshToken string) *Builder {
 	if b.err != nil {
 		return b
 	}
 
-	if !util.IsValidUUID(refreshToken) {
-		b.err = fmt.Errorf("invalid id %s", refreshToken)
+	if !token.IsValidRefreshToken(refreshToken) {
+		b.err = errors.New("invalid refresh token")
 		return b
 	}
 
@@ -80,6 +134,15 @@ func (b *Builder) RefreshToken(refreshToken string) *Builder {
 	return b
 }
 
+func (b *Builder) Scope(scope string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.scope = scope
+	return b
+}
+
 func (b *Builder) Revoked(revoked bool) *Builder {
 	if b.err != nil {
 		return b
@@ -89,6 +152,15 @@ func (b *Builder) Revoked(revoked bool) *Builder {
 	return b
 }
 
+func (b *Builder) Used(used bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.used = used
+	return b
+}
+
 func (b *Builder) CreatedAt(createdAt time.Time) *Builder {
 	if b.err != nil {
 		return b
@@ -124,14 +196,19 @@ func (b *Builder) Build() (Session, error) {
 
 	return Session{
 		id:           b.id,
+		familyID:     b.familyID,
 		userID:       b.userID,
+		tenantID:     b.tenantID,
+		clientID:     b.clientID,
 		refreshToken: b.refreshToken,
+		scope:        b.scope,
 		revoked:      b.revoked,
+		used:         b.used,
 		createdAt:    b.createdAt,
 		updatedAt:    b.updatedAt,
 	}, nil
 }
 
 func NewSessionBuilder() *Builder {
-	return &Builder{}
+	return &Builder{tenantID: tenant.DefaultID}
 }
@@ -11,6 +11,7 @@ import (
 
 const (
 	idKey           = "id"
+	familyIDKey     = "familyID"
 	userIDKey       = "userID"
 	refreshTokenKey = "refreshToken"
 	revokedKey      = "revoked"
@@ -21,16 +22,21 @@ const (
 func TestCreateNewSessionSuccess(t *testing.T) {
 	_, err := session.NewSessionBuilder().UserID(test.NewUUID()).RefreshToken(test.NewUUID()).Build()
 	require.NoError(t, err)
+
+	_, err = session.NewSessionBuilder().UserID(test.NewUUID()).RefreshToken(test.NewRefreshToken()).Build()
+	require.NoError(t, err)
 }
 
 func TestCreateNewSessionValidationFailure(t *testing.T) {
 	testCases := map[string]map[string]interface{}{
 		"test failure when id is empty":                     {idKey: ""},
 		"test failure when id is invalid":                   {idKey: "invalid id"},
+		"test failure when familyID is invalid":             {familyIDKey: "invalid id"},
 		"test failure when userID is empty":                 {userIDKey: ""},
 		"test failure when userID is invalid":               {userIDKey: "invalid id"},
 		"test failure when refreshToken is empty":           {refreshTokenKey: ""},
 		"test failure when refreshToken is invalid":         {refreshTokenKey: "invalid id"},
+		"test failure when refreshToken checksum is wrong":  {refreshTokenKey: test.NewRefreshToken()[:51] + "00000000"},
 		"test failure when created at is set to zero value": {createdAtKey: time.Time{}},
 		"test failure when updated at is set to zero value": {updatedAtKey: time.Time{}},
 	}
@@ -54,6 +60,7 @@ func buildSession(d map[string]interface{}) (session.Session, error) {
 
 	return session.NewSessionBuilder().
 		ID(either(d[idKey], test.NewUUID()).(string)).
+		FamilyID(either(d[familyIDKey], test.NewUUID()).(string)).
 		UserID(either(d[userIDKey], test.NewUUID()).(string)).
 		RefreshToken(either(d[refreshTokenKey], test.NewUUID()).(string)).
 		Revoked(either(d[revokedKey], false).(bool)).
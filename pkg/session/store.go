@@ -5,40 +5,56 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/database"
+	"identification-service/pkg/token"
 	"strings"
 )
 
 const (
-	createSession          = `insert into sessions (user_id, refresh_token) values ($1, $2) returning id`
-	getSession             = `select id, user_id, revoked, created_at, updated_at from sessions where refresh_token=$1`
-	getActiveSessionsCount = `select count(*) from sessions where user_id=$1 and revoked=false`
-	revokeSessions         = `update sessions set revoked=true where refresh_token = ANY($1::uuid[])`
-	getLastNRefreshTokens  = `select refresh_token from sessions where user_id=$1 and revoked=false order by created_at asc limit $2`
+	createSession          = `insert into sessions (user_id, tenant_id, client_id, refresh_token, scope) values ($1, $2, $3, $4, $5) returning id`
+	getSession             = `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where refresh_token=$1`
+	getSessionByID         = `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where id=$1`
+	getActiveSessionsCount = `select count(*) from sessions where user_id=$1 and revoked=false and used=false`
+	revokeSessions         = `update sessions set revoked=true where refresh_token = ANY($1::text[])`
+	getLastNRefreshTokens  = `select refresh_token from sessions where user_id=$1 and revoked=false and used=false order by created_at asc limit $2`
 	revokeAllSessions      = `update sessions set revoked=true where user_id=$1`
+	getSessionFamilies     = `select distinct coalesce(family_id, id) from sessions where user_id=$1 and revoked=false`
+	rotateSession          = `with used_session as (update sessions set used=true where id=$1 and used=false and revoked=false returning user_id, tenant_id, client_id, coalesce(family_id, id) as family_id, scope, created_at) insert into sessions (user_id, tenant_id, client_id, refresh_token, family_id, scope, created_at) select user_id, tenant_id, client_id, $2, family_id, scope, created_at from used_session returning id`
+	revokeSessionFamily    = `update sessions set revoked=true where coalesce(family_id, id)=$1`
+	getLegacyRefreshTokens = `select id, refresh_token from sessions where refresh_token ~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'`
+	hashRefreshTokens      = `update sessions s set refresh_token=v.refresh_token from unnest($1::uuid[], $2::text[]) as v(id, refresh_token) where s.id=v.id`
 )
 
 type Store interface {
 	CreateSession(ctx context.Context, session Session) (string, error)
 	GetSession(ctx context.Context, refreshToken string) (Session, error)
+	GetSessionByID(ctx context.Context, id string) (Session, error)
 	GetActiveSessionsCount(ctx context.Context, userID string) (int, error)
 	RevokeSessions(ctx context.Context, refreshTokens ...string) (int64, error)
 
+	RotateSession(ctx context.Context, sessionID, refreshToken string) (string, error)
+	RevokeSessionFamily(ctx context.Context, familyID string) (int64, error)
+
+	GetSessionFamilies(ctx context.Context, userID string) ([]string, error)
 	RevokeAllSessions(ctx context.Context, userID string) (int64, error)
 
 	//TODO: REFACTOR
 	RevokeLastNSessions(ctx context.Context, userID string, n int) (int64, error)
+
+	HashLegacyRefreshTokens(ctx context.Context) (int64, error)
 }
 
 type sessionStore struct {
-	db database.SQLDatabase
+	db     database.SQLDatabase
+	hasher token.Hasher
 }
 
 func (ss *sessionStore) CreateSession(ctx context.Context, session Session) (string, error) {
 	var sessionID string
 
-	err := ss.db.QueryRowContext(ctx, createSession, session.userID, session.refreshToken).Scan(&sessionID)
+	err := ss.db.QueryRowContext(ctx, createSession, session.userID, session.tenantID, session.clientID, ss.hasher.Hash(session.refreshToken), session.scope).Scan(&sessionID)
 	if err != nil {
 		return "", erx.WithArgs(erx.Operation("Store.CreateSession"), err)
 	}
@@ -47,20 +63,39 @@ func (ss *sessionStore) CreateSession(ctx context.Context, session Session) (str
 }
 
 func (ss *sessionStore) GetSession(ctx context.Context, refreshToken string) (Session, error) {
+	return ss.getSession(ctx, "Store.GetSession", getSession, ss.hasher.Hash(refreshToken))
+}
+
+func (ss *sessionStore) GetSessionByID(ctx context.Context, id string) (Session, error) {
+	return ss.getSession(ctx, "Store.GetSessionByID", getSessionByID, id)
+}
+
+func (ss *sessionStore) getSession(ctx context.Context, op erx.Operation, query string, arg string) (Session, error) {
 	var session Session
 
-	row := ss.db.QueryRowContext(ctx, getSession, refreshToken)
+	row := ss.db.QueryRowContext(ctx, query, arg)
 	if row.Err() != nil {
-		return session, erx.WithArgs(erx.Operation("Store.GetSession"), row.Err())
+		return session, erx.WithArgs(op, row.Err())
 	}
 
 	//TODO: REMOVE NESTED CHECKS HERE
-	err := row.Scan(&session.id, &session.userID, &session.revoked, &session.createdAt, &session.updatedAt)
+	err := row.Scan(
+		&session.id,
+		&session.familyID,
+		&session.userID,
+		&session.tenantID,
+		&session.clientID,
+		&session.scope,
+		&session.revoked,
+		&session.used,
+		&session.createdAt,
+		&session.updatedAt,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return session, erx.WithArgs(erx.Operation("Store.GetSession"), erx.ResourceNotFoundError, err)
+			return session, erx.WithArgs(op, erx.ResourceNotFoundError, err)
 		}
-		return session, erx.WithArgs(erx.Operation("Store.GetSession"), err)
+		return session, erx.WithArgs(op, err)
 	}
 
 	return session, nil
@@ -78,21 +113,71 @@ func (ss *sessionStore) GetActiveSessionsCount(ctx context.Context, userID strin
 }
 
 func (ss *sessionStore) RevokeSessions(ctx context.Context, refreshTokens ...string) (int64, error) {
-	res, err := ss.db.ExecContext(ctx, revokeSessions, toArgs(refreshTokens))
+	hashes := make([]string, len(refreshTokens))
+	for i, refreshToken := range refreshTokens {
+		hashes[i] = ss.hasher.Hash(refreshToken)
+	}
+
+	return ss.revokeSessions(ctx, "Store.RevokeSession", hashes)
+}
+
+func (ss *sessionStore) revokeSessions(ctx context.Context, op erx.Operation, hashes []string) (int64, error) {
+	res, err := ss.db.ExecContext(ctx, revokeSessions, toArgs(hashes))
+	if err != nil {
+		return 0, erx.WithArgs(op, err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return 0, erx.WithArgs(op, err)
+	}
+
+	if c == 0 {
+		return 0, erx.WithArgs(
+			op,
+			erx.ResourceNotFoundError,
+			fmt.Errorf("no session found for %d refresh tokens", len(hashes)),
+		)
+	}
+
+	return c, nil
+}
+
+func (ss *sessionStore) RotateSession(ctx context.Context, sessionID, refreshToken string) (string, error) {
+	var newSessionID string
+
+	//NOTE: THE SESSION IS MARKED USED AND ITS SUCCESSOR INSERTED IN ONE STATEMENT, SO ONLY ONE OF TWO CONCURRENT REFRESHES CAN WIN
+	err := ss.db.QueryRowContext(ctx, rotateSession, sessionID, ss.hasher.Hash(refreshToken)).Scan(&newSessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", erx.WithArgs(
+				erx.Operation("Store.RotateSession"),
+				erx.ResourceNotFoundError,
+				fmt.Errorf("no unused session found for id %s", sessionID),
+			)
+		}
+		return "", erx.WithArgs(erx.Operation("Store.RotateSession"), err)
+	}
+
+	return newSessionID, nil
+}
+
+func (ss *sessionStore) RevokeSessionFamily(ctx context.Context, familyID string) (int64, error) {
+	res, err := ss.db.ExecContext(ctx, revokeSessionFamily, familyID)
 	if err != nil {
-		return 0, erx.WithArgs(erx.Operation("Store.RevokeSession"), err)
+		return 0, erx.WithArgs(erx.Operation("Store.RevokeSessionFamily"), err)
 	}
 
 	c, err := res.RowsAffected()
 	if err != nil {
-		return 0, erx.WithArgs(erx.Operation("Store.RevokeSession"), err)
+		return 0, erx.WithArgs(erx.Operation("Store.RevokeSessionFamily"), err)
 	}
 
 	if c == 0 {
 		return 0, erx.WithArgs(
-			erx.Operation("Store.RevokeSession"),
+			erx.Operation("Store.RevokeSessionFamily"),
 			erx.ResourceNotFoundError,
-			fmt.Errorf("no session found for refresh tokens %v", refreshTokens),
+			fmt.Errorf("no sessions found for family %s", familyID),
 		)
 	}
 
@@ -105,27 +190,61 @@ func (ss *sessionStore) RevokeLastNSessions(ctx context.Context, userID string,
 		return 0, erx.WithArgs(erx.Operation("Store.RevokeLastNSessions"), err)
 	}
 
-	var refreshTokens []string
+	defer rows.Close()
+
+	var hashes []string
 
 	for rows.Next() {
-		var refreshToken string
+		var hash string
 
-		err := rows.Scan(&refreshToken)
+		err := rows.Scan(&hash)
 		if err != nil {
 			return 0, erx.WithArgs(erx.Operation("Store.RevokeLastNSessions"), err)
 		}
 
-		refreshTokens = append(refreshTokens, refreshToken)
+		hashes = append(hashes, hash)
 	}
 
-	if len(refreshTokens) == 0 {
+	if err := rows.Err(); err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.RevokeLastNSessions"), err)
+	}
+
+	if len(hashes) == 0 {
 		return 0, erx.WithArgs(
 			erx.Operation("Store.RevokeLastNSessions"),
 			fmt.Errorf("no refresh tokens found to revoke against %s", userID),
 		)
 	}
 
-	return ss.RevokeSessions(ctx, refreshTokens...)
+	return ss.revokeSessions(ctx, "Store.RevokeLastNSessions", hashes)
+}
+
+func (ss *sessionStore) GetSessionFamilies(ctx context.Context, userID string) ([]string, error) {
+	rows, err := ss.db.QueryContext(ctx, getSessionFamilies, userID)
+	if err != nil {
+		return nil, erx.WithArgs(erx.Operation("Store.GetSessionFamilies"), err)
+	}
+
+	defer rows.Close()
+
+	var familyIDs []string
+
+	for rows.Next() {
+		var familyID string
+
+		err := rows.Scan(&familyID)
+		if err != nil {
+			return nil, erx.WithArgs(erx.Operation("Store.GetSessionFamilies"), err)
+		}
+
+		familyIDs = append(familyIDs, familyID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, erx.WithArgs(erx.Operation("Store.GetSessionFamilies"), err)
+	}
+
+	return familyIDs, nil
 }
 
 func (ss *sessionStore) RevokeAllSessions(ctx context.Context, userID string) (int64, error) {
@@ -149,12 +268,57 @@ func (ss *sessionStore) RevokeAllSessions(ctx context.Context, userID string) (i
 	return c, nil
 }
 
+func (ss *sessionStore) HashLegacyRefreshTokens(ctx context.Context) (int64, error) {
+	//NOTE: SESSIONS CREATED BEFORE REFRESH TOKENS WERE HASHED STILL HOLD THE RAW UUID, THEY ARE REWRITTEN IN PLACE
+	rows, err := ss.db.QueryContext(ctx, getLegacyRefreshTokens)
+	if err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.HashLegacyRefreshTokens"), err)
+	}
+
+	defer rows.Close()
+
+	var ids, hashes []string
+
+	for rows.Next() {
+		var id, refreshToken string
+
+		err := rows.Scan(&id, &refreshToken)
+		if err != nil {
+			return 0, erx.WithArgs(erx.Operation("Store.HashLegacyRefreshTokens"), err)
+		}
+
+		ids = append(ids, id)
+		hashes = append(hashes, ss.hasher.Hash(refreshToken))
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.HashLegacyRefreshTokens"), err)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	res, err := ss.db.ExecContext(ctx, hashRefreshTokens, pq.Array(ids), pq.Array(hashes))
+	if err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.HashLegacyRefreshTokens"), err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return 0, erx.WithArgs(erx.Operation("Store.HashLegacyRefreshTokens"), err)
+	}
+
+	return c, nil
+}
+
 func toArgs(values []string) string {
 	return "{" + strings.Join(values, ",") + "}"
 }
 
-func NewStore(db database.SQLDatabase) Store {
+func NewStore(db database.SQLDatabase, hasher token.Hasher) Store {
 	return &sessionStore{
-		db: db,
+		db:     db,
+		hasher: hasher,
 	}
 }
@@ -12,7 +12,9 @@ import (
 	"identification-service/pkg/password"
 	"identification-service/pkg/queue"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/token"
 	"identification-service/pkg/user"
 	"testing"
 )
@@ -30,7 +32,11 @@ func (sst *sessionStoreIntegrationSuite) SetupSuite() {
 
 	sst.ctx = context.Background()
 	sst.db = test.NewDB(sst.T(), cfg)
-	sst.store = session.NewStore(sst.db)
+
+	hasher, err := token.NewHasher(cfg.TokenConfig())
+	sst.Require().NoError(err)
+
+	sst.store = session.NewStore(sst.db, hasher)
 	sst.userID = createUser(sst, cfg)
 }
 
@@ -174,12 +180,19 @@ func createUser(sst *sessionStoreIntegrationSuite, cfg config.Config) string {
 
 	mockQueueConfig := &config.MockQueueConfig{}
 	mockQueueConfig.On("SignUpQueueName").Return("sign-up")
+	mockQueueConfig.On("EmailVerificationQueueName").Return("email-verification")
 
 	encoder := password.NewEncoder(cfg.PasswordConfig())
 
-	userService := user.NewService(mockQueueConfig, user.NewStore(sst.db), encoder, mockQueue)
+	hasher, err := token.NewHasher(cfg.TokenConfig())
+	require.NoError(sst.T(), err)
+
+	signer, err := token.NewSigner(cfg.TokenConfig())
+	require.NoError(sst.T(), err)
+
+	userService := user.NewService(mockQueueConfig, cfg.UserConfig(), user.NewStore(sst.db, hasher), encoder, signer, mockQueue)
 
-	userID, err := userService.CreateUser(sst.ctx, test.RandString(8), test.NewEmail(), test.NewPassword())
+	userID, err := userService.CreateUser(sst.ctx, tenant.DefaultID, test.RandString(8), test.NewEmail(), test.NewPassword())
 	require.NoError(sst.T(), err)
 	require.NotEmpty(sst.T(), userID)
 
@@ -5,11 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/config"
 	"identification-service/pkg/database"
 	"identification-service/pkg/session"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/token"
 	"regexp"
 	"strings"
 	"testing"
@@ -18,9 +23,10 @@ import (
 
 type sessionStoreSuite struct {
 	suite.Suite
-	mock  sqlmock.Sqlmock
-	db    database.SQLDatabase
-	store session.Store
+	mock   sqlmock.Sqlmock
+	db     database.SQLDatabase
+	hasher token.Hasher
+	store  session.Store
 }
 
 func (st *sessionStoreSuite) SetupSuite() {
@@ -28,19 +34,26 @@ func (st *sessionStoreSuite) SetupSuite() {
 
 	st.db = database.NewSQLDatabase(sqlDB, test.QueryTTL)
 	st.mock = mock
-	st.store = session.NewStore(st.db)
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("RefreshTokenSecret").Return(test.TokenSecret)
+
+	hasher, err := token.NewHasher(mockTokenConfig)
+	st.Require().NoError(err)
+
+	st.hasher = hasher
+	st.store = session.NewStore(st.db, st.hasher)
 }
 
 func (st *sessionStoreSuite) TestCreateSessionSuccess() {
-	userID, refreshToken := test.NewUUID(), test.NewUUID()
+	userID, clientID, refreshToken := test.NewUUID(), test.NewUUID(), test.NewUUID()
 
-	query := `insert into sessions (user_id, refresh_token) values ($1, $2) returning id`
+	query := `insert into sessions (user_id, tenant_id, client_id, refresh_token, scope) values ($1, $2, $3, $4, $5) returning id`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(userID, refreshToken).
+		WithArgs(userID, tenant.DefaultID, clientID, st.hasher.Hash(refreshToken), test.ClientScope).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(test.NewUUID()))
 
-	s, err := session.NewSessionBuilder().UserID(userID).RefreshToken(refreshToken).Build()
+	s, err := session.NewSessionBuilder().UserID(userID).ClientID(clientID).RefreshToken(refreshToken).Scope(test.ClientScope).Build()
 	require.NoError(st.T(), err)
 
 	_, err = st.store.CreateSession(context.Background(), s)
@@ -50,15 +63,15 @@ func (st *sessionStoreSuite) TestCreateSessionSuccess() {
 }
 
 func (st *sessionStoreSuite) TestCreateSessionFailure() {
-	userID, refreshToken := test.NewUUID(), test.NewUUID()
+	userID, clientID, refreshToken := test.NewUUID(), test.NewUUID(), test.NewUUID()
 
-	query := `insert into sessions (user_id, refresh_token) values ($1, $2) returning id`
+	query := `insert into sessions (user_id, tenant_id, client_id, refresh_token, scope) values ($1, $2, $3, $4, $5) returning id`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(userID, refreshToken).
+		WithArgs(userID, tenant.DefaultID, clientID, st.hasher.Hash(refreshToken), test.ClientScope).
 		WillReturnError(errors.New("failed to create session"))
 
-	s, err := session.NewSessionBuilder().UserID(userID).RefreshToken(refreshToken).Build()
+	s, err := session.NewSessionBuilder().UserID(userID).ClientID(clientID).RefreshToken(refreshToken).Scope(test.ClientScope).Build()
 	require.NoError(st.T(), err)
 
 	_, err = st.store.CreateSession(context.Background(), s)
@@ -70,13 +83,13 @@ func (st *sessionStoreSuite) TestCreateSessionFailure() {
 func (st *sessionStoreSuite) TestGetSessionSuccess() {
 	refreshToken := test.NewUUID()
 
-	query := `select id, user_id, revoked, created_at, updated_at from sessions where refresh_token=$1`
+	query := `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where refresh_token=$1`
 
-	rows := sqlmock.NewRows([]string{"id", "user_id", "revoked", "created_at", "updated_at"}).
-		AddRow(test.NewUUID(), test.NewUUID(), false, time.Time{}, time.Time{})
+	rows := sqlmock.NewRows([]string{"id", "family_id", "user_id", "tenant_id", "client_id", "scope", "revoked", "used", "created_at", "updated_at"}).
+		AddRow(test.NewUUID(), test.NewUUID(), test.NewUUID(), tenant.DefaultID, test.NewUUID(), test.ClientScope, false, false, time.Time{}, time.Time{})
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(refreshToken).
+		WithArgs(st.hasher.Hash(refreshToken)).
 		WillReturnRows(rows)
 
 	_, err := st.store.GetSession(context.Background(), refreshToken)
@@ -88,10 +101,10 @@ func (st *sessionStoreSuite) TestGetSessionSuccess() {
 func (st *sessionStoreSuite) TestGetSessionFailure() {
 	refreshToken := test.NewUUID()
 
-	query := `select id, user_id, revoked, created_at, updated_at from sessions where refresh_token=$1`
+	query := `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where refresh_token=$1`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(refreshToken).
+		WithArgs(st.hasher.Hash(refreshToken)).
 		WillReturnError(errors.New("failed to get session"))
 
 	_, err := st.store.GetSession(context.Background(), refreshToken)
@@ -100,10 +113,43 @@ func (st *sessionStoreSuite) TestGetSessionFailure() {
 	require.NoError(st.T(), st.mock.ExpectationsWereMet())
 }
 
+func (st *sessionStoreSuite) TestGetSessionByIDSuccess() {
+	sessionID := test.NewUUID()
+
+	query := `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where id=$1`
+
+	rows := sqlmock.NewRows([]string{"id", "family_id", "user_id", "tenant_id", "client_id", "scope", "revoked", "used", "created_at", "updated_at"}).
+		AddRow(sessionID, sessionID, test.NewUUID(), tenant.DefaultID, test.NewUUID(), test.ClientScope, false, false, time.Time{}, time.Time{})
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(sessionID).
+		WillReturnRows(rows)
+
+	_, err := st.store.GetSessionByID(context.Background(), sessionID)
+	require.NoError(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestGetSessionByIDFailure() {
+	sessionID := test.NewUUID()
+
+	query := `select id, coalesce(family_id, id), user_id, tenant_id, coalesce(client_id::text, ''), scope, revoked, used, created_at, updated_at from sessions where id=$1`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(sessionID).
+		WillReturnError(errors.New("failed to get session"))
+
+	_, err := st.store.GetSessionByID(context.Background(), sessionID)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
 func (st *sessionStoreSuite) TestGetActiveSessionsCountSuccess() {
 	userID := test.NewUUID()
 
-	query := `select count(*) from sessions where user_id=$1 and revoked=false`
+	query := `select count(*) from sessions where user_id=$1 and revoked=false and used=false`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs(userID).
@@ -118,7 +164,7 @@ func (st *sessionStoreSuite) TestGetActiveSessionsCountSuccess() {
 func (st *sessionStoreSuite) TestGetActiveSessionsCountFailure() {
 	userID := test.NewUUID()
 
-	query := `select count(*) from sessions where user_id=$1 and revoked=false`
+	query := `select count(*) from sessions where user_id=$1 and revoked=false and used=false`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
 		WithArgs(userID).
@@ -133,10 +179,10 @@ func (st *sessionStoreSuite) TestGetActiveSessionsCountFailure() {
 func (st *sessionStoreSuite) TestRevokeSessionsSuccess() {
 	refreshToken := test.NewUUID()
 
-	query := `update sessions set revoked=true where refresh_token = ANY($1::uuid[])`
+	query := `update sessions set revoked=true where refresh_token = ANY($1::text[])`
 
 	st.mock.ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(toArgs([]string{refreshToken})).
+		WithArgs(toArgs([]string{st.hasher.Hash(refreshToken)})).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	_, err := st.store.RevokeSessions(context.Background(), refreshToken)
@@ -148,10 +194,10 @@ func (st *sessionStoreSuite) TestRevokeSessionsSuccess() {
 func (st *sessionStoreSuite) TestRevokeSessionsFailure() {
 	refreshToken := test.NewUUID()
 
-	query := `update sessions set revoked=true where refresh_token = ANY($1::uuid[])`
+	query := `update sessions set revoked=true where refresh_token = ANY($1::text[])`
 
 	st.mock.ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(toArgs([]string{refreshToken})).
+		WithArgs(toArgs([]string{st.hasher.Hash(refreshToken)})).
 		WillReturnError(errors.New("failed to revoke session"))
 
 	_, err := st.store.RevokeSessions(context.Background(), refreshToken)
@@ -164,7 +210,7 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsSuccess() {
 	userID := test.NewUUID()
 	refreshToken := test.NewUUID()
 
-	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false order by created_at asc limit $2`
+	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false and used=false order by created_at asc limit $2`
 
 	rows := sqlmock.NewRows([]string{"refresh_token"}).
 		AddRow(refreshToken)
@@ -173,7 +219,7 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsSuccess() {
 		WithArgs(userID, 1).
 		WillReturnRows(rows)
 
-	execQuery := `update sessions set revoked=true where refresh_token = ANY($1::uuid[])`
+	execQuery := `update sessions set revoked=true where refresh_token = ANY($1::text[])`
 
 	st.mock.ExpectExec(regexp.QuoteMeta(execQuery)).
 		WithArgs(toArgs([]string{refreshToken})).
@@ -188,7 +234,7 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsSuccess() {
 func (st *sessionStoreSuite) TestRevokeLastNSessionsFailureWhenFetchFails() {
 	userID := test.NewUUID()
 
-	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false order by created_at asc limit $2`
+	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false and used=false order by created_at asc limit $2`
 
 	st.mock.ExpectQuery(regexp.QuoteMeta(fetchQuery)).
 		WithArgs(userID, 1).
@@ -204,7 +250,7 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsFailureWhenUpdateFails() {
 	userID := test.NewUUID()
 	refreshToken := test.NewUUID()
 
-	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false order by created_at asc limit $2`
+	fetchQuery := `select refresh_token from sessions where user_id=$1 and revoked=false and used=false order by created_at asc limit $2`
 
 	rows := sqlmock.NewRows([]string{"refresh_token"}).
 		AddRow(refreshToken)
@@ -213,7 +259,7 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsFailureWhenUpdateFails() {
 		WithArgs(userID, 1).
 		WillReturnRows(rows)
 
-	execQuery := `update sessions set revoked=true where refresh_token = ANY($1::uuid[])`
+	execQuery := `update sessions set revoked=true where refresh_token = ANY($1::text[])`
 
 	st.mock.ExpectExec(regexp.QuoteMeta(execQuery)).
 		WithArgs(toArgs([]string{refreshToken})).
@@ -225,6 +271,139 @@ func (st *sessionStoreSuite) TestRevokeLastNSessionsFailureWhenUpdateFails() {
 	require.NoError(st.T(), st.mock.ExpectationsWereMet())
 }
 
+func (st *sessionStoreSuite) TestRotateSessionSuccess() {
+	sessionID, refreshToken, nextSessionID := test.NewUUID(), test.NewUUID(), test.NewUUID()
+
+	query := `with used_session as (update sessions set used=true where id=$1 and used=false and revoked=false returning user_id, tenant_id, client_id, coalesce(family_id, id) as family_id, scope, created_at) insert into sessions (user_id, tenant_id, client_id, refresh_token, family_id, scope, created_at) select user_id, tenant_id, client_id, $2, family_id, scope, created_at from used_session returning id`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(sessionID, st.hasher.Hash(refreshToken)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(nextSessionID))
+
+	res, err := st.store.RotateSession(context.Background(), sessionID, refreshToken)
+	require.NoError(st.T(), err)
+	require.Equal(st.T(), nextSessionID, res)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestRotateSessionFailure() {
+	sessionID, refreshToken := test.NewUUID(), test.NewUUID()
+
+	query := `with used_session as (update sessions set used=true where id=$1 and used=false and revoked=false returning user_id, tenant_id, client_id, coalesce(family_id, id) as family_id, scope, created_at) insert into sessions (user_id, tenant_id, client_id, refresh_token, family_id, scope, created_at) select user_id, tenant_id, client_id, $2, family_id, scope, created_at from used_session returning id`
+
+	testCases := map[string]struct {
+		err  error
+		kind erx.Kind
+	}{
+		"test failure when session is already used": {
+			err:  sql.ErrNoRows,
+			kind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			err:  errors.New("failed to rotate session"),
+			kind: erx.Kind(""),
+		},
+	}
+
+	for name, testCase := range testCases {
+		st.Run(name, func() {
+			st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+				WithArgs(sessionID, st.hasher.Hash(refreshToken)).
+				WillReturnError(testCase.err)
+
+			_, err := st.store.RotateSession(context.Background(), sessionID, refreshToken)
+			require.Error(st.T(), err)
+			require.Equal(st.T(), testCase.kind, err.(*erx.Erx).Kind())
+
+			require.NoError(st.T(), st.mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func (st *sessionStoreSuite) TestRevokeSessionFamilySuccess() {
+	familyID := test.NewUUID()
+
+	query := `update sessions set revoked=true where coalesce(family_id, id)=$1`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(familyID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	c, err := st.store.RevokeSessionFamily(context.Background(), familyID)
+	require.NoError(st.T(), err)
+	require.Equal(st.T(), int64(3), c)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestRevokeSessionFamilyFailure() {
+	familyID := test.NewUUID()
+
+	query := `update sessions set revoked=true where coalesce(family_id, id)=$1`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(familyID).
+		WillReturnError(errors.New("failed to revoke session family"))
+
+	_, err := st.store.RevokeSessionFamily(context.Background(), familyID)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestGetSessionFamiliesSuccess() {
+	userID, familyID := test.NewUUID(), test.NewUUID()
+
+	query := `select distinct coalesce(family_id, id) from sessions where user_id=$1 and revoked=false`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}).AddRow(familyID))
+
+	res, err := st.store.GetSessionFamilies(context.Background(), userID)
+	require.NoError(st.T(), err)
+	require.Equal(st.T(), []string{familyID}, res)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestGetSessionFamiliesFailureWhenRowsFail() {
+	userID := test.NewUUID()
+
+	query := `select distinct coalesce(family_id, id) from sessions where user_id=$1 and revoked=false`
+
+	rows := sqlmock.NewRows([]string{"family_id"}).
+		AddRow(test.NewUUID()).
+		AddRow(test.NewUUID()).
+		RowError(1, errors.New("failed to read row"))
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(userID).
+		WillReturnRows(rows).
+		RowsWillBeClosed()
+
+	_, err := st.store.GetSessionFamilies(context.Background(), userID)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestGetSessionFamiliesFailure() {
+	userID := test.NewUUID()
+
+	query := `select distinct coalesce(family_id, id) from sessions where user_id=$1 and revoked=false`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(userID).
+		WillReturnError(errors.New("failed to get session families"))
+
+	_, err := st.store.GetSessionFamilies(context.Background(), userID)
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
 func (st *sessionStoreSuite) TestRevokeAllSessionsSuccess() {
 	userID := test.NewUUID()
 
@@ -255,6 +434,39 @@ func (st *sessionStoreSuite) TestRevokeAllSessionsFailure() {
 	require.NoError(st.T(), st.mock.ExpectationsWereMet())
 }
 
+func (st *sessionStoreSuite) TestHashLegacyRefreshTokensSuccess() {
+	sessionID, refreshToken := test.NewUUID(), test.NewUUID()
+
+	fetchQuery := `select id, refresh_token from sessions where refresh_token ~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(fetchQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "refresh_token"}).AddRow(sessionID, refreshToken))
+
+	execQuery := `update sessions s set refresh_token=v.refresh_token from unnest($1::uuid[], $2::text[]) as v(id, refresh_token) where s.id=v.id`
+
+	st.mock.ExpectExec(regexp.QuoteMeta(execQuery)).
+		WithArgs(pq.Array([]string{sessionID}), pq.Array([]string{st.hasher.Hash(refreshToken)})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, err := st.store.HashLegacyRefreshTokens(context.Background())
+	require.NoError(st.T(), err)
+	require.Equal(st.T(), int64(1), c)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
+func (st *sessionStoreSuite) TestHashLegacyRefreshTokensFailure() {
+	fetchQuery := `select id, refresh_token from sessions where refresh_token ~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'`
+
+	st.mock.ExpectQuery(regexp.QuoteMeta(fetchQuery)).
+		WillReturnError(errors.New("failed to fetch refresh tokens"))
+
+	_, err := st.store.HashLegacyRefreshTokens(context.Background())
+	require.Error(st.T(), err)
+
+	require.NoError(st.T(), st.mock.ExpectationsWereMet())
+}
+
 func toArgs(values []string) string {
 	return "{" + strings.Join(values, ",") + "}"
 }
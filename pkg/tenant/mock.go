@@ -0,0 +1,24 @@
+package tenant
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (mock *MockService) CreateTenant(ctx context.Context, name string) (string, error) {
+	args := mock.Called(ctx, name)
+	return args.String(0), args.Error(1)
+}
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (mock *MockStore) CreateTenant(ctx context.Context, tenant Tenant) (string, error) {
+	args := mock.Called(ctx, tenant)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,36 @@
+package tenant
+
+import (
+	"context"
+	"github.com/nsnikhil/erx"
+)
+
+type Service interface {
+	CreateTenant(ctx context.Context, name string) (string, error)
+}
+
+type tenantService struct {
+	store Store
+}
+
+func (ts *tenantService) CreateTenant(ctx context.Context, name string) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.CreateTenant"), err) }
+
+	tenant, err := NewTenantBuilder().Name(name).Build()
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	id, err := ts.store.CreateTenant(ctx, tenant)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	return id, nil
+}
+
+func NewService(store Store) Service {
+	return &tenantService{
+		store: store,
+	}
+}
@@ -0,0 +1,44 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/test"
+	"testing"
+)
+
+func TestTenantServiceCreateTenantSuccess(t *testing.T) {
+	id := test.NewUUID()
+
+	mockStore := &tenant.MockStore{}
+	mockStore.On("CreateTenant", mock.Anything, mock.AnythingOfType("Tenant")).Return(id, nil)
+
+	res, err := tenant.NewService(mockStore).CreateTenant(context.Background(), "acme")
+	require.NoError(t, err)
+
+	assert.Equal(t, id, res)
+}
+
+func TestTenantServiceCreateTenantFailureWhenNameIsEmpty(t *testing.T) {
+	mockStore := &tenant.MockStore{}
+
+	_, err := tenant.NewService(mockStore).CreateTenant(context.Background(), "")
+	require.Error(t, err)
+
+	assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+	mockStore.AssertNotCalled(t, "CreateTenant", mock.Anything, mock.Anything)
+}
+
+func TestTenantServiceCreateTenantFailureWhenStoreCallFails(t *testing.T) {
+	mockStore := &tenant.MockStore{}
+	mockStore.On("CreateTenant", mock.Anything, mock.AnythingOfType("Tenant")).
+		Return("", errors.New("failed to create tenant"))
+
+	_, err := tenant.NewService(mockStore).CreateTenant(context.Background(), "acme")
+	require.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package tenant
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/database"
+)
+
+const (
+	createTenant = `insert into tenants (name) values ($1) returning id`
+)
+
+type Store interface {
+	CreateTenant(ctx context.Context, tenant Tenant) (string, error)
+}
+
+type tenantStore struct {
+	db database.SQLDatabase
+}
+
+func (ts *tenantStore) CreateTenant(ctx context.Context, tenant Tenant) (string, error) {
+	var id string
+
+	row := ts.db.QueryRowContext(ctx, createTenant, tenant.name)
+	if row.Err() != nil {
+		if pgErr, ok := row.Err().(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return "", erx.WithArgs(erx.Operation("Store.CreateTenant"), erx.DuplicateRecordError, row.Err())
+			}
+		}
+
+		return "", erx.WithArgs(erx.Operation("Store.CreateTenant"), row.Err())
+	}
+
+	err := row.Scan(&id)
+	if err != nil {
+		return "", erx.WithArgs(erx.Operation("Store.CreateTenant"), err)
+	}
+
+	return id, nil
+}
+
+func NewStore(db database.SQLDatabase) Store {
+	return &tenantStore{
+		db: db,
+	}
+}
@@ -0,0 +1,89 @@
+package tenant_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/database"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/test"
+	"regexp"
+	"testing"
+)
+
+const createTenantQuery = `insert into tenants (name) values ($1) returning id`
+
+type tenantStoreSuite struct {
+	suite.Suite
+	mock  sqlmock.Sqlmock
+	store tenant.Store
+}
+
+func (tst *tenantStoreSuite) SetupSuite() {
+	sqlDB, mock := getMockDB(tst.T())
+
+	tst.mock = mock
+	tst.store = tenant.NewStore(database.NewSQLDatabase(sqlDB, test.QueryTTL))
+}
+
+func (tst *tenantStoreSuite) TestCreateTenantSuccess() {
+	id := test.NewUUID()
+
+	tst.mock.ExpectQuery(regexp.QuoteMeta(createTenantQuery)).
+		WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+
+	tn, err := tenant.NewTenantBuilder().Name("acme").Build()
+	tst.Require().NoError(err)
+
+	res, err := tst.store.CreateTenant(context.Background(), tn)
+	tst.Require().NoError(err)
+
+	tst.Assert().Equal(id, res)
+	tst.Require().NoError(tst.mock.ExpectationsWereMet())
+}
+
+func (tst *tenantStoreSuite) TestCreateTenantFailureWhenTenantAlreadyExists() {
+	tst.mock.ExpectQuery(regexp.QuoteMeta(createTenantQuery)).
+		WithArgs("acme").
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	tn, err := tenant.NewTenantBuilder().Name("acme").Build()
+	tst.Require().NoError(err)
+
+	_, err = tst.store.CreateTenant(context.Background(), tn)
+	tst.Require().Error(err)
+
+	tst.Assert().Equal(erx.DuplicateRecordError, err.(*erx.Erx).Kind())
+	tst.Require().NoError(tst.mock.ExpectationsWereMet())
+}
+
+func (tst *tenantStoreSuite) TestCreateTenantFailure() {
+	tst.mock.ExpectQuery(regexp.QuoteMeta(createTenantQuery)).
+		WithArgs("acme").
+		WillReturnError(errors.New("failed to create tenant"))
+
+	tn, err := tenant.NewTenantBuilder().Name("acme").Build()
+	tst.Require().NoError(err)
+
+	_, err = tst.store.CreateTenant(context.Background(), tn)
+	tst.Require().Error(err)
+
+	tst.Require().NoError(tst.mock.ExpectationsWereMet())
+}
+
+func TestTenantStore(t *testing.T) {
+	suite.Run(t, new(tenantStoreSuite))
+}
+
+func getMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	return db, mock
+}
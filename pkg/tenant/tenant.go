@@ -0,0 +1,55 @@
+package tenant
+
+import (
+	"errors"
+	"github.com/nsnikhil/erx"
+)
+
+const (
+	//NOTE: CLIENTS REGISTERED WITHOUT A TENANT, AND EVERY CLIENT AND USER CREATED BEFORE TENANTS, BELONG TO THE DEFAULT TENANT
+	DefaultID = "00000000-0000-4000-8000-000000000000"
+)
+
+type Tenant struct {
+	name string
+}
+
+func (t Tenant) Name() string {
+	return t.name
+}
+
+type Builder struct {
+	name string
+
+	err error
+}
+
+func (b *Builder) Name(name string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if len(name) == 0 {
+		b.err = errors.New("tenant name cannot be empty")
+		return b
+	}
+
+	b.name = name
+	return b
+}
+
+func (b *Builder) Build() (Tenant, error) {
+	if b.err != nil {
+		return Tenant{}, erx.WithArgs(erx.Operation("TenantBuilder.Build"), erx.ValidationError, b.err)
+	}
+
+	if len(b.name) == 0 {
+		return Tenant{}, erx.WithArgs(erx.Operation("TenantBuilder.Build"), erx.ValidationError, errors.New("tenant name cannot be empty"))
+	}
+
+	return Tenant{name: b.name}, nil
+}
+
+func NewTenantBuilder() *Builder {
+	return &Builder{}
+}
@@ -0,0 +1,23 @@
+package tenant_test
+
+import (
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/tenant"
+	"testing"
+)
+
+func TestCreateNewTenantSuccess(t *testing.T) {
+	tn, err := tenant.NewTenantBuilder().Name("acme").Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, "acme", tn.Name())
+}
+
+func TestCreateNewTenantFailureWhenNameIsEmpty(t *testing.T) {
+	_, err := tenant.NewTenantBuilder().Name("").Build()
+	require.Error(t, err)
+
+	assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+}
@@ -0,0 +1,191 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cr "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"identification-service/pkg/webauthn"
+	"log"
+)
+
+type Authenticator struct {
+	id         []byte
+	algorithm  int
+	ecKey      *ecdsa.PrivateKey
+	edKey      ed25519.PrivateKey
+	SignCount  uint32
+	UserVerify bool
+}
+
+func NewAuthenticator(algorithm int) *Authenticator {
+	a := &Authenticator{id: RandBytes(16), algorithm: algorithm, UserVerify: true}
+
+	var err error
+
+	switch algorithm {
+	case webauthn.AlgorithmES256:
+		a.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), cr.Reader)
+	case webauthn.AlgorithmEdDSA:
+		_, a.edKey, err = ed25519.GenerateKey(cr.Reader)
+	default:
+		log.Fatalf("unsupported authenticator algorithm %d", algorithm)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return a
+}
+
+func (a *Authenticator) CredentialID() []byte {
+	return a.id
+}
+
+func (a *Authenticator) PublicKey() []byte {
+	if a.algorithm == webauthn.AlgorithmEdDSA {
+		return cborMap(
+			cborInt(1), cborInt(1),
+			cborInt(3), cborInt(webauthn.AlgorithmEdDSA),
+			cborInt(-1), cborInt(6),
+			cborInt(-2), cborBytes(a.edKey.Public().(ed25519.PublicKey)),
+		)
+	}
+
+	x, y := make([]byte, 32), make([]byte, 32)
+	a.ecKey.X.FillBytes(x)
+	a.ecKey.Y.FillBytes(y)
+
+	return cborMap(
+		cborInt(1), cborInt(2),
+		cborInt(3), cborInt(webauthn.AlgorithmES256),
+		cborInt(-1), cborInt(1),
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+}
+
+func (a *Authenticator) Credential() webauthn.Credential {
+	return webauthn.Credential{ID: a.id, PublicKey: a.PublicKey(), SignCount: a.SignCount}
+}
+
+func (a *Authenticator) Attest(rpID, origin string, challenge []byte) webauthn.Attestation {
+	authData := a.authenticatorData(rpID, 0x40)
+
+	authData = append(authData, make([]byte, 16)...)
+	authData = append(authData, byte(len(a.id)>>8), byte(len(a.id)))
+	authData = append(authData, a.id...)
+	authData = append(authData, a.PublicKey()...)
+
+	return webauthn.Attestation{
+		ClientDataJSON: clientDataJSON("webauthn.create", origin, challenge),
+		AttestationObject: cborMap(
+			cborText("fmt"), cborText("none"),
+			cborText("attStmt"), cborMap(),
+			cborText("authData"), cborBytes(authData),
+		),
+	}
+}
+
+func (a *Authenticator) Assert(rpID, origin string, challenge []byte, userHandle []byte) webauthn.Assertion {
+	a.SignCount++
+
+	authData := a.authenticatorData(rpID, 0)
+	data := clientDataJSON("webauthn.get", origin, challenge)
+	hash := sha256.Sum256(data)
+
+	return webauthn.Assertion{
+		CredentialID:      a.id,
+		ClientDataJSON:    data,
+		AuthenticatorData: authData,
+		Signature:         a.sign(append(append([]byte{}, authData...), hash[:]...)),
+		UserHandle:        userHandle,
+	}
+}
+
+func (a *Authenticator) authenticatorData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	flags |= 0x01
+	if a.UserVerify {
+		flags |= 0x04
+	}
+
+	counter := make([]byte, 4)
+	binary.BigEndian.PutUint32(counter, a.SignCount)
+
+	return append(append(rpIDHash[:], flags), counter...)
+}
+
+func (a *Authenticator) sign(data []byte) []byte {
+	if a.algorithm == webauthn.AlgorithmEdDSA {
+		return ed25519.Sign(a.edKey, data)
+	}
+
+	digest := sha256.Sum256(data)
+
+	signature, err := ecdsa.SignASN1(cr.Reader, a.ecKey, digest[:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return signature
+}
+
+func clientDataJSON(ceremony, origin string, challenge []byte) []byte {
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return data
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	default:
+		return []byte{major<<5 | 26, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, uint64(-1-n))
+	}
+
+	return cborHead(0, uint64(n))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+func cborMap(items ...[]byte) []byte {
+	res := cborHead(5, uint64(len(items)/2))
+
+	for _, item := range items {
+		res = append(res, item...)
+	}
+
+	return res
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/o1egl/paseto"
 	"identification-service/pkg/client"
 	"identification-service/pkg/config"
+	"identification-service/pkg/tenant"
+	"identification-service/pkg/token"
 	"log"
 	"math/rand"
 	"strings"
@@ -20,22 +22,40 @@ const (
 	numbers                        = "0123456789"
 	symbols                        = "!@#$%^&*()"
 	QueryTTL                       = 10000
+	TokenSecret                    = "0d6c7f2a9b4e41c8a3f5e1b7c2d9a604"
 	ClientTableName                = "clients"
 	ClientSessionStrategyRevokeOld = "revoke_old"
+	ClientRedirectURI              = "https://app.example.com/callback"
+	ClientScope                    = "orders:read"
+	ClientAudience                 = "payments"
+	WebAuthnRPID                   = "app.example.com"
+	WebAuthnOrigin                 = "https://app.example.com"
 	UserTableName                  = "users"
 	SessionTableName               = "sessions"
 
-	ClientIdKey                  = "id"
-	ClientNameKey                = "name"
-	ClientSecretKey              = "secret"
-	ClientRevokedKey             = "revoked"
-	ClientAccessTokenTTLKey      = "accessTokenTTL"
-	ClientSessionTTLKey          = "sessionTTL"
-	ClientMaxActiveSessionsKey   = "maxActiveSessions"
-	ClientSessionStrategyNameKey = "sessionStrategyName"
-	ClientPrivateKeyKey          = "privateKey"
-	ClientCreatedAtKey           = "createdAt"
-	ClientUpdatedAtKey           = "updatedAt"
+	ClientIdKey                   = "id"
+	ClientTenantIDKey             = "tenantID"
+	ClientNameKey                 = "name"
+	ClientSecretKey               = "secret"
+	ClientTypeKey                 = "type"
+	ClientRevokedKey              = "revoked"
+	ClientAccessTokenTTLKey       = "accessTokenTTL"
+	ClientSessionTTLKey           = "sessionTTL"
+	ClientMaxActiveSessionsKey    = "maxActiveSessions"
+	ClientSessionStrategyNameKey  = "sessionStrategyName"
+	ClientRotateRefreshTokensKey  = "rotateRefreshTokens"
+	ClientRequireVerifiedEmailKey = "requireVerifiedEmail"
+	ClientAllowMagicLinkSignUpKey = "allowMagicLinkSignUp"
+	ClientWebAuthnRPIDKey         = "webAuthnRPID"
+	ClientWebAuthnOriginsKey      = "webAuthnOrigins"
+	ClientRedirectURIsKey         = "redirectURIs"
+	ClientAllowedScopesKey        = "allowedScopes"
+	ClientAllowedAudiencesKey     = "allowedAudiences"
+	ClientClaimMappingsKey        = "claimMappings"
+	ClientKeyIDKey                = "keyID"
+	ClientPrivateKeyKey           = "privateKey"
+	ClientCreatedAtKey            = "createdAt"
+	ClientUpdatedAtKey            = "updatedAt"
 )
 
 func RandString(n int) string {
@@ -113,13 +133,25 @@ var NewClient = func(cfg config.ClientConfig, d map[string]interface{}) (client.
 
 	return client.NewClientBuilder(cfg).
 		ID(either(d[ClientIdKey], NewUUID()).(string)).
+		TenantID(either(d[ClientTenantIDKey], tenant.DefaultID).(string)).
 		Name(either(d[ClientNameKey], RandString(8)).(string)).
 		Secret(either(d[ClientSecretKey], NewUUID()).(string)).
+		Type(either(d[ClientTypeKey], client.TypeConfidential).(string)).
 		Revoked(either(d[ClientRevokedKey], false).(bool)).
 		AccessTokenTTL(either(d[ClientAccessTokenTTLKey], RandInt(1, 10)).(int)).
 		SessionTTL(either(d[ClientSessionTTLKey], RandInt(1440, 86701)).(int)).
 		MaxActiveSessions(either(d[ClientMaxActiveSessionsKey], RandInt(1, 10)).(int)).
 		SessionStrategy(either(d[ClientSessionStrategyNameKey], ClientSessionStrategyRevokeOld).(string)).
+		RotateRefreshTokens(either(d[ClientRotateRefreshTokensKey], false).(bool)).
+		RequireVerifiedEmail(either(d[ClientRequireVerifiedEmailKey], false).(bool)).
+		AllowMagicLinkSignUp(either(d[ClientAllowMagicLinkSignUpKey], false).(bool)).
+		WebAuthnRPID(either(d[ClientWebAuthnRPIDKey], WebAuthnRPID).(string)).
+		WebAuthnOrigins(either(d[ClientWebAuthnOriginsKey], []string(nil)).([]string)).
+		RedirectURIs(either(d[ClientRedirectURIsKey], []string{ClientRedirectURI}).([]string)).
+		AllowedScopes(either(d[ClientAllowedScopesKey], []string{ClientScope}).([]string)).
+		AllowedAudiences(either(d[ClientAllowedAudiencesKey], []string{ClientAudience}).([]string)).
+		ClaimMappings(either(d[ClientClaimMappingsKey], map[string]string(nil)).(map[string]string)).
+		KeyID(either(d[ClientKeyIDKey], NewUUID()).(string)).
 		PrivateKey(either(d[ClientPrivateKeyKey], ClientPriKeyBytes()).([]byte)).
 		CreatedAt(either(d[ClientCreatedAtKey], CreatedAt).(time.Time)).
 		UpdatedAt(either(d[ClientUpdatedAtKey], UpdatedAt).(time.Time)).
@@ -138,6 +170,19 @@ var NewPasetoToken = func() string {
 	return key
 }
 
+var NewRefreshToken = func() string {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("")
+	mockTokenConfig.On("Issuer").Return("")
+
+	refreshToken, err := token.NewGenerator(mockTokenConfig).GenerateRefreshToken()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return refreshToken
+}
+
 var generateKey = func() (ed25519.PublicKey, ed25519.PrivateKey) {
 	pub, pri, err := ed25519.GenerateKey(cr.Reader)
 	if err != nil {
@@ -2,11 +2,13 @@ package test
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/require"
 	"identification-service/pkg/cache"
 	"identification-service/pkg/config"
 	"identification-service/pkg/database"
+	"identification-service/pkg/libcrypto"
 	"identification-service/pkg/queue"
 	"testing"
 )
@@ -43,6 +45,16 @@ func NewDB(t *testing.T, cfg config.Config) database.SQLDatabase {
 	return db
 }
 
+func NewEnvelope(t *testing.T, cfg config.Config) libcrypto.Envelope {
+	masterKey, err := base64.StdEncoding.DecodeString(cfg.KMSConfig().MasterKey())
+	require.NoError(t, err)
+
+	kms, err := libcrypto.NewLocalKMS(masterKey)
+	require.NoError(t, err)
+
+	return libcrypto.NewEnvelope(kms)
+}
+
 func NewCache(t *testing.T, cfg config.Config) *redis.Client {
 	if redisClient != nil {
 		return redisClient
@@ -0,0 +1,98 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/nsnikhil/erx"
+	"strconv"
+	"time"
+)
+
+const (
+	deniedJtiKeyPrefix = "denied_jti:"
+	jtiGroupKeyPrefix  = "jti_group:"
+)
+
+type Denylist interface {
+	Track(ctx context.Context, groupID string, claims Claims) error
+	DenyGroup(ctx context.Context, groupID string) error
+	IsDenied(ctx context.Context, jti string) (bool, error)
+}
+
+type redisDenylist struct {
+	cache *redis.Client
+}
+
+func (rd *redisDenylist) Track(ctx context.Context, groupID string, claims Claims) error {
+	//NOTE: THE GROUP KEY OUTLIVES THE LAST TOKEN ADDED TO IT, EARLIER TOKENS OF THE GROUP EXPIRE BEFORE THAT
+	ttl := time.Until(claims.Expiration)
+	if len(claims.Jti) == 0 || ttl <= 0 {
+		return nil
+	}
+
+	key := jtiGroupKeyPrefix + groupID
+
+	pipe := rd.cache.TxPipeline()
+	pipe.HSet(ctx, key, claims.Jti, claims.Expiration.Unix())
+	pipe.Expire(ctx, key, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return erx.WithArgs(erx.Operation("Denylist.Track"), err)
+	}
+
+	return nil
+}
+
+func (rd *redisDenylist) DenyGroup(ctx context.Context, groupID string) error {
+	wrap := func(err error) error {
+		return erx.WithArgs(erx.Operation("Denylist.DenyGroup"), err)
+	}
+
+	key := jtiGroupKeyPrefix + groupID
+
+	tokens, err := rd.cache.HGetAll(ctx, key).Result()
+	if err != nil {
+		return wrap(err)
+	}
+
+	now := time.Now()
+	pipe := rd.cache.TxPipeline()
+
+	for jti, exp := range tokens {
+		expiration, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil {
+			return wrap(fmt.Errorf("invalid expiration %s for jti %s", exp, jti))
+		}
+
+		ttl := time.Unix(expiration, 0).Sub(now)
+		if ttl <= 0 {
+			continue
+		}
+
+		pipe.Set(ctx, deniedJtiKeyPrefix+jti, groupID, ttl)
+	}
+
+	pipe.Del(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (rd *redisDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	c, err := rd.cache.Exists(ctx, deniedJtiKeyPrefix+jti).Result()
+	if err != nil {
+		return false, erx.WithArgs(erx.Operation("Denylist.IsDenied"), err)
+	}
+
+	return c > 0, nil
+}
+
+func NewDenylist(cache *redis.Client) Denylist {
+	return &redisDenylist{
+		cache: cache,
+	}
+}
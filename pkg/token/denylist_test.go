@@ -0,0 +1,101 @@
+package token_test
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"testing"
+	"time"
+)
+
+type denylistTest struct {
+	suite.Suite
+	rd       *miniredis.Miniredis
+	denylist token.Denylist
+}
+
+func (dt *denylistTest) SetupTest() {
+	rd, err := miniredis.Run()
+	dt.Require().NoError(err)
+
+	dt.rd = rd
+	dt.denylist = token.NewDenylist(redis.NewClient(&redis.Options{Addr: rd.Addr()}))
+}
+
+func (dt *denylistTest) TearDownTest() {
+	dt.rd.Close()
+}
+
+func TestDenylist(t *testing.T) {
+	suite.Run(t, new(denylistTest))
+}
+
+func (dt *denylistTest) TestDenyGroupDeniesTrackedTokens() {
+	ctx := context.Background()
+	groupID, otherGroupID := test.NewUUID(), test.NewUUID()
+
+	first := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(10 * time.Minute)}
+	second := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(5 * time.Minute)}
+	other := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(10 * time.Minute)}
+
+	dt.Require().NoError(dt.denylist.Track(ctx, groupID, first))
+	dt.Require().NoError(dt.denylist.Track(ctx, groupID, second))
+	dt.Require().NoError(dt.denylist.Track(ctx, otherGroupID, other))
+
+	dt.Require().NoError(dt.denylist.DenyGroup(ctx, groupID))
+
+	for _, claims := range []token.Claims{first, second} {
+		denied, err := dt.denylist.IsDenied(ctx, claims.Jti)
+		dt.Require().NoError(err)
+		dt.Assert().True(denied)
+	}
+
+	denied, err := dt.denylist.IsDenied(ctx, other.Jti)
+	dt.Require().NoError(err)
+	dt.Assert().False(denied)
+
+	ttl := dt.rd.TTL("denied_jti:" + second.Jti)
+	dt.Assert().True(ttl > 0 && ttl <= 5*time.Minute)
+}
+
+func (dt *denylistTest) TestDenialExpiresWithToken() {
+	ctx := context.Background()
+	groupID := test.NewUUID()
+
+	claims := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(time.Minute)}
+
+	dt.Require().NoError(dt.denylist.Track(ctx, groupID, claims))
+	dt.Require().NoError(dt.denylist.DenyGroup(ctx, groupID))
+
+	dt.rd.FastForward(2 * time.Minute)
+
+	denied, err := dt.denylist.IsDenied(ctx, claims.Jti)
+	dt.Require().NoError(err)
+	dt.Assert().False(denied)
+}
+
+func (dt *denylistTest) TestTrackIgnoresExpiredTokens() {
+	ctx := context.Background()
+	groupID := test.NewUUID()
+
+	claims := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(-time.Minute)}
+
+	dt.Require().NoError(dt.denylist.Track(ctx, groupID, claims))
+	dt.Assert().False(dt.rd.Exists("jti_group:" + groupID))
+}
+
+func (dt *denylistTest) TestFailureWhenCacheIsDown() {
+	ctx := context.Background()
+	dt.rd.Close()
+
+	claims := token.Claims{Jti: test.NewUUID(), Expiration: time.Now().Add(time.Minute)}
+
+	dt.Assert().Error(dt.denylist.Track(ctx, test.NewUUID(), claims))
+	dt.Assert().Error(dt.denylist.DenyGroup(ctx, test.NewUUID()))
+
+	_, err := dt.denylist.IsDenied(ctx, claims.Jti)
+	dt.Assert().Error(err)
+}
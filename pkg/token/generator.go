@@ -1,7 +1,9 @@
 package token
 
 import (
-	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/nsnikhil/erx"
 	"github.com/o1egl/paseto"
@@ -10,28 +12,48 @@ import (
 	"time"
 )
 
+const (
+	AudienceClaim = "aud"
+	ScopeClaim    = "scope"
+)
+
 type Generator interface {
-	GenerateAccessToken(ttl int, subject string, claims map[string]string) (string, error)
+	GenerateAccessToken(ttl int, subject string, key libcrypto.Key, claims map[string]string) (string, Claims, error)
 	GenerateRefreshToken() (string, error)
+	GenerateIDToken(ttl int, key libcrypto.Key, claims IDClaims) (string, error)
+}
+
+type Footer struct {
+	KeyID string `json:"kid"`
 }
 
 type pasetoTokenGenerator struct {
-	audience   string
-	issuer     string
-	privateKey crypto.PrivateKey
+	audience string
+	issuer   string
 }
 
-func (tg *pasetoTokenGenerator) GenerateAccessToken(ttl int, subject string, claims map[string]string) (string, error) {
+func (tg *pasetoTokenGenerator) GenerateAccessToken(ttl int, subject string, key libcrypto.Key, claims map[string]string) (string, Claims, error) {
+	if len(key.ID) == 0 {
+		return "", Claims{}, erx.WithArgs(erx.Operation("TokenGenerator.GenerateAccessToken"), errors.New("signing key id cannot be empty"))
+	}
+
+	if len(key.PrivateKey) != ed25519.PrivateKeySize {
+		return "", Claims{}, erx.WithArgs(
+			erx.Operation("TokenGenerator.GenerateAccessToken"),
+			fmt.Errorf("invalid signing key of length %d", len(key.PrivateKey)),
+		)
+	}
+
 	now := time.Now()
 
 	jsonToken := getJSONToken(now, ttl, tg.audience, tg.issuer, subject, claims)
 
-	accessToken, err := paseto.NewV2().Sign(tg.privateKey, jsonToken, nil)
+	accessToken, err := paseto.NewV2().Sign(key.PrivateKey, jsonToken, Footer{KeyID: key.ID})
 	if err != nil {
-		return "", erx.WithArgs(erx.Operation("TokenGenerator.GenerateAccessToken"), err)
+		return "", Claims{}, erx.WithArgs(erx.Operation("TokenGenerator.GenerateAccessToken"), err)
 	}
 
-	return accessToken, nil
+	return accessToken, newClaims(key.ID, jsonToken), nil
 }
 
 func getJSONToken(now time.Time, ttl int, audience, issuer, subject string, claims map[string]string) paseto.JSONToken {
@@ -51,27 +73,26 @@ func getJSONToken(now time.Time, ttl int, audience, issuer, subject string, clai
 		}
 	}
 
+	//NOTE: AN AUDIENCE CLAIM NARROWS THE TOKEN TO A SINGLE SERVICE, AS FOR TOKENS ISSUED THROUGH TOKEN EXCHANGE
+	if audience, ok := claims[AudienceClaim]; ok {
+		token.Audience = audience
+	}
+
 	return token
 }
 
 func (tg *pasetoTokenGenerator) GenerateRefreshToken() (string, error) {
-	id, err := uuid.NewRandom()
+	refreshToken, err := newRefreshToken()
 	if err != nil {
 		return "", erx.WithArgs(erx.Operation("TokenGenerator.GenerateRefreshToken"), err)
 	}
 
-	return id.String(), nil
+	return refreshToken, nil
 }
 
-func NewGenerator(cfg config.TokenConfig, keyGenerator libcrypto.Ed25519Generator) (Generator, error) {
-	_, priKey, err := keyGenerator.FromEncodedPem(cfg.EncodedSigningKey())
-	if err != nil {
-		return nil, erx.WithArgs(erx.Operation("TokenGenerator.NewTokenGenerator"), err)
-	}
-
+func NewGenerator(cfg config.TokenConfig) Generator {
 	return &pasetoTokenGenerator{
-		privateKey: priKey,
-		audience:   cfg.Audience(),
-		issuer:     cfg.Issuer(),
-	}, nil
+		audience: cfg.Audience(),
+		issuer:   cfg.Issuer(),
+	}
 }
@@ -3,9 +3,7 @@ package token_test
 import (
 	"crypto"
 	"crypto/ed25519"
-	"errors"
 	"github.com/o1egl/paseto"
-	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"identification-service/pkg/config"
 	"identification-service/pkg/libcrypto"
@@ -13,6 +11,7 @@ import (
 	"identification-service/pkg/token"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type generatorTest struct {
@@ -24,7 +23,6 @@ func (gt *generatorTest) SetupSuite() {
 	mockClientConfig := &config.MockTokenConfig{}
 	mockClientConfig.On("Audience").Return("user")
 	mockClientConfig.On("Issuer").Return("identification-service")
-	mockClientConfig.On("EncodedSigningKey").Return("signing-key")
 
 	gt.cfg = mockClientConfig
 }
@@ -33,41 +31,35 @@ func TestGenerator(t *testing.T) {
 	suite.Run(t, new(generatorTest))
 }
 
-func (gt *generatorTest) TestCreateNewGeneratorSuccess() {
-	mockGenerator := &libcrypto.MockEd25519Generator{}
-	mockGenerator.On(
-		"FromEncodedPem",
-		mock.AnythingOfType("string"),
-	).Return(ed25519.PublicKey{}, ed25519.PrivateKey{}, nil)
+func (gt *generatorTest) TestAuthTokenGenerateAccessToken() {
+	pub, pri := test.GenerateKey()
+
+	generator := token.NewGenerator(gt.cfg)
 
-	_, err := token.NewGenerator(gt.cfg, mockGenerator)
+	keyID := test.NewUUID()
+
+	accessToken, claims, err := generator.GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{ID: keyID, PrivateKey: pri}, nil)
 	gt.Require().NoError(err)
-}
 
-func (gt *generatorTest) TestCreateNewGeneratorFailure() {
-	mockGenerator := &libcrypto.MockEd25519Generator{}
-	mockGenerator.On(
-		"FromEncodedPem",
-		mock.AnythingOfType("string"),
-	).Return(ed25519.PublicKey{}, ed25519.PrivateKey{}, errors.New("failed to genrate"))
+	var payload paseto.JSONToken
+	var footer token.Footer
 
-	_, err := token.NewGenerator(gt.cfg, mockGenerator)
-	gt.Require().Error(err)
+	_, err = paseto.Parse(accessToken, &payload, &footer, nil, map[paseto.Version]crypto.PublicKey{paseto.Version2: pub})
+	gt.Require().NoError(err)
+
+	gt.Assert().Equal("identification-service", payload.Issuer)
+	gt.Assert().Equal(keyID, footer.KeyID)
+	gt.Assert().Equal(payload.Jti, claims.Jti)
+	gt.Assert().Equal(keyID, claims.KeyID)
+	gt.Assert().True(payload.Expiration.Equal(claims.Expiration.Truncate(time.Second)))
 }
 
-func (gt *generatorTest) TestAuthTokenGenerateAccessToken() {
+func (gt *generatorTest) TestAuthTokenGenerateAccessTokenForAnotherAudience() {
 	pub, pri := test.GenerateKey()
 
-	mockGenerator := &libcrypto.MockEd25519Generator{}
-	mockGenerator.On(
-		"FromEncodedPem",
-		mock.AnythingOfType("string"),
-	).Return(ed25519.PublicKey{}, pri, nil)
-
-	generator, err := token.NewGenerator(gt.cfg, mockGenerator)
-	gt.Require().NoError(err)
+	generator := token.NewGenerator(gt.cfg)
 
-	accessToken, err := generator.GenerateAccessToken(10, test.NewUUID(), nil)
+	accessToken, claims, err := generator.GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri}, map[string]string{token.AudienceClaim: "payments"})
 	gt.Require().NoError(err)
 
 	var payload paseto.JSONToken
@@ -75,26 +67,54 @@ func (gt *generatorTest) TestAuthTokenGenerateAccessToken() {
 	_, err = paseto.Parse(accessToken, &payload, nil, nil, map[paseto.Version]crypto.PublicKey{paseto.Version2: pub})
 	gt.Require().NoError(err)
 
-	gt.Assert().Equal("identification-service", payload.Issuer)
+	gt.Assert().Equal("payments", payload.Audience)
+	gt.Assert().Equal("payments", claims.Audience)
 }
 
-func (gt *generatorTest) TestAuthTokenGenerateRefreshToken() {
-	isValidUUID := func(uuid string) bool {
-		r := regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$")
-		return r.MatchString(uuid)
-	}
-
-	mockGenerator := &libcrypto.MockEd25519Generator{}
-	mockGenerator.On(
-		"FromEncodedPem",
-		mock.AnythingOfType("string"),
-	).Return(ed25519.PublicKey{}, test.ClientPriKey(), nil)
-
-	generator, err := token.NewGenerator(gt.cfg, mockGenerator)
+func (gt *generatorTest) TestAuthTokenGenerateAccessTokenNotVerifiableByOtherKey() {
+	_, pri := test.GenerateKey()
+	otherPub, _ := test.GenerateKey()
+
+	generator := token.NewGenerator(gt.cfg)
+
+	accessToken, _, err := generator.GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri}, nil)
 	gt.Require().NoError(err)
 
+	var payload paseto.JSONToken
+
+	_, err = paseto.Parse(accessToken, &payload, nil, nil, map[paseto.Version]crypto.PublicKey{paseto.Version2: otherPub})
+	gt.Require().Error(err)
+}
+
+func (gt *generatorTest) TestAuthTokenGenerateAccessTokenFailureWhenKeyIsInvalid() {
+	generator := token.NewGenerator(gt.cfg)
+
+	_, _, err := generator.GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{ID: test.NewUUID(), PrivateKey: ed25519.PrivateKey{}}, nil)
+	gt.Require().Error(err)
+}
+
+func (gt *generatorTest) TestAuthTokenGenerateAccessTokenFailureWhenKeyIDIsEmpty() {
+	_, pri := test.GenerateKey()
+
+	generator := token.NewGenerator(gt.cfg)
+
+	_, _, err := generator.GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{PrivateKey: pri}, nil)
+	gt.Require().Error(err)
+}
+
+func (gt *generatorTest) TestAuthTokenGenerateRefreshToken() {
+	r := regexp.MustCompile("^idr_[A-Za-z0-9_-]{43}[a-f0-9]{8}$")
+
+	generator := token.NewGenerator(gt.cfg)
+
 	refreshToken, err := generator.GenerateRefreshToken()
 	gt.Require().NoError(err)
 
-	gt.Assert().True(isValidUUID(refreshToken))
+	gt.Assert().True(r.MatchString(refreshToken))
+	gt.Assert().True(token.IsValidRefreshToken(refreshToken))
+
+	other, err := generator.GenerateRefreshToken()
+	gt.Require().NoError(err)
+
+	gt.Assert().NotEqual(refreshToken, other)
 }
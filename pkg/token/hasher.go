@@ -0,0 +1,50 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/config"
+)
+
+const minSecretLength = 32
+
+type Hasher interface {
+	Hash(refreshToken string) string
+}
+
+type hmacTokenHasher struct {
+	secret []byte
+}
+
+func (th *hmacTokenHasher) Hash(refreshToken string) string {
+	mac := hmac.New(sha256.New, th.secret)
+	mac.Write([]byte(refreshToken))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateSecret(operation, name, secret string) error {
+	if len(secret) < minSecretLength {
+		return erx.WithArgs(
+			erx.Operation(operation),
+			erx.ValidationError,
+			fmt.Errorf("%s must be at least %d bytes, got %d", name, minSecretLength, len(secret)),
+		)
+	}
+
+	return nil
+}
+
+func NewHasher(cfg config.TokenConfig) (Hasher, error) {
+	secret := cfg.RefreshTokenSecret()
+	if err := validateSecret("NewHasher", "refresh token secret", secret); err != nil {
+		return nil, err
+	}
+
+	return &hmacTokenHasher{
+		secret: []byte(secret),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package token_test
+
+import (
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/config"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"testing"
+)
+
+func newTestHasher(t *testing.T, secret string) token.Hasher {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("RefreshTokenSecret").Return(secret)
+
+	hasher, err := token.NewHasher(mockTokenConfig)
+	require.NoError(t, err)
+
+	return hasher
+}
+
+func TestHasherHash(t *testing.T) {
+	refreshToken := test.NewUUID()
+
+	hasher := newTestHasher(t, test.TokenSecret)
+
+	assert.Len(t, hasher.Hash(refreshToken), 64)
+	assert.Equal(t, hasher.Hash(refreshToken), hasher.Hash(refreshToken))
+	assert.NotEqual(t, hasher.Hash(refreshToken), hasher.Hash(test.NewUUID()))
+}
+
+func TestHasherHashDependsOnSecret(t *testing.T) {
+	refreshToken := test.NewUUID()
+
+	assert.NotEqual(t, newTestHasher(t, test.TokenSecret).Hash(refreshToken), newTestHasher(t, test.RandString(32)).Hash(refreshToken))
+}
+
+func TestNewHasherFailureWhenSecretIsTooShort(t *testing.T) {
+	testCases := map[string]string{
+		"test failure when secret is empty":     test.EmptyString,
+		"test failure when secret is too short": test.RandString(31),
+	}
+
+	for name, secret := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTokenConfig := &config.MockTokenConfig{}
+			mockTokenConfig.On("RefreshTokenSecret").Return(secret)
+
+			_, err := token.NewHasher(mockTokenConfig)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"identification-service/pkg/libcrypto"
+	"time"
+)
+
+const idTokenAlgorithm = "EdDSA"
+
+type IDClaims struct {
+	Issuer     string `json:"iss"`
+	Subject    string `json:"sub"`
+	Audience   string `json:"aud"`
+	Expiration int64  `json:"exp"`
+	IssuedAt   int64  `json:"iat"`
+	AuthTime   int64  `json:"auth_time"`
+	Nonce      string `json:"nonce,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Name       string `json:"name,omitempty"`
+}
+
+type idTokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+func (tg *pasetoTokenGenerator) GenerateIDToken(ttl int, key libcrypto.Key, claims IDClaims) (string, error) {
+	wrap := func(err error) (string, error) {
+		return "", erx.WithArgs(erx.Operation("TokenGenerator.GenerateIDToken"), err)
+	}
+
+	if len(key.ID) == 0 {
+		return wrap(errors.New("signing key id cannot be empty"))
+	}
+
+	if len(key.PrivateKey) != ed25519.PrivateKeySize {
+		return wrap(fmt.Errorf("invalid signing key of length %d", len(key.PrivateKey)))
+	}
+
+	now := time.Now()
+
+	claims.IssuedAt = now.Unix()
+	claims.Expiration = now.Add(time.Duration(ttl) * time.Minute).Unix()
+
+	//NOTE: OIDC CLIENTS ONLY UNDERSTAND JWT, SO ID TOKENS ARE SIGNED AS JWS WITH THE SAME ED25519 KEYS PUBLISHED IN THE JWKS
+	header, err := encodeSegment(idTokenHeader{Algorithm: idTokenAlgorithm, Type: "JWT", KeyID: key.ID})
+	if err != nil {
+		return wrap(err)
+	}
+
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return wrap(err)
+	}
+
+	signingInput := header + "." + payload
+	signature := ed25519.Sign(ed25519.PrivateKey(key.PrivateKey), []byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func encodeSegment(data interface{}) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package token_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"strings"
+	"time"
+)
+
+func (gt *generatorTest) TestGenerateIDToken() {
+	pub, pri := test.GenerateKey()
+	keyID, userID, nonce := test.NewUUID(), test.NewUUID(), test.RandString(12)
+	authTime := time.Now().Add(-time.Minute).Unix()
+
+	idToken, err := token.NewGenerator(gt.cfg).GenerateIDToken(
+		10,
+		libcrypto.Key{ID: keyID, PrivateKey: pri},
+		token.IDClaims{
+			Issuer:   "https://id.example.com",
+			Subject:  userID,
+			Audience: "app",
+			AuthTime: authTime,
+			Nonce:    nonce,
+			Email:    "user@example.com",
+			Name:     "user",
+		},
+	)
+
+	gt.Require().NoError(err)
+
+	segments := strings.Split(idToken, ".")
+	gt.Require().Len(segments, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	gt.Require().NoError(err)
+
+	gt.Assert().True(ed25519.Verify(pub, []byte(segments[0]+"."+segments[1]), signature))
+
+	var header map[string]string
+	gt.Require().NoError(decodeSegment(segments[0], &header))
+
+	gt.Assert().Equal(map[string]string{"alg": "EdDSA", "typ": "JWT", "kid": keyID}, header)
+
+	var claims token.IDClaims
+	gt.Require().NoError(decodeSegment(segments[1], &claims))
+
+	gt.Assert().Equal("https://id.example.com", claims.Issuer)
+	gt.Assert().Equal(userID, claims.Subject)
+	gt.Assert().Equal("app", claims.Audience)
+	gt.Assert().Equal(authTime, claims.AuthTime)
+	gt.Assert().Equal(nonce, claims.Nonce)
+	gt.Assert().Equal(int64(600), claims.Expiration-claims.IssuedAt)
+}
+
+func (gt *generatorTest) TestGenerateIDTokenFailure() {
+	_, pri := test.GenerateKey()
+
+	testCases := map[string]libcrypto.Key{
+		"test failure when key id is empty":          {PrivateKey: pri},
+		"test failure when key is of invalid length": {ID: test.NewUUID(), PrivateKey: pri[:10]},
+	}
+
+	for name, key := range testCases {
+		gt.Run(name, func() {
+			_, err := token.NewGenerator(gt.cfg).GenerateIDToken(10, key, token.IDClaims{})
+			gt.Require().Error(err)
+		})
+	}
+}
+
+func decodeSegment(segment string, data interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, data)
+}
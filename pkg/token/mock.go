@@ -1,17 +1,83 @@
 package token
 
-import "github.com/stretchr/testify/mock"
+import (
+	"context"
+	"crypto/ed25519"
+	"github.com/stretchr/testify/mock"
+	"identification-service/pkg/libcrypto"
+)
 
 type MockGenerator struct {
 	mock.Mock
 }
 
-func (mock *MockGenerator) GenerateAccessToken(ttl int, subject string, claims map[string]string) (string, error) {
-	args := mock.Called(ttl, subject, claims)
-	return args.String(0), args.Error(1)
+func (mock *MockGenerator) GenerateAccessToken(ttl int, subject string, key libcrypto.Key, claims map[string]string) (string, Claims, error) {
+	args := mock.Called(ttl, subject, key, claims)
+	return args.String(0), args.Get(1).(Claims), args.Error(2)
 }
 
 func (mock *MockGenerator) GenerateRefreshToken() (string, error) {
 	args := mock.Called()
 	return args.String(0), args.Error(1)
 }
+
+func (mock *MockGenerator) GenerateIDToken(ttl int, key libcrypto.Key, claims IDClaims) (string, error) {
+	args := mock.Called(ttl, key, claims)
+	return args.String(0), args.Error(1)
+}
+
+type MockVerifier struct {
+	mock.Mock
+}
+
+func (mock *MockVerifier) KeyID(accessToken string) (string, error) {
+	args := mock.Called(accessToken)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockVerifier) VerifyAccessToken(accessToken string, publicKey ed25519.PublicKey) (Claims, error) {
+	args := mock.Called(accessToken, publicKey)
+	return args.Get(0).(Claims), args.Error(1)
+}
+
+type MockHasher struct {
+	mock.Mock
+}
+
+func (mock *MockHasher) Hash(refreshToken string) string {
+	args := mock.Called(refreshToken)
+	return args.String(0)
+}
+
+type MockSigner struct {
+	mock.Mock
+}
+
+func (mock *MockSigner) Sign(purpose, subject string, ttl int) (string, SignedClaims, error) {
+	args := mock.Called(purpose, subject, ttl)
+	return args.String(0), args.Get(1).(SignedClaims), args.Error(2)
+}
+
+func (mock *MockSigner) Verify(purpose, signedToken string) (SignedClaims, error) {
+	args := mock.Called(purpose, signedToken)
+	return args.Get(0).(SignedClaims), args.Error(1)
+}
+
+type MockDenylist struct {
+	mock.Mock
+}
+
+func (mock *MockDenylist) Track(ctx context.Context, groupID string, claims Claims) error {
+	args := mock.Called(ctx, groupID, claims)
+	return args.Error(0)
+}
+
+func (mock *MockDenylist) DenyGroup(ctx context.Context, groupID string) error {
+	args := mock.Called(ctx, groupID)
+	return args.Error(0)
+}
+
+func (mock *MockDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	args := mock.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
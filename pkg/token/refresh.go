@@ -0,0 +1,58 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"identification-service/pkg/util"
+	"strings"
+)
+
+const (
+	RefreshTokenPrefix = "idr_"
+
+	refreshTokenBytes       = 32
+	refreshTokenBodyLength  = 43
+	refreshTokenCheckLength = 8
+)
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, refreshTokenBytes)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(b)
+
+	return RefreshTokenPrefix + body + checksum(body), nil
+}
+
+func IsValidRefreshToken(refreshToken string) bool {
+	//NOTE: UUID REFRESH TOKENS ISSUED BEFORE THE PREFIXED FORMAT ARE ACCEPTED UNTIL THEIR SESSIONS EXPIRE
+	if util.IsValidUUID(refreshToken) {
+		return true
+	}
+
+	if !strings.HasPrefix(refreshToken, RefreshTokenPrefix) {
+		return false
+	}
+
+	rest := strings.TrimPrefix(refreshToken, RefreshTokenPrefix)
+	if len(rest) != refreshTokenBodyLength+refreshTokenCheckLength {
+		return false
+	}
+
+	body, check := rest[:refreshTokenBodyLength], rest[refreshTokenBodyLength:]
+
+	if _, err := base64.RawURLEncoding.DecodeString(body); err != nil {
+		return false
+	}
+
+	return checksum(body) == check
+}
+
+func checksum(body string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(body)))
+}
@@ -0,0 +1,42 @@
+package token_test
+
+import (
+	"github.com/stretchr/testify/assert"
+	"identification-service/pkg/config"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"strings"
+	"testing"
+)
+
+func TestIsValidRefreshToken(t *testing.T) {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("user")
+	mockTokenConfig.On("Issuer").Return("identification-service")
+
+	refreshToken, err := token.NewGenerator(mockTokenConfig).GenerateRefreshToken()
+	assert.NoError(t, err)
+
+	tampered := []byte(refreshToken)
+	tampered[len(token.RefreshTokenPrefix)] ^= 1
+
+	testCases := map[string]struct {
+		refreshToken string
+		expected     bool
+	}{
+		"test valid refresh token":                      {refreshToken: refreshToken, expected: true},
+		"test valid legacy uuid refresh token":          {refreshToken: test.NewUUID(), expected: true},
+		"test invalid when checksum does not match":     {refreshToken: string(tampered), expected: false},
+		"test invalid when prefix is missing":           {refreshToken: strings.TrimPrefix(refreshToken, token.RefreshTokenPrefix), expected: false},
+		"test invalid when prefix is different":         {refreshToken: "ida_" + strings.TrimPrefix(refreshToken, token.RefreshTokenPrefix), expected: false},
+		"test invalid when refresh token is truncated":  {refreshToken: refreshToken[:len(refreshToken)-1], expected: false},
+		"test invalid when refresh token is empty":      {refreshToken: "", expected: false},
+		"test invalid when body is not base64 url safe": {refreshToken: token.RefreshTokenPrefix + strings.Repeat("+", 43) + "00000000", expected: false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, token.IsValidRefreshToken(testCase.refreshToken))
+		})
+	}
+}
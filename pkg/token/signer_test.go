@@ -0,0 +1,97 @@
+package token_test
+
+import (
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/config"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, secret string) token.Signer {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("SignedTokenSecret").Return(secret)
+
+	signer, err := token.NewSigner(mockTokenConfig)
+	require.NoError(t, err)
+
+	return signer
+}
+
+func TestNewSignerFailureWhenSecretIsTooShort(t *testing.T) {
+	testCases := map[string]string{
+		"test failure when secret is empty":     test.EmptyString,
+		"test failure when secret is too short": test.RandString(31),
+	}
+
+	for name, secret := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockTokenConfig := &config.MockTokenConfig{}
+			mockTokenConfig.On("SignedTokenSecret").Return(secret)
+
+			_, err := token.NewSigner(mockTokenConfig)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.ValidationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestSignerSignAndVerify(t *testing.T) {
+	userID := test.NewUUID()
+
+	signer := newTestSigner(t, test.TokenSecret)
+
+	signedToken, claims, err := signer.Sign("verify-email", userID, 60)
+	require.NoError(t, err)
+
+	assert.Equal(t, userID, claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), claims.ExpiresAt, 2*time.Second)
+
+	res, err := signer.Verify("verify-email", signedToken)
+	require.NoError(t, err)
+
+	assert.Equal(t, claims, res)
+}
+
+func TestSignerVerifyFailure(t *testing.T) {
+	signer := newTestSigner(t, test.TokenSecret)
+
+	signedToken, _, err := signer.Sign("verify-email", test.NewUUID(), 60)
+	require.NoError(t, err)
+
+	expired, _, err := signer.Sign("verify-email", test.NewUUID(), -1)
+	require.NoError(t, err)
+
+	other, _, err := newTestSigner(t, test.RandString(32)).Sign("verify-email", test.NewUUID(), 60)
+	require.NoError(t, err)
+
+	tampered := []byte(signedToken)
+	tampered[0] ^= 1
+
+	testCases := map[string]struct {
+		purpose     string
+		signedToken string
+	}{
+		"test failure when token is expired":                  {purpose: "verify-email", signedToken: expired},
+		"test failure when token is issued for other purpose": {purpose: "reset-password", signedToken: signedToken},
+		"test failure when token is signed with other secret": {purpose: "verify-email", signedToken: other},
+		"test failure when token payload is tampered":         {purpose: "verify-email", signedToken: string(tampered)},
+		"test failure when token is malformed":                {purpose: "verify-email", signedToken: strings.Replace(signedToken, ".", "", 1)},
+		"test failure when token is empty":                    {purpose: "verify-email", signedToken: ""},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := signer.Verify(testCase.purpose, testCase.signedToken)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.AuthenticationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
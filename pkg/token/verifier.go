@@ -0,0 +1,125 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"github.com/o1egl/paseto"
+	"identification-service/pkg/config"
+	"time"
+)
+
+var registeredClaims = map[string]bool{
+	"aud": true, "iss": true, "jti": true, "sub": true, "exp": true, "iat": true, "nbf": true,
+}
+
+type Verifier interface {
+	KeyID(accessToken string) (string, error)
+	VerifyAccessToken(accessToken string, publicKey ed25519.PublicKey) (Claims, error)
+}
+
+type Claims struct {
+	KeyID      string
+	Jti        string
+	Subject    string
+	Audience   string
+	Issuer     string
+	IssuedAt   time.Time
+	Expiration time.Time
+
+	token paseto.JSONToken
+}
+
+func (c Claims) Get(key string) string {
+	return c.token.Get(key)
+}
+
+func (c Claims) Custom() map[string]string {
+	//NOTE: THE TOKEN KEEPS ITS CUSTOM CLAIMS UNEXPORTED, SO THEY ARE READ BACK FROM ITS JSON FORM
+	b, err := c.token.MarshalJSON()
+	if err != nil {
+		return nil
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil
+	}
+
+	claims := make(map[string]string)
+	for k, v := range raw {
+		if !registeredClaims[k] {
+			claims[k] = v
+		}
+	}
+
+	return claims
+}
+
+func newClaims(keyID string, jsonToken paseto.JSONToken) Claims {
+	return Claims{
+		KeyID:      keyID,
+		Jti:        jsonToken.Jti,
+		Subject:    jsonToken.Subject,
+		Audience:   jsonToken.Audience,
+		Issuer:     jsonToken.Issuer,
+		IssuedAt:   jsonToken.IssuedAt,
+		Expiration: jsonToken.Expiration,
+		token:      jsonToken,
+	}
+}
+
+type pasetoTokenVerifier struct {
+	issuer string
+}
+
+func (tv *pasetoTokenVerifier) KeyID(accessToken string) (string, error) {
+	var footer Footer
+
+	if err := paseto.ParseFooter(accessToken, &footer); err != nil {
+		return "", erx.WithArgs(erx.Operation("TokenVerifier.KeyID"), erx.AuthenticationError, err)
+	}
+
+	if len(footer.KeyID) == 0 {
+		return "", erx.WithArgs(erx.Operation("TokenVerifier.KeyID"), erx.AuthenticationError, errors.New("key id not present in footer"))
+	}
+
+	return footer.KeyID, nil
+}
+
+func (tv *pasetoTokenVerifier) VerifyAccessToken(accessToken string, publicKey ed25519.PublicKey) (Claims, error) {
+	wrap := func(err error) (Claims, error) {
+		return Claims{}, erx.WithArgs(erx.Operation("TokenVerifier.VerifyAccessToken"), erx.AuthenticationError, err)
+	}
+
+	if len(publicKey) != ed25519.PublicKeySize {
+		return wrap(fmt.Errorf("invalid verification key of length %d", len(publicKey)))
+	}
+
+	var jsonToken paseto.JSONToken
+	var footer Footer
+
+	if err := paseto.NewV2().Verify(accessToken, publicKey, &jsonToken, &footer); err != nil {
+		return wrap(err)
+	}
+
+	//NOTE: THE AUDIENCE IS NOT CHECKED, EXCHANGED TOKENS NAME THE SERVICE THEY ARE FOR AND ARE STILL INTROSPECTED HERE
+	err := jsonToken.Validate(
+		paseto.IssuedBy(tv.issuer),
+		paseto.ValidAt(time.Now()),
+	)
+
+	if err != nil {
+		return wrap(err)
+	}
+
+	return newClaims(footer.KeyID, jsonToken), nil
+}
+
+func NewVerifier(cfg config.TokenConfig) Verifier {
+	return &pasetoTokenVerifier{
+		issuer: cfg.Issuer(),
+	}
+}
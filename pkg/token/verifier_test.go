@@ -0,0 +1,137 @@
+package token_test
+
+import (
+	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/config"
+	"identification-service/pkg/libcrypto"
+	"identification-service/pkg/test"
+	"identification-service/pkg/token"
+	"testing"
+)
+
+type verifierTest struct {
+	suite.Suite
+	cfg config.TokenConfig
+}
+
+func (vt *verifierTest) SetupSuite() {
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("Audience").Return("user")
+	mockTokenConfig.On("Issuer").Return("identification-service")
+
+	vt.cfg = mockTokenConfig
+}
+
+func TestVerifier(t *testing.T) {
+	suite.Run(t, new(verifierTest))
+}
+
+func (vt *verifierTest) TestVerifyAccessTokenSuccess() {
+	pub, pri := test.GenerateKey()
+	keyID, subject := test.NewUUID(), test.NewUUID()
+
+	accessToken, _, err := token.NewGenerator(vt.cfg).
+		GenerateAccessToken(10, subject, libcrypto.Key{ID: keyID, PrivateKey: pri}, map[string]string{"session_id": keyID})
+	vt.Require().NoError(err)
+
+	verifier := token.NewVerifier(vt.cfg)
+
+	id, err := verifier.KeyID(accessToken)
+	vt.Require().NoError(err)
+	vt.Assert().Equal(keyID, id)
+
+	claims, err := verifier.VerifyAccessToken(accessToken, pub)
+	vt.Require().NoError(err)
+
+	vt.Assert().Equal(keyID, claims.KeyID)
+	vt.Assert().Equal(subject, claims.Subject)
+	vt.Assert().Equal("user", claims.Audience)
+	vt.Assert().Equal(keyID, claims.Get("session_id"))
+}
+
+func (vt *verifierTest) TestVerifyAccessTokenSuccessForAnotherAudience() {
+	pub, pri := test.GenerateKey()
+
+	accessToken, _, err := token.NewGenerator(vt.cfg).
+		GenerateAccessToken(10, test.NewUUID(), libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri}, map[string]string{token.AudienceClaim: "payments"})
+	vt.Require().NoError(err)
+
+	claims, err := token.NewVerifier(vt.cfg).VerifyAccessToken(accessToken, pub)
+	vt.Require().NoError(err)
+
+	vt.Assert().Equal("payments", claims.Audience)
+}
+
+func (vt *verifierTest) TestVerifyAccessTokenCustomClaims() {
+	pub, pri := test.GenerateKey()
+	sessionID := test.NewUUID()
+
+	accessToken, _, err := token.NewGenerator(vt.cfg).GenerateAccessToken(
+		10,
+		test.NewUUID(),
+		libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri},
+		map[string]string{"session_id": sessionID, token.ScopeClaim: test.ClientScope, token.AudienceClaim: "payments"},
+	)
+	vt.Require().NoError(err)
+
+	claims, err := token.NewVerifier(vt.cfg).VerifyAccessToken(accessToken, pub)
+	vt.Require().NoError(err)
+
+	vt.Assert().Equal(map[string]string{"session_id": sessionID, token.ScopeClaim: test.ClientScope}, claims.Custom())
+}
+
+func (vt *verifierTest) TestVerifyAccessTokenFailure() {
+	pub, pri := test.GenerateKey()
+	otherPub, _ := test.GenerateKey()
+
+	otherIssuerConfig := &config.MockTokenConfig{}
+	otherIssuerConfig.On("Audience").Return("user")
+	otherIssuerConfig.On("Issuer").Return("other-service")
+
+	newToken := func(cfg config.TokenConfig, ttl int) string {
+		accessToken, _, err := token.NewGenerator(cfg).
+			GenerateAccessToken(ttl, test.NewUUID(), libcrypto.Key{ID: test.NewUUID(), PrivateKey: pri}, nil)
+		vt.Require().NoError(err)
+		return accessToken
+	}
+
+	testCases := map[string]struct {
+		accessToken string
+		publicKey   []byte
+	}{
+		"test failure when token is signed by another key": {
+			accessToken: newToken(vt.cfg, 10),
+			publicKey:   otherPub,
+		},
+		"test failure when token is expired": {
+			accessToken: newToken(vt.cfg, -1),
+			publicKey:   pub,
+		},
+		"test failure when token is issued by another issuer": {
+			accessToken: newToken(otherIssuerConfig, 10),
+			publicKey:   pub,
+		},
+		"test failure when token is malformed": {
+			accessToken: "invalid token",
+			publicKey:   pub,
+		},
+		"test failure when public key is invalid": {
+			accessToken: newToken(vt.cfg, 10),
+			publicKey:   []byte{},
+		},
+	}
+
+	verifier := token.NewVerifier(vt.cfg)
+
+	for name, testCase := range testCases {
+		vt.Run(name, func() {
+			_, err := verifier.VerifyAccessToken(testCase.accessToken, testCase.publicKey)
+			vt.Require().Error(err)
+		})
+	}
+}
+
+func (vt *verifierTest) TestKeyIDFailureWhenTokenIsMalformed() {
+	_, err := token.NewVerifier(vt.cfg).KeyID("invalid token")
+	vt.Require().Error(err)
+}
@@ -3,24 +3,60 @@ package user
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (mock *MockService) CreateUser(ctx context.Context, name, email, password string) (string, error) {
-	args := mock.Called(ctx, name, email, password)
+func (mock *MockService) CreateUser(ctx context.Context, tenantID, name, email, password string) (string, error) {
+	args := mock.Called(ctx, tenantID, name, email, password)
 	return args.String(0), args.Error(1)
 }
 
-func (mock *MockService) UpdatePassword(ctx context.Context, email, oldPassword, newPassword string) error {
-	args := mock.Called(ctx, email, oldPassword, newPassword)
+func (mock *MockService) UpdatePassword(ctx context.Context, tenantID, email, oldPassword, newPassword string) error {
+	args := mock.Called(ctx, tenantID, email, oldPassword, newPassword)
 	return args.Error(0)
 }
 
-func (mock *MockService) GetUserID(ctx context.Context, email, password string) (string, error) {
-	args := mock.Called(ctx, email, password)
+func (mock *MockService) GetUserID(ctx context.Context, tenantID, email, password string) (string, error) {
+	args := mock.Called(ctx, tenantID, email, password)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockService) GetUser(ctx context.Context, userID string) (User, error) {
+	args := mock.Called(ctx, userID)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (mock *MockService) VerifyEmail(ctx context.Context, verificationToken string) error {
+	args := mock.Called(ctx, verificationToken)
+	return args.Error(0)
+}
+
+func (mock *MockService) ResendVerification(ctx context.Context, tenantID, email string) error {
+	args := mock.Called(ctx, tenantID, email)
+	return args.Error(0)
+}
+
+func (mock *MockService) ForgotPassword(ctx context.Context, tenantID, email string) error {
+	args := mock.Called(ctx, tenantID, email)
+	return args.Error(0)
+}
+
+func (mock *MockService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	args := mock.Called(ctx, resetToken, newPassword)
+	return args.Error(0)
+}
+
+func (mock *MockService) SendMagicLink(ctx context.Context, clientID, tenantID, email, name string, allowSignUp bool) error {
+	args := mock.Called(ctx, clientID, tenantID, email, name, allowSignUp)
+	return args.Error(0)
+}
+
+func (mock *MockService) RedeemMagicLink(ctx context.Context, clientID, tenantID, magicLinkToken string, allowSignUp bool) (string, error) {
+	args := mock.Called(ctx, clientID, tenantID, magicLinkToken, allowSignUp)
 	return args.String(0), args.Error(1)
 }
 
@@ -33,8 +69,13 @@ func (mock *MockStore) CreateUser(ctx context.Context, user User) (string, error
 	return args.String(0), args.Error(1)
 }
 
-func (mock *MockStore) GetUser(ctx context.Context, email string) (User, error) {
-	args := mock.Called(ctx, email)
+func (mock *MockStore) GetUser(ctx context.Context, tenantID, email string) (User, error) {
+	args := mock.Called(ctx, tenantID, email)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (mock *MockStore) GetUserByID(ctx context.Context, userID string) (User, error) {
+	args := mock.Called(ctx, userID)
 	return args.Get(0).(User), args.Error(1)
 }
 
@@ -42,3 +83,38 @@ func (mock *MockStore) UpdatePassword(ctx context.Context, userID string, newPas
 	args := mock.Called(ctx, userID, newPasswordHash, newPasswordSalt)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (mock *MockStore) CreateEmailVerification(ctx context.Context, id, userID string, expiresAt time.Time) error {
+	args := mock.Called(ctx, id, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (mock *MockStore) VerifyEmail(ctx context.Context, id, userID string, now time.Time) error {
+	args := mock.Called(ctx, id, userID, now)
+	return args.Error(0)
+}
+
+func (mock *MockStore) CreatePasswordReset(ctx context.Context, resetToken, userID string, expiresAt time.Time) error {
+	args := mock.Called(ctx, resetToken, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (mock *MockStore) ResetPassword(ctx context.Context, resetToken, newPasswordHash string, newPasswordSalt []byte, now time.Time) (string, error) {
+	args := mock.Called(ctx, resetToken, newPasswordHash, newPasswordSalt, now)
+	return args.String(0), args.Error(1)
+}
+
+func (mock *MockStore) MarkEmailVerified(ctx context.Context, userID string) error {
+	args := mock.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (mock *MockStore) CreateMagicLink(ctx context.Context, id, clientID, tenantID, email, name string, expiresAt time.Time) error {
+	args := mock.Called(ctx, id, clientID, tenantID, email, name, expiresAt)
+	return args.Error(0)
+}
+
+func (mock *MockStore) ConsumeMagicLink(ctx context.Context, id, clientID, tenantID, email string, now time.Time) (string, error) {
+	args := mock.Called(ctx, id, clientID, tenantID, email, now)
+	return args.String(0), args.Error(1)
+}
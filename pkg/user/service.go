@@ -2,31 +2,77 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/config"
+	"identification-service/pkg/mailer"
 	"identification-service/pkg/password"
 	"identification-service/pkg/queue"
+	"identification-service/pkg/token"
+	"strings"
+	"time"
 )
 
+const (
+	emailVerificationPurpose = "verify-email"
+	magicLinkPurposePrefix   = "magic-link"
+
+	resetTokenBytes = 32
+)
+
+type EmailVerificationEvent struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	ClientID string `json:"client_id,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+type PasswordResetEvent struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	ClientID string `json:"client_id,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+type MagicLinkEvent struct {
+	Email    string `json:"email"`
+	Token    string `json:"token"`
+	ClientID string `json:"client_id,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
 //TODO: RENAME (APPEND USER IN THE NAME)
 type Service interface {
-	CreateUser(ctx context.Context, name, email, password string) (string, error)
-	UpdatePassword(ctx context.Context, email, oldPassword, newPassword string) error
-	GetUserID(ctx context.Context, email, password string) (string, error)
+	CreateUser(ctx context.Context, tenantID, name, email, password string) (string, error)
+	UpdatePassword(ctx context.Context, tenantID, email, oldPassword, newPassword string) error
+	GetUserID(ctx context.Context, tenantID, email, password string) (string, error)
+	GetUser(ctx context.Context, userID string) (User, error)
+	VerifyEmail(ctx context.Context, verificationToken string) error
+	ResendVerification(ctx context.Context, tenantID, email string) error
+	ForgotPassword(ctx context.Context, tenantID, email string) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	SendMagicLink(ctx context.Context, clientID, tenantID, email, name string, allowSignUp bool) error
+	RedeemMagicLink(ctx context.Context, clientID, tenantID, magicLinkToken string, allowSignUp bool) (string, error)
 }
 
 // TODO: RENAME
 type userService struct {
 	cfg     config.QueueConfig
+	userCfg config.UserConfig
 	store   Store
 	encoder password.Encoder
+	signer  token.Signer
 	queue   queue.Queue
 }
 
-func (us *userService) CreateUser(ctx context.Context, name, email, password string) (string, error) {
+func (us *userService) CreateUser(ctx context.Context, tenantID, name, email, password string) (string, error) {
 	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.SignUp"), err) }
 
-	user, err := NewUserBuilder(us.encoder).Name(name).Email(email).Password(password).Build()
+	user, err := NewUserBuilder(us.encoder).TenantID(tenantID).Name(name).Email(email).Password(password).Build()
 	if err != nil {
 		return "", wrap(err)
 	}
@@ -39,11 +85,17 @@ func (us *userService) CreateUser(ctx context.Context, name, email, password str
 	//TODO: CHECK FOR ERROR
 	go us.queue.Push(us.cfg.SignUpQueueName(), []byte(userID))
 
+	//NOTE: THE USER IS ALREADY CREATED, A VERIFICATION WHICH FAILS TO BE SENT CAN BE SENT AGAIN WITH A RESEND
+	err = us.sendVerification(ctx, userID, user.email)
+	if err != nil {
+		return "", wrap(err)
+	}
+
 	return userID, nil
 }
 
-func (us *userService) GetUserID(ctx context.Context, email, password string) (string, error) {
-	user, err := us.store.GetUser(ctx, email)
+func (us *userService) GetUserID(ctx context.Context, tenantID, email, password string) (string, error) {
+	user, err := us.store.GetUser(ctx, tenantID, email)
 	if err != nil {
 		return "", erx.WithArgs(erx.Operation("Service.GetUserID"), erx.InvalidCredentialsError, err)
 	}
@@ -56,7 +108,16 @@ func (us *userService) GetUserID(ctx context.Context, email, password string) (s
 	return user.id, nil
 }
 
-func (us *userService) UpdatePassword(ctx context.Context, email, oldPassword, newPassword string) error {
+func (us *userService) GetUser(ctx context.Context, userID string) (User, error) {
+	user, err := us.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return User{}, erx.WithArgs(erx.Operation("Service.GetUser"), err)
+	}
+
+	return user, nil
+}
+
+func (us *userService) UpdatePassword(ctx context.Context, tenantID, email, oldPassword, newPassword string) error {
 	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.UpdatePassword"), err) }
 
 	err := us.encoder.ValidatePassword(newPassword)
@@ -64,7 +125,7 @@ func (us *userService) UpdatePassword(ctx context.Context, email, oldPassword, n
 		return wrap(err)
 	}
 
-	userID, err := us.GetUserID(ctx, email, oldPassword)
+	userID, err := us.GetUserID(ctx, tenantID, email, oldPassword)
 	if err != nil {
 		return wrap(err)
 	}
@@ -88,11 +149,312 @@ func (us *userService) UpdatePassword(ctx context.Context, email, oldPassword, n
 	return nil
 }
 
-func NewService(cfg config.QueueConfig, store Store, encoder password.Encoder, queue queue.Queue) Service {
+func (us *userService) VerifyEmail(ctx context.Context, verificationToken string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.VerifyEmail"), err) }
+
+	claims, err := us.signer.Verify(emailVerificationPurpose, verificationToken)
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = us.store.VerifyEmail(ctx, claims.ID, claims.Subject, time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (us *userService) ResendVerification(ctx context.Context, tenantID, email string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.ResendVerification"), err) }
+
+	user, err := us.store.GetUser(ctx, tenantID, email)
+	if err != nil {
+		//NOTE: AN UNKNOWN EMAIL IS NOT REPORTED, SO RESENDS CANNOT BE USED TO FIND OUT WHICH EMAILS ARE REGISTERED
+		if isNotFound(err) {
+			return nil
+		}
+
+		return wrap(err)
+	}
+
+	if user.emailVerified {
+		return nil
+	}
+
+	err = us.sendVerification(ctx, user.id, user.email)
+	if err != nil {
+		return wrap(err)
+	}
+
+	return nil
+}
+
+func (us *userService) sendVerification(ctx context.Context, userID, email string) error {
+	verificationToken, claims, err := us.signer.Sign(emailVerificationPurpose, userID, us.userCfg.EmailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	err = us.store.CreateEmailVerification(ctx, claims.ID, userID, claims.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	origin := mailer.OriginFromContext(ctx)
+
+	event, err := json.Marshal(EmailVerificationEvent{
+		UserID:   userID,
+		Email:    email,
+		Token:    verificationToken,
+		ClientID: origin.ClientID,
+		Locale:   origin.Locale,
+	})
+	if err != nil {
+		return err
+	}
+
+	//TODO: CHECK FOR ERROR
+	go us.queue.Push(us.cfg.EmailVerificationQueueName(), event)
+
+	return nil
+}
+
+func (us *userService) ForgotPassword(ctx context.Context, tenantID, email string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.ForgotPassword"), err) }
+
+	user, err := us.store.GetUser(ctx, tenantID, email)
+	if err != nil {
+		//NOTE: AN UNKNOWN EMAIL IS NOT REPORTED, SO RESETS CANNOT BE USED TO FIND OUT WHICH EMAILS ARE REGISTERED
+		if isNotFound(err) {
+			return nil
+		}
+
+		return wrap(err)
+	}
+
+	resetToken, err := newResetToken()
+	if err != nil {
+		return wrap(err)
+	}
+
+	expiresAt := time.Now().UTC().Add(time.Duration(us.userCfg.PasswordResetTTL()) * time.Second)
+
+	err = us.store.CreatePasswordReset(ctx, resetToken, user.id, expiresAt)
+	if err != nil {
+		return wrap(err)
+	}
+
+	origin := mailer.OriginFromContext(ctx)
+
+	event, err := json.Marshal(PasswordResetEvent{
+		UserID:   user.id,
+		Email:    user.email,
+		Token:    resetToken,
+		ClientID: origin.ClientID,
+		Locale:   origin.Locale,
+	})
+	if err != nil {
+		return wrap(err)
+	}
+
+	//TODO: CHECK FOR ERROR
+	go us.queue.Push(us.cfg.PasswordResetQueueName(), event)
+
+	return nil
+}
+
+func (us *userService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.ResetPassword"), err) }
+
+	err := us.encoder.ValidatePassword(newPassword)
+	if err != nil {
+		return wrap(err)
+	}
+
+	salt, err := us.encoder.GenerateSalt()
+	if err != nil {
+		return wrap(err)
+	}
+
+	key := us.encoder.GenerateKey(newPassword, salt)
+	hash := us.encoder.EncodeKey(key)
+
+	userID, err := us.store.ResetPassword(ctx, resetToken, hash, salt, time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return wrap(err)
+	}
+
+	//NOTE: THE SAME EVENT AS AN UPDATE PASSWORD, SO EVERY SESSION OF THE USER IS REVOKED
+	//TODO: CHECK FOR ERROR
+	go us.queue.Push(us.cfg.UpdatePasswordQueueName(), []byte(userID))
+
+	return nil
+}
+
+func (us *userService) SendMagicLink(ctx context.Context, clientID, tenantID, email, name string, allowSignUp bool) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.SendMagicLink"), err) }
+
+	_, err := us.store.GetUser(ctx, tenantID, email)
+	if err != nil {
+		if !isNotFound(err) {
+			return wrap(err)
+		}
+
+		//NOTE: AN UNKNOWN EMAIL IS NOT REPORTED, SO LINKS CANNOT BE USED TO FIND OUT WHICH EMAILS ARE REGISTERED
+		if !allowSignUp {
+			return nil
+		}
+	}
+
+	magicLinkToken, claims, err := us.signer.Sign(magicLinkPurpose(clientID), email, us.userCfg.MagicLinkTTL())
+	if err != nil {
+		return wrap(err)
+	}
+
+	err = us.store.CreateMagicLink(ctx, claims.ID, clientID, tenantID, email, name, claims.ExpiresAt)
+	if err != nil {
+		return wrap(err)
+	}
+
+	origin := mailer.OriginFromContext(ctx)
+
+	event, err := json.Marshal(MagicLinkEvent{
+		Email:    email,
+		Token:    magicLinkToken,
+		ClientID: origin.ClientID,
+		Locale:   origin.Locale,
+	})
+	if err != nil {
+		return wrap(err)
+	}
+
+	//TODO: CHECK FOR ERROR
+	go us.queue.Push(us.cfg.MagicLinkQueueName(), event)
+
+	return nil
+}
+
+func (us *userService) RedeemMagicLink(ctx context.Context, clientID, tenantID, magicLinkToken string, allowSignUp bool) (string, error) {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Service.RedeemMagicLink"), err) }
+
+	claims, err := us.signer.Verify(magicLinkPurpose(clientID), magicLinkToken)
+	if err != nil {
+		return "", wrap(err)
+	}
+
+	name, err := us.store.ConsumeMagicLink(ctx, claims.ID, clientID, tenantID, claims.Subject, time.Now().UTC())
+	if err != nil {
+		if isNotFound(err) {
+			return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+		}
+
+		return "", wrap(err)
+	}
+
+	var userID string
+
+	user, err := us.store.GetUser(ctx, tenantID, claims.Subject)
+	switch {
+	case err == nil:
+		userID = user.id
+	case isNotFound(err) && allowSignUp:
+		userID, err = us.createMagicLinkUser(ctx, tenantID, claims.Subject, name)
+		if err != nil {
+			return "", wrap(err)
+		}
+	case isNotFound(err):
+		return "", wrap(erx.WithArgs(erx.AuthenticationError, err))
+	default:
+		return "", wrap(err)
+	}
+
+	//NOTE: REDEEMING THE LINK PROVES THE USER OWNS THE EMAIL
+	if !user.emailVerified {
+		err = us.store.MarkEmailVerified(ctx, userID)
+		if err != nil {
+			return "", wrap(err)
+		}
+	}
+
+	return userID, nil
+}
+
+func (us *userService) createMagicLinkUser(ctx context.Context, tenantID, email, name string) (string, error) {
+	if len(name) == 0 {
+		name = strings.SplitN(email, "@", 2)[0]
+	}
+
+	//NOTE: THE USER IS GIVEN A RANDOM PASSWORD WHICH IS NEVER SHOWN, THEY CAN SET ONE LATER WITH A PASSWORD RESET
+	secret, err := newResetToken()
+	if err != nil {
+		return "", err
+	}
+
+	salt, err := us.encoder.GenerateSalt()
+	if err != nil {
+		return "", err
+	}
+
+	key := us.encoder.GenerateKey(secret, salt)
+
+	user, err := NewUserBuilder(us.encoder).
+		TenantID(tenantID).
+		Name(name).
+		Email(email).
+		PasswordHash(us.encoder.EncodeKey(key)).
+		PasswordSalt(salt).
+		Build()
+	if err != nil {
+		return "", err
+	}
+
+	userID, err := us.store.CreateUser(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	//TODO: CHECK FOR ERROR
+	go us.queue.Push(us.cfg.SignUpQueueName(), []byte(userID))
+
+	return userID, nil
+}
+
+func magicLinkPurpose(clientID string) string {
+	//NOTE: A LINK IS BOUND TO THE CLIENT IT WAS REQUESTED BY, SO IT CANNOT BE REDEEMED THROUGH ANY OTHER CLIENT
+	return magicLinkPurposePrefix + ":" + clientID
+}
+
+func newResetToken() (string, error) {
+	b := make([]byte, resetTokenBytes)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isNotFound(err error) bool {
+	t, ok := err.(*erx.Erx)
+	return ok && t.Kind() == erx.ResourceNotFoundError
+}
+
+func NewService(cfg config.QueueConfig, userCfg config.UserConfig, store Store, encoder password.Encoder, signer token.Signer, queue queue.Queue) Service {
 	return &userService{
 		cfg:     cfg,
+		userCfg: userCfg,
 		store:   store,
 		encoder: encoder,
+		signer:  signer,
 		queue:   queue,
 	}
 }
@@ -3,6 +3,7 @@ package user_test
 import (
 	"context"
 	"errors"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -10,14 +11,18 @@ import (
 	"identification-service/pkg/config"
 	"identification-service/pkg/password"
 	"identification-service/pkg/queue"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/token"
 	"identification-service/pkg/user"
 	"testing"
 )
 
 type createUserSuite struct {
 	cfg     config.QueueConfig
+	userCfg config.UserConfig
 	encoder password.Encoder
+	signer  token.Signer
 	queue   queue.Queue
 	suite.Suite
 }
@@ -40,10 +45,20 @@ func (cst *createUserSuite) SetupSuite() {
 	mockQueueConfig := &config.MockQueueConfig{}
 	mockQueueConfig.On("SignUpQueueName").Return("sign-up")
 	mockQueueConfig.On("UpdatePasswordQueueName").Return("update-password")
+	mockQueueConfig.On("EmailVerificationQueueName").Return("email-verification")
+
+	mockUserConfig := &config.MockUserConfig{}
+	mockUserConfig.On("EmailVerificationTTL").Return(86400)
+
+	mockSigner := &token.MockSigner{}
+	mockSigner.On("Sign", "verify-email", mock.AnythingOfType("string"), 86400).
+		Return(test.RandString(64), token.SignedClaims{ID: test.NewUUID()}, nil)
 
 	cst.encoder = mockEncoder
 	cst.queue = mockQueue
 	cst.cfg = mockQueueConfig
+	cst.userCfg = mockUserConfig
+	cst.signer = mockSigner
 }
 
 func (cst *createUserSuite) TestCreateUserSuccess() {
@@ -58,6 +73,13 @@ func (cst *createUserSuite) TestCreateUserSuccess() {
 		mock.AnythingOfType("*context.emptyCtx"),
 		mock.AnythingOfType("User"),
 	).Return(test.NewUUID(), nil)
+	mockStore.On(
+		"CreateEmailVerification",
+		mock.Anything,
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("string"),
+		mock.AnythingOfType("time.Time"),
+	).Return(nil)
 
 	//TODO: OVERRIDING GLOBAL ENCODER (REFACTOR)
 	mockEncoder := &password.MockEncoder{}
@@ -66,9 +88,9 @@ func (cst *createUserSuite) TestCreateUserSuccess() {
 	mockEncoder.On("EncodeKey", passwordKey).Return(passwordHash)
 	mockEncoder.On("ValidatePassword", userPassword).Return(nil)
 
-	service := user.NewService(cst.cfg, mockStore, mockEncoder, cst.queue)
+	service := user.NewService(cst.cfg, cst.userCfg, mockStore, mockEncoder, cst.signer, cst.queue)
 
-	_, err := service.CreateUser(context.Background(), test.RandString(8), test.NewEmail(), userPassword)
+	_, err := service.CreateUser(context.Background(), tenant.DefaultID, test.RandString(8), test.NewEmail(), userPassword)
 	assert.Nil(cst.T(), err)
 }
 
@@ -92,9 +114,9 @@ func (cst *createUserSuite) TestCreateFailureWhenStoreCallFails() {
 	mockEncoder.On("EncodeKey", passwordKey).Return(passwordHash)
 	mockEncoder.On("ValidatePassword", userPassword).Return(nil)
 
-	service := user.NewService(cst.cfg, mockStore, mockEncoder, cst.queue)
+	service := user.NewService(cst.cfg, cst.userCfg, mockStore, mockEncoder, cst.signer, cst.queue)
 
-	_, err := service.CreateUser(context.Background(), test.RandString(8), test.NewEmail(), userPassword)
+	_, err := service.CreateUser(context.Background(), tenant.DefaultID, test.RandString(8), test.NewEmail(), userPassword)
 	assert.NotNil(cst.T(), err)
 }
 
@@ -138,10 +160,10 @@ func (cst *createUserSuite) TestCreateFailureWhenInputIsInvalid() {
 	for name, testCase := range testCases {
 		cst.T().Run(name, func(t *testing.T) {
 
-			service := user.NewService(cst.cfg, &user.MockStore{}, cst.encoder, &queue.MockQueue{})
+			service := user.NewService(cst.cfg, cst.userCfg, &user.MockStore{}, cst.encoder, cst.signer, &queue.MockQueue{})
 
 			name, email, userPassword := testCase.input()
-			_, err := service.CreateUser(context.Background(), name, email, userPassword)
+			_, err := service.CreateUser(context.Background(), tenant.DefaultID, name, email, userPassword)
 			assert.NotNil(cst.T(), err)
 		})
 	}
@@ -157,7 +179,7 @@ func TestGetUserIDSuccess(t *testing.T) {
 	userPassword := test.NewPassword()
 
 	mockStore := &user.MockStore{}
-	mockStore.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), userEmail).Return(user.User{}, nil)
+	mockStore.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), tenant.DefaultID, userEmail).Return(user.User{}, nil)
 
 	mockEncoder := &password.MockEncoder{}
 	mockEncoder.On(
@@ -167,12 +189,36 @@ func TestGetUserIDSuccess(t *testing.T) {
 		mock.AnythingOfType("[]uint8"),
 	).Return(nil)
 
-	service := user.NewService(&config.MockQueueConfig{}, mockStore, mockEncoder, &queue.MockQueue{})
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, mockEncoder, &token.MockSigner{}, &queue.MockQueue{})
+
+	_, err := service.GetUserID(context.Background(), tenant.DefaultID, userEmail, userPassword)
+	require.NoError(t, err)
+}
+
+func TestGetUserSuccess(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &user.MockStore{}
+	mockStore.On("GetUserByID", mock.Anything, userID).Return(user.User{}, nil)
+
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, &password.MockEncoder{}, &token.MockSigner{}, &queue.MockQueue{})
 
-	_, err := service.GetUserID(context.Background(), userEmail, userPassword)
+	_, err := service.GetUser(context.Background(), userID)
 	require.NoError(t, err)
 }
 
+func TestGetUserFailure(t *testing.T) {
+	userID := test.NewUUID()
+
+	mockStore := &user.MockStore{}
+	mockStore.On("GetUserByID", mock.Anything, userID).Return(user.User{}, errors.New("failed to get user"))
+
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, &password.MockEncoder{}, &token.MockSigner{}, &queue.MockQueue{})
+
+	_, err := service.GetUser(context.Background(), userID)
+	require.Error(t, err)
+}
+
 func TestGetUserIDFailureWhenStoreCallsFails(t *testing.T) {
 	userEmail := test.NewEmail()
 
@@ -180,12 +226,13 @@ func TestGetUserIDFailureWhenStoreCallsFails(t *testing.T) {
 	mockStore.On(
 		"GetUser",
 		mock.AnythingOfType("*context.emptyCtx"),
+		tenant.DefaultID,
 		userEmail,
 	).Return(user.User{}, errors.New("failed to get user"))
 
-	service := user.NewService(&config.MockQueueConfig{}, mockStore, &password.MockEncoder{}, &queue.MockQueue{})
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, &password.MockEncoder{}, &token.MockSigner{}, &queue.MockQueue{})
 
-	_, err := service.GetUserID(context.Background(), userEmail, test.NewPassword())
+	_, err := service.GetUserID(context.Background(), tenant.DefaultID, userEmail, test.NewPassword())
 	require.Error(t, err)
 }
 
@@ -197,6 +244,7 @@ func TestGetUserIDFailureWhenPasswordVerificationFails(t *testing.T) {
 	mockStore.On(
 		"GetUser",
 		mock.AnythingOfType("*context.emptyCtx"),
+		tenant.DefaultID,
 		userEmail,
 	).Return(user.User{}, nil)
 
@@ -208,9 +256,9 @@ func TestGetUserIDFailureWhenPasswordVerificationFails(t *testing.T) {
 		mock.AnythingOfType("[]uint8"),
 	).Return(errors.New("invalid credentials"))
 
-	service := user.NewService(&config.MockQueueConfig{}, mockStore, mockEncoder, &queue.MockQueue{})
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, mockEncoder, &token.MockSigner{}, &queue.MockQueue{})
 
-	_, err := service.GetUserID(context.Background(), userEmail, userPassword)
+	_, err := service.GetUserID(context.Background(), tenant.DefaultID, userEmail, userPassword)
 	require.Error(t, err)
 }
 
@@ -223,7 +271,7 @@ func TestUpdatePasswordSuccess(t *testing.T) {
 	userPassword := test.NewPassword()
 
 	mockStore := &user.MockStore{}
-	mockStore.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), userEmail).Return(user.User{}, nil)
+	mockStore.On("GetUser", mock.AnythingOfType("*context.emptyCtx"), tenant.DefaultID, userEmail).Return(user.User{}, nil)
 	mockStore.On(
 		"UpdatePassword",
 		mock.AnythingOfType("*context.emptyCtx"),
@@ -248,9 +296,9 @@ func TestUpdatePasswordSuccess(t *testing.T) {
 	mockQueueConfig := &config.MockQueueConfig{}
 	mockQueueConfig.On("UpdatePasswordQueueName").Return("update-password")
 
-	service := user.NewService(mockQueueConfig, mockStore, mockEncoder, mockQueue)
+	service := user.NewService(mockQueueConfig, &config.MockUserConfig{}, mockStore, mockEncoder, &token.MockSigner{}, mockQueue)
 
-	err := service.UpdatePassword(context.Background(), userEmail, userPassword, userPasswordNew)
+	err := service.UpdatePassword(context.Background(), tenant.DefaultID, userEmail, userPassword, userPasswordNew)
 	require.NoError(t, err)
 }
 
@@ -287,6 +335,7 @@ func TestUpdatePasswordFailure(t *testing.T) {
 				mockStore.On(
 					"GetUser",
 					mock.AnythingOfType("*context.emptyCtx"),
+					tenant.DefaultID,
 					userEmail,
 				).Return(user.User{}, errors.New("failed to get user"))
 
@@ -306,6 +355,7 @@ func TestUpdatePasswordFailure(t *testing.T) {
 				mockStore.On(
 					"GetUser",
 					mock.AnythingOfType("*context.emptyCtx"),
+					tenant.DefaultID,
 					userEmail,
 				).Return(user.User{}, nil)
 
@@ -336,6 +386,7 @@ func TestUpdatePasswordFailure(t *testing.T) {
 				mockStore.On(
 					"GetUser",
 					mock.AnythingOfType("*context.emptyCtx"),
+					tenant.DefaultID,
 					userEmail,
 				).Return(user.User{}, nil)
 				mockStore.On(
@@ -372,10 +423,687 @@ func TestUpdatePasswordFailure(t *testing.T) {
 			mockQueueConfig := &config.MockQueueConfig{}
 			mockQueueConfig.On("UpdatePasswordQueueName").Return("update-password")
 
-			service := user.NewService(mockQueueConfig, testCase.store(), testCase.encoder(), &queue.MockQueue{})
+			service := user.NewService(mockQueueConfig, &config.MockUserConfig{}, testCase.store(), testCase.encoder(), &token.MockSigner{}, &queue.MockQueue{})
+
+			err := service.UpdatePassword(context.Background(), tenant.DefaultID, userEmail, userPassword, testCase.newPassword)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestVerifyEmailSuccess(t *testing.T) {
+	verificationToken := test.RandString(64)
+	claims := token.SignedClaims{ID: test.NewUUID(), Subject: test.NewUUID()}
+
+	mockSigner := &token.MockSigner{}
+	mockSigner.On("Verify", "verify-email", verificationToken).Return(claims, nil)
+
+	mockStore := &user.MockStore{}
+	mockStore.On(
+		"VerifyEmail",
+		mock.Anything,
+		claims.ID,
+		claims.Subject,
+		mock.AnythingOfType("time.Time"),
+	).Return(nil)
+
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, &password.MockEncoder{}, mockSigner, &queue.MockQueue{})
+
+	err := service.VerifyEmail(context.Background(), verificationToken)
+	require.NoError(t, err)
+}
+
+func TestVerifyEmailFailure(t *testing.T) {
+	verificationToken := test.RandString(64)
+	claims := token.SignedClaims{ID: test.NewUUID(), Subject: test.NewUUID()}
+
+	testCases := map[string]struct {
+		signer       func() token.Signer
+		store        func() user.Store
+		expectedKind erx.Kind
+	}{
+		"test failure when token is invalid": {
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Verify", "verify-email", verificationToken).
+					Return(token.SignedClaims{}, erx.WithArgs(erx.AuthenticationError, errors.New("invalid signature")))
+
+				return mockSigner
+			},
+			store:        func() user.Store { return &user.MockStore{} },
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when token was already used": {
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Verify", "verify-email", verificationToken).Return(claims, nil)
+
+				return mockSigner
+			},
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("VerifyEmail", mock.Anything, claims.ID, claims.Subject, mock.AnythingOfType("time.Time")).
+					Return(erx.WithArgs(erx.ResourceNotFoundError, errors.New("no pending verification")))
+
+				return mockStore
+			},
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when store call fails": {
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Verify", "verify-email", verificationToken).Return(claims, nil)
+
+				return mockSigner
+			},
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("VerifyEmail", mock.Anything, claims.ID, claims.Subject, mock.AnythingOfType("time.Time")).
+					Return(errors.New("failed to verify email"))
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, testCase.store(), &password.MockEncoder{}, testCase.signer(), &queue.MockQueue{})
+
+			err := service.VerifyEmail(context.Background(), verificationToken)
+			require.Error(t, err)
+
+			if len(testCase.expectedKind) != 0 {
+				assert.Equal(t, testCase.expectedKind, err.(*erx.Erx).Kind())
+			}
+		})
+	}
+}
+
+func TestResendVerificationSuccess(t *testing.T) {
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	unverifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	verifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).EmailVerified(true).Build()
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		store  func() user.Store
+		signer func() token.Signer
+	}{
+		"test resend for unverified user": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(unverifiedUser, nil)
+				mockStore.On("CreateEmailVerification", mock.Anything, mock.AnythingOfType("string"), userID, mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				return mockStore
+			},
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Sign", "verify-email", userID, 86400).
+					Return(test.RandString(64), token.SignedClaims{ID: test.NewUUID(), Subject: userID}, nil)
+
+				return mockSigner
+			},
+		},
+		"test resend is silently skipped for verified user": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(verifiedUser, nil)
+
+				return mockStore
+			},
+			signer: func() token.Signer { return &token.MockSigner{} },
+		},
+		"test resend is silently skipped for unknown email": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+
+				return mockStore
+			},
+			signer: func() token.Signer { return &token.MockSigner{} },
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockQueueConfig := &config.MockQueueConfig{}
+			mockQueueConfig.On("EmailVerificationQueueName").Return("email-verification")
+
+			mockUserConfig := &config.MockUserConfig{}
+			mockUserConfig.On("EmailVerificationTTL").Return(86400)
+
+			mockQueue := &queue.MockQueue{}
+			mockQueue.On("Push", "email-verification", mock.AnythingOfType("[]uint8")).Return(nil)
+
+			service := user.NewService(mockQueueConfig, mockUserConfig, testCase.store(), &password.MockEncoder{}, testCase.signer(), mockQueue)
+
+			err := service.ResendVerification(context.Background(), tenant.DefaultID, userEmail)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestResendVerificationFailure(t *testing.T) {
+	userEmail := test.NewEmail()
+
+	mockStore := &user.MockStore{}
+	mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(user.User{}, errors.New("failed to get user"))
+
+	service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, mockStore, &password.MockEncoder{}, &token.MockSigner{}, &queue.MockQueue{})
+
+	err := service.ResendVerification(context.Background(), tenant.DefaultID, userEmail)
+	require.Error(t, err)
+}
+
+func TestForgotPasswordSuccess(t *testing.T) {
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	existingUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		store func() user.Store
+	}{
+		"test reset is sent for existing user": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(existingUser, nil)
+				mockStore.On("CreatePasswordReset", mock.Anything, mock.AnythingOfType("string"), userID, mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				return mockStore
+			},
+		},
+		"test reset is silently skipped for unknown email": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockQueueConfig := &config.MockQueueConfig{}
+			mockQueueConfig.On("PasswordResetQueueName").Return("password-reset")
+
+			mockUserConfig := &config.MockUserConfig{}
+			mockUserConfig.On("PasswordResetTTL").Return(3600)
+
+			mockQueue := &queue.MockQueue{}
+			mockQueue.On("Push", "password-reset", mock.AnythingOfType("[]uint8")).Return(nil)
+
+			service := user.NewService(mockQueueConfig, mockUserConfig, testCase.store(), &password.MockEncoder{}, &token.MockSigner{}, mockQueue)
+
+			err := service.ForgotPassword(context.Background(), tenant.DefaultID, userEmail)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestForgotPasswordFailure(t *testing.T) {
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	existingUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		store func() user.Store
+	}{
+		"test failure when get user fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(user.User{}, errors.New("failed to get user"))
+
+				return mockStore
+			},
+		},
+		"test failure when create password reset fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(existingUser, nil)
+				mockStore.On("CreatePasswordReset", mock.Anything, mock.AnythingOfType("string"), userID, mock.AnythingOfType("time.Time")).
+					Return(errors.New("failed to create password reset"))
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserConfig := &config.MockUserConfig{}
+			mockUserConfig.On("PasswordResetTTL").Return(3600)
+
+			service := user.NewService(&config.MockQueueConfig{}, mockUserConfig, testCase.store(), &password.MockEncoder{}, &token.MockSigner{}, &queue.MockQueue{})
+
+			err := service.ForgotPassword(context.Background(), tenant.DefaultID, userEmail)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestResetPasswordSuccess(t *testing.T) {
+	userID := test.NewUUID()
+	resetToken := test.RandString(43)
+	passwordSalt := test.RandBytes(86)
+	passwordKey := test.RandBytes(32)
+	passwordHash := test.RandString(44)
+	newPassword := test.NewPassword()
+
+	mockEncoder := &password.MockEncoder{}
+	mockEncoder.On("ValidatePassword", newPassword).Return(nil)
+	mockEncoder.On("GenerateSalt").Return(passwordSalt, nil)
+	mockEncoder.On("GenerateKey", newPassword, passwordSalt).Return(passwordKey)
+	mockEncoder.On("EncodeKey", passwordKey).Return(passwordHash)
+
+	mockStore := &user.MockStore{}
+	mockStore.On("ResetPassword", mock.Anything, resetToken, passwordHash, passwordSalt, mock.AnythingOfType("time.Time")).
+		Return(userID, nil)
+
+	mockQueueConfig := &config.MockQueueConfig{}
+	mockQueueConfig.On("UpdatePasswordQueueName").Return("update-password")
+
+	mockQueue := &queue.MockQueue{}
+	mockQueue.On("Push", "update-password", []byte(userID)).Return(nil)
+
+	service := user.NewService(mockQueueConfig, &config.MockUserConfig{}, mockStore, mockEncoder, &token.MockSigner{}, mockQueue)
+
+	err := service.ResetPassword(context.Background(), resetToken, newPassword)
+	require.NoError(t, err)
+}
+
+func TestResetPasswordFailure(t *testing.T) {
+	resetToken := test.RandString(43)
+	passwordSalt := test.RandBytes(86)
+	passwordKey := test.RandBytes(32)
+	passwordHash := test.RandString(44)
+	newPassword := test.NewPassword()
+
+	validEncoder := func() password.Encoder {
+		mockEncoder := &password.MockEncoder{}
+		mockEncoder.On("ValidatePassword", newPassword).Return(nil)
+		mockEncoder.On("GenerateSalt").Return(passwordSalt, nil)
+		mockEncoder.On("GenerateKey", newPassword, passwordSalt).Return(passwordKey)
+		mockEncoder.On("EncodeKey", passwordKey).Return(passwordHash)
+
+		return mockEncoder
+	}
+
+	testCases := map[string]struct {
+		store        func() user.Store
+		encoder      func() password.Encoder
+		expectedKind erx.Kind
+	}{
+		"test failure when new password does not match spec": {
+			store: func() user.Store { return &user.MockStore{} },
+			encoder: func() password.Encoder {
+				mockEncoder := &password.MockEncoder{}
+				mockEncoder.On("ValidatePassword", newPassword).
+					Return(erx.WithArgs(erx.ValidationError, errors.New("invalid password")))
+
+				return mockEncoder
+			},
+			expectedKind: erx.ValidationError,
+		},
+		"test failure when reset token is invalid or used": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ResetPassword", mock.Anything, resetToken, passwordHash, passwordSalt, mock.AnythingOfType("time.Time")).
+					Return("", erx.WithArgs(erx.ResourceNotFoundError, errors.New("no pending password reset")))
+
+				return mockStore
+			},
+			encoder:      validEncoder,
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when store call fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ResetPassword", mock.Anything, resetToken, passwordHash, passwordSalt, mock.AnythingOfType("time.Time")).
+					Return("", errors.New("failed to reset password"))
+
+				return mockStore
+			},
+			encoder: validEncoder,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, testCase.store(), testCase.encoder(), &token.MockSigner{}, &queue.MockQueue{})
+
+			err := service.ResetPassword(context.Background(), resetToken, newPassword)
+			require.Error(t, err)
+
+			if len(testCase.expectedKind) != 0 {
+				assert.Equal(t, testCase.expectedKind, err.(*erx.Erx).Kind())
+			}
+		})
+	}
+}
+
+func TestSendMagicLinkSuccess(t *testing.T) {
+	clientID := test.NewUUID()
+	userEmail := test.NewEmail()
+	userName := test.RandString(8)
+
+	existingUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(test.NewUUID()).Name(userName).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		allowSignUp bool
+		store       func() user.Store
+		signer      func() token.Signer
+	}{
+		"test link is sent for existing user": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(existingUser, nil)
+				mockStore.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("string"), clientID, tenant.DefaultID, userEmail, userName, mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				return mockStore
+			},
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Sign", "magic-link:"+clientID, userEmail, 900).
+					Return(test.RandString(64), token.SignedClaims{ID: test.NewUUID(), Subject: userEmail}, nil)
+
+				return mockSigner
+			},
+		},
+		"test link is sent for unknown email when sign up is allowed": {
+			allowSignUp: true,
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+				mockStore.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("string"), clientID, tenant.DefaultID, userEmail, userName, mock.AnythingOfType("time.Time")).
+					Return(nil)
+
+				return mockStore
+			},
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Sign", "magic-link:"+clientID, userEmail, 900).
+					Return(test.RandString(64), token.SignedClaims{ID: test.NewUUID(), Subject: userEmail}, nil)
+
+				return mockSigner
+			},
+		},
+		"test link is silently skipped for unknown email when sign up is not allowed": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+
+				return mockStore
+			},
+			signer: func() token.Signer { return &token.MockSigner{} },
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockQueueConfig := &config.MockQueueConfig{}
+			mockQueueConfig.On("MagicLinkQueueName").Return("magic-link")
+
+			mockUserConfig := &config.MockUserConfig{}
+			mockUserConfig.On("MagicLinkTTL").Return(900)
+
+			mockQueue := &queue.MockQueue{}
+			mockQueue.On("Push", "magic-link", mock.AnythingOfType("[]uint8")).Return(nil)
+
+			service := user.NewService(mockQueueConfig, mockUserConfig, testCase.store(), &password.MockEncoder{}, testCase.signer(), mockQueue)
+
+			err := service.SendMagicLink(context.Background(), clientID, tenant.DefaultID, userEmail, userName, testCase.allowSignUp)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSendMagicLinkFailure(t *testing.T) {
+	clientID := test.NewUUID()
+	userEmail := test.NewEmail()
+
+	existingUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(test.NewUUID()).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	validSigner := func() token.Signer {
+		mockSigner := &token.MockSigner{}
+		mockSigner.On("Sign", "magic-link:"+clientID, userEmail, 900).
+			Return(test.RandString(64), token.SignedClaims{ID: test.NewUUID(), Subject: userEmail}, nil)
+
+		return mockSigner
+	}
+
+	testCases := map[string]struct {
+		store  func() user.Store
+		signer func() token.Signer
+	}{
+		"test failure when get user fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(user.User{}, errors.New("failed to get user"))
+
+				return mockStore
+			},
+			signer: func() token.Signer { return &token.MockSigner{} },
+		},
+		"test failure when signing fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(existingUser, nil)
+
+				return mockStore
+			},
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Sign", "magic-link:"+clientID, userEmail, 900).
+					Return("", token.SignedClaims{}, errors.New("failed to sign"))
+
+				return mockSigner
+			},
+		},
+		"test failure when create magic link fails": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(existingUser, nil)
+				mockStore.On("CreateMagicLink", mock.Anything, mock.AnythingOfType("string"), clientID, tenant.DefaultID, userEmail, "", mock.AnythingOfType("time.Time")).
+					Return(errors.New("failed to create magic link"))
+
+				return mockStore
+			},
+			signer: validSigner,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockUserConfig := &config.MockUserConfig{}
+			mockUserConfig.On("MagicLinkTTL").Return(900)
+
+			service := user.NewService(&config.MockQueueConfig{}, mockUserConfig, testCase.store(), &password.MockEncoder{}, testCase.signer(), &queue.MockQueue{})
+
+			err := service.SendMagicLink(context.Background(), clientID, tenant.DefaultID, userEmail, "", false)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRedeemMagicLinkSuccess(t *testing.T) {
+	clientID := test.NewUUID()
+	userID := test.NewUUID()
+	userEmail := test.NewEmail()
+	magicLinkToken := test.RandString(64)
+	claims := token.SignedClaims{ID: test.NewUUID(), Subject: userEmail}
+
+	unverifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).Build()
+	require.NoError(t, err)
+
+	verifiedUser, err := user.NewUserBuilder(&password.MockEncoder{}).ID(userID).Name(test.RandString(8)).Email(userEmail).EmailVerified(true).Build()
+	require.NoError(t, err)
+
+	passwordSalt := test.RandBytes(86)
+	passwordKey := test.RandBytes(32)
+
+	testCases := map[string]struct {
+		allowSignUp bool
+		store       func() user.Store
+	}{
+		"test redeem for verified user": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", nil)
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(verifiedUser, nil)
+
+				return mockStore
+			},
+		},
+		"test redeem marks email of unverified user as verified": {
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", nil)
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(unverifiedUser, nil)
+				mockStore.On("MarkEmailVerified", mock.Anything, userID).Return(nil)
+
+				return mockStore
+			},
+		},
+		"test redeem creates user on first use when sign up is allowed": {
+			allowSignUp: true,
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", nil)
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
+				mockStore.On("CreateUser", mock.Anything, mock.AnythingOfType("User")).Return(userID, nil)
+				mockStore.On("MarkEmailVerified", mock.Anything, userID).Return(nil)
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockSigner := &token.MockSigner{}
+			mockSigner.On("Verify", "magic-link:"+clientID, magicLinkToken).Return(claims, nil)
+
+			mockEncoder := &password.MockEncoder{}
+			mockEncoder.On("GenerateSalt").Return(passwordSalt, nil)
+			mockEncoder.On("GenerateKey", mock.AnythingOfType("string"), passwordSalt).Return(passwordKey)
+			mockEncoder.On("EncodeKey", passwordKey).Return(test.RandString(44))
+
+			mockQueueConfig := &config.MockQueueConfig{}
+			mockQueueConfig.On("SignUpQueueName").Return("sign-up")
+
+			mockQueue := &queue.MockQueue{}
+			mockQueue.On("Push", "sign-up", []byte(userID)).Return(nil)
+
+			service := user.NewService(mockQueueConfig, &config.MockUserConfig{}, testCase.store(), mockEncoder, mockSigner, mockQueue)
+
+			res, err := service.RedeemMagicLink(context.Background(), clientID, tenant.DefaultID, magicLinkToken, testCase.allowSignUp)
+			require.NoError(t, err)
+
+			assert.Equal(t, userID, res)
+		})
+	}
+}
+
+func TestRedeemMagicLinkFailure(t *testing.T) {
+	clientID := test.NewUUID()
+	userEmail := test.NewEmail()
+	magicLinkToken := test.RandString(64)
+	claims := token.SignedClaims{ID: test.NewUUID(), Subject: userEmail}
+
+	validSigner := func() token.Signer {
+		mockSigner := &token.MockSigner{}
+		mockSigner.On("Verify", "magic-link:"+clientID, magicLinkToken).Return(claims, nil)
+
+		return mockSigner
+	}
+
+	testCases := map[string]struct {
+		signer       func() token.Signer
+		store        func() user.Store
+		expectedKind erx.Kind
+	}{
+		"test failure when token is invalid": {
+			signer: func() token.Signer {
+				mockSigner := &token.MockSigner{}
+				mockSigner.On("Verify", "magic-link:"+clientID, magicLinkToken).
+					Return(token.SignedClaims{}, erx.WithArgs(erx.AuthenticationError, errors.New("invalid signature")))
+
+				return mockSigner
+			},
+			store:        func() user.Store { return &user.MockStore{} },
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when link was already used": {
+			signer: validSigner,
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", erx.WithArgs(erx.ResourceNotFoundError, errors.New("no pending magic link")))
+
+				return mockStore
+			},
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when user does not exist and sign up is not allowed": {
+			signer: validSigner,
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", nil)
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).
+					Return(user.User{}, erx.WithArgs(erx.ResourceNotFoundError, errors.New("no user found")))
 
-			err := service.UpdatePassword(context.Background(), userEmail, userPassword, testCase.newPassword)
+				return mockStore
+			},
+			expectedKind: erx.AuthenticationError,
+		},
+		"test failure when get user fails": {
+			signer: validSigner,
+			store: func() user.Store {
+				mockStore := &user.MockStore{}
+				mockStore.On("ConsumeMagicLink", mock.Anything, claims.ID, clientID, tenant.DefaultID, userEmail, mock.AnythingOfType("time.Time")).
+					Return("", nil)
+				mockStore.On("GetUser", mock.Anything, tenant.DefaultID, userEmail).Return(user.User{}, errors.New("failed to get user"))
+
+				return mockStore
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			service := user.NewService(&config.MockQueueConfig{}, &config.MockUserConfig{}, testCase.store(), &password.MockEncoder{}, testCase.signer(), &queue.MockQueue{})
+
+			_, err := service.RedeemMagicLink(context.Background(), clientID, tenant.DefaultID, magicLinkToken, false)
 			require.Error(t, err)
+
+			if len(testCase.expectedKind) != 0 {
+				assert.Equal(t, testCase.expectedKind, err.(*erx.Erx).Kind())
+			}
 		})
 	}
 }
@@ -2,34 +2,55 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/lib/pq"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/database"
+	"identification-service/pkg/token"
+	"time"
 )
 
 const (
-	insertUser     = `insert into users (name, email, password_hash, password_salt) values ($1, $2, $3, $4) returning id`
-	getUserByEmail = `select id, name, email, password_hash, password_salt from users where email = $1`
-	updatePassword = `update users set password_hash=$1, password_salt=$2 where id=$3`
+	insertUser              = `insert into users (tenant_id, name, email, password_hash, password_salt) values ($1, $2, $3, $4, $5) returning id`
+	getUserByEmail          = `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where tenant_id = $1 and email = $2`
+	getUserByID             = `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where id = $1`
+	updatePassword          = `update users set password_hash=$1, password_salt=$2 where id=$3`
+	createEmailVerification = `with previous as (delete from email_verifications where user_id=$2) insert into email_verifications (id, user_id, expires_at) values ($1, $2, $3)`
+	createPasswordReset     = `with previous as (delete from password_resets where user_id=$2) insert into password_resets (token_hash, user_id, expires_at) values ($1, $2, $3)`
+	resetPassword           = `with reset as (delete from password_resets where token_hash=$1 and expires_at > $2 returning user_id) update users set password_hash=$3, password_salt=$4, updated_at=(now() at time zone 'utc') from reset where users.id=reset.user_id returning users.id`
+	verifyEmail             = `with verification as (delete from email_verifications where id=$1 and user_id=$2 and expires_at > $3 returning user_id) update users set email_verified=true, updated_at=(now() at time zone 'utc') from verification where users.id=verification.user_id`
+	markEmailVerified       = `update users set email_verified=true, updated_at=(now() at time zone 'utc') where id=$1 and email_verified=false`
+	createMagicLink         = `with previous as (delete from magic_links where tenant_id=$3 and email=$4) insert into magic_links (id, client_id, tenant_id, email, name, expires_at) values ($1, $2, $3, $4, $5, $6)`
+	consumeMagicLink        = `delete from magic_links where id=$1 and client_id=$2 and tenant_id=$3 and email=$4 and expires_at > $5 returning name`
 )
 
 type Store interface {
 	CreateUser(ctx context.Context, user User) (string, error)
-	GetUser(ctx context.Context, email string) (User, error)
+	GetUser(ctx context.Context, tenantID, email string) (User, error)
+	GetUserByID(ctx context.Context, userID string) (User, error)
 	UpdatePassword(ctx context.Context, userID string, newPasswordHash string, newPasswordSalt []byte) (int64, error)
+	CreateEmailVerification(ctx context.Context, id, userID string, expiresAt time.Time) error
+	VerifyEmail(ctx context.Context, id, userID string, now time.Time) error
+	CreatePasswordReset(ctx context.Context, resetToken, userID string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, resetToken, newPasswordHash string, newPasswordSalt []byte, now time.Time) (string, error)
+	MarkEmailVerified(ctx context.Context, userID string) error
+	CreateMagicLink(ctx context.Context, id, clientID, tenantID, email, name string, expiresAt time.Time) error
+	ConsumeMagicLink(ctx context.Context, id, clientID, tenantID, email string, now time.Time) (string, error)
 }
 
 // TODO: RENAME
 type userStore struct {
-	db database.SQLDatabase
+	db     database.SQLDatabase
+	hasher token.Hasher
 }
 
 func (us *userStore) CreateUser(ctx context.Context, user User) (string, error) {
 	var id string
 
 	//TODO: REMOVE THIS HARD CODING
-	row := us.db.QueryRowContext(ctx, insertUser, user.name, user.email, user.passwordHash, user.passwordSalt)
+	row := us.db.QueryRowContext(ctx, insertUser, user.tenantID, user.name, user.email, user.passwordHash, user.passwordSalt)
 	if row.Err() != nil {
 		if pgErr, ok := row.Err().(*pq.Error); ok {
 			if pgErr.Code == "23505" {
@@ -48,22 +69,47 @@ func (us *userStore) CreateUser(ctx context.Context, user User) (string, error)
 	return id, nil
 }
 
-func (us *userStore) GetUser(ctx context.Context, email string) (User, error) {
+func (us *userStore) GetUser(ctx context.Context, tenantID, email string) (User, error) {
 	var user User
 
-	row := us.db.QueryRowContext(context.Background(), getUserByEmail, email)
+	//NOTE: EMAILS ARE ONLY UNIQUE WITHIN A TENANT, THE SAME EMAIL MAY BELONG TO A DIFFERENT USER IN ANOTHER TENANT
+	row := us.db.QueryRowContext(context.Background(), getUserByEmail, tenantID, email)
 	if row.Err() != nil {
 		return user, erx.WithArgs(erx.Operation("Store.GetUser"), row.Err())
 	}
 
-	err := row.Scan(&user.id, &user.name, &user.email, &user.passwordHash, &user.passwordSalt)
+	err := row.Scan(&user.id, &user.tenantID, &user.name, &user.email, &user.emailVerified, &user.passwordHash, &user.passwordSalt)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, erx.WithArgs(erx.Operation("Store.GetUser"), erx.ResourceNotFoundError, err)
+		}
+
 		return user, erx.WithArgs(erx.Operation("Store.GetUser"), err)
 	}
 
 	return user, nil
 }
 
+func (us *userStore) GetUserByID(ctx context.Context, userID string) (User, error) {
+	var user User
+
+	row := us.db.QueryRowContext(ctx, getUserByID, userID)
+	if row.Err() != nil {
+		return user, erx.WithArgs(erx.Operation("Store.GetUserByID"), row.Err())
+	}
+
+	err := row.Scan(&user.id, &user.tenantID, &user.name, &user.email, &user.emailVerified, &user.passwordHash, &user.passwordSalt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, erx.WithArgs(erx.Operation("Store.GetUserByID"), erx.ResourceNotFoundError, err)
+		}
+
+		return user, erx.WithArgs(erx.Operation("Store.GetUserByID"), err)
+	}
+
+	return user, nil
+}
+
 func (us *userStore) UpdatePassword(ctx context.Context, userID string, newPasswordHash string, newPasswordSalt []byte) (int64, error) {
 	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.UpdatePassword"), err) }
 
@@ -84,8 +130,106 @@ func (us *userStore) UpdatePassword(ctx context.Context, userID string, newPassw
 	return c, nil
 }
 
-func NewStore(db database.SQLDatabase) Store {
+func (us *userStore) CreateEmailVerification(ctx context.Context, id, userID string, expiresAt time.Time) error {
+	//NOTE: ONLY THE LATEST VERIFICATION OF A USER IS KEPT, SO A RESEND INVALIDATES EVERY TOKEN SENT BEFORE IT
+	_, err := us.db.ExecContext(ctx, createEmailVerification, id, userID, expiresAt)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreateEmailVerification"), err)
+	}
+
+	return nil
+}
+
+func (us *userStore) VerifyEmail(ctx context.Context, id, userID string, now time.Time) error {
+	wrap := func(err error) error { return erx.WithArgs(erx.Operation("Store.VerifyEmail"), err) }
+
+	res, err := us.db.ExecContext(ctx, verifyEmail, id, userID, now)
+	if err != nil {
+		return wrap(err)
+	}
+
+	c, err := res.RowsAffected()
+	if err != nil {
+		return wrap(err)
+	}
+
+	if c == 0 {
+		return wrap(erx.WithArgs(erx.ResourceNotFoundError, fmt.Errorf("no pending verification found with id %s", id)))
+	}
+
+	return nil
+}
+
+func (us *userStore) CreatePasswordReset(ctx context.Context, resetToken, userID string, expiresAt time.Time) error {
+	//NOTE: ONLY THE HASH OF A RESET TOKEN IS PERSISTED AND A NEW RESET INVALIDATES EVERY TOKEN SENT BEFORE IT
+	_, err := us.db.ExecContext(ctx, createPasswordReset, us.hasher.Hash(resetToken), userID, expiresAt)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreatePasswordReset"), err)
+	}
+
+	return nil
+}
+
+func (us *userStore) ResetPassword(ctx context.Context, resetToken, newPasswordHash string, newPasswordSalt []byte, now time.Time) (string, error) {
+	var userID string
+
+	err := us.db.QueryRowContext(ctx, resetPassword, us.hasher.Hash(resetToken), now, newPasswordHash, newPasswordSalt).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", erx.WithArgs(
+				erx.Operation("Store.ResetPassword"),
+				erx.ResourceNotFoundError,
+				errors.New("no pending password reset found for token"),
+			)
+		}
+
+		return "", erx.WithArgs(erx.Operation("Store.ResetPassword"), err)
+	}
+
+	return userID, nil
+}
+
+func (us *userStore) MarkEmailVerified(ctx context.Context, userID string) error {
+	_, err := us.db.ExecContext(ctx, markEmailVerified, userID)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.MarkEmailVerified"), err)
+	}
+
+	return nil
+}
+
+func (us *userStore) CreateMagicLink(ctx context.Context, id, clientID, tenantID, email, name string, expiresAt time.Time) error {
+	//NOTE: ONLY THE LATEST LINK OF AN EMAIL IS KEPT, SO A NEW REQUEST INVALIDATES EVERY LINK SENT BEFORE IT
+	_, err := us.db.ExecContext(ctx, createMagicLink, id, clientID, tenantID, email, name, expiresAt)
+	if err != nil {
+		return erx.WithArgs(erx.Operation("Store.CreateMagicLink"), err)
+	}
+
+	return nil
+}
+
+func (us *userStore) ConsumeMagicLink(ctx context.Context, id, clientID, tenantID, email string, now time.Time) (string, error) {
+	var name string
+
+	err := us.db.QueryRowContext(ctx, consumeMagicLink, id, clientID, tenantID, email, now).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", erx.WithArgs(
+				erx.Operation("Store.ConsumeMagicLink"),
+				erx.ResourceNotFoundError,
+				fmt.Errorf("no pending magic link found with id %s", id),
+			)
+		}
+
+		return "", erx.WithArgs(erx.Operation("Store.ConsumeMagicLink"), err)
+	}
+
+	return name, nil
+}
+
+func NewStore(db database.SQLDatabase, hasher token.Hasher) Store {
 	return &userStore{
-		db: db,
+		db:     db,
+		hasher: hasher,
 	}
 }
@@ -7,7 +7,9 @@ import (
 	"identification-service/pkg/config"
 	"identification-service/pkg/database"
 	"identification-service/pkg/password"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/token"
 	"identification-service/pkg/user"
 	"testing"
 )
@@ -23,7 +25,11 @@ func (ust *userStoreIntegrationSuite) SetupSuite() {
 	cfg := config.NewConfig("../../local.env")
 	ust.db = test.NewDB(ust.T(), cfg)
 	ust.ctx = context.Background()
-	ust.store = user.NewStore(ust.db)
+
+	hasher, err := token.NewHasher(cfg.TokenConfig())
+	ust.Require().NoError(err)
+
+	ust.store = user.NewStore(ust.db, hasher)
 }
 
 func (ust *userStoreIntegrationSuite) TearDownSuite() {
@@ -53,12 +59,12 @@ func (ust *userStoreIntegrationSuite) TestGetUserSuccess() {
 	_, err := ust.store.CreateUser(ust.ctx, nu)
 	require.NoError(ust.T(), err)
 
-	_, err = ust.store.GetUser(ust.ctx, email)
+	_, err = ust.store.GetUser(ust.ctx, tenant.DefaultID, email)
 	require.NoError(ust.T(), err)
 }
 
 func (ust *userStoreIntegrationSuite) TestGetUserFailureWhenEmailIsNotPresent() {
-	_, err := ust.store.GetUser(ust.ctx, test.NewEmail())
+	_, err := ust.store.GetUser(ust.ctx, tenant.DefaultID, test.NewEmail())
 	require.Error(ust.T(), err)
 }
 
@@ -5,21 +5,27 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/nsnikhil/erx"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"identification-service/pkg/config"
 	"identification-service/pkg/database"
 	"identification-service/pkg/password"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
+	"identification-service/pkg/token"
 	"identification-service/pkg/user"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type userStoreSuite struct {
 	suite.Suite
-	db    database.SQLDatabase
-	mock  sqlmock.Sqlmock
-	store user.Store
+	db     database.SQLDatabase
+	mock   sqlmock.Sqlmock
+	hasher token.Hasher
+	store  user.Store
 }
 
 func (ust *userStoreSuite) SetupSuite() {
@@ -28,7 +34,14 @@ func (ust *userStoreSuite) SetupSuite() {
 	ust.db = database.NewSQLDatabase(sqlDB, test.QueryTTL)
 	ust.mock = mock
 
-	ust.store = user.NewStore(ust.db)
+	mockTokenConfig := &config.MockTokenConfig{}
+	mockTokenConfig.On("RefreshTokenSecret").Return(test.TokenSecret)
+
+	hasher, err := token.NewHasher(mockTokenConfig)
+	ust.Require().NoError(err)
+
+	ust.hasher = hasher
+	ust.store = user.NewStore(ust.db, ust.hasher)
 }
 
 func (ust *userStoreSuite) TestCreateUserSuccess() {
@@ -39,10 +52,10 @@ func (ust *userStoreSuite) TestCreateUserSuccess() {
 	passwordHash := test.RandString(44)
 	userPassword := test.NewPassword()
 
-	query := `insert into users (name, email, password_hash, password_salt) values ($1, $2, $3, $4) returning id`
+	query := `insert into users (tenant_id, name, email, password_hash, password_salt) values ($1, $2, $3, $4, $5) returning id`
 
 	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(name, email, passwordHash, passwordSalt).
+		WithArgs(tenant.DefaultID, name, email, passwordHash, passwordSalt).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(test.NewUUID()))
 
 	mockEncoder := &password.MockEncoder{}
@@ -68,10 +81,10 @@ func (ust *userStoreSuite) TestCreateUserFailure() {
 	passwordHash := test.RandString(44)
 	userPassword := test.NewPassword()
 
-	query := `insert into users (name, email, password_hash, password_salt) values ($1, $2, $3, $4) returning id`
+	query := `insert into users (tenant_id, name, email, password_hash, password_salt) values ($1, $2, $3, $4, $5) returning id`
 
 	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(name, email, passwordHash, passwordSalt).
+		WithArgs(tenant.DefaultID, name, email, passwordHash, passwordSalt).
 		WillReturnError(errors.New("failed to create new User"))
 
 	mockEncoder := &password.MockEncoder{}
@@ -92,21 +105,21 @@ func (ust *userStoreSuite) TestCreateUserFailure() {
 func (ust *userStoreSuite) TestGetUserSuccess() {
 	userEmail := test.NewEmail()
 
-	query := `select id, name, email, password_hash, password_salt from users where email = $1`
+	query := `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where tenant_id = $1 and email = $2`
 
 	rows := sqlmock.NewRows(
 		[]string{
-			"id", "name", "email", "passwordhash", "passwordsalt",
+			"id", "tenant_id", "name", "email", "email_verified", "passwordhash", "passwordsalt",
 		},
 	)
 
 	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(userEmail).
-		WillReturnRows(rows.AddRow("", "", "", "", ""))
+		WithArgs(tenant.DefaultID, userEmail).
+		WillReturnRows(rows.AddRow("", tenant.DefaultID, "", "", false, "", ""))
 
-	us := user.NewStore(ust.db)
+	us := ust.store
 
-	_, err := us.GetUser(context.Background(), userEmail)
+	_, err := us.GetUser(context.Background(), tenant.DefaultID, userEmail)
 	require.NoError(ust.T(), err)
 
 	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
@@ -115,20 +128,78 @@ func (ust *userStoreSuite) TestGetUserSuccess() {
 func (ust *userStoreSuite) TestGetUserFailure() {
 	userEmail := test.NewEmail()
 
-	query := `select id, name, email, password_hash, password_salt from users where email = $1`
+	query := `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where tenant_id = $1 and email = $2`
 
 	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
-		WithArgs(userEmail).
+		WithArgs(tenant.DefaultID, userEmail).
 		WillReturnError(errors.New("failed to get data"))
 
-	us := user.NewStore(ust.db)
+	us := ust.store
 
-	_, err := us.GetUser(context.Background(), userEmail)
+	_, err := us.GetUser(context.Background(), tenant.DefaultID, userEmail)
 	require.Error(ust.T(), err)
 
 	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
 }
 
+func (ust *userStoreSuite) TestGetUserByIDSuccess() {
+	userID, name, email := test.NewUUID(), test.RandString(8), test.NewEmail()
+
+	query := `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where id = $1`
+
+	rows := sqlmock.NewRows([]string{"id", "tenant_id", "name", "email", "email_verified", "password_hash", "password_salt"}).
+		AddRow(userID, tenant.DefaultID, name, email, true, test.RandString(44), test.RandBytes(86))
+
+	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID).WillReturnRows(rows)
+
+	u, err := ust.store.GetUserByID(context.Background(), userID)
+	require.NoError(ust.T(), err)
+
+	ust.Assert().Equal(userID, u.ID())
+	ust.Assert().Equal(tenant.DefaultID, u.TenantID())
+	ust.Assert().Equal(name, u.Name())
+	ust.Assert().Equal(email, u.Email())
+	ust.Assert().True(u.EmailVerified())
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestGetUserByIDFailure() {
+	userID := test.NewUUID()
+
+	query := `select id, tenant_id, name, email, email_verified, password_hash, password_salt from users where id = $1`
+
+	testCases := map[string]struct {
+		expectQuery  func(eq *sqlmock.ExpectedQuery)
+		expectedKind erx.Kind
+	}{
+		"test failure when user is not found": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id", "name", "email", "email_verified", "password_hash", "password_salt"}))
+			},
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnError(errors.New("failed to get data"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		ust.Run(name, func() {
+			testCase.expectQuery(ust.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(userID))
+
+			_, err := ust.store.GetUserByID(context.Background(), userID)
+			require.Error(ust.T(), err)
+
+			ust.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
 func (ust *userStoreSuite) TestUpdatePasswordSuccess() {
 	email := test.NewEmail()
 	passwordSalt := test.RandBytes(86)
@@ -140,7 +211,7 @@ func (ust *userStoreSuite) TestUpdatePasswordSuccess() {
 		WithArgs(passwordHash, passwordSalt, email).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	us := user.NewStore(ust.db)
+	us := ust.store
 
 	_, err := us.UpdatePassword(context.Background(), email, passwordHash, passwordSalt)
 	require.NoError(ust.T(), err)
@@ -159,7 +230,7 @@ func (ust *userStoreSuite) TestUpdatePasswordFailure() {
 		WithArgs(passwordHash, passwordSalt, email).
 		WillReturnError(errors.New("failed to update password"))
 
-	us := user.NewStore(ust.db)
+	us := ust.store
 
 	_, err := us.UpdatePassword(context.Background(), email, passwordHash, passwordSalt)
 	require.Error(ust.T(), err)
@@ -167,6 +238,287 @@ func (ust *userStoreSuite) TestUpdatePasswordFailure() {
 	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
 }
 
+func (ust *userStoreSuite) TestCreateEmailVerificationSuccess() {
+	id, userID, expiresAt := test.NewUUID(), test.NewUUID(), time.Now().Add(time.Hour)
+
+	query := `with previous as (delete from email_verifications where user_id=$2) insert into email_verifications (id, user_id, expires_at) values ($1, $2, $3)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, userID, expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := ust.store.CreateEmailVerification(context.Background(), id, userID, expiresAt)
+	require.NoError(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestCreateEmailVerificationFailure() {
+	id, userID, expiresAt := test.NewUUID(), test.NewUUID(), time.Now().Add(time.Hour)
+
+	query := `with previous as (delete from email_verifications where user_id=$2) insert into email_verifications (id, user_id, expires_at) values ($1, $2, $3)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, userID, expiresAt).
+		WillReturnError(errors.New("failed to create email verification"))
+
+	err := ust.store.CreateEmailVerification(context.Background(), id, userID, expiresAt)
+	require.Error(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestVerifyEmailSuccess() {
+	id, userID, now := test.NewUUID(), test.NewUUID(), time.Now()
+
+	query := `with verification as (delete from email_verifications where id=$1 and user_id=$2 and expires_at > $3 returning user_id) update users set email_verified=true`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, userID, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := ust.store.VerifyEmail(context.Background(), id, userID, now)
+	require.NoError(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestVerifyEmailFailure() {
+	id, userID, now := test.NewUUID(), test.NewUUID(), time.Now()
+
+	query := `with verification as (delete from email_verifications where id=$1 and user_id=$2 and expires_at > $3 returning user_id) update users set email_verified=true`
+
+	testCases := map[string]struct {
+		expectExec   func(ee *sqlmock.ExpectedExec)
+		expectedKind erx.Kind
+	}{
+		"test failure when verification is used or expired": {
+			expectExec: func(ee *sqlmock.ExpectedExec) {
+				ee.WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when exec fails": {
+			expectExec: func(ee *sqlmock.ExpectedExec) {
+				ee.WillReturnError(errors.New("failed to verify email"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		ust.Run(name, func() {
+			testCase.expectExec(ust.mock.ExpectExec(regexp.QuoteMeta(query)).WithArgs(id, userID, now))
+
+			err := ust.store.VerifyEmail(context.Background(), id, userID, now)
+			require.Error(ust.T(), err)
+
+			ust.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestCreatePasswordResetSuccess() {
+	resetToken, userID, expiresAt := test.RandString(43), test.NewUUID(), time.Now().Add(time.Hour)
+
+	query := `with previous as (delete from password_resets where user_id=$2) insert into password_resets (token_hash, user_id, expires_at) values ($1, $2, $3)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(ust.hasher.Hash(resetToken), userID, expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := ust.store.CreatePasswordReset(context.Background(), resetToken, userID, expiresAt)
+	require.NoError(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestCreatePasswordResetFailure() {
+	resetToken, userID, expiresAt := test.RandString(43), test.NewUUID(), time.Now().Add(time.Hour)
+
+	query := `with previous as (delete from password_resets where user_id=$2) insert into password_resets (token_hash, user_id, expires_at) values ($1, $2, $3)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(ust.hasher.Hash(resetToken), userID, expiresAt).
+		WillReturnError(errors.New("failed to create password reset"))
+
+	err := ust.store.CreatePasswordReset(context.Background(), resetToken, userID, expiresAt)
+	require.Error(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestResetPasswordSuccess() {
+	resetToken, userID, now := test.RandString(43), test.NewUUID(), time.Now()
+	passwordHash, passwordSalt := test.RandString(44), test.RandBytes(86)
+
+	query := `with reset as (delete from password_resets where token_hash=$1 and expires_at > $2 returning user_id) update users set password_hash=$3, password_salt=$4`
+
+	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(ust.hasher.Hash(resetToken), now, passwordHash, passwordSalt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(userID))
+
+	id, err := ust.store.ResetPassword(context.Background(), resetToken, passwordHash, passwordSalt, now)
+	require.NoError(ust.T(), err)
+
+	ust.Assert().Equal(userID, id)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestResetPasswordFailure() {
+	resetToken, now := test.RandString(43), time.Now()
+	passwordHash, passwordSalt := test.RandString(44), test.RandBytes(86)
+
+	query := `with reset as (delete from password_resets where token_hash=$1 and expires_at > $2 returning user_id) update users set password_hash=$3, password_salt=$4`
+
+	testCases := map[string]struct {
+		expectQuery  func(eq *sqlmock.ExpectedQuery)
+		expectedKind erx.Kind
+	}{
+		"test failure when reset is used or expired": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnError(errors.New("failed to reset password"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		ust.Run(name, func() {
+			testCase.expectQuery(ust.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(ust.hasher.Hash(resetToken), now, passwordHash, passwordSalt))
+
+			_, err := ust.store.ResetPassword(context.Background(), resetToken, passwordHash, passwordSalt, now)
+			require.Error(ust.T(), err)
+
+			ust.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestMarkEmailVerifiedSuccess() {
+	userID := test.NewUUID()
+
+	query := `update users set email_verified=true, updated_at=(now() at time zone 'utc') where id=$1 and email_verified=false`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := ust.store.MarkEmailVerified(context.Background(), userID)
+	require.NoError(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestMarkEmailVerifiedFailure() {
+	userID := test.NewUUID()
+
+	query := `update users set email_verified=true, updated_at=(now() at time zone 'utc') where id=$1 and email_verified=false`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(userID).
+		WillReturnError(errors.New("failed to mark email verified"))
+
+	err := ust.store.MarkEmailVerified(context.Background(), userID)
+	require.Error(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestCreateMagicLinkSuccess() {
+	id, clientID, tenantID, email, name := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.NewEmail(), test.RandString(8)
+	expiresAt := time.Now().Add(time.Minute * 15)
+
+	query := `with previous as (delete from magic_links where tenant_id=$3 and email=$4) insert into magic_links (id, client_id, tenant_id, email, name, expires_at) values ($1, $2, $3, $4, $5, $6)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, clientID, tenantID, email, name, expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := ust.store.CreateMagicLink(context.Background(), id, clientID, tenantID, email, name, expiresAt)
+	require.NoError(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestCreateMagicLinkFailure() {
+	id, clientID, tenantID, email, name := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.NewEmail(), test.RandString(8)
+	expiresAt := time.Now().Add(time.Minute * 15)
+
+	query := `with previous as (delete from magic_links where tenant_id=$3 and email=$4) insert into magic_links (id, client_id, tenant_id, email, name, expires_at) values ($1, $2, $3, $4, $5, $6)`
+
+	ust.mock.ExpectExec(regexp.QuoteMeta(query)).
+		WithArgs(id, clientID, tenantID, email, name, expiresAt).
+		WillReturnError(errors.New("failed to create magic link"))
+
+	err := ust.store.CreateMagicLink(context.Background(), id, clientID, tenantID, email, name, expiresAt)
+	require.Error(ust.T(), err)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestConsumeMagicLinkSuccess() {
+	id, clientID, tenantID, email, name, now := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.NewEmail(), test.RandString(8), time.Now()
+
+	query := `delete from magic_links where id=$1 and client_id=$2 and tenant_id=$3 and email=$4 and expires_at > $5 returning name`
+
+	ust.mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(id, clientID, tenantID, email, now).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow(name))
+
+	res, err := ust.store.ConsumeMagicLink(context.Background(), id, clientID, tenantID, email, now)
+	require.NoError(ust.T(), err)
+
+	ust.Assert().Equal(name, res)
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
+func (ust *userStoreSuite) TestConsumeMagicLinkFailure() {
+	id, clientID, tenantID, email, now := test.NewUUID(), test.NewUUID(), test.NewUUID(), test.NewEmail(), time.Now()
+
+	query := `delete from magic_links where id=$1 and client_id=$2 and tenant_id=$3 and email=$4 and expires_at > $5 returning name`
+
+	testCases := map[string]struct {
+		expectQuery  func(eq *sqlmock.ExpectedQuery)
+		expectedKind erx.Kind
+	}{
+		"test failure when link is used or expired": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnRows(sqlmock.NewRows([]string{"name"}))
+			},
+			expectedKind: erx.ResourceNotFoundError,
+		},
+		"test failure when query fails": {
+			expectQuery: func(eq *sqlmock.ExpectedQuery) {
+				eq.WillReturnError(errors.New("failed to consume magic link"))
+			},
+		},
+	}
+
+	for name, testCase := range testCases {
+		ust.Run(name, func() {
+			testCase.expectQuery(ust.mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id, clientID, tenantID, email, now))
+
+			_, err := ust.store.ConsumeMagicLink(context.Background(), id, clientID, tenantID, email, now)
+			require.Error(ust.T(), err)
+
+			ust.Assert().Equal(testCase.expectedKind, err.(*erx.Erx).Kind())
+		})
+	}
+
+	require.NoError(ust.T(), ust.mock.ExpectationsWereMet())
+}
+
 func TestStore(t *testing.T) {
 	suite.Run(t, new(userStoreSuite))
 }
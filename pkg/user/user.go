@@ -5,15 +5,23 @@ import (
 	"fmt"
 	"github.com/nsnikhil/erx"
 	"identification-service/pkg/password"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/util"
 	"time"
 )
 
+const (
+	AttributeName  = "name"
+	AttributeEmail = "email"
+)
+
 type User struct {
-	id string
+	id       string
+	tenantID string
 
-	name  string
-	email string
+	name          string
+	email         string
+	emailVerified bool
 
 	passwordHash string
 	passwordSalt []byte
@@ -22,11 +30,48 @@ type User struct {
 	updatedAt time.Time
 }
 
+func (u User) ID() string {
+	return u.id
+}
+
+func (u User) TenantID() string {
+	return u.tenantID
+}
+
+func (u User) Name() string {
+	return u.name
+}
+
+func (u User) Email() string {
+	return u.email
+}
+
+func (u User) EmailVerified() bool {
+	return u.emailVerified
+}
+
+func (u User) Attribute(attribute string) string {
+	switch attribute {
+	case AttributeName:
+		return u.name
+	case AttributeEmail:
+		return u.email
+	default:
+		return ""
+	}
+}
+
+func IsValidAttribute(attribute string) bool {
+	return attribute == AttributeName || attribute == AttributeEmail
+}
+
 type Builder struct {
-	id string
+	id       string
+	tenantID string
 
-	name  string
-	email string
+	name          string
+	email         string
+	emailVerified bool
 
 	passwordHash string
 	passwordSalt []byte
@@ -53,6 +98,20 @@ func (b *Builder) ID(id string) *Builder {
 	return b
 }
 
+func (b *Builder) TenantID(tenantID string) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if !util.IsValidUUID(tenantID) {
+		b.err = fmt.Errorf("invalid tenant id %s", tenantID)
+		return b
+	}
+
+	b.tenantID = tenantID
+	return b
+}
+
 func (b *Builder) Name(name string) *Builder {
 	if b.err != nil {
 		return b
@@ -81,6 +140,15 @@ func (b *Builder) Email(email string) *Builder {
 	return b
 }
 
+func (b *Builder) EmailVerified(emailVerified bool) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	b.emailVerified = emailVerified
+	return b
+}
+
 func (b *Builder) Password(password string) *Builder {
 	if b.err != nil {
 		return b
@@ -175,16 +243,18 @@ func (b *Builder) Build() (User, error) {
 	//TODO: ADD VALIDATION AGAIN SINCE USER MIGHT NOT HAVE SET ANY REQUIRED FIELDS USING BUILDER PATTERN
 
 	return User{
-		id:           b.id,
-		name:         b.name,
-		email:        b.email,
-		passwordSalt: b.passwordSalt,
-		passwordHash: b.passwordHash,
-		createdAt:    b.createdAt,
-		updatedAt:    b.updatedAt,
+		id:            b.id,
+		tenantID:      b.tenantID,
+		name:          b.name,
+		email:         b.email,
+		emailVerified: b.emailVerified,
+		passwordSalt:  b.passwordSalt,
+		passwordHash:  b.passwordHash,
+		createdAt:     b.createdAt,
+		updatedAt:     b.updatedAt,
 	}, nil
 }
 
 func NewUserBuilder(encoder password.Encoder) *Builder {
-	return &Builder{encoder: encoder}
+	return &Builder{tenantID: tenant.DefaultID, encoder: encoder}
 }
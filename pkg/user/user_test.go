@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"identification-service/pkg/password"
+	"identification-service/pkg/tenant"
 	"identification-service/pkg/test"
 	"identification-service/pkg/user"
 	"testing"
@@ -14,6 +15,7 @@ import (
 
 const (
 	idKey               = "id"
+	tenantIDKey         = "tenantID"
 	nameKey             = "name"
 	emailKey            = "email"
 	userPasswordKey     = "userPassword"
@@ -48,6 +50,7 @@ func TestCreateNewUserValidationFailure(t *testing.T) {
 	testCases := map[string]map[string]interface{}{
 		"test failure when id is empty":                     {idKey: ""},
 		"test failure when id is invalid":                   {idKey: "invalid id"},
+		"test failure when tenant id is invalid":            {tenantIDKey: "invalid tenant id"},
 		"test failure when name is empty":                   {nameKey: ""},
 		"test failure when email is empty":                  {emailKey: ""},
 		"test failure when password is empty":               {userPasswordKey: ""},
@@ -87,6 +90,7 @@ func buildUser(d map[string]interface{}) (user.User, error) {
 
 	return user.NewUserBuilder(mockEncoder).
 		ID(either(d[idKey], test.NewUUID()).(string)).
+		TenantID(either(d[tenantIDKey], tenant.DefaultID).(string)).
 		Name(either(d[nameKey], test.RandString(8)).(string)).
 		Email(either(d[emailKey], test.NewEmail()).(string)).
 		Password(either(d[userPasswordKey], userPassword).(string)).
@@ -112,3 +116,17 @@ func TestCreateNewUserFailureForInvalidPassword(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestUserAttribute(t *testing.T) {
+	name, email := test.RandString(8), test.NewEmail()
+
+	u, err := buildUser(map[string]interface{}{nameKey: name, emailKey: email})
+	assert.NoError(t, err)
+
+	assert.Equal(t, name, u.Attribute(user.AttributeName))
+	assert.Equal(t, email, u.Attribute(user.AttributeEmail))
+	assert.Equal(t, "", u.Attribute("password"))
+
+	assert.True(t, user.IsValidAttribute(user.AttributeEmail))
+	assert.False(t, user.IsValidAttribute("password"))
+}
@@ -0,0 +1,166 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborSimple   = 7
+
+	cborMaxDepth = 16
+)
+
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	//NOTE: ONLY THE SUBSET OF CBOR AUTHENTICATORS USE IS DECODED, INDEFINITE LENGTHS, TAGS AND FLOATS ARE REJECTED
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("cbor nested too deep")
+	}
+
+	major, arg, rest, err := decodeCBORHead(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor integer overflow")
+		}
+
+		return int64(arg), rest, nil
+
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.New("cbor integer overflow")
+		}
+
+		return -1 - int64(arg), rest, nil
+
+	case cborBytes, cborText:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor string exceeds data")
+		}
+
+		if major == cborText {
+			return string(rest[:arg]), rest[arg:], nil
+		}
+
+		return rest[:arg], rest[arg:], nil
+
+	case cborArray:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor array exceeds data")
+		}
+
+		items := make([]interface{}, 0, arg)
+
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			items = append(items, item)
+		}
+
+		return items, rest, nil
+
+	case cborMap:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.New("cbor map exceeds data")
+		}
+
+		entries := make(map[interface{}]interface{}, arg)
+
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor map key must be an integer or a string")
+			}
+
+			if _, ok := entries[key]; ok {
+				return nil, nil, fmt.Errorf("duplicate cbor map key %v", key)
+			}
+
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			entries[key] = value
+		}
+
+		return entries, rest, nil
+
+	case cborSimple:
+		switch arg {
+		case 20:
+			return false, rest, nil
+		case 21:
+			return true, rest, nil
+		case 22:
+			return nil, rest, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("unsupported cbor item %d/%d", major, arg)
+}
+
+func decodeCBORHead(data []byte) (byte, uint64, []byte, error) {
+	if len(data) == 0 {
+		return 0, 0, nil, errors.New("unexpected end of cbor data")
+	}
+
+	major, info, rest := data[0]>>5, data[0]&0x1f, data[1:]
+
+	if info < 24 {
+		return major, uint64(info), rest, nil
+	}
+
+	size := 0
+
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported cbor additional info %d", info)
+	}
+
+	if len(rest) < size {
+		return 0, 0, nil, errors.New("unexpected end of cbor data")
+	}
+
+	buf := make([]byte, 8)
+	copy(buf[8-size:], rest[:size])
+
+	return major, binary.BigEndian.Uint64(buf), rest[size:], nil
+}
@@ -0,0 +1,132 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyModulus   = -1
+	coseKeyExponent  = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+
+	minRSAKeyBits = 2048
+)
+
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+func parsePublicKey(coseKey []byte) (publicKey, error) {
+	value, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return publicKey{}, err
+	}
+
+	if len(rest) != 0 {
+		return publicKey{}, errors.New("trailing data after public key")
+	}
+
+	params, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return publicKey{}, errors.New("public key is not a cose key")
+	}
+
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgorithmES256:
+		return parseEC2Key(params)
+	case kty == coseKeyTypeOKP && alg == AlgorithmEdDSA:
+		return parseOKPKey(params)
+	case kty == coseKeyTypeRSA && alg == AlgorithmRS256:
+		return parseRSAKey(params)
+	}
+
+	return publicKey{}, fmt.Errorf("unsupported public key type %d with algorithm %d", kty, alg)
+}
+
+func parseEC2Key(params map[interface{}]interface{}) (publicKey, error) {
+	crv, _ := params[int64(coseKeyCurve)].(int64)
+	x, _ := params[int64(coseKeyX)].([]byte)
+	y, _ := params[int64(coseKeyY)].([]byte)
+
+	if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+		return publicKey{}, errors.New("invalid p-256 public key")
+	}
+
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !key.Curve.IsOnCurve(key.X, key.Y) {
+		return publicKey{}, errors.New("p-256 public key is not on the curve")
+	}
+
+	return publicKey{algorithm: AlgorithmES256, key: key}, nil
+}
+
+func parseOKPKey(params map[interface{}]interface{}) (publicKey, error) {
+	crv, _ := params[int64(coseKeyCurve)].(int64)
+	x, _ := params[int64(coseKeyX)].([]byte)
+
+	if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+		return publicKey{}, errors.New("invalid ed25519 public key")
+	}
+
+	return publicKey{algorithm: AlgorithmEdDSA, key: ed25519.PublicKey(x)}, nil
+}
+
+func parseRSAKey(params map[interface{}]interface{}) (publicKey, error) {
+	n, _ := params[int64(coseKeyModulus)].([]byte)
+	e, _ := params[int64(coseKeyExponent)].([]byte)
+
+	if len(e) == 0 || len(e) > 4 {
+		return publicKey{}, errors.New("invalid rsa public exponent")
+	}
+
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if key.N.BitLen() < minRSAKeyBits {
+		return publicKey{}, fmt.Errorf("rsa public key shorter than %d bits", minRSAKeyBits)
+	}
+
+	return publicKey{algorithm: AlgorithmRS256, key: key}, nil
+}
+
+func (pk publicKey) verify(data, signature []byte) bool {
+	switch key := pk.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	return false
+}
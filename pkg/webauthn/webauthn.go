@@ -0,0 +1,383 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nsnikhil/erx"
+	"time"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+
+	attestationFormatNone = "none"
+
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+
+	challengeLength       = 32
+	maxCredentialIDLength = 1023
+
+	authDataMinLength = 37
+)
+
+var encoding = base64.RawURLEncoding
+
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+}
+
+type Attestation struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+type Assertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIDHash   []byte
+	flags      byte
+	signCount  uint32
+	credential Credential
+}
+
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeLength)
+
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, erx.WithArgs(erx.Operation("WebAuthn.NewChallenge"), err)
+	}
+
+	return challenge, nil
+}
+
+func VerifyAttestation(rp RelyingParty, challenge []byte, attestation Attestation) (Credential, error) {
+	wrap := func(err error) error {
+		return erx.WithArgs(erx.Operation("WebAuthn.VerifyAttestation"), erx.AuthenticationError, err)
+	}
+
+	err := verifyClientData(rp, ceremonyCreate, challenge, attestation.ClientDataJSON)
+	if err != nil {
+		return Credential{}, wrap(err)
+	}
+
+	rawAuthData, err := parseAttestationObject(attestation.AttestationObject)
+	if err != nil {
+		return Credential{}, wrap(err)
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, wrap(err)
+	}
+
+	err = verifyAuthenticatorData(rp, authData, false)
+	if err != nil {
+		return Credential{}, wrap(err)
+	}
+
+	if authData.flags&flagAttestedCredential == 0 {
+		return Credential{}, wrap(errors.New("attested credential data missing"))
+	}
+
+	return authData.credential, nil
+}
+
+func VerifyAssertion(rp RelyingParty, challenge []byte, credential Credential, assertion Assertion, requireUserVerification bool) (uint32, error) {
+	wrap := func(err error) error {
+		return erx.WithArgs(erx.Operation("WebAuthn.VerifyAssertion"), erx.AuthenticationError, err)
+	}
+
+	if !bytes.Equal(credential.ID, assertion.CredentialID) {
+		return 0, wrap(errors.New("assertion is for a different credential"))
+	}
+
+	err := verifyClientData(rp, ceremonyGet, challenge, assertion.ClientDataJSON)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	authData, err := parseAuthenticatorData(assertion.AuthenticatorData)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	err = verifyAuthenticatorData(rp, authData, requireUserVerification)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	key, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	clientDataHash := sha256.Sum256(assertion.ClientDataJSON)
+	signed := append(append([]byte{}, assertion.AuthenticatorData...), clientDataHash[:]...)
+
+	if !key.verify(signed, assertion.Signature) {
+		return 0, wrap(errors.New("invalid assertion signature"))
+	}
+
+	//NOTE: AUTHENTICATORS WITHOUT A COUNTER ALWAYS REPORT ZERO, ANY OTHER COUNTER THAT DOES NOT GROW POINTS TO A CLONED AUTHENTICATOR
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, wrap(fmt.Errorf("sign count %d did not increase from %d", authData.signCount, credential.SignCount))
+	}
+
+	return authData.signCount, nil
+}
+
+func verifyClientData(rp RelyingParty, ceremony string, challenge, raw []byte) error {
+	var data clientData
+
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return err
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("unexpected client data type %s", data.Type)
+	}
+
+	received, err := encoding.DecodeString(data.Challenge)
+	if err != nil {
+		return err
+	}
+
+	if len(challenge) == 0 || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return errors.New("challenge mismatch")
+	}
+
+	for _, origin := range rp.Origins {
+		if origin == data.Origin {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("origin %s not allowed", data.Origin)
+}
+
+func verifyAuthenticatorData(rp RelyingParty, authData authenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, rpIDHash[:]) != 1 {
+		return errors.New("relying party id mismatch")
+	}
+
+	if authData.flags&flagUserPresent == 0 {
+		return errors.New("user not present")
+	}
+
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return errors.New("user not verified")
+	}
+
+	return nil
+}
+
+func parseAttestationObject(raw []byte) ([]byte, error) {
+	value, rest, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after attestation object")
+	}
+
+	object, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("attestation object is not a map")
+	}
+
+	//NOTE: ONLY THE NONE FORMAT IS ACCEPTED, THE SERVICE DOES NOT RESTRICT WHICH AUTHENTICATOR MODELS CAN REGISTER
+	if format, _ := object["fmt"].(string); format != attestationFormatNone {
+		return nil, fmt.Errorf("unsupported attestation format %s", format)
+	}
+
+	authData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object without authenticator data")
+	}
+
+	return authData, nil
+}
+
+func parseAuthenticatorData(raw []byte) (authenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return authenticatorData{}, errors.New("authenticator data too short")
+	}
+
+	data := authenticatorData{
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if data.flags&flagAttestedCredential == 0 {
+		return data, nil
+	}
+
+	//NOTE: AAGUID (16 BYTES) AND CREDENTIAL ID LENGTH (2 BYTES) PRECEDE THE CREDENTIAL ID AND ITS COSE KEY
+	rest := raw[authDataMinLength:]
+	if len(rest) < 18 {
+		return authenticatorData{}, errors.New("attested credential data too short")
+	}
+
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if idLength == 0 || idLength > maxCredentialIDLength || idLength > len(rest) {
+		return authenticatorData{}, errors.New("invalid credential id length")
+	}
+
+	id := rest[:idLength]
+	rest = rest[idLength:]
+
+	_, extensions, err := decodeCBOR(rest)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+
+	publicKey := rest[:len(rest)-len(extensions)]
+
+	_, err = parsePublicKey(publicKey)
+	if err != nil {
+		return authenticatorData{}, err
+	}
+
+	data.credential = Credential{
+		ID:        append([]byte{}, id...),
+		PublicKey: append([]byte{}, publicKey...),
+		SignCount: data.signCount,
+	}
+
+	return data, nil
+}
+
+type RelyingPartyEntity struct {
+	ID   string
+	Name string
+}
+
+type UserEntity struct {
+	ID          string
+	Name        string
+	DisplayName string
+}
+
+type CredentialParameter struct {
+	Type      string
+	Algorithm int
+}
+
+type CredentialDescriptor struct {
+	Type string
+	ID   string
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string
+	UserVerification string
+}
+
+type CreationOptions struct {
+	RelyingParty           RelyingPartyEntity
+	User                   UserEntity
+	Challenge              string
+	CredentialParameters   []CredentialParameter
+	Timeout                int64
+	ExcludeCredentials     []CredentialDescriptor
+	AuthenticatorSelection AuthenticatorSelection
+	Attestation            string
+}
+
+type RequestOptions struct {
+	Challenge        string
+	Timeout          int64
+	RelyingPartyID   string
+	AllowCredentials []CredentialDescriptor
+	UserVerification string
+}
+
+func NewCreationOptions(rp RelyingParty, challenge []byte, userID, userName, displayName string, exclude [][]byte, timeout time.Duration) CreationOptions {
+	return CreationOptions{
+		RelyingParty: RelyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User: UserEntity{
+			ID:          encoding.EncodeToString(UserHandle(userID)),
+			Name:        userName,
+			DisplayName: displayName,
+		},
+		Challenge: encoding.EncodeToString(challenge),
+		CredentialParameters: []CredentialParameter{
+			{Type: "public-key", Algorithm: AlgorithmES256},
+			{Type: "public-key", Algorithm: AlgorithmEdDSA},
+			{Type: "public-key", Algorithm: AlgorithmRS256},
+		},
+		Timeout:            timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: attestationFormatNone,
+	}
+}
+
+func NewRequestOptions(rp RelyingParty, challenge []byte, allow [][]byte, requireUserVerification bool, timeout time.Duration) RequestOptions {
+	userVerification := "preferred"
+	if requireUserVerification {
+		userVerification = "required"
+	}
+
+	return RequestOptions{
+		Challenge:        encoding.EncodeToString(challenge),
+		Timeout:          timeout.Milliseconds(),
+		RelyingPartyID:   rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: userVerification,
+	}
+}
+
+func UserHandle(userID string) []byte {
+	return []byte(userID)
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	res := make([]CredentialDescriptor, 0, len(ids))
+
+	for _, id := range ids {
+		res = append(res, CredentialDescriptor{Type: "public-key", ID: encoding.EncodeToString(id)})
+	}
+
+	return res
+}
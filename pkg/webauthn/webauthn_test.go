@@ -0,0 +1,180 @@
+package webauthn_test
+
+import (
+	"github.com/nsnikhil/erx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"identification-service/pkg/test"
+	"identification-service/pkg/webauthn"
+	"testing"
+	"time"
+)
+
+func newRelyingParty() webauthn.RelyingParty {
+	return webauthn.RelyingParty{ID: test.WebAuthnRPID, Name: test.RandString(8), Origins: []string{test.WebAuthnOrigin}}
+}
+
+func TestNewChallenge(t *testing.T) {
+	a, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	b, err := webauthn.NewChallenge()
+	require.NoError(t, err)
+
+	assert.Len(t, a, 32)
+	assert.NotEqual(t, a, b)
+}
+
+func TestVerifyAttestationSuccess(t *testing.T) {
+	for name, algorithm := range map[string]int{"es256": webauthn.AlgorithmES256, "eddsa": webauthn.AlgorithmEdDSA} {
+		t.Run(name, func(t *testing.T) {
+			authenticator := test.NewAuthenticator(algorithm)
+			challenge := test.RandBytes(32)
+
+			credential, err := webauthn.VerifyAttestation(
+				newRelyingParty(),
+				challenge,
+				authenticator.Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, authenticator.Credential(), credential)
+		})
+	}
+}
+
+func TestVerifyAttestationFailure(t *testing.T) {
+	testCases := map[string]func() (webauthn.RelyingParty, []byte, webauthn.Attestation){
+		"test failure when challenge does not match": func() (webauthn.RelyingParty, []byte, webauthn.Attestation) {
+			return newRelyingParty(), test.RandBytes(32), test.NewAuthenticator(webauthn.AlgorithmES256).Attest(test.WebAuthnRPID, test.WebAuthnOrigin, test.RandBytes(32))
+		},
+		"test failure when origin is not allowed": func() (webauthn.RelyingParty, []byte, webauthn.Attestation) {
+			challenge := test.RandBytes(32)
+			return newRelyingParty(), challenge, test.NewAuthenticator(webauthn.AlgorithmES256).Attest(test.WebAuthnRPID, "https://evil.example.com", challenge)
+		},
+		"test failure when relying party id does not match": func() (webauthn.RelyingParty, []byte, webauthn.Attestation) {
+			challenge := test.RandBytes(32)
+			return newRelyingParty(), challenge, test.NewAuthenticator(webauthn.AlgorithmES256).Attest("evil.example.com", test.WebAuthnOrigin, challenge)
+		},
+		"test failure when ceremony type is wrong": func() (webauthn.RelyingParty, []byte, webauthn.Attestation) {
+			challenge := test.RandBytes(32)
+			assertion := test.NewAuthenticator(webauthn.AlgorithmES256).Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil)
+			attestation := test.NewAuthenticator(webauthn.AlgorithmES256).Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge)
+			attestation.ClientDataJSON = assertion.ClientDataJSON
+			return newRelyingParty(), challenge, attestation
+		},
+		"test failure when attestation object is malformed": func() (webauthn.RelyingParty, []byte, webauthn.Attestation) {
+			challenge := test.RandBytes(32)
+			attestation := test.NewAuthenticator(webauthn.AlgorithmES256).Attest(test.WebAuthnRPID, test.WebAuthnOrigin, challenge)
+			attestation.AttestationObject = attestation.AttestationObject[:len(attestation.AttestationObject)-10]
+			return newRelyingParty(), challenge, attestation
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			rp, challenge, attestation := testCase()
+
+			_, err := webauthn.VerifyAttestation(rp, challenge, attestation)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.AuthenticationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestVerifyAssertionSuccess(t *testing.T) {
+	for name, algorithm := range map[string]int{"es256": webauthn.AlgorithmES256, "eddsa": webauthn.AlgorithmEdDSA} {
+		t.Run(name, func(t *testing.T) {
+			authenticator := test.NewAuthenticator(algorithm)
+			credential := authenticator.Credential()
+			challenge := test.RandBytes(32)
+
+			signCount, err := webauthn.VerifyAssertion(
+				newRelyingParty(),
+				challenge,
+				credential,
+				authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil),
+				true,
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, credential.SignCount+1, signCount)
+		})
+	}
+}
+
+func TestVerifyAssertionFailure(t *testing.T) {
+	type args struct {
+		credential webauthn.Credential
+		challenge  []byte
+		assertion  webauthn.Assertion
+		requireUV  bool
+	}
+
+	testCases := map[string]func() args{
+		"test failure when signature is invalid": func() args {
+			authenticator := test.NewAuthenticator(webauthn.AlgorithmES256)
+			challenge := test.RandBytes(32)
+			assertion := authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil)
+			assertion.Signature = test.NewAuthenticator(webauthn.AlgorithmES256).Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil).Signature
+			return args{credential: authenticator.Credential(), challenge: challenge, assertion: assertion}
+		},
+		"test failure when credential does not match": func() args {
+			challenge := test.RandBytes(32)
+			assertion := test.NewAuthenticator(webauthn.AlgorithmES256).Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil)
+			return args{credential: test.NewAuthenticator(webauthn.AlgorithmES256).Credential(), challenge: challenge, assertion: assertion}
+		},
+		"test failure when sign count does not increase": func() args {
+			authenticator := test.NewAuthenticator(webauthn.AlgorithmEdDSA)
+			authenticator.SignCount = 10
+			credential := authenticator.Credential()
+			authenticator.SignCount = 5
+			challenge := test.RandBytes(32)
+			return args{credential: credential, challenge: challenge, assertion: authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil)}
+		},
+		"test failure when user verification is required but missing": func() args {
+			authenticator := test.NewAuthenticator(webauthn.AlgorithmEdDSA)
+			authenticator.UserVerify = false
+			challenge := test.RandBytes(32)
+			return args{credential: authenticator.Credential(), challenge: challenge, assertion: authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, challenge, nil), requireUV: true}
+		},
+		"test failure when challenge does not match": func() args {
+			authenticator := test.NewAuthenticator(webauthn.AlgorithmEdDSA)
+			return args{credential: authenticator.Credential(), challenge: test.RandBytes(32), assertion: authenticator.Assert(test.WebAuthnRPID, test.WebAuthnOrigin, test.RandBytes(32), nil)}
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			a := testCase()
+
+			_, err := webauthn.VerifyAssertion(newRelyingParty(), a.challenge, a.credential, a.assertion, a.requireUV)
+			require.Error(t, err)
+
+			assert.Equal(t, erx.AuthenticationError, err.(*erx.Erx).Kind())
+		})
+	}
+}
+
+func TestNewCreationOptions(t *testing.T) {
+	rp := newRelyingParty()
+	exclude := [][]byte{{1, 2, 3}}
+
+	options := webauthn.NewCreationOptions(rp, []byte{4, 5, 6}, "user-id", "name", "display", exclude, time.Minute)
+
+	assert.Equal(t, rp.ID, options.RelyingParty.ID)
+	assert.Equal(t, "BAUG", options.Challenge)
+	assert.Equal(t, "dXNlci1pZA", options.User.ID)
+	assert.Equal(t, int64(60000), options.Timeout)
+	assert.Equal(t, []webauthn.CredentialDescriptor{{Type: "public-key", ID: "AQID"}}, options.ExcludeCredentials)
+	assert.Equal(t, "none", options.Attestation)
+}
+
+func TestNewRequestOptions(t *testing.T) {
+	options := webauthn.NewRequestOptions(newRelyingParty(), []byte{4, 5, 6}, nil, true, time.Minute)
+
+	assert.Equal(t, test.WebAuthnRPID, options.RelyingPartyID)
+	assert.Equal(t, "required", options.UserVerification)
+	assert.Empty(t, options.AllowCredentials)
+}